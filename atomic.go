@@ -0,0 +1,28 @@
+package main
+
+import (
+    "fmt"
+    "os"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+)
+
+// runToTemp lets produce write target's content to a temp path and moves it
+// into place only when produce succeeds, actually wrote the file, and the
+// result differs from what is already on disk.
+func runToTemp(target string, produce func(tmp string) error) error {
+    tmp := atomicfile.TempSibling(target)
+    defer trackTemp(tmp)()
+    if err := produce(tmp); err != nil {
+        os.Remove(tmp)
+        return err
+    }
+    if _, err := os.Stat(tmp); err != nil {
+        return fmt.Errorf("expected output %s was not produced: %w", target, err)
+    }
+    if atomicfile.SameContent(tmp, target) {
+        logger.Info("Unchanged, kept existing", "path", target)
+        return os.Remove(tmp)
+    }
+    return os.Rename(tmp, target)
+}
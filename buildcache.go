@@ -0,0 +1,182 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sync"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+)
+
+// The external tools (openapi-generator, openapi-typescript, oapi-codegen,
+// Redocly, redoc-cli) are slow, so buildCacheFile in the output directory
+// records a hash of what each of their outputs was built from: the
+// bundled spec, the options and config files of the step and the tool
+// itself. A step whose hash matches and whose output still exists is
+// skipped; --force runs it anyway.
+
+const buildCacheFile = ".oas-build-cache" // YAML, but not a fragment file
+
+// buildCache is the content of buildCacheFile: output path (relative to the
+// output directory) -> hash.
+type buildCache struct {
+    Outputs map[string]string `yaml:"outputs"`
+}
+
+// buildCacheMu serializes the updates of the generators running concurrently.
+var buildCacheMu sync.Mutex
+
+// cachedStep runs the external step producing out unless the build cache
+// holds the same hash for it: that of the spec (postprocessed for the
+// bundle and docs), the options and the files or directories in inputs.
+func cachedStep(cfg *Config, what, out string, processed bool, options []string, inputs []string, run func() error) error {
+    hash, err := stepHash(cfg, processed, options, inputs)
+    if err != nil { return err }
+    key := cacheKey(cfg, out)
+    if !cfg.Force && outputExists(out) && readBuildCache(cfg).Outputs[key] == hash {
+        logger.Info(what+" up to date, skipped (--force to rebuild)", "path", out)
+        return nil
+    }
+    err = run()
+    if err != nil { hash = "" }
+    if err := recordBuild(cfg, key, hash); err != nil { logger.Warn("Could not update the build cache: "+err.Error(), "path", filepath.Join(cfg.OutputDir, buildCacheFile)) }
+    return err
+}
+
+// stepHash hashes the spec the step reads, its options and its inputs.
+func stepHash(cfg *Config, processed bool, options []string, inputs []string) (string, error) {
+    spec, err := specHash(cfg, processed)
+    if err != nil { return "", err }
+    h := sha256.New()
+    io.WriteString(h, spec)
+    for _, o := range options { fmt.Fprintf(h, "\x00%s", o) }
+    for _, in := range inputs {
+        if in == "" { continue }
+        if err := hashPath(h, in); err != nil { return "", fmt.Errorf("build cache: %w", err) }
+    }
+    return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type specHashKey struct {
+    root      string
+    processed bool
+}
+
+type specHashResult struct {
+    once sync.Once
+    hash string
+    err  error
+}
+
+// specHashes memoizes specHash between two writes of a root, as bundling a
+// large spec takes a while and several steps hash the same one.
+var specHashes sync.Map // specHashKey -> *specHashResult
+
+// forgetSpecHashes drops the memoized hashes of root, which is being rebuilt.
+func forgetSpecHashes(root string) {
+    specHashes.Delete(specHashKey{root, false})
+    specHashes.Delete(specHashKey{root, true})
+}
+
+// specHash hashes the root bundled into one document, so that a change in
+// any fragment it references counts; processed applies postprocessBundle
+// first, as for the bundle and docs.
+func specHash(cfg *Config, processed bool) (string, error) {
+    v, _ := specHashes.LoadOrStore(specHashKey{cfg.RootPath, processed}, &specHashResult{})
+    r := v.(*specHashResult)
+    r.once.Do(func() {
+        root, err := bundle.Load(cfg.RootPath)
+        if err == nil && processed { err = postprocessBundle(cfg, root) }
+        var spec []byte
+        if err == nil { spec, err = yamlnode.Marshal(root) }
+        if err != nil {
+            r.err = fmt.Errorf("build cache: %w", err)
+            return
+        }
+        sum := sha256.Sum256(spec)
+        r.hash = hex.EncodeToString(sum[:])
+    })
+    return r.hash, r.err
+}
+
+// hashPath adds the names and contents of the file, or the files below the
+// directory, at path to h.
+func hashPath(h io.Writer, path string) error {
+    return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+        if err != nil || d.IsDir() { return err }
+        fmt.Fprintf(h, "\x00%s\x00", p)
+        f, err := os.Open(p)
+        if err != nil { return err }
+        defer f.Close()
+        _, err = io.Copy(h, f)
+        return err
+    })
+}
+
+// toolStamp identifies the installed commands bins (path and modification
+// time, so that upgrades invalidate the cache) or the docker images standing
+// in for them.
+func toolStamp(bins ...string) string {
+    s := ""
+    for _, bin := range bins {
+        p := which(bin)
+        if img := dockerImage(bin); img != "" && p == bin { p = "docker:" + img }
+        if st, err := os.Stat(p); err == nil { p += "@" + st.ModTime().UTC().String() }
+        s += bin + "=" + p + ";"
+    }
+    return s
+}
+
+func cacheKey(cfg *Config, out string) string {
+    if rel, err := filepath.Rel(cfg.OutputDir, out); err == nil { return filepath.ToSlash(rel) }
+    return out
+}
+
+// outputExists reports whether out is a file or a non-empty directory.
+func outputExists(out string) bool {
+    st, err := os.Stat(out)
+    if err != nil { return false }
+    if !st.IsDir() { return true }
+    entries, err := os.ReadDir(out)
+    return err == nil && len(entries) > 0
+}
+
+func readBuildCache(cfg *Config) buildCache {
+    buildCacheMu.Lock()
+    defer buildCacheMu.Unlock()
+    return loadBuildCache(cfg)
+}
+
+func loadBuildCache(cfg *Config) buildCache {
+    var c buildCache
+    if data, err := os.ReadFile(filepath.Join(cfg.OutputDir, buildCacheFile)); err == nil { yaml.Unmarshal(data, &c) }
+    if c.Outputs == nil { c.Outputs = map[string]string{} }
+    return c
+}
+
+// recordBuild stores hash for key, or forgets key when hash is "".
+func recordBuild(cfg *Config, key, hash string) error {
+    buildCacheMu.Lock()
+    defer buildCacheMu.Unlock()
+    c := loadBuildCache(cfg)
+    if c.Outputs[key] == hash { return nil }
+    if hash == "" {
+        delete(c.Outputs, key)
+    } else {
+        c.Outputs[key] = hash
+    }
+    data, err := yaml.Marshal(c)
+    if err != nil { return err }
+    if err := ensureDir(cfg.OutputDir); err != nil { return err }
+    _, err = atomicfile.WriteFile(filepath.Join(cfg.OutputDir, buildCacheFile), data)
+    return err
+}
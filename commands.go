@@ -0,0 +1,331 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+    "github.com/bilbo290/oas-indexer/pkg/mock"
+    "github.com/bilbo290/oas-indexer/pkg/specdiff"
+)
+
+// Subcommands run single phases of the pipeline. Invoking the binary with
+// flags only keeps running the whole pipeline as before.
+
+var commands = map[string]func(args []string) error{
+    "build":    runBuildCommand,
+    "validate": runValidateCommand,
+    "bundle":   runBundleCommand,
+    "docs":     runDocsCommand,
+    "gen":      runGenCommand,
+    "diff":     runDiffCommand,
+    "watch":    runWatchCommand,
+    "serve":    runServeCommand,
+    "mock":     runMockCommand,
+    "graph":    runGraphCommand,
+    "stats":    runStatsCommand,
+    "examples": runExamplesCommand,
+    "split":    runSplitCommand,
+    "import":   runImport,
+    "export":   runExport,
+    "infer":    runInferCommand,
+    "doctor":   runDoctorCommand,
+}
+
+func printCommandsUsage() {
+    fmt.Fprintf(os.Stderr, "Commands:\n")
+    fmt.Fprintf(os.Stderr, "  build                            Check fragments and write the root spec\n")
+    fmt.Fprintf(os.Stderr, "  validate [--preset <p>]          Check fragments and run a validation preset, without writing\n")
+    fmt.Fprintf(os.Stderr, "  bundle [--out <yaml>]            Write the root and bundle it (default: dist/openapi.yaml)\n")
+    fmt.Fprintf(os.Stderr, "  docs [--out <html>]              Write the root and render HTML docs (default: dist/index.html)\n")
+    fmt.Fprintf(os.Stderr, "  gen [--ts <p>] [--go <p>]        Write the root and generate TypeScript and/or Go code\n")
+    fmt.Fprintf(os.Stderr, "  diff                             Fail when the root on disk differs from a fresh build\n")
+    fmt.Fprintf(os.Stderr, "  diff --old <spec|ref> [--new <spec>]  Classify changes as breaking, non-breaking or docs-only\n")
+    fmt.Fprintf(os.Stderr, "  graph [--format dot|mermaid]     Print the $ref graph of paths and components\n")
+    fmt.Fprintf(os.Stderr, "  stats [--format text|json]       Print spec metrics: operations, schemas, documentation and tag coverage\n")
+    fmt.Fprintf(os.Stderr, "  examples [--fill [--bundle-only]]  List media types and schemas without examples, or generate them\n")
+    fmt.Fprintf(os.Stderr, "  watch [--interval <d>]           Re-run the configured pipeline whenever a fragment changes\n")
+    fmt.Fprintf(os.Stderr, "  serve [--addr <host:port>]        Serve live-reloading docs and the bundled spec while editing\n")
+    fmt.Fprintf(os.Stderr, "  mock [--port <n>]                Serve example responses for every operation in the root\n")
+    fmt.Fprintf(os.Stderr, "  split --in <spec> --out <dir>    Decompose a monolithic spec into path and component fragments\n")
+    fmt.Fprintf(os.Stderr, "  import har <file> --input <dir>  Scaffold draft path fragments from a HAR capture\n")
+    fmt.Fprintf(os.Stderr, "  import postman <file> --input <dir>  Scaffold draft path and schema fragments from a Postman collection\n")
+    fmt.Fprintf(os.Stderr, "  infer --from <dir> --out <dir>   Infer draft schema fragments from JSON request/response samples\n")
+    fmt.Fprintf(os.Stderr, "  export postman -o <file>         Write a Postman collection and environment for the joined spec\n")
+    fmt.Fprintf(os.Stderr, "  export bruno|insomnia -o <path>  Write a Bruno collection directory or Insomnia export for the joined spec\n")
+    fmt.Fprintf(os.Stderr, "  export jsonschema -o <dir>       Write each component schema as a JSON Schema 2020-12 document\n")
+    fmt.Fprintf(os.Stderr, "  export graphql -o <file>         Draft a GraphQL schema from the components and operations (experimental)\n")
+    fmt.Fprintf(os.Stderr, "  export proto -o <file>           Convert component schemas to proto3 messages\n")
+    fmt.Fprintf(os.Stderr, "  doctor [--tools t=c,...]         Check the external tools are installed and meet their version pins\n")
+    fmt.Fprintf(os.Stderr, "\nEvery command accepts the options above; run '<command> -h' for its own flags.\n")
+}
+
+// commandFlags sets up a subcommand's flag set with the shared options.
+func commandFlags(name, usage string) (*flag.FlagSet, *optionFlags) {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    opts := registerOptionFlags(fs)
+    fs.Usage = func() {
+        fmt.Fprintf(os.Stderr, "Usage:\n  oas-indexer %s\n\n", usage)
+        fs.PrintDefaults()
+    }
+    return fs, opts
+}
+
+// commandConfig parses args (which must not contain positional arguments)
+// and resolves the shared options into a Config.
+func commandConfig(fs *flag.FlagSet, opts *optionFlags, args []string) (*Config, error) {
+    positional, err := parseInterspersed(fs, args)
+    if err != nil { return nil, withExitCode(exitConfig, err) }
+    if len(positional) > 0 {
+        fs.Usage()
+        return nil, withExitCode(exitConfig, fmt.Errorf("%s: unexpected argument %q", fs.Name(), positional[0]))
+    }
+    return opts.config(fs)
+}
+
+// onlyRoot clears the outputs configured for other phases, so a subcommand
+// run from a shared config file does not also bundle, render or generate.
+func onlyRoot(cfg *Config) {
+    cfg.OutputTS, cfg.OutputGo, cfg.BundleOut, cfg.Redocly = "", "", "", ""
+}
+
+func runBuildCommand(args []string) error {
+    fs, opts := commandFlags("build", "build --input <dir> [options]")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    onlyRoot(cfg)
+    if err := checkInput(cfg); err != nil { return err }
+    if cfg.DryRun {
+        if err := dryRunAsyncAPI(cfg); err != nil { return err }
+        return dryRun(cfg)
+    }
+    if err := writeAsyncAPI(cfg); err != nil { return err }
+    return forEachVersion(cfg, writeRoot)
+}
+
+func runValidateCommand(args []string) error {
+    fs, opts := commandFlags("validate", "validate --input <dir> [--preset <preset>] [--structural] [--validate-examples] [options]")
+    preset := fs.String("preset", "", "Validation preset to run (google, restful); same as --validate")
+    list := fs.Bool("list-presets", false, "List available validation presets")
+    positional, err := parseInterspersed(fs, args)
+    if err != nil { return withExitCode(exitConfig, err) }
+    if *list {
+        listAvailablePresets()
+        return nil
+    }
+    if len(positional) > 0 {
+        fs.Usage()
+        return withExitCode(exitConfig, fmt.Errorf("validate: unexpected argument %q", positional[0]))
+    }
+    cfg, err := opts.config(fs)
+    if err != nil { return err }
+    if p := strings.TrimSpace(*preset); p != "" { cfg.ValidatePreset = p }
+    if cfg.ValidatePreset == "" && !cfg.Structural && !cfg.Examples {
+        return withExitCode(exitConfig, errors.New("validate: no preset given; pass --preset, --structural or --validate-examples (or set validate in " + DefaultConfigFile + ")"))
+    }
+    cfg.SkipValidation = false
+    if err := checkInput(cfg); err != nil { return err }
+    if cfg.Structural {
+        root, err := indexer.RenderRoot(cfg.index())
+        if err != nil { return err }
+        if err := checkStructure(cfg, cfg.RootPath, root); err != nil { return err }
+    }
+    fmt.Fprintln(cfg.progressOut(), "Validation passed")
+    return nil
+}
+
+func runBundleCommand(args []string) error {
+    fs, opts := commandFlags("bundle", "bundle --input <dir> [--out <yaml>] [options]")
+    out := fs.String("out", "", "Bundled spec path (default: --bundle, else dist/openapi.yaml or .json per --format)")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := firstNonEmpty(strings.TrimSpace(*out), cfg.BundleOut, defaultBundlePath(cfg))
+    onlyRoot(cfg)
+    cfg.BundleOut = absJoin(cfg.Cwd, target)
+    cfg.SkipValidation = true
+    if err := checkInput(cfg); err != nil { return err }
+    if cfg.DryRun { return dryRun(cfg) }
+    return forEachVersion(cfg, func(cfg *Config) error {
+        if err := writeRoot(cfg); err != nil { return err }
+        return bundleSpec(cfg)
+    })
+}
+
+func runDocsCommand(args []string) error {
+    fs, opts := commandFlags("docs", "docs --input <dir> [--out <html>] [options]")
+    out := fs.String("out", "", "HTML docs path (default: --redocly, else dist/index.html)")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := firstNonEmpty(strings.TrimSpace(*out), cfg.Redocly, defaultDocsPath(cfg))
+    // Render from the freshly written root rather than a possibly stale bundle.
+    onlyRoot(cfg)
+    cfg.Redocly = absJoin(cfg.Cwd, target)
+    cfg.SkipValidation = true
+    if err := checkInput(cfg); err != nil { return err }
+    if cfg.DryRun { return dryRun(cfg) }
+    return forEachVersion(cfg, func(cfg *Config) error {
+        if err := writeRoot(cfg); err != nil { return err }
+        return buildDocsHTML(cfg)
+    })
+}
+
+func runGenCommand(args []string) error {
+    fs, opts := commandFlags("gen", "gen --input <dir> [--ts <path>] [--go <path>] [--gen <generator>:<dir>] [options]")
+    ts := fs.String("ts", "", "TypeScript output path (default: --output-ts)")
+    goOut := fs.String("go", "", "Go output path (default: --output-go)")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    tsOut, goPath := firstNonEmpty(strings.TrimSpace(*ts), cfg.OutputTS), firstNonEmpty(strings.TrimSpace(*goOut), cfg.OutputGo)
+    if tsOut == "" && goPath == "" && len(cfg.Generators) == 0 {
+        return withExitCode(exitConfig, errors.New("gen: nothing to generate; pass --ts, --go and/or --gen (or set outputTs/outputGo/generators in " + DefaultConfigFile + ")"))
+    }
+    onlyRoot(cfg)
+    cfg.OutputTS, cfg.OutputGo = tsOut, goPath
+    cfg.SkipValidation = true
+    if err := checkInput(cfg); err != nil { return err }
+    if cfg.DryRun { return dryRun(cfg) }
+    return forEachVersion(cfg, func(cfg *Config) error {
+        if err := writeRoot(cfg); err != nil { return err }
+        return generateCode(cfg)
+    })
+}
+
+func runDiffCommand(args []string) error {
+    fs, opts := commandFlags("diff", "diff --input <dir> [--old <spec|git-ref>] [--new <spec>] [--fail-on <level>] [options]")
+    oldArg := fs.String("old", "", "Compare specs instead: the old spec file, or a git ref whose input tree is built")
+    newArg := fs.String("new", "", "New spec file for --old (default: a fresh build of --input)")
+    failOn := fs.String("fail-on", "breaking", "With --old, exit non-zero on: breaking, any or none")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    onlyRoot(cfg)
+    cfg.SkipValidation = true
+    if *oldArg != "" || *newArg != "" {
+        return runSpecDiff(cfg, strings.TrimSpace(*oldArg), strings.TrimSpace(*newArg), strings.ToLower(strings.TrimSpace(*failOn)))
+    }
+    if err := checkInput(cfg); err != nil { return err }
+
+    want, err := indexer.RenderRoot(cfg.index())
+    if err != nil { return err }
+    have, err := os.ReadFile(cfg.RootPath)
+    if err != nil && !os.IsNotExist(err) { return err }
+    if string(have) == string(want) {
+        fmt.Fprintf(os.Stdout, "Root spec up to date: %s\n", cfg.RootPath)
+        return nil
+    }
+    printLineDiff(os.Stdout, cfg.RootFile+" (on disk)", cfg.RootFile+" (generated)", string(have), string(want))
+    return fmt.Errorf("root spec %s is out of date; run 'oas-indexer build'", cfg.RootPath)
+}
+
+// runSpecDiff classifies the changes between two specs (see loadDiffSide).
+func runSpecDiff(cfg *Config, oldArg, newArg, failOn string) error {
+    levels, ok := failOnLevels[failOn]
+    if !ok { return withExitCode(exitConfig, fmt.Errorf("diff: invalid --fail-on %q (expected breaking, any or none)", failOn)) }
+    if oldArg == "" { return errors.New("diff: --new requires --old") }
+    if newArg == "" {
+        if err := checkInput(cfg); err != nil { return err }
+    }
+    oldSpec, err := loadDiffSide(cfg, oldArg)
+    if err != nil { return fmt.Errorf("diff: old spec: %w", err) }
+    newSpec, err := loadDiffSide(cfg, newArg)
+    if err != nil { return fmt.Errorf("diff: new spec: %w", err) }
+
+    counts := printSpecDiff(os.Stdout, specdiff.Compare(oldSpec, newSpec))
+    for _, sev := range levels {
+        if counts[sev] > 0 { return withExitCode(exitValidation, fmt.Errorf("diff: %d %s change(s)", counts[sev], sev)) }
+    }
+    return nil
+}
+
+func runMockCommand(args []string) error {
+    fs, opts := commandFlags("mock", "mock --input <dir> [--port <n>] [--host <host>] [options]")
+    port := fs.Int("port", 8080, "Port to listen on")
+    host := fs.String("host", "localhost", "Interface to listen on (0.0.0.0 for all)")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    onlyRoot(cfg)
+    cfg.SkipValidation = true
+    if err := checkInput(cfg); err != nil { return err }
+    if err := writeRoot(cfg); err != nil { return err }
+    srv, err := mock.Load(cfg.RootPath)
+    if err != nil { return fmt.Errorf("mock: %w", err) }
+
+    addr := net.JoinHostPort(*host, strconv.Itoa(*port))
+    fmt.Fprintf(os.Stdout, "Mocking %s at http://%s/ (Ctrl-C to stop)\n", cfg.RootPath, addr)
+    return http.ListenAndServe(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+        srv.ServeHTTP(rec, r)
+        fmt.Fprintf(os.Stdout, "%s %s -> %d\n", r.Method, r.URL.RequestURI(), rec.status)
+    }))
+}
+
+// statusRecorder remembers the status written through it, for request logs.
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+    r.status = code
+    r.ResponseWriter.WriteHeader(code)
+}
+
+func runWatchCommand(args []string) error {
+    fs, opts := commandFlags("watch", "watch --input <dir> [--interval <duration>] [options]")
+    interval := fs.Duration("interval", time.Second, "How often to poll the input tree for changes")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    if *interval <= 0 { return fmt.Errorf("watch: --interval must be positive (got %s)", *interval) }
+
+    var last string
+    for {
+        snap, err := treeSnapshot(cfg)
+        if err != nil { return err }
+        if snap != last {
+            last = snap
+            cfg.Logger = discoveryLogger()
+            if err := run(cfg); err != nil {
+                logger.Error(err.Error())
+            }
+            logger.Info("Watching for changes (Ctrl-C to stop)", "path", cfg.InputDir)
+        }
+        time.Sleep(*interval)
+    }
+}
+
+// treeSnapshot summarizes name, size and mtime of every fragment and of the
+// header and ignore files, so any edit, addition or removal changes the result.
+func treeSnapshot(cfg *Config) (string, error) {
+    var b strings.Builder
+    for _, dir := range cfg.FragmentDirs() {
+        files, err := indexer.ListFragments(cfg.index(), dir)
+        if _, fatal := indexer.DiscoveryProblems(err); fatal != nil { return "", fatal }
+        for _, f := range files {
+            st, err := os.Stat(f)
+            if err != nil { continue }
+            fmt.Fprintf(&b, "%s\x00%d\x00%d\n", f, st.Size(), st.ModTime().UnixNano())
+        }
+    }
+    for _, name := range append(indexer.HeaderFiles, indexer.IgnoreFile) {
+        if st, err := os.Stat(filepath.Join(cfg.InputDir, name)); err == nil {
+            fmt.Fprintf(&b, "%s\x00%d\x00%d\n", name, st.Size(), st.ModTime().UnixNano())
+        }
+    }
+    if cfg.AutoTags {
+        tagFiles, err := indexer.DirTagFiles(cfg.index())
+        if err != nil { return "", err }
+        for _, f := range tagFiles {
+            if st, err := os.Stat(f); err == nil {
+                fmt.Fprintf(&b, "%s\x00%d\x00%d\n", f, st.Size(), st.ModTime().UnixNano())
+            }
+        }
+    }
+    return b.String(), nil
+}
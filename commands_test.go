@@ -0,0 +1,48 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestBuildCommandWritesAsyncAPI(t *testing.T) {
+    input := writeExitTestTree(t, validUsers)
+    writeTestFile(t, filepath.Join(input, "events", "channels", "user.yaml"), "subscribe:\n  message:\n    payload:\n      type: string\n")
+    output := t.TempDir()
+    if err := commands["build"]([]string{"--input", input, "--output", output}); err != nil { t.Fatal(err) }
+    for _, name := range []string{"root.yaml", "asyncapi.yaml"} {
+        if _, err := os.Stat(filepath.Join(output, name)); err != nil { t.Errorf("build did not write %s: %v", name, err) }
+    }
+}
+
+func TestGenCommandRunsGenerators(t *testing.T) {
+    if openapiGeneratorBin() != "" { t.Skip("openapi-generator is installed") }
+    input := writeExitTestTree(t, validUsers)
+    err := commands["gen"]([]string{"--input", input, "--output", t.TempDir(), "--gen", "kotlin:" + t.TempDir()})
+    if err == nil || !strings.Contains(err.Error(), "--gen needs openapi-generator") { t.Errorf("error = %v, want the --gen target to run", err) }
+}
+
+func TestGenCommandWritesEveryTarget(t *testing.T) {
+    input := writeExitTestTree(t, validUsers)
+    out := t.TempDir()
+    ts, goOut := filepath.Join(out, "web", "api.ts"), filepath.Join(out, "api", "api.gen.go")
+    args := []string{"--input", input, "--output", out, "--ts", ts, "--go", goOut, "--ts-engine", "native", "--go-engine", "native"}
+    if err := commands["gen"](args); err != nil { t.Fatal(err) }
+    for _, f := range []string{ts, goOut} {
+        if _, err := os.Stat(f); err != nil { t.Errorf("gen did not write %s: %v", f, err) }
+    }
+}
+
+func TestGenCommandReportsFailedTargets(t *testing.T) {
+    if openapiGeneratorBin() != "" || which("openapi-typescript") != "" { t.Skip("a TypeScript generator is installed") }
+    input := writeExitTestTree(t, validUsers)
+    out := t.TempDir()
+    goOut := filepath.Join(out, "api.gen.go")
+    err := commands["gen"]([]string{"--input", input, "--output", out, "--ts", filepath.Join(out, "api.ts"), "--go", goOut, "--ts-engine", "external", "--go-engine", "native"})
+    if err == nil || !strings.Contains(err.Error(), "1 of 2 generation targets failed") || exitStatus(err) != exitTool {
+        t.Errorf("error = %v (exit %d), want the TypeScript target to fail with exit %d", err, exitStatus(err), exitTool)
+    }
+    if _, err := os.Stat(goOut); err != nil { t.Errorf("the Go target did not run: %v", err) }
+}
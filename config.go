@@ -0,0 +1,435 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// DefaultConfigFile is loaded from the working directory when --config is not given.
+const DefaultConfigFile = ".oas-indexer.yaml"
+
+// configOption ties a key in the config file to the flag it stands in for and
+// the environment variable that overrides it.
+type configOption struct {
+    Key  string
+    Flag string
+    Env  string
+    Path bool // relative values are resolved against the config file's directory
+    Map  bool // the value may be a mapping, passed to the flag as name=value,...
+    List bool // the value may be a list, passed to the (repeatable) flag one entry per line
+}
+
+var configOptions = []configOption{
+    {Key: "input", Flag: "input", Env: "OAS_INDEXER_INPUT", Path: true},
+    {Key: "output", Flag: "output", Env: "OAS_INDEXER_OUTPUT", Path: true},
+    {Key: "root", Flag: "root", Env: "OAS_INDEXER_ROOT"},
+    {Key: "format", Flag: "format", Env: "OAS_INDEXER_FORMAT"},
+    {Key: "asyncapi", Flag: "asyncapi", Env: "OAS_INDEXER_ASYNCAPI"},
+    {Key: "outputTs", Flag: "output-ts", Env: "OAS_INDEXER_OUTPUT_TS", Path: true},
+    {Key: "outputGo", Flag: "output-go", Env: "OAS_INDEXER_OUTPUT_GO", Path: true},
+    {Key: "redocly", Flag: "redocly", Env: "OAS_INDEXER_REDOCLY", Path: true},
+    {Key: "bundle", Flag: "bundle", Env: "OAS_INDEXER_BUNDLE", Path: true},
+    {Key: "docsRenderer", Flag: "docs-renderer", Env: "OAS_INDEXER_DOCS_RENDERER"},
+    {Key: "bundler", Flag: "bundler", Env: "OAS_INDEXER_BUNDLER"},
+    {Key: "audience", Flag: "audience", Env: "OAS_INDEXER_AUDIENCE"},
+    {Key: "overlays", Flag: "overlay", Env: "OAS_INDEXER_OVERLAYS", Path: true, List: true},
+    {Key: "vars", Flag: "vars", Env: "OAS_INDEXER_VARS", Path: true},
+    {Key: "expandVars", Flag: "expand-vars", Env: "OAS_INDEXER_EXPAND_VARS"},
+    {Key: "redoclyConfig", Flag: "redocly-config", Env: "OAS_INDEXER_REDOCLY_CONFIG", Path: true},
+    {Key: "tsGenerator", Flag: "ts-generator", Env: "TS_GENERATOR"},
+    {Key: "tsEngine", Flag: "ts-engine", Env: "OAS_INDEXER_TS_ENGINE"},
+    {Key: "outputTsRuntime", Flag: "output-ts-runtime", Env: "OAS_INDEXER_OUTPUT_TS_RUNTIME"},
+    {Key: "goGenerator", Flag: "go-generator", Env: "GO_GENERATOR"},
+    {Key: "goEngine", Flag: "go-engine", Env: "OAS_INDEXER_GO_ENGINE"},
+    {Key: "tsTemplateDir", Flag: "ts-template-dir", Env: "OAS_INDEXER_TS_TEMPLATE_DIR", Path: true},
+    {Key: "goTemplateDir", Flag: "go-template-dir", Env: "OAS_INDEXER_GO_TEMPLATE_DIR", Path: true},
+    {Key: "oapiCodegenConfig", Flag: "oapi-codegen-config", Env: "OAS_INDEXER_OAPI_CODEGEN_CONFIG", Path: true},
+    {Key: "goGenerate", Flag: "go-generate", Env: "OAS_INDEXER_GO_GENERATE", List: true},
+    {Key: "additionalProperties", Flag: "additional-properties", Env: "OAS_INDEXER_ADDITIONAL_PROPERTIES", Map: true},
+    {Key: "tools", Flag: "tools", Env: "OAS_INDEXER_TOOLS", Map: true},
+    {Key: "checkTools", Flag: "check-tools", Env: "OAS_INDEXER_CHECK_TOOLS"},
+    {Key: "dryRun", Flag: "dry-run", Env: "OAS_INDEXER_DRY_RUN"},
+    {Key: "force", Flag: "force", Env: "OAS_INDEXER_FORCE"},
+    {Key: "verbose", Flag: "verbose", Env: "OAS_INDEXER_VERBOSE"},
+    {Key: "quiet", Flag: "quiet", Env: "OAS_INDEXER_QUIET"},
+    {Key: "logFormat", Flag: "log-format", Env: "OAS_INDEXER_LOG_FORMAT"},
+    {Key: "toolTimeout", Flag: "tool-timeout", Env: "OAS_INDEXER_TOOL_TIMEOUT"},
+    {Key: "useDocker", Flag: "use-docker", Env: "OAS_INDEXER_USE_DOCKER"},
+    {Key: "generators", Flag: "gen", Env: "OAS_INDEXER_GEN"},
+    {Key: "genArgs", Flag: "gen-args", Env: "OAS_INDEXER_GEN_ARGS"},
+    {Key: "join", Flag: "join", Env: "OAS_INDEXER_JOIN"},
+    {Key: "perVersion", Flag: "per-version", Env: "OAS_INDEXER_PER_VERSION"},
+    {Key: "mergeKeys", Flag: "merge-keys", Env: "OAS_INDEXER_MERGE_KEYS"},
+    {Key: "followSymlinks", Flag: "follow-symlinks", Env: "OAS_INDEXER_FOLLOW_SYMLINKS"},
+    {Key: "include", Flag: "include", Env: "OAS_INDEXER_INCLUDE", List: true},
+    {Key: "exclude", Flag: "exclude", Env: "OAS_INDEXER_EXCLUDE", List: true},
+    {Key: "pathCasing", Flag: "path-casing", Env: "OAS_INDEXER_PATH_CASING"},
+    {Key: "pathVersion", Flag: "path-version", Env: "OAS_INDEXER_PATH_VERSION"},
+    {Key: "pathStripPrefix", Flag: "path-strip-prefix", Env: "OAS_INDEXER_PATH_STRIP_PREFIX"},
+    {Key: "pathRewrite", Flag: "path-rewrite", Env: "OAS_INDEXER_PATH_REWRITE"},
+    {Key: "componentNaming", Flag: "component-naming", Env: "OAS_INDEXER_COMPONENT_NAMING"},
+    {Key: "nameMap", Flag: "name-map", Env: "OAS_INDEXER_NAME_MAP", Map: true},
+    {Key: "namespaceSeparator", Flag: "namespace-separator", Env: "OAS_INDEXER_NAMESPACE_SEPARATOR"},
+    {Key: "sharedComponents", Flag: "shared-components", Env: "OAS_INDEXER_SHARED_COMPONENTS", Path: true},
+    {Key: "vendorDir", Flag: "vendor-dir", Env: "OAS_INDEXER_VENDOR_DIR", Path: true},
+    {Key: "offline", Flag: "offline", Env: "OAS_INDEXER_OFFLINE"},
+    {Key: "deps", Flag: "dep", Env: "OAS_INDEXER_DEPS"},
+    {Key: "methodFiles", Flag: "method-files", Env: "OAS_INDEXER_METHOD_FILES"},
+    {Key: "autoTags", Flag: "auto-tags", Env: "OAS_INDEXER_AUTO_TAGS"},
+    {Key: "standardResponses", Flag: "standard-responses", Env: "OAS_INDEXER_STANDARD_RESPONSES", Map: true},
+    {Key: "openapiVersion", Flag: "openapi-version", Env: "OAS_INDEXER_OPENAPI_VERSION"},
+    {Key: "downconvert", Flag: "downconvert", Env: "OAS_INDEXER_DOWNCONVERT"},
+    {Key: "all", Flag: "all", Env: "OAS_INDEXER_ALL"},
+    {Key: "validate", Flag: "validate", Env: "OAS_INDEXER_VALIDATE"},
+    {Key: "validateRules", Flag: "validate-rules", Env: "OAS_INDEXER_VALIDATE_RULES", Path: true},
+    {Key: "rules", Flag: "rules", Env: "OAS_INDEXER_RULES", Map: true},
+    {Key: "validateBaseline", Flag: "validate-baseline", Env: "OAS_INDEXER_VALIDATE_BASELINE", Path: true},
+    {Key: "updateBaseline", Flag: "update-baseline", Env: "OAS_INDEXER_UPDATE_BASELINE"},
+    {Key: "validateFormat", Flag: "validate-format", Env: "OAS_INDEXER_VALIDATE_FORMAT"},
+    {Key: "validateReport", Flag: "validate-report", Env: "OAS_INDEXER_VALIDATE_REPORT", Path: true},
+    {Key: "structural", Flag: "structural", Env: "OAS_INDEXER_STRUCTURAL"},
+    {Key: "validateExamples", Flag: "validate-examples", Env: "OAS_INDEXER_VALIDATE_EXAMPLES"},
+    {Key: "skipValidation", Flag: "skip-validation", Env: "OAS_INDEXER_SKIP_VALIDATION"},
+    {Key: "validateStopOnError", Flag: "validate-stop-on-error", Env: "OAS_INDEXER_VALIDATE_STOP_ON_ERROR"},
+    {Key: "cycles", Flag: "cycles", Env: "OAS_INDEXER_CYCLES"},
+    {Key: "prune", Flag: "prune", Env: "OAS_INDEXER_PRUNE"},
+    {Key: "strict", Flag: "strict", Env: "OAS_INDEXER_STRICT"},
+    {Key: "expandTabs", Flag: "expand-tabs", Env: "OAS_INDEXER_EXPAND_TABS"},
+}
+
+// pathKeySection maps the keys of the config file's pathKey section to the
+// options they stand for.
+var pathKeySection = map[string]string{
+    "casing": "pathCasing", "version": "pathVersion", "stripPrefix": "pathStripPrefix", "rewrite": "pathRewrite",
+}
+
+// flagShorthands maps short flag names to the long flag they alias.
+var flagShorthands = map[string]string{"i": "input", "o": "output", "r": "root"}
+
+// apiPlaceholder in a config value of a workspace stands for the API's name,
+// e.g. bundle: dist/{api}/openapi.yaml.
+const apiPlaceholder = "{api}"
+
+// workspaceAPI is an entry of the config file's apis section: an API's name
+// and the option values that override the top-level ones for it.
+type workspaceAPI struct {
+    Name string
+    Vals map[string]string
+}
+
+var reAPIName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// loadConfigFile reads a config file into flag-name -> value pairs, plus the
+// APIs of its apis section. Missing files are an error only when explicit is set.
+func loadConfigFile(path string, explicit bool) (map[string]string, []workspaceAPI, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        if os.IsNotExist(err) && !explicit { return nil, nil, nil }
+        return nil, nil, fmt.Errorf("read config: %w", err)
+    }
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, nil, fmt.Errorf("%s: %v", path, err)
+    }
+    vals := map[string]string{}
+    root := yamlnode.DocRoot(&doc)
+    if root == nil { return vals, nil, nil }
+    if root.Kind != yaml.MappingNode {
+        return nil, nil, fmt.Errorf("%s:%d:%d: config must be a mapping", path, root.Line, root.Column)
+    }
+    var apis []workspaceAPI
+    var problems []string
+    for i := 0; i+1 < len(root.Content); i += 2 {
+        k, v := root.Content[i], root.Content[i+1]
+        if k.Value == "apis" {
+            var errs []string
+            apis, errs = loadAPIsSection(path, v)
+            problems = append(problems, errs...)
+            continue
+        }
+        problems = append(problems, loadConfigKey(path, k, v, vals)...)
+    }
+    if len(problems) > 0 {
+        return nil, nil, fmt.Errorf("invalid config file:\n  %s", strings.Join(problems, "\n  "))
+    }
+    return vals, apis, nil
+}
+
+// loadConfigKey reads the option k (or the pathKey or deps section) into vals.
+func loadConfigKey(path string, k, v *yaml.Node, vals map[string]string) []string {
+    switch k.Value {
+    case "pathKey":
+        return loadPathKeySection(path, v, vals)
+    case "deps":
+        return loadDepsSection(path, v, vals)
+    case "generators":
+        return loadGeneratorsSection(path, v, vals)
+    }
+    opt, ok := lookupConfigOption(k.Value)
+    if !ok {
+        return []string{fmt.Sprintf("%s:%d:%d: unknown config key %q", path, k.Line, k.Column, k.Value)}
+    }
+    if opt.Map && v.Kind == yaml.MappingNode {
+        var problems, pairs []string
+        for j := 0; j+1 < len(v.Content); j += 2 {
+            mk, mv := v.Content[j], v.Content[j+1]
+            if mv.Kind != yaml.ScalarNode {
+                problems = append(problems, fmt.Sprintf("%s:%d:%d: %s.%s must be a scalar, found %s", path, mv.Line, mv.Column, k.Value, mk.Value, yamlnode.KindName(mv)))
+                continue
+            }
+            pairs = append(pairs, mk.Value+"="+mv.Value)
+        }
+        vals[opt.Flag] = strings.Join(pairs, ",")
+        return problems
+    }
+    resolve := func(val string) string {
+        if opt.Path && val != "" && !filepath.IsAbs(val) {
+            val = filepath.Join(filepath.Dir(path), filepath.FromSlash(val))
+        }
+        return val
+    }
+    if opt.List && v.Kind == yaml.SequenceNode {
+        var problems, items []string
+        for _, item := range v.Content {
+            if item.Kind != yaml.ScalarNode {
+                problems = append(problems, fmt.Sprintf("%s:%d:%d: %s entries must be scalars, found %s", path, item.Line, item.Column, k.Value, yamlnode.KindName(item)))
+                continue
+            }
+            items = append(items, resolve(item.Value))
+        }
+        vals[opt.Flag] = strings.Join(items, "\n")
+        return problems
+    }
+    if v.Kind != yaml.ScalarNode {
+        return []string{fmt.Sprintf("%s:%d:%d: %s must be a scalar, found %s", path, v.Line, v.Column, k.Value, yamlnode.KindName(v))}
+    }
+    vals[opt.Flag] = resolve(v.Value)
+    return nil
+}
+
+// loadAPIsSection reads the apis section: a mapping of API names to the
+// options (any top-level config key) that differ for each API.
+func loadAPIsSection(path string, section *yaml.Node) ([]workspaceAPI, []string) {
+    if section.Kind != yaml.MappingNode {
+        return nil, []string{fmt.Sprintf("%s:%d:%d: apis must be a mapping of API names to options, found %s", path, section.Line, section.Column, yamlnode.KindName(section))}
+    }
+    var apis []workspaceAPI
+    var problems []string
+    for i := 0; i+1 < len(section.Content); i += 2 {
+        name, opts := section.Content[i], section.Content[i+1]
+        if !reAPIName.MatchString(name.Value) {
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: invalid API name %q (letters, digits, '.', '_' and '-')", path, name.Line, name.Column, name.Value))
+            continue
+        }
+        api := workspaceAPI{Name: name.Value, Vals: map[string]string{}}
+        switch opts.Kind {
+        case yaml.MappingNode:
+            for j := 0; j+1 < len(opts.Content); j += 2 {
+                k, v := opts.Content[j], opts.Content[j+1]
+                if k.Value == "apis" {
+                    problems = append(problems, fmt.Sprintf("%s:%d:%d: apis cannot be nested", path, k.Line, k.Column))
+                    continue
+                }
+                problems = append(problems, loadConfigKey(path, k, v, api.Vals)...)
+            }
+        case yaml.ScalarNode:
+            if opts.Tag != "!!null" { // a bare path is the API's input dir
+                problems = append(problems, loadConfigKey(path, &yaml.Node{Kind: yaml.ScalarNode, Value: "input"}, opts, api.Vals)...)
+            }
+        default:
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: apis.%s must be a mapping of options or an input dir, found %s", path, opts.Line, opts.Column, name.Value, yamlnode.KindName(opts)))
+        }
+        apis = append(apis, api)
+    }
+    return apis, problems
+}
+
+// loadPathKeySection reads the pathKey section into vals. Its rewrite list
+// holds {match, replace} mappings or "pattern=>replacement" strings.
+func loadPathKeySection(path string, section *yaml.Node, vals map[string]string) []string {
+    if section.Kind != yaml.MappingNode {
+        return []string{fmt.Sprintf("%s:%d:%d: pathKey must be a mapping, found %s", path, section.Line, section.Column, yamlnode.KindName(section))}
+    }
+    var problems []string
+    for i := 0; i+1 < len(section.Content); i += 2 {
+        k, v := section.Content[i], section.Content[i+1]
+        opt, _ := lookupConfigOption(pathKeySection[k.Value])
+        switch {
+        case opt.Flag == "":
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: unknown pathKey key %q (expected casing, version, stripPrefix or rewrite)", path, k.Line, k.Column, k.Value))
+        case k.Value == "rewrite" && v.Kind == yaml.SequenceNode:
+            var rules []string
+            for _, item := range v.Content {
+                match, replace := yamlnode.GetKey(item, "match"), yamlnode.GetKey(item, "replace")
+                switch {
+                case item.Kind == yaml.ScalarNode:
+                    rules = append(rules, item.Value)
+                case match != nil && match.Kind == yaml.ScalarNode && (replace == nil || replace.Kind == yaml.ScalarNode):
+                    rule := match.Value + "=>"
+                    if replace != nil { rule += replace.Value }
+                    rules = append(rules, rule)
+                default:
+                    problems = append(problems, fmt.Sprintf("%s:%d:%d: pathKey.rewrite entries need a match (and replace) string", path, item.Line, item.Column))
+                }
+            }
+            vals[opt.Flag] = strings.Join(rules, "\n")
+        case v.Kind != yaml.ScalarNode:
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: pathKey.%s must be a scalar, found %s", path, v.Line, v.Column, k.Value, yamlnode.KindName(v)))
+        default:
+            vals[opt.Flag] = v.Value
+        }
+    }
+    return problems
+}
+
+// loadGeneratorsSection reads the generators section into vals: a mapping of
+// openapi-generator names to output dirs, or a list of targets, each a
+// mapping of generator, output and args (a string or list), or a
+// "<generator>:<output> [args...]" string. Outputs are resolved against the
+// config file's directory.
+func loadGeneratorsSection(path string, section *yaml.Node, vals map[string]string) []string {
+    resolve := func(out string) string {
+        if out != "" && !filepath.IsAbs(out) { out = filepath.Join(filepath.Dir(path), filepath.FromSlash(out)) }
+        return out
+    }
+    var problems, gens []string
+    switch section.Kind {
+    case yaml.MappingNode:
+        for j := 0; j+1 < len(section.Content); j += 2 {
+            k, v := section.Content[j], section.Content[j+1]
+            if v.Kind != yaml.ScalarNode {
+                problems = append(problems, fmt.Sprintf("%s:%d:%d: generators.%s must be a scalar, found %s", path, v.Line, v.Column, k.Value, yamlnode.KindName(v)))
+                continue
+            }
+            gens = append(gens, quoteArg(k.Value+":"+resolve(v.Value)))
+        }
+    case yaml.SequenceNode:
+        for _, item := range section.Content {
+            if item.Kind == yaml.ScalarNode { // "<generator>:<output> [args...]", as for --gen
+                fields, err := splitArgs(item.Value)
+                var name, out string
+                ok := false
+                if err == nil && len(fields) > 0 { name, out, ok = strings.Cut(fields[0], ":") }
+                if !ok { // left for parseGenerator to report
+                    gens = append(gens, item.Value)
+                    continue
+                }
+                fields[0] = name + ":" + resolve(out)
+                for i := range fields { fields[i] = quoteArg(fields[i]) }
+                gens = append(gens, strings.Join(fields, " "))
+                continue
+            }
+            if item.Kind != yaml.MappingNode {
+                problems = append(problems, fmt.Sprintf("%s:%d:%d: generators entries must be mappings, found %s", path, item.Line, item.Column, yamlnode.KindName(item)))
+                continue
+            }
+            var gen, out, args string
+            for j := 0; j+1 < len(item.Content); j += 2 {
+                k, v := item.Content[j], item.Content[j+1]
+                switch {
+                case k.Value == "args" && v.Kind == yaml.SequenceNode:
+                    for _, a := range v.Content {
+                        if a.Kind != yaml.ScalarNode {
+                            problems = append(problems, fmt.Sprintf("%s:%d:%d: generators.args entries must be scalars, found %s", path, a.Line, a.Column, yamlnode.KindName(a)))
+                            continue
+                        }
+                        args += " " + quoteArg(a.Value)
+                    }
+                case k.Value != "generator" && k.Value != "output" && k.Value != "args":
+                    problems = append(problems, fmt.Sprintf("%s:%d:%d: unknown generators key %q (expected generator, output or args)", path, k.Line, k.Column, k.Value))
+                case v.Kind != yaml.ScalarNode:
+                    problems = append(problems, fmt.Sprintf("%s:%d:%d: generators.%s must be a scalar, found %s", path, v.Line, v.Column, k.Value, yamlnode.KindName(v)))
+                case k.Value == "generator":
+                    gen = v.Value
+                case k.Value == "output":
+                    out = resolve(v.Value)
+                default:
+                    args += " " + v.Value
+                }
+            }
+            if gen == "" || out == "" {
+                problems = append(problems, fmt.Sprintf("%s:%d:%d: generators entries need a generator and an output", path, item.Line, item.Column))
+                continue
+            }
+            gens = append(gens, quoteArg(gen+":"+out)+args)
+        }
+    default:
+        return []string{fmt.Sprintf("%s:%d:%d: generators must be a mapping of generator names to output dirs or a list of targets, found %s", path, section.Line, section.Column, yamlnode.KindName(section))}
+    }
+    vals["gen"] = strings.Join(gens, "\n")
+    return problems
+}
+
+// loadDepsSection reads the deps list into vals: each entry is a mapping of
+// git, rev, path and into, or a "git=<url>,rev=<rev>,..." string.
+func loadDepsSection(path string, section *yaml.Node, vals map[string]string) []string {
+    if section.Kind != yaml.SequenceNode {
+        return []string{fmt.Sprintf("%s:%d:%d: deps must be a list, found %s", path, section.Line, section.Column, yamlnode.KindName(section))}
+    }
+    var problems, deps []string
+    for _, item := range section.Content {
+        if item.Kind == yaml.ScalarNode {
+            deps = append(deps, item.Value)
+            continue
+        }
+        if item.Kind != yaml.MappingNode {
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: deps entries must be mappings, found %s", path, item.Line, item.Column, yamlnode.KindName(item)))
+            continue
+        }
+        var pairs []string
+        for j := 0; j+1 < len(item.Content); j += 2 {
+            k, v := item.Content[j], item.Content[j+1]
+            switch {
+            case k.Value != "git" && k.Value != "rev" && k.Value != "path" && k.Value != "into":
+                problems = append(problems, fmt.Sprintf("%s:%d:%d: unknown deps key %q (expected git, rev, path or into)", path, k.Line, k.Column, k.Value))
+            case v.Kind != yaml.ScalarNode:
+                problems = append(problems, fmt.Sprintf("%s:%d:%d: deps.%s must be a scalar, found %s", path, v.Line, v.Column, k.Value, yamlnode.KindName(v)))
+            default:
+                pairs = append(pairs, k.Value+"="+v.Value)
+            }
+        }
+        deps = append(deps, strings.Join(pairs, ","))
+    }
+    vals["dep"] = strings.Join(deps, "\n")
+    return problems
+}
+
+func lookupConfigOption(key string) (configOption, bool) {
+    for _, o := range configOptions {
+        if o.Key == key { return o, true }
+    }
+    return configOption{}, false
+}
+
+// applyConfigLayers fills every flag not given on the command line from its
+// environment variable, else from the config file (config < env < flags).
+func applyConfigLayers(fs *flag.FlagSet, fileVals map[string]string) error {
+    set := map[string]bool{}
+    fs.Visit(func(f *flag.Flag) {
+        set[f.Name] = true
+        if long, ok := flagShorthands[f.Name]; ok { set[long] = true }
+    })
+    for _, o := range configOptions {
+        if set[o.Flag] { continue }
+        if v, ok := os.LookupEnv(o.Env); ok && strings.TrimSpace(v) != "" {
+            if err := fs.Set(o.Flag, v); err != nil {
+                return fmt.Errorf("invalid %s=%q: %v", o.Env, v, err)
+            }
+            continue
+        }
+        if v, ok := fileVals[o.Flag]; ok {
+            if err := fs.Set(o.Flag, v); err != nil {
+                return fmt.Errorf("invalid config value %s: %q: %v", o.Key, v, err)
+            }
+        }
+    }
+    return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestGeneratorOutputsRelativeToConfigFile(t *testing.T) {
+    dir := filepath.Join(t.TempDir(), "api")
+    if err := os.MkdirAll(dir, 0o755); err != nil { t.Fatal(err) }
+    path := filepath.Join(dir, ".oas-indexer.yaml")
+    config := `generators:
+  - kotlin:clients/kotlin
+  - "python:clients/py --global-property models"
+  - generator: go
+    output: clients/go
+  - swift5:` + filepath.ToSlash(filepath.Join(dir, "abs")) + `
+`
+    if err := os.WriteFile(path, []byte(config), 0o644); err != nil { t.Fatal(err) }
+    vals, _, err := loadConfigFile(path, true)
+    if err != nil { t.Fatal(err) }
+    opts := strings.Split(vals["gen"], "\n")
+    want := []externalGenerator{
+        {Name: "kotlin", Out: filepath.Join(dir, "clients", "kotlin")},
+        {Name: "python", Out: filepath.Join(dir, "clients", "py"), Args: []string{"--global-property", "models"}},
+        {Name: "go", Out: filepath.Join(dir, "clients", "go")},
+        {Name: "swift5", Out: filepath.Join(dir, "abs")},
+    }
+    if len(opts) != len(want) { t.Fatalf("generators = %q, want %d entries", opts, len(want)) }
+    for i, s := range opts {
+        g, err := parseGenerator(s)
+        if err != nil { t.Fatalf("parseGenerator(%q): %v", s, err) }
+        if g.Name != want[i].Name || filepath.Clean(g.Out) != want[i].Out || len(g.Args) != len(want[i].Args) {
+            t.Errorf("generator %d = %+v, want %+v", i, g, want[i])
+            continue
+        }
+        for j := range g.Args {
+            if g.Args[j] != want[i].Args[j] { t.Errorf("generator %d args = %q, want %q", i, g.Args, want[i].Args) }
+        }
+    }
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// depStampFile marks a directory as a vendored dependency and records what
+// it was vendored from; a matching stamp means the dependency is up to date.
+const depStampFile = ".oas-dep" // YAML, but not a fragment file
+
+// dependency is a directory of shared fragments vendored from a git
+// repository into the input tree before indexing.
+type dependency struct {
+    Git  string `yaml:"git"`  // repository URL
+    Rev  string `yaml:"rev"`  // tag, branch or commit to check out
+    Path string `yaml:"path"` // directory in the repository, "" for its root
+    Into string `yaml:"into"` // destination below the input directory (default: Path)
+}
+
+// depStamp is the content of depStampFile.
+type depStamp struct {
+    dependency `yaml:",inline"`
+    Commit     string `yaml:"commit"`
+}
+
+// parseDependency parses "git=<url>,rev=<rev>[,path=<dir>][,into=<dir>]".
+func parseDependency(s string) (dependency, error) {
+    var d dependency
+    for _, part := range strings.Split(s, ",") {
+        k, v, ok := strings.Cut(part, "=")
+        v = strings.TrimSpace(v)
+        switch strings.TrimSpace(k) {
+        case "git":
+            d.Git = v
+        case "rev":
+            d.Rev = v
+        case "path":
+            d.Path = filepath.ToSlash(filepath.Clean(v))
+        case "into":
+            d.Into = filepath.ToSlash(filepath.Clean(v))
+        default:
+            ok = false
+        }
+        if !ok { return d, fmt.Errorf("invalid --dep entry %q (expected git=<url>,rev=<rev>[,path=<dir>][,into=<dir>])", part) }
+    }
+    if d.Path == "." { d.Path = "" }
+    if d.Into == "" || d.Into == "." { d.Into = d.Path }
+    switch {
+    case d.Git == "" || d.Rev == "":
+        return d, fmt.Errorf("invalid --dep %q: git and rev are required", s)
+    case strings.HasPrefix(d.Git, "-") || strings.HasPrefix(d.Rev, "-"):
+        return d, fmt.Errorf("invalid --dep %q: git and rev must not start with '-'", s)
+    case d.Into == "":
+        return d, fmt.Errorf("invalid --dep %q: into is required when path is the repository root", s)
+    case escapesDir(d.Path):
+        return d, fmt.Errorf("invalid --dep %q: path must be a directory inside the repository", s)
+    case escapesDir(d.Into):
+        return d, fmt.Errorf("invalid --dep %q: into must be a directory below the input directory", s)
+    }
+    return d, nil
+}
+
+// escapesDir reports whether the cleaned slash path p is absolute or leads
+// out of the directory it is relative to.
+func escapesDir(p string) bool {
+    return filepath.IsAbs(p) || strings.HasPrefix(p, "/") || p == ".." || strings.HasPrefix(p, "../")
+}
+
+// syncDeps vendors every dependency whose stamp does not match its
+// configuration into the input tree, replacing the previous copy.
+func syncDeps(cfg *Config) error {
+    for _, d := range cfg.Deps {
+        dest := filepath.Join(cfg.InputDir, filepath.FromSlash(d.Into))
+        var stamp depStamp
+        data, err := os.ReadFile(filepath.Join(dest, depStampFile))
+        switch {
+        case err == nil:
+            if yaml.Unmarshal(data, &stamp) == nil && stamp.dependency == d { continue }
+        case !os.IsNotExist(err):
+            return err
+        default:
+            if _, err := os.Stat(dest); err == nil {
+                return fmt.Errorf("dependency %s: refusing to replace %s, which has no %s (it was not vendored by oas-indexer)", d.Git, indexer.DisplayPath(cfg.index(), dest), depStampFile)
+            }
+        }
+        if cfg.Vendor.Offline {
+            return fmt.Errorf("dependency %s@%s is not vendored in %s and --offline forbids fetching it", d.Git, d.Rev, indexer.DisplayPath(cfg.index(), dest))
+        }
+        commit, err := vendorDependency(d, dest)
+        if err != nil { return fmt.Errorf("dependency %s@%s: %w", d.Git, d.Rev, err) }
+        logger.Info(fmt.Sprintf("Vendored %s@%s (%s)", d.Git, d.Rev, shortCommit(commit)), "path", indexer.DisplayPath(cfg.index(), dest))
+    }
+    return nil
+}
+
+// vendorDependency checks d out into a temporary clone and copies its
+// directory to dest, returning the commit it was taken from.
+func vendorDependency(d dependency, dest string) (string, error) {
+    tmp, err := os.MkdirTemp("", "oas-dep-*")
+    if err != nil { return "", err }
+    defer os.RemoveAll(tmp)
+    for _, args := range [][]string{
+        {"init", "--quiet"},
+        {"remote", "add", "--", "origin", d.Git},
+        {"fetch", "--quiet", "--depth", "1", "--", "origin", d.Rev},
+        {"checkout", "--quiet", "FETCH_HEAD"},
+    } {
+        if _, err := gitOutput(tmp, args...); err != nil { return "", err }
+    }
+    out, err := gitOutput(tmp, "rev-parse", "HEAD")
+    if err != nil { return "", err }
+    commit := strings.TrimSpace(string(out))
+
+    src := filepath.Join(tmp, filepath.FromSlash(d.Path))
+    if src != tmp && !indexer.IsWithin(tmp, src) { return "", fmt.Errorf("path %q is outside the repository", d.Path) }
+    if st, err := os.Stat(src); err != nil || !st.IsDir() {
+        return "", fmt.Errorf("no directory %q in the repository", d.Path)
+    }
+    if err := os.RemoveAll(dest); err != nil { return "", err }
+    if err := copyTree(src, dest); err != nil { return "", err }
+    stamp, err := yaml.Marshal(depStamp{d, commit})
+    if err != nil { return "", err }
+    return commit, os.WriteFile(filepath.Join(dest, depStampFile), stamp, 0o644)
+}
+
+// copyTree copies the regular files below src to dest, skipping .git.
+func copyTree(src, dest string) error {
+    return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+        if err != nil { return err }
+        if d.IsDir() && d.Name() == ".git" { return filepath.SkipDir }
+        rel, err := filepath.Rel(src, p)
+        if err != nil { return err }
+        target := filepath.Join(dest, rel)
+        if d.IsDir() { return os.MkdirAll(target, 0o755) }
+        if !d.Type().IsRegular() { return nil }
+        in, err := os.Open(p)
+        if err != nil { return err }
+        defer in.Close()
+        out, err := os.Create(target)
+        if err != nil { return err }
+        if _, err := io.Copy(out, in); err != nil {
+            out.Close()
+            return err
+        }
+        return out.Close()
+    })
+}
+
+func shortCommit(c string) string {
+    if len(c) > 12 { return c[:12] }
+    return c
+}
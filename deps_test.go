@@ -0,0 +1,36 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestParseDependencyPaths(t *testing.T) {
+    const repo = "git=https://example.com/specs.git,rev=v1.2.0"
+    tests := []struct {
+        spec, path, into, err string
+    }{
+        {repo + ",path=common", "common", "common", ""},
+        {repo + ",path=specs/common,into=shared", "specs/common", "shared", ""},
+        {repo + ",path=specs/../common", "common", "common", ""},
+        {repo + ",into=vendor/specs", "", "vendor/specs", ""},
+        {repo + ",path=../../..,into=shared", "", "", "path must be a directory inside the repository"},
+        {repo + ",path=specs/../../outside,into=shared", "", "", "path must be a directory inside the repository"},
+        {repo + ",path=/etc,into=shared", "", "", "path must be a directory inside the repository"},
+        {repo + ",path=..,into=shared", "", "", "path must be a directory inside the repository"},
+        {repo + ",path=common,into=../elsewhere", "", "", "into must be a directory below the input directory"},
+        {repo + ",path=common,into=/tmp/x", "", "", "into must be a directory below the input directory"},
+        {repo, "", "", "into is required"},
+        {"git=--upload-pack=touch /tmp/x,rev=v1,into=shared", "", "", "must not start with '-'"},
+        {"git=https://example.com/specs.git,rev=--output=/tmp/x,into=shared", "", "", "must not start with '-'"},
+    }
+    for _, tt := range tests {
+        d, err := parseDependency(tt.spec)
+        if tt.err != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.err) { t.Errorf("parseDependency(%q) error = %v, want %q", tt.spec, err, tt.err) }
+            continue
+        }
+        if err != nil { t.Errorf("parseDependency(%q): %v", tt.spec, err); continue }
+        if d.Path != tt.path || d.Into != tt.into { t.Errorf("parseDependency(%q) = path %q into %q, want %q, %q", tt.spec, d.Path, d.Into, tt.path, tt.into) }
+    }
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+    "os"
+    "os/exec"
+    "path/filepath"
+    "runtime"
+    "strconv"
+    "strings"
+)
+
+// With --use-docker, the external tools that are not installed run from
+// their official container images instead, with the directories they read
+// and write mounted at the same paths so that no argument needs rewriting.
+
+// dockerImages maps tool commands to the images standing in for them.
+var dockerImages = map[string]string{
+    "redocly":               "redocly/cli",
+    "openapi-generator":     "openapitools/openapi-generator-cli",
+    "openapi-generator-cli": "openapitools/openapi-generator-cli",
+}
+
+// useDocker is set by --use-docker.
+var useDocker bool
+
+// dockerImage returns the image to run bin from, or "" when bin runs as
+// installed: without --use-docker, for tools without an image, or when
+// docker itself is missing.
+func dockerImage(bin string) string {
+    if !useDocker { return "" }
+    img := dockerImages[bin]
+    if img == "" { return "" }
+    if _, err := exec.LookPath("docker"); err != nil { return "" }
+    return img
+}
+
+// dockerArgs returns the docker arguments running image with args: the
+// working directory and the directories of absolute path arguments are
+// mounted, and on Unix the tool runs as the current user so that its output
+// is not owned by root.
+func dockerArgs(image string, args []string) []string {
+    cwd, _ := os.Getwd()
+    dirs := []string{cwd}
+    for _, a := range args {
+        if i := strings.Index(a, "="); i >= 0 && strings.HasPrefix(a, "-") { a = a[i+1:] }
+        if filepath.IsAbs(a) { dirs = append(dirs, existingDir(a)) }
+    }
+    out := []string{"run", "--rm", "-w", cwd}
+    if runtime.GOOS != "windows" {
+        out = append(out, "--user", strconv.Itoa(os.Getuid())+":"+strconv.Itoa(os.Getgid()))
+    }
+    for i, d := range dirs {
+        skip := filepath.Dir(d) == d // never the file system root
+        for j, o := range dirs {
+            if j != i && (d == o && j < i || d != o && strings.HasPrefix(d, o+string(filepath.Separator))) { skip = true }
+        }
+        if !skip { out = append(out, "-v", d+":"+d) }
+    }
+    return append(append(out, image), args...)
+}
+
+// existingDir returns path when it is an existing directory, else its
+// nearest existing ancestor.
+func existingDir(path string) string {
+    for {
+        if st, err := os.Stat(path); err == nil && st.IsDir() { return path }
+        parent := filepath.Dir(path)
+        if parent == path { return path }
+        path = parent
+    }
+}
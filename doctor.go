@@ -0,0 +1,250 @@
+package main
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// The external tools the pipeline can run, their version pins (the tools
+// config section, or --tools) and the doctor command checking both.
+
+// externalTool describes one external tool.
+type externalTool struct {
+    Name    string
+    Bins    []string // commands, in order of preference
+    Version []string // arguments printing the version
+    Install string
+}
+
+var externalTools = []externalTool{
+    {Name: "redocly", Bins: []string{"redocly"}, Version: []string{"--version"}, Install: "npm i -g @redocly/cli"},
+    {Name: "openapi-generator", Bins: []string{"openapi-generator", "openapi-generator-cli"}, Version: []string{"version"}, Install: "brew install openapi-generator, or npm i -g @openapitools/openapi-generator-cli"},
+    {Name: "openapi-typescript", Bins: []string{"openapi-typescript"}, Version: []string{"--version"}, Install: "npm i -g openapi-typescript"},
+    {Name: "oapi-codegen", Bins: []string{"oapi-codegen"}, Version: []string{"-version"}, Install: "go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest"},
+    {Name: "git", Bins: []string{"git"}, Version: []string{"--version"}, Install: "https://git-scm.com/downloads"},
+    {Name: "docker", Bins: []string{"docker"}, Version: []string{"--version"}, Install: "https://docs.docker.com/get-docker/"},
+}
+
+func lookupTool(name string) (externalTool, bool) {
+    for _, t := range externalTools {
+        if t.Name == name { return t, true }
+    }
+    return externalTool{}, false
+}
+
+// parseToolPins parses "tool=constraint,...", checking the tool names and constraints.
+func parseToolPins(s string) (map[string]string, error) {
+    pins := map[string]string{}
+    for _, part := range strings.Split(s, ",") {
+        if strings.TrimSpace(part) == "" { continue }
+        name, constraint, ok := strings.Cut(part, "=")
+        name, constraint = strings.TrimSpace(name), strings.TrimSpace(constraint)
+        if !ok || name == "" || constraint == "" {
+            return nil, fmt.Errorf("invalid --tools entry %q (expected tool=constraint, e.g. redocly=>=1.25)", part)
+        }
+        if _, ok := lookupTool(name); !ok {
+            var names []string
+            for _, t := range externalTools { names = append(names, t.Name) }
+            return nil, fmt.Errorf("--tools: unknown tool %q (expected %s)", name, strings.Join(names, ", "))
+        }
+        if _, err := versionSatisfies("0", constraint); err != nil { return nil, fmt.Errorf("--tools %s: %w", name, err) }
+        pins[name] = constraint
+    }
+    return pins, nil
+}
+
+var reVersion = regexp.MustCompile(`\d+(?:\.\d+)*`)
+
+// toolVersion runs bin with the version arguments and returns the first
+// version number in its output.
+func toolVersion(bin string, args []string) (string, error) {
+    var out bytes.Buffer
+    if err := runExternal(bin, args, func(cmd *exec.Cmd) { cmd.Stdout, cmd.Stderr = &out, &out }); err != nil { return "", fmt.Errorf("%s: %w", commandLine(bin, args), err) }
+    v := reVersion.FindString(out.String())
+    if v == "" { return "", fmt.Errorf("%s: no version in output %q", commandLine(bin, args), strings.TrimSpace(out.String())) }
+    return v, nil
+}
+
+// versionSatisfies reports whether version meets constraint: blank-separated
+// comparisons that must all hold, each >=, >, <=, < or = a version, ^ a
+// version (same major), ~ a version (same minor) or a bare version, which
+// matches the components given (7.4 matches 7.4.1, 7.x any 7).
+func versionSatisfies(version, constraint string) (bool, error) {
+    v := versionParts(version)
+    for _, c := range strings.Fields(constraint) {
+        op := ""
+        for _, p := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+            if strings.HasPrefix(c, p) { op, c = p, c[len(p):]; break }
+        }
+        c = strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(c, "v"), ".x"), ".*")
+        if !reVersion.MatchString(c) || reVersion.FindString(c) != c {
+            return false, fmt.Errorf("invalid version constraint %q", op+c)
+        }
+        want := versionParts(c)
+        cmp := compareVersions(v, want)
+        var ok bool
+        switch op {
+        case ">=": ok = cmp >= 0
+        case ">": ok = cmp > 0
+        case "<=": ok = cmp <= 0
+        case "<": ok = cmp < 0
+        case "^": ok = cmp >= 0 && len(v) > 0 && v[0] == want[0]
+        case "~": ok = cmp >= 0 && compareVersions(prefix(v, 2), prefix(want, 2)) == 0
+        default: ok = compareVersions(prefix(v, len(want)), want) == 0
+        }
+        if !ok { return false, nil }
+    }
+    return true, nil
+}
+
+func versionParts(s string) []int {
+    var parts []int
+    for _, p := range strings.Split(s, ".") {
+        n, _ := strconv.Atoi(p)
+        parts = append(parts, n)
+    }
+    return parts
+}
+
+// compareVersions compares a and b, missing components counting as 0.
+func compareVersions(a, b []int) int {
+    for i := 0; i < max(len(a), len(b)); i++ {
+        var x, y int
+        if i < len(a) { x = a[i] }
+        if i < len(b) { y = b[i] }
+        if x != y {
+            if x < y { return -1 }
+            return 1
+        }
+    }
+    return 0
+}
+
+func prefix(v []int, n int) []int {
+    if len(v) > n { return v[:n] }
+    return v
+}
+
+// requiredTools returns the tools cfg cannot do without, with the option
+// needing each: the pinned ones and those the options ask for explicitly.
+// Tools with a built-in fallback are optional.
+func requiredTools(cfg *Config) map[string]string {
+    req := map[string]string{}
+    for name := range cfg.ToolPins { req[name] = "pinned" }
+    if cfg.Bundler == engineRedocly && cfg.BundleOut != "" { req["redocly"] = "--bundler redocly" }
+    if cfg.DocsRenderer == engineRedocly && cfg.Redocly != "" { req["redocly"] = "--docs-renderer redocly" }
+    if len(cfg.Generators) > 0 { req["openapi-generator"] = "--gen" }
+    if cfg.TSTemplateDir != "" { req["openapi-generator"] = "--ts-template-dir" }
+    if cfg.GoTemplateDir != "" { req["openapi-generator"] = "--go-template-dir" }
+    if cfg.oapiCodegenOptions() { req["oapi-codegen"] = "--go-generate/--oapi-codegen-config" }
+    if len(cfg.Deps) > 0 { req["git"] = "deps" }
+    if useDocker { req["docker"] = "--use-docker" }
+    return req
+}
+
+// toolStatus is the outcome of checking one tool.
+type toolStatus struct {
+    Tool    externalTool
+    Path    string // installed command, "" if missing
+    Version string
+    Status  string // ok, docker, missing, mismatch, unknown or optional
+    Detail  string
+}
+
+// checkTool checks whether t is installed and meets its pin.
+func checkTool(cfg *Config, t externalTool, required string) toolStatus {
+    st := toolStatus{Tool: t}
+    for _, b := range t.Bins {
+        if p, err := exec.LookPath(b); err == nil { st.Path = p; break }
+    }
+    if st.Path == "" && t.Name == "redocly" { st.Path = findRedocly(cfg.Cwd) }
+    pin := cfg.ToolPins[t.Name]
+    if st.Path == "" {
+        switch {
+        case dockerImage(t.Name) != "":
+            st.Status, st.Detail = "docker", "runs from the "+dockerImages[t.Name]+" image"
+            if pin != "" { st.Detail += "; pin " + pin + " not checked" }
+        case required != "":
+            st.Status, st.Detail = "missing", "needed by "+required+"; install: "+t.Install
+        default:
+            st.Status, st.Detail = "optional", "not installed; install: "+t.Install
+        }
+        return st
+    }
+    v, err := toolVersion(st.Path, t.Version)
+    if err != nil {
+        st.Status, st.Detail = "unknown", err.Error()
+        if pin == "" { st.Status, st.Detail = "ok", "" }
+        return st
+    }
+    st.Version, st.Status = v, "ok"
+    if pin != "" {
+        if ok, _ := versionSatisfies(v, pin); !ok {
+            st.Status, st.Detail = "mismatch", "want "+pin+"; install: "+t.Install
+        } else {
+            st.Detail = "pin " + pin
+        }
+    }
+    return st
+}
+
+// checkTools checks every known tool, in the order of externalTools.
+func checkTools(cfg *Config) []toolStatus {
+    req := requiredTools(cfg)
+    var out []toolStatus
+    for _, t := range externalTools { out = append(out, checkTool(cfg, t, req[t.Name])) }
+    return out
+}
+
+// toolProblems returns the statuses failing a check: missing required
+// tools, pin mismatches and pinned tools whose version cannot be read.
+func toolProblems(statuses []toolStatus) []toolStatus {
+    var bad []toolStatus
+    for _, st := range statuses {
+        if st.Status == "missing" || st.Status == "mismatch" || st.Status == "unknown" { bad = append(bad, st) }
+    }
+    return bad
+}
+
+func printToolStatuses(w io.Writer, statuses []toolStatus) {
+    for _, st := range statuses {
+        v := st.Version
+        if v == "" { v = "-" }
+        line := fmt.Sprintf("%-9s %-19s %-9s", st.Status, st.Tool.Name, v)
+        if st.Path != "" { line += " " + st.Path }
+        if st.Detail != "" { line += " (" + st.Detail + ")" }
+        fmt.Fprintln(w, strings.TrimRight(line, " "))
+    }
+}
+
+// checkToolPins fails, with --check-tools, when a required tool is missing or
+// a pinned one does not meet its pin.
+func checkToolPins(cfg *Config) error {
+    if !cfg.CheckTools { return nil }
+    bad := toolProblems(checkTools(cfg))
+    if len(bad) == 0 { return nil }
+    printToolStatuses(os.Stderr, bad)
+    return withExitCode(exitTool, fmt.Errorf("--check-tools: %d tool problem(s); run 'oas-indexer doctor' for details", len(bad)))
+}
+
+func runDoctorCommand(args []string) error {
+    fs, opts := commandFlags("doctor", "doctor [--tools tool=constraint,...] [options]")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    statuses := checkTools(cfg)
+    printToolStatuses(os.Stdout, statuses)
+    bad := toolProblems(statuses)
+    if len(bad) == 0 { return nil }
+    var names []string
+    for _, st := range bad { names = append(names, st.Tool.Name) }
+    sort.Strings(names)
+    return withExitCode(exitTool, errors.New("doctor: problems with "+strings.Join(names, ", ")))
+}
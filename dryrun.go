@@ -0,0 +1,96 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// --dry-run prints the actions a run would take instead of taking them: the
+// root, AsyncAPI document and bundle are built in memory and diffed against
+// the files on disk, so no file or directory is created; the other outputs
+// are only listed, as their generators may be external tools, which never
+// run.
+
+// dryRun previews the outputs of cfg, for every version with --per-version.
+func dryRun(cfg *Config) error {
+    fmt.Fprintln(os.Stdout, "Dry run: no output is written and no external tool runs")
+    for _, d := range cfg.Deps {
+        fmt.Fprintf(os.Stdout, "vendor     %s@%s into %s\n", d.Git, d.Rev, indexer.DisplayPath(cfg.index(), d.Into))
+    }
+    if cfg.UpdateBaseline { planned("overwrite", cfg.Baseline, "validation baseline") }
+    if cfg.ValidateReport != "" { planned(action(cfg.ValidateReport), cfg.ValidateReport, "validation report") }
+    return forEachVersion(cfg, dryRunOutputs)
+}
+
+// dryRunAsyncAPI previews the AsyncAPI document of the input tree's events.
+func dryRunAsyncAPI(cfg *Config) error {
+    ok, err := indexer.HasEvents(cfg.index())
+    if err != nil || !ok { return err }
+    data, err := indexer.RenderAsyncAPI(cfg.index())
+    if err != nil { return fmt.Errorf("building AsyncAPI document: %w", err) }
+    return previewFile(cfg.AsyncAPIPath, data)
+}
+
+// dryRunOutputs previews the root and bundle of cfg and lists its other outputs.
+func dryRunOutputs(cfg *Config) error {
+    data, err := indexer.RenderRoot(cfg.index())
+    if err != nil { return err }
+    if err := previewFile(cfg.RootPath, data); err != nil { return err }
+
+    if cfg.BundleOut != "" {
+        if cfg.Bundler != engineNative && findRedocly(cfg.Cwd) != "" {
+            fmt.Fprintln(os.Stdout, "note: the bundle preview uses the built-in bundler; Redocly CLI may format it differently")
+        }
+        root, err := bundle.LoadData(cfg.RootPath, data)
+        if err != nil { return fmt.Errorf("bundle: %w", err) }
+        if err := postprocessBundle(cfg, root); err != nil { return fmt.Errorf("bundle: %w", err) }
+        marshal := yamlnode.Marshal
+        if strings.EqualFold(filepath.Ext(cfg.BundleOut), ".json") { marshal = yamlnode.MarshalJSON }
+        data, err := marshal(root)
+        if err != nil { return fmt.Errorf("bundle: %w", err) }
+        if err := previewFile(cfg.BundleOut, data); err != nil { return err }
+    }
+    if cfg.OutputTS != "" { planned("write", cfg.OutputTS, "TypeScript") }
+    if cfg.OutputTS != "" && cfg.TSRuntime != "" { planned("write", cfg.OutputTS, cfg.TSRuntime+" schemas") }
+    if cfg.OutputGo != "" { planned("write", cfg.OutputGo, "Go") }
+    for _, g := range cfg.Generators { planned("write", g.Out, "openapi-generator "+g.Name) }
+    if cfg.Redocly != "" { planned(action(cfg.Redocly), cfg.Redocly, "docs") }
+    return nil
+}
+
+// previewFile prints whether writing data to path would create, overwrite
+// or keep it, followed by the diff of an overwrite.
+func previewFile(path string, data []byte) error {
+    have, err := os.ReadFile(path)
+    switch {
+    case os.IsNotExist(err):
+        planned("create", path, "")
+        return nil
+    case err != nil:
+        return err
+    case string(have) == string(data):
+        planned("unchanged", path, "")
+        return nil
+    }
+    planned("overwrite", path, "")
+    name := filepath.Base(path)
+    printLineDiff(os.Stdout, name+" (on disk)", name+" (planned)", string(have), string(data))
+    return nil
+}
+
+// action is "overwrite" for an existing path, else "create".
+func action(path string) string {
+    if _, err := os.Stat(path); err == nil { return "overwrite" }
+    return "create"
+}
+
+func planned(action, path, what string) {
+    if what != "" { what = " (" + what + ")" }
+    fmt.Fprintf(os.Stdout, "%-10s %s%s\n", action, path, what)
+}
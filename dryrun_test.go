@@ -0,0 +1,59 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// treeFiles lists every file and directory below dir.
+func treeFiles(t *testing.T, dir string) map[string]bool {
+    files := map[string]bool{}
+    err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+        if err != nil { return err }
+        files[path] = true
+        return nil
+    })
+    if err != nil { t.Fatal(err) }
+    return files
+}
+
+func TestDryRunTouchesNoFile(t *testing.T) {
+    dir := t.TempDir()
+    input := filepath.Join(dir, "api")
+    writeTestFile(t, filepath.Join(input, "header.yaml"), exitTestHeader)
+    writeTestFile(t, filepath.Join(input, "paths", "v1", "users.yaml"), `get:
+  operationId: listUsers
+  responses:
+    '200':
+      description: OK
+      content:
+        application/json:
+          schema:
+            $ref: ../../components/schemas/User.yaml
+`)
+    writeTestFile(t, filepath.Join(input, "components", "schemas", "User.yaml"), "type: object\n")
+    writeTestFile(t, filepath.Join(input, "events", "channels", "user.yaml"), "subscribe:\n  message:\n    payload:\n      type: string\n")
+    before := treeFiles(t, dir)
+
+    output := filepath.Join(dir, "dr", "x")
+    for _, args := range [][]string{
+        {"--input", input, "--output", output, "--all", "--dry-run"},
+        {"--input", input, "--output", output, "--bundle", filepath.Join(dir, "dist", "openapi.json"), "--per-version", "--dry-run"},
+        {"--input", input, "--join", "--dry-run"},
+    } {
+        fs, opts := commandFlags("dry-run", "")
+        cfg, err := commandConfig(fs, opts, args)
+        if err != nil { t.Fatal(err) }
+        if err := run(cfg); err != nil { t.Fatalf("%v: %v", args, err) }
+    }
+    if err := commands["build"]([]string{"--input", input, "--output", output, "--dry-run"}); err != nil { t.Fatal(err) }
+
+    after := treeFiles(t, dir)
+    for f := range after {
+        if !before[f] { t.Errorf("dry run created %s", f) }
+    }
+    for f := range before {
+        if !after[f] { t.Errorf("dry run removed %s", f) }
+    }
+}
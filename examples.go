@@ -0,0 +1,114 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/examples"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// Example generation: list or fill the media types and schemas without
+// examples.
+
+// exampleEdit is a generated example for the node at pointer in a fragment.
+type exampleEdit struct {
+    pointer string
+    value   *yaml.Node
+}
+
+func runExamplesCommand(args []string) error {
+    fs, opts := commandFlags("examples", "examples --input <dir> [--fill [--bundle-only] [--out <yaml>]] [options]")
+    fill := fs.Bool("fill", false, "Generate the missing examples and write them into the fragments")
+    bundleOnly := fs.Bool("bundle-only", false, "With --fill, leave the fragments alone and add the examples to the bundle only")
+    out := fs.String("out", "", "Bundle path for --bundle-only (default: --bundle, else dist/openapi.yaml or .json per --format)")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    if *bundleOnly && !*fill { return errors.New("examples: --bundle-only requires --fill") }
+    if strings.TrimSpace(*out) != "" && !*bundleOnly { return errors.New("examples: --out requires --bundle-only") }
+
+    if *bundleOnly {
+        target := firstNonEmpty(strings.TrimSpace(*out), cfg.BundleOut, defaultBundlePath(cfg))
+        onlyRoot(cfg)
+        cfg.BundleOut = absJoin(cfg.Cwd, target)
+        cfg.SkipValidation = true
+        cfg.FillExamples = true
+        if err := checkInput(cfg); err != nil { return err }
+        return forEachVersion(cfg, func(cfg *Config) error {
+            if err := writeRoot(cfg); err != nil { return err }
+            return bundleSpec(cfg)
+        })
+    }
+
+    cfg.SkipValidation = true
+    if err := checkInput(cfg); err != nil { return err }
+    fragments, root, err := indexer.JoinedFragments(cfg.index())
+    if err != nil { return err }
+    gen := &examples.Generator{Root: root}
+    edits := map[string][]exampleEdit{}
+    var files []string
+    missing := 0
+    for _, f := range fragments {
+        for _, gap := range examples.Gaps(f.Node, f.Schema) {
+            ptr := f.Pointer + gap.Pointer
+            missing++
+            if !*fill {
+                fmt.Fprintf(os.Stdout, "%s %s: no example\n", f.File, firstNonEmpty(ptr, "/"))
+                continue
+            }
+            if edits[f.Path] == nil { files = append(files, f.Path) }
+            edits[f.Path] = append(edits[f.Path], exampleEdit{ptr, gen.Generate(gap.Schema, gap.Request)})
+        }
+    }
+    if !*fill {
+        if missing == 0 {
+            fmt.Fprintln(os.Stdout, "Every media type and schema has an example")
+            return nil
+        }
+        fmt.Fprintf(os.Stdout, "\n%d media type(s) and schema(s) without examples; run with --fill to generate them\n", missing)
+        return nil
+    }
+    filled := 0
+    for _, file := range files {
+        n, err := writeExamples(cfg, file, edits[file])
+        if err != nil { return err }
+        filled += n
+    }
+    fmt.Fprintf(os.Stdout, "Filled %d example(s) in %d fragment(s)\n", filled, len(files))
+    return nil
+}
+
+// writeExamples sets the example of each edit's node in the fragment file
+// and rewrites it, returning how many were set. Nodes the fragment does not
+// have as written (such as ones coming from merge keys) are skipped.
+func writeExamples(cfg *Config, file string, edits []exampleEdit) (int, error) {
+    name := indexer.DisplayPath(cfg.index(), file)
+    raw, err := indexer.ReadFragment(cfg.index(), file)
+    if err != nil { return 0, err }
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(raw), &doc); err != nil { return 0, fmt.Errorf("%s: %v", name, err) }
+    n := 0
+    for _, e := range edits {
+        target, err := bundle.Lookup(yamlnode.DocRoot(&doc), e.pointer)
+        if err != nil || target.Kind != yaml.MappingNode {
+            logger.Warn(fmt.Sprintf("%s %s: not found as written (merge key?); example not added", name, e.pointer))
+            continue
+        }
+        yamlnode.SetKey(target, "example", e.value)
+        n++
+    }
+    if n == 0 { return 0, nil }
+    marshal := yamlnode.Marshal
+    if indexer.FormatForFile(file) == indexer.FormatJSON { marshal = yamlnode.MarshalJSON }
+    data, err := marshal(&doc)
+    if err != nil { return 0, fmt.Errorf("%s: %v", name, err) }
+    if _, err := atomicfile.WriteFile(file, data); err != nil { return 0, err }
+    return n, nil
+}
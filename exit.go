@@ -0,0 +1,46 @@
+package main
+
+import (
+    "errors"
+    "io/fs"
+    "os"
+)
+
+// Exit statuses, by class of failure, so that scripts can tell an invalid
+// spec from a missing tool without reading stderr.
+const (
+    exitFailure     = 1   // anything not classified below
+    exitConfig      = 2   // invalid flags, environment variables or config file
+    exitValidation  = 3   // fragment problems, validation findings or breaking changes
+    exitTool        = 4   // an external tool is missing, off its pin or failed
+    exitIO          = 5   // reading or writing a file failed
+    exitInterrupted = 130 // SIGINT or SIGTERM
+)
+
+// exitError gives err the exit status code.
+type exitError struct {
+    code int
+    err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode returns err with the exit status code, or nil for a nil err.
+func withExitCode(code int, err error) error {
+    if err == nil { return nil }
+    return &exitError{code: code, err: err}
+}
+
+// exitStatus returns the exit status of a run failing with err: its class
+// when one was given, exitIO for a file system error, exitInterrupted after
+// an interrupt, else exitFailure.
+func exitStatus(err error) int {
+    if commandsContext.Err() != nil { return exitInterrupted }
+    var ee *exitError
+    if errors.As(err, &ee) { return ee.code }
+    var pe *fs.PathError
+    var le *os.LinkError
+    if errors.As(err, &pe) || errors.As(err, &le) { return exitIO }
+    return exitFailure
+}
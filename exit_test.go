@@ -0,0 +1,82 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+const exitTestHeader = `openapi: 3.0.3
+info:
+  title: Exit codes
+  version: 1.0.0
+`
+
+// writeExitTestTree writes an input tree with the given path fragment as
+// paths/v1/users.yaml.
+func writeExitTestTree(t *testing.T, users string) string {
+    dir := t.TempDir()
+    writeTestFile(t, filepath.Join(dir, "header.yaml"), exitTestHeader)
+    writeTestFile(t, filepath.Join(dir, "paths", "v1", "users.yaml"), users)
+    return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(path, []byte(content), 0o644); err != nil { t.Fatal(err) }
+}
+
+const validUsers = `get:
+  operationId: listUsers
+  responses:
+    '200':
+      description: OK
+`
+
+func TestExitStatus(t *testing.T) {
+    valid := writeExitTestTree(t, validUsers)
+    // An operation without responses violates the OpenAPI 3.0 schema.
+    invalid := writeExitTestTree(t, "get:\n  operationId: listUsers\n")
+    oldSpec := filepath.Join(t.TempDir(), "old.yaml")
+    writeTestFile(t, oldSpec, exitTestHeader+`paths:
+  /v1/users:
+    get:
+      operationId: listUsers
+      responses:
+        '200':
+          description: OK
+  /v1/orders:
+    get:
+      operationId: listOrders
+      responses:
+        '200':
+          description: OK
+`)
+
+    tests := []struct {
+        name string
+        args []string
+        want int
+    }{
+        {"unknown preset", []string{"validate", "--input", valid, "--preset", "nosuchpreset"}, exitConfig},
+        {"unknown --validate preset", []string{"validate", "--input", valid, "--validate", "nosuchpreset"}, exitConfig},
+        {"no preset", []string{"validate", "--input", valid}, exitConfig},
+        {"nothing to generate", []string{"gen", "--input", valid}, exitConfig},
+        {"invalid --fail-on", []string{"diff", "--input", valid, "--old", oldSpec, "--fail-on", "sometimes"}, exitConfig},
+        {"structural violation", []string{"validate", "--input", invalid, "--structural"}, exitValidation},
+        {"breaking change", []string{"diff", "--input", valid, "--old", oldSpec}, exitValidation},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := commands[tt.args[0]](tt.args[1:])
+            if err == nil { t.Fatalf("%v: no error, want exit status %d", tt.args, tt.want) }
+            if got := exitStatus(err); got != tt.want {
+                t.Errorf("%v: exit status %d (%v), want %d", tt.args, got, err, tt.want)
+            }
+        })
+    }
+
+    if err := commands["validate"]([]string{"--input", valid, "--structural"}); err != nil {
+        t.Errorf("structural check of a valid tree: %v", err)
+    }
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bruno"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/graphql"
+    "github.com/bilbo290/oas-indexer/pkg/insomnia"
+    "github.com/bilbo290/oas-indexer/pkg/jsonschema"
+    "github.com/bilbo290/oas-indexer/pkg/postman"
+    "github.com/bilbo290/oas-indexer/pkg/proto"
+)
+
+// Export to API clients: the joined spec as a Postman, Bruno or Insomnia
+// collection. pkg/postman maps the spec to requests; the other formats are
+// converted from its collection. export jsonschema, graphql and proto write
+// the component schemas (and, for GraphQL, the operations) in those
+// languages instead.
+
+func runExport(args []string) error {
+    if len(args) == 0 {
+        return errors.New("usage: oas-indexer export postman|bruno|insomnia|jsonschema|graphql|proto --input <dir> -o <path>")
+    }
+    switch args[0] {
+    case "postman":
+        return runExportPostman(args[1:])
+    case "bruno":
+        return runExportBruno(args[1:])
+    case "insomnia":
+        return runExportInsomnia(args[1:])
+    case "jsonschema":
+        return runExportJSONSchema(args[1:])
+    case "graphql":
+        return runExportGraphQL(args[1:])
+    case "proto":
+        return runExportProto(args[1:])
+    default:
+        return fmt.Errorf("unknown export target %q (supported: postman, bruno, insomnia, jsonschema, graphql, proto)", args[0])
+    }
+}
+
+func runExportBruno(args []string) error {
+    out, args := takeOutFlag(args)
+    fs, opts := commandFlags("export bruno", "export bruno --input <dir> -o <collection dir> [--server <n>] [--name <name>] [options]")
+    server, name := exportFlags(fs)
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := absJoin(cfg.Cwd, firstNonEmpty(out, filepath.Join("dist", cfg.API, "bruno")))
+    collection, env, err := exportCollection(cfg, *server, *name)
+    if err != nil { return err }
+    changed, err := bruno.Write(target, collection, env)
+    if err != nil { return err }
+    if changed > 0 {
+        fmt.Fprintf(os.Stdout, "Wrote Bruno collection: %s (%d file(s) changed)\n", target, changed)
+    } else {
+        fmt.Fprintf(os.Stdout, "Bruno collection unchanged: %s\n", target)
+    }
+    return nil
+}
+
+func runExportInsomnia(args []string) error {
+    out, args := takeOutFlag(args)
+    fs, opts := commandFlags("export insomnia", "export insomnia --input <dir> -o <export.json> [--server <n>] [--name <name>] [options]")
+    server, name := exportFlags(fs)
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := absJoin(cfg.Cwd, firstNonEmpty(out, filepath.Join("dist", cfg.API, "insomnia.json")))
+    collection, env, err := exportCollection(cfg, *server, *name)
+    if err != nil { return err }
+    return writeJSONFile(target, insomnia.Convert(collection, env), "Insomnia export")
+}
+
+func runExportJSONSchema(args []string) error {
+    out, args := takeOutFlag(args)
+    fs, opts := commandFlags("export jsonschema", "export jsonschema --input <dir> -o <dir> [--base-url <url>] [options]")
+    baseURL := fs.String("base-url", "", "URL the documents are published under; each gets $id <base-url>/<Name>.json")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := absJoin(cfg.Cwd, firstNonEmpty(out, filepath.Join("dist", cfg.API, "schemas")))
+    spec, err := exportSpec(cfg)
+    if err != nil { return err }
+    docs, err := jsonschema.Export(spec, jsonschema.Options{BaseURL: strings.TrimSpace(*baseURL)})
+    if err != nil { return fmt.Errorf("export: %w", err) }
+
+    if err := ensureDir(target); err != nil { return err }
+    written := map[string]bool{}
+    changed := 0
+    for _, d := range docs {
+        data, err := yamlnode.MarshalJSON(d.Schema)
+        if err != nil { return fmt.Errorf("export: %s: %w", d.Component, err) }
+        ok, err := atomicfile.WriteFile(filepath.Join(target, d.File), data)
+        if err != nil { return err }
+        if ok { changed++ }
+        written[d.File] = true
+    }
+    // Documents of removed components go too; other JSON files are kept.
+    entries, err := os.ReadDir(target)
+    if err != nil { return err }
+    for _, e := range entries {
+        if e.IsDir() || filepath.Ext(e.Name()) != ".json" || written[e.Name()] { continue }
+        data, err := os.ReadFile(filepath.Join(target, e.Name()))
+        if err != nil || !bytes.Contains(data, []byte(`"$schema": "`+jsonschema.Draft+`"`)) { continue }
+        if err := os.Remove(filepath.Join(target, e.Name())); err != nil { return err }
+        changed++
+    }
+    if changed > 0 {
+        fmt.Fprintf(os.Stdout, "Wrote JSON Schemas: %s (%d file(s) changed)\n", target, changed)
+    } else {
+        fmt.Fprintf(os.Stdout, "JSON Schemas unchanged: %s\n", target)
+    }
+    return nil
+}
+
+func runExportGraphQL(args []string) error {
+    out, args := takeOutFlag(args)
+    fs, opts := commandFlags("export graphql", "export graphql --input <dir> -o <schema.graphql> [options]")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := absJoin(cfg.Cwd, firstNonEmpty(out, filepath.Join("dist", cfg.API, "schema.graphql")))
+    spec, err := exportSpec(cfg)
+    if err != nil { return err }
+    data, err := graphql.Generate(spec)
+    if err != nil { return fmt.Errorf("export: %w", err) }
+    if err := ensureDir(filepath.Dir(target)); err != nil { return err }
+    changed, err := atomicfile.WriteFile(target, data)
+    if err != nil { return err }
+    if changed {
+        fmt.Fprintf(os.Stdout, "Wrote GraphQL schema: %s\n", target)
+    } else {
+        fmt.Fprintf(os.Stdout, "GraphQL schema unchanged: %s\n", target)
+    }
+    return nil
+}
+
+func runExportProto(args []string) error {
+    out, args := takeOutFlag(args)
+    fs, opts := commandFlags("export proto", "export proto --input <dir> -o <api.proto> [--package <name>] [--go-package <path>] [options]")
+    pkg := fs.String("package", "", "proto package (default: from info.title)")
+    goPkg := fs.String("go-package", "", "If set, option go_package of the file")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := absJoin(cfg.Cwd, firstNonEmpty(out, filepath.Join("dist", cfg.API, "api.proto")))
+    spec, err := exportSpec(cfg)
+    if err != nil { return err }
+    res, err := proto.Generate(spec, proto.Options{Package: strings.TrimSpace(*pkg), GoPackage: strings.TrimSpace(*goPkg)})
+    if err != nil { return fmt.Errorf("export: %w", err) }
+    if n := len(res.Unpinned); n > 0 {
+        logger.Warn(fmt.Sprintf("%d field(s) have no x-proto-field (first: %s); their numbers change when properties are added before them", n, res.Unpinned[0]))
+    }
+    if err := ensureDir(filepath.Dir(target)); err != nil { return err }
+    changed, err := atomicfile.WriteFile(target, res.Data)
+    if err != nil { return err }
+    if changed {
+        fmt.Fprintf(os.Stdout, "Wrote proto file: %s\n", target)
+    } else {
+        fmt.Fprintf(os.Stdout, "Proto file unchanged: %s\n", target)
+    }
+    return nil
+}
+
+// takeOutFlag removes -o/--out from args: it names the export here, not the
+// root's output directory.
+func takeOutFlag(args []string) (string, []string) {
+    out, args := takeFlag(args, "o")
+    if o, rest := takeFlag(args, "out"); o != "" {
+        out, args = o, rest
+    }
+    return strings.TrimSpace(out), args
+}
+
+// exportFlags registers the flags every export target accepts.
+func exportFlags(fs *flag.FlagSet) (*int, *string) {
+    server := fs.Int("server", 0, "Index of the server the environment's baseUrl points at")
+    name := fs.String("name", "", "Collection and environment name (default: info.title)")
+    return server, name
+}
+
+// exportSpec writes the root and bundles it in memory.
+func exportSpec(cfg *Config) (*yaml.Node, error) {
+    onlyRoot(cfg)
+    cfg.SkipValidation = true
+    if err := checkInput(cfg); err != nil { return nil, err }
+    if err := writeRoot(cfg); err != nil { return nil, err }
+    spec, err := bundle.Load(cfg.RootPath)
+    if err != nil { return nil, fmt.Errorf("export: %w", err) }
+    if err := postprocessBundle(cfg, spec); err != nil { return nil, fmt.Errorf("export: %w", err) }
+    return spec, nil
+}
+
+// exportCollection converts the bundled spec into a Postman collection and
+// environment.
+func exportCollection(cfg *Config, server int, name string) (*postman.Collection, *postman.Environment, error) {
+    spec, err := exportSpec(cfg)
+    if err != nil { return nil, nil, err }
+    collection, env, err := postman.Export(spec, postman.Options{Name: name, Server: server})
+    if err != nil { return nil, nil, fmt.Errorf("export: %w", err) }
+    return collection, env, nil
+}
+
+// writeJSONFile writes v as indented JSON to path, reporting it as what.
+func writeJSONFile(path string, v interface{}, what string) error {
+    var buf bytes.Buffer
+    enc := json.NewEncoder(&buf)
+    enc.SetEscapeHTML(false)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(v); err != nil { return err }
+    if err := ensureDir(filepath.Dir(path)); err != nil { return err }
+    changed, err := atomicfile.WriteFile(path, buf.Bytes())
+    if err != nil { return err }
+    if changed {
+        fmt.Fprintf(os.Stdout, "Wrote %s: %s\n", what, path)
+    } else {
+        fmt.Fprintf(os.Stdout, "%s unchanged: %s\n", strings.ToUpper(what[:1])+what[1:], path)
+    }
+    return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Any openapi-generator target besides the TypeScript and Go flows: each
+// --gen kotlin:clients/kotlin runs "openapi-generator generate -g kotlin"
+// on the root, with --gen-args and the target's own arguments appended. The
+// targets run concurrently with each other and with the TypeScript and Go
+// outputs.
+
+// externalGenerator is one --gen entry.
+type externalGenerator struct {
+    Name string   // openapi-generator generator name, e.g. kotlin
+    Out  string   // output directory
+    Args []string // extra arguments for this target, after --gen-args
+}
+
+// parseGenerator parses "<name>:<out> [args...]", split like a shell command line.
+func parseGenerator(s string) (externalGenerator, error) {
+    fields, err := splitArgs(s)
+    if err != nil { return externalGenerator{}, fmt.Errorf("invalid --gen %q: %w", s, err) }
+    if len(fields) == 0 { fields = []string{""} }
+    name, out, ok := strings.Cut(fields[0], ":")
+    g := externalGenerator{Name: name, Out: out, Args: fields[1:]}
+    if !ok || g.Name == "" || g.Out == "" || strings.ContainsAny(g.Name, "/\\") {
+        return g, fmt.Errorf("invalid --gen %q (expected <generator>:<output dir> [args...], e.g. kotlin:clients/kotlin)", s)
+    }
+    return g, nil
+}
+
+// quoteArg quotes s for splitArgs when it is empty or holds blanks, quotes or backslashes.
+func quoteArg(s string) string {
+    if s != "" && !strings.ContainsAny(s, " \t\n'\"\\") { return s }
+    return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// splitArgs splits a command line into arguments the way a POSIX shell
+// would, honoring single and double quotes and backslash escapes, without
+// expanding anything.
+func splitArgs(s string) ([]string, error) {
+    var args []string
+    var cur strings.Builder
+    inArg := false
+    var quote rune
+    escaped := false
+    for _, r := range s {
+        switch {
+        case escaped:
+            cur.WriteRune(r)
+            escaped = false
+        case r == '\\' && quote != '\'':
+            escaped, inArg = true, true
+        case quote != 0:
+            if r == quote {
+                quote = 0
+            } else {
+                cur.WriteRune(r)
+            }
+        case r == '\'' || r == '"':
+            quote, inArg = r, true
+        case r == ' ' || r == '\t' || r == '\n':
+            if inArg {
+                args = append(args, cur.String())
+                cur.Reset()
+                inArg = false
+            }
+        default:
+            cur.WriteRune(r)
+            inArg = true
+        }
+    }
+    if quote != 0 || escaped { return nil, fmt.Errorf("unterminated quote or escape in %q", s) }
+    if inArg { args = append(args, cur.String()) }
+    return args, nil
+}
+
+// generatorArgs returns the openapi-generator arguments running generator gen
+// on the root into out, with the templates in templateDir (if any) and the
+// --additional-properties.
+func (cfg *Config) generatorArgs(gen, out, templateDir string) []string {
+    args := []string{"generate", "-g", gen, "-i", cfg.RootPath, "-o", out}
+    if templateDir != "" { args = append(args, "-t", templateDir) }
+    if cfg.AdditionalProperties != "" { args = append(args, "--additional-properties="+cfg.AdditionalProperties) }
+    return args
+}
+
+// goGenerateTargets are the oapi-codegen -generate targets; std-http is
+// short for std-http-server.
+var goGenerateTargets = []string{"types", "client", "server", "chi-server", "echo-server", "gin-server", "gorilla-server", "fiber-server", "iris-server", "std-http-server", "strict-server", "spec", "embedded-spec", "skip-prune", "skip-fmt"}
+
+// parseGoGenerate parses and checks a comma-separated --go-generate list
+// (one target per line when set from a config file list).
+func parseGoGenerate(s string) (string, error) {
+    var targets []string
+    for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+        t = strings.ToLower(strings.TrimSpace(t))
+        if t == "" { continue }
+        if t == "std-http" { t = "std-http-server" }
+        if !slices.Contains(goGenerateTargets, t) {
+            return "", fmt.Errorf("invalid --go-generate target %q (expected %s)", t, strings.Join(goGenerateTargets, ", "))
+        }
+        targets = append(targets, t)
+    }
+    return strings.Join(targets, ","), nil
+}
+
+// oapiCodegenOptions reports whether the Go output is configured for oapi-codegen.
+func (cfg *Config) oapiCodegenOptions() bool { return cfg.OapiCodegenConfig != "" || cfg.GoGenerate != "" }
+
+// oapiCodegenArgs returns the oapi-codegen arguments writing the root's Go
+// code to out: the config file's settings, or else package pkg and the
+// --go-generate targets.
+func (cfg *Config) oapiCodegenArgs(out, pkg string) []string {
+    if cfg.OapiCodegenConfig != "" { return []string{"-config", cfg.OapiCodegenConfig, "-o", out, cfg.RootPath} }
+    return []string{"-generate", firstNonEmpty(cfg.GoGenerate, "types,client,server"), "-o", out, "-package", pkg, cfg.RootPath}
+}
+
+// openapiGeneratorBin returns the installed openapi-generator command, in
+// the order the TypeScript and Go flows try them.
+func openapiGeneratorBin() string {
+    for _, bin := range []string{"openapi", "openapi-generator", "openapi-generator-cli"} {
+        if which(bin) != "" { return bin }
+    }
+    return ""
+}
+
+// codegenTarget is one output of the generation step: the TypeScript or Go
+// output, or a --gen generator.
+type codegenTarget struct {
+    what string // as reported, e.g. TypeScript or --gen kotlin
+    out  string
+    run  func() error
+}
+
+// generateCode runs every generation target on the root concurrently: the
+// TypeScript and Go outputs and the --gen generators, reporting each one as
+// it finishes. It fails with all the targets' errors once they are done.
+func generateCode(cfg *Config) error {
+    var targets []codegenTarget
+    if cfg.OutputTS != "" { targets = append(targets, codegenTarget{"TypeScript", cfg.OutputTS, func() error { return generateTypeScript(cfg) }}) }
+    if cfg.OutputGo != "" { targets = append(targets, codegenTarget{"Go", cfg.OutputGo, func() error { return generateGo(cfg) }}) }
+    if len(cfg.Generators) > 0 {
+        if err := checkSpecInput(cfg.RootPath); err != nil { return err }
+        bin := openapiGeneratorBin()
+        if bin == "" {
+            return withExitCode(exitTool, fmt.Errorf("--gen needs openapi-generator. Install one of:\n - brew install openapi-generator\n - npm i -g @openapitools/openapi-generator-cli\nor use --use-docker"))
+        }
+        for _, g := range cfg.Generators {
+            targets = append(targets, codegenTarget{"--gen " + g.Name, g.Out, func() error { return runGenerator(cfg, bin, g) }})
+        }
+    }
+
+    errs := make([]error, len(targets))
+    var wg sync.WaitGroup
+    for i, t := range targets {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            start := time.Now()
+            if err := t.run(); err != nil {
+                errs[i] = fmt.Errorf("%s (%s): %w", t.what, t.out, err)
+                logger.Error("Failed "+t.what, "path", t.out, "duration", time.Since(start).Round(100*time.Millisecond))
+            }
+        }()
+    }
+    wg.Wait()
+    var failed []error
+    code := 0
+    for _, err := range errs {
+        if err == nil { continue }
+        failed = append(failed, err)
+        if c := exitStatus(err); code == 0 || code == c { code = c } else { code = exitFailure }
+    }
+    if len(failed) == 0 { return nil }
+    return withExitCode(code, fmt.Errorf("%d of %d generation targets failed:\n%w", len(failed), len(targets), errors.Join(failed...)))
+}
+
+// runGenerator runs the --gen generator g with the openapi-generator command bin.
+func runGenerator(cfg *Config, bin string, g externalGenerator) error {
+    start := time.Now()
+    args := append(append([]string{"generate", "-g", g.Name, "-i", cfg.RootPath, "-o", g.Out}, cfg.GenArgs...), g.Args...)
+    return cachedStep(cfg, g.Name, g.Out, false, append([]string{toolStamp(bin)}, args...), nil, func() error {
+        if err := ensureDir(g.Out); err != nil { return err }
+        if err := runCmd(bin, args...); err != nil { return err }
+        logger.Info("Generated "+g.Name, "path", g.Out, "duration", time.Since(start).Round(100*time.Millisecond))
+        return nil
+    })
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// Ref graph export: paths -> components -> nested components, as Graphviz
+// DOT or a Mermaid flowchart.
+
+const (
+    graphDOT     = "dot"
+    graphMermaid = "mermaid"
+)
+
+func runGraphCommand(args []string) error {
+    // --format names the graph format here, not the root's, so it is taken
+    // out before the shared option flags are parsed.
+    f, args := takeFlag(args, "format")
+    f = strings.ToLower(strings.TrimSpace(f))
+    if f == "" { f = graphDOT }
+    if f != graphDOT && f != graphMermaid {
+        return fmt.Errorf("graph: invalid --format %q (expected dot or mermaid)", f)
+    }
+    fs, opts := commandFlags("graph", "graph --input <dir> [--format dot|mermaid] [--out <file>] [options]")
+    out := fs.String("out", "", "Write the graph to this file (default: stdout)")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+
+    g, err := indexer.BuildRefGraph(cfg.index())
+    if err != nil { return err }
+    var buf bytes.Buffer
+    if f == graphMermaid {
+        writeMermaid(&buf, g)
+    } else {
+        writeDOT(&buf, g)
+    }
+    if *out == "" {
+        _, err := os.Stdout.Write(buf.Bytes())
+        return err
+    }
+    target := absJoin(cfg.Cwd, *out)
+    if err := ensureDir(filepath.Dir(target)); err != nil { return err }
+    if _, err := atomicfile.WriteFile(target, buf.Bytes()); err != nil { return err }
+    fmt.Fprintf(os.Stdout, "Wrote graph: %s\n", target)
+    return nil
+}
+
+// takeFlag removes --name value (or --name=value, single dash too) from args
+// and returns its last value.
+func takeFlag(args []string, name string) (string, []string) {
+    var val string
+    var rest []string
+    for i := 0; i < len(args); i++ {
+        a := args[i]
+        if a == "--" {
+            rest = append(rest, args[i:]...)
+            break
+        }
+        flagName, v, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+        if !strings.HasPrefix(a, "-") || flagName != name {
+            rest = append(rest, a)
+            continue
+        }
+        if !hasValue && i+1 < len(args) {
+            i++
+            v = args[i]
+        }
+        val = v
+    }
+    return val, rest
+}
+
+// graphLabel is a node's name plus, for components, how many fragments
+// reference it, so heavily shared schemas stand out.
+func graphLabel(node string, fanIn map[string]int) string {
+    if strings.HasPrefix(node, "paths/") { return strings.TrimPrefix(node, "paths") }
+    if indexer.IsEntryNode(node) { return node }
+    return fmt.Sprintf("%s (%d)", node, fanIn[node])
+}
+
+func graphFanIn(g *indexer.RefGraph) map[string]int {
+    fanIn := map[string]int{}
+    for _, edges := range g.Edges {
+        for _, e := range edges {
+            fanIn[e.To]++
+        }
+    }
+    return fanIn
+}
+
+func writeDOT(buf *bytes.Buffer, g *indexer.RefGraph) {
+    fanIn := graphFanIn(g)
+    buf.WriteString("digraph refs {\n    rankdir=LR;\n    node [shape=box, style=rounded];\n")
+    for _, n := range g.Nodes {
+        shape := "ellipse"
+        if indexer.IsEntryNode(n) { shape = "box" }
+        fmt.Fprintf(buf, "    %s [label=%s, shape=%s];\n", strconv.Quote(n), strconv.Quote(graphLabel(n, fanIn)), shape)
+    }
+    for _, n := range g.Nodes {
+        for _, e := range g.Edges[n] {
+            fmt.Fprintf(buf, "    %s -> %s;\n", strconv.Quote(n), strconv.Quote(e.To))
+        }
+    }
+    buf.WriteString("}\n")
+}
+
+func writeMermaid(buf *bytes.Buffer, g *indexer.RefGraph) {
+    fanIn := graphFanIn(g)
+    ids := map[string]string{}
+    buf.WriteString("flowchart LR\n")
+    for i, n := range g.Nodes {
+        ids[n] = "n" + strconv.Itoa(i)
+        label := strings.ReplaceAll(graphLabel(n, fanIn), `"`, "#quot;")
+        if indexer.IsEntryNode(n) {
+            fmt.Fprintf(buf, "    %s[\"%s\"]\n", ids[n], label)
+        } else {
+            fmt.Fprintf(buf, "    %s([\"%s\"])\n", ids[n], label)
+        }
+    }
+    for _, n := range g.Nodes {
+        for _, e := range g.Edges[n] {
+            fmt.Fprintf(buf, "    %s --> %s\n", ids[n], ids[e.To])
+        }
+    }
+}
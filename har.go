@@ -0,0 +1,623 @@
+package main
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "net/url"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// HAR import: scaffold draft path fragments from recorded traffic.
+
+type harNameValue struct {
+    Name     string `json:"name"`
+    Value    string `json:"value"`
+    FileName string `json:"fileName"` // multipart file fields
+    optional bool // documented but not sent (Postman's disabled query parameters)
+}
+
+type harEntry struct {
+    Request struct {
+        Method      string         `json:"method"`
+        URL         string         `json:"url"`
+        QueryString []harNameValue `json:"queryString"`
+        PostData    *struct {
+            MimeType string         `json:"mimeType"`
+            Text     string         `json:"text"`
+            Params   []harNameValue `json:"params"` // form fields
+        } `json:"postData"`
+    } `json:"request"`
+    Response struct {
+        Status  int `json:"status"`
+        Content struct {
+            MimeType string `json:"mimeType"`
+            Text     string `json:"text"`
+            Encoding string `json:"encoding"`
+        } `json:"content"`
+    } `json:"response"`
+
+    // Set by imports from richer formats than HAR.
+    summary, description, tag string
+    pathValues                map[string]string // sample value of each {name} segment
+}
+
+type harDocument struct {
+    Log struct {
+        Entries []harEntry `json:"entries"`
+    } `json:"log"`
+}
+
+// harOperation aggregates every recorded call for one method + path template.
+type harOperation struct {
+    Method       string
+    Template     string
+    Calls        int
+    PathParams   []string
+    PathSchemas  []*yaml.Node          // inferred from the segments each path parameter replaced
+    Query        map[string]string     // name -> sample value
+    QuerySchemas map[string]*yaml.Node
+    QueryCalls   map[string]int        // name -> calls sending it; sent by every call means required
+    Body         *yaml.Node            // inferred request body schema (nil when no JSON or form body)
+    BodyMime     string
+    BodyCalls    int
+    Responses    map[int]*yaml.Node    // status -> inferred schema (nil when no JSON body)
+    MimeTypes    map[int]string
+    Summary      string
+    Description  string
+    Tag          string
+}
+
+var (
+    reUUIDSeg    = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+    reHexSeg     = regexp.MustCompile(`(?i)^[0-9a-f]{16,}$`)
+    reNumSeg     = regexp.MustCompile(`^\d+$`)
+    reEmailValue = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+var harStaticExts = map[string]bool{
+    ".js": true, ".css": true, ".map": true, ".png": true, ".jpg": true, ".jpeg": true,
+    ".gif": true, ".svg": true, ".ico": true, ".woff": true, ".woff2": true, ".ttf": true, ".html": true,
+}
+
+// parseInterspersed parses flags that may appear before or after positional args.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+    var positional []string
+    for {
+        if err := fs.Parse(args); err != nil { return nil, err }
+        args = fs.Args()
+        if len(args) == 0 { break }
+        positional = append(positional, args[0])
+        args = args[1:]
+    }
+    return positional, nil
+}
+
+func runImport(args []string) error {
+    if len(args) == 0 {
+        return errors.New("usage: oas-indexer import har|postman <file> --input <dir>")
+    }
+    switch args[0] {
+    case "har":
+        return runImportHAR(args[1:])
+    case "postman":
+        return runImportPostman(args[1:])
+    default:
+        return fmt.Errorf("unknown import source %q (supported: har, postman)", args[0])
+    }
+}
+
+func runImportHAR(args []string) error {
+    fs := flag.NewFlagSet("import har", flag.ContinueOnError)
+    input := fs.String("input", "", "[required] Fragments directory to scaffold into")
+    inputS := fs.String("i", "", "Shorthand for --input")
+    host := fs.String("host", "", "Only import requests to this host")
+    force := fs.Bool("force", false, "Overwrite existing path fragments")
+    casing := fs.String("path-casing", indexer.PathCasingCamel, "Path-key casing the fragments will be indexed with (camel, kebab, preserve)")
+    fs.Usage = func() {
+        fmt.Fprintf(os.Stderr, "Usage:\n  oas-indexer import har <file.har> --input <dir> [--host <host>] [--force] [--path-casing <c>]\n\n")
+        fs.PrintDefaults()
+    }
+    positional, err := parseInterspersed(fs, args)
+    if err != nil { return err }
+    if len(positional) != 1 {
+        fs.Usage()
+        return errors.New("import har: exactly one HAR file is required")
+    }
+    inputDir := firstNonEmpty(*input, *inputS)
+    if strings.TrimSpace(inputDir) == "" {
+        fs.Usage()
+        return errors.New("missing required flag: --input is required")
+    }
+    if !indexer.ValidPathCasing(*casing) {
+        return fmt.Errorf("invalid --path-casing %q (expected camel, kebab or preserve)", *casing)
+    }
+    cwd, _ := os.Getwd()
+    pathsDir := filepath.Join(absJoin(cwd, inputDir), "paths")
+
+    raw, err := os.ReadFile(positional[0])
+    if err != nil { return err }
+    var doc harDocument
+    if err := json.Unmarshal(raw, &doc); err != nil {
+        return fmt.Errorf("failed to parse HAR %s: %w", positional[0], err)
+    }
+
+    ops := collectHAROperations(doc.Log.Entries, strings.TrimSpace(*host))
+    if len(ops) == 0 {
+        return errors.New("import har: no API requests found in capture")
+    }
+    return writeHARFragments(pathsDir, ops, *force, *casing)
+}
+
+func collectHAROperations(entries []harEntry, host string) map[string]map[string]*harOperation {
+    byPath := map[string]map[string]*harOperation{}
+    for _, e := range entries {
+        u, err := url.Parse(e.Request.URL)
+        if err != nil { continue }
+        if host != "" && !strings.EqualFold(u.Hostname(), host) { continue }
+        if harStaticExts[strings.ToLower(filepath.Ext(u.Path))] { continue }
+        mime := strings.ToLower(e.Response.Content.MimeType)
+        if strings.HasPrefix(mime, "text/html") || strings.HasPrefix(mime, "image/") || strings.HasPrefix(mime, "font/") {
+            continue
+        }
+        template, params, values := templatePath(u.Path)
+        if template == "" { continue }
+        method := strings.ToLower(e.Request.Method)
+        if byPath[template] == nil { byPath[template] = map[string]*harOperation{} }
+        op := byPath[template][method]
+        if op == nil {
+            op = &harOperation{
+                Method:       method,
+                Template:     template,
+                PathParams:   params,
+                Query:        map[string]string{},
+                QuerySchemas: map[string]*yaml.Node{},
+                QueryCalls:   map[string]int{},
+                Responses:    map[int]*yaml.Node{},
+                MimeTypes:    map[int]string{},
+            }
+            byPath[template][method] = op
+        }
+        op.Calls++
+        op.Summary, op.Description, op.Tag = firstNonEmpty(op.Summary, e.summary), firstNonEmpty(op.Description, e.description), firstNonEmpty(op.Tag, e.tag)
+        for i, v := range values {
+            if v == "" { v = e.pathValues[params[i]] }
+            if i < len(op.PathSchemas) {
+                mergeSchemaNodes(op.PathSchemas[i], inferParamSchema(v))
+            } else {
+                op.PathSchemas = append(op.PathSchemas, inferParamSchema(v))
+            }
+        }
+        seen := map[string]bool{}
+        for _, q := range e.Request.QueryString {
+            if seen[q.Name] { continue } // repeated (array) parameters count once
+            seen[q.Name] = true
+            if !q.optional { op.QueryCalls[q.Name]++ }
+            if prev, ok := op.QuerySchemas[q.Name]; ok {
+                mergeSchemaNodes(prev, inferParamSchema(q.Value))
+            } else {
+                op.Query[q.Name], op.QuerySchemas[q.Name] = q.Value, inferParamSchema(q.Value)
+            }
+        }
+        if mt, body := harRequestBody(e); body != nil {
+            op.BodyCalls++
+            if op.Body == nil {
+                op.Body, op.BodyMime = body, mt
+            } else if mt == op.BodyMime {
+                mergeSchemaNodes(op.Body, body)
+            }
+        }
+        status := e.Response.Status
+        if status <= 0 { continue }
+        var schema *yaml.Node
+        if body, ok := decodeHARBody(e.Response.Content.Text, e.Response.Content.Encoding); ok && strings.Contains(mime, "json") {
+            if v, err := decodeJSONValue(body); err == nil {
+                schema = inferSchemaNode(v)
+            }
+        }
+        if prev, ok := op.Responses[status]; ok && prev != nil {
+            mergeSchemaNodes(prev, schema)
+        } else {
+            op.Responses[status] = schema
+        }
+        if _, ok := op.MimeTypes[status]; !ok {
+            op.MimeTypes[status] = strings.TrimSpace(strings.Split(mime, ";")[0])
+        }
+    }
+    return byPath
+}
+
+func decodeHARBody(text, encoding string) ([]byte, bool) {
+    if text == "" { return nil, false }
+    if strings.EqualFold(encoding, "base64") {
+        b, err := base64.StdEncoding.DecodeString(text)
+        if err != nil { return nil, false }
+        return b, true
+    }
+    return []byte(text), true
+}
+
+func decodeJSONValue(b []byte) (interface{}, error) {
+    dec := json.NewDecoder(strings.NewReader(string(b)))
+    dec.UseNumber()
+    var v interface{}
+    if err := dec.Decode(&v); err != nil { return nil, err }
+    return v, nil
+}
+
+// templatePath replaces identifier-like segments with path parameters,
+// returning their names and the segments they replaced. Segments already
+// templated as {name} are kept, with an empty value.
+func templatePath(p string) (string, []string, []string) {
+    segs := strings.Split(strings.Trim(p, "/"), "/")
+    var params, values []string
+    var out []string
+    for i, s := range segs {
+        if s == "" { continue }
+        if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") && len(s) > 2 {
+            params = append(params, s[1:len(s)-1])
+            values = append(values, "")
+            out = append(out, s)
+            continue
+        }
+        if reNumSeg.MatchString(s) || reUUIDSeg.MatchString(s) || reHexSeg.MatchString(s) {
+            name := "id"
+            if len(params) > 0 && i > 0 {
+                name = indexer.KebabToCamel(singularize(segs[i-1])) + "Id"
+            }
+            params = append(params, name)
+            values = append(values, s)
+            out = append(out, "{"+name+"}")
+            continue
+        }
+        out = append(out, s)
+    }
+    if len(out) == 0 { return "", nil, nil }
+    return "/" + strings.Join(out, "/"), params, values
+}
+
+func singularize(word string) string {
+    switch {
+    case strings.HasSuffix(word, "ies"):
+        return strings.TrimSuffix(word, "ies") + "y"
+    case strings.HasSuffix(word, "ses"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+        return strings.TrimSuffix(word, "es")
+    case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+        return strings.TrimSuffix(word, "s")
+    default:
+        return word
+    }
+}
+
+// harRequestBody infers the schema of e's request body: a JSON body, or the
+// fields of a form, where file fields are binary strings.
+func harRequestBody(e harEntry) (string, *yaml.Node) {
+    pd := e.Request.PostData
+    if pd == nil { return "", nil }
+    mt := strings.ToLower(strings.TrimSpace(strings.Split(pd.MimeType, ";")[0]))
+    switch {
+    case strings.Contains(mt, "json"):
+        v, err := decodeJSONValue([]byte(pd.Text))
+        if err != nil { return "", nil }
+        return mt, inferSchemaNode(v)
+    case mt == "application/x-www-form-urlencoded" || mt == "multipart/form-data":
+        fields := pd.Params
+        if len(fields) == 0 && mt == "application/x-www-form-urlencoded" {
+            form, err := url.ParseQuery(pd.Text)
+            if err != nil { return "", nil }
+            for name, vals := range form {
+                fields = append(fields, harNameValue{Name: name, Value: vals[0]})
+            }
+        }
+        if len(fields) == 0 { return "", nil }
+        sort.SliceStable(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+        n := yamlnode.Map()
+        yamlnode.SetKey(n, "type", yamlnode.Str("object"))
+        props, required := yamlnode.Map(), yamlnode.Seq()
+        for _, f := range fields {
+            if yamlnode.GetKey(props, f.Name) != nil { continue }
+            schema := inferParamSchema(f.Value)
+            if f.FileName != "" {
+                schema = yamlnode.Map()
+                yamlnode.SetKey(schema, "type", yamlnode.Str("string"))
+                yamlnode.SetKey(schema, "format", yamlnode.Str("binary"))
+            }
+            yamlnode.SetKey(props, f.Name, schema)
+            required.Content = append(required.Content, yamlnode.Str(f.Name))
+        }
+        yamlnode.SetKey(n, "properties", props)
+        yamlnode.SetKey(n, "required", required)
+        return mt, n
+    }
+    return "", nil
+}
+
+// inferParamSchema builds a draft schema from a path segment, query value
+// or form field, which are integers, numbers or booleans when they parse as
+// one.
+func inferParamSchema(s string) *yaml.Node {
+    if _, err := strconv.ParseInt(s, 10, 64); err == nil { return inferSchemaNode(json.Number(s)) }
+    if _, err := strconv.ParseFloat(s, 64); err == nil && s != "" && !strings.ContainsAny(s, "xXnN") { return inferSchemaNode(json.Number(s)) }
+    if s == "true" || s == "false" { return inferSchemaNode(s == "true") }
+    return inferSchemaNode(s)
+}
+
+// paramExample renders the sample value s for a parameter of schema.
+func paramExample(s string, schema *yaml.Node) *yaml.Node {
+    switch yamlnode.GetKey(schema, "type").Value {
+    case "integer":
+        return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: s}
+    case "number":
+        return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: s}
+    case "boolean":
+        return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: s}
+    }
+    return yamlnode.Str(s)
+}
+
+// inferSchemaNode builds a draft schema from a decoded JSON sample.
+func inferSchemaNode(v interface{}) *yaml.Node {
+    n := yamlnode.Map()
+    switch t := v.(type) {
+    case nil:
+        yamlnode.SetKey(n, "nullable", yamlnode.Bool(true))
+    case bool:
+        yamlnode.SetKey(n, "type", yamlnode.Str("boolean"))
+    case json.Number:
+        if _, err := strconv.ParseInt(t.String(), 10, 64); err == nil {
+            yamlnode.SetKey(n, "type", yamlnode.Str("integer"))
+        } else {
+            yamlnode.SetKey(n, "type", yamlnode.Str("number"))
+        }
+    case string:
+        yamlnode.SetKey(n, "type", yamlnode.Str("string"))
+        if f := inferStringFormat(t); f != "" { yamlnode.SetKey(n, "format", yamlnode.Str(f)) }
+    case []interface{}:
+        yamlnode.SetKey(n, "type", yamlnode.Str("array"))
+        items := yamlnode.Map()
+        for i, el := range t {
+            if i == 0 {
+                items = inferSchemaNode(el)
+            } else {
+                mergeSchemaNodes(items, inferSchemaNode(el))
+            }
+        }
+        yamlnode.SetKey(n, "items", items)
+    case map[string]interface{}:
+        yamlnode.SetKey(n, "type", yamlnode.Str("object"))
+        keys := make([]string, 0, len(t))
+        for k := range t { keys = append(keys, k) }
+        sort.Strings(keys)
+        props := yamlnode.Map()
+        required := yamlnode.Seq()
+        for _, k := range keys {
+            yamlnode.SetKey(props, k, inferSchemaNode(t[k]))
+            if t[k] != nil { required.Content = append(required.Content, yamlnode.Str(k)) }
+        }
+        if len(keys) > 0 { yamlnode.SetKey(n, "properties", props) }
+        if len(required.Content) > 0 { yamlnode.SetKey(n, "required", required) }
+    }
+    return n
+}
+
+func inferStringFormat(s string) string {
+    if _, err := time.Parse(time.RFC3339, s); err == nil { return "date-time" }
+    if _, err := time.Parse("2006-01-02", s); err == nil { return "date" }
+    if reUUIDSeg.MatchString(s) { return "uuid" }
+    if reEmailValue.MatchString(s) { return "email" }
+    if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") { return "uri" }
+    return ""
+}
+
+// mergeSchemaNodes folds another sample's schema b into a: properties seen
+// only in b are added, only those required (present and not null) in both
+// stay required, integer widens to number, and a format both do not share
+// or a null sample makes a drop the format or become nullable.
+func mergeSchemaNodes(a, b *yaml.Node) {
+    if a == nil || b == nil { return }
+    at, bt := yamlnode.GetKey(a, "type"), yamlnode.GetKey(b, "type")
+    if at == nil && bt != nil {
+        // a came from a null sample (or an empty array's items); adopt b's shape
+        nullable := yamlnode.GetKey(a, "nullable") != nil
+        a.Content = append([]*yaml.Node{}, b.Content...)
+        if nullable { yamlnode.SetKey(a, "nullable", yamlnode.Bool(true)) }
+        return
+    }
+    if bt == nil {
+        if yamlnode.GetKey(b, "nullable") != nil { yamlnode.SetKey(a, "nullable", yamlnode.Bool(true)) }
+        return
+    }
+    if at.Value == "integer" && bt.Value == "number" { at.Value = "number" }
+    if f := yamlnode.GetKey(a, "format"); f != nil {
+        if g := yamlnode.GetKey(b, "format"); g == nil || g.Value != f.Value { yamlnode.DeleteKey(a, "format") }
+    }
+    if req := yamlnode.GetKey(a, "required"); req != nil && at.Value == "object" {
+        inB := map[string]bool{}
+        if breq := yamlnode.GetKey(b, "required"); breq != nil {
+            for _, r := range breq.Content { inB[r.Value] = true }
+        }
+        kept := req.Content[:0]
+        for _, r := range req.Content {
+            if inB[r.Value] { kept = append(kept, r) }
+        }
+        req.Content = kept
+        if len(kept) == 0 { yamlnode.DeleteKey(a, "required") }
+    }
+    ap, bp := yamlnode.GetKey(a, "properties"), yamlnode.GetKey(b, "properties")
+    if bp != nil {
+        if ap == nil {
+            yamlnode.SetKey(a, "properties", bp)
+            return
+        }
+        for i := 0; i+1 < len(bp.Content); i += 2 {
+            key := bp.Content[i].Value
+            if existing := yamlnode.GetKey(ap, key); existing != nil {
+                mergeSchemaNodes(existing, bp.Content[i+1])
+            } else {
+                yamlnode.SetKey(ap, key, bp.Content[i+1])
+            }
+        }
+    }
+    if ai, bi := yamlnode.GetKey(a, "items"), yamlnode.GetKey(b, "items"); ai != nil && bi != nil {
+        mergeSchemaNodes(ai, bi)
+    }
+}
+
+func harOperationID(op *harOperation) string {
+    var b strings.Builder
+    b.WriteString(op.Method)
+    for _, s := range strings.Split(strings.Trim(op.Template, "/"), "/") {
+        if strings.HasPrefix(s, "{") {
+            b.WriteString("By" + indexer.PascalCase(strings.Trim(s, "{}")))
+            continue
+        }
+        b.WriteString(indexer.PascalCase(s))
+    }
+    return b.String()
+}
+
+func buildHAROperationNode(op *harOperation) *yaml.Node {
+    n := yamlnode.Map()
+    yamlnode.SetKey(n, "operationId", yamlnode.Str(harOperationID(op)))
+    yamlnode.SetKey(n, "summary", yamlnode.Str(firstNonEmpty(op.Summary, strings.ToUpper(op.Method)+" "+op.Template)))
+    if op.Description != "" { yamlnode.SetKey(n, "description", yamlnode.Str(op.Description)) }
+    if op.Tag != "" {
+        tags := yamlnode.Seq()
+        tags.Content = append(tags.Content, yamlnode.Str(op.Tag))
+        yamlnode.SetKey(n, "tags", tags)
+    }
+    yamlnode.SetKey(n, "x-draft", yamlnode.Bool(true))
+
+    params := yamlnode.Seq()
+    for i, p := range op.PathParams {
+        pn := yamlnode.Map()
+        yamlnode.SetKey(pn, "name", yamlnode.Str(p))
+        yamlnode.SetKey(pn, "in", yamlnode.Str("path"))
+        yamlnode.SetKey(pn, "required", yamlnode.Bool(true))
+        yamlnode.SetKey(pn, "schema", op.PathSchemas[i])
+        params.Content = append(params.Content, pn)
+    }
+    qnames := make([]string, 0, len(op.Query))
+    for q := range op.Query { qnames = append(qnames, q) }
+    sort.Strings(qnames)
+    for _, q := range qnames {
+        pn := yamlnode.Map()
+        yamlnode.SetKey(pn, "name", yamlnode.Str(q))
+        yamlnode.SetKey(pn, "in", yamlnode.Str("query"))
+        if op.QueryCalls[q] == op.Calls { yamlnode.SetKey(pn, "required", yamlnode.Bool(true)) }
+        schema := op.QuerySchemas[q]
+        yamlnode.SetKey(pn, "schema", schema)
+        if ex := op.Query[q]; ex != "" && yamlnode.GetKey(schema, "type") != nil { yamlnode.SetKey(pn, "example", paramExample(ex, schema)) }
+        params.Content = append(params.Content, pn)
+    }
+    if len(params.Content) > 0 { yamlnode.SetKey(n, "parameters", params) }
+
+    if op.Body != nil {
+        body := yamlnode.Map()
+        if op.BodyCalls == op.Calls { yamlnode.SetKey(body, "required", yamlnode.Bool(true)) }
+        media := yamlnode.Map()
+        yamlnode.SetKey(media, "schema", op.Body)
+        content := yamlnode.Map()
+        yamlnode.SetKey(content, op.BodyMime, media)
+        yamlnode.SetKey(body, "content", content)
+        yamlnode.SetKey(n, "requestBody", body)
+    }
+
+    statuses := make([]int, 0, len(op.Responses))
+    for s := range op.Responses { statuses = append(statuses, s) }
+    sort.Ints(statuses)
+    responses := yamlnode.Map()
+    for _, s := range statuses {
+        rn := yamlnode.Map()
+        desc := httpStatusText(s)
+        yamlnode.SetKey(rn, "description", yamlnode.Str(desc))
+        if schema := op.Responses[s]; schema != nil {
+            mt := op.MimeTypes[s]
+            if mt == "" { mt = "application/json" }
+            media := yamlnode.Map()
+            yamlnode.SetKey(media, "schema", schema)
+            content := yamlnode.Map()
+            yamlnode.SetKey(content, mt, media)
+            yamlnode.SetKey(rn, "content", content)
+        }
+        responses.Content = append(responses.Content, yamlnode.Str(strconv.Itoa(s)), rn)
+    }
+    if len(statuses) == 0 {
+        rn := yamlnode.Map()
+        yamlnode.SetKey(rn, "description", yamlnode.Str("Response not recorded"))
+        yamlnode.SetKey(responses, "default", rn)
+    }
+    yamlnode.SetKey(n, "responses", responses)
+    return n
+}
+
+// relativizeRefs rewrites the absolute file refs below n (to extracted
+// schemas) relative to dir.
+func relativizeRefs(n *yaml.Node, dir string) {
+    if n == nil { return }
+    if n.Kind == yaml.MappingNode {
+        if ref := yamlnode.GetKey(n, "$ref"); ref != nil && filepath.IsAbs(ref.Value) {
+            if rel, err := filepath.Rel(dir, ref.Value); err == nil { ref.Value = filepath.ToSlash(rel) }
+        }
+    }
+    for _, c := range n.Content { relativizeRefs(c, dir) }
+}
+
+func httpStatusText(code int) string {
+    if t := httpStatusTexts[code]; t != "" { return t }
+    return "Response " + strconv.Itoa(code)
+}
+
+var httpStatusTexts = map[int]string{
+    200: "OK", 201: "Created", 202: "Accepted", 204: "No Content",
+    301: "Moved Permanently", 302: "Found", 304: "Not Modified",
+    400: "Bad Request", 401: "Unauthorized", 403: "Forbidden", 404: "Not Found",
+    409: "Conflict", 422: "Unprocessable Entity", 429: "Too Many Requests",
+    500: "Internal Server Error", 502: "Bad Gateway", 503: "Service Unavailable",
+}
+
+func writeHARFragments(pathsDir string, byPath map[string]map[string]*harOperation, force bool, casing string) error {
+    templates := make([]string, 0, len(byPath))
+    for t := range byPath { templates = append(templates, t) }
+    sort.Strings(templates)
+
+    written, skipped := 0, 0
+    for _, t := range templates {
+        file := filepath.Join(pathsDir, filepath.FromSlash(strings.TrimPrefix(t, "/"))+".yaml")
+        if _, err := os.Stat(file); err == nil && !force {
+            logger.Info("Already exists, skipped (--force to overwrite)", "path", file)
+            skipped++
+            continue
+        }
+        if key := indexer.BuildPathKey(pathsDir, file, casing); key != t {
+            logger.Warn(fmt.Sprintf("%s will be indexed as %s, not %s; rename before publishing", file, key, t))
+        }
+        item := yamlnode.Map()
+        methods := make([]string, 0, len(byPath[t]))
+        for m := range byPath[t] { methods = append(methods, m) }
+        sort.Strings(methods)
+        for _, m := range methods {
+            yamlnode.SetKey(item, m, buildHAROperationNode(byPath[t][m]))
+        }
+        relativizeRefs(item, filepath.Dir(file))
+        if err := ensureDir(filepath.Dir(file)); err != nil { return err }
+        if err := yamlnode.Write(file, item); err != nil { return err }
+        fmt.Fprintf(os.Stdout, "draft: %s\n", file)
+        written++
+    }
+    fmt.Fprintf(os.Stdout, "Imported %d path fragment(s), skipped %d\n", written, skipped)
+    return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Schema inference: draft schema fragments from captured JSON payloads.
+
+func runInferCommand(args []string) error {
+    fs := flag.NewFlagSet("infer", flag.ContinueOnError)
+    from := fs.String("from", "", "[required] Directory of JSON samples (.json, or .jsonl with one sample per line)")
+    out := fs.String("out", "", "[required] Directory to write the schema fragments to, e.g. <input>/components/schemas")
+    force := fs.Bool("force", false, "Overwrite existing schema fragments")
+    fs.Usage = func() {
+        fmt.Fprintf(os.Stderr, "Usage:\n  oas-indexer infer --from <samples> --out <dir> [--force]\n\n")
+        fmt.Fprintf(os.Stderr, "A sample file gives a schema named after it; a subdirectory gives one schema,\nnamed after the directory, from all the samples in it.\n\n")
+        fs.PrintDefaults()
+    }
+    positional, err := parseInterspersed(fs, args)
+    if err != nil { return err }
+    if len(positional) > 0 {
+        fs.Usage()
+        return fmt.Errorf("infer: unexpected argument %q", positional[0])
+    }
+    if strings.TrimSpace(*from) == "" || strings.TrimSpace(*out) == "" {
+        fs.Usage()
+        return errors.New("missing required flag: --from and --out are required")
+    }
+
+    groups, err := collectSamples(*from)
+    if err != nil { return err }
+    if len(groups) == 0 { return fmt.Errorf("infer: no .json or .jsonl samples in %s", *from) }
+    names := make([]string, 0, len(groups))
+    for name := range groups { names = append(names, name) }
+    sort.Strings(names)
+
+    written, skipped := 0, 0
+    for _, name := range names {
+        file := filepath.Join(*out, name+".yaml")
+        if _, err := os.Stat(file); err == nil && !*force {
+            logger.Info("Already exists, skipped (--force to overwrite)", "path", file)
+            skipped++
+            continue
+        }
+        var schema *yaml.Node
+        for _, v := range groups[name] {
+            if schema == nil {
+                schema = inferSchemaNode(v)
+            } else {
+                mergeSchemaNodes(schema, inferSchemaNode(v))
+            }
+        }
+        yamlnode.SetKey(schema, "x-draft", yamlnode.Bool(true))
+        if err := ensureDir(filepath.Dir(file)); err != nil { return err }
+        if err := yamlnode.Write(file, schema); err != nil { return err }
+        fmt.Fprintf(os.Stdout, "draft: %s (%d sample(s))\n", file, len(groups[name]))
+        written++
+    }
+    fmt.Fprintf(os.Stdout, "Inferred %d schema fragment(s), skipped %d\n", written, skipped)
+    return nil
+}
+
+// collectSamples decodes the samples below dir, grouped by schema name: the
+// base name of a file directly in dir, or the first directory below dir.
+func collectSamples(dir string) (map[string][]interface{}, error) {
+    groups := map[string][]interface{}{}
+    err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+        if err != nil { return err }
+        ext := strings.ToLower(filepath.Ext(path))
+        if d.IsDir() || ext != ".json" && ext != ".jsonl" { return nil }
+        rel, err := filepath.Rel(dir, path)
+        if err != nil { return err }
+        name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+        if parts := strings.Split(filepath.ToSlash(rel), "/"); len(parts) > 1 { name = parts[0] }
+        samples, err := readSamples(path, ext == ".jsonl")
+        if err != nil { return err }
+        groups[name] = append(groups[name], samples...)
+        return nil
+    })
+    if err != nil { return nil, err }
+    return groups, nil
+}
+
+// readSamples decodes the JSON sample in path, or each non-empty line when
+// lines is set.
+func readSamples(path string, lines bool) ([]interface{}, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    if !lines {
+        v, err := decodeJSONValue(data)
+        if err != nil { return nil, fmt.Errorf("%s: %v", path, err) }
+        return []interface{}{v}, nil
+    }
+    var out []interface{}
+    sc := bufio.NewScanner(bytes.NewReader(data))
+    sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+    for line := 1; sc.Scan(); line++ {
+        if strings.TrimSpace(sc.Text()) == "" { continue }
+        v, err := decodeJSONValue(sc.Bytes())
+        if err != nil { return nil, fmt.Errorf("%s:%d: %v", path, line, err) }
+        out = append(out, v)
+    }
+    return out, sc.Err()
+}
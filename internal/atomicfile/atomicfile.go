@@ -0,0 +1,100 @@
+// Package atomicfile writes artifacts via a temp file renamed into place, so
+// readers never observe a truncated file.
+package atomicfile
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// File writes to a temp file next to the target and renames it into place on
+// Commit. When the new content is byte-identical to the target, the target is
+// left untouched.
+type File struct {
+    *os.File
+    target    string
+    done      bool
+    Unchanged bool // set by Commit when the existing target already matched
+}
+
+func Create(target string) (*File, error) {
+    f, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+    if err != nil { return nil, err }
+    return &File{File: f, target: target}, nil
+}
+
+// Commit closes the temp file and renames it over the target, or discards it
+// when the target already has the same content, so that an unchanged target
+// keeps its modification time.
+func (a *File) Commit() error {
+    if a.done { return nil }
+    a.done = true
+    if err := a.File.Close(); err != nil {
+        os.Remove(a.File.Name())
+        return err
+    }
+    if SameContent(a.File.Name(), a.target) {
+        a.Unchanged = true
+        return os.Remove(a.File.Name())
+    }
+    // CreateTemp makes the file 0600; keep the target's mode instead.
+    mode := os.FileMode(0o644)
+    if st, err := os.Stat(a.target); err == nil { mode = st.Mode().Perm() }
+    if err := os.Chmod(a.File.Name(), mode); err != nil {
+        os.Remove(a.File.Name())
+        return err
+    }
+    if err := os.Rename(a.File.Name(), a.target); err != nil {
+        os.Remove(a.File.Name())
+        return err
+    }
+    return nil
+}
+
+// Abort discards the temp file; it is a no-op after Commit.
+func (a *File) Abort() {
+    if a.done { return }
+    a.done = true
+    a.File.Close()
+    os.Remove(a.File.Name())
+}
+
+// WriteFile writes data to path via Create. The returned bool is false when
+// path already held exactly data.
+func WriteFile(path string, data []byte) (bool, error) {
+    f, err := Create(path)
+    if err != nil { return false, err }
+    defer f.Abort()
+    if _, err := f.Write(data); err != nil { return false, err }
+    if err := f.Commit(); err != nil { return false, err }
+    return !f.Unchanged, nil
+}
+
+// TempSibling returns an unused temp path next to target that keeps its
+// extension, since external tools pick the output format from it.
+func TempSibling(target string) string {
+    ext := filepath.Ext(target)
+    base := strings.TrimSuffix(filepath.Base(target), ext)
+    for i := 0; ; i++ {
+        p := filepath.Join(filepath.Dir(target), fmt.Sprintf(".%s.tmp-%d-%d%s", base, os.Getpid(), i, ext))
+        if _, err := os.Lstat(p); os.IsNotExist(err) {
+            return p
+        }
+    }
+}
+
+// SameContent reports whether both files exist and are byte-identical.
+func SameContent(a, b string) bool {
+    sa, err := os.Stat(a)
+    if err != nil { return false }
+    sb, err := os.Stat(b)
+    if err != nil || !sb.Mode().IsRegular() || sa.Size() != sb.Size() { return false }
+    ba, err := os.ReadFile(a)
+    if err != nil { return false }
+    bb, err := os.ReadFile(b)
+    if err != nil { return false }
+    return bytes.Equal(ba, bb)
+}
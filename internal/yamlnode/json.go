@@ -0,0 +1,104 @@
+package yamlnode
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "math"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// MarshalJSON renders n as indented JSON, keeping mapping key order.
+// Aliases are expanded; comments and styles are dropped.
+func MarshalJSON(n *yaml.Node) ([]byte, error) {
+    var buf bytes.Buffer
+    if err := writeJSON(&buf, n, ""); err != nil { return nil, err }
+    buf.WriteByte('\n')
+    return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, n *yaml.Node, indent string) error {
+    if n == nil {
+        buf.WriteString("null")
+        return nil
+    }
+    inner := indent + "  "
+    switch n.Kind {
+    case yaml.DocumentNode:
+        return writeJSON(buf, DocRoot(n), indent)
+    case yaml.AliasNode:
+        return writeJSON(buf, n.Alias, indent)
+    case yaml.MappingNode:
+        if len(n.Content) == 0 {
+            buf.WriteString("{}")
+            return nil
+        }
+        buf.WriteString("{\n")
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            k := n.Content[i]
+            if k.Kind != yaml.ScalarNode {
+                return fmt.Errorf("line %d: JSON object keys must be scalars, found %s", k.Line, KindName(k))
+            }
+            if i > 0 { buf.WriteString(",\n") }
+            buf.WriteString(inner)
+            writeJSONString(buf, k.Value)
+            buf.WriteString(": ")
+            if err := writeJSON(buf, n.Content[i+1], inner); err != nil { return err }
+        }
+        buf.WriteString("\n" + indent + "}")
+    case yaml.SequenceNode:
+        if len(n.Content) == 0 {
+            buf.WriteString("[]")
+            return nil
+        }
+        buf.WriteString("[\n")
+        for i, c := range n.Content {
+            if i > 0 { buf.WriteString(",\n") }
+            buf.WriteString(inner)
+            if err := writeJSON(buf, c, inner); err != nil { return err }
+        }
+        buf.WriteString("\n" + indent + "]")
+    default:
+        return writeJSONScalar(buf, n)
+    }
+    return nil
+}
+
+func writeJSONScalar(buf *bytes.Buffer, n *yaml.Node) error {
+    switch n.ShortTag() {
+    case "!!null":
+        buf.WriteString("null")
+    case "!!bool":
+        var b bool
+        if err := n.Decode(&b); err != nil { return err }
+        fmt.Fprint(buf, b)
+    case "!!int":
+        var i int64
+        if err := n.Decode(&i); err != nil {
+            return fmt.Errorf("line %d: integer %s does not fit in JSON: %v", n.Line, n.Value, err)
+        }
+        fmt.Fprint(buf, i)
+    case "!!float":
+        var f float64
+        if err := n.Decode(&f); err != nil { return err }
+        if math.IsInf(f, 0) || math.IsNaN(f) {
+            return fmt.Errorf("line %d: %s has no JSON representation", n.Line, n.Value)
+        }
+        b, _ := json.Marshal(f)
+        buf.Write(b)
+    default:
+        writeJSONString(buf, n.Value)
+    }
+    return nil
+}
+
+// writeJSONString quotes s without escaping <, > and & as HTML.
+func writeJSONString(buf *bytes.Buffer, s string) {
+    var b bytes.Buffer
+    enc := json.NewEncoder(&b)
+    enc.SetEscapeHTML(false)
+    enc.Encode(s)
+    buf.WriteString(strings.TrimSuffix(b.String(), "\n"))
+}
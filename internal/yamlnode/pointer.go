@@ -0,0 +1,54 @@
+package yamlnode
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// maxRefs bounds how many $refs Deref follows, so ref cycles end.
+const maxRefs = 32
+
+// EscapeToken escapes s for use as one JSON pointer token.
+func EscapeToken(s string) string { return strings.NewReplacer("~", "~0", "/", "~1").Replace(s) }
+
+// UnescapeToken reverses EscapeToken.
+func UnescapeToken(tok string) string { return strings.NewReplacer("~1", "/", "~0", "~").Replace(tok) }
+
+// Lookup follows a JSON pointer such as /properties/id from n.
+func Lookup(n *yaml.Node, ptr string) (*yaml.Node, error) {
+    if ptr == "" { return n, nil }
+    if !strings.HasPrefix(ptr, "/") { return nil, fmt.Errorf("invalid JSON pointer %q", ptr) }
+    if n == nil { return nil, fmt.Errorf("JSON pointer %q not found", ptr) }
+    cur := n
+    for _, tok := range strings.Split(ptr[1:], "/") {
+        tok = UnescapeToken(tok)
+        for cur.Kind == yaml.AliasNode { cur = cur.Alias }
+        var next *yaml.Node
+        switch cur.Kind {
+        case yaml.MappingNode:
+            next = GetKey(cur, tok)
+        case yaml.SequenceNode:
+            if i, err := strconv.Atoi(tok); err == nil && i >= 0 && i < len(cur.Content) { next = cur.Content[i] }
+        }
+        if next == nil { return nil, fmt.Errorf("JSON pointer %q not found", ptr) }
+        cur = next
+    }
+    return cur, nil
+}
+
+// Deref follows the local "#/..." $refs of n within root and returns the
+// node they lead to: n itself when it has no $ref, nil when a ref is not
+// local, does not resolve or the chain is too long.
+func Deref(root, n *yaml.Node) *yaml.Node {
+    for i := 0; i < maxRefs && n != nil; i++ {
+        ref := GetKey(n, "$ref")
+        if ref == nil { return n }
+        ptr, ok := strings.CutPrefix(ref.Value, "#")
+        if !ok { return nil }
+        n, _ = Lookup(root, ptr)
+    }
+    return nil
+}
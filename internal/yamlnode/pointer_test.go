@@ -0,0 +1,48 @@
+package yamlnode
+
+import (
+    "reflect"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+const pointerDoc = `components:
+  schemas:
+    a/b~c: {type: string}
+    Alias: {$ref: '#/components/schemas/a~1b~0c'}
+    Loop: {$ref: '#/components/schemas/Loop'}
+    Remote: {$ref: 'https://example.com/x.yaml'}
+    Missing: {$ref: '#/components/schemas/Nope'}
+    Map: {additionalProperties: true}
+    List: {items: {}}
+    Union: {type: [string, 'null'], items: {}}
+`
+
+func TestDeref(t *testing.T) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(pointerDoc), &doc); err != nil { t.Fatal(err) }
+    root := DocRoot(&doc)
+    schemas, err := Lookup(root, "/components/schemas")
+    if err != nil { t.Fatal(err) }
+    tests := []struct {
+        name  string
+        types []string // of the dereferenced schema; nil when it does not resolve
+    }{
+        {"a/b~c", []string{"string"}},
+        {"Alias", []string{"string"}},
+        {"Loop", nil},
+        {"Remote", nil},
+        {"Missing", nil},
+        {"Map", []string{"object"}},
+        {"List", []string{"array"}},
+        {"Union", []string{"string", "null"}},
+    }
+    for _, tt := range tests {
+        n := Deref(root, GetKey(schemas, tt.name))
+        if (n == nil) != (tt.types == nil) { t.Errorf("Deref(%s) = %v", tt.name, n); continue }
+        if got := SchemaTypes(n); n != nil && !reflect.DeepEqual(got, tt.types) { t.Errorf("SchemaTypes(%s) = %v, want %v", tt.name, got, tt.types) }
+    }
+    if _, err := Lookup(root, "/components/schemas/"+EscapeToken("a/b~c")); err != nil { t.Errorf("escaped lookup: %v", err) }
+    if _, err := Lookup(root, "components"); err == nil { t.Error("pointer without a leading / was accepted") }
+}
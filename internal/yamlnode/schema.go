@@ -0,0 +1,30 @@
+package yamlnode
+
+import "gopkg.in/yaml.v3"
+
+// DeclaredTypes is the type (or 3.1 type list) of schema, or nil.
+func DeclaredTypes(schema *yaml.Node) []string {
+    t := GetKey(schema, "type")
+    switch {
+    case t == nil:
+        return nil
+    case t.Kind == yaml.SequenceNode:
+        var out []string
+        for _, c := range t.Content { out = append(out, c.Value) }
+        return out
+    }
+    return []string{t.Value}
+}
+
+// SchemaTypes is the declared type of schema, else the type its keywords
+// imply: object for properties or additionalProperties, array for items.
+func SchemaTypes(schema *yaml.Node) []string {
+    if GetKey(schema, "type") != nil { return DeclaredTypes(schema) }
+    switch {
+    case GetKey(schema, "properties") != nil, GetKey(schema, "additionalProperties") != nil:
+        return []string{"object"}
+    case GetKey(schema, "items") != nil:
+        return []string{"array"}
+    }
+    return nil
+}
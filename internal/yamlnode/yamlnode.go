@@ -0,0 +1,134 @@
+// Package yamlnode has small helpers for building and inspecting yaml.v3 node
+// trees with a stable key order.
+package yamlnode
+
+import (
+    "bytes"
+    "os"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+func Map() *yaml.Node { return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"} }
+
+func Seq() *yaml.Node { return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"} }
+
+func Str(s string) *yaml.Node {
+    return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+func Bool(b bool) *yaml.Node {
+    return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}
+}
+
+func Int(i int) *yaml.Node {
+    return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(i)}
+}
+
+// SetKey sets key to val in mapping m, replacing an existing entry in place.
+func SetKey(m *yaml.Node, key string, val *yaml.Node) {
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if m.Content[i].Value == key {
+            m.Content[i+1] = val
+            return
+        }
+    }
+    m.Content = append(m.Content, Str(key), val)
+}
+
+// DeleteKey removes key and its value from mapping m, if present.
+func DeleteKey(m *yaml.Node, key string) {
+    if m == nil || m.Kind != yaml.MappingNode { return }
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if m.Content[i].Value == key {
+            m.Content = append(m.Content[:i], m.Content[i+2:]...)
+            return
+        }
+    }
+}
+
+// GetKey returns the value for key in mapping m, or nil.
+func GetKey(m *yaml.Node, key string) *yaml.Node {
+    if m == nil || m.Kind != yaml.MappingNode { return nil }
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if m.Content[i].Value == key {
+            return m.Content[i+1]
+        }
+    }
+    return nil
+}
+
+// Key renders s as a mapping key, quoting and escaping it when a plain
+// scalar would be misread (e.g. "a: b", "#x", "1e3", "true").
+func Key(s string) string {
+    b, err := yaml.Marshal(Str(s))
+    if err != nil { return strconv.Quote(s) }
+    return strings.TrimSuffix(string(b), "\n")
+}
+
+// DocRoot returns the top-level node of a parsed document, or nil.
+func DocRoot(doc *yaml.Node) *yaml.Node {
+    if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 { return nil }
+    return doc.Content[0]
+}
+
+// Clone returns a deep copy of n. Aliases in the copy point to the copies
+// of their anchors.
+func Clone(n *yaml.Node) *yaml.Node {
+    return clone(n, map[*yaml.Node]*yaml.Node{})
+}
+
+func clone(n *yaml.Node, seen map[*yaml.Node]*yaml.Node) *yaml.Node {
+    if n == nil { return nil }
+    if c, ok := seen[n]; ok { return c }
+    c := *n
+    seen[n] = &c
+    if n.Content != nil {
+        c.Content = make([]*yaml.Node, len(n.Content))
+        for i, child := range n.Content { c.Content[i] = clone(child, seen) }
+    }
+    c.Alias = clone(n.Alias, seen)
+    return &c
+}
+
+// KindName describes a node's kind for messages ("a sequence", "a mapping", ...).
+func KindName(n *yaml.Node) string {
+    switch n.Kind {
+    case yaml.SequenceNode:
+        return "a sequence"
+    case yaml.ScalarNode:
+        return "a scalar"
+    case yaml.AliasNode:
+        return "an alias"
+    default:
+        return "a mapping"
+    }
+}
+
+// BlockStyle clears flow and quoting styles below n, so a tree parsed from
+// JSON marshals as ordinary block YAML. Strings that would otherwise read as
+// another type are still quoted by the encoder.
+func BlockStyle(n *yaml.Node) {
+    if n == nil { return }
+    if n.Kind != yaml.ScalarNode || n.Tag == "!!str" { n.Style = 0 }
+    for _, c := range n.Content {
+        BlockStyle(c)
+    }
+}
+
+func Marshal(n *yaml.Node) ([]byte, error) {
+    var buf bytes.Buffer
+    enc := yaml.NewEncoder(&buf)
+    enc.SetIndent(2)
+    if err := enc.Encode(n); err != nil { return nil, err }
+    if err := enc.Close(); err != nil { return nil, err }
+    return buf.Bytes(), nil
+}
+
+func Write(path string, n *yaml.Node) error {
+    b, err := Marshal(n)
+    if err != nil { return err }
+    return os.WriteFile(path, b, 0o644)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "strings"
+)
+
+// maxDiffCells bounds the LCS table; larger changes are summarized instead.
+const maxDiffCells = 4 << 20
+
+// printLineDiff writes a minimal line diff of a and b ("-" removed, "+" added)
+// after trimming their common prefix and suffix.
+func printLineDiff(w io.Writer, nameA, nameB, a, b string) {
+    la, lb := splitLines(a), splitLines(b)
+    pre := 0
+    for pre < len(la) && pre < len(lb) && la[pre] == lb[pre] { pre++ }
+    suf := 0
+    for suf < len(la)-pre && suf < len(lb)-pre && la[len(la)-1-suf] == lb[len(lb)-1-suf] { suf++ }
+    ma, mb := la[pre:len(la)-suf], lb[pre:len(lb)-suf]
+
+    fmt.Fprintf(w, "--- %s\n+++ %s\n@@ line %d @@\n", nameA, nameB, pre+1)
+    if len(ma)*len(mb) > maxDiffCells {
+        fmt.Fprintf(w, "(%d lines removed, %d lines added; too large to diff)\n", len(ma), len(mb))
+        return
+    }
+    // lcs[i][j] is the LCS length of ma[i:] and mb[j:].
+    lcs := make([][]int, len(ma)+1)
+    for i := range lcs { lcs[i] = make([]int, len(mb)+1) }
+    for i := len(ma) - 1; i >= 0; i-- {
+        for j := len(mb) - 1; j >= 0; j-- {
+            if ma[i] == mb[j] {
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            } else if lcs[i+1][j] >= lcs[i][j+1] {
+                lcs[i][j] = lcs[i+1][j]
+            } else {
+                lcs[i][j] = lcs[i][j+1]
+            }
+        }
+    }
+    i, j := 0, 0
+    for i < len(ma) || j < len(mb) {
+        switch {
+        case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+            fmt.Fprintf(w, " %s\n", ma[i])
+            i++
+            j++
+        case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+            fmt.Fprintf(w, "-%s\n", ma[i])
+            i++
+        default:
+            fmt.Fprintf(w, "+%s\n", mb[j])
+            j++
+        }
+    }
+}
+
+func splitLines(s string) []string {
+    if s == "" { return nil }
+    return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
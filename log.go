@@ -0,0 +1,130 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "log/slog"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Build steps, external commands and their timings are logged through
+// logger: readable lines by default (info on stdout, warnings and errors on
+// stderr), or JSON objects on stderr with --log-format json. --verbose adds
+// the debug lines (commands and timings), --quiet keeps only warnings and
+// errors.
+
+var logger = slog.New(newTextHandler(slog.LevelInfo))
+
+// Log formats (--log-format).
+const (
+    logFormatText = "text"
+    logFormatJSON = "json"
+)
+
+// configureLogging sets up logger from the logging options.
+func configureLogging(format string, verbose, quiet bool) error {
+    if verbose && quiet { return fmt.Errorf("--verbose and --quiet cannot be combined") }
+    level := slog.LevelInfo
+    if verbose { level = slog.LevelDebug }
+    if quiet { level = slog.LevelWarn }
+    switch format {
+    case "", logFormatText:
+        logger = slog.New(newTextHandler(level))
+    case logFormatJSON:
+        logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+    default:
+        return fmt.Errorf("invalid --log-format %q (expected text or json)", format)
+    }
+    return nil
+}
+
+// discoveryLogger returns the logger the indexer reports discovery warnings
+// through: logger, dropping the repeats of a record, as discovery runs once
+// per phase. watch and serve take a new one for every build, so each build
+// reports its warnings again.
+func discoveryLogger() *slog.Logger { return slog.New(&onceHandler{seen: &sync.Map{}}) }
+
+// onceHandler passes each distinct record on to logger's handler once.
+type onceHandler struct {
+    seen  *sync.Map
+    attrs []slog.Attr
+}
+
+func (h *onceHandler) Enabled(ctx context.Context, l slog.Level) bool { return logger.Handler().Enabled(ctx, l) }
+
+func (h *onceHandler) Handle(ctx context.Context, r slog.Record) error {
+    key := r.Level.String() + " " + r.Message
+    r.Attrs(func(a slog.Attr) bool { key += " " + a.String(); return true })
+    if _, dup := h.seen.LoadOrStore(key, true); dup { return nil }
+    return logger.Handler().WithAttrs(h.attrs).Handle(ctx, r)
+}
+
+func (h *onceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    return &onceHandler{seen: h.seen, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+// WithGroup is not used: groups are flattened into plain attributes.
+func (h *onceHandler) WithGroup(string) slog.Handler { return h }
+
+// logTimed runs fn, logging how long the build step took at debug level.
+func logTimed(step string, fn func() error) error {
+    start := time.Now()
+    err := fn()
+    logger.Debug("Step finished", "step", step, "duration", time.Since(start).Round(time.Millisecond), "ok", err == nil)
+    return err
+}
+
+// textHandler writes a record as its message, then ": " and the path or
+// command attribute, then the other attributes as key=value. Warnings are
+// prefixed with "warning: ".
+type textHandler struct {
+    level  slog.Leveler
+    attrs  []slog.Attr
+    mu     *sync.Mutex
+    stdout io.Writer
+    stderr io.Writer
+}
+
+func newTextHandler(level slog.Leveler) *textHandler {
+    return &textHandler{level: level, mu: &sync.Mutex{}, stdout: os.Stdout, stderr: os.Stderr}
+}
+
+func (h *textHandler) Enabled(_ context.Context, l slog.Level) bool { return l >= h.level.Level() }
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+    var b strings.Builder
+    if r.Level == slog.LevelWarn { b.WriteString("warning: ") }
+    b.WriteString(r.Message)
+    attrs := append([]slog.Attr{}, h.attrs...)
+    r.Attrs(func(a slog.Attr) bool { attrs = append(attrs, a); return true })
+    for _, a := range attrs {
+        if a.Key == "path" || a.Key == "command" { b.WriteString(": " + a.Value.String()) }
+    }
+    for _, a := range attrs {
+        if a.Key == "path" || a.Key == "command" { continue }
+        v := a.Value.String()
+        if v == "" || strings.ContainsAny(v, " \t\n\"") { v = strconv.Quote(v) }
+        b.WriteString(" " + a.Key + "=" + v)
+    }
+    b.WriteString("\n")
+    w := h.stdout
+    if r.Level >= slog.LevelWarn { w = h.stderr }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    h2 := *h
+    h2.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+    return &h2
+}
+
+// WithGroup is not used: groups are flattened into plain attributes.
+func (h *textHandler) WithGroup(string) slog.Handler { return h }
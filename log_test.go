@@ -0,0 +1,77 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// captureJSONLog points logger at a JSON handler writing to the returned
+// buffer for the rest of the test.
+func captureJSONLog(t *testing.T) *bytes.Buffer {
+    var buf bytes.Buffer
+    old := logger
+    logger = slog.New(slog.NewJSONHandler(&buf, nil))
+    t.Cleanup(func() { logger = old })
+    return &buf
+}
+
+// jsonRecords decodes one log record per line, failing on any other output.
+func jsonRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+    var recs []map[string]any
+    for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+        if line == "" { continue }
+        var rec map[string]any
+        if err := json.Unmarshal([]byte(line), &rec); err != nil { t.Fatalf("not a JSON log record: %q", line) }
+        recs = append(recs, rec)
+    }
+    buf.Reset()
+    return recs
+}
+
+func TestDiscoveryWarningsAreLogRecords(t *testing.T) {
+    dir := t.TempDir()
+    paths := filepath.Join(dir, "paths", "v1")
+    if err := os.MkdirAll(paths, 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(paths, "users.yaml"), []byte("get: {}\n"), 0o644); err != nil { t.Fatal(err) }
+    if err := os.Symlink("users.yaml", filepath.Join(paths, "people.yaml")); err != nil { t.Skip("symlinks unavailable:", err) }
+    buf := captureJSONLog(t)
+
+    cfg := indexer.NewConfig(dir, "", "")
+    cfg.Logger = discoveryLogger()
+    for i := 0; i < 2; i++ { // every phase lists the fragments
+        if _, err := indexer.ListFragments(cfg, cfg.PathsDir); err != nil { t.Fatal(err) }
+    }
+    recs := jsonRecords(t, buf)
+    if len(recs) != 1 || recs[0]["level"] != "WARN" || !strings.Contains(recs[0]["msg"].(string), "skipping symlink") {
+        t.Fatalf("records = %v, want one symlink warning", recs)
+    }
+
+    cfg.Logger = discoveryLogger() // the next build reports it again
+    if _, err := indexer.ListFragments(cfg, cfg.PathsDir); err != nil { t.Fatal(err) }
+    if recs := jsonRecords(t, buf); len(recs) != 1 {
+        t.Fatalf("records after a rebuild = %v, want the symlink warning again", recs)
+    }
+}
+
+func TestProblemsAreLogRecords(t *testing.T) {
+    buf := captureJSONLog(t)
+    printProblems(indexer.FragmentErrors{
+        {File: "paths/v1/users.yaml", Line: 3, Message: "bad indentation"},
+        {File: "components/schemas/User.yaml", Message: "not a mapping"},
+    })
+    recs := jsonRecords(t, buf)
+    if len(recs) != 2 { t.Fatalf("records = %v, want 2", recs) }
+    for _, rec := range recs {
+        if rec["level"] != "ERROR" { t.Errorf("record %v: want level ERROR", rec) }
+    }
+    if msg := recs[0]["msg"]; !strings.Contains(msg.(string), "paths/v1/users.yaml") || !strings.Contains(msg.(string), "bad indentation") {
+        t.Errorf("msg = %q, want the file and the problem", msg)
+    }
+}
@@ -1,110 +1,302 @@
 package main
 
 import (
-    "bufio"
+    "bytes"
     "errors"
     "flag"
     "fmt"
-    "io/ioutil"
+    "io"
     "os"
     "os/exec"
     "path/filepath"
-    "regexp"
+    "runtime"
+    "slices"
     "sort"
+    "strconv"
     "strings"
+    "time"
 
     "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/audience"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/dialect"
+    "github.com/bilbo290/oas-indexer/pkg/docs"
+    "github.com/bilbo290/oas-indexer/pkg/examples"
+    "github.com/bilbo290/oas-indexer/pkg/gogen"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+    "github.com/bilbo290/oas-indexer/pkg/overlay"
+    "github.com/bilbo290/oas-indexer/pkg/remote"
+    "github.com/bilbo290/oas-indexer/pkg/tsgen"
+    "github.com/bilbo290/oas-indexer/pkg/validate"
+    "github.com/bilbo290/oas-indexer/pkg/vars"
 )
 
+// Config is the indexer configuration plus the CLI's generator, bundle, docs
+// and validation settings.
 type Config struct {
-    Cwd        string
-    InputDir   string
-    OutputDir  string
-    RootFile   string
-    RootPath   string
-    PathsDir   string
-    SchemasDir string
-    ParamsDir  string
+    indexer.Config
 
     OutputTS string
     OutputGo string
     Redocly  string // html output path (docs)
+    DocsRenderer string // auto (default), redocly or native
 
-    // Redocly bundle
+    // Bundle
     BundleOut     string
+    Bundler       string // auto (default), redocly or native
     RedoclyConfig string
+    Audience      string // if set, the bundle and docs keep only what is meant for this audience (see package audience)
+    FillExamples  bool   // generate the bundle's missing examples (examples --fill --bundle-only)
 
     // Optional: generator overrides
     TSGenerator string // e.g. typescript-fetch
+    TSEngine    string // auto (default), external or native
+    TSRuntime   string // zod or typebox: also write runtime schemas next to the TS types
+    GoEngine    string // auto (default), external or native
     GoGenerator string // e.g. go
-
-    // Behavior
-    Join bool // if true, write joined/inlined root; default false = reference-style
+    TSTemplateDir string // openapi-generator template dir (-t) for TypeScript
+    GoTemplateDir string // openapi-generator template dir (-t) for Go
+    OapiCodegenConfig string // oapi-codegen configuration file (-config)
+    GoGenerate    string // oapi-codegen -generate targets (default: types,client,server)
+    AdditionalProperties string // openapi-generator --additional-properties for TypeScript and Go, as key=val,...
 
     // Validation
     ValidatePreset   string // validation preset to use
     SkipValidation   bool   // skip validation entirely
     ValidateStopOnError bool // stop on first validation error
+    RuleOverrides    map[string]string // rule name -> severity, from --rules
+    Baseline         string // accepted findings file (--validate-baseline)
+    UpdateBaseline   bool   // rewrite Baseline from the current findings
+    ValidateFormat   string // validation report format (validate.ReportText, ReportJSON or ReportJUnit)
+    ValidateReport   string // file for the json/junit report; empty means stdout
+    Structural       bool   // check the built root with validate.Structural
+    Examples         bool   // check fragment examples against their schemas with validate.Examples
+    Cycles           string // cyclesWarn, cyclesError or cyclesOff
+
+    Generators []externalGenerator // openapi-generator targets beyond TS and Go (--gen)
+    GenArgs    []string            // extra openapi-generator arguments for every generator (--gen-args)
+
+    ToolPins   map[string]string // tool name -> version constraint (--tools)
+    CheckTools bool              // fail before running when a required tool is missing or off its pin
+
+    Deps []dependency // git dependencies vendored into the input tree before indexing
+    API  string       // name under the config file's apis section; "" outside a workspace
+
+    DryRun     bool // print the planned writes and diffs instead of writing or running external tools
+    Force      bool // run the external tools even when the build cache says their outputs are up to date
+    PerVersion bool // build a root (and bundle, docs and code) per version directory below paths/
 }
 
-func envOrDefault(key, def string) string {
-    v := strings.TrimSpace(os.Getenv(key))
-    if v != "" {
-        return v
+// index returns the indexer configuration, excluding this run's other
+// outputs from fragment discovery.
+func (cfg *Config) index() *indexer.Config {
+    cfg.Exclude = nil
+    for _, p := range cfg.outputs() {
+        if strings.TrimSpace(p) != "" {
+            cfg.Exclude = append(cfg.Exclude, absJoin(cfg.Cwd, p))
+        }
     }
-    return def
+    return &cfg.Config
 }
 
-func buildConfig() (*Config, error) {
-    var (
-        inputDirFlag  = flag.String("input", "", "[required] Source OpenAPI fragments directory")
-        inputDirFlagS = flag.String("i", "", "Shorthand for --input")
-        outputDirFlag = flag.String("output", "", "[required] Destination directory for the generated root file")
-        outputDirFlagS= flag.String("o", "", "Shorthand for --output")
-        rootFileFlag  = flag.String("root", "", "Name of the aggregated root file (default: root.yaml)")
-        rootFileFlagS = flag.String("r", "", "Shorthand for --root")
+// outputs returns the files and directories the generators, bundle and docs write.
+func (cfg *Config) outputs() []string {
+    out := []string{cfg.BundleOut, cfg.Redocly, cfg.OutputTS, cfg.OutputGo}
+    for _, g := range cfg.Generators { out = append(out, g.Out) }
+    return out
+}
 
-        outputTS      = flag.String("output-ts", "", "If set, generate TypeScript output using an installed OpenAPI tool to this path")
-        outputGo      = flag.String("output-go", "", "If set, generate Go output using an installed OpenAPI tool to this path")
-        redoclyOut    = flag.String("redocly", "", "If set, generate HTML docs using installed 'redocly' CLI to this file")
-        bundleOut     = flag.String("bundle", "", "If set, bundle the spec using Redocly CLI to this YAML file")
-        redoclyCfg    = flag.String("redocly-config", "", "Optional Redocly configuration file path (default: ./redocly.yaml if present)")
+// optionFlags holds the option flags shared by the flat CLI and every subcommand.
+type optionFlags struct {
+    inputDir, inputDirS, outputDir, outputDirS, rootFile, rootFileS, asyncapiFile *string
+    outputTS, outputGo, redoclyOut, bundleOut, redoclyCfg             *string
+    tsGen, goGen, tsTemplateDir, goTemplateDir, additionalProperties, oapiCodegenConfig, goGenerate, toolPins, toolTimeout, logFormat, audience, mergeKeys, pathCasing, pathVersion, pathStripPrefix, componentNaming, nameMap, namespaceSeparator, sharedComponents, vendorDir, validatePreset, validateRules, ruleOverrides, validateBaseline, validateFormat, validateReport, cycles, openapiVersion, configFile, format, bundler, docsRenderer, tsEngine, tsRuntime, goEngine, api, varsFile, standardResponses *string
+    joinOutput, perVersion, followLinks, offline, methodFiles, allDo, skipValidation, validateStopOnError, updateBaseline, structural, examples, prune, strict, downconvert, expandVars, autoTags, useDocker, checkTools, verbose, quiet, dryRun, force *bool
+    expandTabs *int
+    pathRewrites *stringList
+    deps         *stringList
+    overlays     *stringList
+    gens         *stringList
+    includes     *stringList
+    excludes     *stringList
+    genArgs      *string
+}
 
-        tsGen         = flag.String("ts-generator", envOrDefault("TS_GENERATOR", "typescript-fetch"), "Generator name for OpenAPI generator when producing TS (default: typescript-fetch)")
-        goGen         = flag.String("go-generator", envOrDefault("GO_GENERATOR", "go"), "Generator name for OpenAPI generator when producing Go (default: go)")
+// stringList is a repeatable string flag; a value spanning several lines (as
+// set from a config file or environment variable) adds one entry per line.
+type stringList []string
 
-        joinOutput    = flag.Bool("join", false, "Write joined/inlined root instead of reference-style")
-        allDo         = flag.Bool("all", false, "Bundle to dist/openapi.yaml and build HTML to dist/index.html (uses --redocly-config if present)")
+func (l *stringList) String() string { return strings.Join(*l, "\n") }
+
+func (l *stringList) Set(s string) error {
+    for _, line := range strings.Split(s, "\n") {
+        if strings.TrimSpace(line) != "" { *l = append(*l, line) }
+    }
+    return nil
+}
+
+func registerOptionFlags(fs *flag.FlagSet) *optionFlags {
+    rewrites := &stringList{}
+    fs.Var(rewrites, "path-rewrite", "Regexp rewrite applied to derived path keys, as pattern=>replacement (repeatable, applied in order)")
+    deps := &stringList{}
+    fs.Var(deps, "dep", "Git dependency vendored into the input dir before indexing, as git=<url>,rev=<rev>[,path=<dir>][,into=<dir>] (repeatable)")
+    overlays := &stringList{}
+    fs.Var(overlays, "overlay", "OpenAPI Overlay 1.0 document applied to the joined root, or else to the bundle and docs (repeatable, applied in order)")
+    gens := &stringList{}
+    fs.Var(gens, "gen", "Run an openapi-generator target on the root, as <generator>:<output dir>, e.g. kotlin:clients/kotlin (repeatable)")
+    includes := &stringList{}
+    fs.Var(includes, "include", "Only index fragment files matching this glob, relative to the input dir, e.g. 'paths/v2/**' (repeatable)")
+    excludes := &stringList{}
+    fs.Var(excludes, "exclude", "Leave fragment files and directories matching this glob out, as a .oasignore line, e.g. 'fixtures/' or '*.wip.yaml' (repeatable)")
+    return &optionFlags{
+        pathRewrites: rewrites,
+        deps:         deps,
+        overlays:     overlays,
+        gens:         gens,
+        includes:     includes,
+        excludes:     excludes,
+        genArgs:      fs.String("gen-args", "", "Extra openapi-generator arguments for every --gen target, e.g. \"--additional-properties=packageName=api\""),
+        inputDir:   fs.String("input", "", "[required] Source OpenAPI fragments directory"),
+        inputDirS:  fs.String("i", "", "Shorthand for --input"),
+        outputDir:  fs.String("output", "", "[required] Destination directory for the generated root file"),
+        outputDirS: fs.String("o", "", "Shorthand for --output"),
+        rootFile:   fs.String("root", "", "Name of the aggregated root file (default: root.yaml)"),
+        rootFileS:  fs.String("r", "", "Shorthand for --root"),
+        format:     fs.String("format", "", "Format of the root file and default bundle: yaml or json (default: from the --root extension, else yaml)"),
+        asyncapiFile: fs.String("asyncapi", "", "Name of the AsyncAPI document built from <input>/events in the output dir (default: asyncapi.yaml, or asyncapi.json for JSON roots)"),
+
+        outputTS:   fs.String("output-ts", "", "If set, generate TypeScript output to this path (an installed OpenAPI tool, else built-in types)"),
+        outputGo:   fs.String("output-go", "", "If set, generate Go output to this path (an installed OpenAPI tool, else built-in types and client)"),
+        redoclyOut: fs.String("redocly", "", "If set, generate HTML docs using installed 'redocly' CLI to this file"),
+        bundleOut:  fs.String("bundle", "", "If set, bundle the spec using Redocly CLI to this file (.json bundles are written as JSON)"),
+        bundler:    fs.String("bundler", engineAuto, "Bundler for --bundle: auto (Redocly CLI when installed, else native), redocly or native"),
+        docsRenderer: fs.String("docs-renderer", engineAuto, "Renderer for --redocly docs: auto (Redocly CLI or redoc-cli when installed, else native), redocly or native"),
+        audience:   fs.String("audience", "", "Filter the bundle and docs for this audience (e.g. public): drop operations, parameters, properties and components whose x-audience (or x-internal: true) excludes it"),
+        varsFile:   fs.String("vars", "", "YAML file of NAME: value pairs for ${NAME} placeholders in fragment values (implies --expand-vars)"),
+        expandVars: fs.Bool("expand-vars", false, "Expand ${NAME} (or ${NAME:-default}) placeholders in string values of joined roots, bundles and docs from --vars, then the environment"),
+        redoclyCfg: fs.String("redocly-config", "", "Optional Redocly configuration file path (default: ./redocly.yaml if present)"),
+
+        tsGen:      fs.String("ts-generator", "typescript-fetch", "Generator name for OpenAPI generator when producing TS (default: typescript-fetch)"),
+        tsRuntime:  fs.String("output-ts-runtime", "", "With --output-ts, also write runtime validation schemas for the components: zod or typebox"),
+        tsEngine:   fs.String("ts-engine", engineAuto, "Generator for --output-ts: auto (an installed OpenAPI tool, else native), external or native (built-in types.ts emitter)"),
+        goGen:      fs.String("go-generator", "go", "Generator name for OpenAPI generator when producing Go (default: go)"),
+        tsTemplateDir: fs.String("ts-template-dir", "", "Custom openapi-generator (mustache) templates for TypeScript"),
+        goTemplateDir: fs.String("go-template-dir", "", "Custom openapi-generator (mustache) templates for Go"),
+        oapiCodegenConfig: fs.String("oapi-codegen-config", "", "oapi-codegen configuration file for the Go output (its generate and package settings apply)"),
+        goGenerate: fs.String("go-generate", "", "oapi-codegen targets for the Go output, e.g. types,chi-server (default: types,client,server)"),
+        additionalProperties: fs.String("additional-properties", "", "openapi-generator additional properties for TypeScript and Go, as key=val,..."),
+        goEngine:   fs.String("go-engine", engineAuto, "Generator for --output-go: auto (an installed OpenAPI tool, else native), external or native (built-in types and client)"),
+
+        joinOutput:  fs.Bool("join", false, "Write joined/inlined root instead of reference-style"),
+        perVersion:  fs.Bool("per-version", false, "Build a root per version directory below paths/ (root.v1.yaml, root.v2.yaml), each with only the components its paths reach; bundles and docs are named alike, generated code goes to a v1/ subdirectory"),
+        mergeKeys:   fs.String("merge-keys", indexer.MergeKeysResolve, "Join mode handling of YAML merge keys and aliases: resolve or preserve"),
+        followLinks: fs.Bool("follow-symlinks", false, "Follow symlinked fragment files and directories (with cycle protection)"),
+        pathCasing:  fs.String("path-casing", indexer.PathCasingCamel, "Casing of derived path keys: camel, kebab or preserve"),
+        pathVersion: fs.String("path-version", indexer.PathVersionKeep, "First directory below paths/ in derived path keys: keep or strip"),
+        pathStripPrefix: fs.String("path-strip-prefix", "", "Directories below paths/ to leave out of derived path keys, e.g. internal/api"),
+        componentNaming: fs.String("component-naming", indexer.ComponentNamingPascal, "Component names derived from file names: pascal, camel or verbatim"),
+        nameMap:     fs.String("name-map", "", "Explicit component names as file=Name,... (files relative to --input, e.g. components/schemas/oauth-2-token.yaml=OAuth2Token)"),
+        namespaceSeparator: fs.String("namespace-separator", "", "Separator between subdirectory and file names in component names (components/schemas/billing/invoice.yaml: \"\" gives BillingInvoice, \".\" Billing.Invoice)"),
+        sharedComponents: fs.String("shared-components", "", "Components directory shared with other APIs (holding schemas/, parameters/, ...); the shared components this API references are added to its root"),
+        vendorDir:   fs.String("vendor-dir", "", "Directory http(s) $refs are vendored into (default: <input>/"+remote.DefaultDir+")"),
+        toolPins:    fs.String("tools", "", "Version constraints for external tools, as tool=constraint,... (e.g. redocly=>=1.25,openapi-generator=7.x); see the doctor command"),
+        checkTools:  fs.Bool("check-tools", false, "Fail before running when a required external tool is missing or does not meet its --tools pin"),
+        dryRun:      fs.Bool("dry-run", false, "Print the files a run would create or overwrite, with diffs of the root and bundle, without writing anything or running external tools"),
+        force:       fs.Bool("force", false, "Run code generators, Redocly and redoc-cli even when the build cache says their outputs are up to date"),
+        verbose:     fs.Bool("verbose", false, "Also log external commands and step timings"),
+        quiet:       fs.Bool("quiet", false, "Log only warnings and errors"),
+        logFormat:   fs.String("log-format", "", "Log format: text (default) or json (one object per line on stderr)"),
+        toolTimeout: fs.String("tool-timeout", "", "Kill an external tool running longer than this, e.g. 90s or 20m; 0 for no limit (default: 10m)"),
+        useDocker:   fs.Bool("use-docker", false, "Run redocly and openapi-generator from their official docker images when they are not installed"),
+        offline:     fs.Bool("offline", false, "Never fetch http(s) $refs; fail when a referenced document is not vendored yet"),
+        autoTags:    fs.Bool("auto-tags", false, "Tag untagged operations after their directory below the version directory (paths/v1/accounts/ gives Accounts), described by an optional "+indexer.DirTagFile+" there, and list the tags in the root"),
+        standardResponses: fs.String("standard-responses", "", "Responses added to every operation without that status code, as code=Name,... naming components/responses entries (e.g. 401=Unauthorized,500=InternalError)"),
+        methodFiles: fs.Bool("method-files", false, "Treat paths/<path>/<method>.yaml (get.yaml, post.yaml, ...) as one operation of <path>"),
+        downconvert: fs.Bool("downconvert", false, "Accept OpenAPI 3.1 schema keywords in fragments (type arrays, const, examples) and convert them in joined roots and bundles"),
+        openapiVersion: fs.String("openapi-version", indexer.OpenAPI30, "OpenAPI version of the root: 3.0 or 3.1 (3.1 converts nullable and boolean exclusive bounds in joined roots and bundles)"),
+        allDo:       fs.Bool("all", false, "Bundle to dist/openapi.yaml (.json with --format json) and build HTML to dist/index.html (uses --redocly-config if present)"),
 
         // Validation flags
-        validatePreset = flag.String("validate", "", "Run validation with specified preset (google, restful)")
-        skipValidation = flag.Bool("skip-validation", false, "Skip validation entirely")
-        validateStopOnError = flag.Bool("validate-stop-on-error", false, "Stop on first validation error")
-        listPresets    = flag.Bool("list-presets", false, "List available validation presets")
-    )
+        validatePreset:      fs.String("validate", "", "Run validation with specified preset (google, restful)"),
+        ruleOverrides:       fs.String("rules", "", "Per-rule severity overrides, e.g. collection-names-plural=warning,path-case-kebab=off (error, warning, info or off)"),
+        validateBaseline:    fs.String("validate-baseline", "", "JSON file of accepted findings; only findings not in it fail validation"),
+        updateBaseline:      fs.Bool("update-baseline", false, "Record the current findings in --validate-baseline instead of failing"),
+        validateRules:       fs.String("validate-rules", "", "YAML file of custom validation rules, added as a preset (default preset for --validate)"),
+        validateFormat:      fs.String("validate-format", validate.ReportText, "Validation report format: text, json or junit"),
+        validateReport:      fs.String("validate-report", "", "Write the json/junit validation report to this file (default: stdout, with progress on stderr)"),
+        structural:          fs.Bool("structural", false, "Check the generated root against the OpenAPI 3.0 specification after building it"),
+        examples:            fs.Bool("validate-examples", false, "Check every example in the fragments against its schema (types, required properties, enums)"),
+        skipValidation:      fs.Bool("skip-validation", false, "Skip validation entirely"),
+        validateStopOnError: fs.Bool("validate-stop-on-error", false, "Stop on first validation error"),
+
+        cycles:     fs.String("cycles", cyclesWarn, "Circular $refs between components: warn, error or off"),
+        prune:      fs.Bool("prune", false, "Leave components no path fragment references out of the root (and so the bundle)"),
+        strict:     fs.Bool("strict", false, "Fail when a fragment's structure does not match its kind (path item, schema, parameter), or on files outside the layout (non-fragment files, unknown directories, unversioned paths)"),
+        expandTabs: fs.Int("expand-tabs", 0, "Replace tab indentation in fragments with N spaces instead of failing"),
+
+        configFile: fs.String("config", "", "Config file with default option values (default: ./.oas-indexer.yaml if present)"),
+        api:        fs.String("api", "", "Only build this API of the config file's apis section (default: all of them)"),
+    }
+}
+
+func buildConfigs() ([]*Config, error) {
+    opts := registerOptionFlags(flag.CommandLine)
+    listPresets := flag.Bool("list-presets", false, "List available validation presets")
 
     flag.Usage = func() {
         fmt.Fprintf(os.Stderr, "sync-openapi\n\n")
-        fmt.Fprintf(os.Stderr, "Usage:\n  sync-openapi --input <dir> [--output <dir>] [--root <file>] [--bundle <yaml>] [--redocly <html>] [--all] [--validate <preset>]\n\n")
+        fmt.Fprintf(os.Stderr, "Usage:\n  sync-openapi --input <dir> [--output <dir>] [--root <file>] [--bundle <yaml>] [--redocly <html>] [--all] [--validate <preset>]\n")
+        fmt.Fprintf(os.Stderr, "  sync-openapi <command> [options]\n\n")
         fmt.Fprintf(os.Stderr, "Options:\n")
+        fmt.Fprintf(os.Stderr, "      --config <file>    Config file with option defaults (default: ./.oas-indexer.yaml if present)\n")
         fmt.Fprintf(os.Stderr, "  -i, --input <dir>      [required] Source OpenAPI fragments directory\n")
         fmt.Fprintf(os.Stderr, "  -o, --output <dir>     Destination dir for root file (default: same as --input)\n")
         fmt.Fprintf(os.Stderr, "  -r, --root <file>      Name of the aggregated root file (default: root.yaml)\n")
-        fmt.Fprintf(os.Stderr, "      --output-ts <p>    Generate TypeScript output using installed OpenAPI tool to the given path\n")
-        fmt.Fprintf(os.Stderr, "      --output-go <p>    Generate Go output using installed OpenAPI tool to the given path\n")
+        fmt.Fprintf(os.Stderr, "      --asyncapi <file>  Name of the AsyncAPI document built from <input>/events (default: asyncapi.yaml)\n")
+        fmt.Fprintf(os.Stderr, "      --output-ts <p>    Generate TypeScript output to the given path (installed OpenAPI tool, else built-in types)\n")
+        fmt.Fprintf(os.Stderr, "      --output-go <p>    Generate Go output to the given path (installed OpenAPI tool, else built-in types and client)\n")
         fmt.Fprintf(os.Stderr, "      --redocly <html>   Generate HTML docs using installed Redocly CLI to this file\n")
         fmt.Fprintf(os.Stderr, "      --ts-generator <g> Generator for TypeScript when using openapi-generator (default: typescript-fetch)\n")
+        fmt.Fprintf(os.Stderr, "      --ts-engine <e>    TypeScript generator: auto (default), external or native\n")
+        fmt.Fprintf(os.Stderr, "      --output-ts-runtime <r> Also write zod or typebox schemas next to the TypeScript output\n")
         fmt.Fprintf(os.Stderr, "      --go-generator <g> Generator for Go when using openapi-generator (default: go)\n")
+        fmt.Fprintf(os.Stderr, "      --ts-template-dir, --go-template-dir <dir>  Custom openapi-generator templates for TS / Go\n")
+        fmt.Fprintf(os.Stderr, "      --oapi-codegen-config <file>  oapi-codegen configuration file for the Go output\n")
+        fmt.Fprintf(os.Stderr, "      --go-generate <t,...>  oapi-codegen targets: types, client, server, chi-server, echo-server, std-http, ... (default: types,client,server)\n")
+        fmt.Fprintf(os.Stderr, "      --additional-properties k=v,...  openapi-generator additional properties for TS and Go\n")
+        fmt.Fprintf(os.Stderr, "      --tools t=c,...    Version constraints for external tools (see the doctor command)\n")
+        fmt.Fprintf(os.Stderr, "      --check-tools      Fail when a required tool is missing or does not meet its pin\n")
+        fmt.Fprintf(os.Stderr, "      --dry-run          Print planned writes and root/bundle diffs; write nothing, run no external tool\n")
+        fmt.Fprintf(os.Stderr, "      --force            Run external generators and Redocly even when their outputs are up to date\n")
+        fmt.Fprintf(os.Stderr, "      --verbose, --quiet Log external commands and timings too / only warnings and errors\n")
+        fmt.Fprintf(os.Stderr, "      --log-format <f>   Log format: text (default) or json (on stderr)\n")
+        fmt.Fprintf(os.Stderr, "      --tool-timeout <d> Kill an external tool running longer than d; 0 for no limit (default: 10m)\n")
+        fmt.Fprintf(os.Stderr, "      --use-docker       Run redocly and openapi-generator from docker images when not installed\n")
+        fmt.Fprintf(os.Stderr, "      --gen <g>:<dir>    Also run openapi-generator target g (kotlin, python, ...) into dir (repeatable)\n")
+        fmt.Fprintf(os.Stderr, "      --gen-args \"...\"   Extra openapi-generator arguments for every --gen target\n")
+        fmt.Fprintf(os.Stderr, "      --go-engine <e>    Go generator: auto (default), external or native\n")
         fmt.Fprintf(os.Stderr, "      --join            Write joined/inlined root instead of reference-style\n")
+        fmt.Fprintf(os.Stderr, "      --merge-keys <m>  Join mode: resolve (default) expands << merges and aliases, preserve keeps them\n")
+        fmt.Fprintf(os.Stderr, "      --follow-symlinks Follow symlinked fragment files and directories (cycle-safe)\n")
+        fmt.Fprintf(os.Stderr, "      --include <glob>  Only index fragments matching glob, relative to the input dir (repeatable)\n")
+        fmt.Fprintf(os.Stderr, "      --exclude <glob>  Leave matching fragments and directories out, as <input>/.oasignore does (repeatable)\n")
+        fmt.Fprintf(os.Stderr, "      --path-casing <c> Casing of derived path keys: camel (default), kebab, preserve\n")
         fmt.Fprintf(os.Stderr, "      --bundle <yaml>   Bundle the spec using Redocly CLI to the given YAML path\n")
         fmt.Fprintf(os.Stderr, "      --redocly-config <file> Optional Redocly config (default: ./redocly.yaml if present)\n")
         fmt.Fprintf(os.Stderr, "      --all             Do both: bundle -> dist/openapi.yaml and HTML -> dist/index.html\n")
+        fmt.Fprintf(os.Stderr, "      --strict          Fail when a fragment's structure does not match its kind, or on files outside the layout\n")
+        fmt.Fprintf(os.Stderr, "      --expand-tabs <n> Replace tab indentation in fragments with n spaces instead of failing\n")
         fmt.Fprintf(os.Stderr, "\n")
         fmt.Fprintf(os.Stderr, "Validation Options:\n")
         fmt.Fprintf(os.Stderr, "      --validate <preset>         Run validation with specified preset (google, restful)\n")
         fmt.Fprintf(os.Stderr, "      --skip-validation          Skip validation entirely\n")
         fmt.Fprintf(os.Stderr, "      --validate-stop-on-error   Stop on first validation error\n")
         fmt.Fprintf(os.Stderr, "      --list-presets            List available validation presets\n")
+        fmt.Fprintf(os.Stderr, "\n")
+        printCommandsUsage()
     }
 
     flag.Parse()
@@ -115,27 +307,166 @@ func buildConfig() (*Config, error) {
         return nil, nil // Signal to exit without error
     }
 
+    return opts.configs(flag.CommandLine)
+}
+
+// config resolves parsed flags, env vars and the config file into a Config,
+// failing when the config file defines several APIs and --api picks none.
+func (o *optionFlags) config(fs *flag.FlagSet) (*Config, error) {
+    cfgs, err := o.configs(fs)
+    if err != nil { return nil, err }
+    if len(cfgs) > 1 {
+        var names []string
+        for _, cfg := range cfgs {
+            names = append(names, cfg.API)
+        }
+        return nil, fmt.Errorf("%s: the config file defines several apis (%s); pass --api <name> to pick one", fs.Name(), strings.Join(names, ", "))
+    }
+    return cfgs[0], nil
+}
+
+// configs resolves parsed flags, env vars and the config file into a Config
+// per API: a single one, or one per entry of the config file's apis section
+// (only the --api one when given). Each API starts from the top-level
+// options, overridden by its own; "{api}" in their values is its name.
+func (o *optionFlags) configs(fs *flag.FlagSet) ([]*Config, error) {
+    cfgs, err := o.resolveConfigs(fs)
+    return cfgs, withExitCode(exitConfig, err)
+}
+
+func (o *optionFlags) resolveConfigs(fs *flag.FlagSet) ([]*Config, error) {
+    // Fill options not given on the command line from env vars, then the config file.
+    cfgPath, explicit := strings.TrimSpace(*o.configFile), true
+    if cfgPath == "" { cfgPath, explicit = DefaultConfigFile, false }
+    fileVals, apis, err := loadConfigFile(cfgPath, explicit)
+    if err != nil { return nil, err }
+    api := strings.TrimSpace(*o.api)
+    if len(apis) == 0 {
+        if api != "" { return nil, fmt.Errorf("--api %q: %s has no apis section", api, cfgPath) }
+        if err := applyConfigLayers(fs, fileVals); err != nil { return nil, err }
+        cfg, err := o.resolve(fs, "")
+        if err != nil { return nil, err }
+        return []*Config{cfg}, nil
+    }
+    if firstNonEmpty(*o.inputDir, *o.inputDirS) != "" {
+        return nil, fmt.Errorf("--input cannot be combined with the apis section of %s; pass --api <name> to build one of them", cfgPath)
+    }
+
+    var cfgs []*Config
+    var names []string
+    for _, a := range apis {
+        names = append(names, a.Name)
+        if api != "" && a.Name != api { continue }
+        // A fresh flag set per API: the command line applies to every API,
+        // the config file's values per API.
+        afs := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+        afs.Usage = fs.Usage
+        aopts := registerOptionFlags(afs)
+        var err error
+        fs.Visit(func(f *flag.Flag) {
+            if err == nil && afs.Lookup(f.Name) != nil { err = afs.Set(f.Name, f.Value.String()) }
+        })
+        if err != nil { return nil, err }
+        vals := map[string]string{}
+        for k, v := range fileVals {
+            vals[k] = v
+        }
+        for k, v := range a.Vals {
+            vals[k] = v
+        }
+        for k, v := range vals {
+            vals[k] = strings.ReplaceAll(v, apiPlaceholder, a.Name)
+        }
+        if err := applyConfigLayers(afs, vals); err != nil { return nil, fmt.Errorf("api %s: %w", a.Name, err) }
+        cfg, err := aopts.resolve(afs, a.Name)
+        if err != nil { return nil, fmt.Errorf("api %s: %w", a.Name, err) }
+        cfgs = append(cfgs, cfg)
+    }
+    if len(cfgs) == 0 {
+        return nil, fmt.Errorf("--api %q: no such api in %s (defined: %s)", api, cfgPath, strings.Join(names, ", "))
+    }
+    return cfgs, checkWorkspaceOutputs(cfgs)
+}
+
+// checkWorkspaceOutputs fails when two APIs would write the same file.
+func checkWorkspaceOutputs(cfgs []*Config) error {
+    owner := map[string]string{}
+    for _, cfg := range cfgs {
+        for _, p := range append([]string{cfg.RootPath}, cfg.outputs()...) {
+            if strings.TrimSpace(p) == "" { continue }
+            p = absJoin(cfg.Cwd, p)
+            if prev, ok := owner[p]; ok {
+                return fmt.Errorf("apis %s and %s both write %s; give each its own output (e.g. with %s in the path)", prev, cfg.API, p, apiPlaceholder)
+            }
+            owner[p] = cfg.API
+        }
+    }
+    return nil
+}
+
+// resolve turns the flag values, with the config layers applied, into the
+// Config of the API named api ("" outside a workspace).
+func (o *optionFlags) resolve(fs *flag.FlagSet, api string) (*Config, error) {
+    if err := configureLogging(strings.ToLower(strings.TrimSpace(*o.logFormat)), *o.verbose, *o.quiet); err != nil { return nil, err }
     // Determine input/output/root from flags or env
-    inputDir := firstNonEmpty(*inputDirFlag, *inputDirFlagS)
-    outputDir := firstNonEmpty(*outputDirFlag, *outputDirFlagS)
+    inputDir := firstNonEmpty(*o.inputDir, *o.inputDirS)
+    outputDir := firstNonEmpty(*o.outputDir, *o.outputDirS)
     if strings.TrimSpace(inputDir) == "" {
-        flag.Usage()
-        return nil, errors.New("missing required flag: --input is required")
+        fs.Usage()
+        return nil, errors.New("missing required flag: --input is required (or set input in " + DefaultConfigFile + ")")
+    }
+    // An empty output dir defaults to the input dir so root.yaml lives alongside fragments.
+    rootFile := firstNonEmpty(*o.rootFile, *o.rootFileS)
+    format := strings.ToLower(strings.TrimSpace(*o.format))
+    switch format {
+    case "", indexer.FormatYAML:
+    case indexer.FormatJSON:
+        if rootFile == "" { rootFile = "root.json" }
+    default:
+        return nil, fmt.Errorf("invalid --format %q (expected yaml or json)", *o.format)
+    }
+
+    casing := strings.ToLower(strings.TrimSpace(*o.pathCasing))
+    if !indexer.ValidPathCasing(casing) {
+        return nil, fmt.Errorf("invalid --path-casing %q (expected camel, kebab or preserve)", *o.pathCasing)
+    }
+
+    version := strings.TrimSpace(*o.openapiVersion)
+    if version != indexer.OpenAPI30 && version != indexer.OpenAPI31 {
+        return nil, fmt.Errorf("invalid --openapi-version %q (expected 3.0 or 3.1)", *o.openapiVersion)
+    }
+
+    bundler := strings.ToLower(strings.TrimSpace(*o.bundler))
+    if !validEngine(bundler) {
+        return nil, fmt.Errorf("invalid --bundler %q (expected auto, redocly or native)", *o.bundler)
+    }
+    docsRenderer := strings.ToLower(strings.TrimSpace(*o.docsRenderer))
+    if !validEngine(docsRenderer) {
+        return nil, fmt.Errorf("invalid --docs-renderer %q (expected auto, redocly or native)", *o.docsRenderer)
+    }
+
+    tsEngine := strings.ToLower(strings.TrimSpace(*o.tsEngine))
+    if tsEngine != engineAuto && tsEngine != engineExternal && tsEngine != engineNative {
+        return nil, fmt.Errorf("invalid --ts-engine %q (expected auto, external or native)", *o.tsEngine)
+    }
+    tsRuntime := strings.ToLower(strings.TrimSpace(*o.tsRuntime))
+    if tsRuntime != "" && !tsgen.ValidRuntime(tsRuntime) {
+        return nil, fmt.Errorf("invalid --output-ts-runtime %q (expected zod or typebox)", *o.tsRuntime)
     }
-    // If output dir not provided, default to input dir so root.yaml lives alongside fragments.
-    if strings.TrimSpace(outputDir) == "" { outputDir = inputDir }
-    rootFile := firstNonEmpty(*rootFileFlag, *rootFileFlagS)
-    if strings.TrimSpace(rootFile) == "" {
-        rootFile = "root.yaml"
+    goEngine := strings.ToLower(strings.TrimSpace(*o.goEngine))
+    if goEngine != engineAuto && goEngine != engineExternal && goEngine != engineNative {
+        return nil, fmt.Errorf("invalid --go-engine %q (expected auto, external or native)", *o.goEngine)
+    }
+
+    merge := strings.ToLower(strings.TrimSpace(*o.mergeKeys))
+    if merge != indexer.MergeKeysResolve && merge != indexer.MergeKeysPreserve {
+        return nil, fmt.Errorf("invalid --merge-keys %q (expected resolve or preserve)", *o.mergeKeys)
     }
 
     cwd, _ := os.Getwd()
-    inputDir = absJoin(cwd, inputDir)
-    outputDir = absJoin(cwd, outputDir)
-    rootPath := absJoin(outputDir, rootFile)
 
     // Determine default Redocly config if not provided
-    redoclyConfig := strings.TrimSpace(*redoclyCfg)
+    redoclyConfig := strings.TrimSpace(*o.redoclyCfg)
     if redoclyConfig == "" {
         def := filepath.Join(cwd, "redocly.yaml")
         if st, err := os.Stat(def); err == nil && !st.IsDir() {
@@ -144,35 +475,209 @@ func buildConfig() (*Config, error) {
     }
 
     cfg := &Config{
-        Cwd:        cwd,
-        InputDir:   inputDir,
-        OutputDir:  outputDir,
-        RootFile:   rootFile,
-        RootPath:   rootPath,
-        PathsDir:   filepath.Join(inputDir, "paths"),
-        SchemasDir: filepath.Join(inputDir, "components", "schemas"),
-        ParamsDir:  filepath.Join(inputDir, "components", "parameters"),
-        OutputTS:   strings.TrimSpace(*outputTS),
-        OutputGo:   strings.TrimSpace(*outputGo),
-        Redocly:    strings.TrimSpace(*redoclyOut),
-        BundleOut:  strings.TrimSpace(*bundleOut),
+        Config:     *indexer.NewConfig(inputDir, outputDir, rootFile),
+        OutputTS:   strings.TrimSpace(*o.outputTS),
+        OutputGo:   strings.TrimSpace(*o.outputGo),
+        Redocly:    strings.TrimSpace(*o.redoclyOut),
+        DocsRenderer: docsRenderer,
+        BundleOut:  strings.TrimSpace(*o.bundleOut),
+        Bundler:    bundler,
         RedoclyConfig: redoclyConfig,
-        TSGenerator: strings.TrimSpace(*tsGen),
-        GoGenerator: strings.TrimSpace(*goGen),
-        Join:       *joinOutput,
-        ValidatePreset: strings.TrimSpace(*validatePreset),
-        SkipValidation: *skipValidation,
-        ValidateStopOnError: *validateStopOnError,
+        Audience:   strings.ToLower(strings.TrimSpace(*o.audience)),
+        TSGenerator: strings.TrimSpace(*o.tsGen),
+        TSEngine:   tsEngine,
+        TSRuntime:  tsRuntime,
+        GoEngine:   goEngine,
+        GoGenerator: strings.TrimSpace(*o.goGen),
+        ValidatePreset: strings.TrimSpace(*o.validatePreset),
+        SkipValidation: *o.skipValidation,
+        ValidateStopOnError: *o.validateStopOnError,
+        API:        api,
+    }
+    cfg.Logger = discoveryLogger()
+    for _, t := range []struct{ flag, val string; dst *string; engine string }{
+        {"--ts-template-dir", *o.tsTemplateDir, &cfg.TSTemplateDir, tsEngine},
+        {"--go-template-dir", *o.goTemplateDir, &cfg.GoTemplateDir, goEngine},
+    } {
+        if strings.TrimSpace(t.val) == "" { continue }
+        if t.engine == engineNative { return nil, fmt.Errorf("%s needs openapi-generator; it does not apply to the native engine", t.flag) }
+        dir := absJoin(cwd, strings.TrimSpace(t.val))
+        if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+            return nil, fmt.Errorf("%s %s: not a directory", t.flag, t.val)
+        }
+        *t.dst = dir
+    }
+    if c := strings.TrimSpace(*o.oapiCodegenConfig); c != "" {
+        cfg.OapiCodegenConfig = absJoin(cwd, c)
+        if _, err := os.Stat(cfg.OapiCodegenConfig); err != nil { return nil, fmt.Errorf("--oapi-codegen-config: %w", err) }
+    }
+    goGenerate, err := parseGoGenerate(*o.goGenerate)
+    if err != nil { return nil, err }
+    cfg.GoGenerate = goGenerate
+    switch {
+    case cfg.OapiCodegenConfig != "" && cfg.GoGenerate != "":
+        return nil, errors.New("--go-generate cannot be combined with --oapi-codegen-config; set generate in the config file")
+    case cfg.oapiCodegenOptions() && goEngine == engineNative:
+        return nil, errors.New("--oapi-codegen-config and --go-generate need oapi-codegen; they do not apply to the native engine")
+    case cfg.oapiCodegenOptions() && cfg.GoTemplateDir != "":
+        return nil, errors.New("--go-template-dir (openapi-generator) cannot be combined with --oapi-codegen-config or --go-generate (oapi-codegen)")
+    }
+    cfg.AdditionalProperties = strings.TrimSpace(*o.additionalProperties)
+    if cfg.AdditionalProperties != "" {
+        for _, kv := range strings.Split(cfg.AdditionalProperties, ",") {
+            if k, _, ok := strings.Cut(kv, "="); !ok || strings.TrimSpace(k) == "" {
+                return nil, fmt.Errorf("invalid --additional-properties %q (expected key=val,...)", *o.additionalProperties)
+            }
+        }
+    }
+    overrides, err := parseRuleOverrides(*o.ruleOverrides)
+    if err != nil { return nil, err }
+    cfg.RuleOverrides = overrides
+    if b := strings.TrimSpace(*o.validateBaseline); b != "" { cfg.Baseline = absJoin(cwd, b) }
+    cfg.UpdateBaseline = *o.updateBaseline
+    cfg.Structural = *o.structural
+    cfg.Examples = *o.examples
+    if cfg.UpdateBaseline && cfg.Baseline == "" {
+        return nil, errors.New("--update-baseline requires --validate-baseline")
+    }
+    cfg.ValidateFormat = strings.ToLower(strings.TrimSpace(*o.validateFormat))
+    switch cfg.ValidateFormat {
+    case validate.ReportText, validate.ReportJSON, validate.ReportJUnit:
+    default:
+        return nil, fmt.Errorf("invalid --validate-format %q (expected text, json or junit)", *o.validateFormat)
+    }
+    if r := strings.TrimSpace(*o.validateReport); r != "" { cfg.ValidateReport = absJoin(cwd, r) }
+    if rules := strings.TrimSpace(*o.validateRules); rules != "" {
+        name, preset, err := validate.LoadRules(absJoin(cwd, rules))
+        if err != nil { return nil, err }
+        if _, builtin := validate.Presets[name]; builtin {
+            return nil, fmt.Errorf("%s: rules name %q clashes with a built-in preset; set a different name", rules, name)
+        }
+        validate.Presets[name] = preset
+        if cfg.ValidatePreset == "" { cfg.ValidatePreset = name }
     }
+    cfg.Join = *o.joinOutput
+    cfg.PerVersion = *o.perVersion
+    cfg.DryRun = *o.dryRun
+    cfg.Force = *o.force
+    cfg.PathCasing = casing
+    cfg.PathVersion = strings.ToLower(strings.TrimSpace(*o.pathVersion))
+    if cfg.PathVersion != indexer.PathVersionKeep && cfg.PathVersion != indexer.PathVersionStrip {
+        return nil, fmt.Errorf("invalid --path-version %q (expected keep or strip)", *o.pathVersion)
+    }
+    cfg.PathStripPrefix = strings.Trim(filepath.ToSlash(strings.TrimSpace(*o.pathStripPrefix)), "/")
+    for _, r := range *o.pathRewrites {
+        rw, err := indexer.ParsePathRewrite(r)
+        if err != nil { return nil, err }
+        cfg.PathRewrites = append(cfg.PathRewrites, rw)
+    }
+    cfg.ComponentNaming = strings.ToLower(strings.TrimSpace(*o.componentNaming))
+    if !indexer.ValidComponentNaming(cfg.ComponentNaming) {
+        return nil, fmt.Errorf("invalid --component-naming %q (expected pascal, camel or verbatim)", *o.componentNaming)
+    }
+    nameMap, err := parseNameMap(*o.nameMap)
+    if err != nil { return nil, err }
+    cfg.NameMap = nameMap
+    cfg.NamespaceSeparator = *o.namespaceSeparator
+    if !indexer.ValidNamespaceSeparator(cfg.NamespaceSeparator) {
+        return nil, fmt.Errorf("invalid --namespace-separator %q (use '.', '_' or '-', or \"\" for none)", *o.namespaceSeparator)
+    }
+    cfg.MethodFiles = *o.methodFiles
+    cfg.AutoTags = *o.autoTags
+    std, err := parseStandardResponses(*o.standardResponses)
+    if err != nil { return nil, err }
+    cfg.StandardResponses = std
+    cfg.OpenAPIVersion = version
+    cfg.Downconvert = *o.downconvert
+    if cfg.Downconvert && version == indexer.OpenAPI31 {
+        return nil, errors.New("--downconvert produces an OpenAPI 3.0 root; it cannot be combined with --openapi-version 3.1")
+    }
+    cfg.FollowSymlinks = *o.followLinks
+    cfg.Include = *o.includes
+    cfg.Ignore = *o.excludes
+    cfg.MergeKeys = merge
+    cfg.ExpandTabs = *o.expandTabs
+    cfg.Strict = *o.strict
+    useDocker = *o.useDocker
+    toolTimeout = defaultToolTimeout
+    if t := strings.TrimSpace(*o.toolTimeout); t != "" {
+        d, err := time.ParseDuration(t)
+        if err != nil || d < 0 { return nil, fmt.Errorf("invalid --tool-timeout %q (expected a duration such as 90s or 20m, or 0)", *o.toolTimeout) }
+        toolTimeout = d
+    }
+    pins, err := parseToolPins(*o.toolPins)
+    if err != nil { return nil, err }
+    cfg.ToolPins = pins
+    cfg.CheckTools = *o.checkTools
+    cfg.Prune = *o.prune
+    vendorDir := filepath.Join(cfg.InputDir, remote.DefaultDir)
+    if d := strings.TrimSpace(*o.vendorDir); d != "" { vendorDir = absJoin(cwd, d) }
+    if d := strings.TrimSpace(*o.sharedComponents); d != "" { cfg.SharedComponentsDir = absJoin(cwd, d) }
+    cfg.Vendor = remote.New(vendorDir, *o.offline)
+    bundle.Remote = cfg.Vendor.Fetch
+    for _, s := range *o.deps {
+        d, err := parseDependency(s)
+        if err != nil { return nil, err }
+        cfg.Deps = append(cfg.Deps, d)
+    }
+    for _, s := range *o.gens {
+        g, err := parseGenerator(s)
+        if err != nil { return nil, err }
+        cfg.Generators = append(cfg.Generators, g)
+    }
+    genArgs, err := splitArgs(*o.genArgs)
+    if err != nil { return nil, fmt.Errorf("--gen-args: %w", err) }
+    cfg.GenArgs = genArgs
+    for _, p := range *o.overlays {
+        ov, err := overlay.Load(absJoin(cwd, strings.TrimSpace(p)))
+        if err != nil { return nil, err }
+        cfg.Overlays = append(cfg.Overlays, ov)
+    }
+    if p := strings.TrimSpace(*o.varsFile); p != "" {
+        v, err := vars.Load(absJoin(cwd, p))
+        if err != nil { return nil, err }
+        cfg.Vars = v
+    } else if *o.expandVars {
+        cfg.Vars = vars.New()
+    }
+    cfg.Cycles = strings.ToLower(strings.TrimSpace(*o.cycles))
+    if cfg.Cycles != cyclesWarn && cfg.Cycles != cyclesError && cfg.Cycles != cyclesOff {
+        return nil, fmt.Errorf("invalid --cycles %q (expected warn, error or off)", *o.cycles)
+    }
+    if format != "" { cfg.Format = format }
+    cfg.AsyncAPIPath = absJoin(cfg.OutputDir, firstNonEmpty(strings.TrimSpace(*o.asyncapiFile), "asyncapi."+cfg.Format))
 
-    if *allDo {
-        if cfg.BundleOut == "" { cfg.BundleOut = absJoin(cwd, filepath.Join("dist", "openapi.yaml")) }
-        if cfg.Redocly == "" { cfg.Redocly = absJoin(cwd, filepath.Join("dist", "index.html")) }
+    if *o.allDo {
+        if cfg.BundleOut == "" { cfg.BundleOut = absJoin(cwd, defaultBundlePath(cfg)) }
+        if cfg.Redocly == "" { cfg.Redocly = absJoin(cwd, defaultDocsPath(cfg)) }
+    }
+
+    // Files of the run kept in the input tree are no layout violations for --strict
+    for _, d := range cfg.Deps { cfg.Known = append(cfg.Known, filepath.Join(cfg.InputDir, filepath.FromSlash(d.Into))) }
+    for _, p := range append([]string{*o.configFile, cfg.RedoclyConfig, cfg.Baseline, cfg.ValidateReport, cfg.TSTemplateDir, cfg.GoTemplateDir, cfg.OapiCodegenConfig, *o.varsFile}, *o.overlays...) {
+        if p = strings.TrimSpace(p); p != "" { cfg.Known = append(cfg.Known, absJoin(cwd, p)) }
+    }
+
+    // Outputs nested in the input tree are excluded from discovery, but
+    // keeping them apart avoids surprises for other tools scanning the tree.
+    if indexer.IsWithin(cfg.InputDir, cfg.OutputDir) {
+        logger.Warn(fmt.Sprintf("output dir %s is nested inside input dir %s; generated files there are excluded from indexing", cfg.OutputDir, cfg.InputDir))
     }
 
     return cfg, nil
 }
 
+// defaultBundlePath is dist/openapi.yaml, or dist/openapi.json for JSON
+// roots; a workspace API's goes to dist/<api>/.
+func defaultBundlePath(cfg *Config) string {
+    return filepath.Join("dist", cfg.API, "openapi."+cfg.Format)
+}
+
+// defaultDocsPath is dist/index.html, or dist/<api>/index.html for a workspace API.
+func defaultDocsPath(cfg *Config) string {
+    return filepath.Join("dist", cfg.API, "index.html")
+}
+
 func firstNonEmpty(vals ...string) string {
     for _, v := range vals {
         if strings.TrimSpace(v) != "" {
@@ -194,420 +699,250 @@ func ensureDir(dir string) error {
     return os.MkdirAll(dir, 0o755)
 }
 
-func listYAMLFiles(root string) ([]string, error) {
-    var files []string
-    if st, err := os.Stat(root); err != nil || !st.IsDir() {
-        return files, nil
+// runCmd runs an external tool non-interactively with a sanitized environment.
+// Combined output is captured: it is logged on success and included, together
+// with the full command line, in the returned error on failure.
+func runCmd(name string, args ...string) error {
+    if _, err := exec.LookPath(name); err != nil {
+        if img := dockerImage(name); img != "" { name, args = "docker", dockerArgs(img, args) }
     }
-    err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-        if err != nil { return err }
-        name := d.Name()
-        if strings.HasPrefix(name, ".") { // skip dot files/dirs
-            if d.IsDir() && path != root {
-                return filepath.SkipDir
-            }
-        }
-        if d.Type().IsRegular() && strings.HasSuffix(strings.ToLower(name), ".yaml") {
-            files = append(files, path)
-        }
-        return nil
-    })
-    return files, err
-}
-
-func relFrom(baseDir, target string) string {
-    rel, err := filepath.Rel(baseDir, target)
+    var out bytes.Buffer
+    err := runExternal(name, args, func(cmd *exec.Cmd) { cmd.Stdout, cmd.Stderr = &out, &out })
     if err != nil {
-        return target
-    }
-    rel = filepath.ToSlash(rel)
-    if rel == "" || rel == "." {
-        return "./"
-    }
-    if strings.HasPrefix(rel, ".") || strings.HasPrefix(rel, "/") {
-        return rel
+        msg := strings.TrimRight(out.String(), "\n")
+        if msg == "" { msg = "(no output)" }
+        return withExitCode(exitTool, fmt.Errorf("command failed: %s: %w\n%s", commandLine(name, args), err, msg))
     }
-    return "./" + rel
-}
-
-// String helpers similar to the JS version
-func kebabToCamel(s string) string {
-    // convert kebab-case to camelCase
-    out := ""
-    up := false
-    for i := 0; i < len(s); i++ {
-        c := s[i]
-        if c == '-' || c == '_' || c == ' ' {
-            up = true
-            continue
-        }
-        if up {
-            out += strings.ToUpper(string(c))
-            up = false
-        } else {
-            out += string(c)
-        }
-    }
-    return out
-}
-
-func pascalCase(s string) string {
-    camel := kebabToCamel(s)
-    if camel == "" { return camel }
-    return strings.ToUpper(camel[:1]) + camel[1:]
-}
-
-// Build the aggregated root YAML (with $ref entries) without third-party YAML libs
-func writeRootYAML(cfg *Config) error {
-    // Reference-style root (legacy)
-    if err := ensureDir(filepath.Dir(cfg.RootPath)); err != nil { return err }
-
-    paths, err := listYAMLFiles(cfg.PathsDir)
-    if err != nil { return err }
-    schemas, err := listYAMLFiles(cfg.SchemasDir)
-    if err != nil { return err }
-    params, err := listYAMLFiles(cfg.ParamsDir)
-    if err != nil { return err }
-
-    // Stable ordering
-    sort.Strings(paths)
-    sort.Strings(schemas)
-    sort.Strings(params)
-
-    f, err := os.Create(cfg.RootPath)
-    if err != nil { return err }
-    defer f.Close()
-    w := bufio.NewWriter(f)
-
-    // Minimal header; users can edit final file later if needed
-    // Note: keeping it simple to avoid external YAML lib
-    fmt.Fprintln(w, "openapi: \"3.0.0\"")
-    fmt.Fprintln(w, "info:")
-    fmt.Fprintln(w, "  title: API")
-    fmt.Fprintln(w, "  version: \"1.0.0\"")
-
-    // paths
-    fmt.Fprintln(w, "paths:")
-    for _, p := range paths {
-        key := buildPathKey(cfg.PathsDir, p)
-        if key == "" { continue }
-        ref := relFrom(filepath.Dir(cfg.RootPath), p)
-        fmt.Fprintf(w, "  %s:\n", key)
-        fmt.Fprintf(w, "    $ref: %s\n", ref)
-    }
-
-    // components
-    fmt.Fprintln(w, "components:")
-    // schemas
-    fmt.Fprintln(w, "  schemas:")
-    for _, s := range schemas {
-        base := strings.TrimSuffix(filepath.Base(s), ".yaml")
-        name := pascalCase(base)
-        ref := relFrom(filepath.Dir(cfg.RootPath), s)
-        fmt.Fprintf(w, "    %s:\n", name)
-        fmt.Fprintf(w, "      $ref: %s\n", ref)
-    }
-    // parameters
-    fmt.Fprintln(w, "  parameters:")
-    for _, p := range params {
-        base := strings.TrimSuffix(filepath.Base(p), ".yaml")
-        name := pascalCase(base)
-        ref := relFrom(filepath.Dir(cfg.RootPath), p)
-        fmt.Fprintf(w, "    %s:\n", name)
-        fmt.Fprintf(w, "      $ref: %s\n", ref)
-    }
-
-    if err := w.Flush(); err != nil { return err }
+    if msg := strings.TrimRight(out.String(), "\n"); msg != "" { logger.Info(msg) }
     return nil
 }
 
-// Helper: read file as string
-func readText(path string) (string, error) {
-    b, err := ioutil.ReadFile(path)
-    if err != nil { return "", err }
-    return string(b), nil
+// Environment variables passed through to external tools; everything else
+// (tokens, cloud credentials, ...) is dropped.
+var envAllowlist = map[string]bool{
+    "PATH": true, "PATHEXT": true, "HOME": true, "USER": true, "USERPROFILE": true,
+    "APPDATA": true, "LOCALAPPDATA": true, "SYSTEMROOT": true, "COMSPEC": true, "WINDIR": true,
+    "TMP": true, "TEMP": true, "TMPDIR": true, "LANG": true, "TERM": true, "NO_COLOR": true,
+    "HTTP_PROXY": true, "HTTPS_PROXY": true, "NO_PROXY": true, "SSH_AUTH_SOCK": true,
+    "JAVA_HOME": true, "JAVA_OPTS": true, "NODE_PATH": true, "NODE_OPTIONS": true,
+    "GOPATH": true, "GOROOT": true, "GOCACHE": true, "GOMODCACHE": true, "GOPROXY": true,
 }
 
-func indentText(s string, spaces int) string {
-    pad := strings.Repeat(" ", spaces)
-    lines := strings.Split(s, "\n")
-    for i, ln := range lines {
-        if ln == "" {
-            // keep empty lines empty for cleaner YAML
-            continue
+var envAllowPrefixes = []string{"LC_", "NPM_CONFIG_", "REDOCLY_", "OPENAPI_GENERATOR_", "GIT_", "DOCKER_"}
+
+func sanitizedEnv(environ []string) []string {
+    var env []string
+    for _, kv := range environ {
+        k := kv
+        if i := strings.Index(kv, "="); i > 0 { k = kv[:i] }
+        uk := strings.ToUpper(k)
+        allowed := envAllowlist[uk]
+        for _, p := range envAllowPrefixes {
+            if strings.HasPrefix(uk, p) { allowed = true }
         }
-        lines[i] = pad + ln
-    }
-    // Trim a single trailing newline for consistency
-    out := strings.Join(lines, "\n")
-    out = strings.TrimRight(out, "\n")
-    return out + "\n"
-}
-
-// Build a map for schema and parameter file basenames to canonical names
-func buildNameMap(dir string) map[string]string {
-    m := map[string]string{}
-    files, _ := listYAMLFiles(dir)
-    for _, f := range files {
-        base := strings.TrimSuffix(filepath.Base(f), ".yaml")
-        name := pascalCase(base)
-        m[strings.ToLower(base)] = name
-        m[strings.ToLower(filepath.Base(f))] = name
+        if allowed { env = append(env, kv) }
     }
-    return m
+    return env
 }
 
-var (
-    reSchemaPath   = regexp.MustCompile(`(?i)(?:^|.*/)(?:components/)?schemas/([^/#\s]+)\.ya?ml$`)
-    reParamPath    = regexp.MustCompile(`(?i)(?:^|.*/)(?:components/)?parameters/([^/#\s]+)\.ya?ml$`)
-)
-
-func stripQuotes(s string) string {
-    s = strings.TrimSpace(s)
-    if len(s) >= 2 {
-        if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
-            return s[1:len(s)-1]
+// commandLine renders a command for error messages, quoting args with spaces.
+func commandLine(name string, args []string) string {
+    parts := []string{name}
+    for _, a := range args {
+        if a == "" || strings.ContainsAny(a, " \t\"'") {
+            a = strconv.Quote(a)
         }
+        parts = append(parts, a)
     }
-    return s
-}
-
-func rewriteRefs(raw string, schemaMap, paramMap map[string]string) string {
-    lines := strings.Split(raw, "\n")
-    for i, ln := range lines {
-        idx := strings.Index(ln, "$ref:")
-        if idx < 0 { continue }
-        // split into indent+key and value
-        left := ln[:idx]
-        rest := strings.TrimSpace(ln[idx+len("$ref:"):])
-        if rest == "" { continue }
-        val := stripQuotes(rest)
-        // Already internal
-        if strings.HasPrefix(val, "#/components/") {
-            continue
-        }
-        // pseudo forms
-        low := strings.ToLower(val)
-        if strings.HasPrefix(low, "schema:") {
-            base := strings.TrimSpace(val[len("schema:"):])
-            name := pascalCase(base)
-            lines[i] = left + "$ref: #/components/schemas/" + name
-            continue
-        }
-        if strings.HasPrefix(low, "param:") {
-            base := strings.TrimSpace(val[len("param:"):])
-            name := pascalCase(base)
-            lines[i] = left + "$ref: #/components/parameters/" + name
-            continue
-        }
-        // file path style
-        if m := reSchemaPath.FindStringSubmatch(val); len(m) == 2 {
-            base := strings.ToLower(m[1])
-            name := schemaMap[base]
-            if name == "" { name = pascalCase(m[1]) }
-            lines[i] = left + "$ref: #/components/schemas/" + name
-            continue
-        }
-        if m := reParamPath.FindStringSubmatch(val); len(m) == 2 {
-            base := strings.ToLower(m[1])
-            name := paramMap[base]
-            if name == "" { name = pascalCase(m[1]) }
-            lines[i] = left + "$ref: #/components/parameters/" + name
-            continue
-        }
-        // else leave as-is
-    }
-    return strings.Join(lines, "\n")
-}
-
-// Joined/inlined root
-func writeRootJoinedYAML(cfg *Config) error {
-    if err := ensureDir(filepath.Dir(cfg.RootPath)); err != nil { return err }
-
-    paths, err := listYAMLFiles(cfg.PathsDir)
-    if err != nil { return err }
-    schemas, err := listYAMLFiles(cfg.SchemasDir)
-    if err != nil { return err }
-    params, err := listYAMLFiles(cfg.ParamsDir)
-    if err != nil { return err }
-
-    sort.Strings(paths)
-    sort.Strings(schemas)
-    sort.Strings(params)
-
-    schemaMap := buildNameMap(cfg.SchemasDir)
-    paramMap := buildNameMap(cfg.ParamsDir)
-
-    f, err := os.Create(cfg.RootPath)
-    if err != nil { return err }
-    defer f.Close()
-    w := bufio.NewWriter(f)
-
-    // Header
-    fmt.Fprintln(w, "openapi: \"3.0.0\"")
-    fmt.Fprintln(w, "info:")
-    fmt.Fprintln(w, "  title: API")
-    fmt.Fprintln(w, "  version: \"1.0.0\"")
-
-    // paths
-    fmt.Fprintln(w, "paths:")
-    for _, p := range paths {
-        key := buildPathKey(cfg.PathsDir, p)
-        if key == "" { continue }
-        fmt.Fprintf(w, "  %s:\n", key)
-        content, err := readText(p)
-        if err != nil { return err }
-        content = rewriteRefs(content, schemaMap, paramMap)
-        fmt.Fprint(w, indentText(content, 4))
-    }
-
-    // components
-    fmt.Fprintln(w, "components:")
-    // schemas
-    fmt.Fprintln(w, "  schemas:")
-    for _, s := range schemas {
-        base := strings.TrimSuffix(filepath.Base(s), ".yaml")
-        name := pascalCase(base)
-        fmt.Fprintf(w, "    %s:\n", name)
-        content, err := readText(s)
-        if err != nil { return err }
-        content = rewriteRefs(content, schemaMap, paramMap)
-        fmt.Fprint(w, indentText(content, 6))
-    }
-    // parameters
-    fmt.Fprintln(w, "  parameters:")
-    for _, p := range params {
-        base := strings.TrimSuffix(filepath.Base(p), ".yaml")
-        name := pascalCase(base)
-        fmt.Fprintf(w, "    %s:\n", name)
-        content, err := readText(p)
-        if err != nil { return err }
-        content = rewriteRefs(content, schemaMap, paramMap)
-        fmt.Fprint(w, indentText(content, 6))
-    }
-
-    if err := w.Flush(); err != nil { return err }
-    return nil
+    return strings.Join(parts, " ")
 }
 
-func buildPathKey(pathsDir, fullPath string) string {
-    rel, err := filepath.Rel(pathsDir, fullPath)
-    if err != nil { return "" }
-    rel = filepath.ToSlash(rel)
-    segs := strings.Split(rel, "/")
-    if len(segs) == 0 { return "" }
-    file := segs[len(segs)-1]
-    segs = segs[:len(segs)-1]
-    nameNoExt := strings.TrimSuffix(file, ".yaml")
-    tail := kebabToCamel(nameNoExt)
-    // Expect first segment to be version (e.g., v1)
-    if len(segs) == 0 {
-        // No version folder, just use tail at root
-        return "/" + tail
+// checkSpecInput verifies a spec handed to an external tool exists and is non-empty.
+func checkSpecInput(path string) error {
+    st, err := os.Stat(path)
+    if err != nil {
+        return fmt.Errorf("spec input %s is missing: %w", path, err)
     }
-    version := segs[0]
-    remainder := ""
-    if len(segs) > 1 {
-        remainder = strings.Join(segs[1:], "/")
+    if !st.Mode().IsRegular() {
+        return fmt.Errorf("spec input %s is not a regular file", path)
     }
-    if remainder != "" {
-        return "/" + version + "/" + remainder + "/" + tail
+    if st.Size() == 0 {
+        return fmt.Errorf("spec input %s is empty", path)
     }
-    return "/" + version + "/" + tail
-}
-
-func runCmd(name string, args ...string) error {
-    cmd := exec.Command(name, args...)
-    cmd.Stdout = os.Stdout
-    cmd.Stderr = os.Stderr
-    cmd.Stdin = os.Stdin
-    return cmd.Run()
+    return nil
 }
 
+// which returns the path of bin, or bin itself when it runs from a docker
+// image (see dockerImage), or "" when it is not available.
 func which(bin string) string {
     p, err := exec.LookPath(bin)
-    if err != nil { return "" }
+    if err != nil {
+        if dockerImage(bin) != "" { return bin }
+        return ""
+    }
     return p
 }
 
 func findRedocly(cwd string) string {
     if p := which("redocly"); p != "" { return p }
-    local := filepath.Join(cwd, "node_modules", ".bin", "redocly")
-    if st, err := os.Stat(local); err == nil && !st.IsDir() {
-        return local
+    names := []string{"redocly"}
+    if runtime.GOOS == "windows" {
+        // npm installs .cmd shims on Windows
+        names = []string{"redocly.cmd", "redocly.exe", "redocly"}
+    }
+    for _, n := range names {
+        local := filepath.Join(cwd, "node_modules", ".bin", n)
+        if st, err := os.Stat(local); err == nil && !st.IsDir() {
+            return local
+        }
     }
     return ""
 }
 
 func generateTypeScript(cfg *Config) error {
     if cfg.OutputTS == "" { return nil }
+    if err := generateTypeScriptTypes(cfg); err != nil { return err }
+    return generateTypeScriptRuntime(cfg)
+}
+
+func generateTypeScriptTypes(cfg *Config) error {
+    if err := checkSpecInput(cfg.RootPath); err != nil { return err }
+    external := which("openapi") != "" || which("openapi-generator") != ""
+    if cfg.TSEngine == engineNative || cfg.TSEngine == engineAuto && !external && cfg.TSTemplateDir == "" { return generateNativeTypeScript(cfg) }
+    options := []string{"typescript", cfg.TSGenerator, cfg.AdditionalProperties, toolStamp("openapi", "openapi-generator", "openapi-typescript")}
+    return cachedStep(cfg, "TypeScript", cfg.OutputTS, false, options, []string{cfg.TSTemplateDir}, func() error { return generateExternalTypeScript(cfg) })
+}
+
+// generateExternalTypeScript writes the TypeScript output with an installed
+// generator.
+func generateExternalTypeScript(cfg *Config) error {
     // Prefer openapi-generator if available
     if p := which("openapi"); p != "" {
         // Assume syntax: openapi generate -g typescript -i spec -o out
         out := cfg.OutputTS
         // If output is a .ts file and openapi-typescript exists, prefer that
         if strings.HasSuffix(strings.ToLower(out), ".ts") {
-            if which("openapi-typescript") != "" {
-                return runCmd("openapi-typescript", cfg.RootPath, "-o", out)
+            if which("openapi-typescript") != "" && cfg.TSTemplateDir == "" {
+                return runToTemp(out, func(tmp string) error { return runCmd("openapi-typescript", cfg.RootPath, "-o", tmp) })
             }
             // fallback: inform better path
-            fmt.Fprintln(os.Stderr, "Tip: install openapi-typescript for single-file TS types: npm i -g openapi-typescript")
+            logger.Info("Tip: install openapi-typescript for single-file TS types: npm i -g openapi-typescript")
             // Fallback to using openapi as a dir generator by using parent dir
             out = filepath.Dir(out)
         }
-        return runCmd("openapi", "generate", "-g", "typescript", "-i", cfg.RootPath, "-o", out)
+        return runCmd("openapi", cfg.generatorArgs("typescript", out, cfg.TSTemplateDir)...)
     }
     if p := which("openapi-generator"); p != "" {
         out := cfg.OutputTS
         if strings.HasSuffix(strings.ToLower(out), ".ts") {
-            if which("openapi-typescript") != "" {
-                return runCmd("openapi-typescript", cfg.RootPath, "-o", out)
+            if which("openapi-typescript") != "" && cfg.TSTemplateDir == "" {
+                return runToTemp(out, func(tmp string) error { return runCmd("openapi-typescript", cfg.RootPath, "-o", tmp) })
             }
-            fmt.Fprintln(os.Stderr, "Tip: install openapi-typescript for single-file TS types: npm i -g openapi-typescript")
+            logger.Info("Tip: install openapi-typescript for single-file TS types: npm i -g openapi-typescript")
             out = filepath.Dir(out)
         }
         gen := cfg.TSGenerator
         if gen == "" { gen = "typescript-fetch" }
-        return runCmd("openapi-generator", "generate", "-g", gen, "-i", cfg.RootPath, "-o", out)
+        return runCmd("openapi-generator", cfg.generatorArgs(gen, out, cfg.TSTemplateDir)...)
     }
     // Not found: provide guidance
-    return fmt.Errorf("no OpenAPI generator found. Install one of:\n - brew install openapi-generator\n - npm i -g @openapitools/openapi-generator-cli\n - npm i -g openapi-typescript (for single-file types)")
+    if cfg.TSTemplateDir != "" {
+        return withExitCode(exitTool, fmt.Errorf("--ts-template-dir needs openapi-generator. Install one of:\n - brew install openapi-generator\n - npm i -g @openapitools/openapi-generator-cli"))
+    }
+    return withExitCode(exitTool, fmt.Errorf("no OpenAPI generator found. Install one of:\n - brew install openapi-generator\n - npm i -g @openapitools/openapi-generator-cli\n - npm i -g openapi-typescript (for single-file types)\nor use --use-docker or --ts-engine native"))
+}
+
+// generateTypeScriptRuntime writes the --output-ts-runtime schemas next to
+// the types: api.zod.ts beside api.ts, or zod.ts in an output directory.
+func generateTypeScriptRuntime(cfg *Config) error {
+    if cfg.TSRuntime == "" { return nil }
+    out := filepath.Join(cfg.OutputTS, cfg.TSRuntime+".ts")
+    if strings.HasSuffix(strings.ToLower(cfg.OutputTS), ".ts") { out = strings.TrimSuffix(cfg.OutputTS, filepath.Ext(cfg.OutputTS)) + "." + cfg.TSRuntime + ".ts" }
+    root, err := bundle.Load(cfg.RootPath)
+    if err != nil { return fmt.Errorf("ts: %w", err) }
+    if err := postprocessBundle(cfg, root); err != nil { return fmt.Errorf("ts: %w", err) }
+    data, err := tsgen.Runtime(root, cfg.TSRuntime)
+    if err != nil { return fmt.Errorf("ts: %w", err) }
+    if err := ensureDir(filepath.Dir(out)); err != nil { return err }
+    changed, err := atomicfile.WriteFile(out, data)
+    if err != nil { return err }
+    if changed {
+        logger.Info("Wrote "+cfg.TSRuntime+" schemas", "path", out)
+    } else {
+        logger.Info(cfg.TSRuntime+" schemas unchanged", "path", out)
+    }
+    return nil
+}
+
+// generateNativeTypeScript writes the types of the bundled spec with
+// pkg/tsgen: to cfg.OutputTS when it names a .ts file, else to types.ts in
+// that directory.
+func generateNativeTypeScript(cfg *Config) error {
+    out := cfg.OutputTS
+    if !strings.HasSuffix(strings.ToLower(out), ".ts") { out = filepath.Join(out, "types.ts") }
+    root, err := bundle.Load(cfg.RootPath)
+    if err != nil { return fmt.Errorf("ts: %w", err) }
+    if err := postprocessBundle(cfg, root); err != nil { return fmt.Errorf("ts: %w", err) }
+    data, err := tsgen.Generate(root)
+    if err != nil { return fmt.Errorf("ts: %w", err) }
+    if err := ensureDir(filepath.Dir(out)); err != nil { return err }
+    changed, err := atomicfile.WriteFile(out, data)
+    if err != nil { return err }
+    if changed {
+        logger.Info("Wrote TypeScript types", "path", out)
+    } else {
+        logger.Info("TypeScript types unchanged", "path", out)
+    }
+    return nil
 }
 
 func generateGo(cfg *Config) error {
     if cfg.OutputGo == "" { return nil }
+    if err := checkSpecInput(cfg.RootPath); err != nil { return err }
+    external := which("openapi") != "" || which("openapi-generator") != "" || which("oapi-codegen") != ""
+    if cfg.GoEngine == engineNative || cfg.GoEngine == engineAuto && !external && cfg.GoTemplateDir == "" && !cfg.oapiCodegenOptions() { return generateNativeGo(cfg) }
+    options := []string{"go", cfg.GoGenerator, cfg.GoGenerate, cfg.AdditionalProperties, toolStamp("openapi", "openapi-generator", "oapi-codegen")}
+    return cachedStep(cfg, "Go code", cfg.OutputGo, false, options, []string{cfg.GoTemplateDir, cfg.OapiCodegenConfig}, func() error { return generateExternalGo(cfg) })
+}
+
+// generateExternalGo writes the Go output with an installed generator.
+func generateExternalGo(cfg *Config) error {
     // Prefer openapi (if present), then openapi-generator, else oapi-codegen for single file
-    if which("openapi") != "" {
+    if which("openapi") != "" && !cfg.oapiCodegenOptions() {
         out := cfg.OutputGo
         // If .go requested, suggest using oapi-codegen if installed
         if strings.HasSuffix(strings.ToLower(out), ".go") {
-            if which("oapi-codegen") != "" {
+            if which("oapi-codegen") != "" && cfg.GoTemplateDir == "" {
                 pkg := guessPackage(filepath.Dir(out))
-                return runCmd("oapi-codegen", "-generate", "types,client,server", "-o", out, "-package", pkg, cfg.RootPath)
+                return runToTemp(out, func(tmp string) error {
+                    return runCmd("oapi-codegen", cfg.oapiCodegenArgs(tmp, pkg)...)
+                })
             }
-            fmt.Fprintln(os.Stderr, "Tip: install oapi-codegen for single-file Go: go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest")
+            logger.Info("Tip: install oapi-codegen for single-file Go: go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest")
             out = filepath.Dir(out)
         }
         gen := cfg.GoGenerator
         if gen == "" { gen = "go" }
-        return runCmd("openapi", "generate", "-g", gen, "-i", cfg.RootPath, "-o", out)
+        return runCmd("openapi", cfg.generatorArgs(gen, out, cfg.GoTemplateDir)...)
     }
-    if which("openapi-generator") != "" {
+    if which("openapi-generator") != "" && !cfg.oapiCodegenOptions() {
         out := cfg.OutputGo
         if strings.HasSuffix(strings.ToLower(out), ".go") {
-            if which("oapi-codegen") != "" {
+            if which("oapi-codegen") != "" && cfg.GoTemplateDir == "" {
                 pkg := guessPackage(filepath.Dir(out))
-                return runCmd("oapi-codegen", "-generate", "types,client,server", "-o", out, "-package", pkg, cfg.RootPath)
+                return runToTemp(out, func(tmp string) error {
+                    return runCmd("oapi-codegen", cfg.oapiCodegenArgs(tmp, pkg)...)
+                })
             }
-            fmt.Fprintln(os.Stderr, "Tip: install oapi-codegen for single-file Go: go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest")
+            logger.Info("Tip: install oapi-codegen for single-file Go: go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest")
             out = filepath.Dir(out)
         }
         gen := cfg.GoGenerator
         if gen == "" { gen = "go" }
-        return runCmd("openapi-generator", "generate", "-g", gen, "-i", cfg.RootPath, "-o", out)
+        return runCmd("openapi-generator", cfg.generatorArgs(gen, out, cfg.GoTemplateDir)...)
     }
     // As a last resort, single-file generation with oapi-codegen, if available
-    if which("oapi-codegen") != "" {
+    if which("oapi-codegen") != "" && cfg.GoTemplateDir == "" {
         out := cfg.OutputGo
         if !strings.HasSuffix(strings.ToLower(out), ".go") {
             // If directory provided, choose default file name
@@ -618,9 +953,39 @@ func generateGo(cfg *Config) error {
             if err := ensureDir(filepath.Dir(out)); err != nil { return err }
         }
         pkg := guessPackage(filepath.Dir(out))
-        return runCmd("oapi-codegen", "-generate", "types,client,server", "-o", out, "-package", pkg, cfg.RootPath)
+        return runToTemp(out, func(tmp string) error {
+            return runCmd("oapi-codegen", cfg.oapiCodegenArgs(tmp, pkg)...)
+        })
     }
-    return fmt.Errorf("no OpenAPI generator found. Install one of:\n - brew install openapi-generator\n - npm i -g @openapitools/openapi-generator-cli\n - go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest")
+    if cfg.oapiCodegenOptions() {
+        return withExitCode(exitTool, fmt.Errorf("--oapi-codegen-config and --go-generate need oapi-codegen. Install it with:\n - go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest"))
+    }
+    if cfg.GoTemplateDir != "" {
+        return withExitCode(exitTool, fmt.Errorf("--go-template-dir needs openapi-generator. Install one of:\n - brew install openapi-generator\n - npm i -g @openapitools/openapi-generator-cli"))
+    }
+    return withExitCode(exitTool, fmt.Errorf("no OpenAPI generator found. Install one of:\n - brew install openapi-generator\n - npm i -g @openapitools/openapi-generator-cli\n - go install github.com/deepmap/oapi-codegen/v2/cmd/oapi-codegen@latest\nor use --use-docker or --go-engine native"))
+}
+
+// generateNativeGo writes types and a client for the bundled spec with
+// pkg/gogen: to cfg.OutputGo when it names a .go file, else to api.gen.go in
+// that directory. The package is named after the directory.
+func generateNativeGo(cfg *Config) error {
+    out := cfg.OutputGo
+    if !strings.HasSuffix(strings.ToLower(out), ".go") { out = filepath.Join(out, "api.gen.go") }
+    root, err := bundle.Load(cfg.RootPath)
+    if err != nil { return fmt.Errorf("go: %w", err) }
+    if err := postprocessBundle(cfg, root); err != nil { return fmt.Errorf("go: %w", err) }
+    data, err := gogen.Generate(root, gogen.Options{Package: guessPackage(filepath.Dir(out))})
+    if err != nil { return fmt.Errorf("go: %w", err) }
+    if err := ensureDir(filepath.Dir(out)); err != nil { return err }
+    changed, err := atomicfile.WriteFile(out, data)
+    if err != nil { return err }
+    if changed {
+        logger.Info("Wrote Go code", "path", out)
+    } else {
+        logger.Info("Go code unchanged", "path", out)
+    }
+    return nil
 }
 
 func guessPackage(dir string) string {
@@ -642,442 +1007,794 @@ func buildDocsHTML(cfg *Config) error {
     // Prefer bundled spec for docs if available; fallback to root
     input := cfg.BundleOut
     if strings.TrimSpace(input) == "" { input = cfg.RootPath }
+    if err := checkSpecInput(input); err != nil { return err }
+    if input == cfg.RootPath && cfg.postprocessesBundle() {
+        // Render from a filtered bundle, never from the full root.
+        tmp, cleanup, err := processedBundle(cfg)
+        if err != nil { return err }
+        defer cleanup()
+        input = tmp
+    }
 
-    if exe := findRedocly(cfg.Cwd); exe != "" {
-        if err := ensureDir(filepath.Dir(cfg.Redocly)); err != nil { return err }
-        args := []string{"build-docs", input, "--output", cfg.Redocly}
-        if cfg.RedoclyConfig != "" { args = append(args, "--config", cfg.RedoclyConfig) }
-        return runCmd(exe, args...)
+    if cfg.DocsRenderer != engineNative {
+        if exe := findRedocly(cfg.Cwd); exe != "" {
+            if err := ensureDir(filepath.Dir(cfg.Redocly)); err != nil { return err }
+            return cachedStep(cfg, "Docs", cfg.Redocly, true, []string{"docs", toolStamp(exe)}, []string{cfg.RedoclyConfig}, func() error {
+                return runToTemp(cfg.Redocly, func(tmp string) error {
+                    args := []string{"build-docs", input, "--output", tmp}
+                    if cfg.RedoclyConfig != "" { args = append(args, "--config", cfg.RedoclyConfig) }
+                    return runCmd(exe, args...)
+                })
+            })
+        }
+        // Try redoc-cli as alternative
+        if which("redoc-cli") != "" {
+            if err := ensureDir(filepath.Dir(cfg.Redocly)); err != nil { return err }
+            return cachedStep(cfg, "Docs", cfg.Redocly, true, []string{"docs", toolStamp("redoc-cli")}, nil, func() error {
+                return runToTemp(cfg.Redocly, func(tmp string) error { return runCmd("redoc-cli", "build", input, "-o", tmp) })
+            })
+        }
+        if cfg.DocsRenderer == engineRedocly {
+            return withExitCode(exitTool, fmt.Errorf("redocly CLI not found. Install with:\n - npm i -g @redocly/cli\nAlternatively, install redoc-cli: npm i -g redoc-cli, or use --use-docker or --docs-renderer native"))
+        }
+    }
+    changed, err := docs.Write(input, cfg.Redocly)
+    if err != nil { return fmt.Errorf("docs: %w", err) }
+    if changed {
+        logger.Info("Wrote docs", "path", cfg.Redocly)
+    } else {
+        logger.Info("Docs unchanged", "path", cfg.Redocly)
     }
-    // Try redoc-cli as alternative
-    if which("redoc-cli") != "" {
-        if err := ensureDir(filepath.Dir(cfg.Redocly)); err != nil { return err }
-        return runCmd("redoc-cli", "build", input, "-o", cfg.Redocly)
+    return nil
+}
+
+// processedBundle bundles the root natively into a temporary file with
+// postprocessBundle applied, for docs built without --bundle.
+func processedBundle(cfg *Config) (string, func(), error) {
+    root, err := bundle.Load(cfg.RootPath)
+    if err != nil { return "", nil, fmt.Errorf("docs: %w", err) }
+    if err := postprocessBundle(cfg, root); err != nil { return "", nil, fmt.Errorf("docs: %w", err) }
+    dir, err := os.MkdirTemp("", "oas-indexer-docs-")
+    if err != nil { return "", nil, err }
+    cleanup := func() { os.RemoveAll(dir) }
+    tmp := filepath.Join(dir, "openapi.yaml")
+    if _, err := bundle.WriteNode(root, tmp); err != nil {
+        cleanup()
+        return "", nil, err
     }
-    return fmt.Errorf("redocly CLI not found. Install with:\n - npm i -g @redocly/cli\nAlternatively, install redoc-cli: npm i -g redoc-cli")
+    return tmp, cleanup, nil
 }
 
-func bundleWithRedocly(cfg *Config) error {
+// Choices for --bundler and --docs-renderer: auto uses Redocly CLI when it
+// is installed and the built-in Go implementation otherwise. --ts-engine
+// and --go-engine take external in place of redocly.
+const (
+    engineAuto     = "auto"
+    engineRedocly  = "redocly"
+    engineExternal = "external"
+    engineNative   = "native"
+)
+
+func validEngine(s string) bool { return s == engineAuto || s == engineRedocly || s == engineNative }
+
+// bundleSpec writes cfg.BundleOut with Redocly CLI, or with pkg/bundle when
+// --bundler native is set or (with auto) Redocly CLI is not installed.
+func bundleSpec(cfg *Config) error {
     if cfg.BundleOut == "" { return nil }
-    exe := findRedocly(cfg.Cwd)
+    exe := ""
+    if cfg.Bundler != engineNative { exe = findRedocly(cfg.Cwd) }
+    if exe == "" && cfg.Bundler == engineRedocly {
+        return withExitCode(exitTool, fmt.Errorf("redocly CLI not found. Install with one of:\n - npm i -g @redocly/cli\n - npm i -D @redocly/cli (then ensure node_modules/.bin is present), or use --use-docker or --bundler native"))
+    }
     if exe == "" {
-        return fmt.Errorf("redocly CLI not found. Install with one of:\n - npm i -g @redocly/cli\n - npm i -D @redocly/cli (then ensure node_modules/.bin is present)")
+        if err := checkSpecInput(cfg.RootPath); err != nil { return err }
+        root, err := bundle.Load(cfg.RootPath)
+        if err != nil { return fmt.Errorf("bundle: %w", err) }
+        if err := postprocessBundle(cfg, root); err != nil { return fmt.Errorf("bundle: %w", err) }
+        changed, err := bundle.WriteNode(root, cfg.BundleOut)
+        if err != nil { return fmt.Errorf("bundle: %w", err) }
+        if changed {
+            logger.Info("Wrote bundle", "path", cfg.BundleOut)
+        } else {
+            logger.Info("Bundle unchanged", "path", cfg.BundleOut)
+        }
+        return nil
     }
+    return bundleWithRedocly(cfg, exe)
+}
+
+func bundleWithRedocly(cfg *Config, exe string) error {
+    if err := checkSpecInput(cfg.RootPath); err != nil { return err }
     if err := ensureDir(filepath.Dir(cfg.BundleOut)); err != nil { return err }
-    args := []string{"bundle", cfg.RootPath, "-o", cfg.BundleOut}
-    if cfg.RedoclyConfig != "" {
-        args = append(args, "--config", cfg.RedoclyConfig)
-    }
-    return runCmd(exe, args...)
-}
-
-// Validation types and functions
-
-// ValidationRule represents a single validation rule
-type ValidationRule struct {
-	Name        string
-	Description string
-	Validate    func(path string, method string, operation map[string]interface{}) error
-}
-
-// ValidationPreset represents a collection of validation rules
-type ValidationPreset struct {
-	Name        string
-	Description string
-	Rules       []ValidationRule
-}
-
-// ValidationResult holds the validation results
-type ValidationResult struct {
-	Path     string
-	Method   string
-	Rule     string
-	Message  string
-	Severity string // "error" or "warning"
-}
-
-// ValidationConfig holds validation settings
-type ValidationConfig struct {
-	Preset      string
-	StopOnError bool
-	Results     []ValidationResult
-}
-
-// Predefined validation presets
-var ValidationPresets = map[string]ValidationPreset{
-	"google": {
-		Name:        "Google API Design Guide",
-		Description: "Validation rules based on Google's API Design Guide best practices",
-		Rules: []ValidationRule{
-			{
-				Name:        "http-methods-rest",
-				Description: "Use standard HTTP methods (GET, POST, PUT, PATCH, DELETE)",
-				Validate:    validateHTTPMethods,
-			},
-			{
-				Name:        "collection-names-plural",
-				Description: "Collection names should be plural nouns",
-				Validate:    validatePluralCollections,
-			},
-			{
-				Name:        "path-case-kebab",
-				Description: "Path segments should use kebab-case (lowercase with dashes)",
-				Validate:    validateKebabCase,
-			},
-			{
-				Name:        "no-trailing-slash",
-				Description: "Paths should not have trailing slashes",
-				Validate:    validateNoTrailingSlash,
-			},
-			{
-				Name:        "operation-id-present",
-				Description: "All operations should have operationId",
-				Validate:    validateOperationId,
-			},
-			{
-				Name:        "operation-summary-present",
-				Description: "All operations should have summary",
-				Validate:    validateOperationSummary,
-			},
-			{
-				Name:        "response-200-present",
-				Description: "GET operations should have 200 response",
-				Validate:    validateGetResponse200,
-			},
-			{
-				Name:        "resource-id-param",
-				Description: "Resource paths should use {id} parameter naming",
-				Validate:    validateResourceIdParam,
-			},
-		},
-	},
-	"restful": {
-		Name:        "RESTful API Standards",
-		Description: "Common RESTful API design standards",
-		Rules: []ValidationRule{
-			{
-				Name:        "http-methods-rest",
-				Description: "Use standard HTTP methods (GET, POST, PUT, PATCH, DELETE)",
-				Validate:    validateHTTPMethods,
-			},
-			{
-				Name:        "operation-id-present",
-				Description: "All operations should have operationId",
-				Validate:    validateOperationId,
-			},
-			{
-				Name:        "collection-names-plural",
-				Description: "Collection names should be plural nouns",
-				Validate:    validatePluralCollections,
-			},
-			{
-				Name:        "no-trailing-slash",
-				Description: "Paths should not have trailing slashes",
-				Validate:    validateNoTrailingSlash,
-			},
-		},
-	},
-}
-
-// Individual validation functions
-
-func validateHTTPMethods(path string, method string, operation map[string]interface{}) error {
-	validMethods := map[string]bool{
-		"get":     true,
-		"post":    true,
-		"put":     true,
-		"patch":   true,
-		"delete":  true,
-		"head":    true,
-		"options": true,
-	}
-	
-	if !validMethods[strings.ToLower(method)] {
-		return fmt.Errorf("invalid HTTP method '%s', should be one of: GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS", method)
-	}
-	return nil
+    return cachedStep(cfg, "Bundle", cfg.BundleOut, true, []string{"bundle", toolStamp(exe)}, []string{cfg.RedoclyConfig}, func() error {
+        return runToTemp(cfg.BundleOut, func(tmp string) error {
+            args := []string{"bundle", cfg.RootPath, "-o", tmp}
+            if indexer.FormatForFile(cfg.BundleOut) == indexer.FormatJSON {
+                args = append(args, "--ext", "json")
+            }
+            if cfg.RedoclyConfig != "" {
+                args = append(args, "--config", cfg.RedoclyConfig)
+            }
+            if err := runCmd(exe, args...); err != nil { return err }
+            if cfg.postprocessesBundle() { return postprocessBundleFile(cfg, tmp, cfg.BundleOut) }
+            return nil
+        })
+    })
 }
 
-func validatePluralCollections(path string, method string, operation map[string]interface{}) error {
-	// Extract path segments, ignoring parameters
-	segments := strings.Split(strings.Trim(path, "/"), "/")
-	
-	// Common singular words that should be plural in API paths
-	singularWords := []string{
-		"account", "user", "mission", "reward", "partner", "activity", "car", "member",
-		"order", "product", "service", "item", "category", "group", "role", "permission",
-		"resource", "entity", "record", "document", "file", "image", "video", "comment",
+// postprocessBundle converts a bundled spec's OpenAPI 3.1 schema keywords
+// for 3.0 (--downconvert), tags its operations, merges their traits, adds the
+// standard responses and common parameters, applies the overlays and expands placeholders unless the joined root
+// already had that done, and filters it for --audience.
+func postprocessBundle(cfg *Config, root *yaml.Node) error {
+    if cfg.Downconvert { dialect.Downgrade(root) }
+    if !cfg.Join {
+        if cfg.AutoTags {
+            byKey, _, err := indexer.DirTags(cfg.index())
+            if err != nil { return err }
+            indexer.TagOperations(root, byKey)
+        }
+        if err := indexer.ApplyTraits(cfg.index(), root); err != nil { return err }
+        if err := indexer.AddStandardResponses(root, cfg.StandardResponses); err != nil { return err }
+        if err := indexer.AddCommonParameters(cfg.index(), root); err != nil { return err }
+        for _, o := range cfg.Overlays {
+            if err := o.Apply(root); err != nil { return err }
+        }
+        if cfg.Vars != nil {
+            if err := cfg.Vars.Expand(root); err != nil { return err }
+        }
+    }
+    if cfg.FillExamples { examples.Fill(root) }
+    if cfg.Audience != "" { return audience.Filter(root, cfg.Audience) }
+    return nil
+}
+
+// postprocessesBundle reports whether postprocessBundle changes anything.
+func (cfg *Config) postprocessesBundle() bool {
+    if cfg.Downconvert || cfg.Audience != "" || cfg.FillExamples { return true }
+    if cfg.Join { return false }
+    return cfg.AutoTags || len(cfg.StandardResponses) > 0 || len(cfg.Overlays) > 0 || cfg.Vars != nil ||
+        indexer.HasTraits(cfg.index()) || indexer.HasCommonParameters(cfg.index())
+}
+
+// postprocessBundleFile applies postprocessBundle to the bundle at tmp
+// (written for out).
+func postprocessBundleFile(cfg *Config, tmp, out string) error {
+    data, err := os.ReadFile(tmp)
+    if err != nil { return err }
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil { return fmt.Errorf("%s: %v", out, err) }
+    root := yamlnode.DocRoot(&doc)
+    if root == nil { return nil }
+    if err := postprocessBundle(cfg, root); err != nil { return fmt.Errorf("%s: %w", out, err) }
+    marshal := yamlnode.Marshal
+    if indexer.FormatForFile(out) == indexer.FormatJSON { marshal = yamlnode.MarshalJSON }
+    if data, err = marshal(root); err != nil { return fmt.Errorf("%s: %v", out, err) }
+    return os.WriteFile(tmp, data, 0o644)
+}
+
+// loadValidationSpec collects the path, schema and parameter fragments for
+// validation. Unreadable or unparsable files are skipped; CheckFragments
+// reports them.
+func loadValidationSpec(cfg *Config) (*validate.Spec, error) {
+	paths, err := indexer.ListFragments(cfg.index(), cfg.PathsDir)
+	if _, err := indexer.DiscoveryProblems(err); err != nil {
+		return nil, err
 	}
-	
-	for _, segment := range segments {
-		// Skip parameters (enclosed in braces)
-		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+	sort.Strings(paths)
+
+	spec := &validate.Spec{}
+	for _, pathFile := range paths {
+		apiPath, method := indexer.PathKey(cfg.index(), pathFile)
+		if apiPath == "" {
+			continue
+		}
+		pathSpec, lines, ok := readValidationFragment(cfg, pathFile)
+		if !ok {
 			continue
 		}
-		
-		// Skip version segments
-		if matched, _ := regexp.MatchString(`^v\d+$`, segment); matched {
+		if method != "" {
+			pathSpec, lines = map[string]interface{}{method: pathSpec}, map[string]int{method: 1}
+		}
+		spec.Paths = append(spec.Paths, validate.Path{
+			Key:   apiPath,
+			File:  indexer.DisplayPath(cfg.index(), pathFile),
+			Item:  pathSpec,
+			Lines: lines,
+		})
+	}
+
+	for _, c := range indexer.Components {
+		var defs *[]validate.Component
+		switch c.Section {
+		case "schemas":
+			defs = &spec.Schemas
+		case "parameters":
+			defs = &spec.Parameters
+		default:
 			continue
 		}
-		
-		// Check if segment is a known singular word
-		for _, singular := range singularWords {
-			if strings.ToLower(segment) == singular {
-				plural := makePlural(singular)
-				return fmt.Errorf("collection name '%s' should be plural: '%s'", segment, plural)
+		files, err := indexer.ComponentFiles(cfg.index(), c)
+		if _, err := indexer.DiscoveryProblems(err); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			name := cfg.index().ComponentNameFor(f)
+			def, lines, ok := readValidationFragment(cfg, f)
+			if !ok {
+				continue
 			}
+			if keys := indexer.DefinitionKeys(cfg.index(), c, f); keys != nil {
+				for _, k := range keys {
+					sub, _ := def[k].(map[string]interface{})
+					*defs = append(*defs, validate.Component{
+						Name: k,
+						File: indexer.DisplayPath(cfg.index(), f),
+						Line: lines[k],
+						Def:  sub,
+					})
+				}
+				continue
+			}
+			if name == "" {
+				continue
+			}
+			*defs = append(*defs, validate.Component{
+				Name: name,
+				File: indexer.DisplayPath(cfg.index(), f),
+				Line: 1,
+				Def:  def,
+			})
 		}
 	}
-	return nil
+	return spec, nil
 }
 
-func validateKebabCase(path string, method string, operation map[string]interface{}) error {
-	// Extract path segments, ignoring parameters
-	segments := strings.Split(strings.Trim(path, "/"), "/")
-	kebabRegex := regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$|^v\d+$|^\{[^}]+\}$`)
-	
-	for _, segment := range segments {
-		if !kebabRegex.MatchString(segment) {
-			return fmt.Errorf("path segment '%s' should use kebab-case (lowercase with dashes)", segment)
+// readValidationFragment decodes a fragment mapping and the line of each of
+// its top-level keys.
+func readValidationFragment(cfg *Config, file string) (map[string]interface{}, map[string]int, bool) {
+	content, err := indexer.ReadFragment(cfg.index(), file)
+	if err != nil {
+		return nil, nil, false
+	}
+	var doc yaml.Node
+	var out map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, nil, false
+	}
+	if err := doc.Decode(&out); err != nil || out == nil {
+		return nil, nil, false
+	}
+	lines := map[string]int{}
+	if root := yamlnode.DocRoot(&doc); root != nil && root.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			lines[root.Content[i].Value] = root.Content[i].Line
 		}
 	}
-	return nil
+	return out, lines, true
 }
 
-func validateNoTrailingSlash(path string, method string, operation map[string]interface{}) error {
-	if len(path) > 1 && strings.HasSuffix(path, "/") {
-		return fmt.Errorf("path should not have trailing slash")
+func validatePaths(cfg *Config) error {
+	preset, exists := validate.Presets[cfg.ValidatePreset]
+	if !exists {
+		return withExitCode(exitConfig, fmt.Errorf("unknown validation preset: %s", cfg.ValidatePreset))
 	}
-	return nil
-}
+	preset, err := preset.WithOverrides(cfg.RuleOverrides)
+	if err != nil {
+		return err
+	}
+	out := cfg.progressOut()
+
+	fmt.Fprintf(out, "Running validation with preset: %s\n", preset.Name)
+	fmt.Fprintf(out, "Description: %s\n", preset.Description)
+	fmt.Fprintf(out, "Rules: %d\n\n", len(preset.Rules))
 
-func validateOperationId(path string, method string, operation map[string]interface{}) error {
-	if _, exists := operation["operationId"]; !exists {
-		return fmt.Errorf("operation should have operationId")
+	spec, err := loadValidationSpec(cfg)
+	if err != nil {
+		return err
+	}
+	report := validate.Report{Preset: preset, Spec: spec}
+	ignored := 0
+	for _, result := range validate.RunPreset(spec, preset) {
+		if result.Ignored {
+			ignored++
+			report.Skipped = append(report.Skipped, result)
+			continue
+		}
+		report.Results = append(report.Results, result)
+	}
+	if cfg.UpdateBaseline && !cfg.DryRun {
+		baseline := validate.NewBaseline(report.Results)
+		if _, err := baseline.Write(cfg.Baseline); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Recorded %d finding(s) in baseline %s\n", len(baseline.Findings), cfg.Baseline)
+		return nil
+	}
+	suppressed, stale := 0, 0
+	if cfg.Baseline != "" {
+		baseline, err := validate.LoadBaseline(cfg.Baseline)
+		if err != nil {
+			return err
+		}
+		fresh, n := baseline.Filter(report.Results)
+		suppressed, stale = len(report.Results)-len(fresh), n
+		report.Skipped = append(report.Skipped, baselined(report.Results, fresh)...)
+		report.Results = fresh
 	}
-	return nil
-}
 
-func validateOperationSummary(path string, method string, operation map[string]interface{}) error {
-	if _, exists := operation["summary"]; !exists {
-		return fmt.Errorf("operation should have summary")
+	counts := map[string]int{}
+	for _, result := range report.Results {
+		counts[result.Severity]++
+	}
+	if cfg.ValidateFormat != validate.ReportText {
+		if err := writeValidationReport(cfg, report); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range report.Results {
+			fmt.Fprintf(out, "%s %s %s - %s: %s\n",
+				severityIcons[result.Severity],
+				result.Method,
+				result.Path,
+				result.Rule,
+				result.Message)
+
+			if cfg.ValidateStopOnError && result.Severity == validate.SeverityError {
+				return fmt.Errorf("validation failed on first error")
+			}
+		}
 	}
-	return nil
-}
 
-func validateGetResponse200(path string, method string, operation map[string]interface{}) error {
-	if strings.ToLower(method) != "get" {
-		return nil // Skip non-GET methods
+	// Print summary
+	notes := ""
+	if n := counts[validate.SeverityWarning] + counts[validate.SeverityInfo]; n > 0 {
+		notes = fmt.Sprintf(" (%d warning(s), %d info)", counts[validate.SeverityWarning], counts[validate.SeverityInfo])
+	}
+	if ignored > 0 {
+		notes += fmt.Sprintf(" (%d finding(s) ignored via %s)", ignored, validate.IgnoreExtension)
+	}
+	if suppressed > 0 {
+		notes += fmt.Sprintf(" (%d baseline finding(s) suppressed)", suppressed)
 	}
-	
-	responses, ok := operation["responses"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("GET operation should have responses defined")
+	if stale > 0 {
+		fmt.Fprintf(out, "\n%d baseline finding(s) no longer occur; run with --update-baseline to prune them\n", stale)
 	}
-	
-	if _, exists := responses["200"]; !exists {
-		return fmt.Errorf("GET operation should have 200 response")
+	if counts[validate.SeverityError] > 0 {
+		fmt.Fprintf(out, "\n❌ Validation failed with %d error(s)%s\n", counts[validate.SeverityError], notes)
+		return errors.New("validation failed")
+	} else {
+		fmt.Fprintf(out, "\n✅ All validations passed!%s\n", notes)
 	}
+
 	return nil
 }
 
-func validateResourceIdParam(path string, method string, operation map[string]interface{}) error {
-	// Check for parameter patterns that don't follow {id} convention
-	paramRegex := regexp.MustCompile(`\{([^}]+)\}`)
-	matches := paramRegex.FindAllStringSubmatch(path, -1)
-	
-	for _, match := range matches {
-		paramName := match[1]
-		// Allow common patterns but suggest {id} for simple resource identifiers
-		if !isValidParamName(paramName) {
-			return fmt.Errorf("parameter '{%s}' should follow naming convention (consider using {id} for resource identifiers)", paramName)
+// baselined returns the results that Filter dropped from all to get fresh.
+func baselined(all, fresh []validate.Result) []validate.Result {
+	var out []validate.Result
+	for i, j := 0, 0; i < len(all); i++ {
+		if j < len(fresh) && all[i] == fresh[j] {
+			j++
+			continue
 		}
+		out = append(out, all[i])
 	}
-	return nil
+	return out
 }
 
-// Helper functions
+// progressOut is where human-readable validation output goes: stderr when a
+// machine-readable report is written to stdout.
+func (cfg *Config) progressOut() io.Writer {
+	if cfg.ValidateFormat != validate.ReportText && cfg.ValidateReport == "" { return os.Stderr }
+	return os.Stdout
+}
 
-func makePlural(word string) string {
-	// Simple pluralization rules
-	switch {
-	case strings.HasSuffix(word, "y"):
-		return strings.TrimSuffix(word, "y") + "ies"
-	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
-		return word + "es"
-	default:
-		return word + "s"
-	}
+func writeValidationReport(cfg *Config, report validate.Report) error {
+	if cfg.ValidateReport == "" || cfg.DryRun { return report.Write(os.Stdout, cfg.ValidateFormat) }
+	var buf bytes.Buffer
+	if err := report.Write(&buf, cfg.ValidateFormat); err != nil { return err }
+	if err := ensureDir(filepath.Dir(cfg.ValidateReport)); err != nil { return err }
+	if _, err := atomicfile.WriteFile(cfg.ValidateReport, buf.Bytes()); err != nil { return err }
+	fmt.Fprintf(cfg.progressOut(), "Wrote validation report: %s\n", cfg.ValidateReport)
+	return nil
 }
 
-func isValidParamName(name string) bool {
-	// Allow common parameter naming patterns
-	validPatterns := []string{
-		"id", "userId", "accountId", "missionId", "partnerId", 
-		"carId", "activityId", "rewardId", "memberId",
-	}
-	
-	for _, pattern := range validPatterns {
-		if name == pattern {
-			return true
-		}
-	}
-	
-	// Allow patterns like "xxxId"
-	if strings.HasSuffix(name, "Id") && len(name) > 2 {
-		return true
-	}
-	
-	return false
+// checkStructure reports every OpenAPI specification violation in the spec
+// at path, or in data read as that spec when not nil, failing when there is any.
+func checkStructure(cfg *Config, path string, data []byte) error {
+    out := cfg.progressOut()
+    if cfg.OpenAPIVersion == indexer.OpenAPI31 {
+        fmt.Fprintln(out, "ℹ️  Structural check covers OpenAPI 3.0 only; skipped for --openapi-version 3.1")
+        return nil
+    }
+    var errs []validate.StructuralError
+    if data != nil {
+        errs = validate.StructuralData(path, data)
+    } else {
+        var err error
+        if errs, err = validate.Structural(path); err != nil { return fmt.Errorf("structural check: %w", err) }
+    }
+    for _, e := range errs {
+        ptr := e.Pointer
+        if ptr == "" { ptr = "(document)" }
+        fmt.Fprintf(out, "%s %s: %s\n", severityIcons[validate.SeverityError], ptr, e.Message)
+    }
+    if len(errs) > 0 {
+        fmt.Fprintf(out, "\n❌ Structural check failed with %d error(s)\n", len(errs))
+        return withExitCode(exitValidation, fmt.Errorf("structural check failed: %d violation(s) of the OpenAPI specification", len(errs)))
+    }
+    fmt.Fprintln(out, "✅ Structural check passed")
+    return nil
 }
 
-// Main validation functions
+// checkExamples reports every example in the fragments that does not match
+// its schema, failing when there is any.
+func checkExamples(cfg *Config) error {
+    out := cfg.progressOut()
+    fragments, root, err := indexer.JoinedFragments(cfg.index())
+    if err != nil { return err }
+    n := 0
+    for _, f := range fragments {
+        for _, e := range validate.Examples(f.Node, f.Schema, root) {
+            fmt.Fprintf(out, "%s %s:%d %s: %s\n", severityIcons[validate.SeverityError], f.File, e.Line, f.Pointer+e.Pointer, e.Message)
+            n++
+        }
+    }
+    if n > 0 {
+        fmt.Fprintf(out, "\n❌ Example check failed with %d error(s)\n", n)
+        return fmt.Errorf("example check failed: %d example value(s) do not match their schema", n)
+    }
+    fmt.Fprintln(out, "✅ Example check passed")
+    return nil
+}
 
-func validatePaths(cfg *Config, validationCfg *ValidationConfig) error {
-	preset, exists := ValidationPresets[validationCfg.Preset]
-	if !exists {
-		return fmt.Errorf("unknown validation preset: %s", validationCfg.Preset)
-	}
-	
-	fmt.Printf("Running validation with preset: %s\n", preset.Name)
-	fmt.Printf("Description: %s\n", preset.Description)
-	fmt.Printf("Rules: %d\n\n", len(preset.Rules))
-	
-	paths, err := listYAMLFiles(cfg.PathsDir)
-	if err != nil {
-		return err
-	}
-	
-	hasErrors := false
-	
-	for _, pathFile := range paths {
-		apiPath := buildPathKey(cfg.PathsDir, pathFile)
-		if apiPath == "" {
+var severityIcons = map[string]string{
+	validate.SeverityError:   "❌",
+	validate.SeverityWarning: "⚠️ ",
+	validate.SeverityInfo:    "ℹ️ ",
+}
+
+// parseRuleOverrides parses "rule=severity,rule=severity".
+func parseRuleOverrides(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
 			continue
 		}
-		
-		// Parse the YAML file to extract operations
-		content, err := ioutil.ReadFile(pathFile)
+		name, level, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --rules entry %q (expected rule=severity)", part)
+		}
+		sev, err := validate.ParseSeverity(level)
 		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", pathFile, err)
+			return nil, fmt.Errorf("--rules %s: %v", strings.TrimSpace(name), err)
 		}
-		
-		var pathSpec map[string]interface{}
-		if err := yaml.Unmarshal(content, &pathSpec); err != nil {
-			return fmt.Errorf("failed to parse %s: %w", pathFile, err)
+		out[strings.TrimSpace(name)] = sev
+	}
+	return out, nil
+}
+
+// parseNameMap parses "file=Name,file=Name"; files are relative to the input
+// directory and use forward slashes.
+func parseNameMap(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
 		}
-		
-		// Validate each HTTP method in the path
-		for method, operationRaw := range pathSpec {
-			operation, ok := operationRaw.(map[string]interface{})
-			if !ok {
-				continue // Skip non-operation fields
-			}
-			
-			// Run all validation rules
-			for _, rule := range preset.Rules {
-				if err := rule.Validate(apiPath, method, operation); err != nil {
-					result := ValidationResult{
-						Path:     apiPath,
-						Method:   strings.ToUpper(method),
-						Rule:     rule.Name,
-						Message:  err.Error(),
-						Severity: "error",
-					}
-					validationCfg.Results = append(validationCfg.Results, result)
-					
-					// Print error immediately
-					fmt.Printf("❌ %s %s - %s: %s\n", 
-						result.Method, 
-						result.Path, 
-						result.Rule, 
-						result.Message)
-					
-					hasErrors = true
-					
-					if validationCfg.StopOnError {
-						return fmt.Errorf("validation failed on first error")
-					}
-				}
-			}
+		file, name, ok := strings.Cut(part, "=")
+		file = filepath.ToSlash(filepath.Clean(strings.TrimSpace(file)))
+		if !ok || file == "." || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --name-map entry %q (expected file=Name)", part)
 		}
+		out[file] = strings.TrimSpace(name)
 	}
-	
-	// Print summary
-	if hasErrors {
-		fmt.Printf("\n❌ Validation failed with %d error(s)\n", len(validationCfg.Results))
-		return errors.New("validation failed")
-	} else {
-		fmt.Printf("\n✅ All validations passed!\n")
+	return out, nil
+}
+
+// parseStandardResponses parses "code=Name,code=Name".
+func parseStandardResponses(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		code, name, ok := strings.Cut(part, "=")
+		code = strings.TrimSpace(code)
+		if !ok || !indexer.ValidResponseCode(code) || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --standard-responses entry %q (expected code=Name, e.g. 401=Unauthorized)", part)
+		}
+		out[code] = strings.TrimSpace(name)
 	}
-	
-	return nil
+	return out, nil
 }
 
 func listAvailablePresets() {
 	fmt.Println("Available validation presets:")
-	for key, preset := range ValidationPresets {
+	for key, preset := range validate.Presets {
 		fmt.Printf("  %s: %s\n", key, preset.Description)
 		fmt.Printf("    Rules: %d\n", len(preset.Rules))
 	}
 }
 
-func run(cfg *Config) error {
-    if err := ensureDir(cfg.OutputDir); err != nil { return err }
+// printProblems logs each problem as an error record of its own.
+func printProblems(problems indexer.FragmentErrors) {
+    for _, p := range problems {
+        logger.Error(p.Error())
+    }
+}
+
+// checkInput reports every per-file problem and, unless skipped, runs the
+// configured validation preset, failing once after everything was reported.
+func checkInput(cfg *Config) error {
+    if !cfg.DryRun {
+        if err := checkToolPins(cfg); err != nil { return err }
+        if err := syncDeps(cfg); err != nil { return err }
+    }
+    if cfg.PerVersion && cfg.Strict {
+        if err := cfg.knowVersionOutputs(); err != nil { return err }
+    }
+    // Check every fragment upfront; per-file problems are collected rather
+    // than aborting at the first one, and reported with file/line context
+    problems, err := indexer.CheckFragments(cfg.index())
+    if err != nil { return err }
+    cycles, err := cycleProblems(cfg)
+    if err != nil { return err }
+    problems = append(problems, cycles...)
+    if cfg.PerVersion && !cfg.Strict { // --strict reports them with the layout
+        _, outside, err := indexer.PathVersions(cfg.index())
+        if err != nil { return err }
+        problems = append(problems, outside...)
+    }
+    printProblems(problems)
 
     // Run validation first if configured
+    var validationErr error
     if !cfg.SkipValidation && cfg.ValidatePreset != "" {
-        validationCfg := &ValidationConfig{
-            Preset:      cfg.ValidatePreset,
-            StopOnError: cfg.ValidateStopOnError,
-            Results:     []ValidationResult{},
+        validationErr = validatePaths(cfg)
+        var ee *exitError
+        if errors.As(validationErr, &ee) && ee.code == exitConfig { return validationErr } // no preset ran
+        fmt.Fprintln(cfg.progressOut()) // Add spacing after validation
+    }
+    if !cfg.SkipValidation && cfg.Examples {
+        if err := checkExamples(cfg); err != nil && validationErr == nil { validationErr = err }
+        fmt.Fprintln(cfg.progressOut())
+    }
+
+    // Fail once, after everything has been reported
+    switch {
+    case len(problems) > 0 && validationErr != nil:
+        return withExitCode(exitValidation, fmt.Errorf("%d fragment problem(s) in input tree; %w", len(problems), validationErr))
+    case len(problems) > 0:
+        return withExitCode(exitValidation, fmt.Errorf("%d fragment problem(s) in input tree", len(problems)))
+    case validationErr != nil:
+        return withExitCode(exitValidation, validationErr) // already says what failed
+    }
+    return nil
+}
+
+// writeRoot writes the reference-style or joined root and reports whether it changed.
+func writeRoot(cfg *Config) error {
+    if err := ensureDir(cfg.OutputDir); err != nil { return err }
+    if cfg.Version == "" {
+        if err := reportUnused(cfg); err != nil { return err } // forEachVersion reports them once
+    }
+    forgetSpecHashes(cfg.RootPath)
+    changed, err := indexer.BuildRoot(cfg.index())
+    if err != nil {
+        if cfg.Join { return fmt.Errorf("building joined root YAML: %w", err) }
+        return fmt.Errorf("building reference-style root YAML: %w", err)
+    }
+    if changed {
+        logger.Info("Wrote root spec", "path", cfg.RootPath)
+    } else {
+        logger.Info("Root spec unchanged", "path", cfg.RootPath)
+    }
+    if cfg.Downconvert && !cfg.Join && (cfg.OutputTS != "" || cfg.OutputGo != "") {
+        logger.Warn("--downconvert leaves a reference-style root pointing at the fragments as written; generators read it with their OpenAPI 3.1 keywords (add --join)")
+    }
+    return nil
+}
+
+// forEachVersion calls fn with cfg or, with --per-version, once for every
+// version directory below paths/ (see forVersion).
+func forEachVersion(cfg *Config, fn func(*Config) error) error {
+    if !cfg.PerVersion { return fn(cfg) }
+    versions, _, err := indexer.PathVersions(cfg.index())
+    if err != nil { return err }
+    if len(versions) == 0 {
+        return fmt.Errorf("--per-version: no version directories below %s", indexer.DisplayPath(cfg.index(), cfg.PathsDir))
+    }
+    if err := reportUnused(cfg); err != nil { return err }
+    for _, v := range versions {
+        if err := fn(cfg.forVersion(v)); err != nil { return fmt.Errorf("version %s: %w", v, err) }
+    }
+    return nil
+}
+
+// forVersion returns a copy of cfg building the root of the version
+// directory v. The root and the bundle and docs files are named after it
+// (root.v1.yaml, dist/openapi.v1.yaml), generated code goes to a v subdirectory.
+func (cfg *Config) forVersion(v string) *Config {
+    vc := *cfg
+    vc.Version = v
+    vc.RootFile = versionedName(cfg.RootFile, v)
+    vc.RootPath = versionedName(cfg.RootPath, v)
+    if cfg.BundleOut != "" { vc.BundleOut = versionedName(cfg.BundleOut, v) }
+    if cfg.Redocly != "" { vc.Redocly = versionedName(cfg.Redocly, v) }
+    if cfg.OutputTS != "" { vc.OutputTS = filepath.Join(cfg.OutputTS, v) }
+    if cfg.OutputGo != "" { vc.OutputGo = filepath.Join(cfg.OutputGo, v) }
+    vc.Generators = nil
+    for _, g := range cfg.Generators {
+        vc.Generators = append(vc.Generators, externalGenerator{Name: g.Name, Out: filepath.Join(g.Out, v), Args: g.Args})
+    }
+    return &vc
+}
+
+// knowVersionOutputs adds the roots and outputs of every version to Known,
+// so that --strict does not report them as files outside the layout.
+func (cfg *Config) knowVersionOutputs() error {
+    versions, _, err := indexer.PathVersions(cfg.index())
+    if err != nil { return err }
+    for _, v := range versions {
+        vc := cfg.forVersion(v)
+        for _, p := range append([]string{vc.RootPath}, vc.outputs()...) {
+            if strings.TrimSpace(p) == "" { continue }
+            if p = absJoin(cfg.Cwd, p); !slices.Contains(cfg.Known, p) { cfg.Known = append(cfg.Known, p) }
         }
-        
-        if err := validatePaths(cfg, validationCfg); err != nil {
-            return fmt.Errorf("validation failed: %w", err)
+    }
+    return nil
+}
+
+// versionedName inserts v before the extension of name: root.yaml becomes root.v1.yaml.
+func versionedName(name, v string) string {
+    ext := filepath.Ext(name)
+    return strings.TrimSuffix(name, ext) + "." + v + ext
+}
+
+// How circular $refs between components are reported (--cycles).
+const (
+    cyclesWarn  = "warn"
+    cyclesError = "error"
+    cyclesOff   = "off"
+)
+
+// cycleProblems finds circular $refs between components. They are printed as
+// warnings, or returned as problems with --cycles error.
+func cycleProblems(cfg *Config) (indexer.FragmentErrors, error) {
+    if cfg.Cycles == cyclesOff { return nil, nil }
+    cycles, err := indexer.RefCycles(cfg.index())
+    if err != nil { return nil, err }
+    var problems indexer.FragmentErrors
+    for _, c := range cycles {
+        var chain []string
+        for _, s := range c {
+            chain = append(chain, fmt.Sprintf("%s (%s:%d)", s.Node, indexer.DisplayPath(cfg.index(), s.File), s.Line))
         }
-        fmt.Println() // Add spacing after validation
+        msg := "circular $ref: " + strings.Join(chain, " -> ") + " -> " + c[0].Node
+        if cfg.Cycles == cyclesError {
+            problems = append(problems, indexer.FragmentError{File: indexer.DisplayPath(cfg.index(), c[0].File), Line: c[0].Line, Message: msg})
+            continue
+        }
+        logger.Warn(msg)
     }
+    return problems, nil
+}
 
-    if cfg.Join {
-        if err := writeRootJoinedYAML(cfg); err != nil {
-            return fmt.Errorf("building joined root YAML: %w", err)
+// reportUnused lists the components no path fragment references: as
+// warnings, or as pruned from the root when --prune is set. Unused shared
+// components are left out silently; other APIs may use them.
+func reportUnused(cfg *Config) error {
+    all, err := indexer.UnusedComponents(cfg.index())
+    if err != nil { return err }
+    var unused []indexer.UnusedComponent
+    for _, u := range all {
+        if !u.Shared { unused = append(unused, u) }
+    }
+    if len(unused) == 0 { return nil }
+    if cfg.Prune {
+        logger.Info(fmt.Sprintf("Pruned %d unused component(s):", len(unused)))
+        for _, u := range unused {
+            logger.Info(fmt.Sprintf("  %s/%s (%s)", u.Section, u.Name, indexer.DisplayPath(cfg.index(), u.File)))
         }
-    } else {
-        if err := writeRootYAML(cfg); err != nil {
-            return fmt.Errorf("building reference-style root YAML: %w", err)
+        return nil
+    }
+    for _, u := range unused {
+        logger.Warn(fmt.Sprintf("component %s/%s (%s) is not referenced from any path, webhook or event", u.Section, u.Name, indexer.DisplayPath(cfg.index(), u.File)))
+    }
+    logger.Warn(fmt.Sprintf("%d unused component(s); pass --prune to leave them out of the root", len(unused)))
+    return nil
+}
+
+// runAll runs the pipeline for every API in turn, reporting each failure and
+// failing at the end when any API failed.
+func runAll(cfgs []*Config) error {
+    if len(cfgs) == 1 { return run(cfgs[0]) }
+    var failed []string
+    code := 0 // the exit status of every failure, if they share one
+    for _, cfg := range cfgs {
+        fmt.Fprintf(cfg.progressOut(), "== %s (%s)\n", cfg.API, indexer.DisplayPath(cfg.index(), cfg.InputDir))
+        bundle.Remote = cfg.Vendor.Fetch
+        if err := run(cfg); err != nil {
+            logger.Error(cfg.API + ": " + err.Error())
+            failed = append(failed, cfg.API)
+            if c := exitStatus(err); code == 0 || code == c { code = c } else { code = exitFailure }
         }
     }
-    fmt.Fprintf(os.Stdout, "Wrote root spec: %s\n", cfg.RootPath)
+    if len(failed) > 0 {
+        return withExitCode(code, fmt.Errorf("%d of %d apis failed: %s", len(failed), len(cfgs), strings.Join(failed, ", ")))
+    }
+    return nil
+}
 
-    if err := generateTypeScript(cfg); err != nil {
-        return err
+func run(cfg *Config) error {
+    if err := logTimed("check", func() error { return checkInput(cfg) }); err != nil { return err }
+    if cfg.DryRun {
+        if err := dryRunAsyncAPI(cfg); err != nil { return err }
+        return dryRun(cfg)
     }
-    if err := generateGo(cfg); err != nil {
-        return err
+    if err := logTimed("asyncapi", func() error { return writeAsyncAPI(cfg) }); err != nil { return err }
+    return forEachVersion(cfg, runOutputs)
+}
+
+// writeAsyncAPI writes the AsyncAPI document when the input tree has event
+// fragments. It is built once, whatever --per-version says: channels are not
+// versioned like paths.
+func writeAsyncAPI(cfg *Config) error {
+    ok, err := indexer.HasEvents(cfg.index())
+    if err != nil || !ok { return err }
+    if err := ensureDir(filepath.Dir(cfg.AsyncAPIPath)); err != nil { return err }
+    changed, err := indexer.BuildAsyncAPI(cfg.index())
+    if err != nil { return fmt.Errorf("building AsyncAPI document: %w", err) }
+    if changed {
+        logger.Info("Wrote AsyncAPI document", "path", cfg.AsyncAPIPath)
+    } else {
+        logger.Info("AsyncAPI document unchanged", "path", cfg.AsyncAPIPath)
+    }
+    return nil
+}
+
+// runOutputs writes the root and everything built from it.
+func runOutputs(cfg *Config) error {
+    step := func(name string, fn func(*Config) error) error { return logTimed(name, func() error { return fn(cfg) }) }
+    if err := step("root", writeRoot); err != nil { return err }
+    if cfg.Structural {
+        if err := checkStructure(cfg, cfg.RootPath, nil); err != nil { return err }
     }
-    if err := bundleWithRedocly(cfg); err != nil { return err }
-    if err := buildDocsHTML(cfg); err != nil {
+
+    if err := step("generate", generateCode); err != nil { return err }
+    if err := step("bundle", bundleSpec); err != nil { return err }
+    if err := step("docs", buildDocsHTML); err != nil {
         return err
     }
     return nil
 }
 
 func main() {
-    cfg, err := buildConfig()
+    handleInterrupts()
+    if len(os.Args) > 1 && commands[os.Args[1]] != nil {
+        if err := commands[os.Args[1]](os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+            logger.Error(err.Error())
+            os.Exit(exitStatus(err))
+        }
+        return
+    }
+
+    cfgs, err := buildConfigs()
     if err != nil {
-        fmt.Fprintln(os.Stderr, err)
-        os.Exit(1)
+        logger.Error(err.Error())
+        os.Exit(exitStatus(err))
     }
     
     // Handle special case where we just listed presets
-    if cfg == nil {
+    if cfgs == nil {
         return
     }
     
-    if err := run(cfg); err != nil {
-        fmt.Fprintln(os.Stderr, err)
-        os.Exit(1)
+    if err := runAll(cfgs); err != nil {
+        logger.Error(err.Error())
+        os.Exit(exitStatus(err))
     }
 }
 
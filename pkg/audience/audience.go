@@ -0,0 +1,267 @@
+// Package audience filters a bundled OpenAPI document down to what one
+// audience, such as public, partner or internal, may see.
+//
+// Path items, operations, parameters, components and schema properties name
+// their audiences in x-audience (a name or a list of names); x-internal: true
+// is short for x-audience: internal. Anything without either is visible to
+// every audience. Filtering drops what the audience may not see, then the
+// components and tags only dropped operations used, and strips the markers.
+package audience
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Extensions marking who may see an object.
+const (
+    AudienceKey = "x-audience"
+    InternalKey = "x-internal"
+)
+
+// Visible reports whether the object n is meant for audience.
+func Visible(n *yaml.Node, audience string) bool {
+    if n == nil || n.Kind != yaml.MappingNode { return true }
+    var names []string
+    if v := yamlnode.GetKey(n, AudienceKey); v != nil {
+        switch v.Kind {
+        case yaml.ScalarNode:
+            names = append(names, v.Value)
+        case yaml.SequenceNode:
+            for _, c := range v.Content {
+                names = append(names, c.Value)
+            }
+        }
+    }
+    if v := yamlnode.GetKey(n, InternalKey); v != nil && v.Value == "true" { names = append(names, "internal") }
+    if len(names) == 0 { return true }
+    for _, name := range names {
+        if strings.EqualFold(strings.TrimSpace(name), audience) { return true }
+    }
+    return false
+}
+
+// Filter removes from the bundled document root everything not meant for
+// audience, and the components and tags that only removed parts used. It
+// fails when a kept part still refers to a removed component other than
+// through a property or parameter, which are removed along with it.
+func Filter(root *yaml.Node, audience string) error {
+    if root == nil || root.Kind != yaml.MappingNode { return fmt.Errorf("spec must be a mapping") }
+    usedBefore, tagsBefore := reachable(root), operationTags(root)
+
+    hidden := map[string]bool{} // "section/name" of components removed for being marked
+    if comps := yamlnode.GetKey(root, "components"); comps != nil && comps.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(comps.Content); i += 2 {
+            section := comps.Content[i].Value
+            filterMapping(comps.Content[i+1], func(name string, v *yaml.Node) bool {
+                if Visible(v, audience) { return true }
+                hidden[section+"/"+name] = true
+                return false
+            })
+        }
+    }
+    for _, key := range []string{"paths", "webhooks"} {
+        filterMapping(yamlnode.GetKey(root, key), func(_ string, item *yaml.Node) bool {
+            return filterPathItem(item, audience)
+        })
+    }
+    filterNested(root, audience, hidden)
+
+    // Components no longer reachable were only used by what was removed.
+    usedAfter := reachable(root)
+    if comps := yamlnode.GetKey(root, "components"); comps != nil && comps.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(comps.Content); i += 2 {
+            section := comps.Content[i].Value
+            filterMapping(comps.Content[i+1], func(name string, _ *yaml.Node) bool {
+                return !usedBefore[section+"/"+name] || usedAfter[section+"/"+name]
+            })
+        }
+    }
+    tagsAfter := operationTags(root)
+    if tags := yamlnode.GetKey(root, "tags"); tags != nil && tags.Kind == yaml.SequenceNode {
+        var kept []*yaml.Node
+        for _, t := range tags.Content {
+            name := ""
+            if v := yamlnode.GetKey(t, "name"); v != nil { name = v.Value }
+            if !Visible(t, audience) || tagsBefore[name] && !tagsAfter[name] { continue }
+            kept = append(kept, t)
+        }
+        tags.Content = kept
+    }
+    stripMarkers(root)
+
+    var dangling []string
+    for _, v := range refs(root) {
+        if target := componentTarget(v.Value); hidden[target] { dangling = append(dangling, target) }
+    }
+    if len(dangling) > 0 {
+        return fmt.Errorf("audience %s: kept parts of the spec still refer to hidden component(s) %s; mark them for this audience too", audience, strings.Join(unique(dangling), ", "))
+    }
+    return nil
+}
+
+// filterPathItem removes the operations of item not meant for audience and
+// reports whether the path item itself is kept.
+func filterPathItem(item *yaml.Node, audience string) bool {
+    if !Visible(item, audience) { return false }
+    if item.Kind != yaml.MappingNode { return true }
+    ops, kept := 0, 0
+    for _, m := range httpMethods {
+        op := yamlnode.GetKey(item, m)
+        if op == nil { continue }
+        ops++
+        if !Visible(op, audience) {
+            yamlnode.DeleteKey(item, m)
+            continue
+        }
+        kept++
+    }
+    return ops == 0 || kept > 0
+}
+
+// filterNested removes the parameters (in parameters lists) and schema
+// properties below n that are not meant for audience or refer to a hidden
+// component, keeping required lists in step.
+func filterNested(n *yaml.Node, audience string, hidden map[string]bool) {
+    keep := func(v *yaml.Node) bool {
+        if !Visible(v, audience) { return false }
+        if ref := yamlnode.GetKey(v, "$ref"); ref != nil && hidden[componentTarget(ref.Value)] { return false }
+        return true
+    }
+    if n.Kind == yaml.MappingNode {
+        if params := yamlnode.GetKey(n, "parameters"); params != nil && params.Kind == yaml.SequenceNode {
+            var kept []*yaml.Node
+            for _, p := range params.Content {
+                if keep(p) { kept = append(kept, p) }
+            }
+            if len(kept) == 0 && len(params.Content) > 0 {
+                yamlnode.DeleteKey(n, "parameters")
+            } else {
+                params.Content = kept
+            }
+        }
+        if props := yamlnode.GetKey(n, "properties"); props != nil && props.Kind == yaml.MappingNode {
+            removed := map[string]bool{}
+            filterMapping(props, func(name string, v *yaml.Node) bool {
+                if keep(v) { return true }
+                removed[name] = true
+                return false
+            })
+            if req := yamlnode.GetKey(n, "required"); req != nil && req.Kind == yaml.SequenceNode && len(removed) > 0 {
+                var kept []*yaml.Node
+                for _, r := range req.Content {
+                    if !removed[r.Value] { kept = append(kept, r) }
+                }
+                req.Content = kept
+                if len(kept) == 0 { yamlnode.DeleteKey(n, "required") } // an empty list is invalid in OpenAPI 3.0
+            }
+        }
+    }
+    for _, c := range n.Content {
+        filterNested(c, audience, hidden)
+    }
+}
+
+// filterMapping keeps the entries of the mapping m for which keep is true.
+func filterMapping(m *yaml.Node, keep func(key string, v *yaml.Node) bool) {
+    if m == nil || m.Kind != yaml.MappingNode { return }
+    var kept []*yaml.Node
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if keep(m.Content[i].Value, m.Content[i+1]) { kept = append(kept, m.Content[i], m.Content[i+1]) }
+    }
+    m.Content = kept
+}
+
+// reachable returns the components ("section/name") that the document
+// outside components refers to, directly or through other components.
+func reachable(root *yaml.Node) map[string]bool {
+    comps := yamlnode.GetKey(root, "components")
+    used := map[string]bool{}
+    var queue []string
+    visit := func(n *yaml.Node) {
+        for _, v := range refs(n) {
+            if t := componentTarget(v.Value); t != "" && !used[t] {
+                used[t] = true
+                queue = append(queue, t)
+            }
+        }
+    }
+    for i := 0; i+1 < len(root.Content); i += 2 {
+        if root.Content[i].Value != "components" { visit(root.Content[i+1]) }
+    }
+    for len(queue) > 0 {
+        section, name, _ := strings.Cut(queue[0], "/")
+        queue = queue[1:]
+        if n := yamlnode.GetKey(yamlnode.GetKey(comps, section), name); n != nil { visit(n) }
+    }
+    return used
+}
+
+// operationTags returns the tags the operations of paths and webhooks use.
+func operationTags(root *yaml.Node) map[string]bool {
+    tags := map[string]bool{}
+    for _, key := range []string{"paths", "webhooks"} {
+        items := yamlnode.GetKey(root, key)
+        if items == nil { continue }
+        for i := 1; i < len(items.Content); i += 2 {
+            for _, m := range httpMethods {
+                if t := yamlnode.GetKey(yamlnode.GetKey(items.Content[i], m), "tags"); t != nil {
+                    for _, c := range t.Content {
+                        tags[c.Value] = true
+                    }
+                }
+            }
+        }
+    }
+    return tags
+}
+
+// componentTarget returns "section/name" for a ref into components, or "".
+func componentTarget(ref string) string {
+    if !strings.HasPrefix(ref, "#/components/") { return "" }
+    parts := strings.SplitN(strings.TrimPrefix(ref, "#/components/"), "/", 3)
+    if len(parts) < 2 { return "" }
+    return parts[0] + "/" + strings.NewReplacer("~1", "/", "~0", "~").Replace(parts[1])
+}
+
+// refs returns the $ref value nodes below n.
+func refs(n *yaml.Node) []*yaml.Node {
+    if n == nil { return nil }
+    var out []*yaml.Node
+    if n.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if n.Content[i].Value == "$ref" && n.Content[i+1].Kind == yaml.ScalarNode { out = append(out, n.Content[i+1]) }
+        }
+    }
+    for _, c := range n.Content {
+        out = append(out, refs(c)...)
+    }
+    return out
+}
+
+// stripMarkers removes the audience extensions below n.
+func stripMarkers(n *yaml.Node) {
+    if n.Kind == yaml.MappingNode {
+        yamlnode.DeleteKey(n, AudienceKey)
+        yamlnode.DeleteKey(n, InternalKey)
+    }
+    for _, c := range n.Content {
+        stripMarkers(c)
+    }
+}
+
+func unique(s []string) []string {
+    sort.Strings(s)
+    var out []string
+    for i, v := range s {
+        if i == 0 || v != s[i-1] { out = append(out, v) }
+    }
+    return out
+}
@@ -0,0 +1,150 @@
+package audience
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+const spec = `openapi: 3.0.3
+tags:
+  - name: users
+  - name: admin
+  - name: beta
+    x-audience: partner
+paths:
+  /users:
+    get:
+      tags: [users]
+      parameters:
+        - {name: debug, in: query, x-internal: true}
+      responses:
+        '200':
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+    delete:
+      tags: [admin]
+      x-internal: true
+      responses:
+        '200':
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Audit'}
+  /partners:
+    x-audience: [partner, internal]
+    get:
+      responses: {}
+components:
+  schemas:
+    User:
+      type: object
+      required: [id, notes]
+      properties:
+        id: {type: string}
+        notes: {type: string, x-audience: internal}
+    Audit:
+      type: object
+    Unused:
+      type: object
+`
+
+func TestVisible(t *testing.T) {
+    tests := []struct {
+        node     string
+        audience string
+        want     bool
+    }{
+        {"{}", "public", true},
+        {"x-internal: true", "public", false},
+        {"x-internal: true", "internal", true},
+        {"x-audience: partner", "Partner", true},
+        {"x-audience: [partner, ' internal']", "internal", true},
+        {"x-audience: [partner]", "public", false},
+    }
+    for _, tt := range tests {
+        var n yaml.Node
+        if err := yaml.Unmarshal([]byte(tt.node), &n); err != nil { t.Fatal(err) }
+        if got := Visible(n.Content[0], tt.audience); got != tt.want { t.Errorf("Visible(%s, %s) = %v, want %v", tt.node, tt.audience, got, tt.want) }
+    }
+}
+
+func TestFilter(t *testing.T) {
+    tests := []struct {
+        audience string
+        want     string
+    }{
+        {"public", `openapi: 3.0.3
+tags:
+  - name: users
+paths:
+  /users:
+    get:
+      tags: [users]
+      responses:
+        '200':
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+components:
+  schemas:
+    User:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+    Unused:
+      type: object
+`},
+        {"partner", `openapi: 3.0.3
+tags:
+  - name: users
+  - name: beta
+paths:
+  /users:
+    get:
+      tags: [users]
+      responses:
+        '200':
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+  /partners:
+    get:
+      responses: {}
+components:
+  schemas:
+    User:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+    Unused:
+      type: object
+`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.audience, func(t *testing.T) {
+            var doc yaml.Node
+            if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+            if err := Filter(doc.Content[0], tt.audience); err != nil { t.Fatal(err) }
+            var got, want any
+            if err := doc.Content[0].Decode(&got); err != nil { t.Fatal(err) }
+            if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil { t.Fatal(err) }
+            if !reflect.DeepEqual(got, want) {
+                out, _ := yaml.Marshal(doc.Content[0])
+                t.Errorf("filtered:\n%s\nwant:\n%s", out, tt.want)
+            }
+        })
+    }
+}
+
+func TestFilterDanglingRef(t *testing.T) {
+    var doc yaml.Node
+    text := "openapi: 3.0.3\npaths:\n  /a:\n    get:\n      responses:\n        '200': {$ref: '#/components/responses/Secret'}\ncomponents:\n  responses:\n    Secret: {description: x, x-internal: true}\n"
+    if err := yaml.Unmarshal([]byte(text), &doc); err != nil { t.Fatal(err) }
+    err := Filter(doc.Content[0], "public")
+    if err == nil || !strings.Contains(err.Error(), "responses/Secret") { t.Errorf("error = %v", err) }
+}
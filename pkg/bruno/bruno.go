@@ -0,0 +1,239 @@
+// Package bruno writes a Postman collection, as exported by pkg/postman,
+// as a Bruno collection directory: bruno.json, collection.bru with the
+// collection auth, one .bru file per request in a directory per folder, and
+// an environment under environments/.
+package bruno
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/pkg/postman"
+)
+
+// Files renders the collection as file contents keyed by slash-separated
+// path relative to the collection directory.
+func Files(c *postman.Collection, env *postman.Environment) map[string]string {
+    files := map[string]string{}
+    files["bruno.json"] = fmt.Sprintf("{\n  \"version\": \"1\",\n  \"name\": %q,\n  \"type\": \"collection\",\n  \"ignore\": [\"node_modules\", \".git\"]\n}\n", c.Info.Name)
+    var col strings.Builder
+    if c.Auth != nil { writeAuth(&col, c.Auth) }
+    if c.Info.Description != "" { block(&col, "docs", string(c.Info.Description)) }
+    if col.Len() > 0 { files["collection.bru"] = finish(col.String()) }
+    if env != nil { files["environments/"+fileName(env.Name)+".bru"] = finish(environment(env)) }
+
+    var walk func(items []*postman.Item, dir string)
+    walk = func(items []*postman.Item, dir string) {
+        taken := map[string]bool{}
+        name := func(s string) string {
+            base := fileName(s)
+            n := base
+            for i := 2; taken[strings.ToLower(n)]; i++ { n = fmt.Sprintf("%s %d", base, i) }
+            taken[strings.ToLower(n)] = true
+            return dir + n
+        }
+        seq := 0
+        for _, it := range items {
+            if it.Request == nil {
+                sub := name(it.Name) + "/"
+                var b strings.Builder
+                dict(&b, "meta", [][2]string{{"name", it.Name}})
+                if it.Description != "" { block(&b, "docs", string(it.Description)) }
+                files[sub+"folder.bru"] = finish(b.String())
+                walk(it.Item, sub)
+                continue
+            }
+            seq++
+            files[name(it.Name)+".bru"] = finish(request(it, seq))
+        }
+    }
+    walk(c.Item, "")
+    return files
+}
+
+// Write writes the collection into dir and removes the request and folder
+// .bru files there that it did not write, since the directory is generated.
+// It returns how many files changed.
+func Write(dir string, c *postman.Collection, env *postman.Environment) (int, error) {
+    files := Files(c, env)
+    changed := 0
+    paths := make([]string, 0, len(files))
+    for p := range files { paths = append(paths, p) }
+    sort.Strings(paths)
+    for _, p := range paths {
+        target := filepath.Join(dir, filepath.FromSlash(p))
+        if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil { return changed, err }
+        ok, err := atomicfile.WriteFile(target, []byte(files[p]))
+        if err != nil { return changed, err }
+        if ok { changed++ }
+    }
+    // Environments added by hand are kept.
+    err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+        if err != nil { return err }
+        rel, err := filepath.Rel(dir, path)
+        if err != nil { return err }
+        if d.IsDir() && (rel == "environments" || rel == "node_modules" || strings.HasPrefix(d.Name(), ".") && rel != ".") { return filepath.SkipDir }
+        if d.IsDir() || filepath.Ext(path) != ".bru" { return nil }
+        if _, ok := files[filepath.ToSlash(rel)]; ok { return nil }
+        changed++
+        return os.Remove(path)
+    })
+    return changed, err
+}
+
+func request(it *postman.Item, seq int) string {
+    req := it.Request
+    var b strings.Builder
+    dict(&b, "meta", [][2]string{{"name", it.Name}, {"type", "http"}, {"seq", fmt.Sprint(seq)}})
+
+    mode, bodyBlock, bodyEntries, bodyText := "none", "", [][2]string(nil), ""
+    if body := req.Body; body != nil {
+        switch body.Mode {
+        case "urlencoded":
+            mode, bodyBlock, bodyEntries = "formUrlEncoded", "body:form-urlencoded", pairs(body.URLEncoded)
+        case "formdata":
+            mode, bodyBlock = "multipartForm", "body:multipart-form"
+            for _, f := range body.FormData {
+                v := f.Value
+                if f.Type == "file" { v = "@file()" }
+                bodyEntries = append(bodyEntries, [2]string{prefix(f) + f.Key, v})
+            }
+        default:
+            mode = "text"
+            if body.Options != nil && (body.Options.Raw.Language == "json" || body.Options.Raw.Language == "xml") { mode = body.Options.Raw.Language }
+            bodyBlock, bodyText = "body:"+mode, body.Raw
+        }
+    }
+    authMode := "inherit"
+    if req.Auth != nil { authMode = authModes[req.Auth.Type] }
+    dict(&b, strings.ToLower(req.Method), [][2]string{{"url", req.URL.Raw}, {"body", mode}, {"auth", firstNonEmpty(authMode, "none")}})
+
+    if len(req.URL.Query) > 0 { dict(&b, "params:query", pairs(req.URL.Query)) }
+    if len(req.URL.Variable) > 0 { dict(&b, "params:path", pairs(req.URL.Variable)) }
+    if len(req.Header) > 0 { dict(&b, "headers", pairs(req.Header)) }
+    if req.Auth != nil && authModes[req.Auth.Type] != "none" { writeAuthBlock(&b, req.Auth) }
+    switch {
+    case bodyText != "":
+        block(&b, bodyBlock, bodyText)
+    case bodyEntries != nil:
+        dict(&b, bodyBlock, bodyEntries)
+    }
+    if req.Description != "" { block(&b, "docs", string(req.Description)) }
+    return b.String()
+}
+
+func environment(env *postman.Environment) string {
+    var b strings.Builder
+    var vars [][2]string
+    var secrets []string
+    for _, v := range env.Values {
+        if v.Type == "secret" {
+            secrets = append(secrets, v.Key)
+            continue
+        }
+        vars = append(vars, [2]string{v.Key, v.Value})
+    }
+    dict(&b, "vars", vars)
+    if len(secrets) > 0 {
+        b.WriteString("vars:secret [\n  " + strings.Join(secrets, ",\n  ") + "\n]\n\n")
+    }
+    return b.String()
+}
+
+// Bruno's auth modes by Postman auth type.
+var authModes = map[string]string{"noauth": "none", "bearer": "bearer", "basic": "basic", "apikey": "apikey", "oauth2": "oauth2"}
+
+// writeAuth writes the auth mode block and its attributes, for
+// collection.bru.
+func writeAuth(b *strings.Builder, a *postman.Auth) {
+    mode := firstNonEmpty(authModes[a.Type], "none")
+    dict(b, "auth", [][2]string{{"mode", mode}})
+    if mode != "none" { writeAuthBlock(b, a) }
+}
+
+func writeAuthBlock(b *strings.Builder, a *postman.Auth) {
+    switch a.Type {
+    case "bearer":
+        dict(b, "auth:bearer", [][2]string{{"token", attr(a.Bearer, "token")}})
+    case "basic":
+        dict(b, "auth:basic", [][2]string{{"username", attr(a.Basic, "username")}, {"password", attr(a.Basic, "password")}})
+    case "apikey":
+        placement := "header"
+        if attr(a.APIKey, "in") == "query" { placement = "queryparams" }
+        dict(b, "auth:apikey", [][2]string{{"key", attr(a.APIKey, "key")}, {"value", attr(a.APIKey, "value")}, {"placement", placement}})
+    case "oauth2":
+        grant := attr(a.OAuth2, "grant_type")
+        if grant == "password_credentials" { grant = "password" }
+        entries := [][2]string{{"grant_type", firstNonEmpty(grant, "client_credentials")}}
+        for _, k := range [][2]string{{"authUrl", "authorization_url"}, {"accessTokenUrl", "access_token_url"}, {"clientId", "client_id"}, {"clientSecret", "client_secret"}, {"scope", "scope"}} {
+            if v := attr(a.OAuth2, k[0]); v != "" { entries = append(entries, [2]string{k[1], v}) }
+        }
+        dict(b, "auth:oauth2", entries)
+    }
+}
+
+func attr(list []postman.KeyValue, key string) string {
+    for _, kv := range list {
+        if kv.Key == key { return kv.Value }
+    }
+    return ""
+}
+
+// pairs renders key/values as dictionary entries, disabled ones prefixed
+// with ~.
+func pairs(list []postman.KeyValue) [][2]string {
+    out := make([][2]string, 0, len(list))
+    for _, kv := range list { out = append(out, [2]string{prefix(kv) + kv.Key, kv.Value}) }
+    return out
+}
+
+func prefix(kv postman.KeyValue) string {
+    if kv.Disabled { return "~" }
+    return ""
+}
+
+// dict writes a dictionary block; values are kept on one line.
+func dict(b *strings.Builder, name string, entries [][2]string) {
+    b.WriteString(name + " {\n")
+    for _, e := range entries {
+        b.WriteString("  " + e[0] + ": " + strings.TrimSpace(strings.ReplaceAll(e[1], "\n", " ")) + "\n")
+    }
+    b.WriteString("}\n\n")
+}
+
+// block writes a text block, indenting its lines.
+func block(b *strings.Builder, name, text string) {
+    b.WriteString(name + " {\n")
+    for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+        if line == "" {
+            b.WriteString("\n")
+            continue
+        }
+        b.WriteString("  " + line + "\n")
+    }
+    b.WriteString("}\n\n")
+}
+
+// finish drops the blank line after the last block.
+func finish(s string) string { return strings.TrimRight(s, "\n") + "\n" }
+
+var reUnsafe = regexp.MustCompile(`[\\/:*?"<>|]+`)
+
+// fileName makes s safe as a file name on every platform.
+func fileName(s string) string {
+    s = strings.TrimSpace(reUnsafe.ReplaceAllString(s, "-"))
+    if s == "" || s == "." || s == ".." { return "request" }
+    return s
+}
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
@@ -0,0 +1,153 @@
+package bruno
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/pkg/postman"
+)
+
+const spec = `openapi: 3.0.3
+info: {title: Pets, version: 1.0.0}
+servers:
+  - url: https://api.example.com
+security:
+  - token: []
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      summary: List pets
+      parameters:
+        - {name: limit, in: query, example: 10}
+      responses: {}
+    post:
+      tags: [pets]
+      summary: 'Add a pet: dogs/cats'
+      security: []
+      requestBody:
+        content:
+          application/json:
+            example: {name: Rex}
+      responses: {}
+components:
+  securitySchemes:
+    token: {type: http, scheme: bearer}
+`
+
+func collection(t *testing.T) (*postman.Collection, *postman.Environment) {
+    t.Helper()
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+    c, env, err := postman.Export(doc.Content[0], postman.Options{})
+    if err != nil { t.Fatal(err) }
+    return c, env
+}
+
+func TestFiles(t *testing.T) {
+    files := Files(collection(t))
+    want := map[string]string{
+        "bruno.json": "{\n  \"version\": \"1\",\n  \"name\": \"Pets\",\n  \"type\": \"collection\",\n  \"ignore\": [\"node_modules\", \".git\"]\n}\n",
+        "collection.bru": `auth {
+  mode: bearer
+}
+
+auth:bearer {
+  token: {{tokenToken}}
+}
+`,
+        "environments/Pets.bru": `vars {
+  baseUrl: https://api.example.com
+}
+
+vars:secret [
+  tokenToken
+]
+`,
+        "pets/folder.bru": "meta {\n  name: pets\n}\n",
+        "pets/List pets.bru": `meta {
+  name: List pets
+  type: http
+  seq: 1
+}
+
+get {
+  url: {{baseUrl}}/pets
+  body: none
+  auth: inherit
+}
+
+params:query {
+  ~limit: 10
+}
+`,
+        "pets/Add a pet- dogs-cats.bru": `meta {
+  name: Add a pet: dogs/cats
+  type: http
+  seq: 2
+}
+
+post {
+  url: {{baseUrl}}/pets
+  body: json
+  auth: none
+}
+
+headers {
+  Content-Type: application/json
+}
+
+body:json {
+  {
+    "name": "Rex"
+  }
+}
+`,
+    }
+    for name, content := range want {
+        if files[name] != content { t.Errorf("%s:\n%s\nwant:\n%s", name, files[name], content) }
+    }
+    for name := range files {
+        if _, ok := want[name]; !ok { t.Errorf("unexpected file %s", name) }
+    }
+}
+
+func TestWriteRemovesStaleRequests(t *testing.T) {
+    dir := t.TempDir()
+    keep := map[string]string{
+        "environments/Local.bru": "vars {\n}\n",      // environments are the user's
+        ".git/x.bru":             "not a request\n", // hidden directories too
+        "notes.txt":              "hi\n",
+    }
+    for name, content := range keep {
+        p := filepath.Join(dir, filepath.FromSlash(name))
+        if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil { t.Fatal(err) }
+        if err := os.WriteFile(p, []byte(content), 0o644); err != nil { t.Fatal(err) }
+    }
+    if err := os.WriteFile(filepath.Join(dir, "Old request.bru"), []byte("meta {\n}\n"), 0o644); err != nil { t.Fatal(err) }
+
+    c, env := collection(t)
+    changed, err := Write(dir, c, env)
+    if err != nil { t.Fatal(err) }
+    if want := len(Files(c, env)) + 1; changed != want { t.Errorf("first write changed %d files, want %d", changed, want) }
+    if changed, err := Write(dir, c, env); err != nil || changed != 0 { t.Errorf("second write changed %d files (%v), want 0", changed, err) }
+
+    var got []string
+    filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+        if err == nil && !d.IsDir() {
+            rel, _ := filepath.Rel(dir, path)
+            got = append(got, filepath.ToSlash(rel))
+        }
+        return err
+    })
+    var want []string
+    for name := range Files(c, env) { want = append(want, name) }
+    for name := range keep { want = append(want, name) }
+    sort.Strings(want)
+    if !reflect.DeepEqual(got, want) { t.Errorf("files = %v, want %v", got, want) }
+}
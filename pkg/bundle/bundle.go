@@ -0,0 +1,261 @@
+// Package bundle resolves the external $refs of a multi-file OpenAPI spec
+// into a single document, without shelling out to Redocly CLI.
+//
+// Files referenced by an entry under components (e.g. components.schemas.User
+// pointing at ./components/schemas/user.yaml, or components.schemas.Money at
+// ./components/schemas/common.yaml#/Money) are inlined there once, and every
+// other ref to them becomes a local "#/components/..." ref. Any other
+// external ref is replaced by the node it points at. Refs to http(s) URLs are
+// resolved through Remote when it is set, and left untouched otherwise. A spec declaring OpenAPI 3.1 has its 3.0-style schema
+// keywords (nullable, boolean exclusiveMinimum) converted on the way.
+package bundle
+
+import (
+    "fmt"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/dialect"
+)
+
+// Remote, when set, returns the local copy of the document an http(s) $ref
+// points at (see remote.Vendor.Fetch).
+var Remote func(ref string) (string, error)
+
+type bundler struct {
+    root       string                // absolute path of the spec being bundled
+    docs       map[string]*yaml.Node // parsed files by absolute path
+    components map[string]string     // absolute file#pointer -> "#/components/<section>/<name>"
+    inlining   []string              // file#pointer targets being inlined, for cycle detection
+}
+
+// Load reads the spec at path and returns its top-level node with every
+// external $ref resolved.
+func Load(path string) (*yaml.Node, error) {
+    path, err := filepath.Abs(path)
+    if err != nil { return nil, err }
+    b := &bundler{root: path, docs: map[string]*yaml.Node{}, components: map[string]string{}}
+    doc, err := b.load(path)
+    if err != nil { return nil, err }
+    return b.bundle(doc)
+}
+
+// LoadData is Load for a spec not written yet: data is read as the spec at
+// path, and its relative $refs are resolved against path's directory.
+func LoadData(path string, data []byte) (*yaml.Node, error) {
+    path, err := filepath.Abs(path)
+    if err != nil { return nil, err }
+    b := &bundler{root: path, docs: map[string]*yaml.Node{}, components: map[string]string{}}
+    doc, err := b.parse(path, data)
+    if err != nil { return nil, err }
+    return b.bundle(doc)
+}
+
+// bundle returns a copy of doc, the spec at b.root, with every external $ref
+// resolved.
+func (b *bundler) bundle(doc *yaml.Node) (*yaml.Node, error) {
+    path := b.root
+    out := copyNode(doc)
+
+    // Register component entries first, so refs to those files (or to the
+    // definitions they point at) anywhere in the tree, including their own
+    // bodies, become local refs.
+    type entry struct{ node *yaml.Node; file, ptr string }
+    var entries []entry
+    comps := yamlnode.GetKey(out, "components")
+    if comps != nil && comps.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(comps.Content); i += 2 {
+            section, defs := comps.Content[i].Value, comps.Content[i+1]
+            if defs.Kind != yaml.MappingNode { continue }
+            for j := 0; j+1 < len(defs.Content); j += 2 {
+                ref := refOf(defs.Content[j+1])
+                if ref == "" || isLocal(ref) || isRemote(ref) { continue }
+                file, ptr, err := b.target(path, ref)
+                if err != nil { return nil, err }
+                if _, dup := b.components[file+"#"+ptr]; dup { continue }
+                b.components[file+"#"+ptr] = "#/components/" + yamlnode.EscapeToken(section) + "/" + yamlnode.EscapeToken(defs.Content[j].Value)
+                entries = append(entries, entry{defs.Content[j+1], file, ptr})
+            }
+        }
+    }
+    for _, e := range entries {
+        body, err := b.inline(e.file, e.ptr)
+        if err != nil { return nil, err }
+        *e.node = *body
+    }
+    if err := b.walk(out, path); err != nil { return nil, err }
+    if v := yamlnode.GetKey(out, "openapi"); v != nil && dialect.Is31(v.Value) { dialect.Upgrade(out) }
+    return out, nil
+}
+
+// Write bundles the spec at path into out, as JSON when out ends in .json and
+// YAML otherwise. The returned bool is false when out was already up to date.
+func Write(path, out string) (bool, error) {
+    root, err := Load(path)
+    if err != nil { return false, err }
+    return WriteNode(root, out)
+}
+
+// WriteNode writes an already bundled (and possibly post-processed) spec to
+// out, as JSON when out ends in .json and YAML otherwise.
+func WriteNode(root *yaml.Node, out string) (bool, error) {
+    marshal := yamlnode.Marshal
+    if strings.EqualFold(filepath.Ext(out), ".json") { marshal = yamlnode.MarshalJSON }
+    data, err := marshal(root)
+    if err != nil { return false, fmt.Errorf("%s: %v", out, err) }
+    if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil { return false, err }
+    return atomicfile.WriteFile(out, data)
+}
+
+// load parses file once and returns its top-level node.
+func (b *bundler) load(file string) (*yaml.Node, error) {
+    if n, ok := b.docs[file]; ok { return n, nil }
+    data, err := os.ReadFile(file)
+    if err != nil { return nil, err }
+    return b.parse(file, data)
+}
+
+// parse parses data as the contents of file and records it as such.
+func (b *bundler) parse(file string, data []byte) (*yaml.Node, error) {
+    data = []byte(strings.TrimPrefix(string(data), "\uFEFF"))
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil {
+        return nil, fmt.Errorf("%s: %v", file, err)
+    }
+    n := yamlnode.DocRoot(&doc)
+    if n == nil { n = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"} }
+    if strings.EqualFold(filepath.Ext(file), ".json") { yamlnode.BlockStyle(n) }
+    b.docs[file] = n
+    return n, nil
+}
+
+// walk resolves the refs below n, which was read from base.
+func (b *bundler) walk(n *yaml.Node, base string) error {
+    switch n.Kind {
+    case yaml.MappingNode:
+        if v := yamlnode.GetKey(n, "$ref"); v != nil && v.Kind == yaml.ScalarNode {
+            return b.resolve(n, v, base)
+        }
+        for i := 1; i < len(n.Content); i += 2 {
+            if err := b.walk(n.Content[i], base); err != nil { return err }
+        }
+    case yaml.SequenceNode:
+        for _, c := range n.Content {
+            if err := b.walk(c, base); err != nil { return err }
+        }
+    }
+    return nil
+}
+
+// resolve rewrites the ref mapping n (whose $ref value is v) to a local ref,
+// or replaces it with the node it points at.
+func (b *bundler) resolve(n, v *yaml.Node, base string) error {
+    if isRemote(v.Value) && Remote == nil { return nil }
+    file, ptr, err := b.target(base, v.Value)
+    if err != nil { return err }
+    local := ""
+    switch {
+    case file == b.root:
+        local = "#" + ptr
+    default:
+        local = b.componentRef(file, ptr)
+    }
+    if local != "" {
+        v.Value, v.Tag, v.Style = local, "!!str", 0
+        return nil
+    }
+    body, err := b.inline(file, ptr)
+    if err != nil { return fmt.Errorf("%s: $ref %q: %v", base, v.Value, err) }
+    *n = *body
+    return nil
+}
+
+// componentRef returns the local ref for ptr in file when it lies within a
+// component entry, or "".
+func (b *bundler) componentRef(file, ptr string) string {
+    for p := ptr; ; {
+        if c := b.components[file+"#"+p]; c != "" { return c + ptr[len(p):] }
+        i := strings.LastIndex(p, "/")
+        if p == "" || i < 0 { break }
+        p = p[:i]
+    }
+    return ""
+}
+
+// inline returns a resolved copy of the node at ptr in file.
+func (b *bundler) inline(file, ptr string) (*yaml.Node, error) {
+    key := file + "#" + ptr
+    for _, k := range b.inlining {
+        if k == key {
+            return nil, fmt.Errorf("circular $ref through %s; add it under components so it can be referenced by name", key)
+        }
+    }
+    b.inlining = append(b.inlining, key)
+    defer func() { b.inlining = b.inlining[:len(b.inlining)-1] }()
+
+    doc, err := b.load(file)
+    if err != nil { return nil, err }
+    target, err := Lookup(doc, ptr)
+    if err != nil { return nil, fmt.Errorf("%s: %v", file, err) }
+    out := copyNode(target)
+    if err := b.walk(out, file); err != nil { return nil, err }
+    return out, nil
+}
+
+// target splits ref into the absolute file it names (base itself for local
+// refs) and its JSON pointer.
+func (b *bundler) target(base, ref string) (string, string, error) {
+    file, ptr := ref, ""
+    if i := strings.Index(ref, "#"); i >= 0 { file, ptr = ref[:i], ref[i+1:] }
+    ptr, err := url.PathUnescape(ptr)
+    if err != nil { return "", "", fmt.Errorf("%s: invalid $ref %q: %v", base, ref, err) }
+    if file == "" { return base, ptr, nil }
+    if isRemote(file) {
+        local, err := Remote(file)
+        if err != nil { return "", "", fmt.Errorf("%s: $ref %q: %v", base, ref, err) }
+        return local, ptr, nil
+    }
+    file = strings.TrimPrefix(file, "file://")
+    file, err = url.PathUnescape(file)
+    if err != nil { return "", "", fmt.Errorf("%s: invalid $ref %q: %v", base, ref, err) }
+    // file:///C:/x arrives as /C:/x
+    if len(file) > 2 && file[0] == '/' && file[2] == ':' && filepath.VolumeName(file[1:]) != "" { file = file[1:] }
+    file = filepath.FromSlash(strings.ReplaceAll(file, "\\", "/"))
+    if !filepath.IsAbs(file) { file = filepath.Join(filepath.Dir(base), file) }
+    return filepath.Clean(file), ptr, nil
+}
+
+// Lookup follows a JSON pointer such as /properties/id from n.
+func Lookup(n *yaml.Node, ptr string) (*yaml.Node, error) { return yamlnode.Lookup(n, ptr) }
+
+// copyNode deep-copies n, expanding aliases and dropping anchors so the copy
+// can be placed anywhere in the output.
+func copyNode(n *yaml.Node) *yaml.Node {
+    for n.Kind == yaml.AliasNode { n = n.Alias }
+    c := *n
+    c.Anchor = ""
+    c.Content = make([]*yaml.Node, len(n.Content))
+    for i, ch := range n.Content {
+        c.Content[i] = copyNode(ch)
+    }
+    return &c
+}
+
+func refOf(n *yaml.Node) string {
+    if n.Kind != yaml.MappingNode || len(n.Content) != 2 { return "" }
+    if v := yamlnode.GetKey(n, "$ref"); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func isLocal(ref string) bool { return strings.HasPrefix(ref, "#") }
+
+func isRemote(ref string) bool {
+    low := strings.ToLower(ref)
+    return strings.HasPrefix(low, "http://") || strings.HasPrefix(low, "https://")
+}
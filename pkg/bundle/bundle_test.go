@@ -0,0 +1,219 @@
+package bundle
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+// writeFiles creates the files (slash-separated paths relative to dir) with their content.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+    t.Helper()
+    for name, content := range files {
+        p := filepath.Join(dir, filepath.FromSlash(name))
+        if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil { t.Fatal(err) }
+        if err := os.WriteFile(p, []byte(content), 0o644); err != nil { t.Fatal(err) }
+    }
+}
+
+// decode returns the plain Go value of a node or of YAML text.
+func decode(t *testing.T, v any) any {
+    t.Helper()
+    var out any
+    switch v := v.(type) {
+    case *yaml.Node:
+        if err := v.Decode(&out); err != nil { t.Fatal(err) }
+    case string:
+        if err := yaml.Unmarshal([]byte(v), &out); err != nil { t.Fatal(err) }
+    }
+    return out
+}
+
+func TestLoad(t *testing.T) {
+    tests := []struct {
+        name  string
+        files map[string]string // root.yaml is the spec bundled
+        want  string
+    }{
+        {
+            name: "component entries are inlined once and referenced locally",
+            files: map[string]string{
+                "root.yaml": `openapi: 3.0.3
+paths:
+  /users:
+    $ref: ./paths/users.yaml
+components:
+  schemas:
+    User:
+      $ref: ./components/schemas/User.yaml
+    Group:
+      $ref: ./components/schemas/Group.yaml
+`,
+                "paths/users.yaml": `get:
+  responses:
+    '200':
+      description: ok
+      content:
+        application/json:
+          schema:
+            $ref: ../components/schemas/User.yaml
+`,
+                "components/schemas/User.yaml": "type: object\nproperties:\n  group:\n    $ref: ./Group.yaml\n",
+                "components/schemas/Group.yaml": "type: object\nproperties:\n  members:\n    type: array\n    items:\n      $ref: ./User.yaml\n",
+            },
+            want: `openapi: 3.0.3
+paths:
+  /users:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        group:
+          $ref: '#/components/schemas/Group'
+    Group:
+      type: object
+      properties:
+        members:
+          type: array
+          items:
+            $ref: '#/components/schemas/User'
+`,
+        },
+        {
+            name: "other refs are replaced by their target, pointers and escapes included",
+            files: map[string]string{
+                "root.yaml": `openapi: 3.0.3
+paths:
+  /a b:
+    $ref: ./paths/a%20b.yaml
+  /common:
+    get:
+      parameters:
+        - $ref: ./shared/params.yaml#/limit
+        - $ref: '#/components/parameters/Offset'
+      responses: {}
+components:
+  parameters:
+    Offset:
+      name: offset
+      in: query
+`,
+                "paths/a b.yaml": "get:\n  responses: {}\n",
+                "shared/params.yaml": "limit:\n  name: limit\n  in: query\n  schema:\n    $ref: ./types.yaml#/Count\n",
+                "shared/types.yaml": "Count:\n  type: integer\n",
+            },
+            want: `openapi: 3.0.3
+paths:
+  /a b:
+    get:
+      responses: {}
+  /common:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - $ref: '#/components/parameters/Offset'
+      responses: {}
+components:
+  parameters:
+    Offset:
+      name: offset
+      in: query
+`,
+        },
+        {
+            name: "remote refs are left as written",
+            files: map[string]string{
+                "root.yaml": "openapi: 3.0.3\npaths:\n  /money:\n    $ref: https://schemas.example.com/money.yaml\n",
+            },
+            want: "openapi: 3.0.3\npaths:\n  /money:\n    $ref: https://schemas.example.com/money.yaml\n",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            writeFiles(t, dir, tt.files)
+            got, err := Load(filepath.Join(dir, "root.yaml"))
+            if err != nil { t.Fatal(err) }
+            if g, w := decode(t, got), decode(t, tt.want); !reflect.DeepEqual(g, w) {
+                out, _ := yaml.Marshal(got)
+                t.Errorf("bundled:\n%s\nwant:\n%s", out, tt.want)
+            }
+        })
+    }
+}
+
+func TestLoadErrors(t *testing.T) {
+    tests := []struct {
+        name  string
+        files map[string]string
+        want  string
+    }{
+        {
+            name: "circular inline",
+            files: map[string]string{
+                "root.yaml": "openapi: 3.0.3\npaths:\n  /a:\n    $ref: ./a.yaml\n",
+                "a.yaml":    "get:\n  parameters:\n    - $ref: ./b.yaml\n",
+                "b.yaml":    "name: b\nschema:\n  $ref: ./a.yaml\n",
+            },
+            want: "circular $ref",
+        },
+        {
+            name:  "missing file",
+            files: map[string]string{"root.yaml": "openapi: 3.0.3\npaths:\n  /a:\n    $ref: ./missing.yaml\n"},
+            want:  "missing.yaml",
+        },
+        {
+            name: "missing pointer",
+            files: map[string]string{
+                "root.yaml": "openapi: 3.0.3\npaths:\n  /a:\n    $ref: ./a.yaml#/nope\n",
+                "a.yaml":    "get: {}\n",
+            },
+            want: "/nope",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            writeFiles(t, dir, tt.files)
+            _, err := Load(filepath.Join(dir, "root.yaml"))
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Errorf("Load error = %v, want one mentioning %q", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestWriteJSON(t *testing.T) {
+    dir := t.TempDir()
+    writeFiles(t, dir, map[string]string{
+        "root.yaml": "openapi: 3.0.3\ncomponents:\n  schemas:\n    Id:\n      $ref: ./id.yaml\n",
+        "id.yaml":   "type: string\n",
+    })
+    out := filepath.Join(dir, "dist", "openapi.json")
+    for i, want := range []bool{true, false} { // the second write finds it up to date
+        changed, err := Write(filepath.Join(dir, "root.yaml"), out)
+        if err != nil { t.Fatal(err) }
+        if changed != want { t.Errorf("write %d: changed = %v, want %v", i+1, changed, want) }
+    }
+    b, err := os.ReadFile(out)
+    if err != nil { t.Fatal(err) }
+    if !strings.HasPrefix(string(b), "{") || !strings.Contains(string(b), `"type": "string"`) {
+        t.Errorf("JSON bundle:\n%s", b)
+    }
+}
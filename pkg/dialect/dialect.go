@@ -0,0 +1,260 @@
+// Package dialect converts Schema Objects between the OpenAPI 3.0 dialect and
+// the JSON Schema 2020-12 dialect used by OpenAPI 3.1.
+//
+// The walk covers a whole document (or fragment) and treats every mapping
+// outside example values and extensions as a potential schema, so it can be
+// applied to a root, a bundle or a single fragment alike.
+package dialect
+
+import (
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Problem is a construct that cannot be converted, at a position in the
+// walked document.
+type Problem struct {
+    Line    int
+    Column  int
+    Message string
+}
+
+// Is31 reports whether an openapi version string such as "3.1.0" is 3.1.
+func Is31(version string) bool { return strings.HasPrefix(strings.TrimSpace(version), "3.1") }
+
+// Upgrade rewrites 3.0-only schema keywords below n in place:
+// "nullable: true" adds "null" to type (and to enum, when present), and
+// boolean exclusiveMinimum/exclusiveMaximum take the value of
+// minimum/maximum. Constructs Check31 reports are left as they are.
+func Upgrade(n *yaml.Node) {
+    walk(n, func(m *yaml.Node) {
+        if v := yamlnode.GetKey(m, "nullable"); isBool(v) {
+            if v.Value == "true" {
+                if addNullType(m) { yamlnode.DeleteKey(m, "nullable") }
+            } else {
+                yamlnode.DeleteKey(m, "nullable")
+            }
+        }
+        upgradeExclusive(m, "exclusiveMinimum", "minimum")
+        upgradeExclusive(m, "exclusiveMaximum", "maximum")
+    })
+}
+
+// Check31 returns the 3.0 constructs below n that Upgrade cannot express in
+// OpenAPI 3.1.
+func Check31(n *yaml.Node) []Problem {
+    var problems []Problem
+    walk(n, func(m *yaml.Node) {
+        if v := yamlnode.GetKey(m, "nullable"); isBool(v) && v.Value == "true" {
+            if t := yamlnode.GetKey(m, "type"); t == nil || (t.Kind != yaml.ScalarNode && t.Kind != yaml.SequenceNode) {
+                problems = append(problems, Problem{v.Line, v.Column,
+                    "nullable: true without a type has no OpenAPI 3.1 equivalent; declare a type or use oneOf with {type: 'null'}"})
+            }
+        }
+        for _, kw := range [][2]string{{"exclusiveMinimum", "minimum"}, {"exclusiveMaximum", "maximum"}} {
+            if v := yamlnode.GetKey(m, kw[0]); isBool(v) && v.Value == "true" && yamlnode.GetKey(m, kw[1]) == nil {
+                problems = append(problems, Problem{v.Line, v.Column,
+                    kw[0] + ": true without " + kw[1] + "; OpenAPI 3.1 expects the bound itself, e.g. " + kw[0] + ": 0"})
+            }
+        }
+    })
+    return problems
+}
+
+// Downgrade rewrites OpenAPI 3.1 schema keywords below n in place for a 3.0
+// document: a type array becomes a single type plus "nullable: true" (or a
+// oneOf over its types), const becomes a one-value enum, an examples array
+// becomes example, and numeric exclusiveMinimum/exclusiveMaximum become
+// minimum/maximum with the boolean flag. Constructs Check30 reports are left
+// as they are.
+func Downgrade(n *yaml.Node) {
+    walk(n, func(m *yaml.Node) {
+        if t := yamlnode.GetKey(m, "type"); t != nil && t.Kind == yaml.SequenceNode {
+            downgradeType(m, t)
+        }
+        if c := yamlnode.GetKey(m, "const"); c != nil && yamlnode.GetKey(m, "enum") == nil {
+            enum := yamlnode.Seq()
+            enum.Content = append(enum.Content, c)
+            replaceKey(m, "const", "enum", enum)
+        }
+        if ex := yamlnode.GetKey(m, "examples"); ex != nil && ex.Kind == yaml.SequenceNode && yamlnode.GetKey(m, "example") == nil {
+            if len(ex.Content) > 0 {
+                replaceKey(m, "examples", "example", ex.Content[0])
+            } else {
+                yamlnode.DeleteKey(m, "examples")
+            }
+        }
+        downgradeExclusive(m, "exclusiveMinimum", "minimum", func(x, b float64) bool { return x >= b })
+        downgradeExclusive(m, "exclusiveMaximum", "maximum", func(x, b float64) bool { return x <= b })
+    })
+}
+
+// Check30 returns the OpenAPI 3.1 constructs below n that Downgrade cannot
+// express in OpenAPI 3.0.
+func Check30(n *yaml.Node) []Problem {
+    var problems []Problem
+    walk(n, func(m *yaml.Node) {
+        t := yamlnode.GetKey(m, "type")
+        if t == nil || t.Kind != yaml.SequenceNode { return }
+        types, _ := splitTypes(t)
+        switch {
+        case len(types) == 0:
+            problems = append(problems, Problem{t.Line, t.Column,
+                "a type array with only \"null\" has no OpenAPI 3.0 equivalent"})
+        case len(types) > 1 && yamlnode.GetKey(m, "oneOf") != nil:
+            problems = append(problems, Problem{t.Line, t.Column,
+                "a type array next to oneOf has no OpenAPI 3.0 equivalent; move the types into the oneOf alternatives"})
+        }
+    })
+    return problems
+}
+
+// downgradeType replaces the type array t of schema m.
+func downgradeType(m, t *yaml.Node) {
+    types, null := splitTypes(t)
+    switch {
+    case len(types) == 1:
+        yamlnode.SetKey(m, "type", yamlnode.Str(types[0]))
+    case len(types) > 1 && yamlnode.GetKey(m, "oneOf") == nil:
+        alts := yamlnode.Seq()
+        for _, typ := range types {
+            alt := yamlnode.Map()
+            yamlnode.SetKey(alt, "type", yamlnode.Str(typ))
+            if null { yamlnode.SetKey(alt, "nullable", yamlnode.Bool(true)) }
+            alts.Content = append(alts.Content, alt)
+        }
+        replaceKey(m, "type", "oneOf", alts)
+        return
+    default:
+        return
+    }
+    if null { yamlnode.SetKey(m, "nullable", yamlnode.Bool(true)) }
+}
+
+// splitTypes returns the non-null entries of a type array and whether it
+// includes "null".
+func splitTypes(t *yaml.Node) ([]string, bool) {
+    var types []string
+    null := false
+    for _, c := range t.Content {
+        if c.Kind != yaml.ScalarNode { continue }
+        if c.Value == "null" {
+            null = true
+        } else {
+            types = append(types, c.Value)
+        }
+    }
+    return types, null
+}
+
+// downgradeExclusive turns a numeric exclusive bound into bound plus
+// "key: true", unless bound is already tighter (tighter reports whether the
+// exclusive value x is at least as strict as bound b).
+func downgradeExclusive(m *yaml.Node, key, bound string, tighter func(x, b float64) bool) {
+    v := yamlnode.GetKey(m, key)
+    if v == nil || v.Kind != yaml.ScalarNode || v.Tag == "!!bool" { return }
+    x, err := strconv.ParseFloat(v.Value, 64)
+    if err != nil { return }
+    if b := yamlnode.GetKey(m, bound); b != nil {
+        if bv, err := strconv.ParseFloat(b.Value, 64); err == nil && !tighter(x, bv) {
+            yamlnode.DeleteKey(m, key)
+            return
+        }
+    }
+    yamlnode.SetKey(m, bound, v)
+    yamlnode.SetKey(m, key, yamlnode.Bool(true))
+}
+
+// addNullType adds "null" to the type of schema m, reporting false when m
+// has no type to extend.
+func addNullType(m *yaml.Node) bool {
+    t := yamlnode.GetKey(m, "type")
+    switch {
+    case t == nil:
+        return false
+    case t.Kind == yaml.ScalarNode:
+        if t.Value != "null" {
+            seq := yamlnode.Seq()
+            seq.Content = append(seq.Content, yamlnode.Str(t.Value), yamlnode.Str("null"))
+            yamlnode.SetKey(m, "type", seq)
+        }
+    case t.Kind == yaml.SequenceNode:
+        if !hasScalar(t, "null") { t.Content = append(t.Content, yamlnode.Str("null")) }
+    default:
+        return false
+    }
+    if e := yamlnode.GetKey(m, "enum"); e != nil && e.Kind == yaml.SequenceNode && !hasNull(e) {
+        e.Content = append(e.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"})
+    }
+    return true
+}
+
+func upgradeExclusive(m *yaml.Node, key, bound string) {
+    v := yamlnode.GetKey(m, key)
+    if !isBool(v) { return }
+    if v.Value == "false" {
+        yamlnode.DeleteKey(m, key)
+        return
+    }
+    b := yamlnode.GetKey(m, bound)
+    if b == nil || b.Kind != yaml.ScalarNode { return }
+    yamlnode.SetKey(m, key, b)
+    yamlnode.DeleteKey(m, bound)
+}
+
+// skipKeys hold example data rather than schemas.
+var skipKeys = map[string]bool{"example": true, "examples": true, "default": true, "enum": true, "const": true}
+
+// walk calls fn for every mapping below n that may be a schema. The keys of
+// properties are names, so they are never skipped.
+func walk(n *yaml.Node, fn func(m *yaml.Node)) {
+    switch n.Kind {
+    case yaml.DocumentNode, yaml.SequenceNode:
+        for _, c := range n.Content {
+            walk(c, fn)
+        }
+    case yaml.MappingNode:
+        fn(n)
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            k, v := n.Content[i].Value, n.Content[i+1]
+            if k == "properties" && v.Kind == yaml.MappingNode {
+                for j := 1; j < len(v.Content); j += 2 {
+                    walk(v.Content[j], fn)
+                }
+                continue
+            }
+            if skipKeys[k] || strings.HasPrefix(k, "x-") { continue }
+            walk(v, fn)
+        }
+    }
+}
+
+func isBool(n *yaml.Node) bool { return n != nil && n.Kind == yaml.ScalarNode && n.Tag == "!!bool" }
+
+func hasScalar(seq *yaml.Node, v string) bool {
+    for _, c := range seq.Content {
+        if c.Kind == yaml.ScalarNode && c.Value == v { return true }
+    }
+    return false
+}
+
+func hasNull(seq *yaml.Node) bool {
+    for _, c := range seq.Content {
+        if c.Kind == yaml.ScalarNode && c.Tag == "!!null" { return true }
+    }
+    return false
+}
+
+// replaceKey swaps the entry for key in m for newKey: val, keeping its position.
+func replaceKey(m *yaml.Node, key, newKey string, val *yaml.Node) {
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if m.Content[i].Value == key {
+            m.Content[i], m.Content[i+1] = yamlnode.Str(newKey), val
+            return
+        }
+    }
+}
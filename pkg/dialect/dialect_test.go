@@ -0,0 +1,63 @@
+package dialect
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+// parse returns the document node of YAML text.
+func parse(t *testing.T, text string) *yaml.Node {
+    t.Helper()
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(text), &doc); err != nil { t.Fatal(err) }
+    return &doc
+}
+
+// equal reports whether n holds the same data as the YAML text want.
+func equal(t *testing.T, n *yaml.Node, want string) bool {
+    t.Helper()
+    var got, w any
+    if err := n.Decode(&got); err != nil { t.Fatal(err) }
+    if err := yaml.Unmarshal([]byte(want), &w); err != nil { t.Fatal(err) }
+    return reflect.DeepEqual(got, w)
+}
+
+func TestIs31(t *testing.T) {
+    for v, want := range map[string]bool{"3.1.0": true, " 3.1.1": true, "3.0.3": false, "": false} {
+        if Is31(v) != want { t.Errorf("Is31(%q) = %v", v, !want) }
+    }
+}
+
+func TestUpgrade(t *testing.T) {
+    tests := []struct{ name, in, want string }{
+        {"nullable type", "{type: string, nullable: true}", "{type: [string, 'null']}"},
+        {"nullable enum", "{type: string, enum: [a], nullable: true}", "{type: [string, 'null'], enum: [a, null]}"},
+        {"nullable false", "{type: string, nullable: false}", "{type: string}"},
+        {"nullable without type is kept", "{nullable: true}", "{nullable: true}"},
+        {"exclusive bounds", "{minimum: 0, exclusiveMinimum: true, maximum: 9, exclusiveMaximum: false}", "{exclusiveMinimum: 0, maximum: 9}"},
+        {"nested schemas", "{properties: {nullable: {type: object, properties: {a: {type: integer, nullable: true}}}}}",
+            "{properties: {nullable: {type: object, properties: {a: {type: [integer, 'null']}}}}}"},
+        {"examples and extensions are data", "{example: {nullable: true, type: string}, x-s: {nullable: true, type: string}}",
+            "{example: {nullable: true, type: string}, x-s: {nullable: true, type: string}}"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            n := parse(t, tt.in)
+            Upgrade(n)
+            if !equal(t, n, tt.want) {
+                out, _ := yaml.Marshal(n)
+                t.Errorf("Upgrade(%s) = %s, want %s", tt.in, out, tt.want)
+            }
+        })
+    }
+}
+
+func TestCheck31(t *testing.T) {
+    problems := Check31(parse(t, "a:\n  nullable: true\nb:\n  exclusiveMaximum: true\nc:\n  type: string\n  nullable: true\n"))
+    if len(problems) != 2 { t.Fatalf("problems = %+v", problems) }
+    if p := problems[0]; p.Line != 2 || p.Column != 13 || !strings.Contains(p.Message, "nullable: true without a type") { t.Errorf("problem 1 = %+v", p) }
+    if p := problems[1]; p.Line != 4 || !strings.Contains(p.Message, "exclusiveMaximum: true without maximum") { t.Errorf("problem 2 = %+v", p) }
+}
@@ -0,0 +1,260 @@
+// Package docs renders an OpenAPI spec as a single self-contained HTML page
+// (inline CSS and script, no network access), so docs can be built without
+// Redocly CLI or Node. The page runs the Redoc viewer when its bundle was
+// embedded with go generate (see Page), else it is rendered in Go.
+package docs
+
+import (
+    "bytes"
+    _ "embed"
+    "fmt"
+    "html/template"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+)
+
+//go:embed page.html.tmpl
+var pageTemplate string
+
+var page = template.Must(template.New("page").Parse(pageTemplate))
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type server struct{ URL, Description string }
+
+type param struct {
+    Name, In, Type, Description string
+    Required                    bool
+}
+
+type media struct {
+    Type   string
+    Schema template.HTML
+}
+
+type body struct {
+    Description string
+    Required    bool
+    Content     []media
+}
+
+type response struct {
+    Code, Description string
+    Content           []media
+}
+
+type operation struct {
+    Anchor, Method, Path, Summary, Description, OperationID string
+    Deprecated                                              bool
+    Params                                                  []param
+    Body                                                    *body
+    Responses                                               []response
+}
+
+type group struct {
+    Name, Description string
+    Ops               []operation
+}
+
+type schema struct {
+    Name string
+    Body template.HTML
+}
+
+type pageData struct {
+    Title, Version, Description string
+    Servers                     []server
+    Groups                      []group
+    Schemas                     []schema
+}
+
+// Write bundles the spec at specPath and renders it to out with Page. The
+// returned bool is false when out was already up to date.
+func Write(specPath, out string) (bool, error) {
+    spec, err := bundle.Load(specPath)
+    if err != nil { return false, err }
+    html, err := Page(spec)
+    if err != nil { return false, err }
+    if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil { return false, err }
+    return atomicfile.WriteFile(out, html)
+}
+
+// Render renders spec, the top-level node of a bundled OpenAPI document.
+// Operations are grouped by their first tag, in the order of the spec's tags.
+func Render(spec *yaml.Node) ([]byte, error) {
+    if spec == nil || spec.Kind != yaml.MappingNode {
+        return nil, fmt.Errorf("spec must be a mapping")
+    }
+    r := &renderer{root: spec}
+    data := pageData{Title: "API"}
+    info := r.deref(yamlnode.GetKey(spec, "info"))
+    data.Title = firstNonEmpty(scalar(info, "title"), data.Title)
+    data.Version, data.Description = scalar(info, "version"), scalar(info, "description")
+    for _, s := range items(yamlnode.GetKey(spec, "servers")) {
+        data.Servers = append(data.Servers, server{scalar(s, "url"), scalar(s, "description")})
+    }
+
+    groups := map[string]*group{}
+    var order []string
+    addGroup := func(name, desc string) *group {
+        if g := groups[name]; g != nil { return g }
+        groups[name] = &group{Name: name, Description: desc}
+        order = append(order, name)
+        return groups[name]
+    }
+    for _, t := range items(yamlnode.GetKey(spec, "tags")) {
+        if name := scalar(t, "name"); name != "" { addGroup(name, scalar(t, "description")) }
+    }
+    paths := yamlnode.GetKey(spec, "paths")
+    for _, kv := range pairs(paths) {
+        path, item := kv[0].Value, r.deref(kv[1])
+        shared := items(yamlnode.GetKey(item, "parameters"))
+        for _, m := range httpMethods {
+            op := r.deref(yamlnode.GetKey(item, m))
+            if op == nil || op.Kind != yaml.MappingNode { continue }
+            o := r.operation(m, path, op, shared)
+            tag := "default"
+            if tags := items(yamlnode.GetKey(op, "tags")); len(tags) > 0 && tags[0].Value != "" { tag = tags[0].Value }
+            g := addGroup(tag, "")
+            g.Ops = append(g.Ops, o)
+        }
+    }
+    for _, name := range order {
+        if g := groups[name]; len(g.Ops) > 0 { data.Groups = append(data.Groups, *g) }
+    }
+    for _, kv := range pairs(yamlnode.GetKey(yamlnode.GetKey(spec, "components"), "schemas")) {
+        data.Schemas = append(data.Schemas, schema{Name: kv[0].Value, Body: r.schemaHTML(kv[1], true)})
+    }
+
+    var buf bytes.Buffer
+    if err := page.Execute(&buf, data); err != nil { return nil, err }
+    return buf.Bytes(), nil
+}
+
+type renderer struct {
+    root    *yaml.Node
+    anchors map[string]int
+}
+
+// deref follows local refs such as #/components/parameters/UserId, or returns n when
+// they do not resolve.
+func (r *renderer) deref(n *yaml.Node) *yaml.Node {
+    if target := yamlnode.Deref(r.root, n); target != nil { return target }
+    return n
+}
+
+var reAnchorUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func (r *renderer) operation(method, path string, op *yaml.Node, shared []*yaml.Node) operation {
+    o := operation{
+        Method: method, Path: path,
+        Summary: scalar(op, "summary"), Description: scalar(op, "description"),
+        OperationID: scalar(op, "operationId"), Deprecated: scalar(op, "deprecated") == "true",
+    }
+    o.Anchor = r.anchor("op-" + firstNonEmpty(o.OperationID, method+path))
+
+    // Operation parameters override path-level ones with the same name and location.
+    seen := map[string]bool{}
+    for _, list := range [][]*yaml.Node{items(yamlnode.GetKey(op, "parameters")), shared} {
+        for _, p := range list {
+            p = r.deref(p)
+            key := scalar(p, "in") + ":" + scalar(p, "name")
+            if seen[key] { continue }
+            seen[key] = true
+            o.Params = append(o.Params, param{
+                Name: scalar(p, "name"), In: scalar(p, "in"), Required: scalar(p, "required") == "true",
+                Type: r.typeName(yamlnode.GetKey(p, "schema")), Description: scalar(p, "description"),
+            })
+        }
+    }
+    if rb := r.deref(yamlnode.GetKey(op, "requestBody")); rb != nil {
+        o.Body = &body{Description: scalar(rb, "description"), Required: scalar(rb, "required") == "true", Content: r.content(rb)}
+    }
+    for _, kv := range pairs(yamlnode.GetKey(op, "responses")) {
+        resp := r.deref(kv[1])
+        o.Responses = append(o.Responses, response{Code: kv[0].Value, Description: scalar(resp, "description"), Content: r.content(resp)})
+    }
+    return o
+}
+
+// anchor returns a unique HTML id derived from s.
+func (r *renderer) anchor(s string) string {
+    if r.anchors == nil { r.anchors = map[string]int{} }
+    a := strings.Trim(reAnchorUnsafe.ReplaceAllString(s, "-"), "-")
+    r.anchors[a]++
+    if n := r.anchors[a]; n > 1 { a = fmt.Sprintf("%s-%d", a, n) }
+    return a
+}
+
+func (r *renderer) content(n *yaml.Node) []media {
+    var out []media
+    for _, kv := range pairs(yamlnode.GetKey(n, "content")) {
+        out = append(out, media{Type: kv[0].Value, Schema: r.schemaHTML(yamlnode.GetKey(kv[1], "schema"), false)})
+    }
+    return out
+}
+
+// schemaHTML links refs to named schemas and shows anything else as YAML.
+func (r *renderer) schemaHTML(n *yaml.Node, full bool) template.HTML {
+    if n == nil { return "" }
+    if name := schemaRefName(n); name != "" && !full {
+        return template.HTML(fmt.Sprintf(`<p><a href="#schema-%s">%s</a></p>`, template.HTMLEscapeString(name), template.HTMLEscapeString(name)))
+    }
+    text, err := yamlnode.Marshal(n)
+    if err != nil { return "" }
+    return template.HTML("<pre>" + template.HTMLEscapeString(string(text)) + "</pre>")
+}
+
+// typeName summarizes a parameter schema, e.g. "string (uuid)" or "array of User".
+func (r *renderer) typeName(n *yaml.Node) string {
+    if n == nil { return "" }
+    if name := schemaRefName(n); name != "" { return name }
+    t := scalar(n, "type")
+    if t == "array" {
+        if it := r.typeName(yamlnode.GetKey(n, "items")); it != "" { return "array of " + it }
+    }
+    if f := scalar(n, "format"); f != "" { t += " (" + f + ")" }
+    return t
+}
+
+func schemaRefName(n *yaml.Node) string {
+    ref := yamlnode.GetKey(n, "$ref")
+    if ref == nil { return "" }
+    if name := strings.TrimPrefix(ref.Value, "#/components/schemas/"); name != ref.Value { return name }
+    return ""
+}
+
+// scalar returns the scalar value for key in mapping n, or "".
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func items(n *yaml.Node) []*yaml.Node {
+    if n == nil || n.Kind != yaml.SequenceNode { return nil }
+    return n.Content
+}
+
+func pairs(n *yaml.Node) [][2]*yaml.Node {
+    if n == nil || n.Kind != yaml.MappingNode { return nil }
+    var out [][2]*yaml.Node
+    for i := 0; i+1 < len(n.Content); i += 2 {
+        out = append(out, [2]*yaml.Node{n.Content[i], n.Content[i+1]})
+    }
+    return out
+}
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
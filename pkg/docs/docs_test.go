@@ -0,0 +1,67 @@
+package docs
+
+import (
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+func TestRender(t *testing.T) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(`openapi: 3.0.3
+info: {title: Pets <beta>, version: 1.0.0, description: All the pets.}
+servers:
+  - {url: https://api.example.com, description: Production}
+tags:
+  - {name: pets, description: Pet operations.}
+  - {name: unused}
+paths:
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string, format: uuid}}
+    get:
+      tags: [pets]
+      operationId: getPet
+      summary: Get a pet
+      parameters:
+        - $ref: '#/components/parameters/Fields'
+      responses:
+        '200':
+          description: The pet.
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+    delete:
+      deprecated: true
+      responses:
+        '204': {description: Gone.}
+components:
+  parameters:
+    Fields: {name: fields, in: query, schema: {type: array, items: {$ref: '#/components/schemas/Field'}}}
+  schemas:
+    Pet: {type: object, properties: {name: {type: string}}}
+    Field: {type: string}
+`), &doc); err != nil { t.Fatal(err) }
+    out, err := Render(doc.Content[0])
+    if err != nil { t.Fatal(err) }
+    html := string(out)
+
+    tests := []struct{ name, want string }{
+        {"escaped title", "<title>Pets &lt;beta&gt; 1.0.0</title>"},
+        {"server", `<td class="mono">https://api.example.com</td><td>Production</td>`},
+        {"tag group", "<h2>pets</h2>\n<p class=\"desc\">Pet operations.</p>"},
+        {"untagged operations", "<h2>default</h2>"},
+        {"operation anchor", `<section class="op" id="op-getPet">`},
+        {"deprecated operation", `<section class="op deprecated" id="op-delete-pets-id">`},
+        {"operation parameter first", `<td class="mono">fields</td><td>query</td><td>array of Field</td>`},
+        {"path parameter", `<td class="mono">id <span class="req">required</span></td><td>path</td><td>string (uuid)</td>`},
+        {"schema link", `<a href="#schema-Pet">Pet</a>`},
+        {"schema section", "<section id=\"schema-Pet\"><h4 class=\"mono\">Pet</h4><pre>{type: object, properties: {name: {type: string}}}\n</pre></section>"},
+        {"navigation", `<a href="#op-getPet" data-search="get /pets/{id} Get a pet">`},
+    }
+    for _, tt := range tests {
+        if !strings.Contains(html, tt.want) { t.Errorf("%s: page lacks %q", tt.name, tt.want) }
+    }
+    if strings.Contains(html, "unused") { t.Error("a tag without operations got a section") }
+}
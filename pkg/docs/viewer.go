@@ -0,0 +1,72 @@
+package docs
+
+import (
+    "bytes"
+    "embed"
+    "fmt"
+    "html/template"
+    "io/fs"
+    "regexp"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+//go:generate curl -fsSL -o viewer/redoc.standalone.js https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js
+
+// viewerFS holds the Redoc standalone bundle once go generate has fetched
+// it; a build without it renders the built-in page instead.
+//
+//go:embed viewer
+var viewerFS embed.FS
+
+const redocBundle = "viewer/redoc.standalone.js"
+
+//go:embed viewer.html.tmpl
+var viewerTemplate string
+
+var viewerPage = template.Must(template.New("viewer").Parse(viewerTemplate))
+
+// scriptEnd would close the inline script early.
+var scriptEnd = regexp.MustCompile(`(?i)</(script)`)
+
+// HasViewer reports whether the Redoc bundle is embedded in this build.
+func HasViewer() bool {
+    _, err := fs.Stat(viewerFS, redocBundle)
+    return err == nil
+}
+
+// Page renders spec with the embedded Redoc viewer when this build has it,
+// else with Render. Either page is a single file that works offline.
+func Page(spec *yaml.Node) ([]byte, error) {
+    if !HasViewer() { return Render(spec) }
+    script, err := viewerFS.ReadFile(redocBundle)
+    if err != nil { return nil, err }
+    return renderViewer(spec, script)
+}
+
+// renderViewer renders a page running script, the Redoc bundle, on spec
+// inlined as JSON.
+func renderViewer(spec *yaml.Node, script []byte) ([]byte, error) {
+    if spec == nil || spec.Kind != yaml.MappingNode {
+        return nil, fmt.Errorf("spec must be a mapping")
+    }
+    data, err := yamlnode.MarshalJSON(spec)
+    if err != nil { return nil, err }
+    // '<' only occurs inside JSON strings, where \u003c reads the same.
+    data = bytes.ReplaceAll(data, []byte("<"), []byte(`\u003c`))
+    info := yamlnode.GetKey(spec, "info")
+    var buf bytes.Buffer
+    err = viewerPage.Execute(&buf, struct {
+        Title, Version string
+        Script, Spec   template.JS
+    }{
+        Title:   firstNonEmpty(scalar(info, "title"), "API"),
+        Version: scalar(info, "version"),
+        Script:  template.JS(scriptEnd.ReplaceAll(script, []byte(`<\/$1`))),
+        Spec:    template.JS(data),
+    })
+    if err != nil { return nil, err }
+    return buf.Bytes(), nil
+}
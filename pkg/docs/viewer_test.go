@@ -0,0 +1,36 @@
+package docs
+
+import (
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+func TestRenderViewer(t *testing.T) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte("openapi: 3.0.3\ninfo: {title: Pets <beta>, version: 1.0.0, description: '</script><b>'}\npaths: {}\n"), &doc); err != nil { t.Fatal(err) }
+    out, err := renderViewer(doc.Content[0], []byte(`var Redoc = {init: function () {}}; var s = "</SCRIPT>";`))
+    if err != nil { t.Fatal(err) }
+    page := string(out)
+    for _, want := range []string{
+        "<title>Pets &lt;beta&gt; 1.0.0</title>",
+        `var Redoc = {init: function () {}}; var s = "<\/SCRIPT>";`,
+        `"title": "Pets \u003cbeta>"`,
+        `"description": "\u003c/script>\u003cb>"`,
+    } {
+        if !strings.Contains(page, want) { t.Errorf("page lacks %q:\n%s", want, page) }
+    }
+    if n := strings.Count(strings.ToLower(page), "</script>"); n != 2 { t.Errorf("page closes %d scripts, want 2:\n%s", n, page) }
+}
+
+func TestPageFallsBackToRender(t *testing.T) {
+    if HasViewer() { t.Skip("the Redoc bundle is embedded") }
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte("openapi: 3.0.3\ninfo: {title: Pets, version: 1.0.0}\npaths: {}\n"), &doc); err != nil { t.Fatal(err) }
+    page, err := Page(doc.Content[0])
+    if err != nil { t.Fatal(err) }
+    want, err := Render(doc.Content[0])
+    if err != nil { t.Fatal(err) }
+    if string(page) != string(want) { t.Errorf("Page differs from Render") }
+}
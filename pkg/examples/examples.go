@@ -0,0 +1,383 @@
+// Package examples generates example values from schemas, for the media
+// types and schemas of a spec that have none.
+//
+// Values are deterministic, so refilling gives the same result: the
+// schema's own example, default, enum or const when it has one, else a
+// value for its format (date-time, email, uuid, ...) or for the name of the
+// property holding it (email, firstName, city, price, ...), kept within
+// minimum/maximum and minLength/maxLength.
+package examples
+
+import (
+    "strconv"
+    "strings"
+    "unicode"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+)
+
+// maxDepth bounds generation for recursive schemas; deeper properties are
+// left out and deeper arrays are empty.
+const maxDepth = 6
+
+// Gap is a media type or schema without an example.
+type Gap struct {
+    Pointer string     // JSON pointer of the media type or schema, relative to the searched node
+    Schema  *yaml.Node // schema the example is generated from
+    Request bool       // a request body: readOnly properties are left out, else writeOnly ones
+}
+
+// Keys holding values or data rather than OpenAPI objects.
+var valueKeys = map[string]bool{
+    "example": true, "examples": true, "value": true, "default": true, "enum": true, "const": true,
+}
+
+// Gaps returns the media types below n that have a schema but neither
+// example nor examples, and n itself when schema is set and it is a schema
+// without example. Media types whose schema is a $ref are left to the
+// referenced schema.
+func Gaps(n *yaml.Node, schema bool) []Gap {
+    if n == nil || n.Kind != yaml.MappingNode { return nil }
+    if schema {
+        if hasExample(n) { return nil }
+        return []Gap{{Schema: n}}
+    }
+    var gaps []Gap
+    var walk func(n *yaml.Node, ptr string, request bool)
+    walk = func(n *yaml.Node, ptr string, request bool) {
+        if n == nil { return }
+        if n.Kind == yaml.SequenceNode {
+            for i, c := range n.Content {
+                walk(c, ptr+"/"+strconv.Itoa(i), request)
+            }
+            return
+        }
+        if n.Kind != yaml.MappingNode { return }
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            k, v := n.Content[i].Value, n.Content[i+1]
+            at := ptr + "/" + yamlnode.EscapeToken(k)
+            switch {
+            case valueKeys[k] || k == "schema" || k == "schemas" || strings.HasPrefix(k, "x-"):
+            case k == "content" && v.Kind == yaml.MappingNode:
+                for j := 0; j+1 < len(v.Content); j += 2 {
+                    media := v.Content[j+1]
+                    s := yamlnode.GetKey(media, "schema")
+                    if s == nil || hasExample(media) || yamlnode.GetKey(s, "$ref") != nil { continue }
+                    gaps = append(gaps, Gap{Pointer: at + "/" + yamlnode.EscapeToken(v.Content[j].Value), Schema: s, Request: request})
+                }
+            default:
+                walk(v, at, request || k == "requestBody" || k == "requestBodies")
+            }
+        }
+    }
+    walk(n, "", false)
+    return gaps
+}
+
+func hasExample(n *yaml.Node) bool {
+    return yamlnode.GetKey(n, "example") != nil || yamlnode.GetKey(n, "examples") != nil
+}
+
+// Fill sets the example of every gap in the paths, webhooks and components
+// of root, a bundled document, and returns how many it filled.
+func Fill(root *yaml.Node) int {
+    g := &Generator{Root: root}
+    n := 0
+    for _, key := range []string{"paths", "webhooks", "components"} {
+        section := yamlnode.GetKey(root, key)
+        gaps := Gaps(section, false)
+        if key == "components" {
+            schemas := yamlnode.GetKey(section, "schemas")
+            for i := 0; schemas != nil && i+1 < len(schemas.Content); i += 2 {
+                for _, gap := range Gaps(schemas.Content[i+1], true) {
+                    gap.Pointer = "/schemas/" + yamlnode.EscapeToken(schemas.Content[i].Value)
+                    gaps = append(gaps, gap)
+                }
+            }
+        }
+        for _, gap := range gaps {
+            target, err := bundle.Lookup(section, gap.Pointer)
+            if err != nil { continue }
+            yamlnode.SetKey(target, "example", g.Generate(gap.Schema, gap.Request))
+            n++
+        }
+    }
+    return n
+}
+
+// Generator builds example values, resolving #/... refs in Root.
+type Generator struct {
+    Root *yaml.Node
+}
+
+// Generate returns an example value for schema.
+func (g *Generator) Generate(schema *yaml.Node, request bool) *yaml.Node {
+    v := g.value(schema, "", request, 0)
+    if v == nil { return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"} }
+    return v
+}
+
+// deref follows the $refs of schema.
+func (g *Generator) deref(schema *yaml.Node) *yaml.Node { return yamlnode.Deref(g.Root, schema) }
+
+// value generates a value for schema, held by the property name (or the
+// array of that name); nil means nothing sensible can be generated.
+func (g *Generator) value(schema *yaml.Node, name string, request bool, depth int) *yaml.Node {
+    schema = g.deref(schema)
+    if schema == nil || schema.Kind != yaml.MappingNode || depth > maxDepth { return nil }
+    for _, key := range []string{"example", "default", "const"} {
+        if v := yamlnode.GetKey(schema, key); v != nil { return copyNode(v) }
+    }
+    if examples := yamlnode.GetKey(schema, "examples"); examples != nil && examples.Kind == yaml.SequenceNode && len(examples.Content) > 0 {
+        return copyNode(examples.Content[0])
+    }
+    if enum := yamlnode.GetKey(schema, "enum"); enum != nil && enum.Kind == yaml.SequenceNode && len(enum.Content) > 0 {
+        return copyNode(enum.Content[0])
+    }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode {
+        merged := yamlnode.Map()
+        own := yamlnode.Map() // properties next to allOf
+        for i := 0; i+1 < len(schema.Content); i += 2 {
+            if schema.Content[i].Value != "allOf" { own.Content = append(own.Content, schema.Content[i], schema.Content[i+1]) }
+        }
+        parts := all.Content
+        if yamlnode.GetKey(own, "properties") != nil { parts = append(append([]*yaml.Node{}, parts...), own) }
+        for _, part := range parts {
+            v := g.value(part, name, request, depth+1)
+            if v == nil || v.Kind != yaml.MappingNode {
+                if len(parts) == 1 { return v }
+                continue
+            }
+            for i := 0; i+1 < len(v.Content); i += 2 {
+                yamlnode.SetKey(merged, v.Content[i].Value, v.Content[i+1])
+            }
+        }
+        return merged
+    }
+    for _, key := range []string{"oneOf", "anyOf"} {
+        if alts := yamlnode.GetKey(schema, key); alts != nil && alts.Kind == yaml.SequenceNode && len(alts.Content) > 0 {
+            return g.value(alts.Content[0], name, request, depth+1)
+        }
+    }
+
+    switch schemaType(schema) {
+    case "object":
+        obj := yamlnode.Map()
+        props := yamlnode.GetKey(schema, "properties")
+        for i := 0; props != nil && i+1 < len(props.Content); i += 2 {
+            p := g.deref(props.Content[i+1])
+            if request && isTrue(yamlnode.GetKey(p, "readOnly")) || !request && isTrue(yamlnode.GetKey(p, "writeOnly")) { continue }
+            if v := g.value(p, props.Content[i].Value, request, depth+1); v != nil { yamlnode.SetKey(obj, props.Content[i].Value, v) }
+        }
+        if extra := yamlnode.GetKey(schema, "additionalProperties"); props == nil && extra != nil && extra.Kind == yaml.MappingNode {
+            if v := g.value(extra, "", request, depth+1); v != nil { yamlnode.SetKey(obj, "key", v) }
+        }
+        return obj
+    case "array":
+        arr := yamlnode.Seq()
+        count := 1
+        if m, err := strconv.Atoi(scalar(schema, "minItems")); err == nil && m > count { count = m }
+        for i := 0; i < count; i++ {
+            v := g.value(yamlnode.GetKey(schema, "items"), singular(name), request, depth+1)
+            if v == nil { break }
+            arr.Content = append(arr.Content, v)
+        }
+        return arr
+    case "integer":
+        return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(int64(bounded(schema, sampleNumber(name, true))), 10)}
+    case "number":
+        return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(bounded(schema, sampleNumber(name, false)), 'f', -1, 64)}
+    case "boolean":
+        return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"}
+    case "string":
+        return yamlnode.Str(fitLength(schema, sampleString(scalar(schema, "format"), name)))
+    }
+    return nil
+}
+
+// schemaType returns the type of schema, the first non-null one of an
+// OpenAPI 3.1 type array, or the type its keywords imply.
+func schemaType(schema *yaml.Node) string {
+    if t := yamlnode.GetKey(schema, "type"); t != nil {
+        if t.Kind == yaml.ScalarNode { return t.Value }
+        for _, c := range t.Content {
+            if c.Value != "null" { return c.Value }
+        }
+    }
+    switch {
+    case yamlnode.GetKey(schema, "properties") != nil, yamlnode.GetKey(schema, "additionalProperties") != nil:
+        return "object"
+    case yamlnode.GetKey(schema, "items") != nil:
+        return "array"
+    }
+    return ""
+}
+
+// bounded moves v into the schema's minimum/maximum range.
+func bounded(schema *yaml.Node, v float64) float64 {
+    if m, err := strconv.ParseFloat(scalar(schema, "minimum"), 64); err == nil && v < m {
+        v = m
+        if isTrue(yamlnode.GetKey(schema, "exclusiveMinimum")) { v++ }
+    }
+    if m, err := strconv.ParseFloat(scalar(schema, "maximum"), 64); err == nil && v > m {
+        v = m
+        if isTrue(yamlnode.GetKey(schema, "exclusiveMaximum")) { v-- }
+    }
+    return v
+}
+
+// fitLength pads or cuts s to the schema's minLength/maxLength.
+func fitLength(schema *yaml.Node, s string) string {
+    if m, err := strconv.Atoi(scalar(schema, "minLength")); err == nil && len(s) < m { s += strings.Repeat("x", m-len(s)) }
+    if m, err := strconv.Atoi(scalar(schema, "maxLength")); err == nil && len(s) > m { s = s[:m] }
+    return s
+}
+
+// nameMatches reports whether the property name ends in the words of
+// suffix: createdAt, created_at and Created-At all end in "createdat" and
+// "at", but page does not end in "age".
+func nameMatches(name, suffix string) bool {
+    var words []string
+    start := 0
+    for i, r := range name {
+        switch {
+        case r == '_' || r == '-' || r == ' ' || r == '.':
+            words = append(words, name[start:i])
+            start = i + 1
+        case i > start && unicode.IsUpper(r) && !unicode.IsUpper(rune(name[i-1])):
+            words = append(words, name[start:i])
+            start = i
+        }
+    }
+    words = append(words, name[start:])
+    tail := ""
+    for i := len(words) - 1; i >= 0; i-- {
+        tail = strings.ToLower(words[i]) + tail
+        if tail == suffix { return true }
+        if len(tail) >= len(suffix) { return false }
+    }
+    return false
+}
+
+// Sample numbers by property name, matched on its last words.
+var numberSamples = []struct {
+    suffix  string
+    integer float64
+    number  float64
+}{
+    {"latitude", 52, 52.52}, {"lat", 52, 52.52},
+    {"longitude", 13, 13.405}, {"lng", 13, 13.405}, {"lon", 13, 13.405},
+    {"price", 20, 19.99}, {"amount", 100, 99.5}, {"total", 120, 119.5}, {"balance", 250, 250.75},
+    {"percent", 25, 12.5}, {"percentage", 25, 12.5}, {"rate", 5, 0.05}, {"ratio", 1, 0.5},
+    {"age", 30, 30}, {"year", 2024, 2024}, {"month", 6, 6}, {"day", 15, 15},
+    {"page", 1, 1}, {"limit", 20, 20}, {"size", 20, 20}, {"offset", 0, 0},
+    {"count", 3, 3}, {"quantity", 2, 2}, {"port", 8080, 8080},
+    {"id", 42, 42},
+}
+
+func sampleNumber(name string, integer bool) float64 {
+    for _, s := range numberSamples {
+        if nameMatches(name, s.suffix) {
+            if integer { return s.integer }
+            return s.number
+        }
+    }
+    if integer { return 1 }
+    return 1.5
+}
+
+// Sample strings by format.
+var formatSamples = map[string]string{
+    "date-time": "2024-05-17T09:30:00Z",
+    "date":      "2024-05-17",
+    "time":      "09:30:00",
+    "duration":  "PT15M",
+    "email":     "jane.doe@example.com",
+    "idn-email": "jane.doe@example.com",
+    "uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+    "uri":       "https://example.com/resource",
+    "url":       "https://example.com/resource",
+    "iri":       "https://example.com/resource",
+    "uri-reference": "/resource",
+    "hostname":  "api.example.com",
+    "ipv4":      "192.0.2.10",
+    "ipv6":      "2001:db8::10",
+    "byte":      "aGVsbG8gd29ybGQ=",
+    "binary":    "<binary>",
+    "password":  "s3cr3t-Passw0rd",
+    "regex":     "^[a-z]+$",
+}
+
+// Sample strings by property name, matched on its last words so that
+// billingEmail and homeCity are covered too; the first match wins.
+var nameSamples = []struct{ suffix, value string }{
+    {"email", "jane.doe@example.com"},
+    {"firstname", "Jane"}, {"givenname", "Jane"},
+    {"lastname", "Doe"}, {"familyname", "Doe"}, {"surname", "Doe"},
+    {"username", "jdoe"}, {"login", "jdoe"}, {"nickname", "jdoe"},
+    {"fullname", "Jane Doe"}, {"displayname", "Jane Doe"},
+    {"phonenumber", "+1-202-555-0143"}, {"phone", "+1-202-555-0143"}, {"mobile", "+1-202-555-0143"},
+    {"companyname", "Acme Corp"}, {"company", "Acme Corp"}, {"organization", "Acme Corp"},
+    {"street", "221B Baker Street"}, {"address", "221B Baker Street"}, {"addressline1", "221B Baker Street"},
+    {"city", "Berlin"}, {"state", "Berlin"}, {"region", "Berlin"},
+    {"countrycode", "DE"}, {"country", "Germany"},
+    {"zipcode", "10115"}, {"zip", "10115"}, {"postalcode", "10115"}, {"postcode", "10115"},
+    {"currencycode", "EUR"}, {"currency", "EUR"},
+    {"locale", "en-US"}, {"language", "en"}, {"timezone", "Europe/Berlin"},
+    {"url", "https://example.com"}, {"uri", "https://example.com"}, {"website", "https://example.com"}, {"homepage", "https://example.com"}, {"link", "https://example.com"},
+    {"avatar", "https://example.com/avatar.png"}, {"image", "https://example.com/image.png"},
+    {"createdat", "2024-05-17T09:30:00Z"}, {"updatedat", "2024-05-17T09:30:00Z"}, {"deletedat", "2024-05-17T09:30:00Z"}, {"timestamp", "2024-05-17T09:30:00Z"},
+    {"date", "2024-05-17"}, {"birthday", "1990-01-01"},
+    {"uuid", "3fa85f64-5717-4562-b3fc-2c963f66afa6"}, {"id", "3fa85f64-5717-4562-b3fc-2c963f66afa6"},
+    {"token", "eyJhbGciOiJIUzI1NiJ9.e30.ZRrHA1JJJW8opsbCGfG_HACGpVUMN_a9IV7pAx_Zmeo"},
+    {"password", "s3cr3t-Passw0rd"}, {"secret", "s3cr3t"},
+    {"color", "#336699"}, {"colour", "#336699"},
+    {"slug", "example-slug"}, {"sku", "SKU-12345"}, {"iban", "DE89370400440532013000"},
+    {"ip", "192.0.2.10"}, {"hostname", "api.example.com"}, {"host", "api.example.com"},
+    {"title", "Example title"}, {"summary", "A short summary."}, {"description", "A short description."},
+    {"comment", "Looks good to me."}, {"message", "Hello, world!"}, {"note", "A short note."},
+    {"status", "active"}, {"type", "default"}, {"code", "ABC123"}, {"version", "1.0.0"},
+    {"tag", "example"}, {"category", "general"}, {"name", "Example name"},
+}
+
+func sampleString(format, name string) string {
+    if s, ok := formatSamples[format]; ok { return s }
+    for _, s := range nameSamples {
+        if nameMatches(name, s.suffix) { return s.value }
+    }
+    if name != "" { return "example " + name }
+    return "string"
+}
+
+// singular names the items of an array property: tags gives tag and
+// categories category.
+func singular(name string) string {
+    switch {
+    case strings.HasSuffix(name, "ies"):
+        return strings.TrimSuffix(name, "ies") + "y"
+    case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+        return strings.TrimSuffix(name, "s")
+    }
+    return name
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func isTrue(n *yaml.Node) bool { return n != nil && n.Kind == yaml.ScalarNode && n.Value == "true" }
+
+// copyNode deep-copies n, so generated examples do not share nodes with
+// the schemas they came from.
+func copyNode(n *yaml.Node) *yaml.Node {
+    c := *n
+    c.Content = make([]*yaml.Node, len(n.Content))
+    for i, ch := range n.Content {
+        c.Content[i] = copyNode(ch)
+    }
+    return &c
+}
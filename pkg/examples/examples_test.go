@@ -0,0 +1,105 @@
+package examples
+
+import (
+    "reflect"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// parse returns the top-level node of a YAML document.
+func parse(t *testing.T, text string) *yaml.Node {
+    t.Helper()
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(text), &doc); err != nil { t.Fatal(err) }
+    return doc.Content[0]
+}
+
+func TestGenerate(t *testing.T) {
+    root := parse(t, "components:\n  schemas:\n    Money: {type: object, properties: {amount: {type: number}, currency: {type: string}}}\n")
+    tests := []struct {
+        name, schema string
+        request      bool
+        want         string
+    }{
+        {"own example wins", "{type: string, example: hi, default: ho}", false, "hi"},
+        {"enum", "{type: string, enum: [b, a]}", false, "b"},
+        {"format", "{type: string, format: date-time}", false, "'2024-05-17T09:30:00Z'"},
+        {"bounded number", "{type: integer, minimum: 10, exclusiveMinimum: true}", false, "11"},
+        {"fitted string", "{type: string, format: uuid, maxLength: 8}", false, "3fa85f64"},
+        {"property names", `type: object
+properties:
+  billingEmail: {type: string}
+  page: {type: integer}
+  createdAt: {type: string}
+  tags: {type: array, minItems: 2, items: {type: string}}
+  categories: {type: array, items: {type: string}}
+  price: {$ref: '#/components/schemas/Money'}
+`, false, `billingEmail: jane.doe@example.com
+page: 1
+createdAt: '2024-05-17T09:30:00Z'
+tags: [example, example]
+categories: [general]
+price: {amount: 99.5, currency: EUR}
+`},
+        {"read and write only", "properties: {id: {type: integer, readOnly: true}, password: {type: string, writeOnly: true}}", true, "password: s3cr3t-Passw0rd"},
+        {"read and write only in a response", "properties: {id: {type: integer, readOnly: true}, password: {type: string, writeOnly: true}}", false, "id: 42"},
+        {"allOf merges", "allOf: [{$ref: '#/components/schemas/Money'}, {properties: {note: {type: string}}}]", false, "{amount: 99.5, currency: EUR, note: A short note.}"},
+        {"oneOf takes the first", "oneOf: [{type: boolean}, {type: string}]", false, "true"},
+        {"map", "additionalProperties: {type: integer}", false, "key: 1"},
+        {"unknown", "{}", false, "null"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            g := &Generator{Root: root}
+            var got, want any
+            if err := g.Generate(parse(t, tt.schema), tt.request).Decode(&got); err != nil { t.Fatal(err) }
+            if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil { t.Fatal(err) }
+            if !reflect.DeepEqual(got, want) { t.Errorf("Generate = %#v, want %#v", got, want) }
+        })
+    }
+}
+
+func TestGapsAndFill(t *testing.T) {
+    spec := parse(t, `paths:
+  /a~b/c:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {properties: {id: {type: integer, readOnly: true}, name: {type: string}}}
+      responses:
+        '200':
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Item'}
+            text/plain:
+              schema: {type: string}
+              example: done
+components:
+  schemas:
+    Item: {properties: {id: {type: integer}}}
+    Named: {type: string, example: x}
+`)
+    var pointers []string
+    for _, gap := range Gaps(yamlnode.GetKey(spec, "paths"), false) {
+        pointers = append(pointers, gap.Pointer)
+        if !gap.Request { t.Errorf("%s: not a request", gap.Pointer) }
+    }
+    if want := []string{"/~1a~0b~1c/post/requestBody/content/application~1json"}; !reflect.DeepEqual(pointers, want) {
+        t.Errorf("gaps = %v, want %v", pointers, want)
+    }
+
+    if n := Fill(spec); n != 2 { t.Errorf("Fill filled %d gaps, want 2", n) }
+    var got any
+    if err := spec.Decode(&got); err != nil { t.Fatal(err) }
+    paths := got.(map[string]any)["paths"].(map[string]any)
+    media := paths["/a~b/c"].(map[string]any)["post"].(map[string]any)["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)
+    if want := map[string]any{"name": "Example name"}; !reflect.DeepEqual(media["example"], want) { t.Errorf("request example = %v", media["example"]) }
+    schemas := got.(map[string]any)["components"].(map[string]any)["schemas"].(map[string]any)
+    if want := map[string]any{"id": 42}; !reflect.DeepEqual(schemas["Item"].(map[string]any)["example"], want) { t.Errorf("Item example = %v", schemas["Item"]) }
+    if schemas["Named"].(map[string]any)["example"] != "x" { t.Errorf("Named example = %v", schemas["Named"]) }
+    if Fill(spec) != 0 { t.Error("refilling found gaps") }
+}
@@ -0,0 +1,679 @@
+// Package gogen emits Go code for a bundled OpenAPI spec without
+// oapi-codegen or openapi-generator: a type per component schema (structs
+// with json tags, enums as typed constants) and a small net/http client with
+// one method per operation.
+//
+// Optional and nullable struct fields are pointers (slices and maps stay
+// values), inline objects become named types after the field that holds
+// them, allOf embeds its referenced components, and oneOf/anyOf are left to
+// the caller as json.RawMessage.
+package gogen
+
+import (
+    "fmt"
+    "go/format"
+    "sort"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// maxDepth bounds inline expansion; deeper schemas are interface{}.
+const maxDepth = 32
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Options control the generated file.
+type Options struct {
+    Package string // package clause (default: api)
+}
+
+type generator struct {
+    root    *yaml.Node
+    names   map[string]string // component schema name -> Go type
+    structs map[string]bool   // Go types declared as structs
+    taken   map[string]bool   // declared Go type names
+    imports map[string]bool
+    decls   []string
+}
+
+// Generate renders the Go code of spec, the top-level node of a bundled
+// document.
+func Generate(spec *yaml.Node, opts Options) ([]byte, error) {
+    if spec == nil || spec.Kind != yaml.MappingNode { return nil, fmt.Errorf("spec must be a mapping") }
+    g := &generator{root: spec, names: map[string]string{}, structs: map[string]bool{}, taken: map[string]bool{}, imports: map[string]bool{}}
+    for _, reserved := range []string{"Client", "NewClient", "APIError"} { g.taken[reserved] = true }
+
+    schemas := yamlnode.GetKey(yamlnode.GetKey(spec, "components"), "schemas")
+    if schemas != nil && schemas.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(schemas.Content); i += 2 {
+            name := g.unique(Identifier(schemas.Content[i].Value))
+            g.names[schemas.Content[i].Value] = name
+            if isStruct(schemas.Content[i+1]) { g.structs[name] = true }
+        }
+        for i := 0; i+1 < len(schemas.Content); i += 2 {
+            g.declare(g.names[schemas.Content[i].Value], schemas.Content[i+1])
+        }
+    }
+
+    var ops []string
+    methods := map[string]bool{}
+    if paths := yamlnode.GetKey(spec, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(paths.Content); i += 2 {
+            path, item := paths.Content[i].Value, g.deref(paths.Content[i+1])
+            for _, m := range httpMethods {
+                op := g.deref(yamlnode.GetKey(item, m))
+                if op == nil || op.Kind != yaml.MappingNode { continue }
+                name := firstNonEmpty(Identifier(scalar(op, "operationId")), Identifier(m+" "+path))
+                for methods[name] { name += "Operation" }
+                methods[name] = true
+                ops = append(ops, g.operation(name, m, path, item, op))
+            }
+        }
+    }
+    if len(ops) > 0 {
+        for _, imp := range []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "strings"} { g.imports[imp] = true }
+    }
+
+    var b strings.Builder
+    info := yamlnode.GetKey(spec, "info")
+    fmt.Fprintf(&b, "// Code generated by oas-indexer from %s %s. DO NOT EDIT.\n\n", firstNonEmpty(scalar(info, "title"), "API"), scalar(info, "version"))
+    fmt.Fprintf(&b, "package %s\n\n", firstNonEmpty(opts.Package, "api"))
+    if len(g.imports) > 0 {
+        imports := make([]string, 0, len(g.imports))
+        for imp := range g.imports { imports = append(imports, strconv.Quote(imp)) }
+        sort.Strings(imports)
+        b.WriteString("import (\n" + strings.Join(imports, "\n") + "\n)\n\n")
+    }
+    for _, d := range g.decls { b.WriteString(d + "\n") }
+    if len(ops) > 0 {
+        b.WriteString(clientCode)
+        for _, op := range ops { b.WriteString("\n" + op) }
+    }
+    src, err := format.Source([]byte(b.String()))
+    if err != nil { return nil, fmt.Errorf("generated code does not parse: %w", err) }
+    return src, nil
+}
+
+// unique returns name, or name with a number appended when it is taken, and
+// marks it taken.
+func (g *generator) unique(name string) string {
+    n := name
+    for i := 2; g.taken[n]; i++ { n = name + strconv.Itoa(i) }
+    g.taken[n] = true
+    return n
+}
+
+// declare adds the declaration of a named type for schema: a struct, an
+// enum with its constants, or an alias.
+func (g *generator) declare(name string, schema *yaml.Node) {
+    idx := len(g.decls)
+    g.decls = append(g.decls, "")
+    var b strings.Builder
+    b.WriteString(comment(schema))
+    switch {
+    case isStruct(schema):
+        g.structs[name] = true
+        b.WriteString("type " + name + " struct {\n")
+        g.fields(&b, schema, name, 0)
+        b.WriteString("}\n")
+    case isEnum(schema):
+        base := g.goType(withoutEnum(schema), name, 1)
+        fmt.Fprintf(&b, "type %s %s\n\n", name, base)
+        b.WriteString("const (\n")
+        seen := map[string]bool{}
+        for _, v := range yamlnode.GetKey(schema, "enum").Content {
+            if v.Kind != yaml.ScalarNode || v.Tag == "!!null" { continue }
+            c := name + firstNonEmpty(Identifier(v.Value), "Empty")
+            for n := 2; seen[c]; n++ { c = name + firstNonEmpty(Identifier(v.Value), "Empty") + strconv.Itoa(n) }
+            seen[c] = true
+            value := v.Value
+            if base == "string" { value = strconv.Quote(v.Value) }
+            fmt.Fprintf(&b, "%s %s = %s\n", c, name, value)
+        }
+        b.WriteString(")\n")
+    default:
+        fmt.Fprintf(&b, "type %s = %s\n", name, g.goType(schema, name, 1))
+    }
+    g.decls[idx] = b.String()
+}
+
+// fields writes the struct fields of schema, embedding the components its
+// allOf refers to and flattening its inline allOf parts.
+func (g *generator) fields(b *strings.Builder, schema *yaml.Node, owner string, depth int) {
+    seen := map[string]bool{}
+    var walk func(s *yaml.Node, depth int)
+    walk = func(s *yaml.Node, depth int) {
+        if s == nil || depth > maxDepth { return }
+        if all := yamlnode.GetKey(s, "allOf"); all != nil && all.Kind == yaml.SequenceNode {
+            for _, part := range all.Content {
+                if name := g.componentRef(part); name != "" && g.structs[name] {
+                    b.WriteString(name + "\n")
+                    continue
+                }
+                walk(g.deref(part), depth+1)
+            }
+        }
+        props := yamlnode.GetKey(s, "properties")
+        if props == nil || props.Kind != yaml.MappingNode { return }
+        required := map[string]bool{}
+        if req := yamlnode.GetKey(s, "required"); req != nil {
+            for _, r := range req.Content { required[r.Value] = true }
+        }
+        for i := 0; i+1 < len(props.Content); i += 2 {
+            key, prop := props.Content[i].Value, props.Content[i+1]
+            field := firstNonEmpty(Identifier(key), "Field")
+            for n := 2; seen[field]; n++ { field = Identifier(key) + strconv.Itoa(n) }
+            seen[field] = true
+            t := g.goType(prop, owner+field, depth+1)
+            tag := key
+            if !required[key] { tag += ",omitempty" }
+            if !required[key] || isNullable(g.deref(prop)) { t = pointer(t) }
+            b.WriteString(comment(g.deref(prop)))
+            fmt.Fprintf(b, "%s %s `json:%s`\n", field, t, strconv.Quote(tag))
+        }
+    }
+    walk(schema, depth)
+}
+
+// goType returns the Go type of schema, declaring a type named ctx for an
+// inline object.
+func (g *generator) goType(schema *yaml.Node, ctx string, depth int) string {
+    if schema == nil || schema.Kind != yaml.MappingNode || depth > maxDepth { return "interface{}" }
+    if name := g.componentRef(schema); name != "" { return name }
+    if yamlnode.GetKey(schema, "$ref") != nil { return g.goType(g.deref(schema), ctx, depth+1) }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode {
+        if len(all.Content) == 1 && yamlnode.GetKey(schema, "properties") == nil { return g.goType(all.Content[0], ctx, depth+1) }
+        name := g.unique(ctx)
+        g.declare(name, schema)
+        return name
+    }
+    for _, key := range []string{"oneOf", "anyOf"} {
+        if yamlnode.GetKey(schema, key) != nil {
+            g.imports["encoding/json"] = true
+            return "json.RawMessage"
+        }
+    }
+    var types []string
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        if t != "null" { types = append(types, t) }
+    }
+    if len(types) != 1 { return "interface{}" }
+    switch types[0] {
+    case "string":
+        switch scalar(schema, "format") {
+        case "date-time":
+            g.imports["time"] = true
+            return "time.Time"
+        case "binary", "byte":
+            return "[]byte"
+        }
+        return "string"
+    case "integer":
+        if scalar(schema, "format") == "int32" { return "int32" }
+        return "int64"
+    case "number":
+        if scalar(schema, "format") == "float" { return "float32" }
+        return "float64"
+    case "boolean":
+        return "bool"
+    case "array":
+        return "[]" + g.goType(yamlnode.GetKey(schema, "items"), ctx+"Item", depth+1)
+    case "object":
+        if props := yamlnode.GetKey(schema, "properties"); props != nil && len(props.Content) > 0 {
+            name := g.unique(ctx)
+            g.declare(name, schema)
+            return name
+        }
+        if extra := yamlnode.GetKey(schema, "additionalProperties"); extra != nil && extra.Kind == yaml.MappingNode {
+            return "map[string]" + g.goType(extra, ctx+"Value", depth+1)
+        }
+        return "map[string]interface{}"
+    }
+    return "interface{}"
+}
+
+// componentRef is the Go type of a ref to a component schema, if schema is
+// one.
+func (g *generator) componentRef(schema *yaml.Node) string {
+    ref := yamlnode.GetKey(schema, "$ref")
+    if ref == nil { return "" }
+    name, ok := strings.CutPrefix(ref.Value, "#/components/schemas/")
+    if !ok { return "" }
+    return g.names[yamlnode.UnescapeToken(name)]
+}
+
+type param struct {
+    name, in, field, arg, typ string
+    required                 bool
+    schema, node             *yaml.Node
+}
+
+// operation declares the parameter, body and response types of op and
+// returns its client method.
+func (g *generator) operation(name, method, path string, item, op *yaml.Node) string {
+    var params []*param
+    seen := map[string]int{}
+    for _, list := range []*yaml.Node{yamlnode.GetKey(item, "parameters"), yamlnode.GetKey(op, "parameters")} {
+        if list == nil || list.Kind != yaml.SequenceNode { continue }
+        for _, n := range list.Content {
+            n = g.deref(n)
+            p := &param{name: scalar(n, "name"), in: scalar(n, "in"), node: n}
+            if p.name == "" || p.in == "" || p.in == "cookie" { continue }
+            p.schema = yamlnode.GetKey(n, "schema")
+            if p.schema == nil { p.schema = mediaSchema(yamlnode.GetKey(n, "content")) }
+            p.required = p.in == "path" || isTrue(yamlnode.GetKey(n, "required"))
+            key := p.in + ":" + p.name
+            if i, ok := seen[key]; ok {
+                params[i] = p // operation parameters override the path item's
+                continue
+            }
+            seen[key] = len(params)
+            params = append(params, p)
+        }
+    }
+
+    var args, pathArgs []string
+    argNames := map[string]bool{"ctx": true, "params": true, "body": true, "contentType": true, "out": true, "path": true, "query": true, "header": true}
+    var optional []*param
+    for _, p := range params {
+        if p.in != "path" {
+            optional = append(optional, p)
+            continue
+        }
+        p.arg = argName(p.name)
+        for n := 2; argNames[p.arg]; n++ { p.arg = argName(p.name) + strconv.Itoa(n) }
+        argNames[p.arg] = true
+        p.typ = g.goType(p.schema, name+Identifier(p.name), 1)
+        pathArgs = append(pathArgs, p.arg+" "+p.typ)
+    }
+    args = append(args, "ctx context.Context")
+    args = append(args, orderPathArgs(path, params, pathArgs)...)
+
+    var b strings.Builder
+    if len(optional) > 0 {
+        paramsType := g.unique(name + "Params")
+        var decl strings.Builder
+        fmt.Fprintf(&decl, "// %s are the query and header parameters of %s.\ntype %s struct {\n", paramsType, name, paramsType)
+        fields := map[string]bool{}
+        for _, p := range optional {
+            p.field = firstNonEmpty(Identifier(p.name), "Param")
+            for n := 2; fields[p.field]; n++ { p.field = Identifier(p.name) + strconv.Itoa(n) }
+            fields[p.field] = true
+            p.typ = g.goType(p.schema, name+p.field, 1)
+            if !p.required { p.typ = pointer(p.typ) }
+            decl.WriteString(comment(p.node))
+            fmt.Fprintf(&decl, "%s %s\n", p.field, p.typ)
+        }
+        decl.WriteString("}\n")
+        g.decls = append(g.decls, decl.String())
+        args = append(args, "params *"+paramsType)
+    }
+
+    bodyArg, contentType := "nil", `""`
+    if rb := g.deref(yamlnode.GetKey(op, "requestBody")); rb != nil {
+        if mt, schema := media(yamlnode.GetKey(rb, "content")); mt != "" {
+            if strings.Contains(mt, "json") {
+                args = append(args, "body "+g.goType(schema, name+"RequestBody", 1))
+                bodyArg = "body"
+            } else {
+                args = append(args, "contentType string", "body io.Reader")
+                bodyArg, contentType = "body", "contentType"
+            }
+        }
+    }
+
+    result := ""
+    if responses := yamlnode.GetKey(op, "responses"); responses != nil && responses.Kind == yaml.MappingNode {
+        codes := make([]string, 0, len(responses.Content)/2)
+        byCode := map[string]*yaml.Node{}
+        for i := 0; i+1 < len(responses.Content); i += 2 {
+            codes = append(codes, responses.Content[i].Value)
+            byCode[responses.Content[i].Value] = responses.Content[i+1]
+        }
+        sort.Strings(codes)
+        for _, code := range codes {
+            if !strings.HasPrefix(code, "2") { continue }
+            mt, schema := media(yamlnode.GetKey(g.deref(byCode[code]), "content"))
+            if mt == "" { break }
+            if strings.Contains(mt, "json") {
+                result = g.goType(schema, name+"Response", 1)
+            } else {
+                result = "[]byte"
+            }
+            break
+        }
+    }
+
+    fmt.Fprintf(&b, "// %s sends %s %s.\n", name, strings.ToUpper(method), path)
+    if c := comment(op); c != "" { b.WriteString("//\n" + c) }
+    returns, ret := "(*"+result+", error)", "return &out, "
+    if direct(result) { returns, ret = "("+result+", error)", "return out, " }
+    if result == "" { returns = "error" }
+    fmt.Fprintf(&b, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), returns)
+    fmt.Fprintf(&b, "path := %s\n", pathExpr(path, params))
+    b.WriteString("query := url.Values{}\nheader := http.Header{}\n")
+    if len(optional) > 0 {
+        b.WriteString("if params != nil {\n")
+        for _, p := range optional {
+            set := "query.Add"
+            if p.in == "header" { set = "header.Add" }
+            ref := "params." + p.field
+            switch {
+            case strings.HasPrefix(p.typ, "[]") && p.typ != "[]byte":
+                fmt.Fprintf(&b, "for _, v := range %s {\n%s(%q, fmt.Sprint(v))\n}\n", ref, set, p.name)
+            case strings.HasPrefix(p.typ, "*"):
+                fmt.Fprintf(&b, "if %s != nil {\n%s(%q, fmt.Sprint(*%s))\n}\n", ref, set, p.name, ref)
+            default:
+                fmt.Fprintf(&b, "%s(%q, fmt.Sprint(%s))\n", set, p.name, ref)
+            }
+        }
+        b.WriteString("}\n")
+    }
+    if result == "" {
+        fmt.Fprintf(&b, "return c.do(ctx, %q, path, query, header, %s, %s, nil)\n}\n", strings.ToUpper(method), bodyArg, contentType)
+        return b.String()
+    }
+    fmt.Fprintf(&b, "var out %s\n", result)
+    fmt.Fprintf(&b, "if err := c.do(ctx, %q, path, query, header, %s, %s, &out); err != nil {\nreturn nil, err\n}\n", strings.ToUpper(method), bodyArg, contentType)
+    fmt.Fprintf(&b, "%snil\n}\n", ret)
+    return b.String()
+}
+
+// orderPathArgs orders the path parameter arguments as they appear in the
+// path template.
+func orderPathArgs(path string, params []*param, args []string) []string {
+    var out []string
+    pos := func(p *param) int {
+        if i := strings.Index(path, "{"+p.name+"}"); i >= 0 { return i }
+        return len(path)
+    }
+    var pathParams []*param
+    for _, p := range params {
+        if p.in == "path" { pathParams = append(pathParams, p) }
+    }
+    idx := make([]int, len(pathParams))
+    for i := range idx { idx[i] = i }
+    sort.SliceStable(idx, func(a, b int) bool { return pos(pathParams[idx[a]]) < pos(pathParams[idx[b]]) })
+    for _, i := range idx { out = append(out, args[i]) }
+    return out
+}
+
+// pathExpr builds the expression for the request path, escaping each path
+// parameter.
+func pathExpr(path string, params []*param) string {
+    args := map[string]string{}
+    for _, p := range params {
+        if p.in == "path" { args[p.name] = p.arg }
+    }
+    var parts []string
+    rest := path
+    for {
+        open := strings.Index(rest, "{")
+        end := strings.Index(rest, "}")
+        if open < 0 || end < open { break }
+        arg, ok := args[rest[open+1:end]]
+        if !ok {
+            parts = append(parts, strconv.Quote(rest[:end+1]))
+            rest = rest[end+1:]
+            continue
+        }
+        if open > 0 { parts = append(parts, strconv.Quote(rest[:open])) }
+        parts = append(parts, "url.PathEscape(fmt.Sprint("+arg+"))")
+        rest = rest[end+1:]
+    }
+    if rest != "" || len(parts) == 0 { parts = append(parts, strconv.Quote(rest)) }
+    return strings.Join(parts, " + ")
+}
+
+// direct reports types returned as they are rather than by pointer.
+func direct(t string) bool {
+    return strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map[") || t == "interface{}" || t == "json.RawMessage"
+}
+
+// pointer makes t a pointer type, unless it is nil-able already.
+func pointer(t string) string {
+    if direct(t) || strings.HasPrefix(t, "*") { return t }
+    return "*" + t
+}
+
+const clientCode = `// Client calls the API.
+type Client struct {
+	// Server is the base URL, such as https://api.example.com/v1.
+	Server string
+	// HTTPClient sends the requests (default: http.DefaultClient).
+	HTTPClient *http.Client
+	// RequestEditor, when set, is called with every request before it is
+	// sent, for example to add credentials.
+	RequestEditor func(ctx context.Context, req *http.Request) error
+}
+
+// NewClient returns a client for the API at server.
+func NewClient(server string) *Client { return &Client{Server: server} }
+
+// APIError is the error returned for a response outside 2xx.
+type APIError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, bytes.TrimSpace(e.Body))
+}
+
+// do sends a request with body (JSON-encoded unless it is an io.Reader) and
+// decodes a 2xx response into out, which may be nil or a *[]byte for the raw
+// body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body interface{}, contentType string, out interface{}) error {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		if contentType == "" {
+			contentType = "application/json"
+		}
+	}
+	target := strings.TrimRight(c.Server, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.RequestEditor != nil {
+		if err := c.RequestEditor(ctx, req); err != nil {
+			return err
+		}
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Body: data}
+	}
+	switch o := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*o = data
+		return nil
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+`
+
+// comment renders the description (else title) of n, and its deprecation,
+// as a Go comment.
+func comment(n *yaml.Node) string {
+    desc := strings.TrimSpace(firstNonEmpty(scalar(n, "description"), scalar(n, "summary"), scalar(n, "title")))
+    var b strings.Builder
+    if desc != "" {
+        for _, line := range strings.Split(desc, "\n") { b.WriteString(strings.TrimRight("// "+line, " ") + "\n") }
+    }
+    if isTrue(yamlnode.GetKey(n, "deprecated")) {
+        if desc != "" { b.WriteString("//\n") }
+        b.WriteString("// Deprecated: do not use.\n")
+    }
+    return b.String()
+}
+
+// deref follows local refs such as #/components/parameters/Limit.
+func (g *generator) deref(n *yaml.Node) *yaml.Node { return yamlnode.Deref(g.root, n) }
+
+// media picks the JSON media type of content, else the first, with its
+// schema.
+func media(content *yaml.Node) (string, *yaml.Node) {
+    if content == nil || content.Kind != yaml.MappingNode || len(content.Content) < 2 { return "", nil }
+    for i := 0; i+1 < len(content.Content); i += 2 {
+        if strings.Contains(content.Content[i].Value, "json") { return content.Content[i].Value, yamlnode.GetKey(content.Content[i+1], "schema") }
+    }
+    return content.Content[0].Value, yamlnode.GetKey(content.Content[1], "schema")
+}
+
+func mediaSchema(content *yaml.Node) *yaml.Node {
+    _, schema := media(content)
+    return schema
+}
+
+// isStruct reports schemas declared as structs: objects with properties,
+// and allOf compositions.
+func isStruct(schema *yaml.Node) bool {
+    if schema == nil || schema.Kind != yaml.MappingNode || yamlnode.GetKey(schema, "$ref") != nil { return false }
+    if yamlnode.GetKey(schema, "oneOf") != nil || yamlnode.GetKey(schema, "anyOf") != nil { return false }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode && (len(all.Content) > 1 || yamlnode.GetKey(schema, "properties") != nil) { return true }
+    props := yamlnode.GetKey(schema, "properties")
+    if props == nil || len(props.Content) == 0 { return false }
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        if t != "object" && t != "null" { return false }
+    }
+    return true
+}
+
+// isEnum reports scalar enums, which become typed constants.
+func isEnum(schema *yaml.Node) bool {
+    enum := yamlnode.GetKey(schema, "enum")
+    if enum == nil || enum.Kind != yaml.SequenceNode || len(enum.Content) == 0 { return false }
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        switch t {
+        case "string", "integer", "number":
+            return true
+        }
+    }
+    return false
+}
+
+func withoutEnum(schema *yaml.Node) *yaml.Node {
+    out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+    for i := 0; i+1 < len(schema.Content); i += 2 {
+        if schema.Content[i].Value == "enum" { continue }
+        out.Content = append(out.Content, schema.Content[i], schema.Content[i+1])
+    }
+    return out
+}
+
+func isNullable(schema *yaml.Node) bool {
+    if isTrue(yamlnode.GetKey(schema, "nullable")) { return true }
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        if t == "null" { return true }
+    }
+    return false
+}
+
+// initialisms are written in capitals in Go names, as golint expects.
+var initialisms = map[string]bool{"id": true, "url": true, "uri": true, "api": true, "http": true, "https": true, "json": true, "xml": true, "uuid": true, "html": true, "ip": true, "sql": true, "ttl": true, "cpu": true}
+
+// Identifier turns a component name, property, operationId or "method path"
+// into an exported Go identifier, such as UserID for user_id.
+func Identifier(s string) string {
+    var words []string
+    var cur []rune
+    flush := func() {
+        if len(cur) > 0 { words = append(words, string(cur)) }
+        cur = nil
+    }
+    runes := []rune(s)
+    for i, r := range runes {
+        alnum := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+        if !alnum {
+            flush()
+            continue
+        }
+        if r >= 'A' && r <= 'Z' && i > 0 && (runes[i-1] >= 'a' && runes[i-1] <= 'z' || runes[i-1] >= '0' && runes[i-1] <= '9') { flush() }
+        cur = append(cur, r)
+    }
+    flush()
+    var b strings.Builder
+    for _, w := range words {
+        if initialisms[strings.ToLower(w)] {
+            b.WriteString(strings.ToUpper(w))
+            continue
+        }
+        b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+    }
+    out := b.String()
+    if out != "" && out[0] >= '0' && out[0] <= '9' { out = "N" + out }
+    return out
+}
+
+var keywords = map[string]bool{"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true, "defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true, "goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true, "range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true}
+
+// argName is the unexported argument name of a path parameter.
+func argName(name string) string {
+    id := firstNonEmpty(Identifier(name), "Param")
+    i := 0
+    for i < len(id) && id[i] >= 'A' && id[i] <= 'Z' { i++ }
+    switch {
+    case i == len(id):
+        id = strings.ToLower(id)
+    case i > 1:
+        id = strings.ToLower(id[:i-1]) + id[i-1:]
+    default:
+        id = strings.ToLower(id[:1]) + id[1:]
+    }
+    if keywords[id] || id[0] >= '0' && id[0] <= '9' { id = "p" + Identifier(name) }
+    return id
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func isTrue(n *yaml.Node) bool { return n != nil && n.Kind == yaml.ScalarNode && n.Value == "true" }
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
@@ -0,0 +1,477 @@
+// Package graphql drafts a GraphQL schema (SDL) from a bundled OpenAPI spec,
+// as a starting point for a BFF that fronts the REST API.
+//
+// Component objects become types (and, where request bodies use them,
+// input types named <Name>Input), string enums become enums, oneOf/anyOf of
+// objects become unions, and GET operations become Query fields while the
+// other methods become Mutation fields taking their parameters and body as
+// arguments. Whatever has no GraphQL equivalent (maps, mixed unions, free-form
+// objects) is the JSON scalar.
+//
+// An x-graphql extension overrides the mapping: on an operation,
+// {name: users, type: query|mutation, skip: true}; on a component schema,
+// {name: Account, skip: true}.
+package graphql
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// maxDepth bounds inline expansion; deeper schemas are JSON.
+const maxDepth = 32
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type generator struct {
+    root    *yaml.Node
+    names   map[string]string // component schema name -> GraphQL type
+    kinds   map[string]string // component schema name -> type, enum, union or scalar
+    taken   map[string]bool
+    scalars map[string]bool // custom scalars used
+
+    decls      []string
+    inputs     map[string]string // component -> input type name, once requested
+    inputQueue []string
+}
+
+// Generate renders the SDL of spec, the top-level node of a bundled document.
+func Generate(spec *yaml.Node) ([]byte, error) {
+    if spec == nil || spec.Kind != yaml.MappingNode { return nil, fmt.Errorf("spec must be a mapping") }
+    g := &generator{root: spec, names: map[string]string{}, kinds: map[string]string{}, taken: map[string]bool{"Query": true, "Mutation": true, "JSON": true, "DateTime": true}, scalars: map[string]bool{}, inputs: map[string]string{}}
+
+    schemas := yamlnode.GetKey(yamlnode.GetKey(spec, "components"), "schemas")
+    if schemas != nil && schemas.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(schemas.Content); i += 2 {
+            component, schema := schemas.Content[i].Value, schemas.Content[i+1]
+            if isTrue(yamlnode.GetKey(yamlnode.GetKey(schema, "x-graphql"), "skip")) { continue }
+            g.names[component] = g.unique(firstNonEmpty(scalar(yamlnode.GetKey(schema, "x-graphql"), "name"), TypeName(component)))
+        }
+        for component := range g.names { g.kinds[component] = g.kind(yamlnode.GetKey(schemas, component)) }
+        for i := 0; i+1 < len(schemas.Content); i += 2 {
+            if _, ok := g.names[schemas.Content[i].Value]; ok { g.declare(schemas.Content[i].Value, schemas.Content[i+1]) }
+        }
+    }
+
+    var query, mutation []string
+    fields := map[string]bool{}
+    if paths := yamlnode.GetKey(spec, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(paths.Content); i += 2 {
+            path, item := paths.Content[i].Value, g.deref(paths.Content[i+1])
+            for _, m := range httpMethods {
+                op := g.deref(yamlnode.GetKey(item, m))
+                if op == nil || op.Kind != yaml.MappingNode { continue }
+                ext := yamlnode.GetKey(op, "x-graphql")
+                if isTrue(yamlnode.GetKey(ext, "skip")) { continue }
+                name := firstNonEmpty(scalar(ext, "name"), fieldName(firstNonEmpty(scalar(op, "operationId"), m+" "+path)))
+                for fields[name] { name += "_" + m }
+                fields[name] = true
+                field := g.operation(name, m, path, item, op)
+                switch t := strings.ToLower(scalar(ext, "type")); {
+                case t == "query", t == "" && m == "get":
+                    query = append(query, field)
+                default:
+                    mutation = append(mutation, field)
+                }
+            }
+        }
+    }
+    // Input types may request further input types.
+    for len(g.inputQueue) > 0 {
+        component := g.inputQueue[0]
+        g.inputQueue = g.inputQueue[1:]
+        g.declareInput(component)
+    }
+
+    var b strings.Builder
+    info := yamlnode.GetKey(spec, "info")
+    fmt.Fprintf(&b, "# Generated by oas-indexer from %s %s (experimental). Do not edit.\n", firstNonEmpty(scalar(info, "title"), "API"), scalar(info, "version"))
+    if g.scalars["DateTime"] { b.WriteString("\n\"\"\"An RFC 3339 date-time string.\"\"\"\nscalar DateTime\n") }
+    if g.scalars["JSON"] { b.WriteString("\n\"\"\"Any JSON value.\"\"\"\nscalar JSON\n") }
+    for _, d := range g.decls { b.WriteString("\n" + d) }
+    if len(query) > 0 { b.WriteString("\ntype Query {\n" + strings.Join(query, "") + "}\n") }
+    if len(mutation) > 0 { b.WriteString("\ntype Mutation {\n" + strings.Join(mutation, "") + "}\n") }
+    return []byte(b.String()), nil
+}
+
+func (g *generator) unique(name string) string {
+    n := name
+    for i := 2; g.taken[n]; i++ { n = fmt.Sprintf("%s%d", name, i) }
+    g.taken[n] = true
+    return n
+}
+
+// kind is how a component schema is declared.
+func (g *generator) kind(schema *yaml.Node) string {
+    switch {
+    case isEnum(schema):
+        return "enum"
+    case g.unionMembers(schema) != nil:
+        return "union"
+    case isObject(schema):
+        return "type"
+    }
+    return "scalar"
+}
+
+// declare writes the declaration of a component; components that are plain
+// scalars or arrays are not declared and are used in place.
+func (g *generator) declare(component string, schema *yaml.Node) {
+    name := g.names[component]
+    var b strings.Builder
+    b.WriteString(description(schema, ""))
+    switch g.kinds[component] {
+    case "enum":
+        b.WriteString("enum " + name + " {\n")
+        seen := map[string]bool{}
+        for _, v := range yamlnode.GetKey(schema, "enum").Content {
+            value := EnumValue(v.Value)
+            if value == "" || seen[value] { continue }
+            seen[value] = true
+            b.WriteString("  " + value + "\n")
+        }
+        b.WriteString("}\n")
+    case "union":
+        fmt.Fprintf(&b, "union %s = %s\n", name, strings.Join(g.unionMembers(schema), " | "))
+    case "type":
+        idx := len(g.decls)
+        g.decls = append(g.decls, "")
+        b.WriteString("type " + name + " {\n")
+        g.fields(&b, schema, name, false, 0)
+        b.WriteString("}\n")
+        g.decls[idx] = b.String()
+        return
+    default:
+        return
+    }
+    g.decls = append(g.decls, b.String())
+}
+
+// declareInput writes the input variant of an object component.
+func (g *generator) declareInput(component string) {
+    schema := yamlnode.GetKey(yamlnode.GetKey(yamlnode.GetKey(g.root, "components"), "schemas"), component)
+    idx := len(g.decls)
+    g.decls = append(g.decls, "")
+    var b strings.Builder
+    b.WriteString(description(schema, ""))
+    b.WriteString("input " + g.inputs[component] + " {\n")
+    g.fields(&b, schema, g.names[component], true, 0)
+    b.WriteString("}\n")
+    g.decls[idx] = b.String()
+}
+
+// fields writes the fields of an object schema, flattening allOf.
+func (g *generator) fields(b *strings.Builder, schema *yaml.Node, owner string, input bool, depth int) {
+    seen := map[string]bool{}
+    var walk func(s *yaml.Node, depth int)
+    walk = func(s *yaml.Node, depth int) {
+        s = g.deref(s)
+        if s == nil || depth > maxDepth { return }
+        if all := yamlnode.GetKey(s, "allOf"); all != nil && all.Kind == yaml.SequenceNode {
+            for _, part := range all.Content { walk(part, depth+1) }
+        }
+        props := yamlnode.GetKey(s, "properties")
+        if props == nil || props.Kind != yaml.MappingNode { return }
+        required := map[string]bool{}
+        if req := yamlnode.GetKey(s, "required"); req != nil {
+            for _, r := range req.Content { required[r.Value] = true }
+        }
+        for i := 0; i+1 < len(props.Content); i += 2 {
+            key, prop := props.Content[i].Value, props.Content[i+1]
+            // Input types leave out what the server sets, output types what
+            // only clients send.
+            if input && isTrue(yamlnode.GetKey(g.deref(prop), "readOnly")) || !input && isTrue(yamlnode.GetKey(g.deref(prop), "writeOnly")) { continue }
+            name := fieldName(key)
+            if seen[name] { continue }
+            seen[name] = true
+            t := g.typeOf(prop, owner+TypeName(key), input, depth+1)
+            if required[key] && !isNullable(g.deref(prop)) { t += "!" }
+            b.WriteString(description(g.deref(prop), "  "))
+            if !input && isTrue(yamlnode.GetKey(g.deref(prop), "deprecated")) { t += " @deprecated" }
+            fmt.Fprintf(b, "  %s: %s\n", name, t)
+        }
+    }
+    walk(schema, depth)
+}
+
+// typeOf returns the (nullable) GraphQL type of schema, declaring a type
+// named ctx for an inline object.
+func (g *generator) typeOf(schema *yaml.Node, ctx string, input bool, depth int) string {
+    if schema == nil || schema.Kind != yaml.MappingNode || depth > maxDepth { return g.scalar("JSON") }
+    if component := componentRef(schema); component != "" {
+        name, ok := g.names[component]
+        if !ok { return g.scalar("JSON") }
+        switch g.kinds[component] {
+        case "type":
+            if input { return g.input(component) }
+            return name
+        case "union":
+            if input { return g.scalar("JSON") }
+            return name
+        case "enum":
+            return name
+        }
+        return g.typeOf(yamlnode.GetKey(yamlnode.GetKey(yamlnode.GetKey(g.root, "components"), "schemas"), component), ctx, input, depth+1)
+    }
+    if yamlnode.GetKey(schema, "$ref") != nil { return g.typeOf(g.deref(schema), ctx, input, depth+1) }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode && len(all.Content) == 1 && yamlnode.GetKey(schema, "properties") == nil {
+        return g.typeOf(all.Content[0], ctx, input, depth+1)
+    }
+    if members := g.unionMembers(schema); members != nil && !input {
+        name := g.unique(ctx)
+        g.decls = append(g.decls, fmt.Sprintf("union %s = %s\n", name, strings.Join(members, " | ")))
+        return name
+    }
+    if yamlnode.GetKey(schema, "oneOf") != nil || yamlnode.GetKey(schema, "anyOf") != nil { return g.scalar("JSON") }
+    if isObject(schema) {
+        name := g.unique(ctx)
+        if input { name = g.unique(ctx + "Input") }
+        idx := len(g.decls)
+        g.decls = append(g.decls, "")
+        var b strings.Builder
+        keyword := "type "
+        if input { keyword = "input " }
+        b.WriteString(keyword + name + " {\n")
+        g.fields(&b, schema, ctx, input, depth)
+        b.WriteString("}\n")
+        g.decls[idx] = b.String()
+        return name
+    }
+    var types []string
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        if t != "null" { types = append(types, t) }
+    }
+    if len(types) != 1 { return g.scalar("JSON") }
+    switch types[0] {
+    case "string":
+        if scalar(schema, "format") == "date-time" { return g.scalar("DateTime") }
+        return "String"
+    case "integer":
+        return "Int"
+    case "number":
+        return "Float"
+    case "boolean":
+        return "Boolean"
+    case "array":
+        item := g.typeOf(yamlnode.GetKey(schema, "items"), ctx+"Item", input, depth+1)
+        return "[" + item + "!]"
+    }
+    return g.scalar("JSON")
+}
+
+// input returns the input type of an object component, queuing its
+// declaration the first time.
+func (g *generator) input(component string) string {
+    if name, ok := g.inputs[component]; ok { return name }
+    name := g.unique(g.names[component] + "Input")
+    g.inputs[component] = name
+    g.inputQueue = append(g.inputQueue, component)
+    return name
+}
+
+func (g *generator) scalar(name string) string {
+    g.scalars[name] = true
+    return name
+}
+
+// unionMembers returns the member types of a oneOf/anyOf whose members are
+// all object components, else nil.
+func (g *generator) unionMembers(schema *yaml.Node) []string {
+    var list *yaml.Node
+    for _, key := range []string{"oneOf", "anyOf"} {
+        if l := yamlnode.GetKey(schema, key); l != nil && l.Kind == yaml.SequenceNode { list = l }
+    }
+    if list == nil || len(list.Content) == 0 { return nil }
+    var members []string
+    for _, m := range list.Content {
+        component := componentRef(m)
+        if component == "" { return nil }
+        name, ok := g.names[component]
+        if !ok || !isObject(yamlnode.GetKey(yamlnode.GetKey(yamlnode.GetKey(g.root, "components"), "schemas"), component)) { return nil }
+        members = append(members, name)
+    }
+    return members
+}
+
+// operation renders the Query or Mutation field of op.
+func (g *generator) operation(name, method, path string, item, op *yaml.Node) string {
+    type arg struct{ name, typ string }
+    var args []arg
+    seen := map[string]bool{}
+    var params []*yaml.Node
+    byKey := map[string]int{}
+    for _, list := range []*yaml.Node{yamlnode.GetKey(item, "parameters"), yamlnode.GetKey(op, "parameters")} {
+        if list == nil || list.Kind != yaml.SequenceNode { continue }
+        for _, p := range list.Content {
+            p = g.deref(p)
+            in := scalar(p, "in")
+            if in != "path" && in != "query" { continue }
+            key := in + ":" + scalar(p, "name")
+            if i, ok := byKey[key]; ok {
+                params[i] = p
+                continue
+            }
+            byKey[key] = len(params)
+            params = append(params, p)
+        }
+    }
+    ctx := TypeName(name)
+    for _, p := range params {
+        n := fieldName(scalar(p, "name"))
+        if seen[n] { continue }
+        seen[n] = true
+        t := g.typeOf(yamlnode.GetKey(p, "schema"), ctx+TypeName(scalar(p, "name")), true, 1)
+        if scalar(p, "in") == "path" || isTrue(yamlnode.GetKey(p, "required")) { t += "!" }
+        args = append(args, arg{n, t})
+    }
+    if rb := g.deref(yamlnode.GetKey(op, "requestBody")); rb != nil {
+        if schema := mediaSchema(yamlnode.GetKey(rb, "content")); schema != nil {
+            t := g.typeOf(schema, ctx, true, 1)
+            if isTrue(yamlnode.GetKey(rb, "required")) { t += "!" }
+            n := "input"
+            for seen[n] { n = "_" + n }
+            args = append(args, arg{n, t})
+        }
+    }
+
+    result := "Boolean"
+    if responses := yamlnode.GetKey(op, "responses"); responses != nil && responses.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(responses.Content); i += 2 {
+            if !strings.HasPrefix(responses.Content[i].Value, "2") { continue }
+            if schema := mediaSchema(yamlnode.GetKey(g.deref(responses.Content[i+1]), "content")); schema != nil {
+                result = g.typeOf(schema, ctx+"Result", false, 1)
+            }
+            break
+        }
+    }
+
+    var b strings.Builder
+    desc := firstNonEmpty(scalar(op, "summary"), scalar(op, "description"))
+    b.WriteString(blockString(strings.TrimSpace(desc+"\n\n"+strings.ToUpper(method)+" "+path), "  "))
+    b.WriteString("  " + name)
+    if len(args) > 0 {
+        var parts []string
+        for _, a := range args { parts = append(parts, a.name+": "+a.typ) }
+        b.WriteString("(" + strings.Join(parts, ", ") + ")")
+    }
+    b.WriteString(": " + result)
+    if isTrue(yamlnode.GetKey(op, "deprecated")) { b.WriteString(" @deprecated") }
+    b.WriteString("\n")
+    return b.String()
+}
+
+// description renders the description of n as an SDL description.
+func description(n *yaml.Node, indent string) string {
+    return blockString(strings.TrimSpace(firstNonEmpty(scalar(n, "description"), scalar(n, "title"))), indent)
+}
+
+func blockString(s, indent string) string {
+    if s == "" { return "" }
+    s = strings.ReplaceAll(s, `"""`, `\"""`)
+    if !strings.Contains(s, "\n") { return indent + `"""` + s + `"""` + "\n" }
+    var b strings.Builder
+    b.WriteString(indent + `"""` + "\n")
+    for _, line := range strings.Split(s, "\n") { b.WriteString(strings.TrimRight(indent+line, " ") + "\n") }
+    b.WriteString(indent + `"""` + "\n")
+    return b.String()
+}
+
+// deref follows local refs such as #/components/responses/NotFound.
+func (g *generator) deref(n *yaml.Node) *yaml.Node { return yamlnode.Deref(g.root, n) }
+
+// componentRef is the component a schema refers to, if it is a ref to one.
+func componentRef(schema *yaml.Node) string {
+    ref := yamlnode.GetKey(schema, "$ref")
+    if ref == nil { return "" }
+    name, ok := strings.CutPrefix(ref.Value, "#/components/schemas/")
+    if !ok || strings.Contains(name, "/") { return "" }
+    return yamlnode.UnescapeToken(name)
+}
+
+func mediaSchema(content *yaml.Node) *yaml.Node {
+    if content == nil || content.Kind != yaml.MappingNode || len(content.Content) < 2 { return nil }
+    for i := 0; i+1 < len(content.Content); i += 2 {
+        if strings.Contains(content.Content[i].Value, "json") { return yamlnode.GetKey(content.Content[i+1], "schema") }
+    }
+    return yamlnode.GetKey(content.Content[1], "schema")
+}
+
+// isObject reports objects with properties, and allOf compositions.
+func isObject(schema *yaml.Node) bool {
+    if schema == nil || schema.Kind != yaml.MappingNode || yamlnode.GetKey(schema, "$ref") != nil { return false }
+    if yamlnode.GetKey(schema, "oneOf") != nil || yamlnode.GetKey(schema, "anyOf") != nil { return false }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode && (len(all.Content) > 1 || yamlnode.GetKey(schema, "properties") != nil) { return true }
+    props := yamlnode.GetKey(schema, "properties")
+    return props != nil && len(props.Content) > 0
+}
+
+func isEnum(schema *yaml.Node) bool {
+    enum := yamlnode.GetKey(schema, "enum")
+    if enum == nil || enum.Kind != yaml.SequenceNode || len(enum.Content) == 0 { return false }
+    for _, v := range enum.Content {
+        if v.Kind != yaml.ScalarNode || v.Tag != "!!str" && v.Tag != "!!null" { return false }
+    }
+    return true
+}
+
+func isNullable(schema *yaml.Node) bool {
+    if isTrue(yamlnode.GetKey(schema, "nullable")) { return true }
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        if t == "null" { return true }
+    }
+    return false
+}
+
+var (
+    reWord = regexp.MustCompile(`[A-Za-z0-9]+`)
+    reName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+)
+
+// TypeName turns a component name or property into a PascalCase type name.
+func TypeName(s string) string {
+    var b strings.Builder
+    for _, w := range reWord.FindAllString(s, -1) { b.WriteString(strings.ToUpper(w[:1]) + w[1:]) }
+    out := b.String()
+    if out == "" || out[0] >= '0' && out[0] <= '9' { out = "T" + out }
+    return out
+}
+
+// fieldName turns a property, parameter or operationId into a camelCase
+// field name.
+func fieldName(s string) string {
+    if reName.MatchString(s) && !strings.HasPrefix(s, "__") { return s }
+    t := TypeName(s)
+    return strings.ToLower(t[:1]) + t[1:]
+}
+
+var (
+    reNonName = regexp.MustCompile(`[^0-9A-Za-z]+`)
+    reHump    = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+)
+
+// EnumValue turns an enum value into an SDL enum value such as IN_PROGRESS.
+func EnumValue(v string) string {
+    s := strings.Trim(reNonName.ReplaceAllString(reHump.ReplaceAllString(v, "${1}_${2}"), "_"), "_")
+    s = strings.ToUpper(s)
+    if s != "" && s[0] >= '0' && s[0] <= '9' { s = "_" + s }
+    return s
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func isTrue(n *yaml.Node) bool { return n != nil && n.Kind == yaml.ScalarNode && n.Value == "true" }
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
@@ -0,0 +1,102 @@
+package graphql
+
+import (
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+const spec = `openapi: 3.0.3
+info: {title: Pets, version: 1.0.0}
+paths:
+  /pets/{pet-id}:
+    get:
+      operationId: get-pet
+      summary: Fetch a pet.
+      parameters:
+        - {name: pet-id, in: path, schema: {type: integer}}
+        - {name: X-Trace, in: header, schema: {type: string}}
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Animal'}
+    put:
+      operationId: updatePet
+      x-graphql: {name: savePet}
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Dog'}
+      responses:
+        '204': {description: saved}
+    delete:
+      x-graphql: {skip: true}
+      responses:
+        '204': {description: gone}
+components:
+  schemas:
+    Status:
+      type: string
+      enum: [in-progress, doneAlready]
+    Dog:
+      type: object
+      description: A dog.
+      required: [id, name]
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string}
+        status: {$ref: '#/components/schemas/Status'}
+        born: {type: string, format: date-time}
+        toys: {type: array, items: {type: string}}
+        extra: {type: object, additionalProperties: true}
+    Cat:
+      type: object
+      properties:
+        lives: {type: integer}
+    Animal:
+      oneOf:
+        - $ref: '#/components/schemas/Dog'
+        - $ref: '#/components/schemas/Cat'
+    Internal:
+      type: object
+      x-graphql: {skip: true}
+      properties:
+        secret: {type: string}
+`
+
+func TestNames(t *testing.T) {
+    for in, want := range map[string]string{"pet": "Pet", "pet_owner": "PetOwner", "2fa": "T2fa", "": "T"} {
+        if got := TypeName(in); got != want { t.Errorf("TypeName(%q) = %q, want %q", in, got, want) }
+    }
+    for in, want := range map[string]string{"inProgress": "IN_PROGRESS", "in-progress": "IN_PROGRESS", "2x": "_2X", "--": ""} {
+        if got := EnumValue(in); got != want { t.Errorf("EnumValue(%q) = %q, want %q", in, got, want) }
+    }
+}
+
+func TestGenerate(t *testing.T) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+    out, err := Generate(doc.Content[0])
+    if err != nil { t.Fatal(err) }
+    sdl := string(out)
+    for _, want := range []string{
+        "# Generated by oas-indexer from Pets 1.0.0 (experimental). Do not edit.\n",
+        "scalar DateTime\n",
+        "scalar JSON\n",
+        "enum Status {\n  IN_PROGRESS\n  DONE_ALREADY\n}\n",
+        "\"\"\"A dog.\"\"\"\ntype Dog {\n  id: Int!\n  name: String!\n  status: Status\n  born: DateTime\n  toys: [String!]\n  extra: JSON\n}\n",
+        "union Animal = Dog | Cat\n",
+        "input DogInput {\n  name: String!\n  status: Status\n  born: DateTime\n  toys: [String!]\n  extra: JSON\n}\n",
+        "  getPet(petId: Int!): Animal\n",
+        "  savePet(input: DogInput!): Boolean\n",
+    } {
+        if !strings.Contains(sdl, want) { t.Errorf("SDL lacks %q:\n%s", want, sdl) }
+    }
+    for _, unwanted := range []string{"Internal", "secret", "X-Trace", "delete"} {
+        if strings.Contains(sdl, unwanted) { t.Errorf("SDL contains %q:\n%s", unwanted, sdl) }
+    }
+}
@@ -0,0 +1,288 @@
+package indexer
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/dialect"
+)
+
+// AsyncAPI sibling document. Channel fragments under events/channels become
+// channels named after their path below it without extension
+// (events/channels/orders.created.yaml is orders.created), message fragments
+// under events/messages become components.messages named like components.
+// Event fragments refer to components/schemas fragments just as path
+// fragments do, so a payload and a REST response share one schema.
+const (
+    AsyncAPIVersion  = "2.6.0"
+    EventsHeaderFile = "asyncapi.yaml" // in EventsDir: id, info, servers, defaultContentType, tags of the document
+)
+
+// ChannelsDir returns the directory of the channel fragments.
+func (cfg *Config) ChannelsDir() string { return filepath.Join(cfg.EventsDir, "channels") }
+
+// MessagesDir returns the directory of the message fragments.
+func (cfg *Config) MessagesDir() string { return filepath.Join(cfg.EventsDir, "messages") }
+
+// HasEvents reports whether EventsDir holds any channel fragment.
+func HasEvents(cfg *Config) (bool, error) {
+    files, err := ListFragments(cfg, cfg.ChannelsDir())
+    if _, err := DiscoveryProblems(err); err != nil { return false, err }
+    return len(files) > 0, nil
+}
+
+// channelNames maps each channel fragment to its channel name, reporting
+// names taken by another file.
+func channelNames(cfg *Config, files []string) (map[string]string, []FragmentError) {
+    sorted := append([]string(nil), files...)
+    sort.Strings(sorted)
+    names := map[string]string{}
+    owner := map[string]string{}
+    problems := caseCollisions(cfg, sorted)
+    for _, f := range sorted {
+        rel, _ := filepath.Rel(cfg.ChannelsDir(), f)
+        name := trimFragmentExt(filepath.ToSlash(rel))
+        if prev, ok := owner[name]; ok {
+            if strings.EqualFold(prev, f) { continue } // reported as a case collision
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: fmt.Sprintf("channel %q is also defined by %s", name, DisplayPath(cfg, prev))})
+            continue
+        }
+        owner[name] = f
+        names[f] = name
+    }
+    return names, problems
+}
+
+// messageNames maps each message fragment to its components.messages name,
+// derived from the file name as for components.
+func messageNames(cfg *Config, files []string) (map[string]string, []FragmentError) {
+    sorted := append([]string(nil), files...)
+    sort.Strings(sorted)
+    names := map[string]string{}
+    owner := map[string]string{}
+    problems := caseCollisions(cfg, sorted)
+    for _, f := range sorted {
+        name := cfg.ComponentNameFor(f)
+        if name == "" {
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: "cannot derive a message name; rename it using ASCII letters or digits"})
+            continue
+        }
+        if prev, ok := owner[name]; ok {
+            if strings.EqualFold(prev, f) { continue } // reported as a case collision
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: fmt.Sprintf("message name %q is also derived from %s", name, DisplayPath(cfg, prev))})
+            continue
+        }
+        owner[name] = f
+        names[f] = name
+    }
+    return names, problems
+}
+
+// eventFragments lists the channel and message fragments.
+func eventFragments(cfg *Config) ([]string, []string, error) {
+    channels, err := ListFragments(cfg, cfg.ChannelsDir())
+    if _, err := DiscoveryProblems(err); err != nil { return nil, nil, err }
+    messages, err := ListFragments(cfg, cfg.MessagesDir())
+    if _, err := DiscoveryProblems(err); err != nil { return nil, nil, err }
+    sort.Strings(channels)
+    sort.Strings(messages)
+    return channels, messages, nil
+}
+
+// checkEvents reports unreadable event fragments, dead refs in them and
+// channel and message name collisions.
+func checkEvents(cfg *Config, idx componentIndex, names NameMaps) []FragmentError {
+    var problems []FragmentError
+    for _, dir := range []string{cfg.ChannelsDir(), cfg.MessagesDir()} {
+        files, err := ListFragments(cfg, dir)
+        discovered, _ := DiscoveryProblems(err)
+        for _, d := range discovered {
+            d.File = DisplayPath(cfg, d.File)
+            problems = append(problems, d)
+        }
+        for _, f := range files {
+            doc, errs := loadFragment(cfg, f)
+            if len(errs) > 0 {
+                problems = append(problems, errs...)
+                continue
+            }
+            if root := yamlnode.DocRoot(doc); root != nil && root.Kind != yaml.MappingNode {
+                problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Line: root.Line, Column: root.Column, Message: fmt.Sprintf("expected a mapping, found %s", yamlnode.KindName(root))})
+                continue
+            }
+            problems = append(problems, checkRefs(cfg, f, doc, idx, names)...)
+        }
+    }
+    channels, messages, err := eventFragments(cfg)
+    if err != nil { return problems }
+    if len(messages) > 0 && len(channels) == 0 {
+        problems = append(problems, FragmentError{File: DisplayPath(cfg, cfg.MessagesDir()), Message: "message fragments without channel fragments in " + DisplayPath(cfg, cfg.ChannelsDir()) + "; no AsyncAPI document is built"})
+    }
+    _, errs := channelNames(cfg, channels)
+    problems = append(problems, errs...)
+    _, errs = messageNames(cfg, messages)
+    return append(problems, errs...)
+}
+
+// asyncAPIHeader returns the document with asyncapi and info, taken from the
+// root's info, and the fields of EventsHeaderFile; its info fields override
+// the root's.
+func asyncAPIHeader(cfg *Config) (*yaml.Node, []FragmentError) {
+    root, problems := rootHeaderNode(cfg)
+    doc := yamlnode.Map()
+    yamlnode.SetKey(doc, "asyncapi", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: AsyncAPIVersion, Style: yaml.DoubleQuotedStyle})
+    info := yamlnode.GetKey(root, "info")
+    yamlnode.SetKey(doc, "info", info)
+    rel, _ := filepath.Rel(cfg.InputDir, filepath.Join(cfg.EventsDir, EventsHeaderFile))
+    header, name, errs := loadRootFile(cfg, rel)
+    problems = append(problems, errs...)
+    if header == nil { return doc, problems }
+    if header.Kind != yaml.MappingNode {
+        return doc, append(problems, FragmentError{File: name, Line: header.Line, Column: header.Column, Message: fmt.Sprintf("expected a mapping, found %s", yamlnode.KindName(header))})
+    }
+    for i := 0; i+1 < len(header.Content); i += 2 {
+        k, v := header.Content[i], header.Content[i+1]
+        switch k.Value {
+        case "asyncapi", "channels", "components":
+            problems = append(problems, FragmentError{File: name, Line: k.Line, Column: k.Column, Message: fmt.Sprintf("%q is built from the event fragments; leave it out", k.Value)})
+        case "info":
+            if v.Kind != yaml.MappingNode {
+                problems = append(problems, FragmentError{File: name, Line: v.Line, Column: v.Column, Message: fmt.Sprintf("info must be a mapping, found %s", yamlnode.KindName(v))})
+                continue
+            }
+            for j := 0; j+1 < len(v.Content); j += 2 {
+                yamlnode.SetKey(info, v.Content[j].Value, v.Content[j+1])
+            }
+        default:
+            yamlnode.SetKey(doc, k.Value, v)
+        }
+    }
+    return doc, problems
+}
+
+// BuildAsyncAPI writes the AsyncAPI document of the event fragments to
+// AsyncAPIPath: reference-style, or with Join inlined, holding the
+// component schemas the events reach. The returned bool is false when the
+// existing document was already up to date.
+func BuildAsyncAPI(cfg *Config) (bool, error) {
+    out, err := RenderAsyncAPI(cfg)
+    if err != nil { return false, err }
+    if err := os.MkdirAll(filepath.Dir(cfg.AsyncAPIPath), 0o755); err != nil { return false, err }
+    return atomicfile.WriteFile(cfg.AsyncAPIPath, out)
+}
+
+// RenderAsyncAPI returns the document BuildAsyncAPI writes, with refs
+// relative to AsyncAPIPath, without writing anything.
+func RenderAsyncAPI(cfg *Config) ([]byte, error) {
+    channels, messages, err := eventFragments(cfg)
+    if err != nil { return nil, err }
+    if len(channels) == 0 { return nil, fmt.Errorf("%s: no channel fragments", DisplayPath(cfg, cfg.ChannelsDir())) }
+    chNames, problems := channelNames(cfg, channels)
+    msgNames, errs := messageNames(cfg, messages)
+    if problems = append(problems, errs...); len(problems) > 0 { return nil, nameError("invalid event fragment names", problems) }
+    doc, errs := asyncAPIHeader(cfg)
+    if len(errs) > 0 { return nil, FragmentErrors(errs) }
+
+    docDir := filepath.Dir(cfg.AsyncAPIPath)
+    names := BuildNameMaps(cfg)
+    entry := func(f string) (*yaml.Node, error) { return refNode(relFrom(docDir, f)), nil }
+    if cfg.Join {
+        entry = func(f string) (*yaml.Node, error) {
+            body, err := joinFragment(cfg, f, names)
+            if err != nil { return nil, err }
+            for _, v := range collectRefs(body) {
+                target := refFile(f, v.Value)
+                name, ok := msgNames[target]
+                if !ok { continue }
+                v.Value = "#/components/messages/" + pointerToken(name) + refPointer(v.Value)
+                v.Tag, v.Style = "!!str", yaml.SingleQuotedStyle
+            }
+            return body, nil
+        }
+    }
+
+    channelsNode := yamlnode.Map()
+    for _, f := range channels {
+        v, err := entry(f)
+        if err != nil { return nil, err }
+        yamlnode.SetKey(channelsNode, chNames[f], v)
+    }
+    yamlnode.SetKey(doc, "channels", channelsNode)
+    components := yamlnode.Map()
+    if len(messages) > 0 {
+        messagesNode := yamlnode.Map()
+        for _, f := range messages {
+            v, err := entry(f)
+            if err != nil { return nil, err }
+            yamlnode.SetKey(messagesNode, msgNames[f], v)
+        }
+        yamlnode.SetKey(components, "messages", messagesNode)
+    }
+    if cfg.Join {
+        schemas, err := eventSchemas(cfg, doc, components, names)
+        if err != nil { return nil, err }
+        if len(schemas.Content) > 0 {
+            components.Content = append([]*yaml.Node{yamlnode.Str("schemas"), schemas}, components.Content...)
+        }
+    }
+    if len(components.Content) > 0 { yamlnode.SetKey(doc, "components", components) }
+    if cfg.Join {
+        // AsyncAPI payloads are JSON Schema: 3.0's nullable means nothing there.
+        dialect.Upgrade(doc)
+        if cfg.Vars != nil {
+            if err := cfg.Vars.Expand(doc); err != nil { return nil, err }
+        }
+    }
+
+    if FormatForFile(cfg.AsyncAPIPath) == FormatJSON { return yamlnode.MarshalJSON(doc) }
+    return yamlnode.Marshal(doc)
+}
+
+// eventSchemas returns the component schemas the joined nodes reach, by
+// name. Refs to other components fail: OpenAPI parameters, responses and
+// the like have no AsyncAPI counterpart.
+func eventSchemas(cfg *Config, doc, components *yaml.Node, names NameMaps) (*yaml.Node, error) {
+    idx := buildComponentIndex(cfg)
+    parsed := map[string]*yaml.Node{}
+    reached := map[string]*yaml.Node{}
+    queue := []*yaml.Node{doc, components}
+    for len(queue) > 0 {
+        node := queue[0]
+        queue = queue[1:]
+        for _, v := range collectRefs(node) {
+            rest, ok := strings.CutPrefix(v.Value, "#/components/")
+            if !ok { continue }
+            parts := strings.SplitN(rest, "/", 3)
+            if len(parts) < 2 || parts[0] == "messages" { continue }
+            name := strings.NewReplacer("~1", "/", "~0", "~").Replace(parts[1])
+            if parts[0] != "schemas" {
+                return nil, fmt.Errorf("$ref %s: event fragments can only refer to schemas, not %s", v.Value, parts[0])
+            }
+            if _, done := reached[name]; done { continue }
+            e, ok := idx["schemas"][name]
+            if !ok { continue } // dead refs are reported by CheckFragments
+            body, ok := parsed[e.File]
+            if !ok {
+                var err error
+                if body, err = joinFragment(cfg, e.File, names); err != nil { return nil, err }
+                parsed[e.File] = body
+            }
+            if e.Key != "" { body = yamlnode.GetKey(body, e.Key) }
+            reached[name] = body
+            queue = append(queue, body)
+        }
+    }
+    sorted := make([]string, 0, len(reached))
+    for name := range reached { sorted = append(sorted, name) }
+    sort.Strings(sorted)
+    schemas := yamlnode.Map()
+    for _, name := range sorted { yamlnode.SetKey(schemas, name, reached[name]) }
+    return schemas, nil
+}
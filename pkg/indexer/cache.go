@@ -0,0 +1,124 @@
+package indexer
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "runtime"
+    "sync"
+    "time"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Fragments are read and parsed once: ReadFragment, loadFragment and
+// fragmentDoc share the entries of fragmentCache, which stay valid while a
+// file keeps its size and modification time, so that watch and serve
+// rebuilds still see edits. preloadFragments fills the cache for a whole
+// tree with a bounded pool of workers. Parsed trees are handed out as
+// clones, since joining and tagging rewrite them in place.
+
+type fragmentKey struct {
+    path string
+    cwd  string // problems name files relative to it
+    tabs int    // ExpandTabs
+}
+
+type cachedFragment struct {
+    size    int64
+    modTime time.Time
+    text    string // normalized content
+    err     error  // reading the file
+
+    parseOnce sync.Once
+    doc       *yaml.Node
+    parseErr  error
+
+    checkOnce sync.Once
+    problems  []FragmentError // loadFragment's
+}
+
+var fragmentCache = struct {
+    sync.Mutex
+    entries map[fragmentKey]*cachedFragment
+}{entries: map[fragmentKey]*cachedFragment{}}
+
+// fragmentEntry returns the cache entry of the fragment path, reading the
+// file when it is new or has changed since.
+func fragmentEntry(cfg *Config, path string) *cachedFragment {
+    st, err := os.Stat(path)
+    if err != nil { return &cachedFragment{err: err} }
+    key := fragmentKey{path: path, cwd: cfg.Cwd, tabs: cfg.ExpandTabs}
+    fragmentCache.Lock()
+    e := fragmentCache.entries[key]
+    fragmentCache.Unlock()
+    if e != nil && e.size == st.Size() && e.modTime.Equal(st.ModTime()) { return e }
+
+    e = &cachedFragment{size: st.Size(), modTime: st.ModTime()}
+    b, err := os.ReadFile(path)
+    if err != nil { return &cachedFragment{err: err} }
+    tabs := cfg.ExpandTabs
+    if tabs <= 0 && isJSONFragment(path) { tabs = 2 }
+    e.text = normalizeFragment(string(b), tabs)
+    fragmentCache.Lock()
+    fragmentCache.entries[key] = e
+    fragmentCache.Unlock()
+    return e
+}
+
+// parsed returns the document of e, parsing it on first use. The result is
+// shared and must not be modified.
+func (e *cachedFragment) parsed() (*yaml.Node, error) {
+    e.parseOnce.Do(func() {
+        var doc yaml.Node
+        if e.parseErr = yaml.Unmarshal([]byte(e.text), &doc); e.parseErr == nil { e.doc = &doc }
+    })
+    return e.doc, e.parseErr
+}
+
+// sharedFragmentDoc parses the fragment path through the cache. The result
+// is shared and must not be modified; fragmentDoc returns a copy.
+func sharedFragmentDoc(cfg *Config, path string) (*yaml.Node, error) {
+    e := fragmentEntry(cfg, path)
+    if e.err != nil { return nil, e.err }
+    doc, err := e.parsed()
+    if err != nil { return nil, fmt.Errorf("%s: %v", path, err) }
+    return doc, nil
+}
+
+// fragmentDoc parses the fragment path through the cache into a tree the
+// caller owns.
+func fragmentDoc(cfg *Config, path string) (*yaml.Node, error) {
+    doc, err := sharedFragmentDoc(cfg, path)
+    if err != nil { return nil, err }
+    return yamlnode.Clone(doc), nil
+}
+
+// preloadFragments reads, parses and checks every fragment and header file
+// of cfg concurrently, so that the phases walking the tree one file at a
+// time find them in the cache. Problems are left for CheckFragments.
+func preloadFragments(cfg *Config) {
+    var files []string
+    for _, dir := range cfg.FragmentDirs() {
+        found, _ := ListFragments(cfg, dir)
+        files = append(files, found...)
+    }
+    for _, h := range HeaderFiles {
+        path := filepath.Join(cfg.InputDir, h)
+        if _, err := os.Stat(path); err == nil { files = append(files, path) }
+    }
+    work := make(chan string)
+    var wg sync.WaitGroup
+    for i := 0; i < min(runtime.GOMAXPROCS(0), len(files)); i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for f := range work { checkedFragment(cfg, f) }
+        }()
+    }
+    for _, f := range files { work <- f }
+    close(work)
+    wg.Wait()
+}
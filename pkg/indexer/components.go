@@ -0,0 +1,118 @@
+package indexer
+
+import (
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// Component describes one components/ subdirectory and the section of the
+// root's components object its fragments are emitted under.
+type Component struct {
+    Section string // key under components, e.g. "requestBodies"
+    Dir     string // directory name below <input>/components
+    kind    fragmentKind
+    always  bool // emit the section even when it has no fragments
+}
+
+// Components lists the indexed component directories in root order.
+var Components = []Component{
+    {Section: "schemas", Dir: "schemas", kind: kindSchema, always: true},
+    {Section: "parameters", Dir: "parameters", kind: kindParameter, always: true},
+    {Section: "responses", Dir: "responses", kind: kindResponse},
+    {Section: "requestBodies", Dir: "requestBodies", kind: kindRequestBody},
+    {Section: "headers", Dir: "headers", kind: kindHeader},
+    {Section: "examples", Dir: "examples", kind: kindExample},
+    {Section: "securitySchemes", Dir: "security-schemes", kind: kindSecurityScheme},
+}
+
+// ComponentDir returns the fragment directory of c.
+func (cfg *Config) ComponentDir(c Component) string {
+    return filepath.Join(cfg.ComponentsDir, c.Dir)
+}
+
+// componentDirs returns the fragment directories of c: its directory below
+// ComponentsDir, then its directory below SharedComponentsDir when set.
+func (cfg *Config) componentDirs(c Component) []string {
+    dirs := []string{cfg.ComponentDir(c)}
+    if cfg.SharedComponentsDir != "" { dirs = append(dirs, filepath.Join(cfg.SharedComponentsDir, c.Dir)) }
+    return dirs
+}
+
+// IsShared reports whether the component fragment f comes from SharedComponentsDir.
+func (cfg *Config) IsShared(f string) bool {
+    return cfg.SharedComponentsDir != "" && IsWithin(cfg.SharedComponentsDir, f)
+}
+
+// ComponentFiles lists the fragments of c in the input tree and then those
+// in SharedComponentsDir, each sorted (see sortComponentFiles).
+func ComponentFiles(cfg *Config, c Component) ([]string, error) {
+    var files []string
+    var problems FragmentErrors
+    for _, dir := range cfg.componentDirs(c) {
+        found, err := ListFragments(cfg, dir)
+        files = append(files, found...)
+        discovered, err := DiscoveryProblems(err)
+        if err != nil { return files, err }
+        problems = append(problems, discovered...)
+    }
+    sortComponentFiles(cfg, files)
+    if len(problems) > 0 { return files, problems }
+    return files, nil
+}
+
+// sortComponentFiles sorts component fragments by path, those of the input
+// tree before shared ones, so a local component shadows a shared one of the
+// same name.
+func sortComponentFiles(cfg *Config, files []string) {
+    sort.SliceStable(files, func(i, j int) bool {
+        if si, sj := cfg.IsShared(files[i]), cfg.IsShared(files[j]); si != sj { return sj }
+        return files[i] < files[j]
+    })
+}
+
+// fragmentGroup is a fragment directory and the object kind its files hold.
+type fragmentGroup struct {
+    dir  string
+    kind fragmentKind
+}
+
+func (cfg *Config) fragmentGroups() []fragmentGroup {
+    groups := []fragmentGroup{{cfg.PathsDir, kindPathItem}, {cfg.WebhooksDir, kindPathItem}, {cfg.TraitsDir, kindOperation}}
+    for _, c := range Components {
+        for _, dir := range cfg.componentDirs(c) {
+            groups = append(groups, fragmentGroup{dir, c.kind})
+        }
+    }
+    return groups
+}
+
+// FragmentDirs returns the paths, webhooks and traits directories followed
+// by every component directory, shared ones included, and the event
+// directories.
+func (cfg *Config) FragmentDirs() []string {
+    var dirs []string
+    for _, g := range cfg.fragmentGroups() {
+        dirs = append(dirs, g.dir)
+    }
+    return append(dirs, cfg.ChannelsDir(), cfg.MessagesDir())
+}
+
+// reComponentPath matches file refs into any component directory, capturing
+// the directory and the file base name.
+var reComponentPath = func() *regexp.Regexp {
+    var dirs []string
+    for _, c := range Components {
+        dirs = append(dirs, regexp.QuoteMeta(c.Dir))
+    }
+    return regexp.MustCompile(`(?i)(?:^|.*/)(?:components/)?(` + strings.Join(dirs, "|") + `)/([^/#\s]+)\.(?:ya?ml|json)$`)
+}()
+
+// componentForDir returns the component whose directory is dir (case-insensitively).
+func componentForDir(dir string) (Component, bool) {
+    for _, c := range Components {
+        if strings.EqualFold(c.Dir, dir) { return c, true }
+    }
+    return Component{}, false
+}
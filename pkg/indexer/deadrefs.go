@@ -0,0 +1,121 @@
+package indexer
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// componentIndex maps each components section to its component names and
+// the fragment file (and definition) each name comes from.
+type componentIndex map[string]map[string]componentEntry
+
+func buildComponentIndex(cfg *Config) componentIndex {
+    idx := componentIndex{}
+    for _, c := range Components {
+        idx[c.Section] = map[string]componentEntry{}
+        files, _ := ComponentFiles(cfg, c)
+        entries, _ := componentNames(cfg, c, files)
+        for _, e := range entries {
+            idx[c.Section][e.Name] = e
+        }
+    }
+    return idx
+}
+
+// checkRefs reports every $ref in the fragment file (parsed as doc) that does
+// not resolve: a file ref to a missing file, or a #/components/... or
+// schema:/param: ref to a component no fragment defines, or an http(s) ref
+// that cannot be vendored (when cfg.Vendor is set). Other local refs are not
+// checked.
+func checkRefs(cfg *Config, file string, doc *yaml.Node, idx componentIndex, names NameMaps) []FragmentError {
+    var errs []FragmentError
+    name := DisplayPath(cfg, file)
+    var walk func(n *yaml.Node)
+    walk = func(n *yaml.Node) {
+        if n.Kind == yaml.MappingNode {
+            for i := 0; i+1 < len(n.Content); i += 2 {
+                k, v := n.Content[i], n.Content[i+1]
+                if k.Value != "$ref" || v.Kind != yaml.ScalarNode { continue }
+                if msg := danglingRef(cfg, file, v.Value, idx, names); msg != "" {
+                    errs = append(errs, FragmentError{File: name, Line: v.Line, Column: v.Column, Message: msg})
+                }
+            }
+        }
+        for _, c := range n.Content {
+            walk(c)
+        }
+    }
+    walk(doc)
+    return errs
+}
+
+// danglingRef explains why ref, found in file, does not resolve, or returns "".
+func danglingRef(cfg *Config, file, ref string, idx componentIndex, names NameMaps) string {
+    low := strings.ToLower(ref)
+    switch {
+    case strings.HasPrefix(low, "http://"), strings.HasPrefix(low, "https://"):
+        if cfg.Vendor == nil { return "" }
+        if _, err := cfg.Vendor.Fetch(ref); err != nil { return fmt.Sprintf("$ref %q: %v", ref, err) }
+        return ""
+    case strings.HasPrefix(low, "schema:"):
+        return missingComponent(idx, "schemas", names.lookup("schemas", strings.TrimSpace(ref[len("schema:"):])), ref)
+    case strings.HasPrefix(low, "param:"):
+        return missingComponent(idx, "parameters", names.lookup("parameters", strings.TrimSpace(ref[len("param:"):])), ref)
+    case strings.HasPrefix(ref, "#/components/"):
+        parts := strings.SplitN(strings.TrimPrefix(ref, "#/components/"), "/", 3)
+        if len(parts) < 2 { return fmt.Sprintf("$ref %q does not name a component", ref) }
+        if _, ok := idx[parts[0]]; !ok { return "" } // section not indexed from fragments
+        return missingComponent(idx, parts[0], strings.NewReplacer("~1", "/", "~0", "~").Replace(parts[1]), ref)
+    case strings.HasPrefix(ref, "#"):
+        return ""
+    }
+
+    target := refFile(file, ref)
+    if _, err := os.Stat(target); err == nil { return danglingDefinition(cfg, target, ref) }
+    msg := fmt.Sprintf("$ref %q: file not found", ref)
+    if m := reComponentPath.FindStringSubmatch(filepath.ToSlash(target)); len(m) == 3 {
+        c, _ := componentForDir(m[1])
+        if e, ok := idx[c.Section][names.lookup(c.Section, m[2])]; ok {
+            if rel, err := filepath.Rel(filepath.Dir(file), e.File); err == nil {
+                msg += fmt.Sprintf(" (did you mean %s?)", filepath.ToSlash(rel))
+            }
+        }
+    }
+    return msg
+}
+
+// danglingDefinition explains why ref does not resolve into the definitions
+// file target: it names the whole file, or a key the file does not define.
+func danglingDefinition(cfg *Config, target, ref string) string {
+    c, ok := componentOf(cfg, target)
+    if !ok { return "" }
+    keys := DefinitionKeys(cfg, c, target)
+    if keys == nil { return "" }
+    _, pointer := splitRefPointer(ref)
+    key, _ := splitPointer(pointer)
+    if key == "" {
+        return fmt.Sprintf("$ref %q: %s holds several definitions (%s); point at one, e.g. %s#/%s",
+            ref, DisplayPath(cfg, target), strings.Join(keys, ", "), filepath.ToSlash(strings.SplitN(ref, "#", 2)[0]), pointerToken(keys[0]))
+    }
+    for _, k := range keys {
+        if k == key { return "" }
+    }
+    return fmt.Sprintf("$ref %q: no definition %q in %s (defined: %s)", ref, key, DisplayPath(cfg, target), strings.Join(keys, ", "))
+}
+
+func missingComponent(idx componentIndex, section, name, ref string) string {
+    if _, ok := idx[section][name]; ok { return "" }
+    var known []string
+    for n := range idx[section] {
+        known = append(known, n)
+    }
+    sort.Strings(known)
+    hint := "none defined"
+    if len(known) > 0 { hint = "defined: " + strings.Join(known, ", ") }
+    return fmt.Sprintf("$ref %q: no %s component %q (%s)", ref, section, name, hint)
+}
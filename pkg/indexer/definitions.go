@@ -0,0 +1,126 @@
+package indexer
+
+import (
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Definitions files: a component fragment may hold several named objects of
+// its directory's kind, e.g. components/schemas/common.yaml with Money and
+// Currency at the top level. Each becomes a component of its own, named after
+// its key, and is referenced as common.yaml#/Money.
+
+// componentEntry is one component emitted from a fragment file: the whole
+// file, or the definition under Key in a definitions file.
+type componentEntry struct {
+    File string
+    Key  string // top-level key in a definitions file, "" for a whole file
+    Name string // component name
+}
+
+// display names the entry in messages: the file, plus #/Key for a definition.
+func (e componentEntry) display(cfg *Config) string {
+    if e.Key == "" { return DisplayPath(cfg, e.File) }
+    return DisplayPath(cfg, e.File) + "#/" + pointerToken(e.Key)
+}
+
+var kindKeys = map[fragmentKind]map[string]bool{
+    kindPathItem: pathItemKeys, kindOperation: operationKeys, kindSchema: schemaKeys, kindParameter: parameterKeys,
+    kindResponse: responseKeys, kindRequestBody: requestBodyKeys, kindHeader: headerKeys, kindExample: exampleKeys,
+    kindSecurityScheme: securitySchemeKeys,
+}
+
+// definitionKeys returns the top-level keys of root when it is a definitions
+// file of kind: a non-empty mapping of mappings none of whose keys is a field
+// (or extension) of the kind's object. It returns nil for a single object.
+func definitionKeys(kind fragmentKind, root *yaml.Node) []string {
+    if root == nil || root.Kind != yaml.MappingNode || len(root.Content) == 0 { return nil }
+    var keys []string
+    for i := 0; i+1 < len(root.Content); i += 2 {
+        k, v := root.Content[i], root.Content[i+1]
+        if kindKeys[kind][k.Value] || kind == kindSchema && schemaKeys31[k.Value] || strings.HasPrefix(k.Value, "x-") {
+            return nil
+        }
+        if v.Kind != yaml.MappingNode { return nil }
+        keys = append(keys, k.Value)
+    }
+    return keys
+}
+
+// fileDefinitionKeys is definitionKeys for the fragment file f; unreadable
+// and unparsable files count as single objects, CheckFragments reports them.
+func fileDefinitionKeys(cfg *Config, kind fragmentKind, f string) []string {
+    doc, err := sharedFragmentDoc(cfg, f)
+    if err != nil { return nil }
+    return definitionKeys(kind, yamlnode.DocRoot(doc))
+}
+
+// DefinitionKeys returns the names defined by the component fragment f of c
+// when it is a definitions file, sorted, or nil when f is a single component.
+func DefinitionKeys(cfg *Config, c Component, f string) []string {
+    keys := fileDefinitionKeys(cfg, c.kind, f)
+    sort.Strings(keys)
+    return keys
+}
+
+// componentEntries expands the fragment files of c into their components,
+// in file order and, within a definitions file, in key order.
+func componentEntries(cfg *Config, c Component, files []string) []componentEntry {
+    var entries []componentEntry
+    for _, f := range files {
+        keys := DefinitionKeys(cfg, c, f)
+        if keys == nil {
+            entries = append(entries, componentEntry{File: f, Name: cfg.ComponentNameFor(f)})
+            continue
+        }
+        for _, k := range keys {
+            entries = append(entries, componentEntry{File: f, Key: k, Name: k})
+        }
+    }
+    return entries
+}
+
+// componentOf returns the component whose directory (or shared directory) holds file.
+func componentOf(cfg *Config, file string) (Component, bool) {
+    for _, c := range Components {
+        for _, dir := range cfg.componentDirs(c) {
+            if IsWithin(dir, file) { return c, true }
+        }
+    }
+    return Component{}, false
+}
+
+// rewriteDefinitionRefs points the local refs of a definitions file between
+// its own definitions ("#/Currency") at the components they become.
+func rewriteDefinitionRefs(n *yaml.Node, section string, keys []string) {
+    own := map[string]bool{}
+    for _, k := range keys {
+        own[k] = true
+    }
+    for _, v := range collectRefs(n) {
+        if !strings.HasPrefix(v.Value, "#/") || strings.HasPrefix(v.Value, "#/components/") { continue }
+        if seg, _ := splitPointer(v.Value[1:]); own[seg] {
+            v.Value = "#/components/" + section + v.Value[1:]
+            v.Tag = "!!str"
+            v.Style = yaml.SingleQuotedStyle
+        }
+    }
+}
+
+// splitPointer splits a JSON pointer "/Money/properties/amount" into its
+// unescaped first token "Money" and the rest "/properties/amount".
+func splitPointer(pointer string) (string, string) {
+    if !strings.HasPrefix(pointer, "/") { return "", pointer }
+    seg, rest, found := strings.Cut(pointer[1:], "/")
+    if found { rest = "/" + rest }
+    return strings.NewReplacer("~1", "/", "~0", "~").Replace(seg), rest
+}
+
+// pointerToken escapes a key for use as a JSON pointer token.
+func pointerToken(key string) string {
+    return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,224 @@
+package indexer
+
+import (
+    "errors"
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/dialect"
+)
+
+// FragmentError describes a problem located in a single fragment file.
+type FragmentError struct {
+    File    string
+    Line    int
+    Column  int
+    Message string
+}
+
+func (e FragmentError) Error() string {
+    switch {
+    case e.Line > 0 && e.Column > 0:
+        return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+    case e.Line > 0:
+        return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+    default:
+        return fmt.Sprintf("%s: %s", e.File, e.Message)
+    }
+}
+
+var reYAMLErrLine = regexp.MustCompile(`^(?:yaml: )?line (\d+)(?:, column (\d+))?: (.*)$`)
+
+// FragmentErrors is a list of per-file problems reported together.
+type FragmentErrors []FragmentError
+
+func (l FragmentErrors) Error() string {
+    return fmt.Sprintf("%d problem(s) in input tree", len(l))
+}
+
+// DiscoveryProblems separates non-fatal per-file discovery problems (returned
+// by ListFragments as FragmentErrors) from fatal errors.
+func DiscoveryProblems(err error) (FragmentErrors, error) {
+    var fe FragmentErrors
+    if errors.As(err, &fe) { return fe, nil }
+    return nil, err
+}
+
+// parseFragment checks a parsed fragment, returning located errors for
+// syntax problems, duplicate keys and other yaml.v3 type errors.
+func parseFragment(file string, doc *yaml.Node, err error) []FragmentError {
+    if err != nil { return yamlErrorsFor(file, err) }
+    var errs []FragmentError
+    walkDuplicateKeys(file, doc, &errs)
+    var v interface{}
+    if err := doc.Decode(&v); err != nil {
+        for _, fe := range yamlErrorsFor(file, err) {
+            if strings.Contains(fe.Message, "already defined") { continue } // reported with a column above
+            errs = append(errs, fe)
+        }
+    }
+    return errs
+}
+
+func yamlErrorsFor(file string, err error) []FragmentError {
+    var msgs []string
+    var te *yaml.TypeError
+    if errors.As(err, &te) {
+        msgs = te.Errors
+    } else {
+        msgs = []string{err.Error()}
+    }
+    out := make([]FragmentError, 0, len(msgs))
+    for _, m := range msgs {
+        fe := FragmentError{File: file, Message: strings.TrimPrefix(m, "yaml: ")}
+        if sm := reYAMLErrLine.FindStringSubmatch(strings.TrimSpace(m)); sm != nil {
+            fe.Line, _ = strconv.Atoi(sm[1])
+            if sm[2] != "" { fe.Column, _ = strconv.Atoi(sm[2]) }
+            fe.Message = sm[3]
+        }
+        out = append(out, fe)
+    }
+    return out
+}
+
+func walkDuplicateKeys(file string, n *yaml.Node, errs *[]FragmentError) {
+    if n == nil { return }
+    if n.Kind == yaml.MappingNode {
+        seen := map[string]*yaml.Node{}
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            k := n.Content[i]
+            if prev, ok := seen[k.Value]; ok && k.Value != "<<" {
+                *errs = append(*errs, FragmentError{
+                    File: file, Line: k.Line, Column: k.Column,
+                    Message: fmt.Sprintf("duplicate key %q (first defined at line %d)", k.Value, prev.Line),
+                })
+            } else {
+                seen[k.Value] = k
+            }
+        }
+    }
+    for _, c := range n.Content {
+        walkDuplicateKeys(file, c, errs)
+    }
+}
+
+// CheckFragments reads and parses every discovered fragment once, in
+// parallel (see preloadFragments), and returns all problems found:
+// unreadable files and directories, encoding and tab issues, YAML syntax
+// errors, component kind mismatches, schema keywords that cannot be
+// converted to the target OpenAPI version and, with Strict, structure
+// and layout violations. Nothing is fatal per file, so a large tree can be fixed in
+// one pass.
+func CheckFragments(cfg *Config) (FragmentErrors, error) {
+    preloadFragments(cfg)
+    var problems FragmentErrors
+    idx := buildComponentIndex(cfg)
+    names := BuildNameMaps(cfg)
+    for _, group := range cfg.fragmentGroups() {
+        files, err := ListFragments(cfg, group.dir)
+        discovered, err := DiscoveryProblems(err)
+        if err != nil { return nil, err }
+        for _, d := range discovered {
+            d.File = DisplayPath(cfg, d.File)
+            problems = append(problems, d)
+        }
+        for _, f := range files {
+            name := DisplayPath(cfg, f)
+            doc, errs := loadFragment(cfg, f)
+            if len(errs) > 0 {
+                problems = append(problems, errs...)
+                continue
+            }
+            var defs []string // definitions of a definitions file, checked one by one
+            if group.kind != kindPathItem { defs = definitionKeys(group.kind, yamlnode.DocRoot(doc)) }
+            if group.kind != kindPathItem && defs == nil {
+                if msg := componentKindError(group.kind, yamlnode.DocRoot(doc)); msg != "" {
+                    problems = append(problems, FragmentError{File: name, Line: 1, Message: msg})
+                    continue
+                }
+            }
+            var goodDefs []string
+            for _, key := range defs {
+                def := yamlnode.GetKey(yamlnode.DocRoot(doc), key)
+                if msg := componentKindError(group.kind, def); msg != "" {
+                    problems = append(problems, FragmentError{File: name, Line: def.Line, Column: def.Column, Message: fmt.Sprintf("definition %q: %s", key, msg)})
+                    continue
+                }
+                goodDefs = append(goodDefs, key)
+            }
+            problems = append(problems, checkRefs(cfg, f, doc, idx, names)...)
+            var dialectProblems []dialect.Problem
+            if cfg.OpenAPIVersion == OpenAPI31 { dialectProblems = dialect.Check31(doc) }
+            if cfg.Downconvert { dialectProblems = dialect.Check30(doc) }
+            for _, p := range dialectProblems {
+                problems = append(problems, FragmentError{File: name, Line: p.Line, Column: p.Column, Message: p.Message})
+            }
+            if cfg.Strict {
+                kind := group.kind
+                if _, method := PathKey(cfg, f); kind == kindPathItem && group.dir == cfg.PathsDir && method != "" { kind = kindOperation }
+                allow31 := cfg.OpenAPIVersion == OpenAPI31 || cfg.Downconvert
+                if defs == nil {
+                    problems = append(problems, checkFragmentShape(name, kind, doc, allow31)...)
+                }
+                for _, key := range goodDefs {
+                    def := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{yamlnode.GetKey(yamlnode.DocRoot(doc), key)}}
+                    for _, e := range checkFragmentShape(name, kind, def, allow31) {
+                        e.Message = fmt.Sprintf("definition %q: %s", key, e.Message)
+                        problems = append(problems, e)
+                    }
+                }
+            }
+        }
+    }
+    problems = append(problems, checkEvents(cfg, idx, names)...)
+    problems = append(problems, checkNames(cfg)...)
+    problems = append(problems, checkHeader(cfg)...)
+    if cfg.Strict { problems = append(problems, checkLayout(cfg)...) }
+    return problems, nil
+}
+
+// loadFragment reads and parses a fragment, returning located problems for
+// unreadable files, tab indentation and YAML errors.
+func loadFragment(cfg *Config, f string) (*yaml.Node, []FragmentError) {
+    e := checkedFragment(cfg, f)
+    if len(e.problems) > 0 { return nil, e.problems }
+    return yamlnode.Clone(e.doc), nil
+}
+
+// checkedFragment returns the cache entry of f with its problems found.
+func checkedFragment(cfg *Config, f string) *cachedFragment {
+    e := fragmentEntry(cfg, f)
+    e.checkOnce.Do(func() {
+        name := DisplayPath(cfg, f)
+        if e.err != nil {
+            e.problems = []FragmentError{{File: name, Message: e.err.Error()}}
+            return
+        }
+        if lines := tabIndentedLines(e.text); len(lines) > 0 {
+            for _, ln := range lines {
+                e.problems = append(e.problems, FragmentError{
+                    File: name, Line: ln,
+                    Message: "tab character used for indentation; YAML requires spaces (or run with --expand-tabs 2)",
+                })
+            }
+            return
+        }
+        doc, err := e.parsed()
+        e.problems = parseFragment(name, doc, err)
+    })
+    return e
+}
+
+// DisplayPath shortens a path relative to the working directory for messages.
+func DisplayPath(cfg *Config, p string) string {
+    if rel, err := filepath.Rel(cfg.Cwd, p); err == nil && !strings.HasPrefix(rel, "..") {
+        return filepath.ToSlash(rel)
+    }
+    return p
+}
@@ -0,0 +1,128 @@
+package indexer
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Optional files at the input root that fill in the root's header.
+const (
+    InfoFile    = "info.yaml"
+    ServersFile = "servers.yaml"
+    TagsFile    = "tags.yaml"
+)
+
+// OpenAPI versions for --openapi-version
+const (
+    OpenAPI30 = "3.0"
+    OpenAPI31 = "3.1"
+)
+
+// HeaderFiles lists every optional file read from the input root.
+var HeaderFiles = []string{InfoFile, ServersFile, TagsFile, SecurityFile, CommonParametersFile, filepath.Join("events", EventsHeaderFile)}
+
+// loadRootFile parses one of the header files at the input root, returning
+// nil when it does not exist.
+func loadRootFile(cfg *Config, file string) (*yaml.Node, string, []FragmentError) {
+    path := filepath.Join(cfg.InputDir, file)
+    name := DisplayPath(cfg, path)
+    if _, err := os.Stat(path); os.IsNotExist(err) { return nil, name, nil }
+    doc, errs := loadFragment(cfg, path)
+    if len(errs) > 0 { return nil, name, errs }
+    root := yamlnode.DocRoot(doc)
+    if root == nil { return nil, name, nil }
+    return root, name, nil
+}
+
+// loadRootList returns the sequence held by file, which is either the
+// sequence itself or a mapping with key.
+func loadRootList(cfg *Config, file, key string) (*yaml.Node, string, []FragmentError) {
+    root, name, errs := loadRootFile(cfg, file)
+    if root == nil || len(errs) > 0 { return nil, name, errs }
+    list := root
+    if root.Kind == yaml.MappingNode { list = yamlnode.GetKey(root, key) }
+    if list == nil || list.Kind != yaml.SequenceNode {
+        return nil, name, []FragmentError{{File: name, Line: root.Line, Column: root.Column,
+            Message: fmt.Sprintf("expected a sequence (or a mapping with a %q key), found %s", key, yamlnode.KindName(root))}}
+    }
+    return list, name, nil
+}
+
+// loadInfo returns the Info object from InfoFile, or nil when absent.
+func loadInfo(cfg *Config) (*yaml.Node, []FragmentError) {
+    info, name, errs := loadRootFile(cfg, InfoFile)
+    if info == nil || len(errs) > 0 { return nil, errs }
+    if info.Kind == yaml.MappingNode {
+        if inner := yamlnode.GetKey(info, "info"); inner != nil { info = inner }
+    }
+    if info.Kind != yaml.MappingNode {
+        return nil, []FragmentError{{File: name, Line: info.Line, Column: info.Column,
+            Message: fmt.Sprintf("info must be a mapping, found %s", yamlnode.KindName(info))}}
+    }
+    for _, key := range []string{"title", "version"} {
+        if v := yamlnode.GetKey(info, key); v != nil && v.Kind != yaml.ScalarNode {
+            return nil, []FragmentError{{File: name, Line: v.Line, Column: v.Column,
+                Message: fmt.Sprintf("info.%s must be a string, found %s", key, yamlnode.KindName(v))}}
+        } else if v != nil && v.Tag != "!!str" {
+            // "version: 1.0" would otherwise stay a float in the root.
+            v.Tag, v.Style = "!!str", yaml.DoubleQuotedStyle
+        }
+    }
+    return info, nil
+}
+
+// loadNamedList loads a servers or tags list whose items are mappings that
+// must carry required.
+func loadNamedList(cfg *Config, file, key, required string) (*yaml.Node, []FragmentError) {
+    list, name, errs := loadRootList(cfg, file, key)
+    if list == nil || len(errs) > 0 { return nil, errs }
+    for _, item := range list.Content {
+        if item.Kind != yaml.MappingNode {
+            errs = append(errs, FragmentError{File: name, Line: item.Line, Column: item.Column,
+                Message: fmt.Sprintf("%s entries must be mappings, found %s", key, yamlnode.KindName(item))})
+        } else if yamlnode.GetKey(item, required) == nil {
+            errs = append(errs, FragmentError{File: name, Line: item.Line, Column: item.Column,
+                Message: fmt.Sprintf("%s entry has no %q", key, required)})
+        }
+    }
+    if len(errs) > 0 { return nil, errs }
+    return list, nil
+}
+
+// rootHeaderNode returns the root document with openapi, info, servers, tags
+// and security filled in from the header files, defaulting info to
+// "title: API" and version "1.0.0".
+func rootHeaderNode(cfg *Config) (*yaml.Node, []FragmentError) {
+    var problems []FragmentError
+    root := yamlnode.Map()
+    version := "3.0.0"
+    if cfg.OpenAPIVersion == OpenAPI31 { version = "3.1.0" }
+    yamlnode.SetKey(root, "openapi", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: version, Style: yaml.DoubleQuotedStyle})
+    info := yamlnode.Map()
+    yamlnode.SetKey(info, "title", yamlnode.Str("API"))
+    yamlnode.SetKey(info, "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "1.0.0", Style: yaml.DoubleQuotedStyle})
+    custom, errs := loadInfo(cfg)
+    problems = append(problems, errs...)
+    if custom != nil {
+        for i := 0; i+1 < len(custom.Content); i += 2 {
+            yamlnode.SetKey(info, custom.Content[i].Value, custom.Content[i+1])
+        }
+    }
+    yamlnode.SetKey(root, "info", info)
+
+    servers, errs := loadNamedList(cfg, ServersFile, "servers", "url")
+    problems = append(problems, errs...)
+    if servers != nil { yamlnode.SetKey(root, "servers", servers) }
+    tags, errs := loadNamedList(cfg, TagsFile, "tags", "name")
+    problems = append(problems, errs...)
+    if tags != nil { yamlnode.SetKey(root, "tags", tags) }
+    security, errs := loadSecurity(cfg)
+    problems = append(problems, errs...)
+    if security != nil { yamlnode.SetKey(root, "security", security) }
+    return root, problems
+}
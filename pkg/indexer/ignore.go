@@ -0,0 +1,103 @@
+package indexer
+
+import (
+    "path/filepath"
+    "regexp"
+    "strings"
+)
+
+// IgnoreFile, at the input root, lists globs of the files and directories
+// below it that are not fragments (work in progress, fixtures, vendored
+// trees), one per line as in .gitignore: # starts a comment, a trailing /
+// matches directories only, a leading ! re-includes what an earlier line
+// ignored, and a glob without a slash matches a name at any depth. * and ?
+// match within a path segment, ** across segments.
+const IgnoreFile = ".oasignore"
+
+// globRule is one compiled IgnoreFile line, Include or Ignore glob.
+type globRule struct {
+    re      *regexp.Regexp
+    negate  bool
+    dirOnly bool
+}
+
+// parseGlobRules compiles the globs in lines, skipping blank lines and comments.
+func parseGlobRules(lines []string) []globRule {
+    var rules []globRule
+    for _, ln := range lines {
+        ln = strings.TrimSpace(ln)
+        if ln == "" || strings.HasPrefix(ln, "#") { continue }
+        var r globRule
+        if strings.HasPrefix(ln, "!") { r.negate, ln = true, ln[1:] }
+        if strings.HasSuffix(ln, "/") { r.dirOnly, ln = true, strings.TrimRight(ln, "/") }
+        if ln == "" { continue }
+        prefix := "^"
+        if strings.HasPrefix(ln, "/") {
+            ln = strings.TrimLeft(ln, "/")
+        } else if !strings.Contains(ln, "/") {
+            prefix = "^(?:.*/)?"
+        }
+        r.re = regexp.MustCompile(prefix + globPattern(ln) + "$")
+        rules = append(rules, r)
+    }
+    return rules
+}
+
+// globPattern translates a glob into a regexp (without anchors): ** matches
+// across segments (**/ also matches no directory at all), * and ? within one.
+func globPattern(glob string) string {
+    var b strings.Builder
+    for i := 0; i < len(glob); i++ {
+        switch {
+        case strings.HasPrefix(glob[i:], "**/"):
+            b.WriteString("(?:.*/)?")
+            i += 2
+        case strings.HasPrefix(glob[i:], "**"):
+            b.WriteString(".*")
+            i++
+        case glob[i] == '*':
+            b.WriteString("[^/]*")
+        case glob[i] == '?':
+            b.WriteString("[^/]")
+        default:
+            b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+        }
+    }
+    return b.String()
+}
+
+// matchRules reports whether the last rule matching rel ignores it.
+func matchRules(rules []globRule, rel string, dir bool) bool {
+    ignored := false
+    for _, r := range rules {
+        if r.dirOnly && !dir { continue }
+        if r.re.MatchString(rel) { ignored = !r.negate }
+    }
+    return ignored
+}
+
+// ignoreRules returns the rules of IgnoreFile followed by Ignore.
+func (cfg *Config) ignoreRules() []globRule {
+    var lines []string
+    if text, err := ReadFragment(cfg, filepath.Join(cfg.InputDir, IgnoreFile)); err == nil {
+        lines = strings.Split(text, "\n")
+    }
+    return parseGlobRules(append(lines, cfg.Ignore...))
+}
+
+// fragmentFilter returns whether a path below the input directory is left
+// out of discovery: a directory or file matched by the ignore rules, or a
+// file matching none of the Include globs. Paths outside the input
+// directory (a shared components tree) are never left out.
+func (cfg *Config) fragmentFilter() func(path string, dir bool) bool {
+    ignore := cfg.ignoreRules()
+    include := parseGlobRules(cfg.Include)
+    return func(path string, dir bool) bool {
+        if !IsWithin(cfg.InputDir, path) { return false }
+        rel, err := filepath.Rel(cfg.InputDir, path)
+        if err != nil { return false }
+        rel = filepath.ToSlash(rel)
+        if matchRules(ignore, rel, dir) { return true }
+        return !dir && len(include) > 0 && !matchRules(include, rel, false)
+    }
+}
@@ -0,0 +1,391 @@
+// Package indexer builds an OpenAPI root document from a tree of fragments:
+// path items under paths/ and components under components/{schemas,parameters}/.
+// The root is written either reference-style ($ref to each fragment) or
+// joined, with every fragment inlined and its refs rewritten to internal ones.
+package indexer
+
+import (
+    "fmt"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+    "github.com/bilbo290/oas-indexer/pkg/overlay"
+    "github.com/bilbo290/oas-indexer/pkg/remote"
+    "github.com/bilbo290/oas-indexer/pkg/vars"
+)
+
+// Config describes one fragment tree and how its root is built.
+type Config struct {
+    Cwd        string // base for relative paths and for file names in messages
+    InputDir   string
+    OutputDir  string
+    RootFile   string
+    RootPath   string
+    PathsDir   string
+    WebhooksDir string // OpenAPI 3.1 webhooks, one path item fragment per webhook
+    EventsDir  string // AsyncAPI channel and message fragments (see BuildAsyncAPI)
+    AsyncAPIPath string // the AsyncAPI document built from EventsDir, when it holds channels
+    ComponentsDir string // parent of the Components directories
+    TraitsDir  string // operation fields merged into the operations naming them in x-traits (see ApplyTraits)
+    SharedComponentsDir string // optional components tree shared with other APIs; only the components the API references are emitted
+
+    // Exclude lists generated artifacts (besides RootPath) that must never be
+    // read back as fragments, e.g. a bundle written inside the input tree.
+    Exclude []string
+    Include []string // globs relative to InputDir; when set, fragment files must match one (see IgnoreFile for the syntax)
+    Ignore  []string // globs relative to InputDir of files and directories left out, after those of IgnoreFile
+    Known   []string // files and directories below InputDir that are not fragments but belong there (vendored dependencies, config files); Strict accepts them
+
+    // Behavior
+    Join bool // if true, write joined/inlined root; default false = reference-style
+    PathCasing string // camel (default), kebab or preserve; applied to derived path keys
+    PathVersion string // keep (default) or strip: drop the first directory (the version) from derived path keys
+    PathStripPrefix string // directories below paths/ left out of derived path keys, e.g. internal/api
+    PathRewrites []PathRewrite // applied in order to every derived path key
+    Version string // if set, the root holds only the paths below paths/<Version> and the components they reach
+    MethodFiles bool  // paths/<path>/<method>.yaml holds one operation of <path>
+    AutoTags   bool   // tag untagged operations after their directory below the version directory (see DirTags)
+    StandardResponses map[string]string // status code -> components/responses name added to operations without that code
+    ComponentNaming string // pascal (default), camel or verbatim; applied to component file base names
+    NameMap map[string]string // component fragment (relative to InputDir) -> explicit component name
+    NamespaceSeparator string // joins subdirectory names and the file name in component names ("" gives BillingInvoice)
+    Vendor *remote.Vendor // resolves http(s) refs to vendored copies; nil leaves them as written
+    FollowSymlinks bool // descend into symlinked files/dirs during discovery (cycle-safe)
+    MergeKeys  string // join mode: resolve (default) expands << merges and aliases; preserve keeps them verbatim
+    Format     string // root file format: yaml (default) or json
+    Prune      bool   // leave components no path fragment reaches out of the root
+    OpenAPIVersion string // 3.0 (default) or 3.1; the root's openapi field and schema dialect
+    Downconvert    bool   // accept 3.1 schema keywords in fragments and convert them for a 3.0 root
+    Overlays       []*overlay.Overlay // join mode: applied in order to the built root
+    Vars           *vars.Vars // join mode: expands ${NAME} placeholders in the built root; nil leaves them
+
+    // Input normalization
+    ExpandTabs int // if > 0, replace tab indentation with this many spaces instead of failing
+    Strict     bool // fail on fragments whose shape does not match their OpenAPI object kind, and on files outside the layout (see checkLayout)
+
+    // Logger receives discovery warnings and notes (skipped symlinks,
+    // excluded outputs); nil uses slog.Default(). Discovery runs once per
+    // phase, so a caller wanting each reported once dedupes them.
+    Logger *slog.Logger
+}
+
+// NewConfig returns a Config for the fragments in inputDir with the root
+// written to outputDir/rootFile; empty outputDir and rootFile default to
+// inputDir and root.yaml. Relative paths are resolved against the working directory.
+func NewConfig(inputDir, outputDir, rootFile string) *Config {
+    cwd, _ := os.Getwd()
+    if strings.TrimSpace(outputDir) == "" { outputDir = inputDir }
+    if strings.TrimSpace(rootFile) == "" { rootFile = "root.yaml" }
+    inputDir = absJoin(cwd, inputDir)
+    outputDir = absJoin(cwd, outputDir)
+    return &Config{
+        Cwd:        cwd,
+        InputDir:   inputDir,
+        OutputDir:  outputDir,
+        RootFile:   rootFile,
+        RootPath:   absJoin(outputDir, rootFile),
+        PathsDir:   filepath.Join(inputDir, "paths"),
+        WebhooksDir: filepath.Join(inputDir, "webhooks"),
+        EventsDir:  filepath.Join(inputDir, "events"),
+        AsyncAPIPath: absJoin(outputDir, "asyncapi."+FormatForFile(rootFile)),
+        ComponentsDir: filepath.Join(inputDir, "components"),
+        TraitsDir:  filepath.Join(inputDir, "traits"),
+        PathCasing: PathCasingCamel,
+        PathVersion: PathVersionKeep,
+        ComponentNaming: ComponentNamingPascal,
+        MergeKeys:  MergeKeysResolve,
+        OpenAPIVersion: OpenAPI30,
+        Format:     FormatForFile(rootFile),
+    }
+}
+
+// Root file formats.
+const (
+    FormatYAML = "yaml"
+    FormatJSON = "json"
+)
+
+// FormatForFile returns FormatJSON for .json file names and FormatYAML otherwise.
+func FormatForFile(name string) string {
+    if strings.EqualFold(filepath.Ext(name), ".json") { return FormatJSON }
+    return FormatYAML
+}
+
+// BuildRoot writes the reference-style or, with Join, the joined root to
+// RootPath. The returned bool is false when the existing root was already up
+// to date and left untouched.
+func BuildRoot(cfg *Config) (bool, error) {
+    out, err := RenderRoot(cfg)
+    if err != nil { return false, err }
+    if err := os.MkdirAll(filepath.Dir(cfg.RootPath), 0o755); err != nil { return false, err }
+    return atomicfile.WriteFile(cfg.RootPath, out)
+}
+
+func absJoin(base, p string) string {
+    if filepath.IsAbs(p) {
+        return filepath.Clean(p)
+    }
+    return filepath.Clean(filepath.Join(base, p))
+}
+
+// ListFragments returns the .yaml fragments below root, leaving out those
+// IgnoreFile, Ignore or Include filter out. Per-file problems are returned
+// as FragmentErrors alongside the files that could be listed.
+func ListFragments(cfg *Config, root string) ([]string, error) {
+    var files []string
+    if st, err := os.Stat(root); err != nil || !st.IsDir() {
+        return files, nil
+    }
+    skip := cfg.fragmentFilter()
+    if cfg.FollowSymlinks {
+        var problems FragmentErrors
+        w := &symlinkWalk{cfg: cfg, skip: skip, problems: &problems}
+        walked, err := w.walk(root, "", nil)
+        files := dedupeSymlinked(cfg.log(), walked)
+        if err == nil && len(problems) > 0 {
+            return files, problems
+        }
+        return files, err
+    }
+    var problems FragmentErrors
+    err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+        if err != nil {
+            if path == root { return err }
+            if skip(path, d != nil && d.IsDir()) { return nil }
+            // Record and keep walking; reported together with other problems
+            problems = append(problems, FragmentError{File: path, Message: err.Error()})
+            if d != nil && d.IsDir() { return filepath.SkipDir }
+            return nil
+        }
+        name := d.Name()
+        if strings.HasPrefix(name, ".") { // skip dot files/dirs
+            if d.IsDir() && path != root {
+                return filepath.SkipDir
+            }
+        }
+        if cfg.isGeneratedOutput(path) { return nil }
+        if path != root && skip(path, d.IsDir()) {
+            if d.IsDir() { return filepath.SkipDir }
+            return nil
+        }
+        if d.Type()&os.ModeSymlink != 0 {
+            cfg.log().Warn(fmt.Sprintf("skipping symlink %s (use --follow-symlinks to index it)", path))
+            return nil
+        }
+        if d.Type().IsRegular() && IsFragmentFile(name) && name != DirTagFile {
+            files = append(files, path)
+        }
+        return nil
+    })
+    if err == nil && len(problems) > 0 {
+        return files, problems
+    }
+    return files, err
+}
+
+// generatedOutputs lists the artifacts this run writes, as absolute paths.
+func (cfg *Config) generatedOutputs() []string {
+    var out []string
+    for _, p := range append([]string{cfg.RootPath, cfg.AsyncAPIPath}, cfg.Exclude...) {
+        if strings.TrimSpace(p) != "" {
+            out = append(out, absJoin(cfg.Cwd, p))
+        }
+    }
+    return out
+}
+
+// isGeneratedOutput reports whether path is one of this run's own outputs, so
+// a root or bundle written inside the input tree is never read back as a fragment.
+func (cfg *Config) isGeneratedOutput(path string) bool {
+    p := absJoin(cfg.Cwd, path)
+    for _, o := range cfg.generatedOutputs() {
+        if p == o {
+            cfg.log().Info(fmt.Sprintf("excluding generated output %s from fragment discovery", path))
+            return true
+        }
+    }
+    return false
+}
+
+// IsWithin reports whether path is strictly below dir.
+func IsWithin(dir, path string) bool {
+    rel, err := filepath.Rel(dir, path)
+    if err != nil || rel == "." { return false }
+    return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// log returns the logger discovery diagnostics go to.
+func (cfg *Config) log() *slog.Logger {
+    if cfg.Logger == nil { return slog.Default() }
+    return cfg.Logger
+}
+
+// symlinkWalk lists the fragments below a directory, descending into
+// symlinked files and directories.
+type symlinkWalk struct {
+    cfg      *Config
+    skip     func(path string, dir bool) bool
+    problems *FragmentErrors
+    start    string // the resolved directory the walk started in
+}
+
+// walk lists the fragments below dir, reached through the symlink via ("" for
+// none). ancestors holds the resolved paths of the directories on the
+// current branch, so that a link pointing back up the tree, or above the
+// directory the walk started in, is reported, not looped.
+func (w *symlinkWalk) walk(dir, via string, ancestors map[string]bool) ([]symlinkedFile, error) {
+    real, err := filepath.EvalSymlinks(dir)
+    if err != nil { return nil, err }
+    if ancestors == nil {
+        w.start = real
+    } else if ancestors[real] || IsWithin(real, w.start) {
+        w.cfg.log().Warn(fmt.Sprintf("symlink cycle at %s (resolves to %s); not descending", dir, real))
+        return nil, nil
+    }
+    branch := map[string]bool{real: true}
+    for k := range ancestors { branch[k] = true }
+
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        if ancestors == nil { return nil, err }
+        *w.problems = append(*w.problems, FragmentError{File: dir, Message: err.Error()})
+        return nil, nil
+    }
+    var files []symlinkedFile
+    for _, e := range entries {
+        name := e.Name()
+        if strings.HasPrefix(name, ".") { continue } // skip dot files/dirs
+        path := filepath.Join(dir, name)
+        st, err := os.Stat(path) // follows links
+        if err != nil {
+            if e.Type()&os.ModeSymlink != 0 {
+                w.cfg.log().Warn(fmt.Sprintf("skipping broken symlink %s", path))
+                continue
+            }
+            *w.problems = append(*w.problems, FragmentError{File: path, Message: err.Error()})
+            continue
+        }
+        if w.skip(path, st.IsDir()) { continue }
+        entryVia := via
+        if entryVia == "" && e.Type()&os.ModeSymlink != 0 { entryVia = path }
+        if st.IsDir() {
+            sub, err := w.walk(path, entryVia, branch)
+            if err != nil { return nil, err }
+            files = append(files, sub...)
+            continue
+        }
+        if st.Mode().IsRegular() && IsFragmentFile(name) && name != DirTagFile && !w.cfg.isGeneratedOutput(path) {
+            realFile, err := filepath.EvalSymlinks(path)
+            if err != nil { realFile = path }
+            files = append(files, symlinkedFile{path: path, real: realFile, via: entryVia, hops: linkHops(entryVia)})
+        }
+    }
+    return files, nil
+}
+
+// linkHops counts the links followed from path to a file or directory that
+// is not a link; 0 for "".
+func linkHops(path string) int {
+    n := 0
+    for ; path != "" && n < 255; n++ {
+        st, err := os.Lstat(path)
+        if err != nil || st.Mode()&os.ModeSymlink == 0 { break }
+        target, err := os.Readlink(path)
+        if err != nil { break }
+        if !filepath.IsAbs(target) { target = filepath.Join(filepath.Dir(path), target) }
+        path = target
+    }
+    return n
+}
+
+// symlinkedFile is a fragment found by symlinkWalk.
+type symlinkedFile struct {
+    path string
+    real string // path with every symlink resolved
+    via  string // the outermost symlink on path, "" for none
+    hops int    // links followed to resolve via
+}
+
+// dedupeSymlinked returns the paths of files, keeping one path per resolved
+// file so that a fragment reachable both directly and through a symlink (or
+// through two symlinks) is indexed once: the direct path, else the one
+// through the shortest chain of links, else the first. Each link leading
+// only to fragments kept under another path is reported to log.
+func dedupeSymlinked(log *slog.Logger, files []symlinkedFile) []string {
+    keep := map[string]symlinkedFile{}
+    for _, f := range files {
+        if k, ok := keep[f.real]; !ok || f.hops < k.hops { keep[f.real] = f }
+    }
+    var out []string
+    reported := map[string]bool{}
+    for _, f := range files {
+        k := keep[f.real]
+        if k.path == f.path {
+            out = append(out, f.path)
+            continue
+        }
+        if !reported[f.via] {
+            reported[f.via] = true
+            log.Warn(fmt.Sprintf("symlink %s leads to fragments indexed already (e.g. as %s); indexing them once", f.via, k.path))
+        }
+    }
+    return out
+}
+
+// FragmentExts lists the file extensions indexed as fragments. JSON is a
+// subset of YAML, so .json fragments go through the same parser.
+var FragmentExts = []string{".yaml", ".yml", ".json"}
+
+// IsFragmentFile reports whether name has one of FragmentExts.
+func IsFragmentFile(name string) bool {
+    ext := strings.ToLower(filepath.Ext(name))
+    for _, e := range FragmentExts {
+        if ext == e { return true }
+    }
+    return false
+}
+
+func isJSONFragment(path string) bool { return strings.EqualFold(filepath.Ext(path), ".json") }
+
+// trimFragmentExt strips a fragment extension from file.
+func trimFragmentExt(file string) string {
+    if IsFragmentFile(file) { return file[:len(file)-len(filepath.Ext(file))] }
+    return file
+}
+
+// ReadFragment reads a fragment with BOM stripped, line endings normalized to LF and,
+// when ExpandTabs is set, leading tabs replaced by spaces. Leading tabs are
+// always expanded in JSON fragments, where indentation is insignificant.
+// Each file is read once while it does not change (see fragmentCache).
+func ReadFragment(cfg *Config, path string) (string, error) {
+    e := fragmentEntry(cfg, path)
+    return e.text, e.err
+}
+
+func normalizeFragment(s string, tabWidth int) string {
+    s = strings.TrimPrefix(s, "\uFEFF")
+    s = strings.ReplaceAll(s, "\r\n", "\n")
+    s = strings.ReplaceAll(s, "\r", "\n")
+    if tabWidth <= 0 || !strings.Contains(s, "\t") { return s }
+    lines := strings.Split(s, "\n")
+    for i, ln := range lines {
+        lead := len(ln) - len(strings.TrimLeft(ln, " \t"))
+        if !strings.Contains(ln[:lead], "\t") { continue }
+        lines[i] = strings.ReplaceAll(ln[:lead], "\t", strings.Repeat(" ", tabWidth)) + ln[lead:]
+    }
+    return strings.Join(lines, "\n")
+}
+
+// tabIndentedLines returns 1-based line numbers whose indentation contains a tab.
+func tabIndentedLines(s string) []int {
+    var out []int
+    for i, ln := range strings.Split(s, "\n") {
+        lead := ln[:len(ln)-len(strings.TrimLeft(ln, " \t"))]
+        if strings.Contains(lead, "\t") { out = append(out, i+1) }
+    }
+    return out
+}
+
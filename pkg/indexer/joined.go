@@ -0,0 +1,70 @@
+package indexer
+
+import (
+    "sort"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// JoinedFragment is a fragment, or one definition of a definitions file,
+// parsed as for a joined root: its refs point into #/components/.
+type JoinedFragment struct {
+    Path    string     // the fragment file
+    File    string     // DisplayPath of Path
+    Pointer string     // JSON pointer of Node in the file: "" or "/<definition key>"
+    Schema  bool       // Node is a Schema object
+    Node    *yaml.Node // keeps the file's lines
+}
+
+// JoinedFragments returns every path, webhook, trait and component fragment
+// as for a joined root, and a document holding the components their refs
+// point into (sharing their nodes). Fragments that do not parse are left
+// out; CheckFragments reports them.
+func JoinedFragments(cfg *Config) ([]JoinedFragment, *yaml.Node, error) {
+    names := BuildNameMaps(cfg)
+    parsed := map[string]*yaml.Node{}
+    join := func(f string) *yaml.Node {
+        if body, ok := parsed[f]; ok { return body }
+        body, err := joinFragment(cfg, f, names)
+        if err != nil { body = nil }
+        parsed[f] = body
+        return body
+    }
+    var out []JoinedFragment
+    for _, group := range cfg.fragmentGroups() {
+        files, err := ListFragments(cfg, group.dir)
+        if _, err := DiscoveryProblems(err); err != nil { return nil, nil, err }
+        sort.Strings(files)
+        for _, f := range files {
+            body := join(f)
+            if body == nil { continue }
+            schema := group.kind == kindSchema
+            keys := definitionKeys(group.kind, body)
+            if group.kind == kindPathItem || keys == nil {
+                out = append(out, JoinedFragment{Path: f, File: DisplayPath(cfg, f), Schema: schema, Node: body})
+                continue
+            }
+            for _, k := range keys {
+                out = append(out, JoinedFragment{Path: f, File: DisplayPath(cfg, f), Pointer: "/" + pointerToken(k), Schema: schema, Node: yamlnode.GetKey(body, k)})
+            }
+        }
+    }
+
+    components := yamlnode.Map()
+    for _, c := range Components {
+        files, err := ComponentFiles(cfg, c)
+        if err != nil { return nil, nil, err }
+        section := yamlnode.Map()
+        for _, e := range componentEntries(cfg, c, files) {
+            body := join(e.File)
+            if e.Key != "" { body = yamlnode.GetKey(body, e.Key) }
+            if body != nil { yamlnode.SetKey(section, e.Name, body) }
+        }
+        yamlnode.SetKey(components, c.Section, section)
+    }
+    doc := yamlnode.Map()
+    yamlnode.SetKey(doc, "components", components)
+    return out, doc, nil
+}
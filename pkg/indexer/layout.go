@@ -0,0 +1,108 @@
+package indexer
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "slices"
+    "sort"
+    "strings"
+)
+
+// Strict mode also checks the layout of the input tree, as discovery
+// silently passes over whatever does not fit it: files that are not
+// fragments, directories and YAML files where no fragment is read from
+// (a components/schema typo), and path fragments outside a version
+// directory. Dot files, paths the ignore rules leave out, the generated
+// outputs and the Known paths are never reported.
+
+// reVersionDir matches the names of version directories below paths/.
+var reVersionDir = regexp.MustCompile(`^v\d`)
+
+// checkLayout reports what discovery leaves out of the input tree.
+func checkLayout(cfg *Config) FragmentErrors {
+    skip := cfg.fragmentFilter()
+    exempt := append(cfg.generatedOutputs(), cfg.Known...)
+    if IsWithin(cfg.InputDir, cfg.OutputDir) { exempt = append(exempt, cfg.OutputDir) }
+    if cfg.Vendor != nil { exempt = append(exempt, cfg.Vendor.Dir) }
+    // leftOut reports whether path is never a layout violation: a dot file,
+    // ignored, or an exempt path, below one or holding one.
+    leftOut := func(path string, dir bool) bool {
+        if strings.HasPrefix(filepath.Base(path), ".") || skip(path, dir) { return true }
+        for _, e := range exempt {
+            if path == e || IsWithin(e, path) || (dir && IsWithin(path, e)) { return true }
+        }
+        return false
+    }
+    var problems FragmentErrors
+    report := func(path, format string, args ...any) {
+        problems = append(problems, FragmentError{File: DisplayPath(cfg, path), Message: fmt.Sprintf(format, args...)})
+    }
+
+    // The input root, components/ and events/ hold a fixed set of entries.
+    dirs := map[string][]string{cfg.ComponentsDir: nil, cfg.EventsDir: {EventsHeaderFile}}
+    for _, c := range Components { dirs[cfg.ComponentsDir] = append(dirs[cfg.ComponentsDir], c.Dir+"/") }
+    dirs[cfg.EventsDir] = append(dirs[cfg.EventsDir], filepath.Base(cfg.ChannelsDir())+"/", filepath.Base(cfg.MessagesDir())+"/")
+    var top []string
+    for _, d := range []string{cfg.PathsDir, cfg.WebhooksDir, cfg.EventsDir, cfg.ComponentsDir, cfg.TraitsDir} {
+        if filepath.Dir(d) == cfg.InputDir { top = append(top, filepath.Base(d)+"/") }
+    }
+    for _, h := range HeaderFiles {
+        if filepath.Dir(h) == "." { top = append(top, h) }
+    }
+    dirs[cfg.InputDir] = top
+    for _, dir := range []string{cfg.InputDir, cfg.ComponentsDir, cfg.EventsDir} {
+        entries, err := os.ReadDir(dir)
+        if err != nil { continue }
+        expected := dirs[dir]
+        for _, e := range entries {
+            path := filepath.Join(dir, e.Name())
+            isDir := e.IsDir()
+            if isDir {
+                if st, err := os.Stat(path); err == nil { isDir = st.IsDir() } // a followed symlink
+            }
+            name := e.Name()
+            if isDir { name += "/" }
+            if leftOut(path, isDir) || slices.Contains(expected, name) || (!isDir && dir == cfg.InputDir && !IsFragmentFile(name)) { continue }
+            what := "unexpected file"
+            if isDir { what = "unexpected directory" }
+            report(path, "%s: only %s are read here; move it, or list it in %s", what, strings.Join(expected, ", "), IgnoreFile)
+        }
+    }
+
+    // Fragment directories: every file must be a fragment.
+    for _, root := range cfg.FragmentDirs() {
+        if !IsWithin(cfg.InputDir, root) { continue } // a shared components tree
+        filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+            if err != nil || path == root { return nil } // unreadable entries are discovery problems
+            if leftOut(path, d.IsDir()) {
+                if d.IsDir() { return filepath.SkipDir }
+                return nil
+            }
+            if d.Type().IsRegular() && !IsFragmentFile(d.Name()) {
+                report(path, "not a fragment (%s) and not indexed; rename it, or list it in %s", strings.Join(FragmentExts, ", "), IgnoreFile)
+            }
+            return nil
+        })
+    }
+
+    // Path fragments: each in a version directory.
+    files, _ := ListFragments(cfg, cfg.PathsDir)
+    badVersions := map[string]bool{}
+    for _, f := range files {
+        v := pathVersion(cfg, f)
+        if v == "" {
+            report(f, "path fragment is not in a version directory (e.g. paths/v1/)")
+            continue
+        }
+        if !reVersionDir.MatchString(v) { badVersions[filepath.Join(pathKeyBase(cfg, f), v)] = true }
+    }
+    var bad []string
+    for d := range badVersions { bad = append(bad, d) }
+    sort.Strings(bad)
+    for _, d := range bad {
+        report(d, "path fragments below a directory not named like a version (v1, v2, ...); move them to a version directory")
+    }
+    return problems
+}
@@ -0,0 +1,92 @@
+package indexer
+
+import "gopkg.in/yaml.v3"
+
+// Merge-key handling for join mode (--merge-keys)
+const (
+    MergeKeysResolve  = "resolve"
+    MergeKeysPreserve = "preserve"
+)
+
+// resolveMergeKeys rewrites n in place so that aliases are replaced by copies
+// of their anchored nodes and "<<" merge keys are expanded into explicit
+// entries. Explicit keys win over merged ones, and earlier merge sources win
+// over later ones, as in YAML 1.1. Anchors are dropped afterwards, so inlined
+// fragments cannot clash on anchor names in the joined document.
+func resolveMergeKeys(n *yaml.Node) {
+    expanded := expandAliases(n)
+    *n = *expanded
+}
+
+func expandAliases(n *yaml.Node) *yaml.Node {
+    if n == nil { return nil }
+    if n.Kind == yaml.AliasNode {
+        return expandAliases(n.Alias)
+    }
+    cp := *n
+    cp.Anchor = ""
+    cp.Content = make([]*yaml.Node, len(n.Content))
+    for i, c := range n.Content {
+        cp.Content[i] = expandAliases(c)
+    }
+    if cp.Kind == yaml.MappingNode {
+        mergeInto(&cp)
+    }
+    return &cp
+}
+
+// mergeInto expands "<<" entries of an alias-free mapping node.
+func mergeInto(m *yaml.Node) {
+    explicit := map[string]bool{}
+    hasMerge := false
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        if isMergeKey(m.Content[i]) {
+            hasMerge = true
+        } else {
+            explicit[m.Content[i].Value] = true
+        }
+    }
+    if !hasMerge { return }
+    var out []*yaml.Node
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        k, v := m.Content[i], m.Content[i+1]
+        if !isMergeKey(k) {
+            out = append(out, k, v)
+            continue
+        }
+        var sources []*yaml.Node
+        if v.Kind == yaml.SequenceNode {
+            sources = v.Content
+        } else {
+            sources = []*yaml.Node{v}
+        }
+        for _, src := range sources {
+            if src.Kind != yaml.MappingNode { continue }
+            for j := 0; j+1 < len(src.Content); j += 2 {
+                sk := src.Content[j]
+                if explicit[sk.Value] { continue }
+                explicit[sk.Value] = true
+                out = append(out, sk, src.Content[j+1])
+            }
+        }
+    }
+    m.Content = out
+}
+
+func isMergeKey(k *yaml.Node) bool {
+    return k.Kind == yaml.ScalarNode && k.Value == "<<" && (k.Tag == "!!merge" || k.Tag == "" || k.Style == 0)
+}
+
+// untagMergeKeys clears the explicit !!merge tag yaml.v3 would otherwise
+// print in front of preserved "<<" keys.
+func untagMergeKeys(n *yaml.Node) {
+    if n == nil { return }
+    if n.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if n.Content[i].Tag == "!!merge" { n.Content[i].Tag = "" }
+        }
+    }
+    for _, c := range n.Content {
+        untagMergeKeys(c)
+    }
+}
@@ -0,0 +1,187 @@
+package indexer
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// CommonParametersFile, at the input root, lists parameters added to every
+// path item (or those whose key matches the entry's CommonPathsKey globs),
+// such as a tracing header.
+const CommonParametersFile = "common-parameters.yaml"
+
+// CommonPathsKey restricts a common parameter to the path keys matching one
+// of its globs, where * matches within a segment and ** across segments.
+const CommonPathsKey = "x-paths"
+
+const parameterRefPrefix = "#/components/parameters/"
+
+// commonParameter is one entry of CommonParametersFile.
+type commonParameter struct {
+    node  *yaml.Node // the Parameter object or $ref, without CommonPathsKey
+    paths []*regexp.Regexp
+}
+
+// HasCommonParameters reports whether the input has a CommonParametersFile.
+func HasCommonParameters(cfg *Config) bool {
+    _, err := os.Stat(filepath.Join(cfg.InputDir, CommonParametersFile))
+    return err == nil
+}
+
+// loadCommonParameters parses CommonParametersFile, returning nil when it
+// does not exist. Entries are Parameter objects or refs to parameter
+// components; file refs are rejected, as the file is not a fragment.
+func loadCommonParameters(cfg *Config) ([]commonParameter, []FragmentError) {
+    list, name, errs := loadRootList(cfg, CommonParametersFile, "parameters")
+    if list == nil || len(errs) > 0 { return nil, errs }
+    var params []commonParameter
+    for _, item := range list.Content {
+        fail := func(n *yaml.Node, format string, args ...interface{}) {
+            errs = append(errs, FragmentError{File: name, Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...)})
+        }
+        if item.Kind != yaml.MappingNode {
+            fail(item, "parameters entries must be mappings, found %s", yamlnode.KindName(item))
+            continue
+        }
+        p := commonParameter{node: yamlnode.Map()}
+        for i := 0; i+1 < len(item.Content); i += 2 {
+            if item.Content[i].Value != CommonPathsKey { p.node.Content = append(p.node.Content, item.Content[i], item.Content[i+1]) }
+        }
+        if globs := yamlnode.GetKey(item, CommonPathsKey); globs != nil {
+            values := []*yaml.Node{globs}
+            if globs.Kind == yaml.SequenceNode { values = globs.Content }
+            for _, g := range values {
+                if g.Kind != yaml.ScalarNode || !strings.HasPrefix(g.Value, "/") {
+                    fail(g, "%s entries must be path globs such as /v1/**", CommonPathsKey)
+                    continue
+                }
+                p.paths = append(p.paths, pathGlob(g.Value))
+            }
+        }
+        if ref := yamlnode.GetKey(p.node, "$ref"); ref != nil {
+            if !strings.HasPrefix(ref.Value, parameterRefPrefix) {
+                fail(ref, "refer to a parameter component as %s<Name>, not %q", parameterRefPrefix, ref.Value)
+                continue
+            }
+        } else if yamlnode.GetKey(p.node, "name") == nil || yamlnode.GetKey(p.node, "in") == nil {
+            fail(item, "a parameter needs a name and in (or a $ref to a parameter component)")
+            continue
+        }
+        params = append(params, p)
+    }
+    if len(errs) > 0 { return nil, errs }
+    return params, nil
+}
+
+// pathGlob compiles a CommonPathsKey glob.
+func pathGlob(glob string) *regexp.Regexp {
+    return regexp.MustCompile("^" + globPattern(glob) + "$")
+}
+
+// commonParameterRefs returns the parameter components CommonParametersFile
+// refers to, as "parameters/<Name>" ref graph nodes.
+func commonParameterRefs(cfg *Config) []string {
+    params, _ := loadCommonParameters(cfg)
+    var nodes []string
+    for _, p := range params {
+        if ref := yamlnode.GetKey(p.node, "$ref"); ref != nil {
+            nodes = append(nodes, "parameters/"+strings.NewReplacer("~1", "/", "~0", "~").Replace(strings.TrimPrefix(ref.Value, parameterRefPrefix)))
+        }
+    }
+    return nodes
+}
+
+// AddCommonParameters adds the parameters of CommonParametersFile to the
+// path items of root's paths their globs match, unless the item already
+// has a parameter with the same name and location. Items that are still
+// $refs are left alone.
+func AddCommonParameters(cfg *Config, root *yaml.Node) error {
+    params, errs := loadCommonParameters(cfg)
+    if len(errs) > 0 { return FragmentErrors(errs) }
+    if len(params) == 0 { return nil }
+    var missing []string
+    for _, p := range params {
+        if ref := yamlnode.GetKey(p.node, "$ref"); ref != nil && parameterComponent(root, ref.Value) == nil { missing = append(missing, ref.Value) }
+    }
+    if len(missing) > 0 {
+        return fmt.Errorf("%s: no parameter component for %s", CommonParametersFile, strings.Join(missing, ", "))
+    }
+
+    paths := yamlnode.GetKey(root, "paths")
+    if paths == nil || paths.Kind != yaml.MappingNode { return nil }
+    for i := 0; i+1 < len(paths.Content); i += 2 {
+        key, item := paths.Content[i].Value, paths.Content[i+1]
+        if item.Kind != yaml.MappingNode || yamlnode.GetKey(item, "$ref") != nil { continue }
+        list := yamlnode.GetKey(item, "parameters")
+        have := map[string]bool{}
+        if list != nil {
+            for _, p := range list.Content {
+                have[parameterID(root, p)] = true
+            }
+        }
+        var added []*yaml.Node
+        for _, p := range params {
+            id := parameterID(root, p.node)
+            if !p.matches(key) || have[id] { continue }
+            have[id] = true
+            added = append(added, copyNode(p.node))
+        }
+        if len(added) == 0 { continue }
+        if list == nil || list.Kind != yaml.SequenceNode {
+            list = yamlnode.Seq()
+            yamlnode.SetKey(item, "parameters", list)
+        }
+        list.Content = append(added, list.Content...)
+    }
+    return nil
+}
+
+// parameterComponent returns the parameter component of root that ref
+// points to, or nil.
+func parameterComponent(root *yaml.Node, ref string) *yaml.Node {
+    if !strings.HasPrefix(ref, parameterRefPrefix) { return nil }
+    name := strings.NewReplacer("~1", "/", "~0", "~").Replace(strings.TrimPrefix(ref, parameterRefPrefix))
+    return yamlnode.GetKey(yamlnode.GetKey(yamlnode.GetKey(root, "components"), "parameters"), name)
+}
+
+// parameterID identifies the parameter p of root as "in:name", following a
+// ref to a parameter component, or by the ref when its target is not known.
+func parameterID(root, p *yaml.Node) string {
+    ref := yamlnode.GetKey(p, "$ref")
+    if ref != nil {
+        if p = parameterComponent(root, ref.Value); p == nil { return ref.Value }
+    }
+    name, in := yamlnode.GetKey(p, "name"), yamlnode.GetKey(p, "in")
+    if name == nil || in == nil {
+        if ref != nil { return ref.Value }
+        return ""
+    }
+    return in.Value + ":" + name.Value
+}
+
+// matches reports whether the parameter applies to the path key.
+func (p commonParameter) matches(key string) bool {
+    if len(p.paths) == 0 { return true }
+    for _, re := range p.paths {
+        if re.MatchString(key) { return true }
+    }
+    return false
+}
+
+// copyNode deep-copies n, so the items a parameter is added to do not share
+// nodes that later steps (overlays) may change.
+func copyNode(n *yaml.Node) *yaml.Node {
+    c := *n
+    c.Content = make([]*yaml.Node, len(n.Content))
+    for i, ch := range n.Content {
+        c.Content[i] = copyNode(ch)
+    }
+    return &c
+}
@@ -0,0 +1,267 @@
+package indexer
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// String helpers similar to the JS version
+func KebabToCamel(s string) string {
+    // convert kebab-case to camelCase
+    out := ""
+    up := false
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        if c == '-' || c == '_' || c == ' ' {
+            up = true
+            continue
+        }
+        if up {
+            out += strings.ToUpper(string(c))
+            up = false
+        } else {
+            out += string(c)
+        }
+    }
+    return out
+}
+
+// PascalCase is KebabToCamel with the first letter upper-cased.
+func PascalCase(s string) string {
+    camel := KebabToCamel(s)
+    if camel == "" { return camel }
+    return strings.ToUpper(camel[:1]) + camel[1:]
+}
+
+// Path-key casing modes for --path-casing
+const (
+    PathCasingCamel    = "camel"    // legacy: file name tail camelCased, directories verbatim
+    PathCasingKebab    = "kebab"    // every static segment kebab-cased
+    PathCasingPreserve = "preserve" // segments used exactly as named on disk
+)
+
+// Version-segment handling for --path-version
+const (
+    PathVersionKeep  = "keep"  // the first directory (e.g. v1) leads the key
+    PathVersionStrip = "strip" // the first directory is dropped
+)
+
+// PathRewrite is a regexp replacement applied to derived path keys.
+type PathRewrite struct {
+    Pattern *regexp.Regexp
+    Replace string // may use $1-style group references
+}
+
+// ParsePathRewrite parses "pattern=>replacement".
+func ParsePathRewrite(s string) (PathRewrite, error) {
+    pattern, replace, ok := strings.Cut(s, "=>")
+    if !ok { return PathRewrite{}, fmt.Errorf("path rewrite %q: expected pattern=>replacement", s) }
+    re, err := regexp.Compile(strings.TrimSpace(pattern))
+    if err != nil { return PathRewrite{}, fmt.Errorf("path rewrite %q: %v", s, err) }
+    return PathRewrite{re, strings.TrimSpace(replace)}, nil
+}
+
+// ValidPathCasing reports whether c is one of the PathCasing modes.
+func ValidPathCasing(c string) bool {
+    return c == PathCasingCamel || c == PathCasingKebab || c == PathCasingPreserve
+}
+
+var reCamelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+var reNonKebab = regexp.MustCompile(`[^a-z0-9]+`)
+
+// toKebabCase converts a segment so that it satisfies the path-case-kebab rule.
+func toKebabCase(s string) string {
+    s = reCamelBoundary.ReplaceAllString(s, "$1-$2")
+    s = reNonKebab.ReplaceAllString(strings.ToLower(s), "-")
+    return strings.Trim(s, "-")
+}
+
+func casePathSegment(seg, casing string) string {
+    if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+        return seg
+    }
+    // transliterate first: the casing helpers operate on ASCII bytes
+    seg = transliterate(seg)
+    switch casing {
+    case PathCasingKebab:
+        return toKebabCase(seg)
+    case PathCasingPreserve:
+        return seg
+    default:
+        return KebabToCamel(seg)
+    }
+}
+
+// paramSegment turns the escaped path parameter forms [id] and @id, for file
+// systems and shells where braces are awkward, into {id}.
+func paramSegment(seg string) string {
+    switch {
+    case len(seg) > 2 && strings.HasPrefix(seg, "[") && strings.HasSuffix(seg, "]"):
+        return "{" + seg[1:len(seg)-1] + "}"
+    case len(seg) > 1 && strings.HasPrefix(seg, "@"):
+        return "{" + seg[1:] + "}"
+    }
+    return seg
+}
+
+// BuildPathKey derives the path key (e.g. /v1/users/getById) of the path
+// fragment at fullPath below pathsDir.
+func BuildPathKey(pathsDir, fullPath, casing string) string {
+    rel, err := filepath.Rel(pathsDir, fullPath)
+    if err != nil { return "" }
+    rel = filepath.ToSlash(rel)
+    segs := strings.Split(rel, "/")
+    if len(segs) == 0 { return "" }
+    file := segs[len(segs)-1]
+    segs = segs[:len(segs)-1]
+    nameNoExt := paramSegment(trimFragmentExt(file))
+    tail := sanitizePathSegment(casePathSegment(nameNoExt, casing))
+    for i, sg := range segs {
+        sg = paramSegment(sg)
+        if casing == PathCasingKebab {
+            sg = casePathSegment(sg, casing)
+        }
+        segs[i] = sanitizePathSegment(sg)
+    }
+    // Expect first segment to be version (e.g., v1)
+    if len(segs) == 0 {
+        // No version folder, just use tail at root
+        return "/" + tail
+    }
+    version := segs[0]
+    remainder := ""
+    if len(segs) > 1 {
+        remainder = strings.Join(segs[1:], "/")
+    }
+    if remainder != "" {
+        return "/" + version + "/" + remainder + "/" + tail
+    }
+    return "/" + version + "/" + tail
+}
+
+
+// HTTPMethods lists the operation keys of a path item in the order they are
+// emitted.
+var HTTPMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// PathKey returns the path key of the path fragment f and, with MethodFiles,
+// the HTTP method when f is a method file such as paths/v1/users/{id}/get.yaml,
+// whose path is its directory.
+//
+// A top-level x-path in the fragment replaces the derived key verbatim, for
+// keys file names cannot express (/v2/legal-entities/{id}:activate).
+func PathKey(cfg *Config, f string) (string, string) {
+    method := strings.ToLower(fileBaseName(f))
+    if !cfg.MethodFiles || !isHTTPMethod(method) { method = "" }
+    if key, ok := pathOverride(cfg, f); ok { return key, method }
+    if method == "" { return derivePathKey(cfg, f), "" }
+    dir := filepath.Dir(f)
+    if dir == cfg.PathsDir { return "/", method }
+    return derivePathKey(cfg, dir+filepath.Ext(f)), method
+}
+
+// pathKeyBase returns the directory path keys of the path fragment f are
+// derived below: PathsDir, or its PathStripPrefix directory when f is in it.
+func pathKeyBase(cfg *Config, f string) string {
+    if cfg.PathStripPrefix != "" {
+        if prefix := filepath.Join(cfg.PathsDir, filepath.FromSlash(cfg.PathStripPrefix)); IsWithin(prefix, f) { return prefix }
+    }
+    return cfg.PathsDir
+}
+
+// pathVersion returns the version directory of the path fragment f: the
+// first directory below its pathKeyBase, or "" for a file directly in it.
+func pathVersion(cfg *Config, f string) string {
+    rel, err := filepath.Rel(pathKeyBase(cfg, f), f)
+    if err != nil { return "" }
+    dir, _, found := strings.Cut(filepath.ToSlash(rel), "/")
+    if !found { return "" }
+    return dir
+}
+
+// PathVersions returns the version directories of the path fragments,
+// sorted, reporting fragments outside any version directory.
+func PathVersions(cfg *Config) ([]string, FragmentErrors, error) {
+    files, err := ListFragments(cfg, cfg.PathsDir)
+    if _, err := DiscoveryProblems(err); err != nil { return nil, nil, err }
+    seen := map[string]bool{}
+    var versions []string
+    var problems FragmentErrors
+    for _, f := range files {
+        v := pathVersion(cfg, f)
+        if v == "" {
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: "path fragment is not in a version directory (e.g. paths/v1/); --per-version builds one root per version"})
+            continue
+        }
+        if !seen[v] { versions = append(versions, v) }
+        seen[v] = true
+    }
+    sort.Strings(versions)
+    return versions, problems, nil
+}
+
+// rootPathFragments lists the path fragments the root is built from: all of
+// them, or those of the version directory Version.
+func rootPathFragments(cfg *Config) ([]string, error) {
+    files, err := ListFragments(cfg, cfg.PathsDir)
+    if err != nil || cfg.Version == "" { return files, err }
+    var in []string
+    for _, f := range files {
+        if pathVersion(cfg, f) == cfg.Version { in = append(in, f) }
+    }
+    return in, nil
+}
+
+// derivePathKey is BuildPathKey with the PathStripPrefix, PathVersion and
+// PathRewrites rules applied.
+func derivePathKey(cfg *Config, f string) string {
+    key := BuildPathKey(pathKeyBase(cfg, f), f, cfg.PathCasing)
+    if cfg.PathVersion == PathVersionStrip && len(key) > 1 {
+        if i := strings.Index(key[1:], "/"); i >= 0 { key = key[i+1:] }
+    }
+    for _, r := range cfg.PathRewrites {
+        key = r.Pattern.ReplaceAllString(key, r.Replace)
+    }
+    return key
+}
+
+// PathOverrideKey is the extension a path fragment sets its path key with.
+const PathOverrideKey = "x-path"
+
+// pathOverride returns the x-path of the fragment f, if it declares one.
+func pathOverride(cfg *Config, f string) (string, bool) {
+    raw, err := ReadFragment(cfg, f)
+    if err != nil || !strings.Contains(raw, PathOverrideKey) { return "", false }
+    doc, err := sharedFragmentDoc(cfg, f)
+    if err != nil { return "", false }
+    v := yamlnode.GetKey(yamlnode.DocRoot(doc), PathOverrideKey)
+    if v == nil || v.Kind != yaml.ScalarNode { return "", false }
+    return strings.TrimSpace(v.Value), true
+}
+
+// sortOperations orders the operations of a path item assembled from method
+// files by HTTPMethods.
+func sortOperations(item *yaml.Node) {
+    rank := func(k string) int {
+        for i, m := range HTTPMethods {
+            if m == k { return i }
+        }
+        return len(HTTPMethods)
+    }
+    pairs := make([][2]*yaml.Node, 0, len(item.Content)/2)
+    for i := 0; i+1 < len(item.Content); i += 2 {
+        pairs = append(pairs, [2]*yaml.Node{item.Content[i], item.Content[i+1]})
+    }
+    sort.SliceStable(pairs, func(i, j int) bool { return rank(pairs[i][0].Value) < rank(pairs[j][0].Value) })
+    item.Content = item.Content[:0]
+    for _, p := range pairs {
+        item.Content = append(item.Content, p[0], p[1])
+    }
+}
@@ -0,0 +1,346 @@
+package indexer
+
+import (
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// RefGraph is the $ref dependency graph of the input tree. Nodes are
+// "paths<path key>" (e.g. paths/v1/users) for path fragments, plus the
+// upper-cased method for method files (paths/v1/users GET),
+// "webhooks/<name>" for webhook fragments, "traits/<name>" for traits,
+// "events/channels/<name>" and "events/messages/<Name>" for event fragments
+// and "<section>/<Name>" for components; edges point from a fragment to the
+// components it references.
+type RefGraph struct {
+    Nodes []string             // path nodes in path key order, webhooks, traits and events by name, then components in section and name order
+    Files map[string]string    // node -> fragment file
+    Edges map[string][]RefEdge // node -> components it references, first ref of each
+}
+
+// RefEdge is a reference from one fragment to a component.
+type RefEdge struct {
+    To   string // target node
+    Line int    // line of the $ref in the referencing fragment
+}
+
+// BuildRefGraph resolves the $refs of every fragment (only the paths of
+// Version, when set) into a RefGraph. Refs
+// that do not resolve to a component fragment (remote, dangling or into
+// other files) are left out; unparsable fragments have no edges.
+func BuildRefGraph(cfg *Config) (*RefGraph, error) {
+    g := &RefGraph{Files: map[string]string{}, Edges: map[string][]RefEdge{}}
+    idx := buildComponentIndex(cfg)
+    names := BuildNameMaps(cfg)
+    byFile := map[string]string{} // fragment file, plus #/<key> for a definition -> node
+    defKeys := map[string]string{} // node -> definition key within its file
+    for section, m := range idx {
+        for name, e := range m {
+            node := section + "/" + name
+            target := filepath.Clean(e.File)
+            if e.Key != "" {
+                target += "#/" + e.Key
+                defKeys[node] = e.Key
+            }
+            byFile[target] = node
+        }
+    }
+
+    paths, err := rootPathFragments(cfg)
+    if err != nil { return nil, err }
+    keys, _ := pathKeys(cfg, paths)
+    var pathNodes []string
+    for f, key := range keys {
+        node := "paths" + key
+        if _, method := PathKey(cfg, f); method != "" { node += " " + strings.ToUpper(method) }
+        pathNodes = append(pathNodes, node)
+        g.Files[node] = f
+    }
+    sort.Strings(pathNodes)
+    g.Nodes = append(g.Nodes, pathNodes...)
+    hooks, err := ListFragments(cfg, cfg.WebhooksDir)
+    if err != nil { return nil, err }
+    hookNames, _ := webhookNames(cfg, hooks)
+    var hookNodes []string
+    for f, name := range hookNames {
+        node := "webhooks/" + name
+        hookNodes = append(hookNodes, node)
+        g.Files[node] = f
+    }
+    sort.Strings(hookNodes)
+    g.Nodes = append(g.Nodes, hookNodes...)
+    traits, err := ListFragments(cfg, cfg.TraitsDir)
+    if err != nil { return nil, err }
+    sort.Strings(traits)
+    for _, f := range traits {
+        rel, _ := filepath.Rel(cfg.TraitsDir, f)
+        node := "traits/" + trimFragmentExt(filepath.ToSlash(rel))
+        g.Nodes = append(g.Nodes, node)
+        g.Files[node] = f
+    }
+    channels, messages, err := eventFragments(cfg)
+    if err != nil { return nil, err }
+    chNames, _ := channelNames(cfg, channels)
+    msgNames, _ := messageNames(cfg, messages)
+    for _, f := range channels {
+        if name, ok := chNames[f]; ok {
+            g.Nodes = append(g.Nodes, "events/channels/"+name)
+            g.Files["events/channels/"+name] = f
+        }
+    }
+    for _, f := range messages {
+        if name, ok := msgNames[f]; ok {
+            g.Nodes = append(g.Nodes, "events/messages/"+name)
+            g.Files["events/messages/"+name] = f
+        }
+    }
+    for _, c := range Components {
+        var sectionNames []string
+        for name := range idx[c.Section] {
+            sectionNames = append(sectionNames, name)
+        }
+        sort.Strings(sectionNames)
+        for _, name := range sectionNames {
+            node := c.Section + "/" + name
+            g.Nodes = append(g.Nodes, node)
+            g.Files[node] = idx[c.Section][name].File
+        }
+    }
+
+    for _, node := range g.Nodes {
+        file := g.Files[node]
+        doc, errs := loadFragment(cfg, file)
+        if len(errs) > 0 { continue }
+        if key := defKeys[node]; key != "" { doc = yamlnode.GetKey(yamlnode.DocRoot(doc), key) }
+        seen := map[string]bool{}
+        for _, ref := range collectRefs(doc) {
+            to := byFile[refFile(file, ref.Value)]
+            if key, _ := splitPointer(refPointer(ref.Value)); key != "" {
+                target := refFile(file, ref.Value)
+                if strings.HasPrefix(ref.Value, "#/") { target = filepath.Clean(file) }
+                if n := byFile[target+"#/"+key]; n != "" { to = n }
+            }
+            if to == "" {
+                val := ref.Value
+                if !strings.HasPrefix(val, "#/components/") {
+                    r, ok := rewriteRefValue(val, names)
+                    if !ok { continue }
+                    val = r
+                }
+                parts := strings.SplitN(strings.TrimPrefix(val, "#/components/"), "/", 3)
+                if len(parts) < 2 { continue }
+                name := strings.NewReplacer("~1", "/", "~0", "~").Replace(parts[1])
+                if _, ok := idx[parts[0]][name]; !ok { continue }
+                to = parts[0] + "/" + name
+            }
+            if seen[to] { continue }
+            seen[to] = true
+            g.Edges[node] = append(g.Edges[node], RefEdge{To: to, Line: ref.Line})
+        }
+    }
+    return g, nil
+}
+
+// UnusedComponent is a component fragment no path, webhook or event fragment
+// reaches through $refs, directly or via other components.
+type UnusedComponent struct {
+    Section string // e.g. "schemas"
+    Name    string // component name, e.g. "LegacyUser"
+    File    string // fragment file
+    Shared  bool   // from SharedComponentsDir: left out of the root rather than reported
+}
+
+// IsEntryNode reports whether a RefGraph node is a path, webhook, trait or
+// event fragment, which no component references.
+func IsEntryNode(node string) bool {
+    return strings.HasPrefix(node, "paths/") || strings.HasPrefix(node, "webhooks/") || strings.HasPrefix(node, "traits/") || strings.HasPrefix(node, "events/")
+}
+
+// UnusedComponents lists the component fragments that are not reachable from
+// any path (of Version, when set), webhook or event fragment, sorted by section order and name. Security schemes are
+// never reported: operations name them in security requirements, not $refs.
+// StandardResponses and the components CommonParametersFile refers to count
+// as used, since every operation gets them.
+// Unparsable fragments are skipped; CheckFragments reports them.
+func UnusedComponents(cfg *Config) ([]UnusedComponent, error) {
+    g, err := BuildRefGraph(cfg)
+    if err != nil { return nil, err }
+    used := map[string]bool{}
+    var queue []string
+    for _, node := range g.Nodes {
+        if IsEntryNode(node) { queue = append(queue, node) }
+    }
+    seeds := commonParameterRefs(cfg)
+    for _, name := range cfg.StandardResponses {
+        seeds = append(seeds, "responses/"+name)
+    }
+    for _, node := range seeds {
+        if !used[node] {
+            used[node] = true
+            queue = append(queue, node)
+        }
+    }
+    for len(queue) > 0 {
+        node := queue[0]
+        queue = queue[1:]
+        for _, e := range g.Edges[node] {
+            if !used[e.To] {
+                used[e.To] = true
+                queue = append(queue, e.To)
+            }
+        }
+    }
+
+    skip := map[string]bool{"paths": true, "webhooks": true, "traits": true, "events": true}
+    for _, c := range Components {
+        if c.kind == kindSecurityScheme { skip[c.Section] = true }
+    }
+    var unused []UnusedComponent
+    for _, node := range g.Nodes {
+        section, name, _ := strings.Cut(node, "/")
+        if skip[section] || used[node] { continue }
+        unused = append(unused, UnusedComponent{section, name, g.Files[node], cfg.IsShared(g.Files[node])})
+    }
+    return unused, nil
+}
+
+// RefCycle is a circular chain of component refs. Each step refers to the
+// next one, and the last step back to the first.
+type RefCycle []RefStep
+
+// RefStep is one component of a RefCycle and the line of its ref to the next.
+type RefStep struct {
+    Node string
+    File string
+    Line int
+}
+
+// RefCycles reports one $ref cycle for every group of components that refer
+// to each other in a circle, including components referring to themselves.
+func RefCycles(cfg *Config) ([]RefCycle, error) {
+    g, err := BuildRefGraph(cfg)
+    if err != nil { return nil, err }
+    var cycles []RefCycle
+    for _, scc := range g.components() {
+        in := map[string]bool{}
+        for _, n := range scc {
+            in[n] = true
+        }
+        start := scc[0]
+        // Breadth-first from start within the group, until an edge leads back.
+        parent := map[string]RefEdge{}
+        visited := map[string]bool{start: true}
+        queue := []string{start}
+        var last string
+        var back RefEdge
+        for len(queue) > 0 && last == "" {
+            u := queue[0]
+            queue = queue[1:]
+            for _, e := range g.Edges[u] {
+                if e.To == start {
+                    last, back = u, e
+                    break
+                }
+                if in[e.To] && !visited[e.To] {
+                    visited[e.To] = true
+                    parent[e.To] = RefEdge{To: u, Line: e.Line}
+                    queue = append(queue, e.To)
+                }
+            }
+        }
+        if last == "" { continue } // single component without a self-reference
+        steps := []RefStep{{last, g.Files[last], back.Line}}
+        for n := last; n != start; {
+            p := parent[n]
+            steps = append([]RefStep{{p.To, g.Files[p.To], p.Line}}, steps...)
+            n = p.To
+        }
+        cycles = append(cycles, steps)
+    }
+    return cycles, nil
+}
+
+// components returns the strongly connected groups of component nodes
+// (Tarjan's algorithm), each sorted, in order of their first node.
+func (g *RefGraph) components() [][]string {
+    index := map[string]int{}
+    low := map[string]int{}
+    onStack := map[string]bool{}
+    var stack []string
+    var out [][]string
+    var strong func(v string)
+    strong = func(v string) {
+        index[v] = len(index)
+        low[v] = index[v]
+        stack = append(stack, v)
+        onStack[v] = true
+        for _, e := range g.Edges[v] {
+            if _, seen := index[e.To]; !seen {
+                strong(e.To)
+                low[v] = min(low[v], low[e.To])
+            } else if onStack[e.To] {
+                low[v] = min(low[v], index[e.To])
+            }
+        }
+        if low[v] == index[v] {
+            var scc []string
+            for {
+                w := stack[len(stack)-1]
+                stack = stack[:len(stack)-1]
+                onStack[w] = false
+                scc = append(scc, w)
+                if w == v { break }
+            }
+            sort.Strings(scc)
+            out = append(out, scc)
+        }
+    }
+    for _, n := range g.Nodes {
+        if IsEntryNode(n) { continue }
+        if _, seen := index[n]; !seen { strong(n) }
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+    return out
+}
+
+// refFile returns the cleaned path of the file a file ref in from points at,
+// or "" for local, pseudo and remote refs.
+func refFile(from, ref string) string {
+    low := strings.ToLower(ref)
+    if strings.HasPrefix(ref, "#") || strings.HasPrefix(low, "schema:") || strings.HasPrefix(low, "param:") ||
+        strings.HasPrefix(low, "http://") || strings.HasPrefix(low, "https://") {
+        return ""
+    }
+    target, _ := splitRefPointer(ref)
+    target = filepath.FromSlash(strings.ReplaceAll(target, "\\", "/"))
+    if !filepath.IsAbs(target) { target = filepath.Join(filepath.Dir(from), target) }
+    return filepath.Clean(target)
+}
+
+// refPointer returns the JSON pointer of a ref, "" when it has none.
+func refPointer(ref string) string {
+    _, pointer := splitRefPointer(ref)
+    return pointer
+}
+
+// collectRefs returns every $ref value node below n, in document order.
+func collectRefs(n *yaml.Node) []*yaml.Node {
+    var refs []*yaml.Node
+    if n == nil { return nil }
+    if n.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if k, v := n.Content[i], n.Content[i+1]; k.Value == "$ref" && v.Kind == yaml.ScalarNode {
+                refs = append(refs, v)
+            }
+        }
+    }
+    for _, c := range n.Content {
+        refs = append(refs, collectRefs(c)...)
+    }
+    return refs
+}
@@ -0,0 +1,181 @@
+package indexer
+
+import (
+    "fmt"
+    "path/filepath"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/remote"
+)
+
+// NameMap maps the lower-cased paths (with and without extension, e.g.
+// billing/invoice and billing/invoice.yaml) of the component fragments of c,
+// relative to its directory, to their component names, for RewriteRefs.
+// The definitions of a definitions file are keyed "<path>#/<key>", with the
+// key as written.
+func NameMap(cfg *Config, c Component) map[string]string {
+    m := map[string]string{}
+    files, _ := ComponentFiles(cfg, c) // deterministic; case-only collisions are rejected by assignComponentNames
+    set := func(k, name string) {
+        if _, shadowed := m[k]; !shadowed { m[k] = name } // local fragments come first
+    }
+    for _, e := range componentEntries(cfg, c, files) {
+        base := strings.ToLower(componentPath(cfg, e.File))
+        if e.Key != "" {
+            set(base+"#/"+e.Key, e.Name)
+            continue
+        }
+        set(base, e.Name)
+        set(base+strings.ToLower(filepath.Ext(e.File)), e.Name)
+    }
+    return m
+}
+
+// NameMaps holds a NameMap per components section.
+type NameMaps map[string]map[string]string
+
+// BuildNameMaps returns the NameMap of every component directory.
+func BuildNameMaps(cfg *Config) NameMaps {
+    maps := NameMaps{}
+    for _, c := range Components {
+        maps[c.Section] = NameMap(cfg, c)
+    }
+    return maps
+}
+
+// lookup returns the component name of the fragment with base name base in
+// section, falling back to ComponentName for files that do not exist.
+func (maps NameMaps) lookup(section, base string) string {
+    if name := maps[section][strings.ToLower(base)]; name != "" { return name }
+    return ComponentName(base)
+}
+
+// splitRefPointer splits "file.yaml#/a/b" into "file.yaml" and "/a/b".
+func splitRefPointer(val string) (string, string) {
+    if i := strings.Index(val, "#"); i >= 0 {
+        return val[:i], val[i+1:]
+    }
+    return val, ""
+}
+
+// rewriteRefValue maps an external or pseudo ref onto the internal component
+// ref. ok is false when the value should be left untouched.
+func rewriteRefValue(val string, names NameMaps) (string, bool) {
+    // Already internal
+    if strings.HasPrefix(val, "#/components/") {
+        return "", false
+    }
+    // pseudo forms
+    low := strings.ToLower(val)
+    if strings.HasPrefix(low, "schema:") {
+        return "#/components/schemas/" + names.lookup("schemas", strings.TrimSpace(val[len("schema:"):])), true
+    }
+    if strings.HasPrefix(low, "param:") {
+        return "#/components/parameters/" + names.lookup("parameters", strings.TrimSpace(val[len("param:"):])), true
+    }
+    // file path style, optionally with a JSON pointer suffix (file.yaml#/properties/id);
+    // fragments authored on Windows may use backslashes
+    file, pointer := splitRefPointer(val)
+    file = strings.ReplaceAll(file, "\\", "/")
+    if m := reComponentPath.FindStringSubmatch(file); len(m) == 3 {
+        c, _ := componentForDir(m[1])
+        return componentRef(c, m[2], pointer, names), true
+    }
+    return "", false
+}
+
+// componentRef returns the internal ref for pointer into the fragment at path
+// (below the directory of c, without extension), or into the definition the
+// pointer starts with when the fragment is a definitions file.
+func componentRef(c Component, path, pointer string, names NameMaps) string {
+    if key, rest := splitPointer(pointer); key != "" {
+        if name, ok := names[c.Section][strings.ToLower(path)+"#/"+key]; ok {
+            return "#/components/" + c.Section + "/" + name + rest
+        }
+    }
+    return "#/components/" + c.Section + "/" + names.lookup(c.Section, path) + pointer
+}
+
+// RewriteRefs rewrites every $ref in the tree, whether it appears in block
+// style, flow style ({ $ref: ... }) or inside sequences.
+func RewriteRefs(n *yaml.Node, names NameMaps) {
+    if n == nil { return }
+    if n.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            k, v := n.Content[i], n.Content[i+1]
+            if k.Value == "$ref" && v.Kind == yaml.ScalarNode {
+                if ref, ok := rewriteRefValue(v.Value, names); ok {
+                    v.Value = ref
+                    v.Tag = "!!str"
+                    v.Style = yaml.SingleQuotedStyle
+                }
+            }
+        }
+    }
+    for _, c := range n.Content {
+        RewriteRefs(c, names)
+    }
+}
+
+// rewriteFragmentRefs is RewriteRefs for the fragment file: file refs are
+// resolved against file first, so sibling refs such as ./common.yaml#/Money
+// and refs into component subdirectories map onto their components too, and
+// http(s) refs point at their vendored copy when cfg.Vendor is set.
+func rewriteFragmentRefs(cfg *Config, file string, n *yaml.Node, names NameMaps) error {
+    for _, v := range collectRefs(n) {
+        if remote.IsRemote(v.Value) && cfg.Vendor != nil {
+            local, err := cfg.Vendor.Fetch(v.Value)
+            if err != nil { return fmt.Errorf("%s: $ref %q: %v", DisplayPath(cfg, file), v.Value, err) }
+            _, pointer := splitRefPointer(v.Value)
+            v.Value = relFrom(filepath.Dir(cfg.RootPath), local)
+            if pointer != "" { v.Value += "#" + pointer }
+            continue
+        }
+        ref, ok := rewriteRefValue(v.Value, names)
+        if target := refFile(file, v.Value); target != "" {
+            c, within := componentOf(cfg, target)
+            if !within { continue }
+            _, pointer := splitRefPointer(v.Value)
+            ref, ok = componentRef(c, componentPath(cfg, target), pointer, names), true
+        }
+        if ok {
+            v.Value = ref
+            v.Tag = "!!str"
+            v.Style = yaml.SingleQuotedStyle
+        }
+    }
+    return nil
+}
+
+// joinFragment parses a fragment for inlining into the joined root: merge
+// keys are resolved (unless MergeKeys is preserve) and refs are rewritten.
+func joinFragment(cfg *Config, file string, names NameMaps) (*yaml.Node, error) {
+    doc, err := fragmentDoc(cfg, file)
+    if err != nil { return nil, err }
+    body := yamlnode.DocRoot(doc)
+    if body == nil {
+        return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}, nil
+    }
+    if cfg.MergeKeys == MergeKeysPreserve {
+        untagMergeKeys(body)
+    } else {
+        resolveMergeKeys(body)
+    }
+    if c, ok := componentOf(cfg, file); ok {
+        if keys := definitionKeys(c.kind, body); keys != nil { rewriteDefinitionRefs(body, c.Section, keys) }
+    }
+    if err := rewriteFragmentRefs(cfg, file, body, names); err != nil { return nil, err }
+    if IsWithin(cfg.PathsDir, file) { yamlnode.DeleteKey(body, PathOverrideKey) }
+    if isJSONFragment(file) { yamlnode.BlockStyle(body) }
+    // Comments before or after the document belong to the fragment's value.
+    if doc.HeadComment != "" {
+        body.HeadComment = strings.TrimSpace(doc.HeadComment + "\n" + body.HeadComment)
+    }
+    if doc.FootComment != "" {
+        body.FootComment = strings.TrimSpace(body.FootComment + "\n" + doc.FootComment)
+    }
+    return body, nil
+}
@@ -0,0 +1,67 @@
+package indexer
+
+import (
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+var reResponseCode = regexp.MustCompile(`^([1-5][0-9][0-9]|[1-5]XX|default)$`)
+
+// ValidResponseCode reports whether code is a status code, a range such as
+// 4XX, or default.
+func ValidResponseCode(code string) bool { return reResponseCode.MatchString(code) }
+
+// AddStandardResponses gives every operation of root's paths and webhooks
+// a $ref to the components/responses entry std holds for each status code
+// it does not define. Items and operations that are still $refs are left
+// alone; a response std names that root does not define is an error.
+func AddStandardResponses(root *yaml.Node, std map[string]string) error {
+    if len(std) == 0 { return nil }
+    codes := make([]string, 0, len(std))
+    var missing []string
+    defined := yamlnode.GetKey(yamlnode.GetKey(root, "components"), "responses")
+    for code, name := range std {
+        codes = append(codes, code)
+        if yamlnode.GetKey(defined, name) == nil { missing = append(missing, code+": "+name) }
+    }
+    if len(missing) > 0 {
+        sort.Strings(missing)
+        return fmt.Errorf("standard responses not in components/responses: %s", strings.Join(missing, ", "))
+    }
+    // Codes in string order (4XX after 499), default last.
+    sort.Slice(codes, func(i, j int) bool {
+        if (codes[i] == "default") != (codes[j] == "default") { return codes[j] == "default" }
+        return codes[i] < codes[j]
+    })
+    for _, key := range []string{"paths", "webhooks"} {
+        items := yamlnode.GetKey(root, key)
+        if items == nil || items.Kind != yaml.MappingNode { continue }
+        for i := 1; i < len(items.Content); i += 2 {
+            item := items.Content[i]
+            if item.Kind != yaml.MappingNode || yamlnode.GetKey(item, "$ref") != nil { continue }
+            for _, m := range HTTPMethods {
+                op := yamlnode.GetKey(item, m)
+                if op == nil || op.Kind != yaml.MappingNode || yamlnode.GetKey(op, "$ref") != nil { continue }
+                responses := yamlnode.GetKey(op, "responses")
+                if responses == nil {
+                    responses = yamlnode.Map()
+                    yamlnode.SetKey(op, "responses", responses)
+                }
+                if responses.Kind != yaml.MappingNode { continue }
+                for _, code := range codes {
+                    if yamlnode.GetKey(responses, code) != nil { continue }
+                    ref := refNode("#/components/responses/" + pointerToken(std[code]))
+                    ref.Style = yaml.FlowStyle
+                    yamlnode.SetKey(responses, code, ref)
+                }
+            }
+        }
+    }
+    return nil
+}
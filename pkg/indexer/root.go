@@ -0,0 +1,207 @@
+package indexer
+
+import (
+    "fmt"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/dialect"
+)
+
+// escapeRefPath percent-encodes characters that would change the meaning of a
+// $ref URI, such as '#' (fragment) or '?' (query) in a file name, or are not
+// allowed in one (' ', '"').
+var refPathEscaper = strings.NewReplacer("%", "%25", " ", "%20", "#", "%23", "?", "%3F", `"`, "%22")
+
+func escapeRefPath(p string) string { return refPathEscaper.Replace(p) }
+
+// fileURL converts an absolute path (including C:\ drive paths and
+// \\server\share UNC paths, whose server becomes the host) to a file:// URL.
+func fileURL(p string) string {
+    p = filepath.ToSlash(p)
+    vol := filepath.VolumeName(p)
+    if strings.HasPrefix(vol, "//") { return "file:" + escapeRefPath(p) }
+    if vol != "" && !strings.HasPrefix(p, "/") {
+        p = "/" + p
+    }
+    return "file://" + escapeRefPath(p)
+}
+
+func relFrom(baseDir, target string) string {
+    rel, err := filepath.Rel(baseDir, target)
+    if err != nil {
+        return fileURL(target)
+    }
+    rel = escapeRefPath(filepath.ToSlash(rel))
+    if rel == "" || rel == "." {
+        return "./"
+    }
+    if strings.HasPrefix(rel, ".") || strings.HasPrefix(rel, "/") {
+        return rel
+    }
+    return "./" + rel
+}
+
+// fragmentRef returns the $ref from rootDir to the fragment file f or, for a
+// non-empty key, to the definition under key in it.
+func fragmentRef(rootDir, f, key string) string {
+    ref := relFrom(rootDir, f)
+    if key != "" { ref += "#/" + escapeRefPath(pointerToken(key)) }
+    return ref
+}
+
+func refNode(ref string) *yaml.Node {
+    n := yamlnode.Map()
+    yamlnode.SetKey(n, "$ref", yamlnode.Str(ref))
+    return n
+}
+
+// buildRootNode assembles the root document, using entry to produce the value
+// for each path and component fragment file, or for the definition under key
+// in a definitions file.
+func buildRootNode(cfg *Config, entry func(file, key string) (*yaml.Node, error)) (*yaml.Node, error) {
+    paths, err := rootPathFragments(cfg)
+    if err != nil { return nil, err }
+    sort.Strings(paths) // stable ordering
+    pathKeys, err := assignPathKeys(cfg, paths)
+    if err != nil { return nil, err }
+
+    root, errs := rootHeaderNode(cfg)
+    if len(errs) > 0 { return nil, FragmentErrors(errs) }
+    section := func(files []string, names map[string]string) (*yaml.Node, error) {
+        m := yamlnode.Map()
+        for _, f := range files {
+            v, err := entry(f, "")
+            if err != nil { return nil, err }
+            yamlnode.SetKey(m, names[f], v)
+        }
+        return m, nil
+    }
+
+    pathsNode := yamlnode.Map()
+    for _, f := range paths {
+        v, err := entry(f, "")
+        if err != nil { return nil, err }
+        key := pathKeys[f]
+        if _, method := PathKey(cfg, f); method != "" {
+            item := yamlnode.GetKey(pathsNode, key)
+            if item == nil {
+                item = yamlnode.Map()
+                yamlnode.SetKey(pathsNode, key, item)
+            }
+            yamlnode.SetKey(item, method, v)
+            sortOperations(item)
+            continue
+        }
+        yamlnode.SetKey(pathsNode, key, v)
+    }
+    var dirTags map[string]string
+    if cfg.AutoTags {
+        var tags []*yaml.Node
+        if dirTags, tags, err = DirTags(cfg); err != nil { return nil, err }
+        addDirTags(root, tags) // ahead of paths, like a tags.yaml list
+    }
+    yamlnode.SetKey(root, "paths", pathsNode)
+    if cfg.AutoTags { TagOperations(root, dirTags) }
+
+    hooks, err := ListFragments(cfg, cfg.WebhooksDir)
+    if err != nil { return nil, err }
+    if len(hooks) > 0 {
+        if cfg.OpenAPIVersion != OpenAPI31 {
+            return nil, fmt.Errorf("%s: webhooks need an OpenAPI 3.1 root; build with --openapi-version 3.1", DisplayPath(cfg, cfg.WebhooksDir))
+        }
+        sort.Strings(hooks)
+        hookNames, err := assignWebhookNames(cfg, hooks)
+        if err != nil { return nil, err }
+        hooksNode, err := section(hooks, hookNames)
+        if err != nil { return nil, err }
+        yamlnode.SetKey(root, "webhooks", hooksNode)
+    }
+
+    pruned := map[string]bool{}
+    if cfg.Prune || cfg.SharedComponentsDir != "" || cfg.Version != "" {
+        unused, err := UnusedComponents(cfg)
+        if err != nil { return nil, err }
+        for _, u := range unused {
+            if cfg.Prune || u.Shared || cfg.Version != "" { pruned[u.Section+"/"+u.Name] = true }
+        }
+    }
+
+    components := yamlnode.Map()
+    for _, c := range Components {
+        files, err := ComponentFiles(cfg, c)
+        if err != nil { return nil, err }
+        entries, err := assignComponentNames(cfg, c, files)
+        if err != nil { return nil, err }
+        node := yamlnode.Map()
+        for _, e := range entries {
+            if pruned[c.Section+"/"+e.Name] { continue }
+            v, err := entry(e.File, e.Key)
+            if err != nil { return nil, err }
+            yamlnode.SetKey(node, e.Name, v)
+        }
+        if len(node.Content) == 0 && !c.always { continue }
+        yamlnode.SetKey(components, c.Section, node)
+    }
+    yamlnode.SetKey(root, "components", components)
+    if err := ApplyTraits(cfg, root); err != nil { return nil, err }
+    if err := AddStandardResponses(root, cfg.StandardResponses); err != nil { return nil, err }
+    if err := AddCommonParameters(cfg, root); err != nil { return nil, err }
+    return root, nil
+}
+
+// RenderRoot returns the root BuildRoot writes, with refs relative to
+// RootPath, without writing anything.
+func RenderRoot(cfg *Config) ([]byte, error) {
+    preloadFragments(cfg)
+    var root *yaml.Node
+    var err error
+    if cfg.Join {
+        root, err = joinedRootNode(cfg)
+    } else {
+        root, err = referenceRootNode(cfg)
+    }
+    if err != nil { return nil, err }
+    if cfg.Format == FormatJSON { return yamlnode.MarshalJSON(root) }
+    return yamlnode.Marshal(root)
+}
+
+// referenceRootNode builds the aggregated root (with $ref entries) as a
+// yaml.v3 node tree, so keys and refs needing quoting are always emitted as
+// valid YAML. OpenAPI has no $ref for Operation objects, so method files are
+// joined into their path item instead, as traits are.
+func referenceRootNode(cfg *Config) (*yaml.Node, error) {
+    rootDir := filepath.Dir(cfg.RootPath)
+    var names NameMaps
+    if cfg.MethodFiles { names = BuildNameMaps(cfg) }
+    return buildRootNode(cfg, func(f, key string) (*yaml.Node, error) {
+        if _, method := PathKey(cfg, f); method != "" && IsWithin(cfg.PathsDir, f) { return joinFragment(cfg, f, names) }
+        return refNode(fragmentRef(rootDir, f, key)), nil
+    })
+}
+
+// joinedRootNode builds the joined/inlined root. Every fragment is parsed and
+// grafted into the root's node tree, so block scalars, quoting, flow style
+// and comments survive.
+func joinedRootNode(cfg *Config) (*yaml.Node, error) {
+    names := BuildNameMaps(cfg)
+    root, err := buildRootNode(cfg, func(f, key string) (*yaml.Node, error) {
+        body, err := joinFragment(cfg, f, names)
+        if err != nil || key == "" { return body, err }
+        return yamlnode.GetKey(body, key), nil
+    })
+    if err != nil { return nil, err }
+    if cfg.OpenAPIVersion == OpenAPI31 { dialect.Upgrade(root) }
+    if cfg.Downconvert { dialect.Downgrade(root) }
+    for _, o := range cfg.Overlays {
+        if err := o.Apply(root); err != nil { return nil, err }
+    }
+    if cfg.Vars != nil {
+        if err := cfg.Vars.Expand(root); err != nil { return nil, err }
+    }
+    return root, nil
+}
@@ -0,0 +1,282 @@
+package indexer
+
+import (
+    "encoding/json"
+    "net/url"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/validate"
+)
+
+// writeTree creates the files (slash-separated paths relative to dir) with their content.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+    t.Helper()
+    for name, content := range files {
+        p := filepath.Join(dir, filepath.FromSlash(name))
+        if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil { t.Fatal(err) }
+        if err := os.WriteFile(p, []byte(content), 0o644); err != nil { t.Fatal(err) }
+    }
+}
+
+func TestRelFromUsesForwardSlashes(t *testing.T) {
+    base := filepath.Join(t.TempDir(), "api")
+    tests := []struct {
+        dir, target, want string
+    }{
+        {base, filepath.Join(base, "paths", "v1", "users.yaml"), "./paths/v1/users.yaml"},
+        {filepath.Join(base, "out"), filepath.Join(base, "components", "schemas", "User.yaml"), "../components/schemas/User.yaml"},
+        {filepath.Join(base, "out", "v1"), filepath.Join(base, "paths", "v1", "a b", "x#1.yaml"), "../../paths/v1/a%20b/x%231.yaml"},
+        {base, base, "./"},
+    }
+    for _, tt := range tests {
+        if got := relFrom(tt.dir, tt.target); got != tt.want {
+            t.Errorf("relFrom(%q, %q) = %q, want %q", tt.dir, tt.target, got, tt.want)
+        }
+    }
+}
+
+func TestFileURLUnix(t *testing.T) {
+    if runtime.GOOS == "windows" { t.Skip("Unix paths") }
+    tests := []struct{ path, want string }{
+        {"/srv/api/paths/v1/users.yaml", "file:///srv/api/paths/v1/users.yaml"},
+        {"/srv/my api/x#1.yaml", "file:///srv/my%20api/x%231.yaml"},
+    }
+    for _, tt := range tests {
+        if got := fileURL(tt.path); got != tt.want {
+            t.Errorf("fileURL(%q) = %q, want %q", tt.path, got, tt.want)
+        }
+    }
+}
+
+func TestRootRefsUseForwardSlashes(t *testing.T) {
+    dir := t.TempDir()
+    writeTree(t, dir, map[string]string{
+        "api/paths/v1/users/list.yaml": "get:\n  responses:\n    '200':\n      description: ok\n      content:\n        application/json:\n          schema:\n            $ref: ../../../components/schemas/User.yaml\n",
+        "api/components/schemas/User.yaml": "type: object\n",
+    })
+    for _, join := range []bool{false, true} {
+        cfg := NewConfig(filepath.Join(dir, "api"), filepath.Join(dir, "out", "nested"), "root.yaml")
+        cfg.Join = join
+        if _, err := BuildRoot(cfg); err != nil { t.Fatalf("join=%v: %v", join, err) }
+        b, err := os.ReadFile(cfg.RootPath)
+        if err != nil { t.Fatal(err) }
+        root := string(b)
+        if strings.Contains(root, `\`) { t.Errorf("join=%v: backslash in root:\n%s", join, root) }
+        if !join && !strings.Contains(root, "../../api/paths/v1/users/list.yaml") {
+            t.Errorf("reference root lacks the relative path ref:\n%s", root)
+        }
+    }
+}
+
+func TestRewriteRefValueAcceptsBackslashes(t *testing.T) {
+    tests := []struct{ ref, want string }{
+        {`..\..\components\schemas\User.yaml`, "#/components/schemas/User"},
+        {`..\components\schemas\User.yaml#/properties/id`, "#/components/schemas/User/properties/id"},
+        {`..\..\components\parameters\page-size.yaml`, "#/components/parameters/PageSize"},
+    }
+    for _, tt := range tests {
+        if got, ok := rewriteRefValue(tt.ref, nil); !ok || got != tt.want {
+            t.Errorf("rewriteRefValue(%q) = %q, %v, want %q", tt.ref, got, ok, tt.want)
+        }
+    }
+}
+
+// edgeNames are names that are misread as plain YAML scalars or change the
+// meaning of a $ref URI unless quoted or escaped.
+var edgeNames = []string{
+    "a~b", "a/b", "100%", "two words", "#hash", `"quoted"`, "it's", "key: value", "-neg", "?q", "- x", "? y",
+    "/v1/users/{id}:activate", "/v1/it's@home", "true", "1e3", "",
+}
+
+func TestEdgeCaseYAMLKeys(t *testing.T) {
+    m := yamlnode.Map()
+    for _, name := range edgeNames { yamlnode.SetKey(m, name, yamlnode.Str("v:"+name)) }
+    y, err := yamlnode.Marshal(m)
+    if err != nil { t.Fatal(err) }
+    var fromYAML map[string]string
+    if err := yaml.Unmarshal(y, &fromYAML); err != nil { t.Fatalf("%v in\n%s", err, y) }
+    j, err := yamlnode.MarshalJSON(m)
+    if err != nil { t.Fatal(err) }
+    var fromJSON map[string]string
+    if err := json.Unmarshal(j, &fromJSON); err != nil { t.Fatalf("%v in\n%s", err, j) }
+    for _, name := range edgeNames {
+        if fromYAML[name] != "v:"+name { t.Errorf("YAML key %q read back as %q", name, fromYAML[name]) }
+        if fromJSON[name] != "v:"+name { t.Errorf("JSON key %q read back as %q", name, fromJSON[name]) }
+    }
+}
+
+func TestFragmentRefEscaping(t *testing.T) {
+    dir := t.TempDir()
+    tests := []struct{ file, key, want string }{
+        {"odd #1 %.yaml", "", "./odd%20%231%20%25.yaml"},
+        {"a~b.yaml", "", "./a~b.yaml"},
+        {"-lead?.yaml", "", "./-lead%3F.yaml"},
+        {`it's "q": x.yaml`, "", "./it's%20%22q%22:%20x.yaml"},
+        {"defs.yaml", "a/b", "./defs.yaml#/a~1b"},
+        {"defs.yaml", "a~b", "./defs.yaml#/a~0b"},
+        {"defs.yaml", "~/", "./defs.yaml#/~0~1"},
+        {"defs.yaml", "two words#1%", "./defs.yaml#/two%20words%231%25"},
+        {"defs.yaml", "key: value", "./defs.yaml#/key:%20value"},
+        {"defs.yaml", "?q", "./defs.yaml#/%3Fq"},
+        {"defs.yaml", "-neg", "./defs.yaml#/-neg"},
+        {"defs.yaml", `"quoted"`, "./defs.yaml#/%22quoted%22"},
+    }
+    for _, tt := range tests {
+        got := fragmentRef(dir, filepath.Join(dir, tt.file), tt.key)
+        if got != tt.want { t.Errorf("fragmentRef(%q, %q) = %q, want %q", tt.file, tt.key, got, tt.want) }
+        // Resolvers unescape the URI, then the JSON pointer.
+        file, pointer := splitRefPointer(got)
+        if f, err := url.PathUnescape(file); err != nil || f != "./"+tt.file {
+            t.Errorf("file of %q unescapes to %q (%v), want %q", got, f, err, "./"+tt.file)
+        }
+        if tt.key == "" { continue }
+        p, err := url.PathUnescape(pointer)
+        if err != nil { t.Fatalf("pointer of %q: %v", got, err) }
+        if key, rest := splitPointer(p); key != tt.key || rest != "" {
+            t.Errorf("pointer of %q resolves to %q%s, want %q", got, key, rest, tt.key)
+        }
+    }
+}
+
+func TestSanitizePathSegment(t *testing.T) {
+    tests := []struct{ seg, want string }{
+        {"{id}:activate", "{id}:activate"},
+        {"it's@home", "it's@home"},
+        {"a~b", "a~b"},
+        {"#tag %", "tag"},
+        {"two words", "two-words"},
+        {"-lead?", "lead"},
+        {`say "hi"`, "say-hi"},
+        {"{user id}", "{user id}"},
+    }
+    for _, tt := range tests {
+        if got := sanitizePathSegment(tt.seg); got != tt.want {
+            t.Errorf("sanitizePathSegment(%q) = %q, want %q", tt.seg, got, tt.want)
+        }
+    }
+}
+
+func TestEdgeCaseNamesInRoot(t *testing.T) {
+    dir := t.TempDir()
+    op := "get:\n  responses:\n    '200':\n      description: ok\n"
+    files := map[string]string{
+        "api/paths/v1/users/{id}:activate.yaml": op,
+        "api/paths/v1/it's@home.yaml":           op,
+        "api/paths/v1/#tag %.yaml":              op,
+        "api/paths/v1/a~b.yaml":                 op,
+        "api/components/schemas/odd #1 %.yaml":  "type: string\n",
+        "api/components/schemas/-lead?.yaml":    "type: integer\n",
+        "api/components/schemas/it's: x.yaml":   "type: boolean\n",
+    }
+    wantPaths := map[string]string{
+        "/v1/users/{id}:activate": "../api/paths/v1/users/{id}:activate.yaml",
+        "/v1/it's@home":           "../api/paths/v1/it's@home.yaml",
+        "/v1/tag":                 "../api/paths/v1/%23tag%20%25.yaml",
+        "/v1/a~b":                 "../api/paths/v1/a~b.yaml",
+    }
+    wantSchemas := map[string]string{
+        "Odd1": "../api/components/schemas/odd%20%231%20%25.yaml",
+        "Lead": "../api/components/schemas/-lead%3F.yaml",
+        "ItSX": "../api/components/schemas/it's:%20x.yaml",
+    }
+    if runtime.GOOS == "windows" { // ':' and '?' are not allowed in file names
+        delete(files, "api/paths/v1/users/{id}:activate.yaml")
+        delete(files, "api/components/schemas/-lead?.yaml")
+        delete(files, "api/components/schemas/it's: x.yaml")
+        delete(wantPaths, "/v1/users/{id}:activate")
+        delete(wantSchemas, "Lead")
+        delete(wantSchemas, "ItSX")
+    }
+    writeTree(t, dir, files)
+    cfg := NewConfig(filepath.Join(dir, "api"), filepath.Join(dir, "out"), "root.yaml")
+    if _, err := BuildRoot(cfg); err != nil { t.Fatal(err) }
+
+    var root struct {
+        Paths      map[string]map[string]string
+        Components struct{ Schemas map[string]map[string]string }
+    }
+    b, err := os.ReadFile(cfg.RootPath)
+    if err != nil { t.Fatal(err) }
+    if err := yaml.Unmarshal(b, &root); err != nil { t.Fatalf("%v in\n%s", err, b) }
+    for key, ref := range wantPaths {
+        if got := root.Paths[key]["$ref"]; got != ref { t.Errorf("paths[%q].$ref = %q, want %q", key, got, ref) }
+    }
+    for name, ref := range wantSchemas {
+        if got := root.Components.Schemas[name]["$ref"]; got != ref { t.Errorf("schemas[%q].$ref = %q, want %q", name, got, ref) }
+    }
+
+    // The escaped refs resolve to the fragments.
+    bundled, err := bundle.Load(cfg.RootPath)
+    if err != nil { t.Fatal(err) }
+    for key := range wantPaths {
+        item := yamlnode.GetKey(yamlnode.GetKey(bundled, "paths"), key)
+        if yamlnode.GetKey(item, "get") == nil { t.Errorf("bundled paths[%q] has no get operation", key) }
+    }
+    for name := range wantSchemas {
+        schema := yamlnode.GetKey(yamlnode.GetKey(yamlnode.GetKey(bundled, "components"), "schemas"), name)
+        if yamlnode.GetKey(schema, "type") == nil { t.Errorf("bundled schemas[%q] was not resolved", name) }
+    }
+}
+
+func TestMethodFilesReferenceRootIsValid(t *testing.T) {
+    dir := t.TempDir()
+    writeTree(t, dir, map[string]string{
+        "api/paths/v1/users/{id}/get.yaml": `operationId: getUser
+parameters:
+  - $ref: ../../../../components/parameters/user-id.yaml
+responses:
+  '200':
+    description: ok
+    content:
+      application/json:
+        schema:
+          $ref: ../../../../components/schemas/User.yaml
+`,
+        "api/paths/v1/users/{id}/put.yaml": `operationId: putUser
+parameters:
+  - $ref: ../../../../components/parameters/user-id.yaml
+requestBody:
+  content:
+    application/json:
+      schema:
+        $ref: ../../../../components/schemas/User.yaml
+responses:
+  '204':
+    description: updated
+`,
+        "api/paths/v1/users.yaml": "get:\n  operationId: listUsers\n  responses:\n    '200':\n      description: ok\n",
+        "api/components/schemas/User.yaml": "type: object\nproperties:\n  id:\n    type: string\n",
+        "api/components/parameters/user-id.yaml": "name: id\nin: path\nrequired: true\nschema:\n  type: string\n",
+    })
+    cfg := NewConfig(filepath.Join(dir, "api"), filepath.Join(dir, "out"), "root.yaml")
+    cfg.MethodFiles = true
+    if _, err := BuildRoot(cfg); err != nil { t.Fatal(err) }
+
+    errs, err := validate.Structural(cfg.RootPath)
+    if err != nil { t.Fatal(err) }
+    for _, e := range errs { t.Errorf("structural check: %v", e) }
+
+    b, err := os.ReadFile(cfg.RootPath)
+    if err != nil { t.Fatal(err) }
+    var root struct{ Paths map[string]map[string]any }
+    if err := yaml.Unmarshal(b, &root); err != nil { t.Fatal(err) }
+    item := root.Paths["/v1/users/{id}"]
+    for _, method := range []string{"get", "put"} {
+        op, _ := item[method].(map[string]any)
+        if op == nil || op["$ref"] != nil || op["operationId"] == nil {
+            t.Errorf("%s of /v1/users/{id} is not an inlined operation: %v", method, op)
+        }
+    }
+    if !strings.Contains(string(b), "'#/components/schemas/User'") {
+        t.Errorf("inlined operations do not reference the User component:\n%s", b)
+    }
+    if root.Paths["/v1/users"]["$ref"] == nil { t.Errorf("path item file is not referenced: %v", root.Paths["/v1/users"]) }
+}
@@ -0,0 +1,31 @@
+package indexer
+
+import "testing"
+
+func TestFileURLWindows(t *testing.T) {
+    tests := []struct{ path, want string }{
+        {`C:\api\paths\v1\users.yaml`, "file:///C:/api/paths/v1/users.yaml"},
+        {`d:\my api\x#1.yaml`, "file:///d:/my%20api/x%231.yaml"},
+        {`\\server\share\api\users.yaml`, "file://server/share/api/users.yaml"},
+    }
+    for _, tt := range tests {
+        if got := fileURL(tt.path); got != tt.want {
+            t.Errorf("fileURL(%q) = %q, want %q", tt.path, got, tt.want)
+        }
+    }
+}
+
+func TestRelFromWindows(t *testing.T) {
+    tests := []struct{ dir, target, want string }{
+        {`C:\api`, `C:\api\paths\v1\users.yaml`, "./paths/v1/users.yaml"},
+        {`C:\api\out`, `c:\api\components\schemas\User.yaml`, "../components/schemas/User.yaml"},
+        {`C:\api`, `D:\shared\schemas\Money.yaml`, "file:///D:/shared/schemas/Money.yaml"},
+        {`\\server\share\api`, `\\server\share\api\paths\v1\users.yaml`, "./paths/v1/users.yaml"},
+        {`C:\api`, `\\server\share\schemas\Money.yaml`, "file://server/share/schemas/Money.yaml"},
+    }
+    for _, tt := range tests {
+        if got := relFrom(tt.dir, tt.target); got != tt.want {
+            t.Errorf("relFrom(%q, %q) = %q, want %q", tt.dir, tt.target, got, tt.want)
+        }
+    }
+}
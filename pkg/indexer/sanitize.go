@@ -0,0 +1,339 @@
+package indexer
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// Deterministic transliteration of common Latin letters with diacritics.
+var translitTable = map[rune]string{
+    'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a",
+    'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Ā': "A", 'Ą': "A",
+    'æ': "ae", 'Æ': "Ae", 'œ': "oe", 'Œ': "Oe", 'ß': "ss",
+    'ç': "c", 'ć': "c", 'č': "c", 'Ç': "C", 'Ć': "C", 'Č': "C",
+    'ď': "d", 'đ': "d", 'Ď': "D", 'Đ': "D",
+    'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+    'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ē': "E", 'Ę': "E", 'Ě': "E",
+    'ğ': "g", 'Ğ': "G",
+    'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+    'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I", 'Ī': "I", 'İ': "I",
+    'ł': "l", 'Ł': "L",
+    'ñ': "n", 'ń': "n", 'ň': "n", 'Ñ': "N", 'Ń': "N", 'Ň': "N",
+    'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+    'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O", 'Ō': "O", 'Ő': "O",
+    'ř': "r", 'Ř': "R",
+    'ś': "s", 'š': "s", 'ş': "s", 'Ś': "S", 'Š': "S", 'Ş': "S",
+    'ť': "t", 'ţ': "t", 'Ť': "T", 'Ţ': "T",
+    'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+    'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ū': "U", 'Ů': "U", 'Ű': "U",
+    'ý': "y", 'ÿ': "y", 'Ý': "Y",
+    'ź': "z", 'ż': "z", 'ž': "z", 'Ź': "Z", 'Ż': "Z", 'Ž': "Z",
+}
+
+func transliterate(s string) string {
+    var b strings.Builder
+    for _, r := range s {
+        if t, ok := translitTable[r]; ok {
+            b.WriteString(t)
+            continue
+        }
+        b.WriteRune(r)
+    }
+    return b.String()
+}
+
+// sanitizeWith transliterates s and replaces every rune rejected by keep with '-'.
+func sanitizeWith(s string, keep func(r rune) bool) string {
+    var b strings.Builder
+    for _, r := range transliterate(s) {
+        if keep(r) {
+            b.WriteRune(r)
+        } else {
+            b.WriteByte('-')
+        }
+    }
+    return b.String()
+}
+
+func isASCIIAlnum(r rune) bool {
+    return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// ComponentName derives a component key (^[a-zA-Z0-9._-]+$) from a file base
+// name. It returns "" when nothing usable is left after sanitizing.
+func ComponentName(base string) string {
+    clean := sanitizeWith(base, func(r rune) bool { return isASCIIAlnum(r) || r == '.' || r == '_' || r == '-' })
+    return strings.Trim(PascalCase(clean), ".")
+}
+
+// Component naming strategies for --component-naming
+const (
+    ComponentNamingPascal   = "pascal"   // user-profile -> UserProfile
+    ComponentNamingCamel    = "camel"    // user-profile -> userProfile
+    ComponentNamingVerbatim = "verbatim" // user-profile -> user-profile
+)
+
+// ValidComponentNaming reports whether n is one of the ComponentNaming strategies.
+func ValidComponentNaming(n string) bool {
+    return n == ComponentNamingPascal || n == ComponentNamingCamel || n == ComponentNamingVerbatim
+}
+
+// ComponentNameFor returns the component name of the fragment file f: its
+// NameMap entry, else its path below the component directory in the
+// ComponentNaming style, subdirectories first and joined by
+// NamespaceSeparator (schemas/billing/invoice.yaml becomes BillingInvoice).
+func (cfg *Config) ComponentNameFor(f string) string {
+    if rel, err := filepath.Rel(cfg.InputDir, f); err == nil {
+        if name, ok := cfg.NameMap[filepath.ToSlash(rel)]; ok { return name }
+    }
+    var names []string
+    for i, part := range strings.Split(componentPath(cfg, f), "/") {
+        naming := cfg.ComponentNaming
+        if naming == ComponentNamingCamel && i > 0 { naming = ComponentNamingPascal }
+        name := componentNameFromBase(naming, part)
+        if name == "" { return "" }
+        names = append(names, name)
+    }
+    return strings.Join(names, cfg.NamespaceSeparator)
+}
+
+// componentPath returns the path of the component fragment f below its
+// component directory, with forward slashes and without extension
+// (billing/invoice), or its base name when f is outside every component directory.
+func componentPath(cfg *Config, f string) string {
+    if c, ok := componentOf(cfg, f); ok {
+        for _, dir := range cfg.componentDirs(c) {
+            if IsWithin(dir, f) {
+                if rel, err := filepath.Rel(dir, f); err == nil { return trimFragmentExt(filepath.ToSlash(rel)) }
+            }
+        }
+    }
+    return fileBaseName(f)
+}
+
+// componentNameFromBase applies a ComponentNaming style to a file base name.
+func componentNameFromBase(naming, base string) string {
+    switch naming {
+    case ComponentNamingCamel:
+        name := ComponentName(base)
+        if name == "" { return "" }
+        return strings.ToLower(name[:1]) + name[1:]
+    case ComponentNamingVerbatim:
+        clean := sanitizeWith(base, func(r rune) bool { return isASCIIAlnum(r) || r == '.' || r == '_' || r == '-' })
+        return strings.Trim(clean, ".-")
+    }
+    return ComponentName(base)
+}
+
+var (
+    reComponentKey       = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+    reNamespaceSeparator = regexp.MustCompile(`^[._-]*$`)
+)
+
+// ValidNamespaceSeparator reports whether sep keeps namespaced component names
+// valid component keys: it may only use '.', '_' and '-'.
+func ValidNamespaceSeparator(sep string) bool { return reNamespaceSeparator.MatchString(sep) }
+
+// checkNameMap reports NameMap entries that match no component fragment or
+// map to a name OpenAPI does not allow as a component key.
+func checkNameMap(cfg *Config) []FragmentError {
+    known := map[string]bool{}
+    for _, c := range Components {
+        files, _ := ComponentFiles(cfg, c)
+        for _, f := range files {
+            if rel, err := filepath.Rel(cfg.InputDir, f); err == nil { known[filepath.ToSlash(rel)] = true }
+        }
+    }
+    var problems []FragmentError
+    var files []string
+    for f := range cfg.NameMap {
+        files = append(files, f)
+    }
+    sort.Strings(files)
+    for _, f := range files {
+        switch name := cfg.NameMap[f]; {
+        case !known[f]:
+            problems = append(problems, FragmentError{File: f, Message: "nameMap entry matches no component fragment (keys are paths relative to the input dir, e.g. components/schemas/user.yaml)"})
+        case !reComponentKey.MatchString(name):
+            problems = append(problems, FragmentError{File: f, Message: fmt.Sprintf("nameMap name %q is not a valid component key (letters, digits, '.', '_' and '-')", name)})
+        }
+    }
+    return problems
+}
+
+// sanitizePathSegment keeps the characters RFC 3986 allows in a path segment
+// (unreserved, sub-delims, ':' and '@', plus braces for templated
+// parameters) in a derived path segment.
+func sanitizePathSegment(seg string) string {
+    if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+        return seg
+    }
+    clean := sanitizeWith(seg, func(r rune) bool { return isASCIIAlnum(r) || strings.ContainsRune("-._~!$&'()*+,;=:@{}", r) })
+    for strings.Contains(clean, "--") {
+        clean = strings.ReplaceAll(clean, "--", "-")
+    }
+    return strings.Trim(clean, "-")
+}
+
+func fileBaseName(file string) string {
+    return trimFragmentExt(filepath.Base(file))
+}
+
+// caseCollisions reports files whose paths differ only in letter case. Such
+// files overwrite each other on case-insensitive filesystems (macOS, Windows)
+// and collide in the lower-cased ref lookup tables.
+func caseCollisions(cfg *Config, sorted []string) []FragmentError {
+    var problems []FragmentError
+    seen := map[string]string{}
+    for _, f := range sorted {
+        k := strings.ToLower(f)
+        if prev, ok := seen[k]; ok {
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: "file name differs only in case from " + DisplayPath(cfg, prev)})
+            continue
+        }
+        seen[k] = f
+    }
+    return problems
+}
+
+// componentNames expands the fragment files of c into their components,
+// reporting files whose name cannot be derived, definitions whose key is not
+// a valid component name and names taken by another file or definition.
+func componentNames(cfg *Config, c Component, files []string) ([]componentEntry, []FragmentError) {
+    sorted := append([]string(nil), files...)
+    sortComponentFiles(cfg, sorted)
+    var entries []componentEntry
+    owner := map[string]componentEntry{}
+    problems := caseCollisions(cfg, sorted)
+    for _, e := range componentEntries(cfg, c, sorted) {
+        switch {
+        case e.Key != "" && !reComponentKey.MatchString(e.Key):
+            problems = append(problems, FragmentError{File: e.display(cfg), Message: fmt.Sprintf("definition %q is not a valid component name (letters, digits, '.', '_' and '-')", e.Key)})
+            continue
+        case e.Name == "":
+            problems = append(problems, FragmentError{File: e.display(cfg), Message: "cannot derive a component name; rename it using ASCII letters or digits"})
+            continue
+        }
+        if prev, ok := owner[e.Name]; ok {
+            if prev.Key == "" && e.Key == "" && strings.EqualFold(prev.File, e.File) { continue } // reported as a case collision
+            if cfg.IsShared(e.File) && !cfg.IsShared(prev.File) { continue } // shadowed by the local component
+            problems = append(problems, FragmentError{File: e.display(cfg), Message: fmt.Sprintf("component name %q is also derived from %s", e.Name, prev.display(cfg))})
+            continue
+        }
+        owner[e.Name] = e
+        entries = append(entries, e)
+    }
+    return entries, problems
+}
+
+// pathKeys maps each path fragment file to its path key, reporting files
+// whose key cannot be derived or is taken by another file. Method files share
+// their directory's key, one file per method.
+func pathKeys(cfg *Config, files []string) (map[string]string, []FragmentError) {
+    sorted := append([]string(nil), files...)
+    sort.Strings(sorted)
+    keys := map[string]string{}
+    owner := map[string]string{}
+    methodFiles := map[string]bool{} // path key -> built from method files
+    problems := caseCollisions(cfg, sorted)
+    for _, f := range sorted {
+        key, method := PathKey(cfg, f)
+        if _, ok := pathOverride(cfg, f); ok && (!strings.HasPrefix(key, "/") || strings.Contains(key, "//")) {
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: fmt.Sprintf("%s %q must be an absolute path such as /v1/users", PathOverrideKey, key)})
+            continue
+        }
+        if key == "" || strings.Contains(key, "//") || strings.HasSuffix(key, "/") && key != "/" {
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: fmt.Sprintf("cannot derive a valid path key (got %q); rename it using ASCII letters or digits", key)})
+            continue
+        }
+        if byMethod, seen := methodFiles[key]; seen && byMethod != (method != "") {
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: fmt.Sprintf("path key %q is derived from both a path item file and method files; use one layout per path", key)})
+            continue
+        }
+        slot := key
+        if method != "" { slot = key + " " + method }
+        if prev, ok := owner[slot]; ok {
+            if strings.EqualFold(prev, f) { continue } // reported as a case collision
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: fmt.Sprintf("path key %q is also derived from %s", key, DisplayPath(cfg, prev))})
+            continue
+        }
+        owner[slot] = f
+        methodFiles[key] = method != ""
+        keys[f] = key
+    }
+    return keys, problems
+}
+
+// webhookNames maps each webhook fragment file to its webhook name, the file
+// base name as on disk, reporting names taken by another file.
+func webhookNames(cfg *Config, files []string) (map[string]string, []FragmentError) {
+    sorted := append([]string(nil), files...)
+    sort.Strings(sorted)
+    names := map[string]string{}
+    owner := map[string]string{}
+    problems := caseCollisions(cfg, sorted)
+    for _, f := range sorted {
+        name := fileBaseName(f)
+        if prev, ok := owner[name]; ok {
+            if strings.EqualFold(prev, f) { continue } // reported as a case collision
+            problems = append(problems, FragmentError{File: DisplayPath(cfg, f), Message: fmt.Sprintf("webhook name %q is also used by %s", name, DisplayPath(cfg, prev))})
+            continue
+        }
+        owner[name] = f
+        names[f] = name
+    }
+    return names, problems
+}
+
+// assignComponentNames is componentNames, failing on any problem.
+func assignComponentNames(cfg *Config, c Component, files []string) ([]componentEntry, error) {
+    entries, problems := componentNames(cfg, c, files)
+    if len(problems) > 0 { return nil, nameError("invalid component file names", problems) }
+    return entries, nil
+}
+
+// assignWebhookNames is webhookNames, failing on any problem.
+func assignWebhookNames(cfg *Config, files []string) (map[string]string, error) {
+    names, problems := webhookNames(cfg, files)
+    if len(problems) > 0 { return nil, nameError("invalid webhook fragment names", problems) }
+    return names, nil
+}
+
+// assignPathKeys is pathKeys, failing on any problem.
+func assignPathKeys(cfg *Config, files []string) (map[string]string, error) {
+    keys, problems := pathKeys(cfg, files)
+    if len(problems) > 0 { return nil, nameError("invalid path fragment names", problems) }
+    return keys, nil
+}
+
+func nameError(title string, problems []FragmentError) error {
+    lines := make([]string, len(problems))
+    for i, p := range problems {
+        lines[i] = p.Error()
+    }
+    return fmt.Errorf("%s:\n  %s", title, strings.Join(lines, "\n  "))
+}
+
+// checkNames reports every path key, webhook and component name collision
+// in the input tree, across all fragment directories at once, and webhooks
+// when the root is OpenAPI 3.0.
+func checkNames(cfg *Config) []FragmentError {
+    files, _ := ListFragments(cfg, cfg.PathsDir)
+    _, problems := pathKeys(cfg, files)
+    hooks, _ := ListFragments(cfg, cfg.WebhooksDir)
+    if len(hooks) > 0 && cfg.OpenAPIVersion != OpenAPI31 {
+        problems = append(problems, FragmentError{File: DisplayPath(cfg, cfg.WebhooksDir),
+            Message: "webhooks need an OpenAPI 3.1 root; build with --openapi-version 3.1"})
+    }
+    _, errs := webhookNames(cfg, hooks)
+    problems = append(problems, errs...)
+    for _, c := range Components {
+        files, _ := ComponentFiles(cfg, c)
+        _, errs := componentNames(cfg, c, files)
+        problems = append(problems, errs...)
+    }
+    return append(problems, checkNameMap(cfg)...)
+}
@@ -0,0 +1,81 @@
+package indexer
+
+import (
+    "fmt"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// SecurityFile is the optional file at the input root holding the top-level
+// security requirements, e.g. "- BearerAuth: []".
+const SecurityFile = "security.yaml"
+
+var securitySchemeKeys = map[string]bool{
+    "$ref": true, "type": true, "description": true, "name": true, "in": true,
+    "scheme": true, "bearerFormat": true, "flows": true, "openIdConnectUrl": true,
+}
+
+var securitySchemeTypes = map[string]bool{"apiKey": true, "http": true, "oauth2": true, "openIdConnect": true}
+
+// loadSecurity returns the requirements sequence from SecurityFile, or nil
+// when the file does not exist.
+func loadSecurity(cfg *Config) (*yaml.Node, []FragmentError) {
+    reqs, name, errs := loadRootList(cfg, SecurityFile, "security")
+    if reqs == nil || len(errs) > 0 { return nil, errs }
+    for _, req := range reqs.Content {
+        if req.Kind != yaml.MappingNode {
+            errs = append(errs, FragmentError{File: name, Line: req.Line, Column: req.Column,
+                Message: fmt.Sprintf("security requirement must be a mapping of scheme names to scope lists, found %s", yamlnode.KindName(req))})
+            continue
+        }
+        for i := 0; i+1 < len(req.Content); i += 2 {
+            if v := req.Content[i+1]; v.Kind != yaml.SequenceNode {
+                errs = append(errs, FragmentError{File: name, Line: v.Line, Column: v.Column,
+                    Message: fmt.Sprintf("scopes of %q must be a sequence (use [] for none)", req.Content[i].Value)})
+            }
+        }
+    }
+    if len(errs) > 0 { return nil, errs }
+    return reqs, nil
+}
+
+// checkHeader reports problems in the header files, including security
+// requirements naming a scheme with no fragment in components/security-schemes.
+func checkHeader(cfg *Config) []FragmentError {
+    _, paramErrs := loadCommonParameters(cfg)
+    root, errs := rootHeaderNode(cfg)
+    errs = append(errs, paramErrs...)
+    reqs := yamlnode.GetKey(root, "security")
+    if len(errs) > 0 || reqs == nil { return errs }
+    schemes := securitySchemesComponent()
+    files, _ := ComponentFiles(cfg, schemes)
+    known := map[string]bool{}
+    var names []string
+    for _, e := range componentEntries(cfg, schemes, files) {
+        known[e.Name] = true
+        names = append(names, e.Name)
+    }
+    sort.Strings(names)
+    name := DisplayPath(cfg, filepath.Join(cfg.InputDir, SecurityFile))
+    for _, req := range reqs.Content {
+        for i := 0; i+1 < len(req.Content); i += 2 {
+            k := req.Content[i]
+            if known[k.Value] { continue }
+            hint := "no security schemes are defined"
+            if len(names) > 0 { hint = "defined: " + strings.Join(names, ", ") }
+            errs = append(errs, FragmentError{File: name, Line: k.Line, Column: k.Column,
+                Message: fmt.Sprintf("unknown security scheme %q (%s)", k.Value, hint)})
+        }
+    }
+    return errs
+}
+
+func securitySchemesComponent() Component {
+    c, _ := componentForDir("security-schemes")
+    return c
+}
@@ -0,0 +1,199 @@
+package indexer
+
+import (
+    "fmt"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Strict mode: check the top-level shape of every fragment against the
+// OpenAPI object it will be emitted as.
+
+var pathItemKeys = map[string]bool{
+    "get": true, "put": true, "post": true, "delete": true, "options": true,
+    "head": true, "patch": true, "trace": true,
+    "parameters": true, "summary": true, "description": true, "servers": true, "$ref": true,
+}
+
+var operationKeys = map[string]bool{
+    "tags": true, "summary": true, "description": true, "externalDocs": true, "operationId": true,
+    "parameters": true, "requestBody": true, "responses": true, "callbacks": true, "deprecated": true,
+    "security": true, "servers": true,
+}
+
+var schemaKeys = map[string]bool{
+    "$ref": true, "title": true, "description": true, "type": true, "format": true,
+    "properties": true, "required": true, "items": true, "additionalProperties": true,
+    "allOf": true, "oneOf": true, "anyOf": true, "not": true, "discriminator": true,
+    "enum": true, "default": true, "example": true, "nullable": true, "readOnly": true,
+    "writeOnly": true, "deprecated": true, "xml": true, "externalDocs": true,
+    "multipleOf": true, "maximum": true, "exclusiveMaximum": true, "minimum": true,
+    "exclusiveMinimum": true, "maxLength": true, "minLength": true, "pattern": true,
+    "maxItems": true, "minItems": true, "uniqueItems": true, "maxProperties": true,
+    "minProperties": true,
+}
+
+// schemaKeys31 are the JSON Schema keywords OpenAPI 3.1 adds to Schema
+// Objects, allowed when targeting 3.1 or down-converting.
+var schemaKeys31 = map[string]bool{
+    "const": true, "examples": true, "$schema": true, "$id": true, "$defs": true, "$comment": true,
+    "prefixItems": true, "contains": true, "minContains": true, "maxContains": true,
+    "patternProperties": true, "propertyNames": true, "unevaluatedProperties": true, "unevaluatedItems": true,
+    "dependentRequired": true, "dependentSchemas": true, "if": true, "then": true, "else": true,
+    "contentMediaType": true, "contentEncoding": true,
+}
+
+var parameterKeys = map[string]bool{
+    "$ref": true, "name": true, "in": true, "description": true, "required": true,
+    "deprecated": true, "allowEmptyValue": true, "style": true, "explode": true,
+    "allowReserved": true, "schema": true, "example": true, "examples": true, "content": true,
+}
+
+var responseKeys = map[string]bool{
+    "$ref": true, "description": true, "headers": true, "content": true, "links": true,
+}
+
+var requestBodyKeys = map[string]bool{"$ref": true, "description": true, "content": true, "required": true}
+
+// headerKeys are the Parameter fields minus name and in, which a Header must not declare.
+var headerKeys = map[string]bool{
+    "$ref": true, "description": true, "required": true, "deprecated": true,
+    "allowEmptyValue": true, "style": true, "explode": true, "allowReserved": true,
+    "schema": true, "example": true, "examples": true, "content": true,
+}
+
+var exampleKeys = map[string]bool{"$ref": true, "summary": true, "description": true, "value": true, "externalValue": true}
+
+var parameterLocations = map[string]bool{"query": true, "header": true, "path": true, "cookie": true}
+
+// fragmentKind names the OpenAPI object a fragment directory holds.
+type fragmentKind string
+
+const (
+    kindPathItem    fragmentKind = "path item"
+    kindOperation   fragmentKind = "operation"
+    kindSchema      fragmentKind = "schema"
+    kindParameter   fragmentKind = "parameter"
+    kindResponse    fragmentKind = "response"
+    kindRequestBody fragmentKind = "request body"
+    kindHeader      fragmentKind = "header"
+    kindExample     fragmentKind = "example"
+    kindSecurityScheme fragmentKind = "security scheme"
+)
+
+// checkFragmentShape returns located problems for a fragment of the given
+// kind; allow31 admits the OpenAPI 3.1 schema keywords.
+func checkFragmentShape(file string, kind fragmentKind, doc *yaml.Node, allow31 bool) []FragmentError {
+    root := yamlnode.DocRoot(doc)
+    if root == nil {
+        return []FragmentError{{File: file, Message: fmt.Sprintf("empty fragment; expected a %s object", kind)}}
+    }
+    if root.Kind != yaml.MappingNode {
+        return []FragmentError{{File: file, Line: root.Line, Column: root.Column,
+            Message: fmt.Sprintf("expected a %s object (mapping), found %s", kind, yamlnode.KindName(root))}}
+    }
+    var errs []FragmentError
+    at := func(n *yaml.Node, format string, args ...interface{}) {
+        errs = append(errs, FragmentError{File: file, Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...)})
+    }
+    allowed := kindKeys[kind]
+    for i := 0; i+1 < len(root.Content); i += 2 {
+        k, v := root.Content[i], root.Content[i+1]
+        if strings.HasPrefix(k.Value, "x-") { continue }
+        if !allowed[k.Value] && !(allow31 && kind == kindSchema && schemaKeys31[k.Value]) {
+            hint := ""
+            if kind == kindSchema && v.Kind == yaml.MappingNode && yamlnode.GetKey(v, "type") != nil {
+                hint = " (this looks like one of several schemas in a single file; a definitions file may hold only named schemas)"
+            }
+            at(k, "unexpected key %q in %s fragment%s", k.Value, kind, hint)
+            continue
+        }
+        if kind == kindPathItem && isHTTPMethod(k.Value) && v.Kind != yaml.MappingNode {
+            at(v, "operation %q must be a mapping, found %s", k.Value, yamlnode.KindName(v))
+        }
+    }
+    if kind == kindParameter && yamlnode.GetKey(root, "$ref") == nil {
+        name, in := yamlnode.GetKey(root, "name"), yamlnode.GetKey(root, "in")
+        if name == nil { at(root, "parameter is missing required field \"name\"") }
+        if in == nil {
+            at(root, "parameter is missing required field \"in\"")
+        } else if !parameterLocations[in.Value] {
+            at(in, "parameter \"in\" must be one of query, header, path, cookie (got %q)", in.Value)
+        } else if in.Value == "path" {
+            if req := yamlnode.GetKey(root, "required"); req == nil || req.Value != "true" {
+                at(root, "path parameter must declare required: true")
+            }
+        }
+        if yamlnode.GetKey(root, "schema") == nil && yamlnode.GetKey(root, "content") == nil {
+            at(root, "parameter must define either \"schema\" or \"content\"")
+        }
+    }
+    return errs
+}
+
+func isHTTPMethod(k string) bool {
+    switch k {
+    case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+        return true
+    }
+    return false
+}
+
+// Component kind checks. Unlike the strict shape checks these always run: a
+// fragment in the wrong components directory yields a root that only fails
+// later in downstream tools.
+
+func looksLikeParameter(root *yaml.Node) bool {
+    return yamlnode.GetKey(root, "name") != nil && yamlnode.GetKey(root, "in") != nil
+}
+
+func looksLikeSchema(root *yaml.Node) bool {
+    for _, k := range []string{"type", "properties", "items", "allOf", "oneOf", "anyOf", "enum"} {
+        if yamlnode.GetKey(root, k) != nil { return true }
+    }
+    return false
+}
+
+// componentKindError explains why root cannot be emitted as kind, or "".
+func componentKindError(kind fragmentKind, root *yaml.Node) string {
+    if root == nil || root.Kind != yaml.MappingNode {
+        return fmt.Sprintf("expected a %s object (mapping)", kind)
+    }
+    if yamlnode.GetKey(root, "$ref") != nil { return "" }
+    switch kind {
+    case kindParameter:
+        if looksLikeParameter(root) { return "" }
+        if looksLikeSchema(root) {
+            return "this is a Schema object, not a Parameter (missing name/in); move it to components/schemas"
+        }
+        return "not a Parameter object: missing required fields name and in"
+    case kindSchema:
+        if looksLikeParameter(root) && (yamlnode.GetKey(root, "schema") != nil || yamlnode.GetKey(root, "content") != nil) {
+            return "this is a Parameter object, not a Schema; move it to components/parameters"
+        }
+    case kindResponse:
+        if yamlnode.GetKey(root, "description") != nil { return "" }
+        if looksLikeSchema(root) {
+            return "this is a Schema object, not a Response (missing description); move it to components/schemas"
+        }
+        return "not a Response object: missing required field description"
+    case kindRequestBody:
+        if yamlnode.GetKey(root, "content") != nil { return "" }
+        if looksLikeSchema(root) {
+            return "this is a Schema object, not a Request Body (missing content); move it to components/schemas or wrap it in content"
+        }
+        return "not a Request Body object: missing required field content"
+    case kindSecurityScheme:
+        if t := yamlnode.GetKey(root, "type"); t == nil || !securitySchemeTypes[t.Value] {
+            return "not a Security Scheme object: type must be apiKey, http, oauth2 or openIdConnect"
+        }
+    case kindHeader:
+        if looksLikeParameter(root) {
+            return "this is a Parameter object, not a Header (headers have no name/in); move it to components/parameters"
+        }
+    }
+    return ""
+}
@@ -0,0 +1,160 @@
+package indexer
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// DirTagFile, in a tag directory, holds the Tag object (description,
+// externalDocs, or a name replacing the derived one) of its operations
+// under AutoTags. It is never indexed as a fragment.
+const DirTagFile = "_tag.yaml"
+
+// tagDir returns the tag directory of the path fragment f: the first
+// directory below its version directory (paths/v1/accounts for
+// paths/v1/accounts/list.yaml), or "" when f is not that deep.
+func tagDir(cfg *Config, f string) string {
+    base := pathKeyBase(cfg, f)
+    rel, err := filepath.Rel(base, f)
+    if err != nil { return "" }
+    segs := strings.Split(filepath.ToSlash(rel), "/")
+    if len(segs) < 3 { return "" }
+    return filepath.Join(base, segs[0], segs[1])
+}
+
+// dirTagName derives a tag name from a directory name: payment-methods
+// gives "Payment Methods".
+func dirTagName(dir string) string {
+    words := strings.FieldsFunc(transliterate(dir), func(r rune) bool { return r == '-' || r == '_' || r == ' ' })
+    for i, w := range words {
+        words[i] = strings.ToUpper(w[:1]) + w[1:]
+    }
+    return strings.Join(words, " ")
+}
+
+// DirTagFiles lists the DirTagFile of every tag directory, so watchers can
+// notice edits to them.
+func DirTagFiles(cfg *Config) ([]string, error) {
+    files, err := rootPathFragments(cfg)
+    if _, err := DiscoveryProblems(err); err != nil { return nil, err }
+    seen := map[string]bool{}
+    var out []string
+    for _, f := range files {
+        dir := tagDir(cfg, f)
+        if dir == "" || seen[dir] { continue }
+        seen[dir] = true
+        if _, err := os.Stat(filepath.Join(dir, DirTagFile)); err == nil { out = append(out, filepath.Join(dir, DirTagFile)) }
+    }
+    sort.Strings(out)
+    return out, nil
+}
+
+// DirTags returns the tag of every path key whose fragments are in a tag
+// directory, and the Tag objects for those tags in name order. Directories
+// of different versions with the same name share a tag, described by the
+// first DirTagFile found.
+func DirTags(cfg *Config) (map[string]string, []*yaml.Node, error) {
+    paths, err := rootPathFragments(cfg)
+    if err != nil { return nil, nil, err }
+    sort.Strings(paths)
+    keys, err := assignPathKeys(cfg, paths)
+    if err != nil { return nil, nil, err }
+
+    byKey := map[string]string{}
+    byDir := map[string]string{}
+    objects := map[string]*yaml.Node{}
+    var problems FragmentErrors
+    for _, f := range paths {
+        dir := tagDir(cfg, f)
+        if dir == "" { continue }
+        name, ok := byDir[dir]
+        if !ok {
+            tag, errs := loadDirTag(cfg, dir)
+            problems = append(problems, errs...)
+            if tag == nil { continue }
+            name = yamlnode.GetKey(tag, "name").Value
+            byDir[dir] = name
+            if cur := objects[name]; cur == nil || len(cur.Content) == 2 && len(tag.Content) > 2 { objects[name] = tag }
+        }
+        byKey[keys[f]] = name
+    }
+    if len(problems) > 0 { return nil, nil, problems }
+    names := make([]string, 0, len(objects))
+    for name := range objects {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    tags := make([]*yaml.Node, len(names))
+    for i, name := range names {
+        tags[i] = objects[name]
+    }
+    return byKey, tags, nil
+}
+
+// loadDirTag returns the Tag object of the tag directory dir: its
+// DirTagFile with the name filled in, or just the derived name.
+func loadDirTag(cfg *Config, dir string) (*yaml.Node, []FragmentError) {
+    tag := yamlnode.Map()
+    path := filepath.Join(dir, DirTagFile)
+    if _, err := os.Stat(path); err == nil {
+        doc, errs := loadFragment(cfg, path)
+        if len(errs) > 0 { return nil, errs }
+        n := yamlnode.DocRoot(doc)
+        if n != nil && n.Kind != yaml.MappingNode {
+            return nil, []FragmentError{{File: DisplayPath(cfg, path), Line: n.Line, Column: n.Column,
+                Message: fmt.Sprintf("a tag must be a mapping, found %s", yamlnode.KindName(n))}}
+        }
+        if n != nil { tag = n }
+    }
+    if v := yamlnode.GetKey(tag, "name"); v == nil || v.Kind != yaml.ScalarNode || strings.TrimSpace(v.Value) == "" {
+        yamlnode.DeleteKey(tag, "name")
+        tag.Content = append([]*yaml.Node{yamlnode.Str("name"), yamlnode.Str(dirTagName(filepath.Base(dir)))}, tag.Content...)
+    }
+    return tag, nil
+}
+
+// addDirTags appends the Tag objects of tags not in the root's tags list.
+func addDirTags(root *yaml.Node, tags []*yaml.Node) {
+    if len(tags) == 0 { return }
+    list := yamlnode.GetKey(root, "tags")
+    if list == nil {
+        list = yamlnode.Seq()
+        yamlnode.SetKey(root, "tags", list)
+    }
+    listed := map[string]bool{}
+    for _, t := range list.Content {
+        if v := yamlnode.GetKey(t, "name"); v != nil { listed[v.Value] = true }
+    }
+    for _, t := range tags {
+        if !listed[yamlnode.GetKey(t, "name").Value] { list.Content = append(list.Content, t) }
+    }
+}
+
+// TagOperations gives the operations of every path item in root's paths
+// that have no tags the tag byKey holds for its key. Items and operations
+// that are still $refs are left alone.
+func TagOperations(root *yaml.Node, byKey map[string]string) {
+    paths := yamlnode.GetKey(root, "paths")
+    if paths == nil || paths.Kind != yaml.MappingNode { return }
+    for i := 0; i+1 < len(paths.Content); i += 2 {
+        name, ok := byKey[paths.Content[i].Value]
+        item := paths.Content[i+1]
+        if !ok || item.Kind != yaml.MappingNode || yamlnode.GetKey(item, "$ref") != nil { continue }
+        for _, m := range HTTPMethods {
+            op := yamlnode.GetKey(item, m)
+            if op == nil || op.Kind != yaml.MappingNode || yamlnode.GetKey(op, "$ref") != nil { continue }
+            if t := yamlnode.GetKey(op, "tags"); t != nil && len(t.Content) > 0 { continue }
+            tags := yamlnode.Seq()
+            tags.Style = yaml.FlowStyle
+            tags.Content = append(tags.Content, yamlnode.Str(name))
+            yamlnode.SetKey(op, "tags", tags)
+        }
+    }
+}
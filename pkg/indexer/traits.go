@@ -0,0 +1,148 @@
+package indexer
+
+import (
+    "fmt"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// TraitsKey lists the traits merged into an operation, or into every
+// operation of a path item. A trait is a fragment below TraitsDir holding
+// operation fields (parameters, responses, security, ...), named by its
+// path below TraitsDir without the extension.
+const TraitsKey = "x-traits"
+
+// HasTraits reports whether TraitsDir holds any trait.
+func HasTraits(cfg *Config) bool {
+    files, _ := ListFragments(cfg, cfg.TraitsDir)
+    return len(files) > 0
+}
+
+// loadTraits returns the traits by name, with their refs rewritten as in a
+// joined root.
+func loadTraits(cfg *Config) (map[string]*yaml.Node, error) {
+    files, err := ListFragments(cfg, cfg.TraitsDir)
+    if err != nil { return nil, err }
+    traits := map[string]*yaml.Node{}
+    if len(files) == 0 { return traits, nil }
+    names := BuildNameMaps(cfg)
+    for _, f := range files {
+        rel, err := filepath.Rel(cfg.TraitsDir, f)
+        if err != nil { return nil, err }
+        body, err := joinFragment(cfg, f, names)
+        if err != nil { return nil, err }
+        if body.Kind != yaml.MappingNode {
+            return nil, fmt.Errorf("%s: a trait must be a mapping of operation fields, found %s", DisplayPath(cfg, f), yamlnode.KindName(body))
+        }
+        traits[trimFragmentExt(filepath.ToSlash(rel))] = body
+    }
+    return traits, nil
+}
+
+// traitNames returns the names listed in the TraitsKey of n.
+func traitNames(n *yaml.Node) []string {
+    v := yamlnode.GetKey(n, TraitsKey)
+    if v == nil { return nil }
+    if v.Kind == yaml.ScalarNode { return []string{v.Value} }
+    var names []string
+    for _, c := range v.Content {
+        names = append(names, c.Value)
+    }
+    return names
+}
+
+// ApplyTraits merges the traits named by the operations and path items of
+// root's paths and webhooks into their operations and removes TraitsKey.
+// The operation's own fields win, then its traits in order, then those of
+// its path item: parameters (by name and location) and tags are added when
+// missing, as are the entries of mappings such as responses, and other
+// fields are set when absent. Items and operations that are still $refs
+// are left alone.
+func ApplyTraits(cfg *Config, root *yaml.Node) error {
+    var traits map[string]*yaml.Node
+    var problems []string
+    for _, key := range []string{"paths", "webhooks"} {
+        items := yamlnode.GetKey(root, key)
+        if items == nil || items.Kind != yaml.MappingNode { continue }
+        for i := 0; i+1 < len(items.Content); i += 2 {
+            item := items.Content[i+1]
+            if item.Kind != yaml.MappingNode || yamlnode.GetKey(item, "$ref") != nil { continue }
+            itemTraits := traitNames(item)
+            yamlnode.DeleteKey(item, TraitsKey)
+            for _, m := range HTTPMethods {
+                op := yamlnode.GetKey(item, m)
+                if op == nil || op.Kind != yaml.MappingNode || yamlnode.GetKey(op, "$ref") != nil { continue }
+                names := append(traitNames(op), itemTraits...)
+                yamlnode.DeleteKey(op, TraitsKey)
+                if len(names) == 0 { continue }
+                if traits == nil {
+                    var err error
+                    if traits, err = loadTraits(cfg); err != nil { return err }
+                }
+                for _, name := range names {
+                    trait, ok := traits[name]
+                    if !ok {
+                        problems = append(problems, fmt.Sprintf("%s %s %s: unknown trait %q (%s)", key[:len(key)-1], strings.ToUpper(m), items.Content[i].Value, name, knownTraits(cfg, traits)))
+                        continue
+                    }
+                    mergeTrait(root, op, trait)
+                }
+            }
+        }
+    }
+    if len(problems) > 0 { return fmt.Errorf("traits:\n  %s", strings.Join(problems, "\n  ")) }
+    return nil
+}
+
+func knownTraits(cfg *Config, traits map[string]*yaml.Node) string {
+    if len(traits) == 0 { return "no traits in " + DisplayPath(cfg, cfg.TraitsDir) }
+    var names []string
+    for name := range traits {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return "traits: " + strings.Join(names, ", ")
+}
+
+// mergeTrait adds the fields of trait that op does not have to op.
+func mergeTrait(root, op, trait *yaml.Node) {
+    for i := 0; i+1 < len(trait.Content); i += 2 {
+        k, v := trait.Content[i].Value, trait.Content[i+1]
+        cur := yamlnode.GetKey(op, k)
+        switch {
+        case cur == nil:
+            yamlnode.SetKey(op, k, copyNode(v))
+        case k == "parameters" && cur.Kind == yaml.SequenceNode && v.Kind == yaml.SequenceNode:
+            have := map[string]bool{}
+            for _, p := range cur.Content {
+                have[parameterID(root, p)] = true
+            }
+            for _, p := range v.Content {
+                if id := parameterID(root, p); !have[id] {
+                    have[id] = true
+                    cur.Content = append(cur.Content, copyNode(p))
+                }
+            }
+        case k == "tags" && cur.Kind == yaml.SequenceNode && v.Kind == yaml.SequenceNode:
+            have := map[string]bool{}
+            for _, t := range cur.Content {
+                have[t.Value] = true
+            }
+            for _, t := range v.Content {
+                if !have[t.Value] {
+                    have[t.Value] = true
+                    cur.Content = append(cur.Content, copyNode(t))
+                }
+            }
+        case cur.Kind == yaml.MappingNode && v.Kind == yaml.MappingNode:
+            for j := 0; j+1 < len(v.Content); j += 2 {
+                if yamlnode.GetKey(cur, v.Content[j].Value) == nil { yamlnode.SetKey(cur, v.Content[j].Value, copyNode(v.Content[j+1])) }
+            }
+        }
+    }
+}
@@ -0,0 +1,189 @@
+// Package insomnia converts a Postman collection, as exported by
+// pkg/postman, into an Insomnia v4 export: a workspace with a base
+// environment, a sub-environment holding the credentials, a request group
+// per folder and the requests.
+//
+// Resource IDs are derived from the workspace name and the resource's
+// place in it, so re-importing an export updates the requests rather than
+// duplicating them.
+package insomnia
+
+import (
+    "crypto/sha1"
+    "encoding/hex"
+    "regexp"
+
+    "github.com/bilbo290/oas-indexer/pkg/postman"
+)
+
+// Export is an Insomnia export file.
+type Export struct {
+    Type      string     `json:"_type"`
+    Format    int        `json:"__export_format"`
+    Source    string     `json:"__export_source"`
+    Resources []Resource `json:"resources"`
+}
+
+// Resource is a workspace, environment, request group or request; the
+// fields used depend on Type.
+type Resource struct {
+    ID             string                 `json:"_id"`
+    Type           string                 `json:"_type"`
+    ParentID       *string                `json:"parentId"`
+    Name           string                 `json:"name"`
+    Description    string                 `json:"description,omitempty"`
+    Scope          string                 `json:"scope,omitempty"`
+    Data           map[string]string      `json:"data,omitempty"`
+    Method         string                 `json:"method,omitempty"`
+    URL            string                 `json:"url,omitempty"`
+    Body           *Body                  `json:"body,omitempty"`
+    Parameters     []Param                `json:"parameters,omitempty"`
+    PathParameters []Param                `json:"pathParameters,omitempty"`
+    Headers        []Param                `json:"headers,omitempty"`
+    Authentication map[string]interface{} `json:"authentication,omitempty"`
+    SortKey        int                    `json:"metaSortKey,omitempty"`
+}
+
+type Body struct {
+    MimeType string  `json:"mimeType"`
+    Text     string  `json:"text,omitempty"`
+    Params   []Param `json:"params,omitempty"`
+}
+
+type Param struct {
+    Name        string `json:"name"`
+    Value       string `json:"value"`
+    Type        string `json:"type,omitempty"` // file for multipart file fields
+    Description string `json:"description,omitempty"`
+    Disabled    bool   `json:"disabled,omitempty"`
+}
+
+var reVariable = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// Convert converts a collection and its environment.
+func Convert(c *postman.Collection, env *postman.Environment) *Export {
+    out := &Export{Type: "export", Format: 4, Source: "oas-indexer"}
+    workspace := id("wrk", c.Info.Name, "")
+    out.Resources = append(out.Resources, Resource{ID: workspace, Type: "workspace", Name: c.Info.Name, Description: string(c.Info.Description), Scope: "collection"})
+
+    base := map[string]string{}
+    for _, v := range c.Variable { base[v.Key] = v.Value }
+    secrets := map[string]string{}
+    if env != nil {
+        for _, v := range env.Values {
+            if v.Type == "secret" {
+                secrets[v.Key] = v.Value
+            } else {
+                base[v.Key] = v.Value
+            }
+        }
+    }
+    for k, v := range base { base[k] = vars(v) }
+    baseEnv := id("env", c.Info.Name, "base")
+    out.Resources = append(out.Resources, Resource{ID: baseEnv, Type: "environment", ParentID: &workspace, Name: "Base Environment", Data: base})
+    if len(secrets) > 0 {
+        out.Resources = append(out.Resources, Resource{ID: id("env", c.Info.Name, "credentials"), Type: "environment", ParentID: &baseEnv, Name: "Credentials", Data: secrets})
+    }
+
+    var walk func(items []*postman.Item, parent, path string)
+    walk = func(items []*postman.Item, parent, path string) {
+        for i, it := range items {
+            key := path + "/" + it.Name
+            p := parent
+            if it.Request == nil {
+                group := id("fld", c.Info.Name, key)
+                out.Resources = append(out.Resources, Resource{ID: group, Type: "request_group", ParentID: &p, Name: it.Name, Description: string(it.Description), SortKey: i})
+                walk(it.Item, group, key)
+                continue
+            }
+            req := it.Request
+            r := Resource{
+                ID:          id("req", c.Info.Name, key+" "+req.Method+" "+req.URL.Raw),
+                Type:        "request",
+                ParentID:    &p,
+                Name:        it.Name,
+                Description: string(req.Description),
+                Method:      req.Method,
+                URL:         vars(req.URL.Raw),
+                Parameters:  params(req.URL.Query),
+                Headers:     params(req.Header),
+                SortKey:     i,
+            }
+            r.PathParameters = params(req.URL.Variable)
+            auth := req.Auth
+            if auth == nil { auth = c.Auth }
+            r.Authentication = authentication(auth)
+            r.Body = body(req)
+            out.Resources = append(out.Resources, r)
+        }
+    }
+    walk(c.Item, workspace, "")
+    return out
+}
+
+func body(req *postman.Request) *Body {
+    b := req.Body
+    if b == nil { return nil }
+    switch b.Mode {
+    case "urlencoded":
+        return &Body{MimeType: "application/x-www-form-urlencoded", Params: params(b.URLEncoded)}
+    case "formdata":
+        return &Body{MimeType: "multipart/form-data", Params: params(b.FormData)}
+    }
+    mt := "text/plain"
+    for _, h := range req.Header {
+        if h.Key == "Content-Type" { mt = h.Value }
+    }
+    return &Body{MimeType: mt, Text: vars(b.Raw)}
+}
+
+func params(list []postman.KeyValue) []Param {
+    var out []Param
+    for _, kv := range list {
+        p := Param{Name: kv.Key, Value: vars(kv.Value), Description: string(kv.Description), Disabled: kv.Disabled}
+        if kv.Type == "file" { p.Type, p.Value = "file", "" }
+        out = append(out, p)
+    }
+    return out
+}
+
+// authentication converts Postman auth; nil means none.
+func authentication(a *postman.Auth) map[string]interface{} {
+    if a == nil { return nil }
+    switch a.Type {
+    case "bearer":
+        return map[string]interface{}{"type": "bearer", "token": vars(attr(a.Bearer, "token"))}
+    case "basic":
+        return map[string]interface{}{"type": "basic", "username": vars(attr(a.Basic, "username")), "password": vars(attr(a.Basic, "password"))}
+    case "apikey":
+        addTo := "header"
+        if attr(a.APIKey, "in") == "query" { addTo = "queryParams" }
+        return map[string]interface{}{"type": "apikey", "key": attr(a.APIKey, "key"), "value": vars(attr(a.APIKey, "value")), "addTo": addTo}
+    case "oauth2":
+        grant := attr(a.OAuth2, "grant_type")
+        if grant == "password_credentials" { grant = "password" }
+        if grant == "" { grant = "client_credentials" }
+        auth := map[string]interface{}{"type": "oauth2", "grantType": grant}
+        for _, k := range [][2]string{{"authUrl", "authorizationUrl"}, {"accessTokenUrl", "accessTokenUrl"}, {"clientId", "clientId"}, {"clientSecret", "clientSecret"}, {"scope", "scope"}} {
+            if v := attr(a.OAuth2, k[0]); v != "" { auth[k[1]] = vars(v) }
+        }
+        return auth
+    }
+    return map[string]interface{}{"type": "none"}
+}
+
+func attr(list []postman.KeyValue, key string) string {
+    for _, kv := range list {
+        if kv.Key == key { return kv.Value }
+    }
+    return ""
+}
+
+// vars rewrites Postman {{name}} variables as Insomnia's {{ _.name }}.
+func vars(s string) string { return reVariable.ReplaceAllString(s, "{{ _.$1 }}") }
+
+// id derives a stable resource ID of the given kind.
+func id(kind, workspace, key string) string {
+    sum := sha1.Sum([]byte(workspace + "\x00" + kind + "\x00" + key))
+    return kind + "_" + hex.EncodeToString(sum[:])[:24]
+}
@@ -0,0 +1,101 @@
+package insomnia
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/pkg/postman"
+)
+
+const spec = `openapi: 3.0.3
+info: {title: Pets, version: 1.0.0}
+servers:
+  - url: https://{region}.example.com
+    variables:
+      region: {default: eu}
+security:
+  - token: []
+paths:
+  /pets/{petId}:
+    get:
+      tags: [pets]
+      summary: Get a pet
+      parameters:
+        - {name: petId, in: path, required: true, example: 42}
+        - {name: q, in: query, example: '{{term}}'}
+      responses: {}
+  /upload:
+    post:
+      security:
+        - key: []
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              properties:
+                file: {type: string, format: binary}
+            example: {file: x}
+      responses: {}
+components:
+  securitySchemes:
+    token: {type: http, scheme: bearer}
+    key: {type: apiKey, in: query, name: api_key}
+`
+
+func convert(t *testing.T) *Export {
+    t.Helper()
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+    c, env, err := postman.Export(doc.Content[0], postman.Options{})
+    if err != nil { t.Fatal(err) }
+    return Convert(c, env)
+}
+
+func TestConvert(t *testing.T) {
+    out := convert(t)
+    if out.Type != "export" || out.Format != 4 { t.Errorf("export = %s format %d", out.Type, out.Format) }
+    byName := map[string]Resource{}
+    var types []string
+    for _, r := range out.Resources {
+        byName[r.Name] = r
+        types = append(types, r.Type)
+    }
+    if want := []string{"workspace", "environment", "environment", "request_group", "request", "request"}; !reflect.DeepEqual(types, want) {
+        t.Fatalf("resource types = %v, want %v", types, want)
+    }
+
+    ws, base, creds := out.Resources[0], byName["Base Environment"], byName["Credentials"]
+    if ws.ParentID != nil || *base.ParentID != ws.ID || *creds.ParentID != base.ID { t.Errorf("environment parents are wrong: %+v", out.Resources[:3]) }
+    if want := map[string]string{"baseUrl": "https://{{ _.region }}.example.com", "region": "eu"}; !reflect.DeepEqual(base.Data, want) {
+        t.Errorf("base environment = %v, want %v", base.Data, want)
+    }
+    if want := map[string]string{"tokenToken": "", "keyKey": ""}; !reflect.DeepEqual(creds.Data, want) { t.Errorf("credentials = %v", creds.Data) }
+
+    get, upload := byName["Get a pet"], byName["POST /upload"]
+    if *get.ParentID != byName["pets"].ID || *upload.ParentID != ws.ID { t.Errorf("request parents are wrong") }
+    if get.Method != "GET" || get.URL != "{{ _.baseUrl }}/pets/:petId" { t.Errorf("get = %s %s", get.Method, get.URL) }
+    if want := []Param{{Name: "q", Value: "{{ _.term }}", Disabled: true}}; !reflect.DeepEqual(get.Parameters, want) { t.Errorf("parameters = %+v", get.Parameters) }
+    if want := []Param{{Name: "petId", Value: "42"}}; !reflect.DeepEqual(get.PathParameters, want) { t.Errorf("path parameters = %+v", get.PathParameters) }
+    // Requests without their own auth inherit the collection's.
+    if want := map[string]interface{}{"type": "bearer", "token": "{{ _.tokenToken }}"}; !reflect.DeepEqual(get.Authentication, want) {
+        t.Errorf("get auth = %v", get.Authentication)
+    }
+    if want := map[string]interface{}{"type": "apikey", "key": "api_key", "value": "{{ _.keyKey }}", "addTo": "queryParams"}; !reflect.DeepEqual(upload.Authentication, want) {
+        t.Errorf("upload auth = %v", upload.Authentication)
+    }
+    if want := (&Body{MimeType: "multipart/form-data", Params: []Param{{Name: "file", Type: "file"}}}); !reflect.DeepEqual(upload.Body, want) {
+        t.Errorf("upload body = %+v", upload.Body)
+    }
+
+    // IDs are stable across runs and distinct.
+    again := convert(t)
+    seen := map[string]bool{}
+    for i, r := range out.Resources {
+        if r.ID != again.Resources[i].ID { t.Errorf("%s: ID changed from %s to %s", r.Name, r.ID, again.Resources[i].ID) }
+        if seen[r.ID] || !strings.Contains(r.ID, "_") { t.Errorf("%s: bad or duplicate ID %s", r.Name, r.ID) }
+        seen[r.ID] = true
+    }
+}
@@ -0,0 +1,213 @@
+// Package jsonschema converts the component schemas of a bundled OpenAPI
+// spec into standalone JSON Schema draft 2020-12 documents, one per
+// component, for validators and form generators that do not read OpenAPI.
+//
+// Refs between components point at the other documents by file name
+// (User.json, User.json#/properties/address); other local refs are inlined.
+// OpenAPI 3.0 keywords are upgraded (nullable, boolean exclusive bounds,
+// example), and OpenAPI-only keywords (discriminator, xml, externalDocs and
+// x- extensions) are dropped so strict validators accept the result.
+package jsonschema
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/dialect"
+)
+
+// Draft is the $schema of the documents.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Options control the documents.
+type Options struct {
+    BaseURL string // if set, each document gets $id <BaseURL>/<file>
+}
+
+// Document is one converted component.
+type Document struct {
+    Component string
+    File      string // <name>.json
+    Schema    *yaml.Node
+}
+
+// maxDepth bounds the inlining of local refs.
+const maxDepth = 64
+
+// Keywords whose value is a schema, a list of schemas, or a map of them.
+var (
+    schemaKeys     = map[string]bool{"items": true, "additionalProperties": true, "not": true, "if": true, "then": true, "else": true, "contains": true, "propertyNames": true, "unevaluatedItems": true, "unevaluatedProperties": true, "additionalItems": true, "contentSchema": true}
+    schemaListKeys = map[string]bool{"allOf": true, "anyOf": true, "oneOf": true, "prefixItems": true}
+    schemaMapKeys  = map[string]bool{"properties": true, "patternProperties": true, "$defs": true, "definitions": true, "dependentSchemas": true}
+    openAPIOnly    = map[string]bool{"discriminator": true, "xml": true, "externalDocs": true}
+)
+
+// Export converts every component schema of spec, in the order they are
+// declared.
+func Export(spec *yaml.Node, opts Options) ([]Document, error) {
+    schemas := yamlnode.GetKey(yamlnode.GetKey(spec, "components"), "schemas")
+    if schemas == nil || schemas.Kind != yaml.MappingNode || len(schemas.Content) == 0 {
+        return nil, fmt.Errorf("the spec has no component schemas")
+    }
+    files := map[string]string{}
+    taken := map[string]bool{}
+    for i := 0; i+1 < len(schemas.Content); i += 2 {
+        name := fileName(schemas.Content[i].Value)
+        base := name
+        for n := 2; taken[strings.ToLower(name)]; n++ { name = fmt.Sprintf("%s%d", base, n) }
+        taken[strings.ToLower(name)] = true
+        files[schemas.Content[i].Value] = name + ".json"
+    }
+    upgrade := !dialect.Is31(scalar(spec, "openapi"))
+
+    var docs []Document
+    for i := 0; i+1 < len(schemas.Content); i += 2 {
+        component := schemas.Content[i].Value
+        c := &converter{spec: spec, files: files}
+        schema, err := c.convert(schemas.Content[i+1], 0)
+        if err != nil { return nil, fmt.Errorf("%s: %w", component, err) }
+        if upgrade { dialect.Upgrade(schema) }
+        if schema.Kind != yaml.MappingNode {
+            // true or false: wrap it so the document can carry $schema.
+            schema = wrap(schema)
+        }
+        doc := yamlnode.Map()
+        yamlnode.SetKey(doc, "$schema", yamlnode.Str(Draft))
+        if opts.BaseURL != "" { yamlnode.SetKey(doc, "$id", yamlnode.Str(strings.TrimRight(opts.BaseURL, "/")+"/"+files[component])) }
+        if yamlnode.GetKey(schema, "title") == nil { yamlnode.SetKey(doc, "title", yamlnode.Str(component)) }
+        for j := 0; j+1 < len(schema.Content); j += 2 {
+            if k := schema.Content[j].Value; k == "$schema" || k == "$id" { continue }
+            doc.Content = append(doc.Content, schema.Content[j], schema.Content[j+1])
+        }
+        docs = append(docs, Document{Component: component, File: files[component], Schema: doc})
+    }
+    return docs, nil
+}
+
+type converter struct {
+    spec  *yaml.Node
+    files map[string]string
+}
+
+// convert returns a converted copy of the schema n.
+func (c *converter) convert(n *yaml.Node, depth int) (*yaml.Node, error) {
+    if depth > maxDepth { return nil, fmt.Errorf("local $refs nested deeper than %d levels", maxDepth) }
+    if n == nil || n.Kind != yaml.MappingNode { return copyNode(n), nil }
+    if ref := yamlnode.GetKey(n, "$ref"); ref != nil && ref.Kind == yaml.ScalarNode {
+        out := yamlnode.Map()
+        if target, ok := c.componentRef(ref.Value); ok {
+            yamlnode.SetKey(out, "$ref", yamlnode.Str(target))
+        } else if strings.HasPrefix(ref.Value, "#/") {
+            target, err := bundle.Lookup(c.spec, ref.Value[1:])
+            if err != nil { return nil, fmt.Errorf("$ref %s: %w", ref.Value, err) }
+            return c.convert(target, depth+1)
+        } else {
+            yamlnode.SetKey(out, "$ref", yamlnode.Str(ref.Value))
+        }
+        // 3.1 allows keywords next to $ref; keep them.
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if n.Content[i].Value == "$ref" { continue }
+            if err := c.keyword(out, n.Content[i], n.Content[i+1], depth); err != nil { return nil, err }
+        }
+        return out, nil
+    }
+    out := yamlnode.Map()
+    for i := 0; i+1 < len(n.Content); i += 2 {
+        if err := c.keyword(out, n.Content[i], n.Content[i+1], depth); err != nil { return nil, err }
+    }
+    return out, nil
+}
+
+// keyword adds the converted keyword k: v to out.
+func (c *converter) keyword(out, k, v *yaml.Node, depth int) error {
+    key := k.Value
+    switch {
+    case openAPIOnly[key], strings.HasPrefix(key, "x-"):
+        return nil
+    case key == "example":
+        // 3.0's example becomes 2020-12's examples, unless both are given.
+        if yamlnode.GetKey(out, "examples") == nil {
+            list := yamlnode.Seq()
+            list.Content = append(list.Content, copyNode(v))
+            yamlnode.SetKey(out, "examples", list)
+        }
+        return nil
+    case key == "examples":
+        if v.Kind != yaml.SequenceNode { return nil }
+    case schemaKeys[key]:
+        s, err := c.convert(v, depth+1)
+        if err != nil { return err }
+        if key == "items" && s.Kind == yaml.SequenceNode {
+            // Draft 4 tuple form.
+            key = "prefixItems"
+        }
+        yamlnode.SetKey(out, key, s)
+        return nil
+    case schemaListKeys[key] && v.Kind == yaml.SequenceNode:
+        list := yamlnode.Seq()
+        for _, item := range v.Content {
+            s, err := c.convert(item, depth+1)
+            if err != nil { return err }
+            list.Content = append(list.Content, s)
+        }
+        yamlnode.SetKey(out, key, list)
+        return nil
+    case schemaMapKeys[key] && v.Kind == yaml.MappingNode:
+        m := yamlnode.Map()
+        for i := 0; i+1 < len(v.Content); i += 2 {
+            s, err := c.convert(v.Content[i+1], depth+1)
+            if err != nil { return err }
+            m.Content = append(m.Content, copyNode(v.Content[i]), s)
+        }
+        if key == "definitions" { key = "$defs" }
+        yamlnode.SetKey(out, key, m)
+        return nil
+    }
+    yamlnode.SetKey(out, key, copyNode(v))
+    return nil
+}
+
+// componentRef maps a ref into a component schema to the component's
+// document.
+func (c *converter) componentRef(ref string) (string, bool) {
+    rest, ok := strings.CutPrefix(ref, "#/components/schemas/")
+    if !ok { return "", false }
+    name, ptr, _ := strings.Cut(rest, "/")
+    file, ok := c.files[strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")]
+    if !ok { return "", false }
+    if ptr != "" { return file + "#/" + ptr, true }
+    return file, true
+}
+
+func wrap(b *yaml.Node) *yaml.Node {
+    m := yamlnode.Map()
+    if b.Value == "false" { yamlnode.SetKey(m, "not", yamlnode.Map()) }
+    return m
+}
+
+var reUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fileName makes a component name safe as a file name.
+func fileName(name string) string {
+    s := strings.Trim(reUnsafe.ReplaceAllString(name, "_"), "._")
+    if s == "" { return "schema" }
+    return s
+}
+
+func copyNode(n *yaml.Node) *yaml.Node {
+    if n == nil { return nil }
+    c := *n
+    c.Content = make([]*yaml.Node, len(n.Content))
+    for i, child := range n.Content { c.Content[i] = copyNode(child) }
+    return &c
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
@@ -0,0 +1,115 @@
+package jsonschema
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+// export converts the component schemas of a spec of the given version.
+func export(t *testing.T, version, schemas string, opts Options) ([]Document, error) {
+    t.Helper()
+    var doc yaml.Node
+    text := "openapi: " + version + "\ninfo: {title: Test, version: 1.0.0}\ncomponents:\n  schemas:\n" + schemas
+    if err := yaml.Unmarshal([]byte(text), &doc); err != nil { t.Fatal(err) }
+    return Export(doc.Content[0], opts)
+}
+
+func TestExport(t *testing.T) {
+    tests := []struct {
+        name, version string
+        schemas       string // the components, indented under components.schemas
+        opts          Options
+        want          map[string]string // file -> document
+    }{
+        {
+            name:    "3.0 keywords are upgraded and OpenAPI-only ones dropped",
+            version: "3.0.3",
+            schemas: `    Price:
+      type: number
+      nullable: true
+      minimum: 0
+      exclusiveMinimum: true
+      example: 9.99
+      x-unit: EUR
+      externalDocs: {url: https://example.com}
+`,
+            want: map[string]string{"Price.json": `$schema: https://json-schema.org/draft/2020-12/schema
+title: Price
+type: [number, 'null']
+exclusiveMinimum: 0
+examples: [9.99]
+`},
+        },
+        {
+            name:    "component refs point at files, other local refs are inlined",
+            version: "3.1.0",
+            schemas: `    User:
+      title: A user
+      properties:
+        address: {$ref: '#/components/schemas/Address'}
+        city: {$ref: '#/components/schemas/Address/properties/city'}
+        id: {$ref: '#/components/parameters/Id/schema'}
+    Address:
+      properties:
+        city: {type: string}
+  parameters:
+    Id: {name: id, in: path, schema: {type: integer}}
+`,
+            opts: Options{BaseURL: "https://example.com/schemas/"},
+            want: map[string]string{
+                "User.json": `$schema: https://json-schema.org/draft/2020-12/schema
+$id: https://example.com/schemas/User.json
+title: A user
+properties:
+  address: {$ref: Address.json}
+  city: {$ref: 'Address.json#/properties/city'}
+  id: {type: integer}
+`,
+                "Address.json": `$schema: https://json-schema.org/draft/2020-12/schema
+$id: https://example.com/schemas/Address.json
+title: Address
+properties:
+  city: {type: string}
+`,
+            },
+        },
+        {
+            name:    "file names are made safe and unique",
+            version: "3.1.0",
+            schemas: "    a/b: {type: string}\n    a_b: {type: integer}\n    Never: false\n",
+            want: map[string]string{
+                "a_b.json":   "$schema: https://json-schema.org/draft/2020-12/schema\ntitle: a/b\ntype: string\n",
+                "a_b2.json":  "$schema: https://json-schema.org/draft/2020-12/schema\ntitle: a_b\ntype: integer\n",
+                "Never.json": "$schema: https://json-schema.org/draft/2020-12/schema\ntitle: Never\nnot: {}\n",
+            },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            docs, err := export(t, tt.version, tt.schemas, tt.opts)
+            if err != nil { t.Fatal(err) }
+            if len(docs) != len(tt.want) { t.Fatalf("%d documents, want %d", len(docs), len(tt.want)) }
+            for _, d := range docs {
+                var got, want any
+                if err := d.Schema.Decode(&got); err != nil { t.Fatal(err) }
+                if err := yaml.Unmarshal([]byte(tt.want[d.File]), &want); err != nil { t.Fatal(err) }
+                if !reflect.DeepEqual(got, want) {
+                    out, _ := yaml.Marshal(d.Schema)
+                    t.Errorf("%s (%s):\n%s\nwant:\n%s", d.File, d.Component, out, tt.want[d.File])
+                }
+            }
+        })
+    }
+}
+
+func TestExportErrors(t *testing.T) {
+    if _, err := export(t, "3.1.0", "    {}\n", Options{}); err == nil || !strings.Contains(err.Error(), "no component schemas") {
+        t.Errorf("no schemas: %v", err)
+    }
+    if _, err := export(t, "3.1.0", "    A: {$ref: '#/components/nope'}\n", Options{}); err == nil || !strings.HasPrefix(err.Error(), "A: $ref #/components/nope") {
+        t.Errorf("dangling ref: %v", err)
+    }
+}
@@ -0,0 +1,348 @@
+// Package mock serves canned responses for every operation of a bundled
+// OpenAPI spec, using the spec's examples and falling back to values
+// generated from the response schema.
+//
+// A client picks a response with the Prism-style "Prefer" header, e.g.
+// "Prefer: code=404" or "Prefer: example=empty"; otherwise the lowest 2xx
+// response (else default) is used and its content type is negotiated from
+// the Accept header.
+package mock
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+)
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// maxDepth bounds schema generation for recursive schemas.
+const maxDepth = 8
+
+type route struct {
+    path    string
+    re      *regexp.Regexp
+    params  int
+    methods map[string]*yaml.Node // upper-case method -> operation
+}
+
+// Server is an http.Handler answering requests from a spec's examples.
+type Server struct {
+    root   *yaml.Node
+    prefix string // path of the first server URL, accepted before every path
+    routes []*route
+}
+
+var reTemplateParam = regexp.MustCompile(`\{[^}/]+\}`)
+
+// New returns a Server for spec, the top-level node of a bundled document.
+func New(spec *yaml.Node) (*Server, error) {
+    if spec == nil || spec.Kind != yaml.MappingNode { return nil, fmt.Errorf("spec must be a mapping") }
+    s := &Server{root: spec}
+    if servers := yamlnode.GetKey(spec, "servers"); servers != nil && servers.Kind == yaml.SequenceNode && len(servers.Content) > 0 {
+        if u := yamlnode.GetKey(servers.Content[0], "url"); u != nil {
+            if parsed, err := url.Parse(u.Value); err == nil { s.prefix = strings.TrimSuffix(parsed.Path, "/") }
+        }
+    }
+    paths := yamlnode.GetKey(spec, "paths")
+    if paths == nil || paths.Kind != yaml.MappingNode { return nil, fmt.Errorf("spec has no paths") }
+    for i := 0; i+1 < len(paths.Content); i += 2 {
+        key, item := paths.Content[i].Value, s.deref(paths.Content[i+1])
+        r := &route{path: key, methods: map[string]*yaml.Node{}}
+        var pattern strings.Builder
+        last := 0
+        for _, loc := range reTemplateParam.FindAllStringIndex(key, -1) {
+            pattern.WriteString(regexp.QuoteMeta(key[last:loc[0]]) + `[^/]+`)
+            last = loc[1]
+            r.params++
+        }
+        pattern.WriteString(regexp.QuoteMeta(key[last:]))
+        r.re = regexp.MustCompile("^" + pattern.String() + "$")
+        for _, m := range httpMethods {
+            if op := s.deref(yamlnode.GetKey(item, m)); op != nil && op.Kind == yaml.MappingNode {
+                r.methods[strings.ToUpper(m)] = op
+            }
+        }
+        s.routes = append(s.routes, r)
+    }
+    // Literal paths win over templated ones: /users/me before /users/{id}.
+    sort.SliceStable(s.routes, func(i, j int) bool { return s.routes[i].params < s.routes[j].params })
+    return s, nil
+}
+
+// Load bundles the spec at path and returns a Server for it.
+func Load(path string) (*Server, error) {
+    spec, err := bundle.Load(path)
+    if err != nil { return nil, err }
+    return New(spec)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    p := r.URL.Path
+    if s.prefix != "" && strings.HasPrefix(p, s.prefix+"/") { p = strings.TrimPrefix(p, s.prefix) }
+    var matched *route
+    for _, rt := range s.routes {
+        if rt.re.MatchString(p) {
+            matched = rt
+            break
+        }
+    }
+    if matched == nil {
+        writeProblem(w, http.StatusNotFound, fmt.Sprintf("no path in the spec matches %s", r.URL.Path))
+        return
+    }
+    op := matched.methods[r.Method]
+    if op == nil {
+        var allow []string
+        for m := range matched.methods { allow = append(allow, m) }
+        sort.Strings(allow)
+        w.Header().Set("Allow", strings.Join(allow, ", "))
+        writeProblem(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s does not define %s", matched.path, r.Method))
+        return
+    }
+    prefer := parsePrefer(r.Header.Get("Prefer"))
+    code, resp := s.pickResponse(op, prefer["code"])
+    if resp == nil {
+        writeProblem(w, http.StatusNotImplemented, fmt.Sprintf("%s %s has no response %s", r.Method, matched.path, firstNonEmpty(prefer["code"], "to mock")))
+        return
+    }
+    status := http.StatusOK
+    if n, err := strconv.Atoi(code); err == nil { status = n }
+
+    mediaType, media := s.pickMedia(resp, r.Header.Get("Accept"))
+    if media == nil || r.Method == http.MethodHead {
+        w.WriteHeader(status)
+        return
+    }
+    val, ok := s.example(media, prefer["example"])
+    if !ok { val = s.generate(yamlnode.GetKey(media, "schema"), 0) }
+    body, err := encode(mediaType, val)
+    if err != nil {
+        writeProblem(w, http.StatusInternalServerError, err.Error())
+        return
+    }
+    w.Header().Set("Content-Type", mediaType)
+    w.WriteHeader(status)
+    w.Write(body)
+}
+
+// deref follows local refs such as #/components/responses/NotFound, or
+// returns n when they do not resolve.
+func (s *Server) deref(n *yaml.Node) *yaml.Node {
+    if target := yamlnode.Deref(s.root, n); target != nil { return target }
+    return n
+}
+
+// pickResponse returns the response for want, or the lowest 2xx, default,
+// or first response when want is empty.
+func (s *Server) pickResponse(op *yaml.Node, want string) (string, *yaml.Node) {
+    responses := yamlnode.GetKey(op, "responses")
+    if responses == nil || responses.Kind != yaml.MappingNode || len(responses.Content) == 0 { return "", nil }
+    if want != "" {
+        if r := yamlnode.GetKey(responses, want); r != nil { return want, s.deref(r) }
+        return "", nil
+    }
+    best := ""
+    for i := 0; i+1 < len(responses.Content); i += 2 {
+        code := responses.Content[i].Value
+        if strings.HasPrefix(code, "2") && (best == "" || code < best) { best = code }
+    }
+    if best == "" && yamlnode.GetKey(responses, "default") != nil { best = "default" }
+    if best == "" { best = responses.Content[0].Value }
+    return best, s.deref(yamlnode.GetKey(responses, best))
+}
+
+// pickMedia chooses the first content type accepted by the Accept header.
+func (s *Server) pickMedia(resp *yaml.Node, accept string) (string, *yaml.Node) {
+    content := yamlnode.GetKey(resp, "content")
+    if content == nil || content.Kind != yaml.MappingNode || len(content.Content) == 0 { return "", nil }
+    var ranges []string
+    for _, a := range strings.Split(accept, ",") {
+        if a = strings.TrimSpace(strings.SplitN(a, ";", 2)[0]); a != "" { ranges = append(ranges, strings.ToLower(a)) }
+    }
+    for _, rng := range ranges {
+        for i := 0; i+1 < len(content.Content); i += 2 {
+            mt := strings.ToLower(content.Content[i].Value)
+            if rng == "*/*" || rng == mt || strings.HasSuffix(rng, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(rng, "*")) {
+                return content.Content[i].Value, content.Content[i+1]
+            }
+        }
+    }
+    return content.Content[0].Value, content.Content[1]
+}
+
+// example returns the media type's example (or the named or first entry of
+// examples), falling back to an example on the schema itself.
+func (s *Server) example(media *yaml.Node, name string) (interface{}, bool) {
+    if examples := yamlnode.GetKey(media, "examples"); examples != nil && examples.Kind == yaml.MappingNode && len(examples.Content) > 0 {
+        ex := examples.Content[1]
+        if name != "" {
+            if named := yamlnode.GetKey(examples, name); named != nil { ex = named }
+        }
+        if v := yamlnode.GetKey(s.deref(ex), "value"); v != nil { return decode(v), true }
+    }
+    if ex := yamlnode.GetKey(media, "example"); ex != nil { return decode(ex), true }
+    if ex := yamlnode.GetKey(s.deref(yamlnode.GetKey(media, "schema")), "example"); ex != nil { return decode(ex), true }
+    return nil, false
+}
+
+// generate builds a value matching schema, preferring its example, default
+// and first enum value.
+func (s *Server) generate(schema *yaml.Node, depth int) interface{} {
+    schema = s.deref(schema)
+    if schema == nil || depth > maxDepth { return nil }
+    for _, key := range []string{"example", "default"} {
+        if v := yamlnode.GetKey(schema, key); v != nil { return decode(v) }
+    }
+    if enum := yamlnode.GetKey(schema, "enum"); enum != nil && enum.Kind == yaml.SequenceNode && len(enum.Content) > 0 {
+        return decode(enum.Content[0])
+    }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode {
+        merged := map[string]interface{}{}
+        for _, part := range all.Content {
+            if m, ok := s.generate(part, depth+1).(map[string]interface{}); ok {
+                for k, v := range m { merged[k] = v }
+            }
+        }
+        return merged
+    }
+    for _, key := range []string{"oneOf", "anyOf"} {
+        if alts := yamlnode.GetKey(schema, key); alts != nil && alts.Kind == yaml.SequenceNode && len(alts.Content) > 0 {
+            return s.generate(alts.Content[0], depth+1)
+        }
+    }
+    typ := scalar(schema, "type")
+    if t := yamlnode.GetKey(schema, "type"); t != nil && t.Kind == yaml.SequenceNode {
+        // OpenAPI 3.1 type arrays: sample the first non-null type
+        for _, c := range t.Content {
+            if c.Value != "null" { typ = c.Value; break }
+        }
+    }
+    if typ == "" && yamlnode.GetKey(schema, "properties") != nil { typ = "object" }
+    switch typ {
+    case "object":
+        obj := map[string]interface{}{}
+        if props := yamlnode.GetKey(schema, "properties"); props != nil && props.Kind == yaml.MappingNode {
+            for i := 0; i+1 < len(props.Content); i += 2 {
+                obj[props.Content[i].Value] = s.generate(props.Content[i+1], depth+1)
+            }
+        }
+        return obj
+    case "array":
+        n := 1
+        if m, err := strconv.Atoi(scalar(schema, "minItems")); err == nil && m > n { n = m }
+        arr := make([]interface{}, 0, n)
+        if depth < maxDepth {
+            for i := 0; i < n; i++ { arr = append(arr, s.generate(yamlnode.GetKey(schema, "items"), depth+1)) }
+        }
+        return arr
+    case "integer":
+        if m, err := strconv.ParseInt(scalar(schema, "minimum"), 10, 64); err == nil { return m }
+        return 0
+    case "number":
+        if m, err := strconv.ParseFloat(scalar(schema, "minimum"), 64); err == nil { return m }
+        return 0.0
+    case "boolean":
+        return true
+    case "string":
+        return sampleString(scalar(schema, "format"))
+    }
+    return nil
+}
+
+func sampleString(format string) string {
+    switch format {
+    case "date-time":
+        return "2024-01-01T00:00:00Z"
+    case "date":
+        return "2024-01-01"
+    case "email":
+        return "user@example.com"
+    case "uuid":
+        return "00000000-0000-4000-8000-000000000000"
+    case "uri", "url":
+        return "https://example.com"
+    case "hostname":
+        return "example.com"
+    case "ipv4":
+        return "192.0.2.1"
+    case "byte":
+        return "c3RyaW5n"
+    }
+    return "string"
+}
+
+// encode renders v as JSON, except for YAML and plain-text media types.
+func encode(mediaType string, v interface{}) ([]byte, error) {
+    mt := strings.ToLower(mediaType)
+    switch {
+    case strings.Contains(mt, "yaml"):
+        return yaml.Marshal(v)
+    case strings.HasPrefix(mt, "text/"):
+        if str, ok := v.(string); ok { return []byte(str), nil }
+    }
+    return json.MarshalIndent(v, "", "  ")
+}
+
+// decode converts n to plain Go values that encoding/json can marshal.
+func decode(n *yaml.Node) interface{} {
+    var v interface{}
+    if err := n.Decode(&v); err != nil { return nil }
+    return jsonSafe(v)
+}
+
+// jsonSafe converts map[interface{}]interface{} (from non-string keys) into
+// map[string]interface{}.
+func jsonSafe(v interface{}) interface{} {
+    switch t := v.(type) {
+    case map[string]interface{}:
+        for k, e := range t { t[k] = jsonSafe(e) }
+    case map[interface{}]interface{}:
+        m := make(map[string]interface{}, len(t))
+        for k, e := range t { m[fmt.Sprint(k)] = jsonSafe(e) }
+        return m
+    case []interface{}:
+        for i, e := range t { t[i] = jsonSafe(e) }
+    }
+    return v
+}
+
+// parsePrefer parses "code=404, example=empty" into a map.
+func parsePrefer(h string) map[string]string {
+    out := map[string]string{}
+    for _, part := range strings.Split(h, ",") {
+        if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+            out[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+        }
+    }
+    return out
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+    w.Header().Set("Content-Type", "application/problem+json")
+    w.WriteHeader(status)
+    b, _ := json.Marshal(map[string]interface{}{"status": status, "title": http.StatusText(status), "detail": detail})
+    w.Write(b)
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
@@ -0,0 +1,104 @@
+package mock
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+const spec = `openapi: 3.0.3
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{id}:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              examples:
+                jane: {value: {id: 1, name: Jane}}
+                empty: {$ref: '#/components/examples/Empty'}
+            text/plain:
+              example: Jane
+        '404':
+          $ref: '#/components/responses/NotFound'
+  /users/me:
+    get:
+      responses:
+        default:
+          description: generated
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  id: {type: integer, minimum: 7}
+                  email: {type: string, format: email}
+                  role: {type: string, enum: [admin, member]}
+                  tags: {type: array, items: {type: string}}
+                  plan: {$ref: '#/components/schemas/Plan'}
+    delete:
+      responses:
+        '204': {description: gone}
+components:
+  examples:
+    Empty: {value: {}}
+  responses:
+    NotFound:
+      description: missing
+      content:
+        application/json:
+          schema: {type: object, properties: {message: {type: string, example: not found}}}
+  schemas:
+    Plan: {allOf: [{properties: {name: {type: string, default: free}}}, {properties: {seats: {type: integer}}}]}
+`
+
+func TestServeHTTP(t *testing.T) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+    s, err := New(doc.Content[0])
+    if err != nil { t.Fatal(err) }
+
+    tests := []struct {
+        name, method, path string
+        header             map[string]string
+        status             int
+        contentType, body  string
+    }{
+        {"first example", "GET", "/users/1", nil, 200, "application/json", `{"id":1,"name":"Jane"}`},
+        {"server path prefix", "GET", "/v1/users/1", nil, 200, "application/json", `{"id":1,"name":"Jane"}`},
+        {"named example by ref", "GET", "/users/1", map[string]string{"Prefer": "example=empty"}, 200, "application/json", `{}`},
+        {"accept", "GET", "/users/1", map[string]string{"Accept": "text/*;q=0.9"}, 200, "text/plain", "Jane"},
+        {"preferred code by ref", "GET", "/users/1", map[string]string{"Prefer": `code="404"`}, 404, "application/json", `{"message":"not found"}`},
+        {"missing code", "GET", "/users/1", map[string]string{"Prefer": "code=500"}, 501, "application/problem+json", ""},
+        {"literal path first, generated", "GET", "/users/me", nil, 200, "application/json",
+            `{"email":"user@example.com","id":7,"plan":{"name":"free","seats":0},"role":"admin","tags":["string"]}`},
+        {"no content", "DELETE", "/users/me", nil, 204, "", ""},
+        {"method not allowed", "POST", "/users/me", nil, 405, "application/problem+json", ""},
+        {"no path", "GET", "/orders", nil, 404, "application/problem+json", ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(tt.method, tt.path, nil)
+            for k, v := range tt.header { req.Header.Set(k, v) }
+            rec := httptest.NewRecorder()
+            s.ServeHTTP(rec, req)
+            if rec.Code != tt.status { t.Errorf("status %d, want %d: %s", rec.Code, tt.status, rec.Body) }
+            if ct := rec.Header().Get("Content-Type"); ct != tt.contentType { t.Errorf("Content-Type %q, want %q", ct, tt.contentType) }
+            got := rec.Body.String()
+            var compact bytes.Buffer
+            if json.Compact(&compact, rec.Body.Bytes()) == nil { got = compact.String() }
+            if tt.body != "" && got != tt.body { t.Errorf("body %s, want %s", got, tt.body) }
+        })
+    }
+
+    rec := httptest.NewRecorder()
+    s.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/users/me", nil))
+    if allow := rec.Header().Get("Allow"); allow != "DELETE, GET" { t.Errorf("Allow = %q", allow) }
+}
@@ -0,0 +1,288 @@
+package overlay
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// The JSONPath subset overlay targets are written in (RFC 9535):
+//
+//   $                   the document
+//   .name  ['name']     a member of an object
+//   .*  [*]             every member or element
+//   [0]  [-1]           an element of an array
+//   ..name  ..*  ..[0]  the selector applied to every descendant as well
+//   [?@.a.b == 'x']     the members or elements for which the comparison
+//                       (==, !=) holds, or which have the path ([?@.a]);
+//                       the parenthesized form [?(@.a == 'x')] works too
+
+// segment is one selector of a path.
+type segment struct {
+    descendant bool
+    kind       segmentKind
+    name       string // segName
+    index      int    // segIndex
+    filter     *filter
+}
+
+type segmentKind int
+
+const (
+    segName segmentKind = iota
+    segWildcard
+    segIndex
+    segFilter
+)
+
+// filter is a [?...] test on a member or element.
+type filter struct {
+    path  []string // member names below @
+    op    string   // "==", "!=", or "" for an existence test
+    value string
+    str   bool // value was a quoted string, which only equals string scalars
+}
+
+// match is a node selected by a path, with where it sits in its parent.
+type match struct {
+    node   *yaml.Node
+    parent *yaml.Node // nil for the document itself
+}
+
+// parsePath parses a JSONPath expression into its segments.
+func parsePath(expr string) ([]segment, error) {
+    p := strings.TrimSpace(expr)
+    if !strings.HasPrefix(p, "$") { return nil, fmt.Errorf("JSONPath %q must start with $", expr) }
+    p = p[1:]
+    var segs []segment
+    for p != "" {
+        var seg segment
+        switch {
+        case strings.HasPrefix(p, ".."):
+            seg.descendant = true
+            p = p[2:]
+            if !strings.HasPrefix(p, "[") {
+                var err error
+                if seg, p, err = parseDotted(p); err != nil { return nil, fmt.Errorf("JSONPath %q: %v", expr, err) }
+                seg.descendant = true
+                segs = append(segs, seg)
+                continue
+            }
+        case strings.HasPrefix(p, "."):
+            var err error
+            if seg, p, err = parseDotted(p[1:]); err != nil { return nil, fmt.Errorf("JSONPath %q: %v", expr, err) }
+            segs = append(segs, seg)
+            continue
+        case !strings.HasPrefix(p, "["):
+            return nil, fmt.Errorf("JSONPath %q: unexpected %q", expr, p)
+        }
+        end := closingBracket(p)
+        if end < 0 { return nil, fmt.Errorf("JSONPath %q: unterminated [", expr) }
+        inner := strings.TrimSpace(p[1:end])
+        p = p[end+1:]
+        if err := parseBracket(inner, &seg); err != nil { return nil, fmt.Errorf("JSONPath %q: %v", expr, err) }
+        segs = append(segs, seg)
+    }
+    return segs, nil
+}
+
+// parseDotted parses the member name (or *) after a dot.
+func parseDotted(p string) (segment, string, error) {
+    i := strings.IndexAny(p, ".[")
+    if i < 0 { i = len(p) }
+    name := p[:i]
+    switch name {
+    case "":
+        return segment{}, p, fmt.Errorf("missing member name")
+    case "*":
+        return segment{kind: segWildcard}, p[i:], nil
+    }
+    return segment{kind: segName, name: name}, p[i:], nil
+}
+
+// closingBracket returns the index of the ] closing the [ at p[0], skipping
+// quoted strings, or -1.
+func closingBracket(p string) int {
+    var quote byte
+    depth := 0
+    for i := 0; i < len(p); i++ {
+        c := p[i]
+        switch {
+        case quote != 0:
+            if c == '\\' { i++ } else if c == quote { quote = 0 }
+        case c == '\'' || c == '"':
+            quote = c
+        case c == '[':
+            depth++
+        case c == ']':
+            depth--
+            if depth == 0 { return i }
+        }
+    }
+    return -1
+}
+
+func parseBracket(inner string, seg *segment) error {
+    switch {
+    case inner == "*":
+        seg.kind = segWildcard
+    case strings.HasPrefix(inner, "?"):
+        f, err := parseFilter(strings.TrimSpace(inner[1:]))
+        if err != nil { return err }
+        seg.kind, seg.filter = segFilter, f
+    case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, "\""):
+        name, rest, err := unquote(inner)
+        if err != nil { return err }
+        if strings.TrimSpace(rest) != "" { return fmt.Errorf("unexpected %q after member name", rest) }
+        seg.kind, seg.name = segName, name
+    default:
+        i, err := strconv.Atoi(inner)
+        if err != nil { return fmt.Errorf("unsupported selector [%s]", inner) }
+        seg.kind, seg.index = segIndex, i
+    }
+    return nil
+}
+
+// parseFilter parses "@.a.b == 'x'", "(@.a != 1)" or "@.a".
+func parseFilter(s string) (*filter, error) {
+    if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") { s = strings.TrimSpace(s[1 : len(s)-1]) }
+    if !strings.HasPrefix(s, "@") { return nil, fmt.Errorf("filter %q must test @", s) }
+    f := &filter{}
+    left, right := s, ""
+    for _, op := range []string{"==", "!="} {
+        if i := strings.Index(s, op); i >= 0 {
+            f.op, left, right = op, strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+len(op):])
+            break
+        }
+    }
+    for _, name := range strings.Split(strings.TrimPrefix(left, "@"), ".")[1:] {
+        if name == "" { return nil, fmt.Errorf("filter %q: empty member name", s) }
+        f.path = append(f.path, name)
+    }
+    if strings.TrimPrefix(left, "@") != "" && !strings.HasPrefix(strings.TrimPrefix(left, "@"), ".") {
+        return nil, fmt.Errorf("filter %q: only @.member paths are supported", s)
+    }
+    if f.op == "" { return f, nil }
+    if strings.HasPrefix(right, "'") || strings.HasPrefix(right, "\"") {
+        v, rest, err := unquote(right)
+        if err != nil { return nil, err }
+        if strings.TrimSpace(rest) != "" { return nil, fmt.Errorf("filter %q: unexpected %q", s, rest) }
+        f.value, f.str = v, true
+        return f, nil
+    }
+    if right == "" { return nil, fmt.Errorf("filter %q: missing value", s) }
+    f.value = right
+    return f, nil
+}
+
+// unquote reads the quoted string at the start of s.
+func unquote(s string) (string, string, error) {
+    quote := s[0]
+    var b strings.Builder
+    for i := 1; i < len(s); i++ {
+        switch c := s[i]; {
+        case c == '\\' && i+1 < len(s):
+            i++
+            b.WriteByte(s[i])
+        case c == quote:
+            return b.String(), s[i+1:], nil
+        default:
+            b.WriteByte(c)
+        }
+    }
+    return "", "", fmt.Errorf("unterminated string %s", s)
+}
+
+// selectPath returns the nodes below root the segments select, each once,
+// in document order.
+func selectPath(root *yaml.Node, segs []segment) []match {
+    cur := []match{{node: root}}
+    for _, seg := range segs {
+        var next []match
+        seen := map[*yaml.Node]bool{}
+        add := func(m match) {
+            if !seen[m.node] {
+                seen[m.node] = true
+                next = append(next, m)
+            }
+        }
+        for _, m := range cur {
+            from := []*yaml.Node{m.node}
+            if seg.descendant { from = descendants(m.node) }
+            for _, n := range from {
+                for _, c := range seg.apply(n) {
+                    add(match{node: c, parent: n})
+                }
+            }
+        }
+        cur = next
+    }
+    return cur
+}
+
+// descendants returns n and every mapping and sequence below it.
+func descendants(n *yaml.Node) []*yaml.Node {
+    out := []*yaml.Node{n}
+    for _, c := range children(n) {
+        if c.Kind == yaml.MappingNode || c.Kind == yaml.SequenceNode { out = append(out, descendants(c)...) }
+    }
+    return out
+}
+
+// children returns the member values of a mapping or the elements of a sequence.
+func children(n *yaml.Node) []*yaml.Node {
+    switch n.Kind {
+    case yaml.MappingNode:
+        var out []*yaml.Node
+        for i := 1; i < len(n.Content); i += 2 {
+            out = append(out, n.Content[i])
+        }
+        return out
+    case yaml.SequenceNode:
+        return n.Content
+    }
+    return nil
+}
+
+// apply returns the children of n the segment selects.
+func (s segment) apply(n *yaml.Node) []*yaml.Node {
+    switch s.kind {
+    case segName:
+        if n.Kind != yaml.MappingNode { return nil }
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if n.Content[i].Value == s.name { return []*yaml.Node{n.Content[i+1]} }
+        }
+    case segWildcard:
+        return children(n)
+    case segIndex:
+        if n.Kind != yaml.SequenceNode { return nil }
+        i := s.index
+        if i < 0 { i += len(n.Content) }
+        if i >= 0 && i < len(n.Content) { return []*yaml.Node{n.Content[i]} }
+    case segFilter:
+        var out []*yaml.Node
+        for _, c := range children(n) {
+            if s.filter.holds(c) { out = append(out, c) }
+        }
+        return out
+    }
+    return nil
+}
+
+// holds reports whether the filter accepts n.
+func (f *filter) holds(n *yaml.Node) bool {
+    for _, name := range f.path {
+        if n.Kind != yaml.MappingNode { return false }
+        var next *yaml.Node
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if n.Content[i].Value == name { next = n.Content[i+1] }
+        }
+        if next == nil { return false }
+        n = next
+    }
+    if f.op == "" { return true }
+    equal := n.Kind == yaml.ScalarNode && n.Value == f.value && (!f.str || n.Tag == "!!str" || n.Tag == "")
+    return equal == (f.op == "==")
+}
@@ -0,0 +1,165 @@
+// Package overlay applies OpenAPI Overlay 1.0 documents to a built spec, so
+// environment- or customer-specific changes (server URLs, descriptions,
+// removals) need no fork of the fragments.
+//
+// Each action selects nodes with a JSONPath target (see jsonpath.go for the
+// supported subset) and either removes them or merges its update into them:
+// members of an update object replace the target's members of the same name,
+// recursing into objects, and an update to an array is appended to it.
+// Targets selecting nothing leave the document unchanged, as the
+// specification requires.
+package overlay
+
+import (
+    "fmt"
+    "os"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Overlay is a parsed overlay document.
+type Overlay struct {
+    Path    string // file the overlay was read from, for messages
+    Title   string
+    Actions []Action
+}
+
+// Action is one entry of an overlay's actions.
+type Action struct {
+    Target      string
+    Description string
+    Update      *yaml.Node // nil for a remove action
+    Remove      bool
+    Line        int
+    segments    []segment
+}
+
+// Load reads and checks the overlay document at path.
+func Load(path string) (*Overlay, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, fmt.Errorf("read overlay: %w", err) }
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil { return nil, fmt.Errorf("%s: %v", path, err) }
+    root := yamlnode.DocRoot(&doc)
+    if root == nil || root.Kind != yaml.MappingNode { return nil, fmt.Errorf("%s: an overlay must be a mapping", path) }
+    v := yamlnode.GetKey(root, "overlay")
+    if v == nil || !strings.HasPrefix(v.Value, "1.") {
+        return nil, fmt.Errorf("%s: not an OpenAPI Overlay 1.x document (missing overlay: 1.0.0)", path)
+    }
+    o := &Overlay{Path: path}
+    if t := yamlnode.GetKey(yamlnode.GetKey(root, "info"), "title"); t != nil { o.Title = t.Value }
+    actions := yamlnode.GetKey(root, "actions")
+    if actions == nil || actions.Kind != yaml.SequenceNode || len(actions.Content) == 0 {
+        return nil, fmt.Errorf("%s: actions must be a non-empty list", path)
+    }
+    var problems []string
+    for _, a := range actions.Content {
+        act, err := parseAction(a)
+        if err != nil {
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: %v", path, a.Line, a.Column, err))
+            continue
+        }
+        o.Actions = append(o.Actions, act)
+    }
+    if len(problems) > 0 { return nil, fmt.Errorf("invalid overlay:\n  %s", strings.Join(problems, "\n  ")) }
+    return o, nil
+}
+
+func parseAction(n *yaml.Node) (Action, error) {
+    if n.Kind != yaml.MappingNode { return Action{}, fmt.Errorf("an action must be a mapping, found %s", yamlnode.KindName(n)) }
+    a := Action{Line: n.Line, Update: yamlnode.GetKey(n, "update")}
+    if t := yamlnode.GetKey(n, "target"); t != nil && t.Kind == yaml.ScalarNode { a.Target = t.Value }
+    if d := yamlnode.GetKey(n, "description"); d != nil { a.Description = d.Value }
+    if r := yamlnode.GetKey(n, "remove"); r != nil { a.Remove = r.Value == "true" }
+    switch {
+    case a.Target == "":
+        return a, fmt.Errorf("action has no target")
+    case a.Remove && a.Update != nil:
+        return a, fmt.Errorf("action %s: update and remove: true cannot be combined", a.Target)
+    case !a.Remove && a.Update == nil:
+        return a, fmt.Errorf("action %s: needs an update or remove: true", a.Target)
+    }
+    segs, err := parsePath(a.Target)
+    if err != nil { return a, err }
+    a.segments = segs
+    return a, nil
+}
+
+// Apply runs the overlay's actions on root, in order.
+func (o *Overlay) Apply(root *yaml.Node) error {
+    for _, a := range o.Actions {
+        for _, m := range selectPath(root, a.segments) {
+            if a.Remove {
+                if m.parent == nil { return fmt.Errorf("%s:%d: action %s: cannot remove the whole document", o.Path, a.Line, a.Target) }
+                removeChild(m.parent, m.node)
+                continue
+            }
+            if err := merge(m.node, a.Update); err != nil {
+                return fmt.Errorf("%s:%d: action %s: %v", o.Path, a.Line, a.Target, err)
+            }
+        }
+    }
+    return nil
+}
+
+// merge applies update to target in place.
+func merge(target, update *yaml.Node) error {
+    switch {
+    case target.Kind == yaml.SequenceNode:
+        if update.Kind == yaml.SequenceNode {
+            for _, c := range update.Content {
+                target.Content = append(target.Content, copyNode(c))
+            }
+        } else {
+            target.Content = append(target.Content, copyNode(update))
+        }
+    case target.Kind == yaml.MappingNode && update.Kind == yaml.MappingNode:
+        for i := 0; i+1 < len(update.Content); i += 2 {
+            k, v := update.Content[i].Value, update.Content[i+1]
+            if cur := yamlnode.GetKey(target, k); cur != nil && cur.Kind == yaml.MappingNode && v.Kind == yaml.MappingNode {
+                if err := merge(cur, v); err != nil { return err }
+                continue
+            }
+            yamlnode.SetKey(target, k, copyNode(v))
+        }
+    case target.Kind == yaml.MappingNode:
+        return fmt.Errorf("cannot update an object with %s", yamlnode.KindName(update))
+    default:
+        return fmt.Errorf("cannot update %s; target its parent object instead", yamlnode.KindName(target))
+    }
+    return nil
+}
+
+// removeChild deletes the member value or element n from parent.
+func removeChild(parent, n *yaml.Node) {
+    switch parent.Kind {
+    case yaml.MappingNode:
+        for i := 1; i < len(parent.Content); i += 2 {
+            if parent.Content[i] == n {
+                parent.Content = append(parent.Content[:i-1], parent.Content[i+1:]...)
+                return
+            }
+        }
+    case yaml.SequenceNode:
+        for i, c := range parent.Content {
+            if c == n {
+                parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+                return
+            }
+        }
+    }
+}
+
+// copyNode deep-copies n, so one update merged into several targets does not
+// share nodes between them.
+func copyNode(n *yaml.Node) *yaml.Node {
+    c := *n
+    c.Content = make([]*yaml.Node, len(n.Content))
+    for i, ch := range n.Content {
+        c.Content[i] = copyNode(ch)
+    }
+    return &c
+}
@@ -0,0 +1,155 @@
+package overlay
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+const spec = `openapi: 3.0.3
+info:
+  title: Pets
+  version: 1.0.0
+servers:
+  - url: https://dev.example.com
+paths:
+  /pets:
+    get:
+      summary: List pets
+      x-internal: true
+      tags: [pets]
+    post:
+      summary: Add a pet
+      tags: [pets]
+  /admin:
+    get:
+      summary: Admin
+      x-internal: true
+`
+
+// loadOverlay writes the overlay text to a file and loads it.
+func loadOverlay(t *testing.T, text string) (*Overlay, error) {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "overlay.yaml")
+    if err := os.WriteFile(path, []byte(text), 0o644); err != nil { t.Fatal(err) }
+    return Load(path)
+}
+
+func TestApply(t *testing.T) {
+    tests := []struct {
+        name    string
+        actions string
+        want    string // the changed part of spec, as a YAML document holding the top-level keys compared
+    }{
+        {
+            name: "update merges into objects and appends to arrays",
+            actions: `  - target: $.info
+    update:
+      title: Pets (public)
+      x-audience: public
+  - target: $.servers
+    update:
+      url: https://api.example.com
+`,
+            want: `info:
+  title: Pets (public)
+  version: 1.0.0
+  x-audience: public
+servers:
+  - url: https://dev.example.com
+  - url: https://api.example.com
+`,
+        },
+        {
+            name: "remove with a filter and a descendant wildcard",
+            actions: `  - target: $.paths.*[?@.x-internal == true]
+    remove: true
+  - target: $..tags
+    remove: true
+`,
+            want: `paths:
+  /pets:
+    post:
+      summary: Add a pet
+  /admin: {}
+`,
+        },
+        {
+            name: "bracket names, indexes and targets selecting nothing",
+            actions: `  - target: $.paths['/pets'].post
+    update:
+      operationId: addPet
+  - target: $.servers[0]
+    remove: true
+  - target: $.paths['/missing']
+    update:
+      summary: never applied
+`,
+            want: `servers: []
+paths:
+  /pets:
+    get:
+      summary: List pets
+      x-internal: true
+      tags: [pets]
+    post:
+      summary: Add a pet
+      tags: [pets]
+      operationId: addPet
+  /admin:
+    get:
+      summary: Admin
+      x-internal: true
+`,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            o, err := loadOverlay(t, "overlay: 1.0.0\ninfo:\n  title: test\n  version: 1.0.0\nactions:\n"+tt.actions)
+            if err != nil { t.Fatal(err) }
+            var doc yaml.Node
+            if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+            root := doc.Content[0]
+            if err := o.Apply(root); err != nil { t.Fatal(err) }
+            var got, want map[string]any
+            if err := root.Decode(&got); err != nil { t.Fatal(err) }
+            if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil { t.Fatal(err) }
+            for k, w := range want {
+                if !reflect.DeepEqual(got[k], w) { t.Errorf("%s = %v, want %v", k, got[k], w) }
+            }
+        })
+    }
+}
+
+func TestLoadErrors(t *testing.T) {
+    tests := []struct{ name, text, want string }{
+        {"not an overlay", "openapi: 3.0.3\n", "not an OpenAPI Overlay"},
+        {"no actions", "overlay: 1.0.0\nactions: []\n", "non-empty list"},
+        {"no target", "overlay: 1.0.0\nactions:\n  - remove: true\n", "no target"},
+        {"update and remove", "overlay: 1.0.0\nactions:\n  - target: $.info\n    remove: true\n    update: {}\n", "cannot be combined"},
+        {"neither", "overlay: 1.0.0\nactions:\n  - target: $.info\n", "needs an update"},
+        {"bad path", "overlay: 1.0.0\nactions:\n  - target: info\n    remove: true\n", "must start with $"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := loadOverlay(t, tt.text)
+            if err == nil || !strings.Contains(err.Error(), tt.want) {
+                t.Errorf("Load error = %v, want one mentioning %q", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestApplyErrors(t *testing.T) {
+    o, err := loadOverlay(t, "overlay: 1.0.0\nactions:\n  - target: $.info.title\n    update: {x: 1}\n")
+    if err != nil { t.Fatal(err) }
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+    if err := o.Apply(doc.Content[0]); err == nil || !strings.Contains(err.Error(), "parent object") {
+        t.Errorf("updating a scalar: error = %v", err)
+    }
+}
@@ -0,0 +1,315 @@
+package postman
+
+import (
+    "encoding/json"
+    "errors"
+    "net/url"
+    "regexp"
+    "strings"
+)
+
+// Text is a description, which collections may also hold as an object
+// with its content and type.
+type Text string
+
+func (t *Text) UnmarshalJSON(b []byte) error {
+    var s string
+    if err := json.Unmarshal(b, &s); err == nil {
+        *t = Text(s)
+        return nil
+    }
+    var obj struct {
+        Content string `json:"content"`
+    }
+    if err := json.Unmarshal(b, &obj); err != nil { return err }
+    *t = Text(obj.Content)
+    return nil
+}
+
+// UnmarshalJSON accepts any JSON value, since collection variables may hold
+// numbers and booleans.
+func (kv *KeyValue) UnmarshalJSON(b []byte) error {
+    type plain KeyValue
+    var raw struct {
+        plain
+        Value interface{} `json:"value"`
+    }
+    if err := json.Unmarshal(b, &raw); err != nil { return err }
+    *kv = KeyValue(raw.plain)
+    switch v := raw.Value.(type) {
+    case nil:
+    case string:
+        kv.Value = v
+    default:
+        data, _ := json.Marshal(v)
+        kv.Value = string(data)
+    }
+    return nil
+}
+
+// UnmarshalJSON accepts a request given as just its URL.
+func (r *Request) UnmarshalJSON(b []byte) error {
+    var s string
+    if err := json.Unmarshal(b, &s); err == nil {
+        *r = Request{Method: "GET", URL: URL{Raw: s}}
+        return nil
+    }
+    type plain Request
+    var raw struct {
+        plain
+        Header json.RawMessage `json:"header"`
+    }
+    if err := json.Unmarshal(b, &raw); err != nil { return err }
+    *r = Request(raw.plain)
+    r.Header = decodeHeaders(raw.Header)
+    return nil
+}
+
+// UnmarshalJSON accepts a URL given as a string, and host and path given as
+// strings rather than lists of segments.
+func (u *URL) UnmarshalJSON(b []byte) error {
+    var s string
+    if err := json.Unmarshal(b, &s); err == nil {
+        *u = URL{Raw: s}
+        return nil
+    }
+    type plain URL
+    var raw struct {
+        plain
+        Host json.RawMessage `json:"host"`
+        Path json.RawMessage `json:"path"`
+    }
+    if err := json.Unmarshal(b, &raw); err != nil { return err }
+    *u = URL(raw.plain)
+    u.Host, u.Path = segments(raw.Host, "."), segments(raw.Path, "/")
+    return nil
+}
+
+func (r *Response) UnmarshalJSON(b []byte) error {
+    type plain Response
+    var raw struct {
+        plain
+        Header json.RawMessage `json:"header"`
+    }
+    if err := json.Unmarshal(b, &raw); err != nil { return err }
+    *r = Response(raw.plain)
+    r.Header = decodeHeaders(raw.Header)
+    return nil
+}
+
+// decodeHeaders reads a header list, or a raw "Name: value" block.
+func decodeHeaders(b json.RawMessage) []KeyValue {
+    var list []KeyValue
+    if json.Unmarshal(b, &list) == nil { return list }
+    var s string
+    if json.Unmarshal(b, &s) != nil { return nil }
+    for _, line := range strings.Split(s, "\n") {
+        if k, v, ok := strings.Cut(line, ":"); ok { list = append(list, KeyValue{Key: strings.TrimSpace(k), Value: strings.TrimSpace(v)}) }
+    }
+    return list
+}
+
+// segments reads a list of segments (strings, or objects with a value), or
+// a string split at sep.
+func segments(b json.RawMessage, sep string) []string {
+    var s string
+    if json.Unmarshal(b, &s) == nil {
+        return strings.Split(strings.Trim(s, sep), sep)
+    }
+    var list []json.RawMessage
+    if json.Unmarshal(b, &list) != nil { return nil }
+    var out []string
+    for _, item := range list {
+        var seg string
+        if json.Unmarshal(item, &seg) != nil {
+            var obj struct {
+                Value string `json:"value"`
+            }
+            json.Unmarshal(item, &obj)
+            seg = obj.Value
+        }
+        out = append(out, seg)
+    }
+    return out
+}
+
+// Decode reads a Postman v2.0 or v2.1 collection.
+func Decode(data []byte) (*Collection, error) {
+    var c Collection
+    if err := json.Unmarshal(data, &c); err != nil { return nil, err }
+    if c.Item == nil {
+        if strings.Contains(c.Info.Schema, "v1") { return nil, errors.New("Postman v1 collections are not supported; export the collection as v2.1") }
+        return nil, errors.New("not a Postman collection: no items")
+    }
+    return &c, nil
+}
+
+// Call is one request of a collection, with its saved responses, in the
+// terms of the path fragment it becomes.
+type Call struct {
+    Folder      string // top-level folder, the operation's tag
+    Name        string
+    Description string
+    Method      string
+    Path        string            // with {name} for :name and {{name}} segments
+    PathValues  map[string]string // sample value of each {name}
+    Query       []KeyValue        // disabled ones are optional
+    BodyMime    string
+    Body        *Body
+    Responses   []Example
+}
+
+// Example is a saved response.
+type Example struct {
+    Code     int
+    MimeType string
+    Body     string
+}
+
+var reVariable = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// Calls flattens the collection's folders into its requests, with the
+// collection's variables substituted where they are known.
+func (c *Collection) Calls() []Call {
+    vars := map[string]string{}
+    for _, v := range c.Variable { vars[v.Key] = v.Value }
+    resolve := func(s string) string {
+        return reVariable.ReplaceAllStringFunc(s, func(m string) string {
+            if v, ok := vars[reVariable.FindStringSubmatch(m)[1]]; ok { return v }
+            return m
+        })
+    }
+    var out []Call
+    var walk func(items []*Item, folder string)
+    walk = func(items []*Item, folder string) {
+        for _, it := range items {
+            if it.Request == nil {
+                f := folder
+                if f == "" { f = it.Name }
+                walk(it.Item, f)
+                continue
+            }
+            out = append(out, call(it, folder, vars, resolve))
+        }
+    }
+    walk(c.Item, "")
+    return out
+}
+
+func call(it *Item, folder string, vars map[string]string, resolve func(string) string) Call {
+    req := it.Request
+    c := Call{
+        Folder:      folder,
+        Name:        it.Name,
+        Description: firstNonEmpty(string(it.Description), string(req.Description)),
+        Method:      strings.ToUpper(firstNonEmpty(req.Method, "GET")),
+        PathValues:  map[string]string{},
+    }
+    pathVars := map[string]string{}
+    for _, v := range req.URL.Variable { pathVars[v.Key] = resolve(v.Value) }
+
+    segs, query := req.URL.Path, req.URL.Query
+    if len(segs) == 0 && req.URL.Raw != "" { segs, query = splitRaw(req.URL.Raw, query) }
+    var path []string
+    for _, s := range segs {
+        switch m := reVariable.FindStringSubmatch(s); {
+        case s == "":
+            continue
+        case strings.HasPrefix(s, ":"):
+            name := s[1:]
+            c.PathValues[name] = pathVars[name]
+            s = "{" + name + "}"
+        case m != nil && m[0] == s:
+            c.PathValues[m[1]] = vars[m[1]]
+            s = "{" + m[1] + "}"
+        default:
+            s = resolve(s)
+        }
+        path = append(path, s)
+    }
+    c.Path = "/" + strings.Join(path, "/")
+    for _, q := range query {
+        q.Value = resolve(q.Value)
+        c.Query = append(c.Query, q)
+    }
+
+    if b := req.Body; b != nil && !b.empty() {
+        c.BodyMime = bodyMime(b, header(req.Header, "Content-Type"))
+        body := *b
+        body.Raw = resolve(b.Raw)
+        c.Body = &body
+    }
+    for _, r := range it.Response {
+        mt := strings.TrimSpace(strings.Split(header(r.Header, "Content-Type"), ";")[0])
+        if mt == "" && r.Language == "json" { mt = "application/json" }
+        c.Responses = append(c.Responses, Example{Code: r.Code, MimeType: mt, Body: r.Body})
+    }
+    return c
+}
+
+// empty reports a body Postman would not send.
+func (b *Body) empty() bool {
+    switch b.Mode {
+    case "raw":
+        return strings.TrimSpace(b.Raw) == ""
+    case "urlencoded":
+        return len(b.URLEncoded) == 0
+    case "formdata":
+        return len(b.FormData) == 0
+    }
+    return true
+}
+
+// bodyMime is the request's Content-Type, else the one its body mode and
+// raw language imply.
+func bodyMime(b *Body, contentType string) string {
+    if mt := strings.TrimSpace(strings.Split(contentType, ";")[0]); mt != "" && !reVariable.MatchString(mt) { return strings.ToLower(mt) }
+    switch b.Mode {
+    case "urlencoded":
+        return "application/x-www-form-urlencoded"
+    case "formdata":
+        return "multipart/form-data"
+    }
+    lang := ""
+    if b.Options != nil { lang = b.Options.Raw.Language }
+    switch lang {
+    case "json":
+        return "application/json"
+    case "xml":
+        return "application/xml"
+    case "html":
+        return "text/html"
+    case "javascript":
+        return "application/javascript"
+    }
+    if t := strings.TrimSpace(b.Raw); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") { return "application/json" }
+    return "text/plain"
+}
+
+// splitRaw takes the path segments and query of a raw URL such as
+// {{baseUrl}}/users/:id?limit=10, dropping its scheme and host.
+func splitRaw(raw string, query []KeyValue) ([]string, []KeyValue) {
+    rest, q, _ := strings.Cut(raw, "?")
+    if i := strings.Index(rest, "://"); i >= 0 { rest = rest[i+3:] }
+    if i := strings.Index(rest, "/"); i >= 0 {
+        rest = rest[i:]
+    } else {
+        rest = ""
+    }
+    if len(query) == 0 && q != "" {
+        for _, pair := range strings.Split(q, "&") {
+            k, v, _ := strings.Cut(pair, "=")
+            if uk, err := url.QueryUnescape(k); err == nil { k = uk }
+            if uv, err := url.QueryUnescape(v); err == nil { v = uv }
+            query = append(query, KeyValue{Key: k, Value: v})
+        }
+    }
+    return strings.Split(strings.Trim(rest, "/"), "/"), query
+}
+
+func header(list []KeyValue, name string) string {
+    for _, h := range list {
+        if !h.Disabled && strings.EqualFold(h.Key, name) { return h.Value }
+    }
+    return ""
+}
@@ -0,0 +1,522 @@
+// Package postman converts a bundled OpenAPI spec into a Postman v2.1
+// collection, with an environment holding the server URL and the
+// credentials of its security schemes.
+//
+// Operations are grouped into folders by their first tag. Request bodies,
+// parameters and saved responses use the spec's examples, falling back to
+// values generated from the schemas by pkg/examples.
+package postman
+
+import (
+    "fmt"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/examples"
+)
+
+// SchemaURL identifies the collection format written by Export.
+const SchemaURL = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// BaseURL is the variable holding the server URL every request starts with.
+const BaseURL = "baseUrl"
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Collection is a Postman v2.1 collection.
+type Collection struct {
+    Info     Info       `json:"info"`
+    Item     []*Item    `json:"item"`
+    Auth     *Auth      `json:"auth,omitempty"`
+    Variable []KeyValue `json:"variable,omitempty"`
+}
+
+type Info struct {
+    Name        string `json:"name"`
+    Description Text   `json:"description,omitempty"`
+    Schema      string `json:"schema"`
+}
+
+// Item is a folder (with Item) or a request (with Request).
+type Item struct {
+    Name        string     `json:"name"`
+    Description Text       `json:"description,omitempty"`
+    Item        []*Item    `json:"item,omitempty"`
+    Request     *Request   `json:"request,omitempty"`
+    Response    []Response `json:"response,omitempty"`
+}
+
+type Request struct {
+    Method      string     `json:"method"`
+    Header      []KeyValue `json:"header"`
+    Body        *Body      `json:"body,omitempty"`
+    URL         URL        `json:"url"`
+    Auth        *Auth      `json:"auth,omitempty"`
+    Description Text       `json:"description,omitempty"`
+}
+
+type URL struct {
+    Raw      string     `json:"raw"`
+    Host     []string   `json:"host,omitempty"`
+    Path     []string   `json:"path,omitempty"`
+    Query    []KeyValue `json:"query,omitempty"`
+    Variable []KeyValue `json:"variable,omitempty"`
+}
+
+// KeyValue is a header, query or form parameter, path or collection
+// variable, or auth attribute.
+type KeyValue struct {
+    Key         string `json:"key"`
+    Value       string `json:"value"`
+    Type        string `json:"type,omitempty"`
+    Description Text   `json:"description,omitempty"`
+    Disabled    bool   `json:"disabled,omitempty"`
+}
+
+type Body struct {
+    Mode       string       `json:"mode"`
+    Raw        string       `json:"raw,omitempty"`
+    URLEncoded []KeyValue   `json:"urlencoded,omitempty"`
+    FormData   []KeyValue   `json:"formdata,omitempty"`
+    Options    *BodyOptions `json:"options,omitempty"`
+}
+
+type BodyOptions struct {
+    Raw struct {
+        Language string `json:"language"`
+    } `json:"raw"`
+}
+
+// Auth holds the attributes of its Type under the key of the same name.
+type Auth struct {
+    Type   string     `json:"type"`
+    Bearer []KeyValue `json:"bearer,omitempty"`
+    Basic  []KeyValue `json:"basic,omitempty"`
+    APIKey []KeyValue `json:"apikey,omitempty"`
+    OAuth2 []KeyValue `json:"oauth2,omitempty"`
+}
+
+// Response is a saved example response.
+type Response struct {
+    Name            string     `json:"name"`
+    OriginalRequest *Request   `json:"originalRequest,omitempty"`
+    Status          string     `json:"status"`
+    Code            int        `json:"code"`
+    Language        string     `json:"_postman_previewlanguage,omitempty"`
+    Header          []KeyValue `json:"header"`
+    Body            string     `json:"body"`
+}
+
+// Environment is a Postman environment file.
+type Environment struct {
+    Name   string     `json:"name"`
+    Values []EnvValue `json:"values"`
+    Scope  string     `json:"_postman_variable_scope"`
+}
+
+type EnvValue struct {
+    Key     string `json:"key"`
+    Value   string `json:"value"`
+    Type    string `json:"type"` // default or secret
+    Enabled bool   `json:"enabled"`
+}
+
+// Options tune Export.
+type Options struct {
+    Name   string // collection name; default: info.title
+    Server int    // index of the server the environment points at
+}
+
+type exporter struct {
+    root *yaml.Node
+    gen  *examples.Generator
+    env  *Environment
+}
+
+var reServerVar = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Export converts spec, the top-level node of a bundled document, into a
+// collection and the environment its variables come from.
+func Export(spec *yaml.Node, opts Options) (*Collection, *Environment, error) {
+    if spec == nil || spec.Kind != yaml.MappingNode { return nil, nil, fmt.Errorf("spec must be a mapping") }
+    e := &exporter{root: spec, gen: &examples.Generator{Root: spec}}
+    info := yamlnode.GetKey(spec, "info")
+    name := firstNonEmpty(opts.Name, scalar(info, "title"), "API")
+    c := &Collection{Info: Info{Name: name, Description: Text(scalar(info, "description")), Schema: SchemaURL}}
+    e.env = &Environment{Name: name, Scope: "environment"}
+
+    base, err := e.serverVars(yamlnode.GetKey(spec, "servers"), opts.Server)
+    if err != nil { return nil, nil, err }
+    c.Variable = append(c.Variable, KeyValue{Key: BaseURL, Value: base})
+    e.securityVars()
+    if sec := yamlnode.GetKey(spec, "security"); sec != nil { c.Auth = e.auth(sec) }
+
+    folders := map[string]*Item{}
+    addFolder := func(tag, desc string) *Item {
+        if f := folders[tag]; f != nil { return f }
+        f := &Item{Name: tag, Description: Text(desc)}
+        folders[tag] = f
+        c.Item = append(c.Item, f)
+        return f
+    }
+    if tags := yamlnode.GetKey(spec, "tags"); tags != nil && tags.Kind == yaml.SequenceNode {
+        for _, t := range tags.Content {
+            if n := scalar(t, "name"); n != "" { addFolder(n, scalar(t, "description")) }
+        }
+    }
+    if paths := yamlnode.GetKey(spec, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(paths.Content); i += 2 {
+            path, pathItem := paths.Content[i].Value, e.deref(paths.Content[i+1])
+            for _, m := range httpMethods {
+                op := e.deref(yamlnode.GetKey(pathItem, m))
+                if op == nil || op.Kind != yaml.MappingNode { continue }
+                item := e.operation(path, m, pathItem, op)
+                if tags := yamlnode.GetKey(op, "tags"); tags != nil && tags.Kind == yaml.SequenceNode && len(tags.Content) > 0 {
+                    f := addFolder(tags.Content[0].Value, "")
+                    f.Item = append(f.Item, item)
+                } else {
+                    c.Item = append(c.Item, item)
+                }
+            }
+        }
+    }
+    // Declared tags without operations would be empty folders.
+    items := c.Item[:0]
+    for _, it := range c.Item {
+        if it.Request != nil || len(it.Item) > 0 { items = append(items, it) }
+    }
+    c.Item = items
+    return c, e.env, nil
+}
+
+// serverVars adds the URL of the chosen server, with its variables turned
+// into environment variables, to the environment and returns the URL with
+// the variables' defaults for the collection.
+func (e *exporter) serverVars(servers *yaml.Node, index int) (string, error) {
+    if servers == nil || servers.Kind != yaml.SequenceNode || len(servers.Content) == 0 {
+        e.setEnv(BaseURL, "http://localhost", false)
+        return "http://localhost", nil
+    }
+    if index < 0 || index >= len(servers.Content) {
+        return "", fmt.Errorf("server %d out of range (the spec has %d)", index, len(servers.Content))
+    }
+    s := servers.Content[index]
+    raw := strings.TrimSuffix(scalar(s, "url"), "/")
+    vars := yamlnode.GetKey(s, "variables")
+    templated := reServerVar.ReplaceAllStringFunc(raw, func(m string) string {
+        name := m[1 : len(m)-1]
+        e.setEnv(name, scalar(yamlnode.GetKey(vars, name), "default"), false)
+        return "{{" + name + "}}"
+    })
+    e.env.Values = append([]EnvValue{{Key: BaseURL, Value: templated, Type: "default", Enabled: true}}, e.env.Values...)
+    return reServerVar.ReplaceAllStringFunc(raw, func(m string) string {
+        return scalar(yamlnode.GetKey(vars, m[1:len(m)-1]), "default")
+    }), nil
+}
+
+func (e *exporter) setEnv(key, value string, secret bool) {
+    for _, v := range e.env.Values {
+        if v.Key == key { return }
+    }
+    typ := "default"
+    if secret { typ = "secret" }
+    e.env.Values = append(e.env.Values, EnvValue{Key: key, Value: value, Type: typ, Enabled: true})
+}
+
+// securityVars adds the credentials of every security scheme to the
+// environment, empty, for the user to fill in.
+func (e *exporter) securityVars() {
+    schemes := yamlnode.GetKey(yamlnode.GetKey(e.root, "components"), "securitySchemes")
+    if schemes == nil || schemes.Kind != yaml.MappingNode { return }
+    for i := 0; i+1 < len(schemes.Content); i += 2 {
+        name, s := schemes.Content[i].Value, e.deref(schemes.Content[i+1])
+        for _, v := range schemeVars(name, s) { e.setEnv(v, "", true) }
+    }
+}
+
+// schemeVars names the environment variables holding the credentials of
+// the security scheme s.
+func schemeVars(name string, s *yaml.Node) []string {
+    switch scalar(s, "type") {
+    case "http":
+        if strings.EqualFold(scalar(s, "scheme"), "basic") { return []string{name + "Username", name + "Password"} }
+        return []string{name + "Token"}
+    case "apiKey":
+        return []string{name + "Key"}
+    case "oauth2", "openIdConnect":
+        return []string{name + "AccessToken", name + "ClientId", name + "ClientSecret"}
+    }
+    return nil
+}
+
+// auth converts the first requirement of a security list; an empty list (or
+// an optional {} requirement) means no auth.
+func (e *exporter) auth(sec *yaml.Node) *Auth {
+    if sec.Kind != yaml.SequenceNode { return nil }
+    if len(sec.Content) == 0 || len(sec.Content[0].Content) == 0 { return &Auth{Type: "noauth"} }
+    name := sec.Content[0].Content[0].Value
+    s := e.deref(yamlnode.GetKey(yamlnode.GetKey(yamlnode.GetKey(e.root, "components"), "securitySchemes"), name))
+    if s == nil { return nil }
+    ref := func(v string) string { return "{{" + v + "}}" }
+    switch scalar(s, "type") {
+    case "http":
+        if strings.EqualFold(scalar(s, "scheme"), "basic") {
+            return &Auth{Type: "basic", Basic: []KeyValue{
+                {Key: "username", Value: ref(name + "Username"), Type: "string"},
+                {Key: "password", Value: ref(name + "Password"), Type: "string"},
+            }}
+        }
+        return &Auth{Type: "bearer", Bearer: []KeyValue{{Key: "token", Value: ref(name + "Token"), Type: "string"}}}
+    case "apiKey":
+        in := scalar(s, "in")
+        if in == "cookie" { return nil } // Postman has no cookie API keys; see the Cookie header
+        return &Auth{Type: "apikey", APIKey: []KeyValue{
+            {Key: "key", Value: scalar(s, "name"), Type: "string"},
+            {Key: "value", Value: ref(name + "Key"), Type: "string"},
+            {Key: "in", Value: in, Type: "string"},
+        }}
+    case "oauth2", "openIdConnect":
+        attrs := []KeyValue{
+            {Key: "accessToken", Value: ref(name + "AccessToken"), Type: "string"},
+            {Key: "addTokenTo", Value: "header", Type: "string"},
+        }
+        flows := yamlnode.GetKey(s, "flows")
+        for _, f := range []struct{ key, grant string }{
+            {"authorizationCode", "authorization_code"},
+            {"clientCredentials", "client_credentials"},
+            {"implicit", "implicit"},
+            {"password", "password_credentials"},
+        } {
+            flow := yamlnode.GetKey(flows, f.key)
+            if flow == nil { continue }
+            attrs = append(attrs, KeyValue{Key: "grant_type", Value: f.grant, Type: "string"})
+            if u := scalar(flow, "authorizationUrl"); u != "" { attrs = append(attrs, KeyValue{Key: "authUrl", Value: u, Type: "string"}) }
+            if u := scalar(flow, "tokenUrl"); u != "" { attrs = append(attrs, KeyValue{Key: "accessTokenUrl", Value: u, Type: "string"}) }
+            attrs = append(attrs,
+                KeyValue{Key: "clientId", Value: ref(name + "ClientId"), Type: "string"},
+                KeyValue{Key: "clientSecret", Value: ref(name + "ClientSecret"), Type: "string"})
+            var scopes []string
+            for _, sc := range sec.Content[0].Content[1].Content { scopes = append(scopes, sc.Value) }
+            if len(scopes) > 0 { attrs = append(attrs, KeyValue{Key: "scope", Value: strings.Join(scopes, " "), Type: "string"}) }
+            break
+        }
+        return &Auth{Type: "oauth2", OAuth2: attrs}
+    }
+    return nil
+}
+
+// operation converts the operation method of path into a request item.
+func (e *exporter) operation(path, method string, pathItem, op *yaml.Node) *Item {
+    req := &Request{Method: strings.ToUpper(method), Header: []KeyValue{}, Description: Text(scalar(op, "description"))}
+    segs := []string{}
+    rawPath := ""
+    for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+        if s == "" { continue }
+        if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") { s = ":" + s[1:len(s)-1] }
+        segs = append(segs, s)
+        rawPath += "/" + s
+    }
+    req.URL = URL{Host: []string{"{{" + BaseURL + "}}"}, Path: segs}
+
+    for _, p := range e.parameters(pathItem, op) {
+        name, in := scalar(p, "name"), scalar(p, "in")
+        kv := KeyValue{Key: name, Value: e.paramValue(p), Description: Text(scalar(p, "description"))}
+        switch in {
+        case "path":
+            req.URL.Variable = append(req.URL.Variable, kv)
+        case "query":
+            kv.Disabled = scalar(p, "required") != "true"
+            req.URL.Query = append(req.URL.Query, kv)
+        case "header":
+            // OpenAPI ignores these as parameters; they come from the body, responses and auth.
+            switch strings.ToLower(name) {
+            case "accept", "content-type", "authorization":
+                continue
+            }
+            kv.Disabled = scalar(p, "required") != "true"
+            req.Header = append(req.Header, kv)
+        case "cookie":
+            req.Header = append(req.Header, KeyValue{Key: "Cookie", Value: name + "=" + kv.Value, Description: kv.Description, Disabled: scalar(p, "required") != "true"})
+        }
+    }
+    raw := "{{" + BaseURL + "}}" + rawPath
+    var q []string
+    for _, kv := range req.URL.Query {
+        if !kv.Disabled { q = append(q, kv.Key+"="+kv.Value) }
+    }
+    if len(q) > 0 { raw += "?" + strings.Join(q, "&") }
+    req.URL.Raw = raw
+
+    if rb := e.deref(yamlnode.GetKey(op, "requestBody")); rb != nil {
+        if mt, media := pickMedia(yamlnode.GetKey(rb, "content")); media != nil {
+            // Postman sets the multipart header itself, with the boundary.
+            if mt != "multipart/form-data" { req.Header = append(req.Header, KeyValue{Key: "Content-Type", Value: mt}) }
+            req.Body = e.body(mt, media)
+        }
+    }
+    if sec := yamlnode.GetKey(op, "security"); sec != nil { req.Auth = e.auth(sec) }
+
+    item := &Item{Name: firstNonEmpty(scalar(op, "summary"), scalar(op, "operationId"), req.Method+" "+path), Request: req}
+    if responses := yamlnode.GetKey(op, "responses"); responses != nil && responses.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(responses.Content); i += 2 {
+            code := responseCode(responses.Content[i].Value)
+            if code == 0 { continue }
+            resp := e.deref(responses.Content[i+1])
+            out := Response{
+                Name:            firstNonEmpty(scalar(resp, "description"), strconv.Itoa(code)+" "+http.StatusText(code)),
+                OriginalRequest: req,
+                Status:          http.StatusText(code),
+                Code:            code,
+                Header:          []KeyValue{},
+            }
+            if mt, media := pickMedia(yamlnode.GetKey(resp, "content")); media != nil {
+                out.Header = append(out.Header, KeyValue{Key: "Content-Type", Value: mt})
+                out.Body = render(e.example(media, false))
+                out.Language = language(mt)
+            }
+            item.Response = append(item.Response, out)
+        }
+    }
+    return item
+}
+
+// parameters merges the path item's parameters with the operation's, which
+// override them by name and location.
+func (e *exporter) parameters(pathItem, op *yaml.Node) []*yaml.Node {
+    var out []*yaml.Node
+    index := map[string]int{}
+    for _, list := range []*yaml.Node{yamlnode.GetKey(pathItem, "parameters"), yamlnode.GetKey(op, "parameters")} {
+        if list == nil || list.Kind != yaml.SequenceNode { continue }
+        for _, p := range list.Content {
+            p = e.deref(p)
+            if p == nil { continue }
+            key := scalar(p, "in") + ":" + scalar(p, "name")
+            if i, ok := index[key]; ok {
+                out[i] = p
+                continue
+            }
+            index[key] = len(out)
+            out = append(out, p)
+        }
+    }
+    return out
+}
+
+// paramValue is the example of parameter p as it appears in a URL or header.
+func (e *exporter) paramValue(p *yaml.Node) string {
+    v := e.example(p, true)
+    if v.Kind == yaml.SequenceNode {
+        var parts []string
+        for _, c := range v.Content { parts = append(parts, render(c)) }
+        return strings.Join(parts, ",")
+    }
+    return render(v)
+}
+
+// body converts the example of a request media type into a Postman body.
+func (e *exporter) body(mt string, media *yaml.Node) *Body {
+    v := e.example(media, true)
+    switch mt {
+    case "application/x-www-form-urlencoded", "multipart/form-data":
+        schema := e.deref(yamlnode.GetKey(media, "schema"))
+        var fields []KeyValue
+        if v.Kind == yaml.MappingNode {
+            for i := 0; i+1 < len(v.Content); i += 2 {
+                key := v.Content[i].Value
+                kv := KeyValue{Key: key, Value: render(v.Content[i+1]), Type: "text"}
+                prop := e.deref(yamlnode.GetKey(yamlnode.GetKey(schema, "properties"), key))
+                if mt == "multipart/form-data" && (scalar(prop, "format") == "binary" || scalar(prop, "format") == "base64") {
+                    kv.Value, kv.Type = "", "file"
+                }
+                fields = append(fields, kv)
+            }
+        }
+        if mt == "multipart/form-data" { return &Body{Mode: "formdata", FormData: fields} }
+        return &Body{Mode: "urlencoded", URLEncoded: fields}
+    }
+    b := &Body{Mode: "raw", Raw: render(v)}
+    if lang := language(mt); lang != "text" {
+        b.Options = &BodyOptions{}
+        b.Options.Raw.Language = lang
+    }
+    return b
+}
+
+// example returns the example of a media type or parameter: its example,
+// its first examples entry, else one generated from its schema.
+func (e *exporter) example(n *yaml.Node, request bool) *yaml.Node {
+    if ex := yamlnode.GetKey(n, "example"); ex != nil { return ex }
+    if exs := yamlnode.GetKey(n, "examples"); exs != nil && exs.Kind == yaml.MappingNode && len(exs.Content) > 1 {
+        if v := yamlnode.GetKey(e.deref(exs.Content[1]), "value"); v != nil { return v }
+    }
+    schema := yamlnode.GetKey(n, "schema")
+    if schema == nil { // a parameter may describe itself with content instead
+        if _, media := pickMedia(yamlnode.GetKey(n, "content")); media != nil { return e.example(media, request) }
+    }
+    return e.gen.Generate(schema, request)
+}
+
+// deref follows local refs such as #/components/parameters/Limit.
+func (e *exporter) deref(n *yaml.Node) *yaml.Node { return yamlnode.Deref(e.root, n) }
+
+// pickMedia prefers a JSON media type, else takes the first.
+func pickMedia(content *yaml.Node) (string, *yaml.Node) {
+    if content == nil || content.Kind != yaml.MappingNode || len(content.Content) < 2 { return "", nil }
+    for i := 0; i+1 < len(content.Content); i += 2 {
+        if language(content.Content[i].Value) == "json" { return content.Content[i].Value, content.Content[i+1] }
+    }
+    return content.Content[0].Value, content.Content[1]
+}
+
+// language is the raw body language Postman highlights mt with.
+func language(mt string) string {
+    mt = strings.ToLower(mt)
+    switch {
+    case strings.Contains(mt, "json"):
+        return "json"
+    case strings.Contains(mt, "xml"):
+        return "xml"
+    case strings.Contains(mt, "html"):
+        return "html"
+    case strings.Contains(mt, "javascript"):
+        return "javascript"
+    }
+    return "text"
+}
+
+// responseCode maps a response key to a status code: 2XX to 200, default
+// to 0 (left out).
+func responseCode(key string) int {
+    if n, err := strconv.Atoi(key); err == nil { return n }
+    if len(key) == 3 && strings.HasSuffix(strings.ToUpper(key), "XX") && key[0] >= '1' && key[0] <= '5' { return int(key[0]-'0') * 100 }
+    return 0
+}
+
+// render gives a scalar's value as is and anything else as JSON.
+func render(v *yaml.Node) string {
+    if v == nil { return "" }
+    if v.Kind == yaml.ScalarNode {
+        if v.Tag == "!!null" { return "" }
+        return v.Value
+    }
+    data, err := yamlnode.MarshalJSON(v)
+    if err != nil { return "" }
+    return strings.TrimSuffix(string(data), "\n")
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
@@ -0,0 +1,135 @@
+package postman
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+const spec = `openapi: 3.0.3
+info: {title: Pets, version: 1.0.0, description: The pet API.}
+servers:
+  - url: https://{region}.example.com/v1/
+    variables:
+      region: {default: eu}
+security:
+  - token: []
+tags:
+  - {name: pets, description: Pet operations.}
+  - {name: unused}
+paths:
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true, example: 42}
+    get:
+      tags: [pets]
+      summary: Get a pet
+      parameters:
+        - {name: fields, in: query, example: [name, age]}
+        - {name: verbose, in: query, required: true, example: true}
+        - {name: X-Trace, in: header, example: abc}
+        - {name: Accept, in: header, example: text/plain}
+      responses:
+        '200':
+          description: The pet.
+          content:
+            application/json:
+              example: {name: Rex}
+        default:
+          description: An error.
+    put:
+      tags: [pets]
+      operationId: updatePet
+      security: []
+      requestBody:
+        content:
+          application/json:
+            example: {name: Rex}
+      responses:
+        2XX: {description: Saved.}
+  /upload:
+    post:
+      security:
+        - key: []
+      requestBody:
+        content:
+          multipart/form-data:
+            schema:
+              properties:
+                file: {type: string, format: binary}
+            example: {file: x, note: hi}
+      responses: {}
+components:
+  securitySchemes:
+    token: {type: http, scheme: bearer}
+    key: {type: apiKey, in: header, name: X-Key}
+`
+
+func export(t *testing.T, opts Options) (*Collection, *Environment, error) {
+    t.Helper()
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(spec), &doc); err != nil { t.Fatal(err) }
+    return Export(doc.Content[0], opts)
+}
+
+func TestExport(t *testing.T) {
+    c, env, err := export(t, Options{})
+    if err != nil { t.Fatal(err) }
+    if c.Info.Name != "Pets" || c.Info.Description != "The pet API." || c.Info.Schema != SchemaURL { t.Errorf("info = %+v", c.Info) }
+    if want := []KeyValue{{Key: BaseURL, Value: "https://eu.example.com/v1"}}; !reflect.DeepEqual(c.Variable, want) {
+        t.Errorf("variables = %+v, want %+v", c.Variable, want)
+    }
+    if want := (&Auth{Type: "bearer", Bearer: []KeyValue{{Key: "token", Value: "{{tokenToken}}", Type: "string"}}}); !reflect.DeepEqual(c.Auth, want) {
+        t.Errorf("auth = %+v", c.Auth)
+    }
+    wantEnv := []EnvValue{
+        {Key: BaseURL, Value: "https://{{region}}.example.com/v1", Type: "default", Enabled: true},
+        {Key: "region", Value: "eu", Type: "default", Enabled: true},
+        {Key: "tokenToken", Type: "secret", Enabled: true},
+        {Key: "keyKey", Type: "secret", Enabled: true},
+    }
+    if !reflect.DeepEqual(env.Values, wantEnv) { t.Errorf("environment = %+v, want %+v", env.Values, wantEnv) }
+
+    // The unused tag has no folder; untagged operations sit at the top.
+    if len(c.Item) != 2 || c.Item[0].Name != "pets" || c.Item[0].Description != "Pet operations." || c.Item[1].Name != "POST /upload" {
+        t.Fatalf("items = %+v", c.Item)
+    }
+    get, put, upload := c.Item[0].Item[0], c.Item[0].Item[1], c.Item[1]
+
+    if get.Name != "Get a pet" { t.Errorf("name = %q", get.Name) }
+    u := get.Request.URL
+    if u.Raw != "{{baseUrl}}/pets/:petId?verbose=true" || !reflect.DeepEqual(u.Path, []string{"pets", ":petId"}) {
+        t.Errorf("url = %+v", u)
+    }
+    if want := []KeyValue{{Key: "petId", Value: "42"}}; !reflect.DeepEqual(u.Variable, want) { t.Errorf("path variables = %+v", u.Variable) }
+    wantQuery := []KeyValue{{Key: "fields", Value: "name,age", Disabled: true}, {Key: "verbose", Value: "true"}}
+    if !reflect.DeepEqual(u.Query, wantQuery) { t.Errorf("query = %+v, want %+v", u.Query, wantQuery) }
+    if want := []KeyValue{{Key: "X-Trace", Value: "abc", Disabled: true}}; !reflect.DeepEqual(get.Request.Header, want) {
+        t.Errorf("headers = %+v", get.Request.Header)
+    }
+    if len(get.Response) != 1 || get.Response[0].Code != 200 || get.Response[0].Body != "{\n  \"name\": \"Rex\"\n}" || get.Response[0].Language != "json" {
+        t.Errorf("responses = %+v", get.Response)
+    }
+
+    if put.Name != "updatePet" || put.Request.Auth == nil || put.Request.Auth.Type != "noauth" { t.Errorf("put = %+v", put.Request) }
+    if b := put.Request.Body; b == nil || b.Mode != "raw" || b.Raw != "{\n  \"name\": \"Rex\"\n}" || b.Options.Raw.Language != "json" {
+        t.Errorf("put body = %+v", b)
+    }
+    if len(put.Response) != 1 || put.Response[0].Code != 200 || put.Response[0].Status != "OK" { t.Errorf("put responses = %+v", put.Response) }
+
+    wantForm := []KeyValue{{Key: "file", Type: "file"}, {Key: "note", Value: "hi", Type: "text"}}
+    if b := upload.Request.Body; b == nil || b.Mode != "formdata" || !reflect.DeepEqual(b.FormData, wantForm) {
+        t.Errorf("upload body = %+v", b)
+    }
+    if len(upload.Request.Header) != 0 { t.Errorf("upload headers = %+v", upload.Request.Header) }
+    wantKey := &Auth{Type: "apikey", APIKey: []KeyValue{{Key: "key", Value: "X-Key", Type: "string"}, {Key: "value", Value: "{{keyKey}}", Type: "string"}, {Key: "in", Value: "header", Type: "string"}}}
+    if !reflect.DeepEqual(upload.Request.Auth, wantKey) { t.Errorf("upload auth = %+v", upload.Request.Auth) }
+}
+
+func TestExportServerOutOfRange(t *testing.T) {
+    if _, _, err := export(t, Options{Server: 1}); err == nil || !strings.Contains(err.Error(), "out of range") {
+        t.Errorf("error = %v", err)
+    }
+}
@@ -0,0 +1,445 @@
+// Package proto converts the component schemas of a bundled OpenAPI spec
+// into proto3 messages and enums, to keep gRPC contracts in line with the
+// REST API.
+//
+// Field numbers are part of the wire format, so a property pins its number
+// with x-proto-field; properties without one take the lowest free numbers in
+// declaration order, which shift when a property is inserted before them.
+// x-proto-reserved on a schema lists numbers of removed fields that must not
+// be reused.
+package proto
+
+import (
+    "fmt"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Options control the generated file.
+type Options struct {
+    Package   string // proto package (default: from info.title)
+    GoPackage string // if set, option go_package
+}
+
+// Result is a generated .proto file.
+type Result struct {
+    Data     []byte
+    Unpinned []string // Message.field of the fields numbered without x-proto-field
+}
+
+// maxDepth bounds inline expansion.
+const maxDepth = 32
+
+const (
+    structImport    = "google/protobuf/struct.proto"
+    timestampImport = "google/protobuf/timestamp.proto"
+)
+
+type generator struct {
+    root     *yaml.Node
+    schemas  *yaml.Node
+    names    map[string]string // component -> message or enum name
+    kinds    map[string]string // component -> message, enum or inline
+    imports  map[string]bool
+    outer    []string // enclosing messages, for qualified names in messages
+    unpinned []string
+    errs     []string
+}
+
+type field struct {
+    label, typ, name, jsonName, doc string
+    num                             int
+    pinned                          bool
+}
+
+// Generate converts every component schema of spec.
+func Generate(spec *yaml.Node, opts Options) (*Result, error) {
+    g := &generator{root: spec, names: map[string]string{}, kinds: map[string]string{}, imports: map[string]bool{}}
+    g.schemas = yamlnode.GetKey(yamlnode.GetKey(spec, "components"), "schemas")
+    if g.schemas == nil || g.schemas.Kind != yaml.MappingNode || len(g.schemas.Content) == 0 {
+        return nil, fmt.Errorf("the spec has no component schemas")
+    }
+    taken := map[string]bool{}
+    for i := 0; i+1 < len(g.schemas.Content); i += 2 {
+        component, schema := g.schemas.Content[i].Value, g.schemas.Content[i+1]
+        name := pascal(component)
+        for n := 2; taken[name]; n++ { name = pascal(component) + strconv.Itoa(n) }
+        taken[name] = true
+        g.names[component] = name
+        switch {
+        case isEnum(schema):
+            g.kinds[component] = "enum"
+        case isMessage(schema) || oneOfRefs(schema) != nil:
+            g.kinds[component] = "message"
+        default:
+            g.kinds[component] = "inline"
+        }
+    }
+
+    var decls []string
+    for i := 0; i+1 < len(g.schemas.Content); i += 2 {
+        component, schema := g.schemas.Content[i].Value, g.schemas.Content[i+1]
+        switch g.kinds[component] {
+        case "enum":
+            decls = append(decls, enum(g.names[component], schema, ""))
+        case "message":
+            decls = append(decls, g.message(g.names[component], schema, "", 0))
+        }
+    }
+    if len(g.errs) > 0 { return nil, fmt.Errorf("%s", strings.Join(g.errs, "\n")) }
+
+    var b strings.Builder
+    info := yamlnode.GetKey(spec, "info")
+    fmt.Fprintf(&b, "// Generated by oas-indexer from %s %s. Do not edit.\n\n", firstNonEmpty(scalar(info, "title"), "API"), scalar(info, "version"))
+    b.WriteString("syntax = \"proto3\";\n\n")
+    fmt.Fprintf(&b, "package %s;\n", firstNonEmpty(opts.Package, PackageName(scalar(info, "title"))))
+    if len(g.imports) > 0 {
+        b.WriteString("\n")
+        imports := make([]string, 0, len(g.imports))
+        for imp := range g.imports { imports = append(imports, imp) }
+        sort.Strings(imports)
+        for _, imp := range imports { fmt.Fprintf(&b, "import %q;\n", imp) }
+    }
+    if opts.GoPackage != "" { fmt.Fprintf(&b, "\noption go_package = %q;\n", opts.GoPackage) }
+    for _, d := range decls { b.WriteString("\n" + d) }
+    return &Result{Data: []byte(b.String()), Unpinned: g.unpinned}, nil
+}
+
+// message renders an object schema (or a oneOf of components) as a message,
+// with the messages of its inline objects nested in it.
+func (g *generator) message(name string, schema *yaml.Node, indent string, depth int) string {
+    var b strings.Builder
+    b.WriteString(comment(schema, indent))
+    b.WriteString(indent + "message " + name + " {\n")
+    inner := indent + "  "
+    g.outer = append(g.outer, name)
+    defer func() { g.outer = g.outer[:len(g.outer)-1] }()
+    qual := strings.Join(g.outer, ".")
+
+    if refs := oneOfRefs(schema); refs != nil {
+        b.WriteString(inner + "oneof value {\n")
+        for i, ref := range refs {
+            fmt.Fprintf(&b, "%s  %s %s = %d;\n", inner, g.names[ref], snake(ref), i+1)
+        }
+        b.WriteString(inner + "}\n" + indent + "}\n")
+        return b.String()
+    }
+
+    var fields []*field
+    var nested []string
+    nestedNames := map[string]bool{}
+    seen := map[string]bool{}
+    reserved := map[int]bool{}
+    var walk func(s *yaml.Node, depth int)
+    walk = func(s *yaml.Node, depth int) {
+        s = g.deref(s)
+        if s == nil || depth > maxDepth { return }
+        if r := yamlnode.GetKey(s, "x-proto-reserved"); r != nil {
+            for _, n := range r.Content {
+                if v, err := strconv.Atoi(n.Value); err == nil { reserved[v] = true }
+            }
+        }
+        if all := yamlnode.GetKey(s, "allOf"); all != nil && all.Kind == yaml.SequenceNode {
+            for _, part := range all.Content { walk(part, depth+1) }
+        }
+        props := yamlnode.GetKey(s, "properties")
+        if props == nil || props.Kind != yaml.MappingNode { return }
+        required := map[string]bool{}
+        if req := yamlnode.GetKey(s, "required"); req != nil {
+            for _, r := range req.Content { required[r.Value] = true }
+        }
+        for i := 0; i+1 < len(props.Content); i += 2 {
+            key, prop := props.Content[i], props.Content[i+1]
+            f := &field{name: snake(key.Value)}
+            if seen[f.name] { continue }
+            seen[f.name] = true
+            if f.name != key.Value && lowerCamel(f.name) != key.Value { f.jsonName = key.Value }
+            nestedName := pascal(key.Value)
+            typ, repeated := g.fieldType(prop, nestedName, inner, depth+1, func(n string) string {
+                base := n
+                for i := 2; nestedNames[n]; i++ { n = base + strconv.Itoa(i) }
+                nestedNames[n] = true
+                return n
+            }, &nested)
+            f.typ = typ
+            switch {
+            case repeated:
+                f.label = "repeated"
+            case strings.HasPrefix(typ, "map<"):
+            case isScalar(typ) && (!required[key.Value] || isNullable(g.deref(prop))):
+                f.label = "optional"
+            }
+            f.doc = comment(g.deref(prop), inner)
+            // A $ref property may carry its own number next to the $ref.
+            n := yamlnode.GetKey(prop, "x-proto-field")
+            if n == nil { n = yamlnode.GetKey(g.deref(prop), "x-proto-field") }
+            if n != nil {
+                v, err := strconv.Atoi(n.Value)
+                switch {
+                case err != nil || v < 1 || v > 536870911:
+                    g.errs = append(g.errs, fmt.Sprintf("%s.%s: x-proto-field %q must be a number from 1 to 536870911", qual, key.Value, n.Value))
+                case v >= 19000 && v <= 19999:
+                    g.errs = append(g.errs, fmt.Sprintf("%s.%s: x-proto-field %d is in the range 19000-19999 reserved by Protocol Buffers", qual, key.Value, v))
+                }
+                f.num, f.pinned = v, true
+            }
+            fields = append(fields, f)
+        }
+    }
+    walk(schema, depth)
+
+    used := map[int]string{}
+    for _, f := range fields {
+        if !f.pinned { continue }
+        if other, ok := used[f.num]; ok {
+            g.errs = append(g.errs, fmt.Sprintf("%s: fields %s and %s both have x-proto-field %d", qual, other, f.name, f.num))
+        }
+        if reserved[f.num] {
+            g.errs = append(g.errs, fmt.Sprintf("%s.%s: x-proto-field %d is listed in x-proto-reserved", qual, f.name, f.num))
+        }
+        used[f.num] = f.name
+    }
+    next := 1
+    for _, f := range fields {
+        if f.pinned { continue }
+        for used[next] != "" || reserved[next] || next >= 19000 && next <= 19999 { next++ }
+        f.num = next
+        used[next] = f.name
+        g.unpinned = append(g.unpinned, qual+"."+f.name)
+    }
+
+    if len(reserved) > 0 {
+        nums := make([]int, 0, len(reserved))
+        for n := range reserved { nums = append(nums, n) }
+        sort.Ints(nums)
+        parts := make([]string, len(nums))
+        for i, n := range nums { parts[i] = strconv.Itoa(n) }
+        b.WriteString(inner + "reserved " + strings.Join(parts, ", ") + ";\n")
+    }
+    for _, n := range nested { b.WriteString(n) }
+    for _, f := range fields {
+        b.WriteString(f.doc)
+        line := inner
+        if f.label != "" { line += f.label + " " }
+        line += fmt.Sprintf("%s %s = %d", f.typ, f.name, f.num)
+        if f.jsonName != "" { line += fmt.Sprintf(" [json_name = %q]", f.jsonName) }
+        b.WriteString(line + ";\n")
+    }
+    b.WriteString(indent + "}\n")
+    return b.String()
+}
+
+// fieldType returns the type of a field holding schema and whether it is
+// repeated. Inline objects are declared into nested, named by nestedName.
+func (g *generator) fieldType(schema *yaml.Node, ctx, indent string, depth int, nestedName func(string) string, nested *[]string) (string, bool) {
+    if schema == nil || schema.Kind != yaml.MappingNode || depth > maxDepth { return g.wellKnown("google.protobuf.Value"), false }
+    if component := componentRef(schema); component != "" {
+        if _, ok := g.names[component]; ok {
+            if g.kinds[component] != "inline" { return g.names[component], false }
+            return g.fieldType(yamlnode.GetKey(g.schemas, component), ctx, indent, depth+1, nestedName, nested)
+        }
+    }
+    if yamlnode.GetKey(schema, "$ref") != nil { return g.fieldType(g.deref(schema), ctx, indent, depth+1, nestedName, nested) }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode && len(all.Content) == 1 && yamlnode.GetKey(schema, "properties") == nil {
+        return g.fieldType(all.Content[0], ctx, indent, depth+1, nestedName, nested)
+    }
+    if isMessage(schema) {
+        name := nestedName(ctx)
+        *nested = append(*nested, g.message(name, schema, indent, depth+1))
+        return name, false
+    }
+    if yamlnode.GetKey(schema, "oneOf") != nil || yamlnode.GetKey(schema, "anyOf") != nil { return g.wellKnown("google.protobuf.Value"), false }
+    var types []string
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        if t != "null" { types = append(types, t) }
+    }
+    if len(types) != 1 { return g.wellKnown("google.protobuf.Value"), false }
+    switch types[0] {
+    case "string":
+        switch scalar(schema, "format") {
+        case "date-time":
+            g.imports[timestampImport] = true
+            return "google.protobuf.Timestamp", false
+        case "binary", "byte":
+            return "bytes", false
+        }
+        return "string", false
+    case "integer":
+        if scalar(schema, "format") == "int32" { return "int32", false }
+        return "int64", false
+    case "number":
+        if scalar(schema, "format") == "float" { return "float", false }
+        return "double", false
+    case "boolean":
+        return "bool", false
+    case "array":
+        item, repeated := g.fieldType(yamlnode.GetKey(schema, "items"), ctx+"Item", indent, depth+1, nestedName, nested)
+        if repeated || strings.HasPrefix(item, "map<") { return g.wellKnown("google.protobuf.ListValue"), false }
+        return item, true
+    case "object":
+        if extra := yamlnode.GetKey(schema, "additionalProperties"); extra != nil && extra.Kind == yaml.MappingNode {
+            value, repeated := g.fieldType(extra, ctx+"Value", indent, depth+1, nestedName, nested)
+            if repeated || strings.HasPrefix(value, "map<") { value = g.wellKnown("google.protobuf.Value") }
+            return "map<string, " + value + ">", false
+        }
+        return g.wellKnown("google.protobuf.Struct"), false
+    }
+    return g.wellKnown("google.protobuf.Value"), false
+}
+
+func (g *generator) wellKnown(t string) string {
+    g.imports[structImport] = true
+    return t
+}
+
+// enum renders a string enum; values are prefixed with the enum name, as
+// proto3 enum values share their package's scope, and numbered from 1 in
+// declaration order after <NAME>_UNSPECIFIED.
+func enum(name string, schema *yaml.Node, indent string) string {
+    prefix := strings.ToUpper(snake(name)) + "_"
+    var b strings.Builder
+    b.WriteString(comment(schema, indent))
+    b.WriteString(indent + "enum " + name + " {\n")
+    fmt.Fprintf(&b, "%s  %sUNSPECIFIED = 0;\n", indent, prefix)
+    seen := map[string]bool{prefix + "UNSPECIFIED": true}
+    n := 1
+    for _, v := range yamlnode.GetKey(schema, "enum").Content {
+        if v.Tag == "!!null" { continue }
+        words := reWord.FindAllString(reHump.ReplaceAllString(v.Value, "${1}_${2}"), -1)
+        value := prefix + strings.ToUpper(strings.Join(words, "_"))
+        if value == prefix || seen[value] { continue }
+        seen[value] = true
+        fmt.Fprintf(&b, "%s  %s = %d;\n", indent, value, n)
+        n++
+    }
+    b.WriteString(indent + "}\n")
+    return b.String()
+}
+
+func comment(n *yaml.Node, indent string) string {
+    desc := strings.TrimSpace(firstNonEmpty(scalar(n, "description"), scalar(n, "title")))
+    if desc == "" { return "" }
+    var b strings.Builder
+    for _, line := range strings.Split(desc, "\n") { b.WriteString(strings.TrimRight(indent+"// "+line, " ") + "\n") }
+    return b.String()
+}
+
+// deref follows local refs.
+func (g *generator) deref(n *yaml.Node) *yaml.Node { return yamlnode.Deref(g.root, n) }
+
+func componentRef(schema *yaml.Node) string {
+    ref := yamlnode.GetKey(schema, "$ref")
+    if ref == nil { return "" }
+    name, ok := strings.CutPrefix(ref.Value, "#/components/schemas/")
+    if !ok || strings.Contains(name, "/") { return "" }
+    return yamlnode.UnescapeToken(name)
+}
+
+// oneOfRefs returns the components of a oneOf/anyOf made only of component
+// refs, else nil.
+func oneOfRefs(schema *yaml.Node) []string {
+    for _, key := range []string{"oneOf", "anyOf"} {
+        list := yamlnode.GetKey(schema, key)
+        if list == nil || list.Kind != yaml.SequenceNode || len(list.Content) == 0 { continue }
+        var out []string
+        for _, m := range list.Content {
+            c := componentRef(m)
+            if c == "" { return nil }
+            out = append(out, c)
+        }
+        return out
+    }
+    return nil
+}
+
+func isMessage(schema *yaml.Node) bool {
+    if schema == nil || schema.Kind != yaml.MappingNode || yamlnode.GetKey(schema, "$ref") != nil { return false }
+    if yamlnode.GetKey(schema, "oneOf") != nil || yamlnode.GetKey(schema, "anyOf") != nil { return false }
+    if all := yamlnode.GetKey(schema, "allOf"); all != nil && all.Kind == yaml.SequenceNode && (len(all.Content) > 1 || yamlnode.GetKey(schema, "properties") != nil) { return true }
+    props := yamlnode.GetKey(schema, "properties")
+    return props != nil && len(props.Content) > 0
+}
+
+func isEnum(schema *yaml.Node) bool {
+    e := yamlnode.GetKey(schema, "enum")
+    if e == nil || e.Kind != yaml.SequenceNode || len(e.Content) == 0 { return false }
+    for _, v := range e.Content {
+        if v.Kind != yaml.ScalarNode || v.Tag != "!!str" && v.Tag != "!!null" { return false }
+    }
+    return true
+}
+
+func isNullable(schema *yaml.Node) bool {
+    if isTrue(yamlnode.GetKey(schema, "nullable")) { return true }
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        if t == "null" { return true }
+    }
+    return false
+}
+
+// isScalar reports proto scalar types, which need optional for presence.
+func isScalar(t string) bool {
+    switch t {
+    case "string", "bytes", "bool", "int32", "int64", "float", "double":
+        return true
+    }
+    return false
+}
+
+var (
+    reWord = regexp.MustCompile(`[A-Za-z0-9]+`)
+    reHump = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+)
+
+func pascal(s string) string {
+    var b strings.Builder
+    for _, w := range reWord.FindAllString(s, -1) { b.WriteString(strings.ToUpper(w[:1]) + w[1:]) }
+    out := b.String()
+    if out == "" || out[0] >= '0' && out[0] <= '9' { out = "M" + out }
+    return out
+}
+
+// snake turns a property or enum value into lower_snake_case.
+func snake(s string) string {
+    words := reWord.FindAllString(reHump.ReplaceAllString(s, "${1}_${2}"), -1)
+    out := strings.ToLower(strings.Join(words, "_"))
+    if out == "" || out[0] >= '0' && out[0] <= '9' { out = "f_" + out }
+    return out
+}
+
+// lowerCamel is the JSON name protoc derives from a snake_case field name.
+func lowerCamel(s string) string {
+    parts := strings.Split(s, "_")
+    for i := 1; i < len(parts); i++ {
+        if parts[i] != "" { parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:] }
+    }
+    return strings.Join(parts, "")
+}
+
+// PackageName derives a proto package from an API title, such as
+// example_api from "Example API".
+func PackageName(title string) string {
+    out := snake(title)
+    if out == "" || strings.HasPrefix(out, "f_") { return "api" }
+    return out
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func isTrue(n *yaml.Node) bool { return n != nil && n.Kind == yaml.ScalarNode && n.Value == "true" }
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
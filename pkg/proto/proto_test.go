@@ -0,0 +1,93 @@
+package proto
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+// generate converts the component schemas given as YAML.
+func generate(t *testing.T, schemas string) (*Result, error) {
+    t.Helper()
+    var doc yaml.Node
+    text := "openapi: 3.0.3\ninfo: {title: Pet Store, version: 1.0.0}\ncomponents:\n  schemas:\n" + schemas
+    if err := yaml.Unmarshal([]byte(text), &doc); err != nil { t.Fatal(err) }
+    return Generate(doc.Content[0], Options{})
+}
+
+func TestPackageName(t *testing.T) {
+    for in, want := range map[string]string{"Example API": "example_api", "petStore": "pet_store", "": "api", "3D": "api"} {
+        if got := PackageName(in); got != want { t.Errorf("PackageName(%q) = %q, want %q", in, got, want) }
+    }
+}
+
+func TestGenerate(t *testing.T) {
+    res, err := generate(t, `    Status:
+      description: Where the pet is.
+      type: string
+      enum: [available, onHold, null]
+    Pet:
+      type: object
+      required: [id]
+      x-proto-reserved: [3]
+      properties:
+        id: {type: integer, format: int64, x-proto-field: 2}
+        petName: {type: string}
+        status: {$ref: '#/components/schemas/Status'}
+        tags: {type: array, items: {type: string}}
+        born: {type: string, format: date-time}
+        owner:
+          type: object
+          properties:
+            email: {type: string}
+        labels: {type: object, additionalProperties: {type: string}}
+        extra: {}
+    Animal:
+      oneOf:
+        - $ref: '#/components/schemas/Pet'
+`)
+    if err != nil { t.Fatal(err) }
+    for _, want := range []string{
+        "syntax = \"proto3\";\n\npackage pet_store;\n\nimport \"google/protobuf/struct.proto\";\nimport \"google/protobuf/timestamp.proto\";\n",
+        "// Where the pet is.\nenum Status {\n  STATUS_UNSPECIFIED = 0;\n  STATUS_AVAILABLE = 1;\n  STATUS_ON_HOLD = 2;\n}\n",
+        `message Pet {
+  reserved 3;
+  message Owner {
+    optional string email = 1;
+  }
+  int64 id = 2;
+  optional string pet_name = 1;
+  // Where the pet is.
+  Status status = 4;
+  repeated string tags = 5;
+  google.protobuf.Timestamp born = 6;
+  Owner owner = 7;
+  map<string, string> labels = 8;
+  google.protobuf.Value extra = 9;
+}
+`,
+        "message Animal {\n  oneof value {\n    Pet pet = 1;\n  }\n}\n",
+    } {
+        if !strings.Contains(string(res.Data), want) { t.Errorf("proto lacks:\n%s\nin:\n%s", want, res.Data) }
+    }
+    unpinned := []string{"Pet.Owner.email", "Pet.pet_name", "Pet.status", "Pet.tags", "Pet.born", "Pet.owner", "Pet.labels", "Pet.extra"}
+    if !reflect.DeepEqual(res.Unpinned, unpinned) { t.Errorf("Unpinned = %v, want %v", res.Unpinned, unpinned) }
+}
+
+func TestGenerateErrors(t *testing.T) {
+    tests := []struct{ name, schemas, want string }{
+        {"no schemas", "    {}\n", "no component schemas"},
+        {"bad number", "    A: {properties: {x: {type: string, x-proto-field: zero}}}\n", `A.x: x-proto-field "zero" must be a number`},
+        {"reserved range", "    A: {properties: {x: {type: string, x-proto-field: 19001}}}\n", "reserved by Protocol Buffers"},
+        {"duplicate", "    A: {properties: {x: {type: string, x-proto-field: 1}, y: {type: string, x-proto-field: 1}}}\n", "A: fields x and y both have x-proto-field 1"},
+        {"listed as reserved", "    A: {x-proto-reserved: [1], properties: {x: {type: string, x-proto-field: 1}}}\n", "is listed in x-proto-reserved"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := generate(t, tt.schemas)
+            if err == nil || !strings.Contains(err.Error(), tt.want) { t.Errorf("error = %v, want one mentioning %q", err, tt.want) }
+        })
+    }
+}
@@ -0,0 +1,129 @@
+// Package remote vendors the documents behind http(s) $refs into a local
+// directory, so builds resolve them like fragment files and can run offline.
+//
+// A document is stored at <dir>/<host>/<path>, mirroring its URL, so the
+// relative refs between vendored documents resolve as plain file refs. Once
+// vendored, a document is never fetched again; delete it to refresh.
+package remote
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/atomicfile"
+)
+
+// DefaultDir is the vendor directory below the input directory.
+const DefaultDir = ".oas-vendor"
+
+// Vendor fetches remote documents into Dir.
+type Vendor struct {
+    Dir     string
+    Offline bool // fail instead of fetching documents missing from Dir
+    Client  *http.Client
+    done    map[string]error // document URL -> result of vendoring it
+}
+
+// New returns a Vendor storing documents below dir.
+func New(dir string, offline bool) *Vendor {
+    return &Vendor{Dir: dir, Offline: offline, Client: &http.Client{Timeout: 30 * time.Second}, done: map[string]error{}}
+}
+
+// IsRemote reports whether ref points at an http(s) URL.
+func IsRemote(ref string) bool {
+    low := strings.ToLower(ref)
+    return strings.HasPrefix(low, "http://") || strings.HasPrefix(low, "https://")
+}
+
+// Path returns where the document at ref (any #fragment is ignored) is
+// vendored below Dir.
+func (v *Vendor) Path(ref string) (string, error) {
+    u, err := url.Parse(ref)
+    if err != nil { return "", fmt.Errorf("invalid remote $ref %q: %v", ref, err) }
+    if u.Host == "" || u.RawQuery != "" || strings.HasSuffix(u.Path, "/") || u.Path == "" {
+        return "", fmt.Errorf("remote $ref %q must name a document by host and path, without a query", ref)
+    }
+    host := strings.NewReplacer(":", "_").Replace(strings.ToLower(u.Host))
+    if host == "." || host == ".." || strings.ContainsAny(host, `/\`) {
+        return "", fmt.Errorf("remote $ref %q has an invalid host %q", ref, u.Host)
+    }
+    local := filepath.Join(v.Dir, host, filepath.FromSlash(path.Clean("/"+u.Path)))
+    if rel, err := filepath.Rel(v.Dir, local); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+        return "", fmt.Errorf("remote $ref %q would be vendored outside %s", ref, v.Dir)
+    }
+    return local, nil
+}
+
+// Fetch vendors the document at ref, and every document its refs lead to,
+// and returns the local path of the first.
+func (v *Vendor) Fetch(ref string) (string, error) {
+    doc, _, _ := strings.Cut(ref, "#")
+    local, err := v.Path(doc)
+    if err != nil { return "", err }
+    if err, seen := v.done[doc]; seen { return local, err }
+    v.done[doc] = nil // refs leading back here are satisfied
+    err = v.vendor(doc, local)
+    v.done[doc] = err
+    return local, err
+}
+
+func (v *Vendor) vendor(doc, local string) error {
+    data, err := os.ReadFile(local)
+    if os.IsNotExist(err) {
+        if v.Offline {
+            return fmt.Errorf("%s is not vendored in %s and --offline forbids fetching it", doc, v.Dir)
+        }
+        if data, err = v.download(doc); err != nil { return err }
+        if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil { return err }
+        if _, err := atomicfile.WriteFile(local, data); err != nil { return err }
+    } else if err != nil {
+        return err
+    }
+    var root yaml.Node
+    if yaml.Unmarshal(data, &root) != nil { return nil } // reported when the document is read
+    base, _ := url.Parse(doc)
+    for _, ref := range refs(&root) {
+        if strings.HasPrefix(ref, "#") { continue }
+        u, err := base.Parse(ref)
+        if err != nil || !IsRemote(u.String()) { continue }
+        if _, err := v.Fetch(u.String()); err != nil { return fmt.Errorf("%s: %v", doc, err) }
+    }
+    return nil
+}
+
+func (v *Vendor) download(doc string) ([]byte, error) {
+    resp, err := v.Client.Get(doc)
+    if err != nil { return nil, fmt.Errorf("fetch %s: %v", doc, err) }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("fetch %s: %s", doc, resp.Status)
+    }
+    data, err := io.ReadAll(resp.Body)
+    if err != nil { return nil, fmt.Errorf("fetch %s: %v", doc, err) }
+    return data, nil
+}
+
+// refs returns every $ref value below n.
+func refs(n *yaml.Node) []string {
+    var out []string
+    if n.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if k, v := n.Content[i], n.Content[i+1]; k.Value == "$ref" && v.Kind == yaml.ScalarNode {
+                out = append(out, v.Value)
+            }
+        }
+    }
+    for _, c := range n.Content {
+        out = append(out, refs(c)...)
+    }
+    return out
+}
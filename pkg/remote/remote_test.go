@@ -0,0 +1,78 @@
+package remote
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestPath(t *testing.T) {
+    v := New("vendor", false)
+    tests := []struct{ ref, want, err string }{
+        {ref: "https://schemas.example.com/money.yaml", want: "vendor/schemas.example.com/money.yaml"},
+        {ref: "HTTP://Example.COM:8080/a/b.yaml#/Money", want: "vendor/example.com_8080/a/b.yaml"},
+        {ref: "https://example.com/a/../../b.yaml", want: "vendor/example.com/b.yaml"},
+        {ref: "https://example.com/a.yaml?v=2", err: "without a query"},
+        {ref: "https://example.com/dir/", err: "must name a document"},
+        {ref: "https:///a.yaml", err: "must name a document"},
+        {ref: "http://../x.yaml", err: "invalid host"},
+        {ref: "http://./x.yaml", err: "invalid host"},
+        {ref: `http://a\b/x.yaml`, err: "invalid"},
+    }
+    for _, tt := range tests {
+        got, err := v.Path(tt.ref)
+        if tt.err != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("Path(%q) error = %v, want one mentioning %q", tt.ref, err, tt.err)
+            }
+            continue
+        }
+        if err != nil || got != filepath.FromSlash(tt.want) {
+            t.Errorf("Path(%q) = %q, %v; want %q", tt.ref, got, err, tt.want)
+        }
+    }
+}
+
+func TestFetch(t *testing.T) {
+    docs := map[string]string{
+        "/money.yaml":    "Money:\n  properties:\n    currency:\n      $ref: ./currency.yaml\n",
+        "/currency.yaml": "type: string\nx-self:\n  $ref: '#/type'\nx-back:\n  $ref: ./money.yaml#/Money\n",
+    }
+    hits := map[string]int{}
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        hits[r.URL.Path]++
+        doc, ok := docs[r.URL.Path]
+        if !ok { http.NotFound(w, r); return }
+        w.Write([]byte(doc))
+    }))
+    defer srv.Close()
+
+    dir := t.TempDir()
+    v := New(dir, false)
+    local, err := v.Fetch(srv.URL + "/money.yaml#/Money")
+    if err != nil { t.Fatal(err) }
+    for p, want := range docs {
+        host := strings.ReplaceAll(strings.TrimPrefix(srv.URL, "http://"), ":", "_")
+        b, err := os.ReadFile(filepath.Join(dir, host, filepath.FromSlash(p)))
+        if err != nil || string(b) != want { t.Errorf("vendored %s = %q, %v", p, b, err) }
+    }
+    if want, _ := v.Path(srv.URL + "/money.yaml"); local != want { t.Errorf("Fetch = %s, want %s", local, want) }
+
+    // Vendored documents are read from disk from then on, offline or not.
+    for _, offline := range []bool{false, true} {
+        if _, err := New(dir, offline).Fetch(srv.URL + "/money.yaml"); err != nil { t.Errorf("offline=%v: %v", offline, err) }
+    }
+    for p, n := range hits {
+        if n != 1 { t.Errorf("%s fetched %d times", p, n) }
+    }
+
+    if _, err := New(dir, true).Fetch(srv.URL + "/other.yaml"); err == nil || !strings.Contains(err.Error(), "--offline") {
+        t.Errorf("offline fetch of a missing document: %v", err)
+    }
+    if _, err := New(dir, false).Fetch(srv.URL + "/other.yaml"); err == nil || !strings.Contains(err.Error(), "404") {
+        t.Errorf("fetch of a missing document: %v", err)
+    }
+}
@@ -0,0 +1,387 @@
+// Package specdiff compares two bundled OpenAPI documents and classifies
+// each difference by its impact on existing clients.
+package specdiff
+
+import (
+    "fmt"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Severity classifies a Change.
+type Severity string
+
+const (
+    Breaking    Severity = "breaking"     // existing clients may fail
+    NonBreaking Severity = "non-breaking" // additive or relaxing changes
+    DocsOnly    Severity = "docs-only"    // summaries, descriptions, examples
+)
+
+// Change is one difference between the old and new spec.
+type Change struct {
+    Severity Severity
+    Location string // e.g. "GET /v1/users/{id} response 200"
+    Message  string
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// docKeys change wording only.
+var docKeys = []string{"summary", "description", "title", "example", "examples", "externalDocs"}
+
+type direction int
+
+const (
+    request direction = iota
+    response
+)
+
+type differ struct {
+    oldRoot, newRoot *yaml.Node
+    changes          []Change
+    // seen holds schema pairs already compared, so recursive schemas end and
+    // a shared component's changes are reported once, at its first use.
+    seen map[[2]*yaml.Node]bool
+}
+
+// Compare returns the changes from old to new, both top-level nodes of
+// bundled documents, sorted by location.
+func Compare(old, new *yaml.Node) []Change {
+    d := &differ{oldRoot: old, newRoot: new, seen: map[[2]*yaml.Node]bool{}}
+    d.docs("info", yamlnode.GetKey(old, "info"), yamlnode.GetKey(new, "info"))
+    oldPaths, newPaths := yamlnode.GetKey(old, "paths"), yamlnode.GetKey(new, "paths")
+    for _, p := range keys(oldPaths) {
+        if yamlnode.GetKey(newPaths, p) == nil {
+            d.add(Breaking, p, "path removed")
+            continue
+        }
+        d.pathItem(p, d.derefOld(yamlnode.GetKey(oldPaths, p)), d.derefNew(yamlnode.GetKey(newPaths, p)))
+    }
+    for _, p := range keys(newPaths) {
+        if yamlnode.GetKey(oldPaths, p) == nil { d.add(NonBreaking, p, "path added") }
+    }
+    sort.SliceStable(d.changes, func(i, j int) bool { return d.changes[i].Location < d.changes[j].Location })
+    return d.changes
+}
+
+func (d *differ) add(sev Severity, loc, format string, args ...interface{}) {
+    d.changes = append(d.changes, Change{Severity: sev, Location: loc, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) derefOld(n *yaml.Node) *yaml.Node { return deref(d.oldRoot, n) }
+func (d *differ) derefNew(n *yaml.Node) *yaml.Node { return deref(d.newRoot, n) }
+
+// deref follows local refs such as #/components/schemas/User, or returns n when
+// they do not resolve.
+func deref(root, n *yaml.Node) *yaml.Node {
+    if target := yamlnode.Deref(root, n); target != nil { return target }
+    return n
+}
+
+func (d *differ) pathItem(path string, old, new *yaml.Node) {
+    for _, m := range httpMethods {
+        o, n := d.derefOld(yamlnode.GetKey(old, m)), d.derefNew(yamlnode.GetKey(new, m))
+        loc := strings.ToUpper(m) + " " + path
+        switch {
+        case o == nil && n == nil:
+        case n == nil:
+            d.add(Breaking, loc, "operation removed")
+        case o == nil:
+            d.add(NonBreaking, loc, "operation added")
+        default:
+            d.operation(loc, o, n, yamlnode.GetKey(old, "parameters"), yamlnode.GetKey(new, "parameters"))
+        }
+    }
+}
+
+func (d *differ) operation(loc string, old, new, oldShared, newShared *yaml.Node) {
+    d.docs(loc, old, new)
+    if scalar(new, "deprecated") == "true" && scalar(old, "deprecated") != "true" {
+        d.add(NonBreaking, loc, "operation deprecated")
+    }
+    if o, n := scalar(old, "operationId"), scalar(new, "operationId"); o != n && o != "" {
+        d.add(Breaking, loc, "operationId changed from %q to %q (generated client method names change)", o, n)
+    }
+
+    oldParams, newParams := d.params(old, oldShared, d.derefOld), d.params(new, newShared, d.derefNew)
+    for _, key := range sortedKeys(oldParams) {
+        op, np := oldParams[key], newParams[key]
+        ploc := loc + " parameter " + key
+        if np == nil {
+            d.add(Breaking, ploc, "parameter removed")
+            continue
+        }
+        if o, n := scalar(op, "required") == "true", scalar(np, "required") == "true"; o != n {
+            if n {
+                d.add(Breaking, ploc, "parameter became required")
+            } else {
+                d.add(NonBreaking, ploc, "parameter became optional")
+            }
+        }
+        d.docs(ploc, op, np)
+        d.schema(ploc, yamlnode.GetKey(op, "schema"), yamlnode.GetKey(np, "schema"), request)
+    }
+    for _, key := range sortedKeys(newParams) {
+        if oldParams[key] != nil { continue }
+        if scalar(newParams[key], "required") == "true" {
+            d.add(Breaking, loc+" parameter "+key, "required parameter added")
+        } else {
+            d.add(NonBreaking, loc+" parameter "+key, "optional parameter added")
+        }
+    }
+
+    ob, nb := d.derefOld(yamlnode.GetKey(old, "requestBody")), d.derefNew(yamlnode.GetKey(new, "requestBody"))
+    bloc := loc + " request body"
+    switch {
+    case ob == nil && nb == nil:
+    case nb == nil:
+        d.add(Breaking, bloc, "request body removed")
+    case ob == nil:
+        if scalar(nb, "required") == "true" {
+            d.add(Breaking, bloc, "required request body added")
+        } else {
+            d.add(NonBreaking, bloc, "optional request body added")
+        }
+    default:
+        if scalar(nb, "required") == "true" && scalar(ob, "required") != "true" {
+            d.add(Breaking, bloc, "request body became required")
+        }
+        d.docs(bloc, ob, nb)
+        d.content(bloc, ob, nb, request)
+    }
+
+    oldResp, newResp := yamlnode.GetKey(old, "responses"), yamlnode.GetKey(new, "responses")
+    for _, code := range keys(oldResp) {
+        rloc := loc + " response " + code
+        nr := yamlnode.GetKey(newResp, code)
+        if nr == nil {
+            sev := NonBreaking
+            if strings.HasPrefix(code, "2") { sev = Breaking }
+            d.add(sev, rloc, "response removed")
+            continue
+        }
+        or, nrr := d.derefOld(yamlnode.GetKey(oldResp, code)), d.derefNew(nr)
+        d.docs(rloc, or, nrr)
+        d.content(rloc, or, nrr, response)
+    }
+    for _, code := range keys(newResp) {
+        if yamlnode.GetKey(oldResp, code) == nil { d.add(NonBreaking, loc+" response "+code, "response added") }
+    }
+}
+
+// params indexes an operation's parameters (including path-level ones it
+// does not override) by "in:name".
+func (d *differ) params(op, shared *yaml.Node, deref func(*yaml.Node) *yaml.Node) map[string]*yaml.Node {
+    out := map[string]*yaml.Node{}
+    for _, list := range []*yaml.Node{yamlnode.GetKey(op, "parameters"), shared} {
+        if list == nil || list.Kind != yaml.SequenceNode { continue }
+        for _, p := range list.Content {
+            p = deref(p)
+            key := scalar(p, "in") + ":" + scalar(p, "name")
+            if _, ok := out[key]; !ok { out[key] = p }
+        }
+    }
+    return out
+}
+
+func (d *differ) content(loc string, old, new *yaml.Node, dir direction) {
+    oc, nc := yamlnode.GetKey(old, "content"), yamlnode.GetKey(new, "content")
+    for _, mt := range keys(oc) {
+        nm := yamlnode.GetKey(nc, mt)
+        if nm == nil {
+            d.add(Breaking, loc, "media type %s removed", mt)
+            continue
+        }
+        om := yamlnode.GetKey(oc, mt)
+        d.docs(loc+" "+mt, om, nm)
+        d.schema(loc+" "+mt, yamlnode.GetKey(om, "schema"), yamlnode.GetKey(nm, "schema"), dir)
+    }
+    for _, mt := range keys(nc) {
+        if yamlnode.GetKey(oc, mt) == nil { d.add(NonBreaking, loc, "media type %s added", mt) }
+    }
+}
+
+// schema compares two schemas. Requests break when they accept less than
+// before; responses break when they may return something clients have not
+// seen before.
+func (d *differ) schema(loc string, old, new *yaml.Node, dir direction) {
+    old, new = d.derefOld(old), d.derefNew(new)
+    if old == nil || new == nil {
+        if old != nil || new != nil { d.add(Breaking, loc, "schema added or removed") }
+        return
+    }
+    pair := [2]*yaml.Node{old, new}
+    if d.seen[pair] { return }
+    d.seen[pair] = true
+
+    d.docs(loc, old, new)
+    if o, n := scalar(old, "type"), scalar(new, "type"); o != n {
+        d.add(Breaking, loc, "type changed from %s to %s", orNone(o), orNone(n))
+        return
+    }
+    if o, n := scalar(old, "format"), scalar(new, "format"); o != n {
+        d.add(Breaking, loc, "format changed from %s to %s", orNone(o), orNone(n))
+    }
+    if o, n := scalar(old, "nullable") == "true", scalar(new, "nullable") == "true"; o != n {
+        if (dir == request && o) || (dir == response && n) {
+            d.add(Breaking, loc, "nullable changed to %t", n)
+        } else {
+            d.add(NonBreaking, loc, "nullable changed to %t", n)
+        }
+    }
+
+    oldEnum, newEnum := values(yamlnode.GetKey(old, "enum")), values(yamlnode.GetKey(new, "enum"))
+    narrowed, widened := Breaking, NonBreaking
+    if dir == response { narrowed, widened = NonBreaking, Breaking }
+    switch {
+    case oldEnum == nil && newEnum != nil:
+        d.add(narrowed, loc, "enum added: only %s allowed", strings.Join(newEnum, ", "))
+    case oldEnum != nil && newEnum == nil:
+        d.add(widened, loc, "enum removed: any value allowed")
+    case oldEnum != nil:
+        removed, added := setDiff(oldEnum, newEnum), setDiff(newEnum, oldEnum)
+        if len(removed) > 0 {
+            d.add(narrowed, loc, "enum narrowed: %s no longer allowed", strings.Join(removed, ", "))
+        }
+        if len(added) > 0 {
+            d.add(widened, loc, "enum widened: %s added", strings.Join(added, ", "))
+        }
+    }
+    if dir == request { d.limits(loc, old, new) }
+
+    oldReq, newReq := values(yamlnode.GetKey(old, "required")), values(yamlnode.GetKey(new, "required"))
+    for _, p := range setDiff(newReq, oldReq) {
+        if dir == request {
+            d.add(Breaking, loc, "property %s became required", p)
+        } else {
+            d.add(NonBreaking, loc, "property %s is now always returned", p)
+        }
+    }
+    for _, p := range setDiff(oldReq, newReq) {
+        if dir == response {
+            d.add(Breaking, loc, "property %s is no longer always returned", p)
+        } else {
+            d.add(NonBreaking, loc, "property %s is no longer required", p)
+        }
+    }
+
+    oldProps, newProps := yamlnode.GetKey(old, "properties"), yamlnode.GetKey(new, "properties")
+    for _, p := range keys(oldProps) {
+        np := yamlnode.GetKey(newProps, p)
+        if np == nil {
+            if dir == response {
+                d.add(Breaking, loc, "property %s removed", p)
+            } else {
+                d.add(NonBreaking, loc, "property %s removed", p)
+            }
+            continue
+        }
+        d.schema(loc+"."+p, yamlnode.GetKey(oldProps, p), np, dir)
+    }
+    for _, p := range keys(newProps) {
+        if yamlnode.GetKey(oldProps, p) == nil && !(dir == request && contains(newReq, p)) {
+            d.add(NonBreaking, loc, "property %s added", p)
+        }
+    }
+    if oi, ni := yamlnode.GetKey(old, "items"), yamlnode.GetKey(new, "items"); oi != nil || ni != nil {
+        d.schema(loc+"[]", oi, ni, dir)
+    }
+}
+
+// limits reports request constraints that got tighter.
+func (d *differ) limits(loc string, old, new *yaml.Node) {
+    for _, key := range []string{"maxLength", "maximum", "maxItems"} {
+        if tighter(scalar(old, key), scalar(new, key), func(o, n float64) bool { return n < o }) {
+            d.add(Breaking, loc, "%s lowered from %s to %s", key, orNone(scalar(old, key)), scalar(new, key))
+        }
+    }
+    for _, key := range []string{"minLength", "minimum", "minItems"} {
+        if tighter(scalar(old, key), scalar(new, key), func(o, n float64) bool { return n > o }) {
+            d.add(Breaking, loc, "%s raised from %s to %s", key, orNone(scalar(old, key)), scalar(new, key))
+        }
+    }
+}
+
+func tighter(old, new string, less func(o, n float64) bool) bool {
+    if new == "" { return false }
+    n, err := strconv.ParseFloat(new, 64)
+    if err != nil { return false }
+    if old == "" { return true }
+    o, err := strconv.ParseFloat(old, 64)
+    return err == nil && less(o, n)
+}
+
+// docs reports wording changes under docKeys.
+func (d *differ) docs(loc string, old, new *yaml.Node) {
+    for _, key := range docKeys {
+        o, n := yamlnode.GetKey(old, key), yamlnode.GetKey(new, key)
+        if o == nil && n == nil { continue }
+        if o == nil || n == nil || !sameNode(o, n) { d.add(DocsOnly, loc, "%s changed", key) }
+    }
+}
+
+// sameNode reports whether a and b hold the same data, whatever their style
+// (block or flow, quoting) and key order.
+func sameNode(a, b *yaml.Node) bool {
+    var x, y any
+    if a.Decode(&x) != nil || b.Decode(&y) != nil { return false }
+    return reflect.DeepEqual(x, y)
+}
+
+func keys(m *yaml.Node) []string {
+    if m == nil || m.Kind != yaml.MappingNode { return nil }
+    var out []string
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        out = append(out, m.Content[i].Value)
+    }
+    return out
+}
+
+func sortedKeys(m map[string]*yaml.Node) []string {
+    out := make([]string, 0, len(m))
+    for k := range m { out = append(out, k) }
+    sort.Strings(out)
+    return out
+}
+
+// values returns the scalar items of a sequence, or nil when n is not one.
+func values(n *yaml.Node) []string {
+    if n == nil || n.Kind != yaml.SequenceNode { return nil }
+    out := []string{}
+    for _, c := range n.Content {
+        out = append(out, c.Value)
+    }
+    return out
+}
+
+// setDiff returns the items of a that are not in b.
+func setDiff(a, b []string) []string {
+    var out []string
+    for _, v := range a {
+        if !contains(b, v) { out = append(out, v) }
+    }
+    return out
+}
+
+func contains(list []string, v string) bool {
+    for _, x := range list {
+        if x == v { return true }
+    }
+    return false
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func orNone(s string) string {
+    if s == "" { return "(none)" }
+    return s
+}
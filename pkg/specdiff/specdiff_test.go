@@ -0,0 +1,173 @@
+package specdiff
+
+import (
+    "reflect"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+const base = `openapi: 3.0.3
+info:
+  title: Users
+  version: 1.0.0
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      summary: List users
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/User'
+    post:
+      operationId: addUser
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/User'
+      responses:
+        '201':
+          description: created
+components:
+  schemas:
+    User:
+      type: object
+      required: [id]
+      properties:
+        id:
+          type: string
+        role:
+          type: string
+          enum: [admin, member]
+`
+
+// node parses a YAML document and returns its top-level node.
+func node(t *testing.T, text string) *yaml.Node {
+    t.Helper()
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(text), &doc); err != nil { t.Fatal(err) }
+    return doc.Content[0]
+}
+
+// edit returns base with the node at path replaced by value, removed for an
+// empty value, or added when the last key of path is missing.
+func edit(t *testing.T, path []string, value string) string {
+    t.Helper()
+    root := node(t, base)
+    n := root
+    for i, key := range path {
+        for j := 0; j+1 < len(n.Content); j += 2 {
+            if n.Content[j].Value != key { continue }
+            if i < len(path)-1 {
+                n = n.Content[j+1]
+                break
+            }
+            if value == "" {
+                n.Content = append(n.Content[:j], n.Content[j+2:]...)
+            } else {
+                n.Content[j+1] = node(t, value)
+            }
+            return marshal(t, root)
+        }
+        if i == len(path)-1 && value != "" {
+            n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node(t, value))
+            return marshal(t, root)
+        }
+    }
+    t.Fatalf("no %v in the base spec", path)
+    return ""
+}
+
+func marshal(t *testing.T, n *yaml.Node) string {
+    t.Helper()
+    out, err := yaml.Marshal(n)
+    if err != nil { t.Fatal(err) }
+    return string(out)
+}
+
+func TestCompare(t *testing.T) {
+    user := []string{"components", "schemas", "User"}
+    tests := []struct {
+        name string
+        path []string
+        with string
+        want []Change
+    }{
+        {"unchanged", []string{"info", "title"}, "Users", nil},
+        {"path removed", []string{"paths", "/users"}, "", []Change{
+            {Breaking, "/users", "path removed"},
+        }},
+        {"operation removed", []string{"paths", "/users", "post"}, "", []Change{
+            {Breaking, "POST /users", "operation removed"},
+        }},
+        {"summary changed", []string{"paths", "/users", "get", "summary"}, "All users", []Change{
+            {DocsOnly, "GET /users", "summary changed"},
+        }},
+        {"operationId changed", []string{"paths", "/users", "get", "operationId"}, "getUsers", []Change{
+            {Breaking, "GET /users", `operationId changed from "listUsers" to "getUsers" (generated client method names change)`},
+        }},
+        {"parameter became required and tighter", []string{"paths", "/users", "get", "parameters"},
+            "- {name: limit, in: query, required: true, schema: {type: integer, maximum: 50}}", []Change{
+                {Breaking, "GET /users parameter query:limit", "parameter became required"},
+                {Breaking, "GET /users parameter query:limit", "maximum lowered from 100 to 50"},
+            }},
+        {"required parameter added", []string{"paths", "/users", "get", "parameters"},
+            "- {name: limit, in: query, schema: {type: integer, maximum: 100}}\n- {name: team, in: query, required: true}", []Change{
+                {Breaking, "GET /users parameter query:team", "required parameter added"},
+            }},
+        {"success response removed", []string{"paths", "/users", "post", "responses"}, "'400': {description: bad}", []Change{
+            {Breaking, "POST /users response 201", "response removed"},
+            {NonBreaking, "POST /users response 400", "response added"},
+        }},
+        {"enum narrowed in a shared schema", append(user, "properties", "role", "enum"), "[admin]", []Change{
+            // a component is compared once, at its first use: here a
+            // response, where returning fewer values is safe
+            {NonBreaking, "GET /users response 200 application/json[].role", "enum narrowed: member no longer allowed"},
+        }},
+        {"property required in requests", append(user, "required"), "[id, role]", []Change{
+            {NonBreaking, "GET /users response 200 application/json[]", "property role is now always returned"},
+        }},
+        {"type changed", append(user, "properties", "id"), "{type: integer}", []Change{
+            {Breaking, "GET /users response 200 application/json[].id", "type changed from string to integer"},
+        }},
+        {"path added", []string{"paths", "/teams"}, "{get: {responses: {'200': {description: ok}}}}", []Change{
+            {NonBreaking, "/teams", "path added"},
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := Compare(node(t, base), node(t, edit(t, tt.path, tt.with)))
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("changes:\n%v\nwant:\n%v", got, tt.want)
+            }
+        })
+    }
+}
+
+// A spec compared with itself after a JSON round trip, which changes the
+// style of every node and the key order of none, has no changes.
+func TestCompareRoundTrip(t *testing.T) {
+    user := []string{"components", "schemas", "User"}
+    old := node(t, edit(t, append(user, "example"), "{id: u1, role: admin, tags: [a, 'b']}"))
+    j, err := yamlnode.MarshalJSON(old)
+    if err != nil { t.Fatal(err) }
+    if got := Compare(old, node(t, string(j))); len(got) != 0 { t.Errorf("changes after a JSON round trip:\n%v", got) }
+
+    reordered := node(t, edit(t, append(user, "example"), "{role: admin, id: u1, tags: [a, b]}"))
+    if got := Compare(old, reordered); len(got) != 0 { t.Errorf("changes after reordering example keys:\n%v", got) }
+}
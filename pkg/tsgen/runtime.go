@@ -0,0 +1,326 @@
+package tsgen
+
+import (
+    "bytes"
+    "fmt"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// Runtime schema libraries for Runtime.
+const (
+    RuntimeZod     = "zod"
+    RuntimeTypeBox = "typebox"
+)
+
+// ValidRuntime reports whether s names a supported runtime schema library.
+func ValidRuntime(s string) bool { return s == RuntimeZod || s == RuntimeTypeBox }
+
+type runtimeGen struct {
+    *generator
+    kind     string
+    done     map[string]bool // components emitted so far
+    visiting map[string]bool
+    schemas  *yaml.Node
+}
+
+// Runtime renders a validation schema per component schema of spec for the
+// zod or typebox library, as <Name>Schema constants. Components are emitted
+// after the ones they reference; a reference that closes a cycle is lazy
+// with zod and unchecked with TypeBox, which cannot express it.
+func Runtime(spec *yaml.Node, kind string) ([]byte, error) {
+    if !ValidRuntime(kind) { return nil, fmt.Errorf("unknown runtime schema library %q (expected zod or typebox)", kind) }
+    if spec == nil || spec.Kind != yaml.MappingNode { return nil, fmt.Errorf("spec must be a mapping") }
+    g := &runtimeGen{generator: &generator{root: spec, names: map[string]string{}}, kind: kind, done: map[string]bool{}, visiting: map[string]bool{}}
+    info := yamlnode.GetKey(spec, "info")
+    fmt.Fprintf(&g.buf, "// Generated by oas-indexer from %s %s. Do not edit.\n", firstNonEmpty(scalar(info, "title"), "API"), scalar(info, "version"))
+    if kind == RuntimeZod {
+        g.buf.WriteString("import { z } from \"zod\";\n")
+    } else {
+        g.buf.WriteString("import { Type } from \"@sinclair/typebox\";\n")
+    }
+
+    g.schemas = yamlnode.GetKey(yamlnode.GetKey(spec, "components"), "schemas")
+    if g.schemas == nil || g.schemas.Kind != yaml.MappingNode { return g.buf.Bytes(), nil }
+    taken := map[string]bool{}
+    for i := 0; i+1 < len(g.schemas.Content); i += 2 {
+        name := Identifier(g.schemas.Content[i].Value) + "Schema"
+        for n := 2; taken[name]; n++ { name = Identifier(g.schemas.Content[i].Value) + strconv.Itoa(n) + "Schema" }
+        taken[name] = true
+        g.names[g.schemas.Content[i].Value] = name
+    }
+    for i := 0; i+1 < len(g.schemas.Content); i += 2 { g.emit(g.schemas.Content[i].Value) }
+    return g.buf.Bytes(), nil
+}
+
+// emit writes the component and, first, the components it references.
+func (g *runtimeGen) emit(component string) {
+    if g.done[component] || g.visiting[component] { return }
+    schema := yamlnode.GetKey(g.schemas, component)
+    g.visiting[component] = true
+    for _, dep := range refs(schema, nil) { g.emit(dep) }
+    delete(g.visiting, component)
+
+    var body bytes.Buffer
+    cyclic := false
+    expr := g.expr(schema, "", 0, &cyclic)
+    body.WriteString("\n")
+    body.WriteString(g.docString(schema, ""))
+    name := g.names[component]
+    if cyclic && g.kind == RuntimeZod {
+        fmt.Fprintf(&body, "export const %s: z.ZodType<any> = %s;\n", name, expr)
+    } else {
+        fmt.Fprintf(&body, "export const %s = %s;\n", name, expr)
+    }
+    g.buf.Write(body.Bytes())
+    g.done[component] = true
+}
+
+// refs lists the components schema references, in order.
+func refs(n *yaml.Node, out []string) []string {
+    if n == nil { return out }
+    if n.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(n.Content); i += 2 {
+            if n.Content[i].Value == "$ref" {
+                if name, ok := strings.CutPrefix(n.Content[i+1].Value, "#/components/schemas/"); ok { out = append(out, yamlnode.UnescapeToken(name)) }
+                continue
+            }
+            out = refs(n.Content[i+1], out)
+        }
+        return out
+    }
+    for _, c := range n.Content { out = refs(c, out) }
+    return out
+}
+
+// expr renders schema as a zod or TypeBox expression. cyclic is set when it
+// references a component not emitted yet.
+func (g *runtimeGen) expr(schema *yaml.Node, indent string, depth int, cyclic *bool) string {
+    if schema == nil || depth > maxDepth || schema.Kind != yaml.MappingNode { return g.lib("unknown") }
+    if ref := yamlnode.GetKey(schema, "$ref"); ref != nil {
+        if name, ok := strings.CutPrefix(ref.Value, "#/components/schemas/"); ok {
+            component := yamlnode.UnescapeToken(name)
+            if ts, ok := g.names[component]; ok {
+                if g.done[component] { return ts }
+                *cyclic = true
+                if g.kind == RuntimeZod { return "z.lazy(() => " + ts + ")" }
+                return "Type.Unknown() /* cyclic: " + ts + " */"
+            }
+        }
+        return g.expr(g.deref(schema), indent, depth+1, cyclic)
+    }
+    e := g.base(schema, indent, depth, cyclic)
+    if isTrue(yamlnode.GetKey(schema, "nullable")) {
+        if g.kind == RuntimeZod { return e + ".nullable()" }
+        return "Type.Union([" + e + ", Type.Null()])"
+    }
+    return e
+}
+
+func (g *runtimeGen) base(schema *yaml.Node, indent string, depth int, cyclic *bool) string {
+    zod := g.kind == RuntimeZod
+    if c := yamlnode.GetKey(schema, "const"); c != nil { return g.literal(c) }
+    if enum := yamlnode.GetKey(schema, "enum"); enum != nil && enum.Kind == yaml.SequenceNode && len(enum.Content) > 0 {
+        allStrings := true
+        var lits []string
+        for _, v := range enum.Content {
+            if v.Kind != yaml.ScalarNode || v.Tag != "!!str" { allStrings = false }
+            lits = append(lits, g.literal(v))
+        }
+        if zod && allStrings {
+            var vals []string
+            for _, v := range enum.Content { vals = append(vals, strconv.Quote(v.Value)) }
+            return "z.enum([" + strings.Join(vals, ", ") + "])"
+        }
+        return g.union(lits)
+    }
+    for _, key := range []string{"oneOf", "anyOf"} {
+        if list := yamlnode.GetKey(schema, key); list != nil && list.Kind == yaml.SequenceNode && len(list.Content) > 0 {
+            var parts []string
+            for _, s := range list.Content { parts = append(parts, g.expr(s, indent, depth+1, cyclic)) }
+            return g.union(parts)
+        }
+    }
+    if list := yamlnode.GetKey(schema, "allOf"); list != nil && list.Kind == yaml.SequenceNode && len(list.Content) > 0 {
+        var parts []string
+        for _, s := range list.Content { parts = append(parts, g.expr(s, indent, depth+1, cyclic)) }
+        if yamlnode.GetKey(schema, "properties") != nil { parts = append(parts, g.object(schema, indent, depth, cyclic)) }
+        if len(parts) == 1 { return parts[0] }
+        if zod { return parts[0] + ".and(" + strings.Join(parts[1:], ").and(") + ")" }
+        return "Type.Intersect([" + strings.Join(parts, ", ") + "])"
+    }
+
+    var parts []string
+    for _, t := range yamlnode.SchemaTypes(schema) {
+        switch t {
+        case "string":
+            parts = append(parts, g.str(schema))
+        case "integer", "number":
+            parts = append(parts, g.num(schema, t == "integer"))
+        case "boolean":
+            parts = append(parts, g.lib("boolean"))
+        case "null":
+            parts = append(parts, g.lib("null"))
+        case "array":
+            item := g.expr(yamlnode.GetKey(schema, "items"), indent, depth+1, cyclic)
+            if zod {
+                parts = append(parts, "z.array("+item+")"+g.bounds(schema, "minItems", ".min", "maxItems", ".max"))
+            } else {
+                parts = append(parts, "Type.Array("+item+g.options(schema, "minItems", "maxItems", "uniqueItems")+")")
+            }
+        case "object":
+            parts = append(parts, g.object(schema, indent, depth, cyclic))
+        }
+    }
+    switch len(parts) {
+    case 0:
+        return g.lib("unknown")
+    case 1:
+        return parts[0]
+    }
+    return g.union(parts)
+}
+
+// object renders an object schema; one without properties is a record.
+func (g *runtimeGen) object(schema *yaml.Node, indent string, depth int, cyclic *bool) string {
+    zod := g.kind == RuntimeZod
+    props := yamlnode.GetKey(schema, "properties")
+    extra := yamlnode.GetKey(schema, "additionalProperties")
+    closed := extra != nil && extra.Kind == yaml.ScalarNode && extra.Value == "false"
+    if props == nil || len(props.Content) == 0 {
+        value := g.lib("unknown")
+        if extra != nil && extra.Kind == yaml.MappingNode { value = g.expr(extra, indent, depth+1, cyclic) }
+        if closed {
+            if zod { return "z.object({}).strict()" }
+            return "Type.Object({}, { additionalProperties: false })"
+        }
+        if zod { return "z.record(z.string(), " + value + ")" }
+        return "Type.Record(Type.String(), " + value + ")"
+    }
+    required := map[string]bool{}
+    if req := yamlnode.GetKey(schema, "required"); req != nil {
+        for _, r := range req.Content { required[r.Value] = true }
+    }
+    inner := indent + "  "
+    var b strings.Builder
+    if zod {
+        b.WriteString("z.object({\n")
+    } else {
+        b.WriteString("Type.Object({\n")
+    }
+    for i := 0; i+1 < len(props.Content); i += 2 {
+        key, prop := props.Content[i].Value, props.Content[i+1]
+        e := g.expr(prop, inner, depth+1, cyclic)
+        if !required[key] {
+            if zod { e += ".optional()" } else { e = "Type.Optional(" + e + ")" }
+        }
+        fmt.Fprintf(&b, "%s%s: %s,\n", inner, propertyKey(key), e)
+    }
+    b.WriteString(indent + "}")
+    switch {
+    case zod && closed:
+        return b.String() + ").strict()"
+    case zod && extra != nil && extra.Kind == yaml.MappingNode:
+        return b.String() + ").catchall(" + g.expr(extra, indent, depth+1, cyclic) + ")"
+    case zod:
+        return b.String() + ")"
+    case closed:
+        return b.String() + ", { additionalProperties: false })"
+    case extra != nil && extra.Kind == yaml.MappingNode:
+        return b.String() + ", { additionalProperties: " + g.expr(extra, indent, depth+1, cyclic) + " })"
+    }
+    return b.String() + ")"
+}
+
+// zod string refinements by format.
+var zodFormats = map[string]string{"email": ".email()", "uuid": ".uuid()", "uri": ".url()", "url": ".url()", "date-time": ".datetime({ offset: true })", "date": ".date()", "ipv4": ".ip({ version: \"v4\" })", "ipv6": ".ip({ version: \"v6\" })"}
+
+func (g *runtimeGen) str(schema *yaml.Node) string {
+    format := scalar(schema, "format")
+    if g.kind != RuntimeZod {
+        if format == "binary" { return "Type.Unknown()" }
+        return "Type.String(" + strings.TrimPrefix(g.options(schema, "format", "minLength", "maxLength", "pattern"), ", ") + ")"
+    }
+    if format == "binary" { return "z.instanceof(Blob)" }
+    e := "z.string()" + zodFormats[format] + g.bounds(schema, "minLength", ".min", "maxLength", ".max")
+    if p := scalar(schema, "pattern"); p != "" { e += ".regex(new RegExp(" + strconv.Quote(p) + "))" }
+    return e
+}
+
+func (g *runtimeGen) num(schema *yaml.Node, integer bool) string {
+    if g.kind != RuntimeZod {
+        fn := "Type.Number("
+        if integer { fn = "Type.Integer(" }
+        // TypeBox takes the 3.1 form: the exclusive bound itself.
+        var opts []string
+        for _, k := range [][2]string{{"minimum", "exclusiveMinimum"}, {"maximum", "exclusiveMaximum"}} {
+            bound, excl := yamlnode.GetKey(schema, k[0]), yamlnode.GetKey(schema, k[1])
+            switch {
+            case excl != nil && excl.Tag != "!!bool":
+                opts = append(opts, k[1]+": "+excl.Value)
+            case bound != nil && isTrue(excl):
+                opts = append(opts, k[1]+": "+bound.Value)
+            case bound != nil:
+                opts = append(opts, k[0]+": "+bound.Value)
+            }
+        }
+        if v := scalar(schema, "multipleOf"); v != "" { opts = append(opts, "multipleOf: "+v) }
+        if len(opts) == 0 { return fn + ")" }
+        return fn + "{ " + strings.Join(opts, ", ") + " })"
+    }
+    e := "z.number()"
+    if integer { e += ".int()" }
+    lower, upper := ".min", ".max"
+    // 3.0 flags the bound itself as exclusive; 3.1 gives the bound instead.
+    if isTrue(yamlnode.GetKey(schema, "exclusiveMinimum")) { lower = ".gt" }
+    if isTrue(yamlnode.GetKey(schema, "exclusiveMaximum")) { upper = ".lt" }
+    e += g.bounds(schema, "minimum", lower, "maximum", upper)
+    for _, k := range [][2]string{{"exclusiveMinimum", ".gt"}, {"exclusiveMaximum", ".lt"}} {
+        if v := yamlnode.GetKey(schema, k[0]); v != nil && (v.Tag == "!!int" || v.Tag == "!!float") { e += k[1] + "(" + v.Value + ")" }
+    }
+    if v := scalar(schema, "multipleOf"); v != "" { e += ".multipleOf(" + v + ")" }
+    return e
+}
+
+// bounds renders zod min/max calls for the given keywords.
+func (g *runtimeGen) bounds(schema *yaml.Node, minKey, minFn, maxKey, maxFn string) string {
+    e := ""
+    if v := yamlnode.GetKey(schema, minKey); v != nil && (v.Tag == "!!int" || v.Tag == "!!float") { e += minFn + "(" + v.Value + ")" }
+    if v := yamlnode.GetKey(schema, maxKey); v != nil && (v.Tag == "!!int" || v.Tag == "!!float") { e += maxFn + "(" + v.Value + ")" }
+    return e
+}
+
+// options renders the given scalar keywords as a TypeBox options argument,
+// with a leading comma.
+func (g *runtimeGen) options(schema *yaml.Node, keys ...string) string {
+    var opts []string
+    for _, k := range keys {
+        v := yamlnode.GetKey(schema, k)
+        if v == nil || v.Kind != yaml.ScalarNode { continue }
+        opts = append(opts, k+": "+literal(v))
+    }
+    if len(opts) == 0 { return "" }
+    return ", { " + strings.Join(opts, ", ") + " }"
+}
+
+func (g *runtimeGen) literal(v *yaml.Node) string {
+    if v.Kind == yaml.ScalarNode && v.Tag == "!!null" { return g.lib("null") }
+    if g.kind == RuntimeZod { return "z.literal(" + literal(v) + ")" }
+    return "Type.Literal(" + literal(v) + ")"
+}
+
+func (g *runtimeGen) union(parts []string) string {
+    if len(parts) == 1 { return parts[0] }
+    if g.kind == RuntimeZod { return "z.union([" + strings.Join(parts, ", ") + "])" }
+    return "Type.Union([" + strings.Join(parts, ", ") + "])"
+}
+
+// lib renders a primitive: unknown, boolean or null.
+func (g *runtimeGen) lib(name string) string {
+    if g.kind == RuntimeZod { return "z." + name + "()" }
+    return "Type." + strings.ToUpper(name[:1]) + name[1:] + "()"
+}
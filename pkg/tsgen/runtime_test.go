@@ -0,0 +1,59 @@
+package tsgen
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestRuntime(t *testing.T) {
+    const schemas = `    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string, minLength: 1}
+        tag: {type: string, nullable: true}
+        age: {type: integer, minimum: 0, exclusiveMinimum: true}
+        kind: {$ref: '#/components/schemas/Kind'}
+        owner: {$ref: '#/components/schemas/Owner'}
+    Kind:
+      type: string
+      enum: [dog, cat]
+    Owner:
+      type: object
+      additionalProperties: false
+      properties:
+        email: {type: string, format: email}
+        pets: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+`
+    tests := []struct {
+        kind string
+        want []string
+    }{
+        {RuntimeZod, []string{
+            "import { z } from \"zod\";\n",
+            "export const KindSchema = z.enum([\"dog\", \"cat\"]);\n",
+            // Owner is emitted before Pet and refers back to it lazily.
+            "export const OwnerSchema: z.ZodType<any> = z.object({\n  email: z.string().email().optional(),\n  pets: z.array(z.lazy(() => PetSchema)).optional(),\n}).strict();\n",
+            "export const PetSchema = z.object({\n  name: z.string().min(1),\n  tag: z.string().nullable().optional(),\n  age: z.number().int().gt(0).optional(),\n  kind: KindSchema.optional(),\n  owner: OwnerSchema.optional(),\n});\n",
+        }},
+        {RuntimeTypeBox, []string{
+            "import { Type } from \"@sinclair/typebox\";\n",
+            "export const KindSchema = Type.Union([Type.Literal(\"dog\"), Type.Literal(\"cat\")]);\n",
+            "  age: Type.Optional(Type.Integer({ exclusiveMinimum: 0 })),\n",
+            "  pets: Type.Optional(Type.Array(Type.Unknown() /* cyclic: PetSchema */)),\n",
+            "  tag: Type.Optional(Type.Union([Type.String(), Type.Null()])),\n",
+            "}, { additionalProperties: false });\n",
+        }},
+    }
+    spec := parse(t, "openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\ncomponents:\n  schemas:\n"+schemas)
+    for _, tt := range tests {
+        t.Run(tt.kind, func(t *testing.T) {
+            out, err := Runtime(spec, tt.kind)
+            if err != nil { t.Fatal(err) }
+            for _, want := range tt.want {
+                if !strings.Contains(string(out), want) { t.Errorf("output lacks:\n%s\nin:\n%s", want, out) }
+            }
+        })
+    }
+    if _, err := Runtime(spec, "joi"); err == nil || !strings.Contains(err.Error(), "unknown runtime schema library") { t.Errorf("error = %v", err) }
+}
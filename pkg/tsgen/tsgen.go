@@ -0,0 +1,360 @@
+// Package tsgen emits TypeScript types for a bundled OpenAPI spec without
+// Node or an external generator: an interface or type alias per component
+// schema, and per operation its parameters, request body and responses.
+//
+// Schemas map as openapi-typescript maps them: objects to interfaces (or
+// inline object types), enums and const to literal unions, oneOf/anyOf to
+// unions, allOf to intersections, nullable and 3.1 "null" types to "| null",
+// and binary strings to Blob.
+package tsgen
+
+import (
+    "bytes"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// maxDepth bounds inline expansion; deeper schemas are unknown.
+const maxDepth = 32
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type generator struct {
+    root  *yaml.Node
+    names map[string]string // component schema name -> TS name
+    buf   bytes.Buffer
+}
+
+// Generate renders the types of spec, the top-level node of a bundled
+// document.
+func Generate(spec *yaml.Node) ([]byte, error) {
+    if spec == nil || spec.Kind != yaml.MappingNode { return nil, fmt.Errorf("spec must be a mapping") }
+    g := &generator{root: spec, names: map[string]string{}}
+    info := yamlnode.GetKey(spec, "info")
+    fmt.Fprintf(&g.buf, "// Generated by oas-indexer from %s %s. Do not edit.\n", firstNonEmpty(scalar(info, "title"), "API"), scalar(info, "version"))
+
+    schemas := yamlnode.GetKey(yamlnode.GetKey(spec, "components"), "schemas")
+    taken := map[string]bool{}
+    if schemas != nil && schemas.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(schemas.Content); i += 2 {
+            name := Identifier(schemas.Content[i].Value)
+            for n := 2; taken[name]; n++ { name = Identifier(schemas.Content[i].Value) + strconv.Itoa(n) }
+            taken[name] = true
+            g.names[schemas.Content[i].Value] = name
+        }
+        for i := 0; i+1 < len(schemas.Content); i += 2 {
+            g.declare(g.names[schemas.Content[i].Value], schemas.Content[i+1])
+        }
+    }
+
+    paths := yamlnode.GetKey(spec, "paths")
+    if paths == nil || paths.Kind != yaml.MappingNode { return g.buf.Bytes(), nil }
+    for i := 0; i+1 < len(paths.Content); i += 2 {
+        path, item := paths.Content[i].Value, g.deref(paths.Content[i+1])
+        for _, m := range httpMethods {
+            op := g.deref(yamlnode.GetKey(item, m))
+            if op == nil || op.Kind != yaml.MappingNode { continue }
+            name := firstNonEmpty(Identifier(scalar(op, "operationId")), Identifier(m+" "+path))
+            for taken[name] { name += "Operation" }
+            taken[name] = true
+            g.operation(name, m, path, item, op)
+        }
+    }
+    return g.buf.Bytes(), nil
+}
+
+// declare writes a component schema as an interface when it is a plain
+// object, else as a type alias.
+func (g *generator) declare(name string, schema *yaml.Node) {
+    g.buf.WriteString("\n")
+    g.doc(schema, "")
+    if isPlainObject(schema) {
+        fmt.Fprintf(&g.buf, "export interface %s %s\n", name, g.object(schema, "", 0))
+        return
+    }
+    fmt.Fprintf(&g.buf, "export type %s = %s;\n", name, g.typeOf(schema, "", 0))
+}
+
+// operation writes the parameter, request body and response types of op.
+func (g *generator) operation(name, method, path string, item, op *yaml.Node) {
+    fmt.Fprintf(&g.buf, "\n// %s %s\n", strings.ToUpper(method), path)
+    params := map[string][]*yaml.Node{}
+    seen := map[string]int{}
+    for _, list := range []*yaml.Node{yamlnode.GetKey(item, "parameters"), yamlnode.GetKey(op, "parameters")} {
+        if list == nil || list.Kind != yaml.SequenceNode { continue }
+        for _, p := range list.Content {
+            p = g.deref(p)
+            in, pname := scalar(p, "in"), scalar(p, "name")
+            if in == "" || pname == "" { continue }
+            key := in + ":" + pname
+            if i, ok := seen[key]; ok {
+                params[in][i] = p // operation parameters override the path item's
+                continue
+            }
+            seen[key] = len(params[in])
+            params[in] = append(params[in], p)
+        }
+    }
+    suffixes := map[string]string{"path": "PathParams", "query": "QueryParams", "header": "HeaderParams", "cookie": "CookieParams"}
+    for _, in := range []string{"path", "query", "header", "cookie"} {
+        if len(params[in]) == 0 { continue }
+        fmt.Fprintf(&g.buf, "export interface %s%s {\n", name, suffixes[in])
+        for _, p := range params[in] {
+            schema := yamlnode.GetKey(p, "schema")
+            if schema == nil { schema = mediaSchema(yamlnode.GetKey(p, "content")) }
+            g.doc(p, "  ")
+            opt := "?"
+            if in == "path" || isTrue(yamlnode.GetKey(p, "required")) { opt = "" }
+            fmt.Fprintf(&g.buf, "  %s%s: %s;\n", propertyKey(scalar(p, "name")), opt, g.typeOf(schema, "  ", 1))
+        }
+        g.buf.WriteString("}\n")
+    }
+
+    if rb := g.deref(yamlnode.GetKey(op, "requestBody")); rb != nil {
+        if schema := mediaSchema(yamlnode.GetKey(rb, "content")); schema != nil {
+            fmt.Fprintf(&g.buf, "export type %sRequestBody = %s;\n", name, g.typeOf(schema, "", 1))
+        }
+    }
+
+    responses := yamlnode.GetKey(op, "responses")
+    if responses == nil || responses.Kind != yaml.MappingNode { return }
+    var success []string
+    for i := 0; i+1 < len(responses.Content); i += 2 {
+        code := responses.Content[i].Value
+        resp := g.deref(responses.Content[i+1])
+        typ := "void"
+        if schema := mediaSchema(yamlnode.GetKey(resp, "content")); schema != nil { typ = g.typeOf(schema, "", 1) }
+        suffix := strings.ToUpper(code)
+        if code == "default" { suffix = "Default" }
+        fmt.Fprintf(&g.buf, "export type %sResponse%s = %s;\n", name, suffix, typ)
+        if strings.HasPrefix(code, "2") { success = append(success, name+"Response"+suffix) }
+    }
+    if len(success) > 0 { fmt.Fprintf(&g.buf, "export type %sResponse = %s;\n", name, strings.Join(success, " | ")) }
+}
+
+// typeOf renders schema as a type expression; indent is the indentation of
+// the line it starts on.
+func (g *generator) typeOf(schema *yaml.Node, indent string, depth int) string {
+    if schema == nil || depth > maxDepth { return "unknown" }
+    if schema.Kind == yaml.ScalarNode && schema.Tag == "!!bool" {
+        if schema.Value == "false" { return "never" }
+        return "unknown"
+    }
+    if schema.Kind != yaml.MappingNode { return "unknown" }
+    if ref := yamlnode.GetKey(schema, "$ref"); ref != nil {
+        if name, ok := strings.CutPrefix(ref.Value, "#/components/schemas/"); ok {
+            if ts, ok := g.names[yamlnode.UnescapeToken(name)]; ok { return ts }
+        }
+        return g.typeOf(g.deref(schema), indent, depth+1)
+    }
+    t := g.baseType(schema, indent, depth)
+    if isTrue(yamlnode.GetKey(schema, "nullable")) && t != "unknown" && !strings.HasSuffix(t, "| null") { t = union([]string{t, "null"}) }
+    return t
+}
+
+func (g *generator) baseType(schema *yaml.Node, indent string, depth int) string {
+    if c := yamlnode.GetKey(schema, "const"); c != nil { return literal(c) }
+    if enum := yamlnode.GetKey(schema, "enum"); enum != nil && enum.Kind == yaml.SequenceNode && len(enum.Content) > 0 {
+        var lits []string
+        for _, v := range enum.Content { lits = append(lits, literal(v)) }
+        return union(lits)
+    }
+    for _, key := range []string{"oneOf", "anyOf"} {
+        if list := yamlnode.GetKey(schema, key); list != nil && list.Kind == yaml.SequenceNode && len(list.Content) > 0 {
+            var parts []string
+            for _, s := range list.Content { parts = append(parts, g.typeOf(s, indent, depth+1)) }
+            return union(parts)
+        }
+    }
+    if list := yamlnode.GetKey(schema, "allOf"); list != nil && list.Kind == yaml.SequenceNode && len(list.Content) > 0 {
+        var parts []string
+        for _, s := range list.Content { parts = append(parts, wrap(g.typeOf(s, indent, depth+1), "&")) }
+        if yamlnode.GetKey(schema, "properties") != nil { parts = append(parts, g.object(schema, indent, depth)) }
+        return strings.Join(parts, " & ")
+    }
+
+    types := yamlnode.SchemaTypes(schema)
+    var parts []string
+    for _, t := range types {
+        switch t {
+        case "string":
+            if f := scalar(schema, "format"); f == "binary" {
+                parts = append(parts, "Blob")
+            } else {
+                parts = append(parts, "string")
+            }
+        case "integer", "number":
+            parts = append(parts, "number")
+        case "boolean":
+            parts = append(parts, "boolean")
+        case "null":
+            parts = append(parts, "null")
+        case "array":
+            items := yamlnode.GetKey(schema, "items")
+            if items == nil { parts = append(parts, "unknown[]") } else { parts = append(parts, wrap(g.typeOf(items, indent, depth+1), "[]")+"[]") }
+        case "object":
+            parts = append(parts, g.object(schema, indent, depth))
+        }
+    }
+    if len(parts) == 0 { return "unknown" }
+    return union(parts)
+}
+
+// object renders an object schema as an inline object type.
+func (g *generator) object(schema *yaml.Node, indent string, depth int) string {
+    props := yamlnode.GetKey(schema, "properties")
+    extra := yamlnode.GetKey(schema, "additionalProperties")
+    if (props == nil || len(props.Content) == 0) && (extra == nil || extra.Kind == yaml.ScalarNode && extra.Value == "true") {
+        return "Record<string, unknown>"
+    }
+    if (props == nil || len(props.Content) == 0) && extra != nil && extra.Kind == yaml.MappingNode {
+        return "Record<string, " + g.typeOf(extra, indent, depth+1) + ">"
+    }
+    required := map[string]bool{}
+    if req := yamlnode.GetKey(schema, "required"); req != nil {
+        for _, r := range req.Content { required[r.Value] = true }
+    }
+    inner := indent + "  "
+    var b strings.Builder
+    b.WriteString("{\n")
+    if props != nil {
+        for i := 0; i+1 < len(props.Content); i += 2 {
+            key, prop := props.Content[i].Value, props.Content[i+1]
+            b.WriteString(g.docString(prop, inner))
+            opt := "?"
+            if required[key] { opt = "" }
+            ro := ""
+            if isTrue(yamlnode.GetKey(g.deref(prop), "readOnly")) { ro = "readonly " }
+            fmt.Fprintf(&b, "%s%s%s%s: %s;\n", inner, ro, propertyKey(key), opt, g.typeOf(prop, inner, depth+1))
+        }
+    }
+    if extra != nil && !(extra.Kind == yaml.ScalarNode && extra.Value == "false") {
+        t := "unknown"
+        if extra.Kind == yaml.MappingNode { t = g.typeOf(extra, inner, depth+1) }
+        fmt.Fprintf(&b, "%s[key: string]: %s;\n", inner, t)
+    }
+    b.WriteString(indent + "}")
+    return b.String()
+}
+
+// doc writes schema's description and deprecation as a JSDoc comment.
+func (g *generator) doc(n *yaml.Node, indent string) { g.buf.WriteString(g.docString(n, indent)) }
+
+func (g *generator) docString(n *yaml.Node, indent string) string {
+    desc := strings.TrimSpace(firstNonEmpty(scalar(n, "description"), scalar(n, "title")))
+    deprecated := isTrue(yamlnode.GetKey(n, "deprecated"))
+    if desc == "" && !deprecated { return "" }
+    var lines []string
+    if desc != "" { lines = strings.Split(strings.ReplaceAll(desc, "*/", "*\\/"), "\n") }
+    if deprecated { lines = append(lines, "@deprecated") }
+    if len(lines) == 1 { return indent + "/** " + lines[0] + " */\n" }
+    var b strings.Builder
+    b.WriteString(indent + "/**\n")
+    for _, l := range lines { b.WriteString(strings.TrimRight(indent+" * "+l, " ") + "\n") }
+    b.WriteString(indent + " */\n")
+    return b.String()
+}
+
+// deref follows local refs such as #/components/responses/NotFound.
+func (g *generator) deref(n *yaml.Node) *yaml.Node { return yamlnode.Deref(g.root, n) }
+
+// mediaSchema picks the schema of the JSON media type, else of the first.
+func mediaSchema(content *yaml.Node) *yaml.Node {
+    if content == nil || content.Kind != yaml.MappingNode || len(content.Content) < 2 { return nil }
+    for i := 0; i+1 < len(content.Content); i += 2 {
+        if strings.Contains(content.Content[i].Value, "json") { return yamlnode.GetKey(content.Content[i+1], "schema") }
+    }
+    return yamlnode.GetKey(content.Content[1], "schema")
+}
+
+func isPlainObject(schema *yaml.Node) bool {
+    if schema == nil || schema.Kind != yaml.MappingNode || yamlnode.GetKey(schema, "$ref") != nil { return false }
+    for _, k := range []string{"allOf", "oneOf", "anyOf", "enum", "const"} {
+        if yamlnode.GetKey(schema, k) != nil { return false }
+    }
+    if isTrue(yamlnode.GetKey(schema, "nullable")) || yamlnode.GetKey(schema, "properties") == nil { return false }
+    types := yamlnode.SchemaTypes(schema)
+    return len(types) == 1 && types[0] == "object"
+}
+
+// literal renders an enum or const value.
+func literal(v *yaml.Node) string {
+    if v.Kind != yaml.ScalarNode { return "unknown" }
+    switch v.Tag {
+    case "!!null":
+        return "null"
+    case "!!int", "!!float", "!!bool":
+        return v.Value
+    }
+    return strconv.Quote(v.Value)
+}
+
+// union joins types with |, dropping duplicates.
+func union(parts []string) string {
+    seen := map[string]bool{}
+    var out []string
+    for _, p := range parts {
+        if seen[p] { continue }
+        seen[p] = true
+        out = append(out, wrap(p, "|"))
+    }
+    return strings.Join(out, " | ")
+}
+
+// wrap parenthesizes t where op would otherwise bind into it.
+func wrap(t, op string) string {
+    if strings.HasPrefix(t, "{") || strings.HasPrefix(t, "Record<") { return t }
+    if (op == "[]" || op == "&") && (strings.Contains(t, " | ") || strings.Contains(t, " & ")) { return "(" + t + ")" }
+    if op == "|" && strings.Contains(t, " & ") { return "(" + t + ")" }
+    return t
+}
+
+var reIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// propertyKey quotes keys that are not identifiers.
+func propertyKey(k string) string {
+    if reIdent.MatchString(k) { return k }
+    return strconv.Quote(k)
+}
+
+// Identifier turns a component name, operationId or "method path" into a
+// PascalCase TypeScript identifier.
+func Identifier(s string) string {
+    var b strings.Builder
+    up := true
+    for _, r := range s {
+        if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+            up = true
+            continue
+        }
+        if up {
+            b.WriteString(strings.ToUpper(string(r)))
+            up = false
+            continue
+        }
+        b.WriteRune(r)
+    }
+    out := b.String()
+    if out != "" && out[0] >= '0' && out[0] <= '9' { out = "_" + out }
+    return out
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := yamlnode.GetKey(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+func isTrue(n *yaml.Node) bool { return n != nil && n.Kind == yaml.ScalarNode && n.Value == "true" }
+
+func firstNonEmpty(vals ...string) string {
+    for _, v := range vals {
+        if v != "" { return v }
+    }
+    return ""
+}
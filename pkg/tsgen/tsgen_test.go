@@ -0,0 +1,109 @@
+package tsgen
+
+import (
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+// parse returns the top-level node of a YAML document.
+func parse(t *testing.T, text string) *yaml.Node {
+    t.Helper()
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(text), &doc); err != nil { t.Fatal(err) }
+    return doc.Content[0]
+}
+
+func TestIdentifier(t *testing.T) {
+    tests := []struct{ in, want string }{
+        {"user", "User"},
+        {"list-users", "ListUsers"},
+        {"get /v1/users/{id}", "GetV1UsersId"},
+        {"snake_case", "Snake_case"},
+        {"2fa.Token", "_2faToken"},
+        {"", ""},
+    }
+    for _, tt := range tests {
+        if got := Identifier(tt.in); got != tt.want { t.Errorf("Identifier(%q) = %q, want %q", tt.in, got, tt.want) }
+    }
+}
+
+func TestGenerateSchemas(t *testing.T) {
+    tests := []struct{ name, schema, want string }{
+        {"object", "type: object\nrequired: [id]\nproperties:\n  id: {type: string, readOnly: true}\n  tags: {type: array, items: {type: string}}\n",
+            "export interface T {\n  readonly id: string;\n  tags?: string[];\n}\n"},
+        {"enum", "type: string\nenum: [a, b]\n", `export type T = "a" | "b";`},
+        {"nullable", "type: integer\nnullable: true\n", "export type T = number | null;"},
+        {"3.1 type list", "type: [string, 'null']\n", "export type T = string | null;"},
+        {"const", "const: 3\n", "export type T = 3;"},
+        {"oneOf of refs", "oneOf:\n  - $ref: '#/components/schemas/Other'\n  - type: boolean\n", "export type T = Other | boolean;"},
+        {"allOf", "allOf:\n  - $ref: '#/components/schemas/Other'\n  - properties: {x: {type: number}}\n", "export type T = Other & {\n  x?: number;\n};"},
+        {"map", "type: object\nadditionalProperties: {type: string}\n", "export type T = Record<string, string>;"},
+        {"binary", "type: string\nformat: binary\n", "export type T = Blob;"},
+        {"array of a union", "type: array\nitems: {oneOf: [{type: string}, {type: number}]}\n", "export type T = (string | number)[];"},
+        {"documented", "description: A thing.\ndeprecated: true\ntype: string\n", "/**\n * A thing.\n * @deprecated\n */\nexport type T = string;"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            spec := parse(t, "openapi: 3.1.0\ninfo: {title: Test, version: '1'}\ncomponents:\n  schemas:\n    Other: {type: object}\n    T:\n"+indent(tt.schema, "      "))
+            out, err := Generate(spec)
+            if err != nil { t.Fatal(err) }
+            if !strings.Contains(string(out), tt.want) { t.Errorf("output:\n%s\nwant it to contain:\n%s", out, tt.want) }
+        })
+    }
+}
+
+func TestGenerateOperations(t *testing.T) {
+    spec := parse(t, `openapi: 3.0.3
+info: {title: Users, version: 1.0.0}
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, schema: {type: string}}
+    get:
+      operationId: get-user
+      parameters:
+        - {name: expand, in: query, schema: {type: boolean}}
+        - $ref: '#/components/parameters/Trace'
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+        '404':
+          $ref: '#/components/responses/NotFound'
+    delete:
+      responses:
+        '204': {description: gone}
+components:
+  parameters:
+    Trace: {name: X-Trace, in: header, required: true, schema: {type: string}}
+  responses:
+    NotFound:
+      description: missing
+      content:
+        application/json:
+          schema: {type: object, properties: {message: {type: string}}}
+  schemas:
+    User: {type: object, properties: {id: {type: string}}}
+`)
+    out, err := Generate(spec)
+    if err != nil { t.Fatal(err) }
+    for _, want := range []string{
+        "// Generated by oas-indexer from Users 1.0.0. Do not edit.\n",
+        "export interface User {\n  id?: string;\n}\n",
+        "// GET /users/{id}\nexport interface GetUserPathParams {\n  id: string;\n}\nexport interface GetUserQueryParams {\n  expand?: boolean;\n}\nexport interface GetUserHeaderParams {\n  \"X-Trace\": string;\n}\n",
+        "export type GetUserResponse200 = User;\n",
+        "export type GetUserResponse404 = {\n  message?: string;\n};\n",
+        "export type GetUserResponse = GetUserResponse200;\n",
+        "// DELETE /users/{id}\nexport interface DeleteUsersIdPathParams {\n  id: string;\n}\nexport type DeleteUsersIdResponse204 = void;\n",
+    } {
+        if !strings.Contains(string(out), want) { t.Errorf("output:\n%s\nwant it to contain:\n%s", out, want) }
+    }
+}
+
+func indent(s, prefix string) string {
+    return prefix + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n"+prefix) + "\n"
+}
@@ -0,0 +1,101 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bilbo290/oas-indexer/internal/atomicfile"
+)
+
+// Baseline lists accepted findings, so only new ones fail validation.
+// Findings match by rule, method and path; messages are informational.
+type Baseline struct {
+	Version  int             `json:"version"`
+	Findings []BaselineEntry `json:"findings"`
+}
+
+// BaselineEntry is one accepted finding.
+type BaselineEntry struct {
+	Rule    string `json:"rule"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e BaselineEntry) key() string { return e.Rule + " " + e.Method + " " + e.Path }
+
+// NewBaseline records results as accepted findings.
+func NewBaseline(results []Result) *Baseline {
+	b := &Baseline{Version: 1, Findings: []BaselineEntry{}}
+	seen := map[string]bool{}
+	for _, r := range results {
+		e := BaselineEntry{Rule: r.Rule, Method: r.Method, Path: r.Path, Message: r.Message}
+		if seen[e.key()] {
+			continue
+		}
+		seen[e.key()] = true
+		b.Findings = append(b.Findings, e)
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		x, y := b.Findings[i], b.Findings[j]
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		if x.Method != y.Method {
+			return x.Method < y.Method
+		}
+		return x.Rule < y.Rule
+	})
+	return b
+}
+
+// LoadBaseline reads a baseline file.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read baseline: %w", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if b.Version != 1 {
+		return nil, fmt.Errorf("%s: unsupported baseline version %d", path, b.Version)
+	}
+	return &b, nil
+}
+
+// Write saves the baseline as indented JSON, reporting whether it changed.
+func (b *Baseline) Write(path string) (bool, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	return atomicfile.WriteFile(path, append(data, '\n'))
+}
+
+// Filter drops the results recorded in the baseline. It returns the new
+// results and the number of baseline entries that no longer occur.
+func (b *Baseline) Filter(results []Result) ([]Result, int) {
+	accepted := map[string]bool{}
+	for _, e := range b.Findings {
+		accepted[e.key()] = true
+	}
+	matched := map[string]bool{}
+	var fresh []Result
+	for _, r := range results {
+		k := BaselineEntry{Rule: r.Rule, Method: r.Method, Path: r.Path}.key()
+		if accepted[k] {
+			matched[k] = true
+			continue
+		}
+		fresh = append(fresh, r)
+	}
+	return fresh, len(accepted) - len(matched)
+}
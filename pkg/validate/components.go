@@ -0,0 +1,190 @@
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Component is a schema or parameter definition under validation.
+type Component struct {
+	Name string                 // component key, e.g. Order
+	File string                 // fragment it came from, if any
+	Line int                    // line of the definition in File, if known
+	Def  map[string]interface{} // decoded definition
+}
+
+// Targets reported in Result.Method for component findings, whose Path is
+// the component's JSON pointer.
+const (
+	TargetSchema    = "SCHEMA"
+	TargetParameter = "PARAMETER"
+)
+
+// componentTarget is a rule's target kind and the components it checks.
+type componentTarget struct {
+	method  string
+	section string
+	defs    []Component
+	check   func(Rule) func(string, map[string]interface{}) error
+}
+
+func (s *Spec) componentTargets() []componentTarget {
+	return []componentTarget{
+		{TargetSchema, "schemas", s.Schemas, func(r Rule) func(string, map[string]interface{}) error { return r.ValidateSchema }},
+		{TargetParameter, "parameters", s.Parameters, func(r Rule) func(string, map[string]interface{}) error { return r.ValidateParameter }},
+	}
+}
+
+func sortComponents(defs []Component) {
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+}
+
+// Schema rules
+
+var reCamelCase = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+// walkSchema calls fn for s and every schema nested in it, with the location
+// of each relative to s (e.g. "properties.items.items"), stopping at the
+// first error.
+func walkSchema(s map[string]interface{}, at string, fn func(at string, s map[string]interface{}) error) error {
+	if err := fn(at, s); err != nil {
+		return err
+	}
+	join := func(k string) string {
+		if at == "" {
+			return k
+		}
+		return at + "." + k
+	}
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, ok := props[name].(map[string]interface{}); ok {
+				if err := walkSchema(p, join("properties."+name), fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, k := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := s[k].(map[string]interface{}); ok {
+			if err := walkSchema(sub, join(k), fn); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range []string{"allOf", "oneOf", "anyOf"} {
+		subs, _ := s[k].([]interface{})
+		for i, v := range subs {
+			if sub, ok := v.(map[string]interface{}); ok {
+				if err := walkSchema(sub, join(fmt.Sprintf("%s[%d]", k, i)), fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func locationSuffix(at string) string {
+	if at == "" {
+		return ""
+	}
+	return " at " + at
+}
+
+func validateSchemaPropertyCase(name string, schema map[string]interface{}) error {
+	return walkSchema(schema, "", func(at string, s map[string]interface{}) error {
+		props, _ := s["properties"].(map[string]interface{})
+		names := make([]string, 0, len(props))
+		for prop := range props {
+			names = append(names, prop)
+		}
+		sort.Strings(names)
+		for _, prop := range names {
+			if !reCamelCase.MatchString(prop) {
+				return fmt.Errorf("property '%s'%s should use camelCase", prop, locationSuffix(at))
+			}
+		}
+		return nil
+	})
+}
+
+func validateSchemaDescription(name string, schema map[string]interface{}) error {
+	if _, ok := schema["$ref"]; ok {
+		return nil
+	}
+	if d, _ := schema["description"].(string); strings.TrimSpace(d) == "" {
+		return fmt.Errorf("schema should have a description")
+	}
+	return nil
+}
+
+// hasSchemaType reports whether s says what it holds: a type, a $ref, a
+// composition or an enum.
+func hasSchemaType(s map[string]interface{}) bool {
+	for _, k := range []string{"type", "$ref", "allOf", "oneOf", "anyOf", "not", "enum", "const"} {
+		if _, ok := s[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSchemaType(name string, schema map[string]interface{}) error {
+	return walkSchema(schema, "", func(at string, s map[string]interface{}) error {
+		if !hasSchemaType(s) {
+			return fmt.Errorf("schema%s should declare a type", locationSuffix(at))
+		}
+		return nil
+	})
+}
+
+func validateNoBareObject(name string, schema map[string]interface{}) error {
+	return walkSchema(schema, "", func(at string, s map[string]interface{}) error {
+		if s["type"] != "object" {
+			return nil
+		}
+		for _, k := range []string{"properties", "additionalProperties", "allOf", "oneOf", "anyOf", "$ref"} {
+			if _, ok := s[k]; ok {
+				return nil
+			}
+		}
+		return fmt.Errorf("object schema%s has no properties; declare properties or additionalProperties", locationSuffix(at))
+	})
+}
+
+// Parameter rules
+
+func validateParameterDescription(name string, param map[string]interface{}) error {
+	if _, ok := param["$ref"]; ok {
+		return nil
+	}
+	if d, _ := param["description"].(string); strings.TrimSpace(d) == "" {
+		return fmt.Errorf("parameter '%v' should have a description", param["name"])
+	}
+	return nil
+}
+
+func validateParameterSchema(name string, param map[string]interface{}) error {
+	if _, ok := param["$ref"]; ok {
+		return nil
+	}
+	if _, ok := param["content"]; ok {
+		return nil
+	}
+	schema, ok := param["schema"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("parameter '%v' should have a schema", param["name"])
+	}
+	if !hasSchemaType(schema) {
+		return fmt.Errorf("parameter '%v' schema should declare a type", param["name"])
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFile is the schema of a custom rules file:
+//
+//	name: acme
+//	extends: google
+//	rules:
+//	  - name: segments-lowercase
+//	    description: Path segments are lowercase
+//	    path:
+//	      segment: '^[a-z0-9-]+$'
+//	  - name: internal-tagged
+//	    methods: [get, post]
+//	    require: [operationId, tags, responses.200]
+//	    forbid: [x-internal]
+//	    severity: warning
+type RulesFile struct {
+	Name        string       `yaml:"name"`
+	Description string       `yaml:"description"`
+	Extends     string       `yaml:"extends"` // built-in preset whose rules run first
+	Rules       []CustomRule `yaml:"rules"`
+}
+
+// CustomRule is one declarative rule. Every check that is set must pass.
+type CustomRule struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Message     string   `yaml:"message"`  // replaces the generated message
+	Severity    string   `yaml:"severity"` // error (default), warning or info
+	Methods     []string `yaml:"methods"` // limit the rule to these methods
+	Path        struct {
+		Match   string `yaml:"match"`   // the path key must match
+		Forbid  string `yaml:"forbid"`  // the path key must not match
+		Segment string `yaml:"segment"` // every literal segment (not {params} or versions) must match
+	} `yaml:"path"`
+	Require []string `yaml:"require"` // operation fields that must be present; dots descend (responses.200)
+	Forbid  []string `yaml:"forbid"`  // operation fields that must be absent
+}
+
+var reVersionSegment = regexp.MustCompile(`^v\d+$`)
+
+// LoadRules reads a rules file and returns it as a preset with its name
+// (default "custom").
+func LoadRules(path string) (string, Preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", Preset{}, fmt.Errorf("read rules: %w", err)
+	}
+	name, p, err := ParseRules(data)
+	if err != nil {
+		return "", Preset{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return name, p, nil
+}
+
+// ParseRules compiles a rules file into a preset.
+func ParseRules(data []byte) (string, Preset, error) {
+	var f RulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return "", Preset{}, err
+	}
+	name := f.Name
+	if name == "" {
+		name = "custom"
+	}
+	p := Preset{Name: name, Description: f.Description}
+	if p.Description == "" {
+		p.Description = "Custom rules"
+	}
+	if f.Extends != "" {
+		base, ok := Presets[f.Extends]
+		if !ok {
+			return "", Preset{}, fmt.Errorf("extends unknown preset %q", f.Extends)
+		}
+		p.Rules = append(p.Rules, base.Rules...)
+	}
+	seen := map[string]bool{}
+	for i, cr := range f.Rules {
+		if cr.Name == "" {
+			return "", Preset{}, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if seen[cr.Name] {
+			return "", Preset{}, fmt.Errorf("duplicate rule name %q", cr.Name)
+		}
+		seen[cr.Name] = true
+		rule, err := compileRule(cr)
+		if err != nil {
+			return "", Preset{}, fmt.Errorf("rule %q: %w", cr.Name, err)
+		}
+		p.Rules = append(p.Rules, rule)
+	}
+	if len(p.Rules) == 0 {
+		return "", Preset{}, fmt.Errorf("no rules defined")
+	}
+	return name, p, nil
+}
+
+func compileRule(cr CustomRule) (Rule, error) {
+	compile := func(field, expr string) (*regexp.Regexp, error) {
+		if expr == "" {
+			return nil, nil
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("path.%s: %v", field, err)
+		}
+		return re, nil
+	}
+	match, err := compile("match", cr.Path.Match)
+	if err != nil {
+		return Rule{}, err
+	}
+	forbid, err := compile("forbid", cr.Path.Forbid)
+	if err != nil {
+		return Rule{}, err
+	}
+	segment, err := compile("segment", cr.Path.Segment)
+	if err != nil {
+		return Rule{}, err
+	}
+	if match == nil && forbid == nil && segment == nil && len(cr.Require) == 0 && len(cr.Forbid) == 0 {
+		return Rule{}, fmt.Errorf("no checks defined (set path, require or forbid)")
+	}
+	methods := map[string]bool{}
+	for _, m := range cr.Methods {
+		methods[strings.ToLower(m)] = true
+	}
+	fail := func(format string, args ...interface{}) error {
+		if cr.Message != "" {
+			return fmt.Errorf("%s", cr.Message)
+		}
+		return fmt.Errorf(format, args...)
+	}
+
+	validate := func(path string, method string, operation map[string]interface{}) error {
+		if len(methods) > 0 && !methods[strings.ToLower(method)] {
+			return nil
+		}
+		if match != nil && !match.MatchString(path) {
+			return fail("path does not match %s", match)
+		}
+		if forbid != nil && forbid.MatchString(path) {
+			return fail("path matches forbidden pattern %s", forbid)
+		}
+		if segment != nil {
+			for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+				if seg == "" || strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") || reVersionSegment.MatchString(seg) {
+					continue
+				}
+				if !segment.MatchString(seg) {
+					return fail("path segment '%s' does not match %s", seg, segment)
+				}
+			}
+		}
+		for _, field := range cr.Require {
+			if _, ok := lookupField(operation, field); !ok {
+				return fail("operation is missing required field '%s'", field)
+			}
+		}
+		for _, field := range cr.Forbid {
+			if _, ok := lookupField(operation, field); ok {
+				return fail("operation must not define '%s'", field)
+			}
+		}
+		return nil
+	}
+	severity := SeverityError
+	if cr.Severity != "" {
+		if severity, err = ParseSeverity(cr.Severity); err != nil || severity == SeverityOff {
+			return Rule{}, fmt.Errorf("invalid severity %q (expected error, warning or info)", cr.Severity)
+		}
+	}
+	desc := cr.Description
+	if desc == "" {
+		desc = "Custom rule " + cr.Name
+	}
+	return Rule{Name: cr.Name, Description: desc, Severity: severity, Validate: validate}, nil
+}
+
+// lookupField follows a dotted path (responses.200) through decoded maps.
+func lookupField(v interface{}, field string) (interface{}, bool) {
+	cur := v
+	for _, part := range strings.Split(field, ".") {
+		switch m := cur.(type) {
+		case map[string]interface{}:
+			next, ok := m[part]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case map[interface{}]interface{}:
+			found := false
+			for k, next := range m {
+				if fmt.Sprint(k) == part {
+					cur, found = next, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
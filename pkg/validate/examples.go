@@ -0,0 +1,311 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// ExampleError is an example value that does not conform to its schema.
+type ExampleError struct {
+	Pointer string // JSON pointer of the offending value, relative to the checked node
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ExampleError) Error() string { return e.Pointer + ": " + e.Message }
+
+// maxRefDepth bounds the $refs followed without descending into the value,
+// so schemas referring to each other in a loop cannot recurse forever.
+const maxRefDepth = 32
+
+// Examples checks every example below n against the schema it illustrates:
+// the example and examples of parameters, headers and media types against
+// their schema, and those of schemas (schema says whether n is one) against
+// the schema itself. Values must have the schema's type, its required
+// properties and one of its enum values; properties, items, allOf, anyOf and
+// oneOf are checked recursively. $refs to #/... are resolved in root; other
+// refs, and examples given by externalValue, are not checked.
+func Examples(n *yaml.Node, schema bool, root *yaml.Node) []ExampleError {
+	c := &exampleChecker{root: root}
+	c.walk(n, "", schema)
+	return c.errs
+}
+
+type exampleChecker struct {
+	root *yaml.Node
+	errs []ExampleError
+}
+
+// Keys whose value maps names to objects of one kind, walked entry by entry.
+var namedObjectKeys = map[string]bool{
+	"paths": true, "webhooks": true, "responses": true, "content": true, "headers": true,
+	"callbacks": true, "encoding": true, "requestBodies": true, "parameters": true,
+}
+
+// Keys holding values or data rather than OpenAPI objects.
+var exampleValueKeys = map[string]bool{
+	"example": true, "examples": true, "value": true, "default": true, "enum": true, "const": true,
+}
+
+// walk finds the examples below n, which is a Schema object when schema is set.
+func (c *exampleChecker) walk(n *yaml.Node, ptr string, schema bool) {
+	if n == nil || n.Kind != yaml.MappingNode { return }
+	if s := yamlnode.GetKey(n, "schema"); s != nil && !schema {
+		if ex := yamlnode.GetKey(n, "example"); ex != nil { c.check(ex, s, ptr+"/example") }
+		if exs := yamlnode.GetKey(n, "examples"); exs != nil && exs.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(exs.Content); i += 2 {
+				c.checkExampleObject(exs.Content[i+1], s, ptr+"/examples/"+yamlnode.EscapeToken(exs.Content[i].Value))
+			}
+		}
+	}
+	if schema {
+		if ex := yamlnode.GetKey(n, "example"); ex != nil { c.check(ex, n, ptr+"/example") }
+		if exs := yamlnode.GetKey(n, "examples"); exs != nil && exs.Kind == yaml.SequenceNode {
+			for i, ex := range exs.Content {
+				c.check(ex, n, ptr+"/examples/"+strconv.Itoa(i))
+			}
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i].Value, n.Content[i+1]
+		at := ptr + "/" + yamlnode.EscapeToken(k)
+		switch {
+		case exampleValueKeys[k] || strings.HasPrefix(k, "x-"):
+		case schema && (k == "properties" || k == "patternProperties"):
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				c.walk(v.Content[j+1], at+"/"+yamlnode.EscapeToken(v.Content[j].Value), true)
+			}
+		case schema && (k == "allOf" || k == "anyOf" || k == "oneOf"):
+			for j, sub := range v.Content {
+				c.walk(sub, at+"/"+strconv.Itoa(j), true)
+			}
+		case k == "schema" || schema && (k == "items" || k == "additionalProperties" || k == "not"):
+			c.walk(v, at, true)
+		case schema:
+		case k == "schemas":
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				c.walk(v.Content[j+1], at+"/"+yamlnode.EscapeToken(v.Content[j].Value), true)
+			}
+		case v.Kind == yaml.SequenceNode:
+			for j, item := range v.Content {
+				c.walk(item, at+"/"+strconv.Itoa(j), false)
+			}
+		case namedObjectKeys[k] && v.Kind == yaml.MappingNode:
+			for j := 0; j+1 < len(v.Content); j += 2 {
+				c.walk(v.Content[j+1], at+"/"+yamlnode.EscapeToken(v.Content[j].Value), false)
+			}
+		default:
+			c.walk(v, at, false)
+		}
+	}
+}
+
+// checkExampleObject checks the value of an Example object, which may be a
+// $ref to an examples component.
+func (c *exampleChecker) checkExampleObject(ex, schema *yaml.Node, ptr string) {
+	if ref := yamlnode.GetKey(ex, "$ref"); ref != nil {
+		target := c.resolve(ref.Value)
+		v := yamlnode.GetKey(target, "value")
+		if v == nil { return }
+		for _, msg := range c.match(v, schema, "", 0) {
+			c.errs = append(c.errs, ExampleError{Pointer: ptr, Line: ref.Line, Column: ref.Column, Message: ref.Value + "/value" + msg.at + ": " + msg.text})
+		}
+		return
+	}
+	if v := yamlnode.GetKey(ex, "value"); v != nil { c.check(v, schema, ptr+"/value") }
+}
+
+// check reports every way v, at ptr, does not conform to schema.
+func (c *exampleChecker) check(v, schema *yaml.Node, ptr string) {
+	for _, m := range c.match(v, schema, "", 0) {
+		c.errs = append(c.errs, ExampleError{Pointer: ptr + m.at, Line: m.node.Line, Column: m.node.Column, Message: m.text})
+	}
+}
+
+// mismatch is a value below an example, at the JSON pointer at relative to
+// the example, that does not conform to its schema.
+type mismatch struct {
+	at   string
+	node *yaml.Node
+	text string
+}
+
+// resolve returns the node a #/... ref points to in root, or nil.
+func (c *exampleChecker) resolve(ref string) *yaml.Node {
+	ptr, ok := strings.CutPrefix(ref, "#")
+	if !ok { return nil }
+	n, _ := yamlnode.Lookup(c.root, ptr)
+	return n
+}
+
+// match returns the mismatches of v against schema; depth counts the $refs
+// followed since the last step into v.
+func (c *exampleChecker) match(v, schema *yaml.Node, at string, depth int) []mismatch {
+	for v.Kind == yaml.AliasNode && v.Alias != nil {
+		v = v.Alias
+	}
+	if schema == nil || schema.Kind != yaml.MappingNode { return nil }
+	if ref := yamlnode.GetKey(schema, "$ref"); ref != nil {
+		if depth >= maxRefDepth { return nil }
+		return c.match(v, c.resolve(ref.Value), at, depth+1)
+	}
+	fail := func(format string, args ...interface{}) []mismatch {
+		return []mismatch{{at, v, fmt.Sprintf(format, args...)}}
+	}
+
+	kind := valueType(v)
+	if kind == "null" && isTrue(yamlnode.GetKey(schema, "nullable")) { return nil }
+	if types := yamlnode.DeclaredTypes(schema); len(types) > 0 && !typeAllowed(types, kind, v) {
+		return fail("expected %s, found %s", strings.Join(types, " or "), kind)
+	}
+	if enum := yamlnode.GetKey(schema, "enum"); enum != nil && enum.Kind == yaml.SequenceNode {
+		found := false
+		for _, e := range enum.Content {
+			if sameValue(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values := make([]string, len(enum.Content))
+			for i, e := range enum.Content {
+				values[i] = describe(e)
+			}
+			return fail("%s is not one of the enum values %s", describe(v), strings.Join(values, ", "))
+		}
+	}
+	if cv := yamlnode.GetKey(schema, "const"); cv != nil && !sameValue(v, cv) {
+		return fail("%s is not the const value %s", describe(v), describe(cv))
+	}
+
+	var out []mismatch
+	for _, sub := range seqItems(yamlnode.GetKey(schema, "allOf")) {
+		out = append(out, c.match(v, sub, at, depth+1)...)
+	}
+	for _, k := range []string{"anyOf", "oneOf"} {
+		subs := seqItems(yamlnode.GetKey(schema, k))
+		if len(subs) == 0 { continue }
+		matched := false
+		for _, sub := range subs {
+			if len(c.match(v, sub, at, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched { out = append(out, fail("matches none of the %s schemas", k)...) }
+	}
+
+	switch v.Kind {
+	case yaml.MappingNode:
+		props := yamlnode.GetKey(schema, "properties")
+		for _, name := range seqItems(yamlnode.GetKey(schema, "required")) {
+			p := c.deref(yamlnode.GetKey(props, name.Value))
+			if yamlnode.GetKey(v, name.Value) != nil || isTrue(yamlnode.GetKey(p, "readOnly")) || isTrue(yamlnode.GetKey(p, "writeOnly")) { continue }
+			out = append(out, mismatch{at, v, fmt.Sprintf("missing required property %q", name.Value)})
+		}
+		extra := yamlnode.GetKey(schema, "additionalProperties")
+		for i := 0; i+1 < len(v.Content); i += 2 {
+			name, val := v.Content[i].Value, v.Content[i+1]
+			sub := at + "/" + yamlnode.EscapeToken(name)
+			if p := yamlnode.GetKey(props, name); p != nil {
+				out = append(out, c.match(val, p, sub, 0)...)
+				continue
+			}
+			switch {
+			case extra == nil:
+			case extra.Kind == yaml.ScalarNode && extra.Value == "false":
+				out = append(out, mismatch{sub, v.Content[i], fmt.Sprintf("property %q is not allowed (additionalProperties is false)", name)})
+			case extra.Kind == yaml.MappingNode:
+				out = append(out, c.match(val, extra, sub, 0)...)
+			}
+		}
+	case yaml.SequenceNode:
+		if items := yamlnode.GetKey(schema, "items"); items != nil {
+			for i, item := range v.Content {
+				out = append(out, c.match(item, items, at+"/"+strconv.Itoa(i), 0)...)
+			}
+		}
+	}
+	return out
+}
+
+// deref follows the $refs of schema s.
+func (c *exampleChecker) deref(s *yaml.Node) *yaml.Node { return yamlnode.Deref(c.root, s) }
+
+// valueType returns the JSON type of v: string, integer, number, boolean,
+// null, array or object.
+func valueType(v *yaml.Node) string {
+	switch v.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch v.ShortTag() {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string" // !!str, and !!timestamp for unquoted dates
+}
+
+func typeAllowed(types []string, kind string, v *yaml.Node) bool {
+	for _, t := range types {
+		switch {
+		case t == kind,
+			t == "number" && kind == "integer",
+			t == "integer" && kind == "number" && isIntegral(v.Value):
+			return true
+		}
+	}
+	return false
+}
+
+func isIntegral(s string) bool {
+	f, err := strconv.ParseFloat(s, 64)
+	return err == nil && f == math.Trunc(f) && !math.IsInf(f, 0)
+}
+
+func isTrue(n *yaml.Node) bool { return n != nil && n.Kind == yaml.ScalarNode && n.Value == "true" }
+
+func seqItems(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode { return nil }
+	return n.Content
+}
+
+// sameValue compares two nodes as the JSON values they decode to.
+func sameValue(a, b *yaml.Node) bool {
+	var x, y interface{}
+	if a.Decode(&x) != nil || b.Decode(&y) != nil { return false }
+	return reflect.DeepEqual(x, y)
+}
+
+// describe renders a value for a message on one line.
+func describe(v *yaml.Node) string {
+	var x interface{}
+	if err := v.Decode(&x); err != nil { return yamlnode.KindName(v) }
+	switch x := x.(type) {
+	case string:
+		return strconv.Quote(x)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(x)
+}
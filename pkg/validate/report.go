@@ -0,0 +1,208 @@
+package validate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Report formats accepted by --validate-format.
+const (
+	ReportText  = "text"
+	ReportJSON  = "json"
+	ReportJUnit = "junit"
+)
+
+// Report is the outcome of one validation run, for machine-readable output.
+type Report struct {
+	Preset  Preset
+	Spec    *Spec
+	Results []Result // findings that count
+	Skipped []Result // findings ignored via x-lint-ignore or the baseline
+}
+
+// Write renders the report in the given format (json or junit).
+func (r Report) Write(w io.Writer, format string) error {
+	switch format {
+	case ReportJSON:
+		return r.writeJSON(w)
+	case ReportJUnit:
+		return r.writeJUnit(w)
+	}
+	return fmt.Errorf("unknown report format %q (expected text, json or junit)", format)
+}
+
+type jsonFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func toJSONFindings(results []Result) []jsonFinding {
+	out := []jsonFinding{}
+	for _, r := range results {
+		out = append(out, jsonFinding{r.Rule, r.Severity, r.Method, r.Path, r.File, r.Line, r.Message})
+	}
+	return out
+}
+
+func (r Report) writeJSON(w io.Writer) error {
+	counts := map[string]int{}
+	for _, res := range r.Results {
+		counts[res.Severity]++
+	}
+	doc := struct {
+		Preset   string         `json:"preset"`
+		Passed   bool           `json:"passed"`
+		Summary  map[string]int `json:"summary"`
+		Findings []jsonFinding  `json:"findings"`
+		Skipped  []jsonFinding  `json:"skipped"`
+	}{
+		Preset: r.Preset.Name,
+		Passed: counts[SeverityError] == 0,
+		Summary: map[string]int{
+			"errors":   counts[SeverityError],
+			"warnings": counts[SeverityWarning],
+			"info":     counts[SeverityInfo],
+			"skipped":  len(r.Skipped),
+		},
+		Findings: toJSONFindings(r.Results),
+		Skipped:  toJSONFindings(r.Skipped),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit emits one test suite per rule with a test case per operation or
+// component it checks, so passing checks show up as well. Errors are failures,
+// ignored findings are skipped, and warnings and info pass with the message
+// as system-out.
+func (r Report) writeJUnit(w io.Writer) error {
+	type key struct{ rule, method, path string }
+	found := map[key]Result{}
+	skipped := map[key]Result{}
+	for _, res := range r.Results {
+		found[key{res.Rule, res.Method, res.Path}] = res
+	}
+	for _, res := range r.Skipped {
+		skipped[key{res.Rule, res.Method, res.Path}] = res
+	}
+
+	doc := junitSuites{Name: "oas-indexer validation: " + r.Preset.Name}
+	for _, rule := range r.Preset.Rules {
+		suite := junitSuite{Name: rule.Name}
+		for _, t := range r.Spec.targets(rule) {
+			k := key{rule.Name, t.method, t.path}
+			tc := junitCase{
+				Name:      t.method + " " + t.path,
+				Classname: rule.Name,
+				File:      t.file,
+				Line:      t.line,
+			}
+			if res, ok := found[k]; ok {
+				if res.Severity == SeverityError {
+					tc.Failure = &junitMessage{Message: res.Message, Type: rule.Name, Text: res.Message}
+					suite.Failures++
+				} else {
+					tc.SystemOut = res.Severity + ": " + res.Message
+				}
+			} else if res, ok := skipped[k]; ok {
+				tc.Skipped = &junitMessage{Message: res.Message}
+				suite.Skipped++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// target is an operation or component a rule checks, keyed like its results.
+type target struct {
+	method string
+	path   string
+	file   string
+	line   int
+}
+
+// targets lists what rule checks, in the order RunPreset checks it.
+func (s *Spec) targets(rule Rule) []target {
+	var out []target
+	if rule.Validate != nil {
+		for _, path := range s.Paths {
+			var methods []string
+			for method, v := range path.Item {
+				if _, ok := v.(map[string]interface{}); ok {
+					methods = append(methods, method)
+				}
+			}
+			sort.Strings(methods)
+			for _, method := range methods {
+				out = append(out, target{strings.ToUpper(method), path.Key, path.File, path.Lines[method]})
+			}
+		}
+	}
+	for _, t := range s.componentTargets() {
+		if t.check(rule) == nil {
+			continue
+		}
+		for _, c := range t.defs {
+			out = append(out, target{t.method, "#/components/" + t.section + "/" + c.Name, c.File, c.Line})
+		}
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Individual validation functions
+
+func validateHTTPMethods(path string, method string, operation map[string]interface{}) error {
+	validMethods := map[string]bool{
+		"get":     true,
+		"post":    true,
+		"put":     true,
+		"patch":   true,
+		"delete":  true,
+		"head":    true,
+		"options": true,
+	}
+	
+	if !validMethods[strings.ToLower(method)] {
+		return fmt.Errorf("invalid HTTP method '%s', should be one of: GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS", method)
+	}
+	return nil
+}
+
+func validatePluralCollections(path string, method string, operation map[string]interface{}) error {
+	// Extract path segments, ignoring parameters
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	
+	// Common singular words that should be plural in API paths
+	singularWords := []string{
+		"account", "user", "mission", "reward", "partner", "activity", "car", "member",
+		"order", "product", "service", "item", "category", "group", "role", "permission",
+		"resource", "entity", "record", "document", "file", "image", "video", "comment",
+	}
+	
+	for _, segment := range segments {
+		// Skip parameters (enclosed in braces)
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			continue
+		}
+		
+		// Skip version segments
+		if matched, _ := regexp.MatchString(`^v\d+$`, segment); matched {
+			continue
+		}
+		
+		// Check if segment is a known singular word
+		for _, singular := range singularWords {
+			if strings.ToLower(segment) == singular {
+				plural := makePlural(singular)
+				return fmt.Errorf("collection name '%s' should be plural: '%s'", segment, plural)
+			}
+		}
+	}
+	return nil
+}
+
+func validateKebabCase(path string, method string, operation map[string]interface{}) error {
+	// Extract path segments, ignoring parameters
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	kebabRegex := regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$|^v\d+$|^\{[^}]+\}$`)
+	
+	for _, segment := range segments {
+		if !kebabRegex.MatchString(segment) {
+			return fmt.Errorf("path segment '%s' should use kebab-case (lowercase with dashes): '%s' (see --path-casing kebab)", segment, kebabSuggestion(segment))
+		}
+	}
+	return nil
+}
+
+func validateNoTrailingSlash(path string, method string, operation map[string]interface{}) error {
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		return fmt.Errorf("path should not have trailing slash")
+	}
+	return nil
+}
+
+func validateOperationId(path string, method string, operation map[string]interface{}) error {
+	if _, exists := operation["operationId"]; !exists {
+		return fmt.Errorf("operation should have operationId")
+	}
+	return nil
+}
+
+func validateOperationSummary(path string, method string, operation map[string]interface{}) error {
+	if _, exists := operation["summary"]; !exists {
+		return fmt.Errorf("operation should have summary")
+	}
+	return nil
+}
+
+func validateGetResponse200(path string, method string, operation map[string]interface{}) error {
+	if strings.ToLower(method) != "get" {
+		return nil // Skip non-GET methods
+	}
+	
+	responses, ok := operation["responses"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("GET operation should have responses defined")
+	}
+	
+	if _, exists := responses["200"]; !exists {
+		return fmt.Errorf("GET operation should have 200 response")
+	}
+	return nil
+}
+
+func validateResourceIdParam(path string, method string, operation map[string]interface{}) error {
+	// Check for parameter patterns that don't follow {id} convention
+	paramRegex := regexp.MustCompile(`\{([^}]+)\}`)
+	matches := paramRegex.FindAllStringSubmatch(path, -1)
+	
+	for _, match := range matches {
+		paramName := match[1]
+		// Allow common patterns but suggest {id} for simple resource identifiers
+		if !isValidParamName(paramName) {
+			return fmt.Errorf("parameter '{%s}' should follow naming convention (consider using {id} for resource identifiers)", paramName)
+		}
+	}
+	return nil
+}
+
+// Helper functions
+
+func makePlural(word string) string {
+	// Simple pluralization rules
+	switch {
+	case strings.HasSuffix(word, "y"):
+		return strings.TrimSuffix(word, "y") + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+func isValidParamName(name string) bool {
+	// Allow common parameter naming patterns
+	validPatterns := []string{
+		"id", "userId", "accountId", "missionId", "partnerId", 
+		"carId", "activityId", "rewardId", "memberId",
+	}
+	
+	for _, pattern := range validPatterns {
+		if name == pattern {
+			return true
+		}
+	}
+	
+	// Allow patterns like "xxxId"
+	if strings.HasSuffix(name, "Id") && len(name) > 2 {
+		return true
+	}
+	
+	return false
+}
+
+var (
+	reCamelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	reNonKebab      = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// kebabSuggestion converts a segment so that it satisfies the path-case-kebab rule.
+func kebabSuggestion(s string) string {
+	s = reCamelBoundary.ReplaceAllString(s, "$1-$2")
+	s = reNonKebab.ReplaceAllString(strings.ToLower(s), "-")
+	return strings.Trim(s, "-")
+}
@@ -0,0 +1,157 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/bilbo290/oas-indexer/internal/yamlnode"
+)
+
+// StructuralError is a violation of the OpenAPI specification itself, at a
+// JSON pointer into the checked document.
+type StructuralError struct {
+	Pointer string
+	Message string
+}
+
+func (e StructuralError) Error() string { return e.Pointer + ": " + e.Message }
+
+// Structural loads the OpenAPI document at path, resolving its file $refs,
+// and checks it against the OpenAPI 3.0 specification with kin-openapi. Each
+// operation, component and top-level section is checked separately, so every
+// broken one is reported. The error is only set when path cannot be read.
+func Structural(path string) ([]StructuralError, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return []StructuralError{{"", err.Error()}}, nil
+	}
+	return structuralErrors(doc), nil
+}
+
+// StructuralData is Structural for a document not written yet: data is read
+// as the document at path, and its file $refs are resolved against path's
+// directory.
+func StructuralData(path string, data []byte) []StructuralError {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return []StructuralError{{"", err.Error()}}
+	}
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	doc, err := loader.LoadFromDataWithPath(data, &url.URL{Path: filepath.ToSlash(abs)})
+	if err != nil {
+		return []StructuralError{{"", err.Error()}}
+	}
+	return structuralErrors(doc)
+}
+
+// structuralErrors checks the loaded document doc.
+func structuralErrors(doc *openapi3.T) []StructuralError {
+
+	ctx := context.Background()
+	var errs []StructuralError
+	check := func(ptr string, err error) {
+		if err != nil {
+			errs = append(errs, StructuralError{ptr, err.Error()})
+		}
+	}
+
+	if doc.OpenAPI == "" {
+		check("/openapi", fmt.Errorf("must be a non-empty string"))
+	}
+	if doc.Info == nil {
+		check("/info", fmt.Errorf("must be an object"))
+	} else {
+		check("/info", doc.Info.Validate(ctx))
+	}
+	for i, s := range doc.Servers {
+		check("/servers/"+strconv.Itoa(i), s.Validate(ctx))
+	}
+	for i, t := range doc.Tags {
+		check("/tags/"+strconv.Itoa(i), t.Validate(ctx))
+	}
+	for i, s := range doc.Security {
+		check("/security/"+strconv.Itoa(i), s.Validate(ctx))
+	}
+	if doc.ExternalDocs != nil {
+		check("/externalDocs", doc.ExternalDocs.Validate(ctx))
+	}
+
+	if c := doc.Components; c != nil {
+		type validator interface {
+			Validate(context.Context, ...openapi3.ValidationOption) error
+		}
+		section := func(name string, keys []string, get func(string) validator) {
+			for _, k := range keys {
+				ptr := "/components/" + name + "/" + yamlnode.EscapeToken(k)
+				if err := openapi3.ValidateIdentifier(k); err != nil {
+					check(ptr, err)
+					continue
+				}
+				check(ptr, get(k).Validate(ctx))
+			}
+		}
+		section("schemas", sortedKeys(c.Schemas), func(k string) validator { return c.Schemas[k] })
+		section("parameters", sortedKeys(c.Parameters), func(k string) validator { return c.Parameters[k] })
+		section("requestBodies", sortedKeys(c.RequestBodies), func(k string) validator { return c.RequestBodies[k] })
+		section("responses", sortedKeys(c.Responses), func(k string) validator { return c.Responses[k] })
+		section("headers", sortedKeys(c.Headers), func(k string) validator { return c.Headers[k] })
+		section("securitySchemes", sortedKeys(c.SecuritySchemes), func(k string) validator { return c.SecuritySchemes[k] })
+		section("examples", sortedKeys(c.Examples), func(k string) validator { return c.Examples[k] })
+		section("links", sortedKeys(c.Links), func(k string) validator { return c.Links[k] })
+		section("callbacks", sortedKeys(c.Callbacks), func(k string) validator { return c.Callbacks[k] })
+	}
+
+	if doc.Paths == nil {
+		check("/paths", fmt.Errorf("must be an object"))
+	} else {
+		// An invalid shared component fails every operation using it;
+		// it was reported under /components already.
+		shared := map[string]bool{}
+		for _, e := range errs {
+			shared[e.Message] = true
+		}
+		before := len(errs)
+		for _, key := range sortedKeys(doc.Paths.Map()) {
+			item := doc.Paths.Value(key)
+			base := "/paths/" + yamlnode.EscapeToken(key)
+			ops := item.Operations()
+			for _, method := range sortedKeys(ops) {
+				if err := ops[method].Validate(ctx); err != nil && !shared[err.Error()] {
+					check(base+"/"+strings.ToLower(method), err)
+				}
+			}
+			if item.Parameters != nil {
+				check(base+"/parameters", item.Parameters.Validate(ctx))
+			}
+		}
+		// Path-level checks (template parameters, duplicate paths) only
+		// report their first problem, so run them once the rest is clean.
+		if len(errs) == before {
+			check("/paths", doc.Paths.Validate(ctx))
+		}
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,352 @@
+// Package validate checks the paths and operations of an OpenAPI spec against
+// predefined rule presets.
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity levels of rules and results. Only errors fail validation.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+	SeverityOff     = "off" // overrides only: the rule does not run
+)
+
+// ParseSeverity normalizes a severity name; "warn" is accepted for warning.
+func ParseSeverity(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case SeverityError:
+		return SeverityError, nil
+	case SeverityWarning, "warn":
+		return SeverityWarning, nil
+	case SeverityInfo:
+		return SeverityInfo, nil
+	case SeverityOff:
+		return SeverityOff, nil
+	}
+	return "", fmt.Errorf("invalid severity %q (expected error, warning, info or off)", s)
+}
+
+// Rule represents a single validation rule
+type Rule struct {
+	Name        string
+	Description string
+	Severity    string // severity of its findings; empty means error
+	Validate    func(path string, method string, operation map[string]interface{}) error
+
+	// ValidateSchema and ValidateParameter check schema and parameter
+	// components instead of operations; a rule sets one of the three.
+	ValidateSchema    func(name string, schema map[string]interface{}) error
+	ValidateParameter func(name string, param map[string]interface{}) error
+}
+
+// Preset represents a collection of validation rules
+type Preset struct {
+	Name        string
+	Description string
+	Rules       []Rule
+}
+
+// Result is a single finding
+type Result struct {
+	Path     string
+	File     string // fragment the path item came from, if known
+	Line     int    // line of the operation in File, if known
+	Method   string
+	Rule     string
+	Message  string
+	Severity string // SeverityError, SeverityWarning or SeverityInfo
+	Ignored  bool   // listed in an x-lint-ignore extension of the path item or operation
+}
+
+// IgnoreExtension lists rule names to skip for a path item or operation.
+const IgnoreExtension = "x-lint-ignore"
+
+// lintIgnores reads an x-lint-ignore value: a rule name or a list of them.
+func lintIgnores(v interface{}) map[string]bool {
+	out := map[string]bool{}
+	switch v := v.(type) {
+	case string:
+		out[v] = true
+	case []interface{}:
+		for _, name := range v {
+			if s, ok := name.(string); ok {
+				out[s] = true
+			}
+		}
+	}
+	return out
+}
+
+// Predefined validation presets
+var Presets = map[string]Preset{
+	"google": {
+		Name:        "Google API Design Guide",
+		Description: "Validation rules based on Google's API Design Guide best practices",
+		Rules: []Rule{
+			{
+				Name:        "http-methods-rest",
+				Description: "Use standard HTTP methods (GET, POST, PUT, PATCH, DELETE)",
+				Validate:    validateHTTPMethods,
+			},
+			{
+				Name:        "collection-names-plural",
+				Description: "Collection names should be plural nouns",
+				Validate:    validatePluralCollections,
+			},
+			{
+				Name:        "path-case-kebab",
+				Description: "Path segments should use kebab-case (lowercase with dashes)",
+				Validate:    validateKebabCase,
+			},
+			{
+				Name:        "no-trailing-slash",
+				Description: "Paths should not have trailing slashes",
+				Validate:    validateNoTrailingSlash,
+			},
+			{
+				Name:        "operation-id-present",
+				Description: "All operations should have operationId",
+				Validate:    validateOperationId,
+			},
+			{
+				Name:        "operation-summary-present",
+				Description: "All operations should have summary",
+				Validate:    validateOperationSummary,
+			},
+			{
+				Name:        "response-200-present",
+				Description: "GET operations should have 200 response",
+				Validate:    validateGetResponse200,
+			},
+			{
+				Name:        "resource-id-param",
+				Description: "Resource paths should use {id} parameter naming",
+				Validate:    validateResourceIdParam,
+			},
+			{
+				Name:           "schema-properties-camel-case",
+				Description:    "Schema property names should use camelCase",
+				ValidateSchema: validateSchemaPropertyCase,
+			},
+			{
+				Name:           "schema-description-present",
+				Description:    "Schemas should have a description",
+				Severity:       SeverityWarning,
+				ValidateSchema: validateSchemaDescription,
+			},
+			{
+				Name:           "schema-type-present",
+				Description:    "Schemas and their properties should declare a type",
+				ValidateSchema: validateSchemaType,
+			},
+			{
+				Name:           "schema-no-bare-object",
+				Description:    "Object schemas should declare properties or additionalProperties",
+				ValidateSchema: validateNoBareObject,
+			},
+			{
+				Name:              "parameter-description-present",
+				Description:       "Parameters should have a description",
+				Severity:          SeverityWarning,
+				ValidateParameter: validateParameterDescription,
+			},
+			{
+				Name:              "parameter-schema-typed",
+				Description:       "Parameters should have a schema with a type",
+				ValidateParameter: validateParameterSchema,
+			},
+		},
+	},
+	"restful": {
+		Name:        "RESTful API Standards",
+		Description: "Common RESTful API design standards",
+		Rules: []Rule{
+			{
+				Name:        "http-methods-rest",
+				Description: "Use standard HTTP methods (GET, POST, PUT, PATCH, DELETE)",
+				Validate:    validateHTTPMethods,
+			},
+			{
+				Name:        "operation-id-present",
+				Description: "All operations should have operationId",
+				Validate:    validateOperationId,
+			},
+			{
+				Name:        "collection-names-plural",
+				Description: "Collection names should be plural nouns",
+				Validate:    validatePluralCollections,
+			},
+			{
+				Name:        "no-trailing-slash",
+				Description: "Paths should not have trailing slashes",
+				Validate:    validateNoTrailingSlash,
+			},
+			{
+				Name:           "schema-type-present",
+				Description:    "Schemas and their properties should declare a type",
+				ValidateSchema: validateSchemaType,
+			},
+			{
+				Name:              "parameter-schema-typed",
+				Description:       "Parameters should have a schema with a type",
+				ValidateParameter: validateParameterSchema,
+			},
+		},
+	},
+}
+
+// WithOverrides returns p with the severities of the named rules replaced;
+// SeverityOff removes a rule. Naming a rule p does not have is an error.
+func (p Preset) WithOverrides(overrides map[string]string) (Preset, error) {
+	known := map[string]bool{}
+	for _, r := range p.Rules {
+		known[r.Name] = true
+	}
+	for name := range overrides {
+		if !known[name] {
+			return Preset{}, fmt.Errorf("severity override for unknown rule %q in preset %s", name, p.Name)
+		}
+	}
+	out := p
+	out.Rules = nil
+	for _, r := range p.Rules {
+		if sev, ok := overrides[r.Name]; ok {
+			if sev == SeverityOff {
+				continue
+			}
+			r.Severity = sev
+		}
+		out.Rules = append(out.Rules, r)
+	}
+	return out, nil
+}
+
+// Path is one path item of the spec under validation.
+type Path struct {
+	Key  string                 // path key, e.g. /v1/users/{id}
+	File  string                 // fragment the path item came from, if any
+	Item  map[string]interface{} // decoded path item
+	Lines map[string]int         // line of each method key in File, if known
+}
+
+// Spec holds the path items and components validated by Run.
+type Spec struct {
+	Paths      []Path
+	Schemas    []Component
+	Parameters []Component
+}
+
+// ParseSpec reads the paths, schemas and parameters of a joined or bundled
+// OpenAPI document (YAML or JSON).
+func ParseSpec(data []byte) (*Spec, error) {
+	var doc struct {
+		Paths      map[string]map[string]interface{} `yaml:"paths"`
+		Components struct {
+			Schemas    map[string]map[string]interface{} `yaml:"schemas"`
+			Parameters map[string]map[string]interface{} `yaml:"parameters"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	spec := &Spec{}
+	for key, item := range doc.Paths {
+		spec.Paths = append(spec.Paths, Path{Key: key, Item: item})
+	}
+	sort.Slice(spec.Paths, func(i, j int) bool { return spec.Paths[i].Key < spec.Paths[j].Key })
+	for name, def := range doc.Components.Schemas {
+		spec.Schemas = append(spec.Schemas, Component{Name: name, Def: def})
+	}
+	for name, def := range doc.Components.Parameters {
+		spec.Parameters = append(spec.Parameters, Component{Name: name, Def: def})
+	}
+	sortComponents(spec.Schemas)
+	sortComponents(spec.Parameters)
+	return spec, nil
+}
+
+// Run applies every rule of the named preset to each operation or component
+// in spec and returns all findings, in path order followed by components. The error is only set for an unknown preset.
+func Run(spec *Spec, preset string) ([]Result, error) {
+	p, exists := Presets[preset]
+	if !exists {
+		return nil, fmt.Errorf("unknown validation preset: %s", preset)
+	}
+	return RunPreset(spec, p), nil
+}
+
+// RunPreset is Run for a preset value, e.g. one returned by WithOverrides.
+// Findings of rules named in x-lint-ignore are returned with Ignored set.
+func RunPreset(spec *Spec, p Preset) []Result {
+	var results []Result
+	report := func(rule Rule, err error, r Result, ignored bool) {
+		severity := rule.Severity
+		if severity == "" {
+			severity = SeverityError
+		}
+		r.Rule, r.Message, r.Severity, r.Ignored = rule.Name, err.Error(), severity, ignored
+		results = append(results, r)
+	}
+	for _, path := range spec.Paths {
+		pathIgnores := lintIgnores(path.Item[IgnoreExtension])
+		methods := make([]string, 0, len(path.Item))
+		for method := range path.Item {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		// Validate each HTTP method in the path
+		for _, method := range methods {
+			operation, ok := path.Item[method].(map[string]interface{})
+			if !ok {
+				continue // Skip non-operation fields
+			}
+
+			ignores := lintIgnores(operation[IgnoreExtension])
+
+			// Run all validation rules
+			for _, rule := range p.Rules {
+				if rule.Validate == nil {
+					continue
+				}
+				if err := rule.Validate(path.Key, method, operation); err != nil {
+					report(rule, err, Result{
+						Path:   path.Key,
+						File:   path.File,
+						Line:   path.Lines[method],
+						Method: strings.ToUpper(method),
+					}, pathIgnores[rule.Name] || ignores[rule.Name])
+				}
+			}
+		}
+	}
+
+	// Validate schema and parameter components
+	for _, t := range spec.componentTargets() {
+		for _, c := range t.defs {
+			ignores := lintIgnores(c.Def[IgnoreExtension])
+			for _, rule := range p.Rules {
+				check := t.check(rule)
+				if check == nil {
+					continue
+				}
+				if err := check(c.Name, c.Def); err != nil {
+					report(rule, err, Result{
+						Path:   "#/components/" + t.section + "/" + c.Name,
+						File:   c.File,
+						Line:   c.Line,
+						Method: t.method,
+					}, ignores[rule.Name])
+				}
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,108 @@
+// Package vars expands ${NAME} placeholders in the string values of a built
+// spec, so server URLs, contact emails and example hosts can differ between
+// staging and production docs built from the same fragments.
+//
+// ${NAME:-default} falls back to default when NAME is not set, and $${ is a
+// literal ${. Values come from a vars file (a YAML mapping of names to
+// scalars) and then from the environment.
+package vars
+
+import (
+    "fmt"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// Vars holds the values placeholders expand to.
+type Vars struct {
+    values map[string]string
+    env    bool
+}
+
+// New returns Vars that look names up in the environment only.
+func New() *Vars { return &Vars{values: map[string]string{}, env: true} }
+
+// Load returns Vars holding the mapping in the file at path, falling back
+// to the environment.
+func Load(path string) (*Vars, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, fmt.Errorf("read vars: %w", err) }
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil { return nil, fmt.Errorf("%s: %v", path, err) }
+    v := New()
+    if len(doc.Content) == 0 { return v, nil }
+    m := doc.Content[0]
+    if m.Kind != yaml.MappingNode { return nil, fmt.Errorf("%s: vars must be a mapping of names to values", path) }
+    var problems []string
+    for i := 0; i+1 < len(m.Content); i += 2 {
+        k, val := m.Content[i], m.Content[i+1]
+        switch {
+        case !reName.MatchString(k.Value):
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: invalid variable name %q", path, k.Line, k.Column, k.Value))
+        case val.Kind != yaml.ScalarNode:
+            problems = append(problems, fmt.Sprintf("%s:%d:%d: %s must be a scalar", path, val.Line, val.Column, k.Value))
+        default:
+            v.values[k.Value] = val.Value
+        }
+    }
+    if len(problems) > 0 { return nil, fmt.Errorf("invalid vars:\n  %s", strings.Join(problems, "\n  ")) }
+    return v, nil
+}
+
+var (
+    reName        = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+    rePlaceholder = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+)
+
+func (v *Vars) lookup(name string) (string, bool) {
+    if s, ok := v.values[name]; ok { return s, true }
+    if v.env { return os.LookupEnv(name) }
+    return "", false
+}
+
+// Expand replaces the placeholders in the scalar values below root (mapping
+// keys are left alone). It fails naming every variable that is not set and
+// has no default.
+func (v *Vars) Expand(root *yaml.Node) error {
+    missing := map[string]bool{}
+    var walk func(n *yaml.Node)
+    walk = func(n *yaml.Node) {
+        switch n.Kind {
+        case yaml.ScalarNode:
+            if strings.Contains(n.Value, "${") { n.Value = v.expandString(n.Value, missing) }
+        case yaml.MappingNode:
+            for i := 1; i < len(n.Content); i += 2 {
+                walk(n.Content[i])
+            }
+        default:
+            for _, c := range n.Content {
+                walk(c)
+            }
+        }
+    }
+    walk(root)
+    if len(missing) > 0 {
+        var names []string
+        for name := range missing {
+            names = append(names, name)
+        }
+        sort.Strings(names)
+        return fmt.Errorf("undefined variable(s) %s: set them in --vars or the environment, or give a default as ${NAME:-default}", strings.Join(names, ", "))
+    }
+    return nil
+}
+
+func (v *Vars) expandString(s string, missing map[string]bool) string {
+    return rePlaceholder.ReplaceAllStringFunc(s, func(p string) string {
+        if strings.HasPrefix(p, "$$") { return p[1:] }
+        m := rePlaceholder.FindStringSubmatch(p)
+        if val, ok := v.lookup(m[1]); ok { return val }
+        if strings.Contains(p, ":-") { return m[2] }
+        missing[m[1]] = true
+        return p
+    })
+}
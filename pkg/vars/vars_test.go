@@ -0,0 +1,57 @@
+package vars
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "gopkg.in/yaml.v3"
+)
+
+func TestExpand(t *testing.T) {
+    t.Setenv("OAS_VARS_TEST_HOST", "env.example.com")
+    t.Setenv("OAS_VARS_TEST_REGION", "from-env")
+    path := filepath.Join(t.TempDir(), "vars.yaml")
+    if err := os.WriteFile(path, []byte("OAS_VARS_TEST_REGION: eu\nport: 8080\n"), 0o644); err != nil { t.Fatal(err) }
+    v, err := Load(path)
+    if err != nil { t.Fatal(err) }
+
+    tests := []struct{ in, want string }{
+        {"https://${OAS_VARS_TEST_HOST}:${port}", "https://env.example.com:8080"},
+        {"${OAS_VARS_TEST_REGION}", "eu"}, // the file wins over the environment
+        {"${OAS_VARS_TEST_UNSET:-fallback}", "fallback"},
+        {"${OAS_VARS_TEST_UNSET:-}x", "x"},
+        {"$${OAS_VARS_TEST_HOST}", "${OAS_VARS_TEST_HOST}"},
+        {"no placeholder $HOME {x}", "no placeholder $HOME {x}"},
+    }
+    for _, tt := range tests {
+        n := &yaml.Node{Kind: yaml.ScalarNode, Value: tt.in}
+        if err := v.Expand(n); err != nil { t.Errorf("%s: %v", tt.in, err); continue }
+        if n.Value != tt.want { t.Errorf("Expand(%q) = %q, want %q", tt.in, n.Value, tt.want) }
+    }
+}
+
+func TestExpandLeavesKeysAndReportsMissing(t *testing.T) {
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte("${KEY}: ['${OAS_VARS_TEST_B}', '${OAS_VARS_TEST_A}', '${OAS_VARS_TEST_A}']\n"), &doc); err != nil { t.Fatal(err) }
+    err := New().Expand(&doc)
+    if err == nil || !strings.Contains(err.Error(), "undefined variable(s) OAS_VARS_TEST_A, OAS_VARS_TEST_B:") { t.Fatalf("error = %v", err) }
+    if k := doc.Content[0].Content[0].Value; k != "${KEY}" { t.Errorf("key expanded to %q", k) }
+}
+
+func TestLoadErrors(t *testing.T) {
+    tests := []struct{ name, text, want string }{
+        {"not a mapping", "- a\n", "must be a mapping"},
+        {"bad name", "1abc: x\n", `1:1: invalid variable name "1abc"`},
+        {"not a scalar", "a: [1]\n", "1:4: a must be a scalar"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "vars.yaml")
+            if err := os.WriteFile(path, []byte(tt.text), 0o644); err != nil { t.Fatal(err) }
+            _, err := Load(path)
+            if err == nil || !strings.Contains(err.Error(), tt.want) { t.Errorf("Load error = %v, want one mentioning %q", err, tt.want) }
+        })
+    }
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+    "github.com/bilbo290/oas-indexer/pkg/postman"
+)
+
+// Postman: export the joined spec as a collection, and import a collection
+// into draft path and schema fragments.
+
+func runExportPostman(args []string) error {
+    out, args := takeOutFlag(args)
+    fs, opts := commandFlags("export postman", "export postman --input <dir> -o <collection.json> [--environment <file>] [--server <n>] [--name <name>] [options]")
+    envOut := fs.String("environment", "", "Environment file with the server URL and credentials (default: <collection>.postman_environment.json next to the collection)")
+    server, name := exportFlags(fs)
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    target := absJoin(cfg.Cwd, firstNonEmpty(out, filepath.Join("dist", cfg.API, "postman_collection.json")))
+    envTarget := absJoin(cfg.Cwd, firstNonEmpty(strings.TrimSpace(*envOut), environmentPath(target)))
+    collection, env, err := exportCollection(cfg, *server, *name)
+    if err != nil { return err }
+    if err := writeJSONFile(target, collection, "collection"); err != nil { return err }
+    return writeJSONFile(envTarget, env, "environment")
+}
+
+// environmentPath derives the environment file from the collection's:
+// api.postman_collection.json (or api.json) gives
+// api.postman_environment.json.
+func environmentPath(collection string) string {
+    base := strings.TrimSuffix(collection, filepath.Ext(collection))
+    base = strings.TrimSuffix(strings.TrimSuffix(base, ".postman_collection"), "_collection")
+    if strings.HasSuffix(base, "postman") { return base + "_environment.json" }
+    return base + ".postman_environment.json"
+}
+
+func runImportPostman(args []string) error {
+    fs := flag.NewFlagSet("import postman", flag.ContinueOnError)
+    input := fs.String("input", "", "[required] Fragments directory to scaffold into")
+    inputS := fs.String("i", "", "Shorthand for --input")
+    force := fs.Bool("force", false, "Overwrite existing path and schema fragments")
+    casing := fs.String("path-casing", indexer.PathCasingCamel, "Path-key casing the fragments will be indexed with (camel, kebab, preserve)")
+    fs.Usage = func() {
+        fmt.Fprintf(os.Stderr, "Usage:\n  oas-indexer import postman <collection.json> --input <dir> [--force] [--path-casing <c>]\n\n")
+        fs.PrintDefaults()
+    }
+    positional, err := parseInterspersed(fs, args)
+    if err != nil { return err }
+    if len(positional) != 1 {
+        fs.Usage()
+        return errors.New("import postman: exactly one collection file is required")
+    }
+    inputDir := firstNonEmpty(*input, *inputS)
+    if strings.TrimSpace(inputDir) == "" {
+        fs.Usage()
+        return errors.New("missing required flag: --input is required")
+    }
+    if !indexer.ValidPathCasing(*casing) {
+        return fmt.Errorf("invalid --path-casing %q (expected camel, kebab or preserve)", *casing)
+    }
+    cwd, _ := os.Getwd()
+    root := absJoin(cwd, inputDir)
+
+    raw, err := os.ReadFile(positional[0])
+    if err != nil { return err }
+    collection, err := postman.Decode(raw)
+    if err != nil { return fmt.Errorf("failed to parse Postman collection %s: %w", positional[0], err) }
+
+    ops := collectHAROperations(postmanEntries(collection.Calls()), "")
+    if len(ops) == 0 {
+        return errors.New("import postman: no requests found in collection")
+    }
+    if err := extractSchemas(filepath.Join(root, "components", "schemas"), ops, *force); err != nil { return err }
+    return writeHARFragments(filepath.Join(root, "paths"), ops, *force, *casing)
+}
+
+// postmanEntries turns each saved response of a call (or the call alone,
+// when it has none) into a HAR entry.
+func postmanEntries(calls []postman.Call) []harEntry {
+    var out []harEntry
+    for _, c := range calls {
+        var e harEntry
+        e.Request.Method = c.Method
+        e.Request.URL = "http://postman" + c.Path
+        e.summary, e.description, e.tag, e.pathValues = c.Name, c.Description, c.Folder, c.PathValues
+        for _, q := range c.Query {
+            e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: q.Key, Value: q.Value, optional: q.Disabled})
+        }
+        if b := c.Body; b != nil {
+            e.Request.PostData = &struct {
+                MimeType string         `json:"mimeType"`
+                Text     string         `json:"text"`
+                Params   []harNameValue `json:"params"` // form fields
+            }{MimeType: c.BodyMime, Text: b.Raw}
+            for _, f := range append(b.URLEncoded, b.FormData...) {
+                if f.Disabled { continue }
+                p := harNameValue{Name: f.Key, Value: f.Value}
+                if f.Type == "file" { p.FileName = f.Key }
+                e.Request.PostData.Params = append(e.Request.PostData.Params, p)
+            }
+        }
+        if len(c.Responses) == 0 {
+            out = append(out, e)
+            continue
+        }
+        for _, r := range c.Responses {
+            e.Response.Status = r.Code
+            e.Response.Content.MimeType = r.MimeType
+            e.Response.Content.Text = r.Body
+            out = append(out, e)
+        }
+    }
+    return out
+}
+
+// extractSchemas moves the object schemas of request and response bodies
+// (or of the items of array bodies) into schema fragments under dir named
+// after the operation, such as CreateUserRequest and GetUserResponse, or
+// Error for error responses. Identical schemas share one fragment. The
+// bodies get absolute refs, which writeHARFragments makes relative.
+func extractSchemas(dir string, byPath map[string]map[string]*harOperation, force bool) error {
+    byContent := map[string]string{} // marshaled schema -> fragment file
+    used := map[string]bool{}
+    written, skipped := 0, 0
+    extract := func(schema *yaml.Node, name string) (*yaml.Node, error) {
+        target := schema
+        if yamlnode.GetKey(schema, "type") != nil && yamlnode.GetKey(schema, "type").Value == "array" {
+            target, name = yamlnode.GetKey(schema, "items"), name+" item"
+        }
+        if target == nil || yamlnode.GetKey(target, "properties") == nil { return schema, nil }
+        data, err := yamlnode.Marshal(target)
+        if err != nil { return nil, err }
+        file, ok := byContent[string(data)]
+        if !ok {
+            base := indexer.ComponentName(name)
+            name = base
+            for i := 2; used[name]; i++ { name = base + strconv.Itoa(i) }
+            used[name] = true
+            file = filepath.Join(dir, name+".yaml")
+            byContent[string(data)] = file
+            if _, err := os.Stat(file); err == nil && !force {
+                logger.Info("Already exists, skipped (--force to overwrite)", "path", file)
+                skipped++
+            } else {
+                fragment := *target
+                fragment.Content = append([]*yaml.Node(nil), target.Content...)
+                yamlnode.SetKey(&fragment, "x-draft", yamlnode.Bool(true))
+                if err := ensureDir(dir); err != nil { return nil, err }
+                if err := yamlnode.Write(file, &fragment); err != nil { return nil, err }
+                fmt.Fprintf(os.Stdout, "draft: %s\n", file)
+                written++
+            }
+        }
+        ref := yamlnode.Map()
+        yamlnode.SetKey(ref, "$ref", yamlnode.Str(file))
+        if target == schema { return ref, nil }
+        yamlnode.SetKey(schema, "items", ref)
+        return schema, nil
+    }
+
+    templates := make([]string, 0, len(byPath))
+    for t := range byPath { templates = append(templates, t) }
+    sort.Strings(templates)
+    for _, t := range templates {
+        methods := make([]string, 0, len(byPath[t]))
+        for m := range byPath[t] { methods = append(methods, m) }
+        sort.Strings(methods)
+        for _, m := range methods {
+            op := byPath[t][m]
+            name := firstNonEmpty(op.Summary, harOperationID(op))
+            var err error
+            if op.Body != nil && strings.Contains(op.BodyMime, "json") {
+                if op.Body, err = extract(op.Body, name+" request"); err != nil { return err }
+            }
+            statuses := make([]int, 0, len(op.Responses))
+            for s := range op.Responses { statuses = append(statuses, s) }
+            sort.Ints(statuses)
+            first := true
+            for _, s := range statuses {
+                if op.Responses[s] == nil { continue }
+                schemaName := name + " response"
+                switch {
+                case s >= 400:
+                    schemaName = "error"
+                case !first:
+                    schemaName = name + " " + strconv.Itoa(s) + " response"
+                default:
+                    first = false
+                }
+                if op.Responses[s], err = extract(op.Responses[s], schemaName); err != nil { return err }
+            }
+        }
+    }
+    if written+skipped > 0 { fmt.Fprintf(os.Stdout, "Extracted %d schema fragment(s), skipped %d\n", written, skipped) }
+    return nil
+}
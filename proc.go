@@ -0,0 +1,97 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
+)
+
+// External commands run under commandsContext: each one is killed, with its
+// child processes, after --tool-timeout, and all of them on SIGINT or
+// SIGTERM, after which the temp outputs of runToTemp are removed and the
+// process exits.
+
+var commandsContext, cancelCommands = context.WithCancel(context.Background())
+
+// toolTimeout is set by --tool-timeout; 0 means no limit.
+var toolTimeout = defaultToolTimeout
+
+const defaultToolTimeout = 10 * time.Minute
+
+var (
+    running sync.WaitGroup // external commands in flight
+
+    tempsMu sync.Mutex
+    temps   = map[string]bool{} // runToTemp outputs not moved into place yet
+)
+
+// handleInterrupts stops the external commands on the first SIGINT or
+// SIGTERM and exits once they are gone; a second signal exits at once.
+func handleInterrupts() {
+    sig := make(chan os.Signal, 1)
+    signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+    go func() {
+        <-sig
+        signal.Stop(sig)
+        logger.Warn("interrupted, stopping external tools")
+        cancelCommands()
+        running.Wait()
+        tempsMu.Lock()
+        for tmp := range temps { os.Remove(tmp) }
+        tempsMu.Unlock()
+        os.Exit(130)
+    }()
+}
+
+// runExternal runs name with args under commandsContext and --tool-timeout,
+// with a sanitized environment; setup sets the command's directory and
+// output. A timeout or an interrupt is named as the cause of a failure.
+func runExternal(name string, args []string, setup func(cmd *exec.Cmd)) error {
+    var ctx context.Context
+    var cancel context.CancelFunc
+    if toolTimeout > 0 {
+        ctx, cancel = context.WithTimeout(commandsContext, toolTimeout)
+    } else {
+        ctx, cancel = context.WithCancel(commandsContext)
+    }
+    defer cancel()
+    cmd := exec.CommandContext(ctx, name, args...)
+    cmd.Env = sanitizedEnv(os.Environ())
+    cmd.WaitDelay = 5 * time.Second // do not hang on grandchildren holding the output pipes
+    killProcessGroup(cmd)
+    setup(cmd)
+    running.Add(1)
+    defer running.Done()
+    if commandsContext.Err() != nil { return errors.New("interrupted") }
+    logger.Debug("Running", "command", commandLine(name, args))
+    start := time.Now()
+    err := cmd.Run()
+    logger.Debug("Finished", "command", commandLine(name, args), "duration", time.Since(start).Round(time.Millisecond), "ok", err == nil)
+    switch {
+    case err == nil:
+        return nil
+    case commandsContext.Err() != nil:
+        return fmt.Errorf("interrupted: %w", err)
+    case errors.Is(ctx.Err(), context.DeadlineExceeded):
+        return fmt.Errorf("timed out after %s (--tool-timeout): %w", toolTimeout, err)
+    }
+    return err
+}
+
+// trackTemp registers tmp for removal on interrupt until untrack is called.
+func trackTemp(tmp string) (untrack func()) {
+    tempsMu.Lock()
+    temps[tmp] = true
+    tempsMu.Unlock()
+    return func() {
+        tempsMu.Lock()
+        delete(temps, tmp)
+        tempsMu.Unlock()
+    }
+}
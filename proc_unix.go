@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+    "os/exec"
+    "syscall"
+)
+
+// killProcessGroup starts cmd in its own process group and makes
+// cancelling it kill the whole group, so tools started through wrappers
+// (npx, shell scripts, java launchers) do not outlive it.
+func killProcessGroup(cmd *exec.Cmd) {
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+    cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package main
+
+import "os/exec"
+
+// killProcessGroup leaves cmd to exec.CommandContext, which kills the
+// process itself.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,147 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "html"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/docs"
+)
+
+// reloadScript polls /__version and reloads the page once a rebuild lands.
+const reloadScript = `<script>
+(function () {
+  var v = %q;
+  setInterval(function () {
+    fetch("/__version").then(function (r) { return r.text(); }).then(function (t) {
+      if (t !== v) location.reload();
+    }).catch(function () {});
+  }, 1000);
+})();
+</script>
+`
+
+// served is the latest build: the bundled spec and its docs page, or the
+// error that stopped the build.
+type served struct {
+    mu      sync.RWMutex
+    version int
+    yaml    []byte
+    json    []byte
+    html    []byte
+    err     error
+}
+
+func runServeCommand(args []string) error {
+    fs, opts := commandFlags("serve", "serve --input <dir> [--addr <host:port>] [--interval <duration>] [options]")
+    addr := fs.String("addr", "localhost:8080", "Address to listen on")
+    interval := fs.Duration("interval", time.Second, "How often to poll the input tree for changes")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    if *interval <= 0 { return fmt.Errorf("serve: --interval must be positive (got %s)", *interval) }
+    onlyRoot(cfg)
+    cfg.SkipValidation = cfg.ValidatePreset == ""
+
+    s := &served{}
+    snap, err := treeSnapshot(cfg)
+    if err != nil { return err }
+    s.rebuild(cfg)
+    go func() {
+        last := snap
+        for {
+            time.Sleep(*interval)
+            snap, err := treeSnapshot(cfg)
+            if err != nil || snap == last { continue }
+            last = snap
+            s.rebuild(cfg)
+        }
+    }()
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/", s.serveDocs)
+    mux.HandleFunc("/openapi.yaml", s.serveSpec("application/yaml", func() []byte { return s.yaml }))
+    mux.HandleFunc("/openapi.json", s.serveSpec("application/json", func() []byte { return s.json }))
+    mux.HandleFunc("/__version", func(w http.ResponseWriter, r *http.Request) {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        w.Header().Set("Cache-Control", "no-store")
+        fmt.Fprint(w, s.version)
+    })
+    fmt.Fprintf(os.Stdout, "Serving docs at http://%s/ and the spec at /openapi.yaml (Ctrl-C to stop)\n", *addr)
+    return http.ListenAndServe(*addr, mux)
+}
+
+// rebuild writes the root and re-renders the bundle and docs in memory.
+func (s *served) rebuild(cfg *Config) {
+    var y, j, h []byte
+    cfg.Logger = discoveryLogger()
+    err := checkInput(cfg)
+    if err == nil { err = writeRoot(cfg) }
+    if err == nil {
+        spec, berr := bundle.Load(cfg.RootPath)
+        err = berr
+        if err == nil { err = postprocessBundle(cfg, spec) }
+        if err == nil { y, err = yamlnode.Marshal(spec) }
+        if err == nil { j, err = yamlnode.MarshalJSON(spec) }
+        if err == nil { h, err = docs.Page(spec) }
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.version++
+    s.err = err
+    if err != nil {
+        logger.Error(err.Error())
+        return
+    }
+    s.yaml, s.json, s.html = y, j, h
+    logger.Info("Rebuilt docs (" + time.Now().Format("15:04:05") + ")")
+}
+
+func (s *served) serveDocs(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+        http.NotFound(w, r)
+        return
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    reload := fmt.Sprintf(reloadScript, strconv.Itoa(s.version))
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.Header().Set("Cache-Control", "no-store")
+    if s.err != nil {
+        w.WriteHeader(http.StatusInternalServerError)
+        fmt.Fprintf(w, "<!DOCTYPE html><title>Build failed</title><h1>Build failed</h1><pre>%s</pre>%s", html.EscapeString(s.err.Error()), reload)
+        return
+    }
+    page := string(s.html)
+    if i := strings.LastIndex(page, "</body>"); i >= 0 {
+        page = page[:i] + reload + page[i:]
+    } else {
+        page += reload
+    }
+    fmt.Fprint(w, page)
+}
+
+func (s *served) serveSpec(contentType string, body func() []byte) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        b := body()
+        if b == nil {
+            err := s.err
+            if err == nil { err = errors.New("spec not built yet") }
+            http.Error(w, err.Error(), http.StatusServiceUnavailable)
+            return
+        }
+        w.Header().Set("Content-Type", contentType)
+        w.Header().Set("Cache-Control", "no-store")
+        w.Write(b)
+    }
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+    "archive/zip"
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+    "github.com/bilbo290/oas-indexer/pkg/specdiff"
+)
+
+// Values for diff --fail-on.
+var failOnLevels = map[string][]specdiff.Severity{
+    "breaking": {specdiff.Breaking},
+    "any":      {specdiff.Breaking, specdiff.NonBreaking, specdiff.DocsOnly},
+    "none":     nil,
+}
+
+// loadDiffSide returns the bundled spec named by arg: a spec file, a git ref
+// whose input tree is built with the current options, or (when empty) a
+// fresh build of the working tree.
+func loadDiffSide(cfg *Config, arg string) (*yaml.Node, error) {
+    if arg == "" {
+        root, err := indexer.RenderRoot(cfg.index())
+        if err != nil { return nil, err }
+        return bundle.LoadData(cfg.RootPath, root)
+    }
+    if st, err := os.Stat(arg); err == nil && !st.IsDir() { return bundle.Load(arg) }
+    root, cleanup, err := buildRootAtRef(cfg, arg)
+    if err != nil { return nil, err }
+    defer cleanup()
+    return bundle.Load(root)
+}
+
+// buildRootAtRef exports the input tree as of a git ref into a temp dir and
+// builds its root there.
+func buildRootAtRef(cfg *Config, ref string) (string, func(), error) {
+    top, err := gitOutput(cfg.InputDir, "rev-parse", "--show-toplevel")
+    if err != nil { return "", nil, fmt.Errorf("%q is neither a spec file nor a git ref: %v", ref, err) }
+    repo := strings.TrimSpace(string(top))
+    if _, err := gitOutput(repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+        return "", nil, fmt.Errorf("%q is neither a spec file nor a git ref", ref)
+    }
+    rel, err := filepath.Rel(repo, cfg.InputDir)
+    if err != nil || strings.HasPrefix(rel, "..") {
+        return "", nil, fmt.Errorf("input dir %s is outside the git repository %s", cfg.InputDir, repo)
+    }
+    archive, err := gitOutput(repo, "archive", "--format=zip", ref, "--", filepath.ToSlash(rel))
+    if err != nil { return "", nil, err }
+
+    tmp, err := os.MkdirTemp("", "oas-indexer-diff-")
+    if err != nil { return "", nil, err }
+    cleanup := func() { os.RemoveAll(tmp) }
+    if err := unzip(archive, tmp); err != nil {
+        cleanup()
+        return "", nil, fmt.Errorf("extract %s: %v", ref, err)
+    }
+    // Build with every option of this run, moving the directories below the
+    // input tree (and the root) into the export.
+    cur := cfg.index()
+    dir := filepath.Join(tmp, rel)
+    moved := func(p string) string {
+        if p == cur.InputDir { return dir }
+        if !indexer.IsWithin(cur.InputDir, p) { return p }
+        r, _ := filepath.Rel(cur.InputDir, p)
+        return filepath.Join(dir, r)
+    }
+    old := *cur
+    fresh := indexer.NewConfig(dir, "", cfg.RootFile)
+    old.InputDir, old.OutputDir, old.RootPath, old.AsyncAPIPath = dir, fresh.OutputDir, fresh.RootPath, fresh.AsyncAPIPath
+    old.PathsDir, old.WebhooksDir, old.EventsDir = moved(cur.PathsDir), moved(cur.WebhooksDir), moved(cur.EventsDir)
+    old.ComponentsDir, old.TraitsDir, old.SharedComponentsDir = moved(cur.ComponentsDir), moved(cur.TraitsDir), moved(cur.SharedComponentsDir)
+    old.Exclude, old.Known = nil, nil
+    for _, p := range cur.Exclude { old.Exclude = append(old.Exclude, moved(p)) }
+    for _, p := range cur.Known { old.Known = append(old.Known, moved(p)) }
+    if _, err := indexer.BuildRoot(&old); err != nil {
+        cleanup()
+        return "", nil, fmt.Errorf("build root at %s: %w", ref, err)
+    }
+    return old.RootPath, cleanup, nil
+}
+
+func gitOutput(dir string, args ...string) ([]byte, error) {
+    var out, stderr bytes.Buffer
+    err := runExternal("git", args, func(cmd *exec.Cmd) { cmd.Dir, cmd.Stdout, cmd.Stderr = dir, &out, &stderr })
+    if err != nil {
+        return nil, fmt.Errorf("command failed: %s: %w\n%s", commandLine("git", args), err, strings.TrimRight(stderr.String(), "\n"))
+    }
+    return out.Bytes(), nil
+}
+
+// unzip extracts a git archive into dir, rejecting entries that escape it.
+func unzip(data []byte, dir string) error {
+    zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil { return err }
+    for _, f := range zr.File {
+        target := filepath.Join(dir, filepath.FromSlash(f.Name))
+        if !indexer.IsWithin(dir, target) { return fmt.Errorf("entry %q escapes the archive", f.Name) }
+        if f.FileInfo().IsDir() {
+            if err := os.MkdirAll(target, 0o755); err != nil { return err }
+            continue
+        }
+        if !f.Mode().IsRegular() { continue }
+        if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil { return err }
+        rc, err := f.Open()
+        if err != nil { return err }
+        b, err := io.ReadAll(rc)
+        rc.Close()
+        if err != nil { return err }
+        if err := os.WriteFile(target, b, 0o644); err != nil { return err }
+    }
+    return nil
+}
+
+// printSpecDiff lists changes grouped by severity and returns how many there
+// were of each.
+func printSpecDiff(w io.Writer, changes []specdiff.Change) map[specdiff.Severity]int {
+    counts := map[specdiff.Severity]int{}
+    for _, sev := range []specdiff.Severity{specdiff.Breaking, specdiff.NonBreaking, specdiff.DocsOnly} {
+        first := true
+        for _, c := range changes {
+            if c.Severity != sev { continue }
+            if first {
+                fmt.Fprintf(w, "%s changes:\n", strings.ToUpper(string(sev[:1]))+string(sev[1:]))
+                first = false
+            }
+            fmt.Fprintf(w, "  %s: %s\n", c.Location, c.Message)
+            counts[sev]++
+        }
+    }
+    fmt.Fprintf(w, "%d breaking, %d non-breaking, %d docs-only change(s)\n", counts[specdiff.Breaking], counts[specdiff.NonBreaking], counts[specdiff.DocsOnly])
+    return counts
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestBuildRootAtRefKeepsOptions(t *testing.T) {
+    if _, err := exec.LookPath("git"); err != nil { t.Skip("git not installed") }
+    repo := t.TempDir()
+    input := filepath.Join(repo, "api")
+    writeTestFile(t, filepath.Join(input, "header.yaml"), exitTestHeader)
+    writeTestFile(t, filepath.Join(input, "paths", "v1", "users.yaml"), validUsers)
+    writeTestFile(t, filepath.Join(input, "paths", "v1", "drafts.yaml"), validUsers)
+    for _, args := range [][]string{
+        {"init", "--quiet"},
+        {"add", "."},
+        {"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "base"},
+    } {
+        cmd := exec.Command("git", args...)
+        cmd.Dir = repo
+        if out, err := cmd.CombinedOutput(); err != nil { t.Fatalf("git %v: %v\n%s", args, err, out) }
+    }
+
+    fs, opts := commandFlags("diff", "")
+    cfg, err := commandConfig(fs, opts, []string{"--input", input, "--exclude", "paths/v1/drafts.yaml"})
+    if err != nil { t.Fatal(err) }
+    root, cleanup, err := buildRootAtRef(cfg, "HEAD")
+    if err != nil { t.Fatal(err) }
+    defer cleanup()
+    data, err := os.ReadFile(root)
+    if err != nil { t.Fatal(err) }
+    if !strings.Contains(string(data), "/v1/users") { t.Errorf("root lacks /v1/users:\n%s", data) }
+    if strings.Contains(string(data), "drafts") { t.Errorf("root at HEAD ignores --exclude:\n%s", data) }
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+
+    "github.com/getkin/kin-openapi/openapi2"
+    "github.com/getkin/kin-openapi/openapi2conv"
+    "gopkg.in/yaml.v3"
+
+    "github.com/bilbo290/oas-indexer/internal/yamlnode"
+    "github.com/bilbo290/oas-indexer/pkg/dialect"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// Split: decompose a monolithic spec into the fragment layout.
+
+// splitFile is one fragment to be written; refs in node are made relative to path.
+type splitFile struct {
+    path string
+    node *yaml.Node
+}
+
+func runSplitCommand(args []string) error {
+    fs := flag.NewFlagSet("split", flag.ContinueOnError)
+    in := fs.String("in", "", "[required] Monolithic OpenAPI 3.0 or Swagger 2.0 file (YAML or JSON)")
+    out := fs.String("out", "", "[required] Directory to write the fragment tree into")
+    force := fs.Bool("force", false, "Overwrite existing fragments")
+    casing := fs.String("path-casing", indexer.PathCasingCamel, "Path-key casing the fragments will be indexed with (camel, kebab, preserve)")
+    fs.Usage = func() {
+        fmt.Fprintf(os.Stderr, "Usage:\n  oas-indexer split --in <openapi.yaml> --out <dir> [--force] [--path-casing <c>]\n\n")
+        fs.PrintDefaults()
+    }
+    positional, err := parseInterspersed(fs, args)
+    if err != nil { return err }
+    if len(positional) > 0 {
+        fs.Usage()
+        return fmt.Errorf("split: unexpected argument %q", positional[0])
+    }
+    if strings.TrimSpace(*in) == "" || strings.TrimSpace(*out) == "" {
+        fs.Usage()
+        return errors.New("split: --in and --out are required")
+    }
+    if !indexer.ValidPathCasing(*casing) {
+        return fmt.Errorf("invalid --path-casing %q (expected camel, kebab or preserve)", *casing)
+    }
+    cwd, _ := os.Getwd()
+    outDir := absJoin(cwd, *out)
+
+    raw, err := os.ReadFile(*in)
+    if err != nil { return err }
+    var doc yaml.Node
+    if err := yaml.Unmarshal([]byte(strings.TrimPrefix(string(raw), "\uFEFF")), &doc); err != nil {
+        return fmt.Errorf("%s: %v", *in, err)
+    }
+    root := yamlnode.DocRoot(&doc)
+    if root == nil || root.Kind != yaml.MappingNode {
+        return fmt.Errorf("%s: not an OpenAPI document (expected a mapping)", *in)
+    }
+    if v := yamlnode.GetKey(root, "swagger"); v != nil {
+        if root, err = convertSwagger(root); err != nil { return fmt.Errorf("%s: %v", *in, err) }
+    }
+    files, err := splitSpec(root, outDir, *casing)
+    if err != nil { return err }
+    return writeSplitFiles(files, *force)
+}
+
+// splitSpec maps the document onto fragment files below outDir, rewriting
+// #/components refs into relative file refs.
+func splitSpec(root *yaml.Node, outDir, casing string) ([]splitFile, error) {
+    if v := yamlnode.GetKey(root, "openapi"); v != nil && dialect.Is31(v.Value) {
+        logger.Warn(fmt.Sprintf("input is OpenAPI %s; build the fragments with --openapi-version 3.1", v.Value))
+    } else if v != nil && !strings.HasPrefix(v.Value, "3.0") {
+        logger.Warn(fmt.Sprintf("input is OpenAPI %s; the generated root declares 3.0.0", v.Value))
+    }
+
+    // Component file per "#/components/<section>/<name>" prefix.
+    targets := map[string]string{}
+    var files []splitFile
+    comps := yamlnode.GetKey(root, "components")
+    for _, c := range indexer.Components {
+        defs := yamlnode.GetKey(comps, c.Section)
+        if defs == nil || defs.Kind != yaml.MappingNode { continue }
+        for i := 0; i+1 < len(defs.Content); i += 2 {
+            name := defs.Content[i].Value
+            file := filepath.Join(outDir, "components", c.Dir, name+".yaml")
+            if indexed := indexer.ComponentName(name); indexed != name {
+                logger.Warn(fmt.Sprintf("%s will be indexed as component %s, not %s", file, indexed, name))
+            }
+            targets["#/components/"+c.Section+"/"+escapeToken(name)] = file
+            files = append(files, splitFile{file, defs.Content[i+1]})
+        }
+    }
+    if comps != nil {
+        for i := 0; i+1 < len(comps.Content); i += 2 {
+            if _, ok := indexerSection(comps.Content[i].Value); !ok {
+                logger.Warn(fmt.Sprintf("components.%s is not carried over; the fragment layout has no directory for it", comps.Content[i].Value))
+            }
+        }
+    }
+
+    pathsDir := filepath.Join(outDir, "paths")
+    if paths := yamlnode.GetKey(root, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(paths.Content); i += 2 {
+            key := paths.Content[i].Value
+            file := filepath.Join(pathsDir, filepath.FromSlash(strings.Trim(key, "/"))+".yaml")
+            if strings.Trim(key, "/") == "" {
+                logger.Warn(fmt.Sprintf("path %q cannot be expressed as a fragment file; not carried over", key))
+                continue
+            }
+            item := paths.Content[i+1]
+            if indexed := indexer.BuildPathKey(pathsDir, file, casing); indexed != key {
+                if item.Kind != yaml.MappingNode {
+                    logger.Warn(fmt.Sprintf("%s will be indexed as %s, not %s (try --path-casing preserve)", file, indexed, key))
+                } else {
+                    // Pin the key rather than rely on the file name.
+                    item.Content = append([]*yaml.Node{yamlnode.Str(indexer.PathOverrideKey), yamlnode.Str(key)}, item.Content...)
+                }
+            }
+            files = append(files, splitFile{file, item})
+        }
+    }
+
+    if hooks := yamlnode.GetKey(root, "webhooks"); hooks != nil && hooks.Kind == yaml.MappingNode {
+        for i := 0; i+1 < len(hooks.Content); i += 2 {
+            file := filepath.Join(outDir, "webhooks", hooks.Content[i].Value+".yaml")
+            files = append(files, splitFile{file, hooks.Content[i+1]})
+        }
+    }
+
+    header := map[string]string{
+        "info": indexer.InfoFile, "servers": indexer.ServersFile,
+        "tags": indexer.TagsFile, "security": indexer.SecurityFile,
+    }
+    for i := 0; i+1 < len(root.Content); i += 2 {
+        k := root.Content[i].Value
+        switch {
+        case header[k] != "":
+            files = append(files, splitFile{filepath.Join(outDir, header[k]), root.Content[i+1]})
+        case k == "openapi" || k == "paths" || k == "webhooks" || k == "components":
+        default:
+            logger.Warn(fmt.Sprintf("top-level %s is not carried over", k))
+        }
+    }
+
+    for _, f := range files {
+        rewriteSplitRefs(f, targets)
+    }
+    return files, nil
+}
+
+// convertSwagger converts a Swagger 2.0 document to OpenAPI 3.0: definitions
+// become components/schemas, body and formData parameters become request
+// bodies, and refs are rewritten to match.
+func convertSwagger(root *yaml.Node) (*yaml.Node, error) {
+    if v := yamlnode.GetKey(root, "swagger"); v.Value != "2.0" {
+        return nil, fmt.Errorf("unsupported swagger version %q (expected 2.0)", v.Value)
+    }
+    data, err := yamlnode.MarshalJSON(root)
+    if err != nil { return nil, err }
+    var doc2 openapi2.T
+    if err := json.Unmarshal(data, &doc2); err != nil { return nil, fmt.Errorf("swagger 2.0: %v", err) }
+    doc3, err := openapi2conv.ToV3(&doc2)
+    if err != nil { return nil, fmt.Errorf("convert swagger 2.0: %v", err) }
+    if data, err = json.Marshal(doc3); err != nil { return nil, err }
+    var doc yaml.Node
+    if err := yaml.Unmarshal(data, &doc); err != nil { return nil, err }
+    out := yamlnode.DocRoot(&doc)
+    yamlnode.BlockStyle(out)
+    return out, nil
+}
+
+// rewriteSplitRefs points the #/components refs in f at the component files.
+func rewriteSplitRefs(f splitFile, targets map[string]string) {
+    var walk func(n *yaml.Node)
+    walk = func(n *yaml.Node) {
+        if n.Kind == yaml.MappingNode {
+            for i := 0; i+1 < len(n.Content); i += 2 {
+                k, v := n.Content[i], n.Content[i+1]
+                if k.Value != "$ref" || v.Kind != yaml.ScalarNode || !strings.HasPrefix(v.Value, "#/") { continue }
+                prefix, rest := v.Value, ""
+                if parts := strings.SplitN(v.Value, "/", 5); len(parts) == 5 {
+                    prefix, rest = strings.Join(parts[:4], "/"), "/"+parts[4]
+                }
+                target, ok := targets[prefix]
+                if !ok {
+                    logger.Warn(fmt.Sprintf("%s: $ref %q left as is; it does not point at a split component", f.path, v.Value))
+                    continue
+                }
+                rel, err := filepath.Rel(filepath.Dir(f.path), target)
+                if err != nil { continue }
+                ref := filepath.ToSlash(rel)
+                if !strings.HasPrefix(ref, ".") { ref = "./" + ref }
+                if rest != "" { ref += "#" + rest }
+                v.Value, v.Tag, v.Style = ref, "!!str", 0
+            }
+        }
+        for _, c := range n.Content {
+            walk(c)
+        }
+    }
+    walk(f.node)
+}
+
+func writeSplitFiles(files []splitFile, force bool) error {
+    sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+    written, skipped := 0, 0
+    for _, f := range files {
+        if _, err := os.Stat(f.path); err == nil && !force {
+            logger.Info("Already exists, skipped (--force to overwrite)", "path", f.path)
+            skipped++
+            continue
+        }
+        yamlnode.BlockStyle(f.node)
+        if err := ensureDir(filepath.Dir(f.path)); err != nil { return err }
+        if err := yamlnode.Write(f.path, f.node); err != nil { return err }
+        written++
+    }
+    fmt.Fprintf(os.Stdout, "Wrote %d fragment(s), skipped %d\n", written, skipped)
+    return nil
+}
+
+func indexerSection(section string) (indexer.Component, bool) {
+    for _, c := range indexer.Components {
+        if c.Section == section { return c, true }
+    }
+    return indexer.Component{}, false
+}
+
+// escapeToken escapes a JSON pointer token.
+func escapeToken(s string) string {
+    return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
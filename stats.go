@@ -0,0 +1,231 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+
+    "github.com/bilbo290/oas-indexer/pkg/bundle"
+    "github.com/bilbo290/oas-indexer/pkg/indexer"
+)
+
+// Spec metrics for the stats command.
+
+// specStats summarizes a bundled spec. JSON field names are part of the
+// --format json output.
+type specStats struct {
+    Paths              int            `json:"paths"`
+    Operations         int            `json:"operations"`
+    OperationsByMethod map[string]int `json:"operationsByMethod"`
+    Schemas            int            `json:"schemas"`
+    Parameters         int            `json:"parameters"`
+    MissingDescription []string       `json:"operationsMissingDescription"`
+    MissingExamples    []string       `json:"operationsMissingExamples"`
+    AverageSchemaDepth float64        `json:"averageSchemaDepth"`
+    MaxSchemaDepth     int            `json:"maxSchemaDepth"`
+    TaggedOperations   int            `json:"taggedOperations"`
+    TagCoverage        float64        `json:"tagCoverage"`                  // share of operations with at least one tag
+    UndeclaredTags     []string       `json:"undeclaredTags"`               // used by operations but missing from the root's tags
+    UnusedTags         []string       `json:"unusedTags"`                   // declared but used by no operation
+}
+
+var statsMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func runStatsCommand(args []string) error {
+    // --format names the report format here, not the root's.
+    format, args := takeFlag(args, "format")
+    format = strings.ToLower(strings.TrimSpace(format))
+    if format == "" { format = "text" }
+    if format != "text" && format != "json" {
+        return fmt.Errorf("stats: invalid --format %q (expected text or json)", format)
+    }
+    fs, opts := commandFlags("stats", "stats --input <dir> [--format text|json] [options]")
+    cfg, err := commandConfig(fs, opts, args)
+    if err != nil { return err }
+    root, err := indexer.RenderRoot(cfg.index())
+    if err != nil { return err }
+    node, err := bundle.LoadData(cfg.RootPath, root)
+    if err != nil { return err }
+    var spec map[string]interface{}
+    if err := node.Decode(&spec); err != nil { return err }
+
+    st := collectStats(spec)
+    if format == "json" {
+        enc := json.NewEncoder(os.Stdout)
+        enc.SetIndent("", "  ")
+        return enc.Encode(st)
+    }
+    printStats(os.Stdout, st)
+    return nil
+}
+
+func collectStats(spec map[string]interface{}) specStats {
+    st := specStats{OperationsByMethod: map[string]int{}, MissingDescription: []string{}, MissingExamples: []string{}, UndeclaredTags: []string{}, UnusedTags: []string{}}
+    components, _ := spec["components"].(map[string]interface{})
+    schemas, _ := components["schemas"].(map[string]interface{})
+    params, _ := components["parameters"].(map[string]interface{})
+    st.Schemas, st.Parameters = len(schemas), len(params)
+
+    declared := map[string]bool{}
+    tags, _ := spec["tags"].([]interface{})
+    for _, t := range tags {
+        if m, ok := t.(map[string]interface{}); ok {
+            if name, ok := m["name"].(string); ok { declared[name] = true }
+        }
+    }
+    used := map[string]bool{}
+
+    paths, _ := spec["paths"].(map[string]interface{})
+    st.Paths = len(paths)
+    keys := make([]string, 0, len(paths))
+    for k := range paths {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        item, _ := paths[key].(map[string]interface{})
+        for _, method := range statsMethods {
+            op, ok := item[method].(map[string]interface{})
+            if !ok { continue }
+            name := strings.ToUpper(method) + " " + key
+            st.Operations++
+            st.OperationsByMethod[strings.ToUpper(method)]++
+            if d, _ := op["description"].(string); strings.TrimSpace(d) == "" {
+                st.MissingDescription = append(st.MissingDescription, name)
+            }
+            if !operationHasExample(spec, op) {
+                st.MissingExamples = append(st.MissingExamples, name)
+            }
+            if opTags, _ := op["tags"].([]interface{}); len(opTags) > 0 {
+                st.TaggedOperations++
+                for _, t := range opTags {
+                    if s, ok := t.(string); ok { used[s] = true }
+                }
+            }
+        }
+    }
+    if st.Operations > 0 {
+        st.TagCoverage = float64(st.TaggedOperations) / float64(st.Operations)
+    }
+    for t := range used {
+        if !declared[t] { st.UndeclaredTags = append(st.UndeclaredTags, t) }
+    }
+    for t := range declared {
+        if !used[t] { st.UnusedTags = append(st.UnusedTags, t) }
+    }
+    sort.Strings(st.UndeclaredTags)
+    sort.Strings(st.UnusedTags)
+
+    total := 0
+    for _, s := range schemas {
+        d := schemaDepth(s)
+        total += d
+        if d > st.MaxSchemaDepth { st.MaxSchemaDepth = d }
+    }
+    if len(schemas) > 0 {
+        st.AverageSchemaDepth = float64(total) / float64(len(schemas))
+    }
+    return st
+}
+
+// operationHasExample reports whether any request or response media type of
+// op carries an example, either itself or in its schema (following refs).
+func operationHasExample(spec, op map[string]interface{}) bool {
+    hasExample := func(content interface{}) bool {
+        media, _ := content.(map[string]interface{})
+        for _, mt := range media {
+            m, _ := mt.(map[string]interface{})
+            if m["example"] != nil || m["examples"] != nil { return true }
+            if schemaHasExample(spec, m["schema"], map[string]bool{}) { return true }
+        }
+        return false
+    }
+    if body, ok := op["requestBody"].(map[string]interface{}); ok && hasExample(body["content"]) { return true }
+    responses, _ := op["responses"].(map[string]interface{})
+    for _, r := range responses {
+        if m, ok := r.(map[string]interface{}); ok && hasExample(m["content"]) { return true }
+    }
+    return false
+}
+
+func schemaHasExample(spec map[string]interface{}, v interface{}, seen map[string]bool) bool {
+    s, ok := v.(map[string]interface{})
+    if !ok { return false }
+    if ref, ok := s["$ref"].(string); ok {
+        if seen[ref] || !strings.HasPrefix(ref, "#/") { return false }
+        seen[ref] = true
+        var target interface{} = spec
+        for _, tok := range strings.Split(ref[2:], "/") {
+            m, _ := target.(map[string]interface{})
+            target = m[strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)]
+        }
+        return schemaHasExample(spec, target, seen)
+    }
+    if s["example"] != nil || s["examples"] != nil { return true }
+    if props, ok := s["properties"].(map[string]interface{}); ok {
+        for _, p := range props {
+            if schemaHasExample(spec, p, seen) { return true }
+        }
+    }
+    for _, k := range []string{"items", "allOf", "oneOf", "anyOf"} {
+        if subs, ok := s[k].([]interface{}); ok {
+            for _, sub := range subs {
+                if schemaHasExample(spec, sub, seen) { return true }
+            }
+        } else if schemaHasExample(spec, s[k], seen) {
+            return true
+        }
+    }
+    return false
+}
+
+// schemaDepth is the nesting depth of a schema: 1 for a scalar or $ref, plus
+// one per level of properties, items or composition.
+func schemaDepth(v interface{}) int {
+    s, ok := v.(map[string]interface{})
+    if !ok { return 0 }
+    deepest := 0
+    visit := func(child interface{}) {
+        if d := schemaDepth(child); d > deepest { deepest = d }
+    }
+    if props, ok := s["properties"].(map[string]interface{}); ok {
+        for _, p := range props {
+            visit(p)
+        }
+    }
+    visit(s["items"])
+    visit(s["additionalProperties"])
+    for _, k := range []string{"allOf", "oneOf", "anyOf"} {
+        subs, _ := s[k].([]interface{})
+        for _, sub := range subs {
+            visit(sub)
+        }
+    }
+    return deepest + 1
+}
+
+func printStats(w io.Writer, st specStats) {
+    fmt.Fprintf(w, "Paths:       %d\n", st.Paths)
+    fmt.Fprintf(w, "Operations:  %d\n", st.Operations)
+    for _, m := range statsMethods {
+        if n := st.OperationsByMethod[strings.ToUpper(m)]; n > 0 {
+            fmt.Fprintf(w, "  %-8s %d\n", strings.ToUpper(m), n)
+        }
+    }
+    fmt.Fprintf(w, "Schemas:     %d (average depth %.1f, max %d)\n", st.Schemas, st.AverageSchemaDepth, st.MaxSchemaDepth)
+    fmt.Fprintf(w, "Parameters:  %d\n", st.Parameters)
+    fmt.Fprintf(w, "Tags:        %d of %d operation(s) tagged (%.0f%%)\n", st.TaggedOperations, st.Operations, st.TagCoverage*100)
+    if len(st.UndeclaredTags) > 0 { fmt.Fprintf(w, "  used but not declared: %s\n", strings.Join(st.UndeclaredTags, ", ")) }
+    if len(st.UnusedTags) > 0 { fmt.Fprintf(w, "  declared but unused:   %s\n", strings.Join(st.UnusedTags, ", ")) }
+    list := func(title string, ops []string) {
+        fmt.Fprintf(w, "%s: %d\n", title, len(ops))
+        for _, op := range ops {
+            fmt.Fprintf(w, "  %s\n", op)
+        }
+    }
+    list("Operations without description", st.MissingDescription)
+    list("Operations without examples", st.MissingExamples)
+}